@@ -2,20 +2,35 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/mlkem"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"net"
 	"os"
+	"os/signal"
 	"runtime"
+	"slices"
 	"strconv"
+	"text/tabwriter"
 	"time"
 	
 	"github.com/melihxz/holocompute/internal/config"
+	"github.com/melihxz/holocompute/internal/configsync"
+	"github.com/melihxz/holocompute/internal/datadir"
+	"github.com/melihxz/holocompute/internal/doctor"
 	"github.com/melihxz/holocompute/internal/dsm"
 	"github.com/melihxz/holocompute/internal/hyperbus"
 	"github.com/melihxz/holocompute/internal/log"
 	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/internal/metrics"
 	"github.com/melihxz/holocompute/internal/scheduler"
+	"github.com/melihxz/holocompute/internal/script"
+	"github.com/melihxz/holocompute/internal/task"
+	"github.com/melihxz/holocompute/internal/wasm"
+	"github.com/melihxz/holocompute/pkg/holocompute"
 	"github.com/melihxz/holocompute/pkg/proto"
 	"github.com/spf13/cobra"
 )
@@ -99,6 +114,42 @@ var (
 		Short: "Show cluster topology",
 		RunE:  runTop,
 	}
+	
+	// Leases command
+	leasesCmd = &cobra.Command{
+		Use:   "leases [array-id]",
+		Short: "Show page leases held on an array",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runLeases,
+	}
+	
+	// Doctor command
+	doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Run node self-diagnostics",
+		RunE:  runDoctor,
+	}
+	
+	// Config command
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the cluster configuration",
+	}
+	
+	// Config export command
+	configExportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Print the configuration the cluster runs with",
+		RunE:  runConfigExport,
+	}
+	
+	// Config import command
+	configImportCmd = &cobra.Command{
+		Use:   "import [filename]",
+		Short: "Validate a configuration and apply it across the cluster",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runConfigImport,
+	}
 )
 
 // mockHandler implements the hyperbus.MessageHandler interface
@@ -121,11 +172,22 @@ func init() {
 	rootCmd.AddCommand(allocCmd)
 	
 	// Add run subcommands
+	runScriptCmd.Flags().StringP("output", "o", "text", "Output format (text or json)")
+	runScriptCmd.Flags().Bool("continue-on-error", false, "Keep executing directives after one fails")
 	runCmd.AddCommand(runScriptCmd)
 	rootCmd.AddCommand(runCmd)
 	
 	rootCmd.AddCommand(drainCmd)
+	topCmd.Flags().BoolP("watch", "w", false, "Render the topology again on every membership change")
 	rootCmd.AddCommand(topCmd)
+	rootCmd.AddCommand(leasesCmd)
+	rootCmd.AddCommand(doctorCmd)
+	
+	// Add config subcommands
+	configExportCmd.Flags().StringP("output", "o", "yaml", "Output format (yaml or json)")
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+	rootCmd.AddCommand(configCmd)
 }
 
 func main() {
@@ -144,9 +206,30 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 	
-	fmt.Printf("Node ID: %s\n", cfg.Node.ID)
 	fmt.Printf("Listening on: %s\n", cfg.Network.ListenAddr)
 	
+	// Create or migrate the data directory layout
+	layout, err := datadir.Open(cfg.Node.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open data directory: %w", err)
+	}
+	fmt.Printf("Data directory: %s\n", layout.Root)
+	
+	// Load the node's identity, creating it on first run
+	identity, err := layout.LoadOrCreateIdentity()
+	if err != nil {
+		return fmt.Errorf("failed to load node identity: %w", err)
+	}
+	publicKey := identity.Public().(ed25519.PublicKey)
+	
+	// Nodes without a configured ID are named after their key
+	nodeID := hyperbus.NodeID(cfg.Node.ID)
+	if nodeID == "" {
+		nodeID = hyperbus.NodeIDFromKey(publicKey)
+	}
+	fmt.Printf("Node ID: %s\n", nodeID)
+	fmt.Printf("Public key: %x\n", publicKey)
+	
 	// 1. Initialize the hyperbus
 	fmt.Println("1. Initializing hyperbus...")
 	// Create a logger
@@ -163,10 +246,16 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to parse port: %w", err)
 	}
 	
+	listenAddr, err := net.ResolveUDPAddr("udp", cfg.Network.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to resolve listen address: %w", err)
+	}
+	
 	// Create local node info
 	localNode := hyperbus.NodeInfo{
-		ID:      hyperbus.NodeID(cfg.Node.ID),
-		Address: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port},
+		ID:        nodeID,
+		Address:   listenAddr,
+		PublicKey: publicKey,
 		
 		Capabilities: &proto.NodeCapabilities{
 			CpuCores:    int32(runtime.NumCPU()),
@@ -175,15 +264,46 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		},
 	}
 	
-	// Create a mock handler for now
-	handler := &mockHandler{}
-	bus := hyperbus.New(localNode, handler, logger)
+	// Dispatch incoming messages by type
+	mux := hyperbus.NewMux()
+	
+	// Pin the keys peers may present, when a trusted keys file exists
+	busOpts := []hyperbus.QUICOption{hyperbus.WithIdentity(identity)}
+	if cfg.Network.IdleTimeout > 0 {
+		busOpts = append(busOpts, hyperbus.WithIdleTimeout(cfg.Network.IdleTimeout))
+	}
+	trusted, err := loadTrustedKeys(cfg.Security.TrustedKeysFile)
+	if err != nil {
+		return err
+	}
+	if trusted != nil {
+		busOpts = append(busOpts, hyperbus.WithTrustedKeys(trusted))
+	} else {
+		logger.Warn("no trusted keys file, accepting any peer key", "path", cfg.Security.TrustedKeysFile)
+	}
+	
+	quicBus, err := hyperbus.NewQUICBus(localNode, mux, logger, busOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to start hyperbus: %w", err)
+	}
+	defer quicBus.Close()
+	bus := quicBus.Bus
+	bus.SetHandshakeTimeout(cfg.Timeouts.Handshake)
+	bus.SetKeepalive(cfg.Network.KeepaliveInterval, cfg.Network.IdleTimeout)
+	bus.SetConnectionIdleTimeout(cfg.Network.ConnectionIdleTimeout)
+	if cfg.Network.EnablePQ {
+		pqKey, err := mlkem.GenerateKey768()
+		if err != nil {
+			return fmt.Errorf("failed to generate PQ key: %w", err)
+		}
+		bus.SetPQKey(pqKey, identity)
+	}
 	
 	// 2. Start the membership service
 	fmt.Println("2. Starting membership service...")
 	member := &membership.Member{
-		ID:           hyperbus.NodeID(cfg.Node.ID),
-		Address:      &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: port},
+		ID:           nodeID,
+		Address:      &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: port},
 		LastSeen:     time.Now(),
 		Status:       membership.Alive,
 		Capabilities: &proto.NodeCapabilities{
@@ -193,19 +313,147 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		},
 	}
 	
-	_ = membership.NewMembership(member, logger)
+	members := membership.NewMembership(member, logger)
+	bus.SetPeerObserver(members)
+	bus.SetPeerDirectory(members)
+	
+	// Gossip membership and probe members for failure
+	swimConfig := membership.DefaultSWIMConfig()
+	swim := membership.NewSWIM(members, bus, swimConfig, logger)
+	prober := membership.NewBusProber(bus, swimConfig.Probe.Timeout)
+	swim.SetProber(prober)
+	mux.Handle(hyperbus.MsgClusterState, swim)
+	mux.Handle(hyperbus.MsgProbePing, prober)
+	mux.Handle(hyperbus.MsgProbePingReq, prober)
 	
 	// 3. Initialize the memory manager
 	fmt.Println("3. Initializing memory manager...")
-	_ = dsm.NewMemoryManager(bus, logger)
+	memoryManager := dsm.NewMemoryManager(bus, logger)
+	memoryManager.SetLivenessChecker(members)
+	if cfg.Storage.MaxInflightRequests > 0 {
+		memoryManager.SetMaxInflightPerNode(cfg.Storage.MaxInflightRequests)
+	}
+	memoryManager.SetReadRepairRate(cfg.Storage.ReadRepairRate)
+	memoryManager.SetCacheMaxAge(cfg.Storage.CacheMaxAge)
+	memoryManager.SetPageRequestTimeout(cfg.Timeouts.PageRequest)
+	
+	// Array leases and names are shared with the cluster through gossip
+	swim.AddGossipParticipant(memoryManager.ArrayLeases())
+	swim.AddGossipParticipant(memoryManager.ArrayNames())
+	mux.Handle(hyperbus.MsgPageRequest, memoryManager)
+	mux.Handle(hyperbus.MsgArrayQuery, memoryManager)
+	mux.Handle(hyperbus.MsgPagePush, memoryManager)
+	mux.Handle(hyperbus.MsgPageRemap, memoryManager)
+	
+	// Spill cold pages to the data directory past the configured threshold
+	spill, err := dsm.NewSpillStore(layout.Spill())
+	if err != nil {
+		return fmt.Errorf("failed to open spill store: %w", err)
+	}
+	memoryManager.SetSpill(spill, int64(cfg.Storage.SpillThreshold)*1024*1024)
+	
+	// Log page writes so a crash can't lose them, replaying what the last run left
+	if cfg.Storage.WAL {
+		wal, err := dsm.OpenWAL(layout.WAL())
+		if err != nil {
+			return fmt.Errorf("failed to open WAL: %w", err)
+		}
+		defer wal.Close()
+		
+		memoryManager.SetWAL(wal)
+		if _, err := memoryManager.Recover(); err != nil {
+			return fmt.Errorf("failed to recover from WAL: %w", err)
+		}
+		defer func() {
+			if err := memoryManager.Checkpoint(); err != nil {
+				logger.Error("failed to checkpoint pages", "error", err)
+			}
+		}()
+	}
+	
+	// Place pages by consistent hashing with the cluster-wide hash. Peers
+	// join the ring as they join the cluster; see followMembership.
+	hash, err := dsm.LookupHash(cfg.Storage.HashFunction)
+	if err != nil {
+		return fmt.Errorf("invalid storage config: %w", err)
+	}
+	ring := dsm.NewRing(hash, 0)
+	ring.Add(localNode.ID)
+	memoryManager.SetRing(ring)
+	
+	// Peers placing pages with another hash would disagree on page owners,
+	// so the handshake refuses them
+	hashName := cfg.Storage.HashFunction
+	if hashName == "" {
+		hashName = dsm.DefaultHash
+	}
+	bus.SetPlacementHash(hashName)
 	
 	// 4. Start the task scheduler
 	fmt.Println("4. Starting task scheduler...")
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	
+	// Reclaim expired page leases so they can't block writers forever
+	leases := dsm.NewLeaseManager(dsm.DefaultLeaseTTL, logger)
+	leases.SetAcquireTimeout(cfg.Timeouts.LeaseAcquire)
+	leases.SetEpochSource(memoryManager)
+	leases.SetNodeID(localNode.ID)
+	mux.Handle(hyperbus.MsgLeaseQuery, leases)
+	leases.StartSweeper(ctx, cfg.Storage.LeaseSweepInterval)
+	defer leases.StopSweeper()
+	
+	swim.Start(ctx)
+	defer swim.Stop()
+	
+	// Join the cluster through the configured bootstrap nodes
+	if len(cfg.Network.BootstrapNodes) > 0 {
+		if err := swim.Bootstrap(ctx, quicBus.Dial, cfg.Network.BootstrapNodes); err != nil {
+			logger.Warn("starting a new cluster", "error", err)
+		}
+	}
+	
+	// Ping peers, drop the ones that went silent and close unused connections
+	go bus.RunKeepalive(ctx)
+	go bus.RunIdleSweeper(ctx)
+	
+	// Delete arrays once no member holds a lease on them
+	go memoryManager.RunCollector(ctx, cfg.Storage.LeaseSweepInterval)
+	
+	// Keep the ring in step with the live members, moving pages as they change
+	go followMembership(ctx, members, ring, memoryManager, logger)
+	
+	if cfg.Storage.WAL {
+		go memoryManager.RunCheckpointer(ctx, cfg.Storage.CheckpointInterval)
+	}
+	
+	// Run tasks submitted by other nodes against the local memory manager
+	executor, err := wasm.NewExecutor(ctx, memoryManager, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create task executor: %w", err)
+	}
+	defer executor.Close(context.Background())
+	worker := task.NewWorker(bus, executor, logger)
+	worker.SetExecuteTimeout(cfg.Timeouts.TaskSubmit)
+	mux.Handle(hyperbus.MsgTaskSubmit, worker)
+	mux.Handle(hyperbus.MsgTaskCancel, worker)
+	mux.Handle(hyperbus.MsgRangeRun, worker)
+	mux.Handle(hyperbus.MsgMetricsQuery, metrics.NewServer(localNode.ID, memoryManager.CollectMetrics, worker.CollectMetrics))
+	
+	// Serve the cluster config and save signed updates to it; the task
+	// timeout changes live, the rest once the agent restarts
+	configServer := configsync.NewServer(localNode.ID, "config.yaml", cfg.Cluster(), trusted, logger)
+	configServer.SetOnApply(func(cc *config.ClusterConfig) {
+		worker.SetExecuteTimeout(cc.Timeouts.TaskSubmit)
+	})
+	mux.Handle(hyperbus.MsgConfigQuery, configServer)
+	mux.Handle(hyperbus.MsgConfigUpdate, configServer)
+	
 	scheduler := scheduler.NewScheduler(logger)
-	scheduler.Start(ctx)
+	scheduler.SetWorkers(cfg.Node.Workers)
+	if err := scheduler.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start scheduler: %w", err)
+	}
 	defer scheduler.Stop()
 	
 	// 5. Begin accepting connections
@@ -220,6 +468,57 @@ func runAgent(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// followMembership adds members to the ring as they join or come back from
+// the dead and takes them off as they leave or die, rebalancing the pages
+// placed on the ring after every change, until ctx is done
+func followMembership(ctx context.Context, members *membership.Membership, ring *dsm.Ring, memoryManager *dsm.MemoryManager, logger *log.Logger) {
+	events := members.Subscribe()
+	defer members.Unsubscribe(events)
+	
+	// Members known before subscribing
+	for _, member := range members.Snapshot() {
+		if member.Status == membership.Alive {
+			ring.Add(member.ID)
+		}
+	}
+	memoryManager.Rebalance(ctx)
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			switch {
+			case event.Type == membership.MemberLeft || event.NewStatus == membership.Dead:
+				ring.Remove(event.Member.ID)
+			case event.Type == membership.MemberJoined && event.Member.Status == membership.Alive,
+				event.OldStatus == membership.Dead && event.NewStatus == membership.Alive:
+				ring.Add(event.Member.ID)
+			default:
+				continue
+			}
+			logger.Debug("ring changed", "member_id", event.Member.ID, "status", event.Member.Status)
+			memoryManager.Rebalance(ctx)
+		}
+	}
+}
+
+// loadTrustedKeys reads the pinned peer keys, returning nil when no file is
+// configured or it doesn't exist
+func loadTrustedKeys(path string) (*hyperbus.TrustedKeys, error) {
+	if path == "" {
+		return nil, nil
+	}
+	keys, err := hyperbus.LoadTrustedKeys(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trusted keys: %w", err)
+	}
+	return keys, nil
+}
+
 func runJoin(cmd *cobra.Command, args []string) error {
 	address := args[0]
 	fmt.Printf("Joining cluster at %s...\n", address)
@@ -346,26 +645,57 @@ func runAllocArray(cmd *cobra.Command, args []string) error {
 
 func runScript(cmd *cobra.Command, args []string) error {
 	filename := args[0]
-	fmt.Printf("Running script %s...\n", filename)
 	
-	// 1. Load and parse the script
-	// In a real implementation, we would load and parse the script file
-	fmt.Printf("Loading script file: %s\n", filename)
+	output, _ := cmd.Flags().GetString("output")
+	continueOnError, _ := cmd.Flags().GetBool("continue-on-error")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("invalid output format %q: must be text or json", output)
+	}
 	
-	data, err := os.ReadFile(filename)
+	// Load configuration
+	cfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	
+	// 1. Load and parse the script
+	f, err := os.Open(filename)
 	if err != nil {
 		return fmt.Errorf("failed to read script file: %w", err)
 	}
+	defer f.Close()
 	
-	fmt.Printf("Script content (%d bytes):\n%s\n", len(data), string(data))
+	directives, err := script.Parse(f)
+	if err != nil {
+		return err
+	}
 	
 	// 2. Execute the script in the cluster
-	// In a real implementation, we would execute the script in the cluster
-	fmt.Println("Executing script in cluster")
+	localNode := hyperbus.NodeInfo{
+		ID:      hyperbus.NodeID(cfg.Node.ID),
+		Address: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8443}, // Use default port
+	}
+	logger := log.New(slog.LevelWarn)
+	bus := hyperbus.New(localNode, &mockHandler{}, logger)
+	memoryManager := dsm.NewMemoryManager(bus, logger)
+	
+	executor := script.NewExecutor(memoryManager, localNode.ID, logger)
+	executor.ContinueOnError = continueOnError
+	result := executor.Execute(context.Background(), directives)
 	
 	// 3. Return results
-	// In a real implementation, we would return the execution results
-	fmt.Println("Script execution completed successfully")
+	if output == "json" {
+		err = result.WriteJSON(os.Stdout)
+	} else {
+		err = result.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	
+	if !result.OK() {
+		return fmt.Errorf("%d directives failed", result.Failed)
+	}
 	
 	return nil
 }
@@ -394,6 +724,49 @@ func runDrain(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runLeases(cmd *cobra.Command, args []string) error {
+	arrayID := holocompute.ArrayID(args[0])
+	fmt.Printf("Showing leases on array %s...\n", arrayID)
+	
+	// Load configuration
+	cfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	
+	// 1. Connect to the cluster
+	fmt.Println("Connecting to cluster")
+	ctx := context.Background()
+	cluster, err := holocompute.Connect(ctx, holocompute.Options{
+		Bootstrap:   cfg.Network.BootstrapNodes,
+		TaskTimeout: cfg.Timeouts.TaskSubmit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+	defer cluster.Close()
+	
+	// 2. Collect a lease report from every member
+	fmt.Println("Collecting lease reports")
+	leases, err := cluster.Leases(ctx, arrayID)
+	if err != nil {
+		return fmt.Errorf("failed to collect leases: %w", err)
+	}
+	
+	// 3. Display the merged leases
+	if len(leases) == 0 {
+		fmt.Println("No active leases")
+		return nil
+	}
+	
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PAGE\tTYPE\tOWNER\tEXPIRES")
+	for _, lease := range leases {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", lease.PageID, lease.Type, lease.Owner, lease.ExpiresAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
 func runTop(cmd *cobra.Command, args []string) error {
 	fmt.Println("Showing cluster topology...")
 	
@@ -403,22 +776,194 @@ func runTop(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 	
-	// 1. Connect to the cluster
-	// In a real implementation, we would connect to the cluster
+	// 1. Connect to the cluster through its agents
 	fmt.Println("Connecting to cluster")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cluster, err := holocompute.Connect(ctx, holocompute.Options{
+		Bootstrap:   cfg.Network.BootstrapNodes,
+		TaskTimeout: cfg.Timeouts.TaskSubmit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+	defer cluster.Close()
 	
-	// 2. Query cluster topology
-	// In a real implementation, we would query the cluster topology
+	// 2. Query cluster topology, once or on every membership change
 	fmt.Println("Querying cluster topology")
+	watch, _ := cmd.Flags().GetBool("watch")
+	if !watch {
+		topology, err := cluster.Topology(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to query topology: %w", err)
+		}
+		return printTopology(topology)
+	}
+	
+	updates, err := cluster.WatchTopology(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to watch topology: %w", err)
+	}
+	for topology := range updates {
+		if err := printTopology(topology); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// printTopology renders a cluster topology as a table
+func printTopology(topology *holocompute.Topology) error {
+	fmt.Printf("Cluster Topology: %d nodes, %d pages, %d connected\n", len(topology.Nodes), topology.TotalPages, topology.Connected)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tADDRESS\tSTATUS\tCPUS\tMEMORY\tPAGES\tCONNECTION")
+	for _, node := range topology.Nodes {
+		var cpus int32
+		var memory int64
+		if node.Capabilities != nil {
+			cpus, memory = node.Capabilities.CpuCores, node.Capabilities.MemoryBytes
+		}
+		
+		connection := "none"
+		switch {
+		case node.Local:
+			connection = "local"
+		case node.Connected:
+			connection = "connected"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%dMB\t%d\t%s\n", node.ID, node.Address, node.Status, cpus, memory>>20, node.OwnedPages, connection)
+	}
+	return w.Flush()
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	fmt.Println("Running node diagnostics...")
+	
+	// The config check gates the others since they depend on its values
+	cfg, configResult := doctor.CheckConfig("config.yaml")
+	results := []doctor.Result{configResult}
+	
+	if cfg != nil {
+		checks := []doctor.Check{
+			func(ctx context.Context) doctor.Result {
+				return doctor.CheckCerts(cfg.Security.CertFile, cfg.Security.KeyFile)
+			},
+			func(ctx context.Context) doctor.Result {
+				return doctor.CheckListenAddr(cfg.Network.ListenAddr)
+			},
+			func(ctx context.Context) doctor.Result {
+				return doctor.CheckDiskSpace(cfg.Node.DataDir, doctor.MinFreeBytes)
+			},
+			func(ctx context.Context) doctor.Result {
+				skews := doctor.SampleClockSkew(ctx, cfg.Network.BootstrapNodes, 5*time.Second)
+				return doctor.CheckClockSkew(skews, doctor.MaxClockSkew)
+			},
+			func(ctx context.Context) doctor.Result {
+				return doctor.CheckBootstrap(ctx, cfg.Network.BootstrapNodes, 5*time.Second)
+			},
+		}
+		results = append(results, doctor.Run(context.Background(), checks)...)
+	}
+	
+	for _, r := range results {
+		fmt.Printf("[%s] %-10s %s\n", r.Status, r.Name, r.Message)
+	}
+	
+	if failed := doctor.Failed(results); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	
+	fmt.Println("All checks passed")
+	return nil
+}
+
+func runConfigExport(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	
+	ctx := context.Background()
+	cluster, err := holocompute.Connect(ctx, holocompute.Options{Bootstrap: cfg.Network.BootstrapNodes})
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+	defer cluster.Close()
+	
+	// The agents report what they run with, not what this node's file says
+	clusterConfig, err := cluster.Config(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to query cluster config: %w", err)
+	}
+	
+	format, _ := cmd.Flags().GetString("output")
+	return clusterConfig.Export(os.Stdout, format)
+}
+
+func runConfigImport(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", args[0], err)
+	}
+	
+	// Nothing is sent unless the whole configuration is valid
+	clusterConfig, err := config.Import(data)
+	if err != nil {
+		return err
+	}
 	
-	// 3. Display an interactive view of the cluster
-	// In a real implementation, we would display an interactive view
-	fmt.Println("Cluster Topology:")
-	fmt.Printf("  Node ID: %s\n", cfg.Node.ID)
-	fmt.Printf("  Address: %s\n", cfg.Network.ListenAddr)
-	fmt.Println("  Status: Active")
-	fmt.Println("  CPU Cores: ", runtime.NumCPU())
-	fmt.Println("  Memory: 1GB (placeholder)")
+	cfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	
+	// Agents accept updates signed by a node they trust
+	layout, err := datadir.Open(cfg.Node.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open data directory: %w", err)
+	}
+	identity, err := layout.LoadOrCreateIdentity()
+	if err != nil {
+		return fmt.Errorf("failed to load node identity: %w", err)
+	}
+	
+	ctx := context.Background()
+	cluster, err := holocompute.Connect(ctx, holocompute.Options{Bootstrap: cfg.Network.BootstrapNodes})
+	if err != nil {
+		return fmt.Errorf("failed to connect to cluster: %w", err)
+	}
+	defer cluster.Close()
+	
+	results, err := cluster.UpdateConfig(ctx, clusterConfig, identity)
+	if err != nil {
+		return fmt.Errorf("failed to update cluster config: %w", err)
+	}
 	
+	nodes := make([]holocompute.NodeID, 0, len(results))
+	for nodeID := range results {
+		nodes = append(nodes, nodeID)
+	}
+	slices.Sort(nodes)
+	
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tRESULT")
+	for _, nodeID := range nodes {
+		result := "applied"
+		if err := results[nodeID]; err != nil {
+			result = err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\n", nodeID, result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	
+	if failed > 0 {
+		return fmt.Errorf("%d of %d agents did not apply the config", failed, len(results))
+	}
+	fmt.Println("Settings other than timeouts.task_submit take effect when each agent restarts")
 	return nil
-}
\ No newline at end of file
+}
@@ -54,7 +54,10 @@ func main() {
 
 	// Start the scheduler
 	ctx, cancel := context.WithCancel(context.Background())
-	taskScheduler.Start(ctx)
+	if err := taskScheduler.Start(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to start scheduler:", err)
+		os.Exit(1)
+	}
 	defer func() {
 		cancel()
 		taskScheduler.Stop()
@@ -24,7 +24,7 @@ func main() {
 	// Create a shared array of 100M elements
 	fmt.Println("Creating shared array...")
 	start := time.Now()
-	arr, err := c.NewSharedArray(100_000_000, holocompute.Policy{
+	arr, err := holocompute.NewTypedArray[int64](c, 100_000_000, holocompute.Policy{
 		Replication: 1,
 	})
 	if err != nil {
@@ -57,7 +57,7 @@ func main() {
 	fmt.Println("Computing sum...")
 	start = time.Now()
 	var sum interface{}
-	err = c.Reduce(arr,
+	err = c.Reduce(arr.Shared(),
 		func(v interface{}) (interface{}, error) { return v, nil },
 		func(a, b interface{}) interface{} {
 			// In a real implementation, we would do proper type assertions
@@ -24,14 +24,14 @@ func main() {
 	// Create input arrays
 	fmt.Println("Creating input arrays...")
 	start := time.Now()
-	arrA, err := c.NewSharedArray(10_000_000, holocompute.Policy{
+	arrA, err := holocompute.NewTypedArray[float32](c, 10_000_000, holocompute.Policy{
 		Replication: 1,
 	})
 	if err != nil {
 		log.Fatal("Failed to create array A:", err)
 	}
 
-	arrB, err := c.NewSharedArray(10_000_000, holocompute.Policy{
+	arrB, err := holocompute.NewTypedArray[float32](c, 10_000_000, holocompute.Policy{
 		Replication: 1,
 	})
 	if err != nil {
@@ -68,7 +68,7 @@ func main() {
 	fmt.Printf("Input arrays created and filled in %v\n", time.Since(start))
 
 	// Create output array
-	arrC, err := c.NewSharedArray(10_000_000, holocompute.Policy{
+	arrC, err := holocompute.NewTypedArray[float32](c, 10_000_000, holocompute.Policy{
 		Replication: 1,
 	})
 	if err != nil {
@@ -84,11 +84,11 @@ func main() {
 		Module: mod,
 		Func:   "vec_add",
 		Inputs: holocompute.Inputs{
-			"A": arrA,
-			"B": arrB,
+			"A": arrA.Shared(),
+			"B": arrB.Shared(),
 		},
 		Outputs: holocompute.Outputs{
-			"C": arrC,
+			"C": arrC.Shared(),
 		},
 		ResourceHints: holocompute.ResourceHints{
 			CPU:      2,
@@ -114,8 +114,7 @@ func main() {
 		a, _ := arrA.Get(i)
 		b, _ := arrB.Get(i)
 		c, _ := arrC.Get(i)
-		// In a real implementation, we would do proper type assertions
-		fmt.Printf("Index %d: A=%v, B=%v, C=%v\n", i, a, b, c)
+		fmt.Printf("Index %d: A=%g, B=%g, C=%g\n", i, a, b, c)
 	}
 
 	// Clean up
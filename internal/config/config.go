@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 	
 	"gopkg.in/yaml.v3"
 )
@@ -20,6 +22,9 @@ type Config struct {
 	
 	// Security configuration
 	Security SecurityConfig `yaml:"security"`
+	
+	// Default deadlines for operations whose caller sets none
+	Timeouts TimeoutConfig `yaml:"timeouts"`
 }
 
 // NodeConfig contains node-specific configuration
@@ -32,6 +37,9 @@ type NodeConfig struct {
 	
 	// DataDir is the directory for storing data
 	DataDir string `yaml:"data_dir"`
+	
+	// Workers sizes the task scheduler's worker pool; 0 uses one per CPU
+	Workers int `yaml:"workers"`
 }
 
 // NetworkConfig contains network configuration
@@ -47,6 +55,15 @@ type NetworkConfig struct {
 	
 	// EnablePQ enables post-quantum cryptography
 	EnablePQ bool `yaml:"enable_pq"`
+	
+	// KeepaliveInterval is how often each peer connection is pinged
+	KeepaliveInterval time.Duration `yaml:"keepalive_interval"`
+	
+	// IdleTimeout closes a peer connection that sent nothing for this long
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	
+	// ConnectionIdleTimeout closes a peer connection unused for this long; 0 keeps it open
+	ConnectionIdleTimeout time.Duration `yaml:"connection_idle_timeout"`
 }
 
 // StorageConfig contains storage configuration
@@ -56,6 +73,42 @@ type StorageConfig struct {
 	
 	// SpillThreshold is the threshold for spilling to disk in MB
 	SpillThreshold int `yaml:"spill_threshold"`
+	
+	// MaxInflightRequests caps concurrent page requests to a single node
+	MaxInflightRequests int `yaml:"max_inflight_requests"`
+	
+	// HashFunction places pages on the consistent-hash ring; every node must use the same one
+	HashFunction string `yaml:"hash_function"`
+	
+	// LeaseSweepInterval is how often expired page leases are reclaimed and arrays whose leases all lapsed are collected
+	LeaseSweepInterval time.Duration `yaml:"lease_sweep_interval"`
+	
+	// CacheMaxAge is how long a cached remote page is served before its version is rechecked; 0 never
+	CacheMaxAge time.Duration `yaml:"cache_max_age"`
+	
+	// ReadRepairRate caps stale replicas repaired per second; 0 disables read-repair
+	ReadRepairRate int `yaml:"read_repair_rate"`
+	
+	// WAL logs page writes to the data directory so they survive a crash
+	WAL bool `yaml:"wal"`
+	
+	// CheckpointInterval is how often pages are flushed and the WAL truncated
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval"`
+}
+
+// TimeoutConfig contains default operation deadlines; 0 leaves an operation unbounded
+type TimeoutConfig struct {
+	// PageRequest bounds fetching a page or array metadata from a peer
+	PageRequest time.Duration `yaml:"page_request"`
+	
+	// LeaseAcquire bounds waiting for a contended page lease
+	LeaseAcquire time.Duration `yaml:"lease_acquire"`
+	
+	// TaskSubmit bounds a submitted task from scheduling to result
+	TaskSubmit time.Duration `yaml:"task_submit"`
+	
+	// Handshake bounds the ControlHello exchange on a new connection
+	Handshake time.Duration `yaml:"handshake"`
 }
 
 // SecurityConfig contains security configuration
@@ -66,7 +119,7 @@ type SecurityConfig struct {
 	// KeyFile is the path to the TLS key file
 	KeyFile string `yaml:"key_file"`
 	
-	// TrustedKeysFile is the path to the trusted keys file
+	// TrustedKeysFile holds the PEM keys peers may present, each optionally naming its node in a Node-Id header; peers are unpinned if it is missing
 	TrustedKeysFile string `yaml:"trusted_keys_file"`
 }
 
@@ -88,42 +141,76 @@ func DefaultConfig() *Config {
 			DataDir: dataDir,
 		},
 		Network: NetworkConfig{
-			ListenAddr:      "0.0.0.0:8443",
-			PublicAddr:      "127.0.0.1:8443",
-			BootstrapNodes:  []string{},
-			EnablePQ:        true,
+			ListenAddr:            "0.0.0.0:8443",
+			PublicAddr:            "127.0.0.1:8443",
+			BootstrapNodes:        []string{},
+			EnablePQ:              true,
+			KeepaliveInterval:     10 * time.Second,
+			IdleTimeout:           30 * time.Second,
+			ConnectionIdleTimeout: 5 * time.Minute,
 		},
 		Storage: StorageConfig{
-			CacheSize:       1024, // 1GB
-			SpillThreshold:  512,  // 512MB
+			CacheSize:           1024, // 1GB
+			SpillThreshold:      512,  // 512MB
+			MaxInflightRequests: 64,
+			HashFunction:        "xxhash64",
+			LeaseSweepInterval:  10 * time.Second,
+			ReadRepairRate:      100,
+			CheckpointInterval:  time.Minute,
 		},
 		Security: SecurityConfig{
-			CertFile:        filepath.Join(dataDir, "cert.pem"),
-			KeyFile:         filepath.Join(dataDir, "key.pem"),
-			TrustedKeysFile: filepath.Join(dataDir, "trusted_keys.pem"),
+			CertFile:        filepath.Join(dataDir, "certs", "cert.pem"),
+			KeyFile:         filepath.Join(dataDir, "certs", "key.pem"),
+			TrustedKeysFile: filepath.Join(dataDir, "certs", "trusted_keys.pem"),
+		},
+		Timeouts: TimeoutConfig{
+			PageRequest:  10 * time.Second,
+			LeaseAcquire: 30 * time.Second,
+			TaskSubmit:   10 * time.Minute,
+			Handshake:    10 * time.Second,
 		},
 	}
 }
 
-// LoadConfig loads configuration from a file
+// LoadConfig loads configuration from a file, applies the HOLO_*
+// environment variable overrides and validates it
 func LoadConfig(filename string) (*Config, error) {
-	// If file doesn't exist, return default config
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		return DefaultConfig(), nil
-	}
-	
-	// Read the file
-	data, err := os.ReadFile(filename)
+	config, err := LoadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 	
-	// Parse YAML
-	config := &Config{}
-	if err := yaml.Unmarshal(data, config); err != nil {
+	// Environment variables take precedence over the file
+	if err := config.ApplyEnv(); err != nil {
 		return nil, err
 	}
 	
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	
+	return config, nil
+}
+
+// LoadFile loads configuration from a file alone, without the environment
+// overrides or validation, as it is to be saved back
+func LoadFile(filename string) (*Config, error) {
+	config := DefaultConfig()
+	
+	// If file doesn't exist, start from the default config
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		// Read the file
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		
+		// Parse YAML; settings the file leaves out keep their defaults
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, err
+		}
+	}
+	
 	return config, nil
 }
 
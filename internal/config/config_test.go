@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 	
 	"github.com/stretchr/testify/assert"
 )
@@ -30,6 +31,15 @@ func TestDefaultConfig(t *testing.T) {
 	assert.NotEmpty(t, config.Security.CertFile)
 	assert.NotEmpty(t, config.Security.KeyFile)
 	assert.NotEmpty(t, config.Security.TrustedKeysFile)
+	
+	// Verify default deadlines
+	assert.Greater(t, config.Timeouts.PageRequest, time.Duration(0))
+	assert.Greater(t, config.Timeouts.LeaseAcquire, time.Duration(0))
+	assert.Greater(t, config.Timeouts.TaskSubmit, time.Duration(0))
+	assert.Greater(t, config.Timeouts.Handshake, time.Duration(0))
+	assert.Greater(t, config.Network.IdleTimeout, config.Network.KeepaliveInterval)
+	assert.False(t, config.Storage.WAL)
+	assert.Greater(t, config.Storage.CheckpointInterval, time.Duration(0))
 }
 
 func TestSaveLoadConfig(t *testing.T) {
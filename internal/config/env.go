@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables overriding the configuration file
+const (
+	EnvNodeID         = "HOLO_NODE_ID"
+	EnvListenAddr     = "HOLO_LISTEN_ADDR"
+	EnvBootstrapNodes = "HOLO_BOOTSTRAP_NODES" // comma-separated
+	EnvCacheSize      = "HOLO_CACHE_SIZE"      // in MB
+)
+
+// ApplyEnv overrides settings with the environment variables that are set
+// and not empty, leaving the others as they are
+func (c *Config) ApplyEnv() error {
+	if v := os.Getenv(EnvNodeID); v != "" {
+		c.Node.ID = v
+	}
+	if v := os.Getenv(EnvListenAddr); v != "" {
+		c.Network.ListenAddr = v
+	}
+	if v := os.Getenv(EnvBootstrapNodes); v != "" {
+		var nodes []string
+		for _, node := range strings.Split(v, ",") {
+			if node = strings.TrimSpace(node); node != "" {
+				nodes = append(nodes, node)
+			}
+		}
+		c.Network.BootstrapNodes = nodes
+	}
+	if v := os.Getenv(EnvCacheSize); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", EnvCacheSize, v, err)
+		}
+		c.Storage.CacheSize = size
+	}
+	return nil
+}
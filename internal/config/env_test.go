@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig_EnvOverrides(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte("node:\n  id: file-node\nnetwork:\n  listen_addr: \"0.0.0.0:9000\"\n  public_addr: \"10.0.0.1:9000\"\nstorage:\n  cache_size: 1024\n  spill_threshold: 512\n")
+	assert.NoError(t, os.WriteFile(file, data, 0644))
+
+	t.Setenv(EnvNodeID, "env-node")
+	t.Setenv(EnvBootstrapNodes, "10.0.0.2:8443, 10.0.0.3:8443")
+	t.Setenv(EnvCacheSize, "2048")
+
+	cfg, err := LoadConfig(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "env-node", cfg.Node.ID)
+	assert.Equal(t, []string{"10.0.0.2:8443", "10.0.0.3:8443"}, cfg.Network.BootstrapNodes)
+	assert.Equal(t, 2048, cfg.Storage.CacheSize)
+
+	// Unset variables fall back to the file
+	assert.Equal(t, "0.0.0.0:9000", cfg.Network.ListenAddr)
+	assert.Equal(t, "10.0.0.1:9000", cfg.Network.PublicAddr)
+
+	// Overrides apply without a file too
+	cfg, err = LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "env-node", cfg.Node.ID)
+}
+
+func TestLoadConfig_InvalidEnv(t *testing.T) {
+	t.Setenv(EnvCacheSize, "lots")
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, EnvCacheSize)
+
+	// Overrides are validated like the file
+	t.Setenv(EnvCacheSize, "")
+	t.Setenv(EnvListenAddr, "no-port")
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "network.listen_addr")
+}
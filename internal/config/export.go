@@ -0,0 +1,108 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ClusterConfig is the part of the configuration every node of a cluster
+// shares, as exported from and imported into a running cluster. Settings
+// that identify or locate a single node, such as its ID, addresses,
+// bootstrap peers and directories, stay out of it.
+type ClusterConfig struct {
+	Network  ClusterNetworkConfig `yaml:"network"`
+	Storage  StorageConfig        `yaml:"storage"`
+	Timeouts TimeoutConfig        `yaml:"timeouts"`
+}
+
+// ClusterNetworkConfig holds the network settings shared across the
+// cluster, under the same keys as in NetworkConfig
+type ClusterNetworkConfig struct {
+	EnablePQ              bool          `yaml:"enable_pq"`
+	KeepaliveInterval     time.Duration `yaml:"keepalive_interval"`
+	IdleTimeout           time.Duration `yaml:"idle_timeout"`
+	ConnectionIdleTimeout time.Duration `yaml:"connection_idle_timeout"`
+}
+
+// Cluster returns the cluster-wide part of the configuration
+func (c *Config) Cluster() *ClusterConfig {
+	return &ClusterConfig{
+		Network: ClusterNetworkConfig{
+			EnablePQ:              c.Network.EnablePQ,
+			KeepaliveInterval:     c.Network.KeepaliveInterval,
+			IdleTimeout:           c.Network.IdleTimeout,
+			ConnectionIdleTimeout: c.Network.ConnectionIdleTimeout,
+		},
+		Storage:  c.Storage,
+		Timeouts: c.Timeouts,
+	}
+}
+
+// ApplyCluster replaces the cluster-wide part of the configuration with
+// cc, keeping the node's own settings
+func (c *Config) ApplyCluster(cc *ClusterConfig) {
+	c.Network.EnablePQ = cc.Network.EnablePQ
+	c.Network.KeepaliveInterval = cc.Network.KeepaliveInterval
+	c.Network.IdleTimeout = cc.Network.IdleTimeout
+	c.Network.ConnectionIdleTimeout = cc.Network.ConnectionIdleTimeout
+	c.Storage = cc.Storage
+	c.Timeouts = cc.Timeouts
+}
+
+// Validate checks the settings the way Config.Validate does
+func (cc *ClusterConfig) Validate() error {
+	cfg := DefaultConfig()
+	cfg.ApplyCluster(cc)
+	return cfg.Validate()
+}
+
+// Export writes the configuration to w as YAML, or as JSON with the same
+// keys if format is "json"
+func (cc *ClusterConfig) Export(w io.Writer, format string) error {
+	data, err := yaml.Marshal(cc)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	switch format {
+	case "", "yaml":
+	case "json":
+		// Round-trip through a generic document so JSON keeps the YAML keys
+		// and durations stay readable
+		var doc map[string]any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+		if data, err = json.MarshalIndent(doc, "", "  "); err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("unknown config format %q", format)
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// Import parses a cluster configuration exported as YAML or JSON and
+// validates it. Settings the document leaves out keep their defaults, and
+// unknown keys, such as a node's own settings, are rejected.
+func Import(data []byte) (*ClusterConfig, error) {
+	cc := DefaultConfig().Cluster()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := cc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cc, nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Storage.CacheSize = 2048
+	cfg.Storage.ReadRepairRate = 7
+	cfg.Storage.CacheMaxAge = 90 * time.Second
+	cfg.Network.KeepaliveInterval = 3 * time.Second
+	cfg.Timeouts.PageRequest = 3 * time.Second
+
+	for _, format := range []string{"yaml", "json"} {
+		var buf bytes.Buffer
+		assert.NoError(t, cfg.Cluster().Export(&buf, format))
+
+		// The node's own settings aren't part of the document
+		assert.NotContains(t, buf.String(), "node-1", format)
+		assert.NotContains(t, buf.String(), "listen_addr", format)
+		assert.NotContains(t, buf.String(), "data_dir", format)
+
+		imported, err := Import(buf.Bytes())
+		assert.NoError(t, err, format)
+		assert.Equal(t, cfg.Cluster(), imported, format)
+	}
+
+	assert.Error(t, cfg.Cluster().Export(&bytes.Buffer{}, "toml"))
+}
+
+func TestConfig_ApplyClusterKeepsNodeSettings(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Node.ID = "node-7"
+	cfg.Network.ListenAddr = "0.0.0.0:9443"
+
+	cc := DefaultConfig().Cluster()
+	cc.Storage.CacheSize = 4096
+	cfg.ApplyCluster(cc)
+	assert.Equal(t, "node-7", cfg.Node.ID)
+	assert.Equal(t, "0.0.0.0:9443", cfg.Network.ListenAddr)
+	assert.Equal(t, 4096, cfg.Storage.CacheSize)
+}
+
+func TestImport_RejectsInvalidConfig(t *testing.T) {
+	for name, mutate := range map[string]func(cc *ClusterConfig){
+		"cache size":      func(cc *ClusterConfig) { cc.Storage.CacheSize = -1 },
+		"spill threshold": func(cc *ClusterConfig) { cc.Storage.SpillThreshold = cc.Storage.CacheSize + 1 },
+	} {
+		cc := DefaultConfig().Cluster()
+		mutate(cc)
+		var buf bytes.Buffer
+		assert.NoError(t, cc.Export(&buf, "yaml"))
+
+		_, err := Import(buf.Bytes())
+		assert.ErrorContains(t, err, "invalid config", name)
+	}
+
+	_, err := Import([]byte("storage: [not a mapping"))
+	assert.ErrorContains(t, err, "failed to parse config")
+
+	// A node's own settings can't be imported into the cluster
+	_, err = Import([]byte("node:\n  id: node-2\n"))
+	assert.ErrorContains(t, err, "failed to parse config")
+	_, err = Import([]byte("network:\n  listen_addr: 0.0.0.0:1\n"))
+	assert.ErrorContains(t, err, "failed to parse config")
+}
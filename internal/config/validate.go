@@ -0,0 +1,42 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Validate checks the configuration for settings the agent can't start
+// with, returning every problem found joined into one error
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Node.ID == "" {
+		errs = append(errs, errors.New("node.id is empty"))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Network.ListenAddr); err != nil {
+		errs = append(errs, fmt.Errorf("invalid network.listen_addr %q: %w", c.Network.ListenAddr, err))
+	}
+	if c.Network.PublicAddr != "" {
+		if _, _, err := net.SplitHostPort(c.Network.PublicAddr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid network.public_addr %q: %w", c.Network.PublicAddr, err))
+		}
+	}
+
+	if c.Storage.CacheSize <= 0 {
+		errs = append(errs, fmt.Errorf("storage.cache_size must be positive, got %d", c.Storage.CacheSize))
+	}
+	switch {
+	case c.Storage.SpillThreshold <= 0:
+		errs = append(errs, fmt.Errorf("storage.spill_threshold must be positive, got %d", c.Storage.SpillThreshold))
+	case c.Storage.CacheSize > 0 && c.Storage.SpillThreshold > c.Storage.CacheSize:
+		errs = append(errs, fmt.Errorf("storage.spill_threshold %d exceeds storage.cache_size %d", c.Storage.SpillThreshold, c.Storage.CacheSize))
+	}
+
+	if (c.Security.CertFile == "") != (c.Security.KeyFile == "") {
+		errs = append(errs, errors.New("security.cert_file and security.key_file must be set together"))
+	}
+
+	return errors.Join(errs...)
+}
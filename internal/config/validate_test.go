@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, DefaultConfig().Validate())
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		field  string
+	}{
+		{name: "empty node ID", modify: func(c *Config) { c.Node.ID = "" }, field: "node.id"},
+		{name: "listen address without port", modify: func(c *Config) { c.Network.ListenAddr = "0.0.0.0" }, field: "network.listen_addr"},
+		{name: "public address without port", modify: func(c *Config) { c.Network.PublicAddr = "example.com" }, field: "network.public_addr"},
+		{name: "negative cache size", modify: func(c *Config) { c.Storage.CacheSize = -1 }, field: "storage.cache_size"},
+		{name: "zero spill threshold", modify: func(c *Config) { c.Storage.SpillThreshold = 0 }, field: "storage.spill_threshold"},
+		{name: "spill threshold above cache size", modify: func(c *Config) { c.Storage.SpillThreshold = c.Storage.CacheSize + 1 }, field: "exceeds storage.cache_size"},
+		{name: "cert without key", modify: func(c *Config) { c.Security.KeyFile = "" }, field: "security.key_file"},
+		{name: "key without cert", modify: func(c *Config) { c.Security.CertFile = "" }, field: "security.cert_file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(cfg)
+			assert.ErrorContains(t, cfg.Validate(), tt.field)
+		})
+	}
+
+	// Neither certificate nor key is fine
+	cfg := DefaultConfig()
+	cfg.Security.CertFile, cfg.Security.KeyFile = "", ""
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoadConfig_ReportsEveryProblem(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	data := []byte("node:\n  id: \"\"\nnetwork:\n  listen_addr: \"0.0.0.0\"\nstorage:\n  cache_size: -5\n")
+	assert.NoError(t, os.WriteFile(file, data, 0644))
+
+	_, err := LoadConfig(file)
+	assert.ErrorContains(t, err, "node.id")
+	assert.ErrorContains(t, err, "network.listen_addr")
+	assert.ErrorContains(t, err, "storage.cache_size")
+
+	// Settings a file leaves out keep their defaults
+	assert.NoError(t, os.WriteFile(file, []byte("node:\n  id: worker-7\n"), 0644))
+	cfg, err := LoadConfig(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "worker-7", cfg.Node.ID)
+	assert.Equal(t, DefaultConfig().Storage.CacheSize, cfg.Storage.CacheSize)
+}
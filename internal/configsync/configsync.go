@@ -0,0 +1,235 @@
+// Package configsync propagates the cluster-wide configuration: members
+// answer queries for the configuration they run with and apply updates
+// signed by a trusted node
+package configsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/melihxz/holocompute/internal/config"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+var (
+	// ErrBadSignature is returned for an update whose signature doesn't verify
+	ErrBadSignature = errors.New("bad config update signature")
+
+	// ErrStaleUpdate is returned for an update older than the one applied
+	ErrStaleUpdate = errors.New("stale config update")
+)
+
+// Sign creates an update carrying cc, signed with key. Members apply
+// updates in version order, so version should grow with every update, as
+// issue times do.
+func Sign(cc *config.ClusterConfig, version int64, key ed25519.PrivateKey) (*proto.ConfigUpdate, error) {
+	var buf bytes.Buffer
+	if err := cc.Export(&buf, "yaml"); err != nil {
+		return nil, err
+	}
+	return &proto.ConfigUpdate{
+		Version:   version,
+		Config:    buf.Bytes(),
+		SignerKey: key.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(key, signedData(version, buf.Bytes())),
+	}, nil
+}
+
+// Verify checks an update's signature and that its signer is trusted. A
+// nil trusted set accepts any signer, as unpinned nodes accept any peer.
+func Verify(update *proto.ConfigUpdate, trusted *hyperbus.TrustedKeys) error {
+	key := ed25519.PublicKey(update.SignerKey)
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, signedData(update.Version, update.Config), update.Signature) {
+		return ErrBadSignature
+	}
+	if trusted != nil && !trusted.Contains(key) {
+		return fmt.Errorf("%w: config update signed by %s", hyperbus.ErrUntrustedKey, hyperbus.NodeIDFromKey(key))
+	}
+	return nil
+}
+
+// signedData returns the bytes an update's signature covers
+func signedData(version int64, cfg []byte) []byte {
+	data := binary.BigEndian.AppendUint64(nil, uint64(version))
+	return append(data, cfg...)
+}
+
+// Server answers configuration queries with the cluster configuration the
+// node runs with, and applies signed updates to it, saving them to the
+// node's configuration file
+type Server struct {
+	nodeID  hyperbus.NodeID
+	path    string
+	trusted *hyperbus.TrustedKeys
+	current *config.ClusterConfig
+	version int64 // of the last update applied
+	onApply func(cc *config.ClusterConfig)
+	logger  *log.Logger
+	mu      sync.Mutex
+}
+
+// NewServer creates a server for a node running with current, saving
+// updates to the configuration file at path. Updates must be signed by a
+// key in trusted, or by any key if trusted is nil.
+func NewServer(nodeID hyperbus.NodeID, path string, current *config.ClusterConfig, trusted *hyperbus.TrustedKeys, logger *log.Logger) *Server {
+	return &Server{
+		nodeID:  nodeID,
+		path:    path,
+		trusted: trusted,
+		current: current,
+		logger:  logger,
+	}
+}
+
+// SetOnApply sets a function called with every update applied, so the
+// settings that can change at runtime take effect without a restart
+func (s *Server) SetOnApply(onApply func(cc *config.ClusterConfig)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onApply = onApply
+}
+
+// Current returns the cluster configuration the node runs with and the
+// version of the last update applied, 0 if none
+func (s *Server) Current() (*config.ClusterConfig, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current, s.version
+}
+
+// HandleMessage answers a ConfigQuery or applies a ConfigUpdate
+func (s *Server) HandleMessage(ctx context.Context, conn hyperbus.Connection, stream hyperbus.Stream, data []byte) error {
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	var msg []byte
+	switch header.Type {
+	case hyperbus.MsgConfigQuery:
+		current, version := s.Current()
+		var buf bytes.Buffer
+		if err := current.Export(&buf, "yaml"); err != nil {
+			return err
+		}
+		msg, err = hyperbus.EncodeReply(data, hyperbus.MsgConfigDocument, &proto.ConfigDocument{
+			NodeId:  string(s.nodeID),
+			Config:  buf.Bytes(),
+			Version: version,
+		})
+
+	case hyperbus.MsgConfigUpdate:
+		var update proto.ConfigUpdate
+		if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &update); err != nil {
+			return err
+		}
+		ack := &proto.ConfigAck{NodeId: string(s.nodeID)}
+		if err := s.Apply(&update); err != nil {
+			s.logger.Warn("rejected config update", "node_id", conn.NodeID(), "version", update.Version, "error", err)
+			ack.Error = err.Error()
+		}
+		msg, err = hyperbus.EncodeReply(data, hyperbus.MsgConfigAck, ack)
+
+	default:
+		return fmt.Errorf("unexpected message type %d", header.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode reply: %w", err)
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// Apply verifies and validates an update, saves it to the configuration
+// file over the node's cluster-wide settings and makes it current.
+// Applying the current update again succeeds without saving it twice.
+func (s *Server) Apply(update *proto.ConfigUpdate) error {
+	if err := Verify(update, s.trusted); err != nil {
+		return err
+	}
+	cc, err := config.Import(update.Config)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case update.Version == s.version:
+		return nil
+	case update.Version < s.version:
+		return fmt.Errorf("%w: version %d, applied %d", ErrStaleUpdate, update.Version, s.version)
+	}
+
+	// The file keeps the node's own settings, without environment overrides
+	file, err := config.LoadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", s.path, err)
+	}
+	file.ApplyCluster(cc)
+	if err := file.SaveConfig(s.path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", s.path, err)
+	}
+
+	s.current = cc
+	s.version = update.Version
+	s.logger.Info("applied cluster config update", "version", update.Version, "signer", hyperbus.NodeIDFromKey(update.SignerKey))
+	if s.onApply != nil {
+		s.onApply(cc)
+	}
+	return nil
+}
+
+// Query asks a node for the cluster configuration it runs with
+func Query(ctx context.Context, bus *hyperbus.Bus, nodeID hyperbus.NodeID) (*proto.ConfigDocument, error) {
+	data, err := bus.Call(ctx, nodeID, hyperbus.MsgConfigQuery, &proto.ConfigQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.Type != hyperbus.MsgConfigDocument {
+		return nil, fmt.Errorf("unexpected message type %d in reply to config query", header.Type)
+	}
+
+	var doc proto.ConfigDocument
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Push sends an update to a node, returning why it rejected it, if it did
+func Push(ctx context.Context, bus *hyperbus.Bus, nodeID hyperbus.NodeID, update *proto.ConfigUpdate) error {
+	data, err := bus.Call(ctx, nodeID, hyperbus.MsgConfigUpdate, update)
+	if err != nil {
+		return err
+	}
+
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+	if header.Type != hyperbus.MsgConfigAck {
+		return fmt.Errorf("unexpected message type %d in reply to config update", header.Type)
+	}
+
+	var ack proto.ConfigAck
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &ack); err != nil {
+		return err
+	}
+	if ack.Error != "" {
+		return fmt.Errorf("%s rejected the update: %s", ack.NodeId, ack.Error)
+	}
+	return nil
+}
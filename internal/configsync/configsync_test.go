@@ -0,0 +1,115 @@
+package configsync
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/config"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// newKey generates an ed25519 signing key
+func newKey(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	return key
+}
+
+func TestServer_ExportImportRoundTrip(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The member's file holds its own settings
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := config.DefaultConfig()
+	file.Node.ID = "server"
+	file.Network.ListenAddr = "0.0.0.0:9443"
+	assert.NoError(t, file.SaveConfig(path))
+
+	operator := newKey(t)
+	server := NewServer("server", path, file.Cluster(), hyperbus.NewTrustedKeys(operator.Public().(ed25519.PublicKey)), logger)
+	var applied *config.ClusterConfig
+	server.SetOnApply(func(cc *config.ClusterConfig) { applied = cc })
+	mux := hyperbus.NewMux()
+	mux.Handle(hyperbus.MsgConfigQuery, server)
+	mux.Handle(hyperbus.MsgConfigUpdate, server)
+	client := hyperbus.New(hyperbus.NodeInfo{ID: "client"}, hyperbus.NewMux(), logger)
+	hyperbus.ConnectMemory(client, hyperbus.New(hyperbus.NodeInfo{ID: "server"}, mux, logger))
+
+	// Export what the member runs with, change it and import it back
+	doc, err := Query(ctx, client, "server")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), doc.Version)
+	exported, err := config.Import(doc.Config)
+	assert.NoError(t, err)
+	assert.Equal(t, file.Cluster(), exported)
+
+	exported.Storage.CacheSize = 4096
+	exported.Timeouts.TaskSubmit = time.Minute
+	update, err := Sign(exported, 1, operator)
+	assert.NoError(t, err)
+	assert.NoError(t, Push(ctx, client, "server", update))
+	assert.Equal(t, exported, applied)
+
+	doc, err = Query(ctx, client, "server")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), doc.Version)
+	current, err := config.Import(doc.Config)
+	assert.NoError(t, err)
+	assert.Equal(t, exported, current)
+
+	// The file took the update and kept the member's own settings
+	saved, err := config.LoadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "server", saved.Node.ID)
+	assert.Equal(t, "0.0.0.0:9443", saved.Network.ListenAddr)
+	assert.Equal(t, 4096, saved.Storage.CacheSize)
+	assert.Equal(t, time.Minute, saved.Timeouts.TaskSubmit)
+
+	// Pushing the same update again is harmless
+	assert.NoError(t, Push(ctx, client, "server", update))
+}
+
+func TestServer_RejectsBadUpdates(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	operator := newKey(t)
+	server := NewServer("server", path, config.DefaultConfig().Cluster(), hyperbus.NewTrustedKeys(operator.Public().(ed25519.PublicKey)), logger)
+
+	valid := config.DefaultConfig().Cluster()
+	update, err := Sign(valid, 2, operator)
+	assert.NoError(t, err)
+	assert.NoError(t, server.Apply(update))
+
+	// An invalid configuration is rejected even when properly signed
+	invalid := config.DefaultConfig().Cluster()
+	invalid.Storage.CacheSize = -1
+	update, err = Sign(invalid, 3, operator)
+	assert.NoError(t, err)
+	assert.ErrorContains(t, server.Apply(update), "invalid config")
+
+	// So are untrusted signers, tampered documents and older versions
+	update, err = Sign(valid, 3, newKey(t))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, server.Apply(update), hyperbus.ErrUntrustedKey)
+
+	update, err = Sign(valid, 3, operator)
+	assert.NoError(t, err)
+	update.Config = append(update.Config, '\n')
+	assert.ErrorIs(t, server.Apply(update), ErrBadSignature)
+
+	update, err = Sign(valid, 1, operator)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, server.Apply(update), ErrStaleUpdate)
+
+	_, version := server.Current()
+	assert.Equal(t, int64(2), version)
+}
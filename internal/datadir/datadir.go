@@ -0,0 +1,180 @@
+// Package datadir manages the on-disk layout of a node's data directory
+package datadir
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Subdirectories of the data directory
+const (
+	CertsDir   = "certs"
+	SpillDir   = "spill"
+	MetaDir    = "meta"
+	ModulesDir = "modules"
+	WALDir     = "wal"
+)
+
+// Version is the current layout version
+const Version = 1
+
+// versionFile is the layout version marker, relative to the data directory
+var versionFile = filepath.Join(MetaDir, "layout_version")
+
+// dirPerm is the permission used for the data directory and its subdirectories
+const dirPerm = 0700
+
+// ErrUnsupportedVersion is returned for a layout newer than this build understands
+var ErrUnsupportedVersion = errors.New("unsupported data directory layout version")
+
+// migration upgrades a data directory from one layout version to the next
+type migration func(root string) error
+
+// migrations maps a layout version to the migration that upgrades it
+var migrations = map[int]migration{
+	0: migrateV0,
+}
+
+// Layout describes the data directory of a node
+type Layout struct {
+	Root string
+}
+
+// Certs returns the directory holding TLS certificates and keys
+func (l Layout) Certs() string {
+	return filepath.Join(l.Root, CertsDir)
+}
+
+// Spill returns the directory holding pages spilled to disk
+func (l Layout) Spill() string {
+	return filepath.Join(l.Root, SpillDir)
+}
+
+// Meta returns the directory holding node metadata
+func (l Layout) Meta() string {
+	return filepath.Join(l.Root, MetaDir)
+}
+
+// Modules returns the directory holding cached WASM modules
+func (l Layout) Modules() string {
+	return filepath.Join(l.Root, ModulesDir)
+}
+
+// WAL returns the directory holding the page write-ahead log
+func (l Layout) WAL() string {
+	return filepath.Join(l.Root, WALDir)
+}
+
+// Open creates or validates the data directory at root, migrating an older
+// layout to the current version
+func Open(root string) (Layout, error) {
+	layout := Layout{Root: root}
+
+	if err := os.MkdirAll(root, dirPerm); err != nil {
+		return layout, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := checkDir(root); err != nil {
+		return layout, err
+	}
+
+	version, err := readVersion(root)
+	if err != nil {
+		return layout, err
+	}
+	if version > Version {
+		return layout, fmt.Errorf("%w: %d (supported up to %d)", ErrUnsupportedVersion, version, Version)
+	}
+
+	for ; version < Version; version++ {
+		if err := migrations[version](root); err != nil {
+			return layout, fmt.Errorf("failed to migrate data directory from version %d: %w", version, err)
+		}
+		if err := writeVersion(root, version+1); err != nil {
+			return layout, err
+		}
+	}
+
+	for _, dir := range []string{layout.Certs(), layout.Spill(), layout.Meta(), layout.Modules()} {
+		if err := os.MkdirAll(dir, dirPerm); err != nil {
+			return layout, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := checkDir(dir); err != nil {
+			return layout, err
+		}
+	}
+
+	return layout, nil
+}
+
+// checkDir rejects a directory that other users could tamper with
+func checkDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if err := checkOwner(info); err != nil {
+		return fmt.Errorf("insecure data directory %s: %w", dir, err)
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("insecure data directory %s: writable by group or others (mode %v)", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// readVersion returns the layout version, or 0 for a directory without a marker
+func readVersion(root string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(root, versionFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read layout version: %w", err)
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid layout version: %q", data)
+	}
+	return version, nil
+}
+
+// writeVersion records the layout version
+func writeVersion(root string, version int) error {
+	if err := os.MkdirAll(filepath.Join(root, MetaDir), dirPerm); err != nil {
+		return fmt.Errorf("failed to create meta directory: %w", err)
+	}
+	data := []byte(strconv.Itoa(version) + "\n")
+	if err := os.WriteFile(filepath.Join(root, versionFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to write layout version: %w", err)
+	}
+	return nil
+}
+
+// migrateV0 moves certificates kept at the top of an unversioned data
+// directory into certs/
+func migrateV0(root string) error {
+	if err := os.MkdirAll(filepath.Join(root, CertsDir), dirPerm); err != nil {
+		return err
+	}
+	for _, name := range []string{"cert.pem", "key.pem", "trusted_keys.pem"} {
+		oldPath := filepath.Join(root, name)
+		newPath := filepath.Join(root, CertsDir, name)
+		if _, err := os.Stat(oldPath); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := os.Stat(newPath); err == nil {
+			return fmt.Errorf("both %s and %s exist", oldPath, newPath)
+		}
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
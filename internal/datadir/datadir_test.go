@@ -0,0 +1,142 @@
+package datadir
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen_CreatesLayout(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "data")
+
+	layout, err := Open(root)
+	assert.NoError(t, err)
+
+	for _, dir := range []string{layout.Certs(), layout.Spill(), layout.Meta(), layout.Modules()} {
+		info, err := os.Stat(dir)
+		assert.NoError(t, err)
+		assert.True(t, info.IsDir())
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	}
+
+	version, err := readVersion(root)
+	assert.NoError(t, err)
+	assert.Equal(t, Version, version)
+
+	// Opening again is a no-op
+	_, err = Open(root)
+	assert.NoError(t, err)
+}
+
+func TestOpen_MigratesUnversioned(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.Chmod(root, 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "cert.pem"), []byte("cert"), 0600))
+
+	layout, err := Open(root)
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(layout.Certs(), "cert.pem"))
+	assert.NoError(t, err)
+	assert.Equal(t, "cert", string(data))
+
+	_, err = os.Stat(filepath.Join(root, "cert.pem"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestOpen_RejectsNewerVersion(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.Chmod(root, 0700))
+	assert.NoError(t, writeVersion(root, Version+1))
+
+	_, err := Open(root)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestOpen_RejectsInsecureDirectory(t *testing.T) {
+	// A world-writable directory is rejected
+	root := t.TempDir()
+	assert.NoError(t, os.Chmod(root, 0777))
+
+	_, err := Open(root)
+	assert.ErrorContains(t, err, "insecure data directory")
+
+	// A directory owned by another user with lax permissions is rejected
+	if os.Getuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+	other := t.TempDir()
+	assert.NoError(t, os.Chown(other, 65534, 65534))
+	assert.NoError(t, os.Chmod(other, 0755))
+
+	_, err = Open(other)
+	assert.ErrorContains(t, err, "owned by uid 65534")
+}
+
+func TestLoadOrCreateIdentity_StableAcrossRestarts(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "data")
+
+	// First startup generates and persists a key
+	layout, err := Open(root)
+	assert.NoError(t, err)
+	first, err := layout.LoadOrCreateIdentity()
+	assert.NoError(t, err)
+
+	info, err := os.Stat(layout.IdentityKey())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// A restart with the same data directory loads it back
+	layout, err = Open(root)
+	assert.NoError(t, err)
+	second, err := layout.LoadOrCreateIdentity()
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// A different data directory gets a different identity
+	other, err := Open(filepath.Join(t.TempDir(), "data"))
+	assert.NoError(t, err)
+	third, err := other.LoadOrCreateIdentity()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, third)
+}
+
+func TestLoadOrCreateIdentity_RejectsCorruptKey(t *testing.T) {
+	layout, err := Open(filepath.Join(t.TempDir(), "data"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(layout.IdentityKey(), []byte("not a key"), 0600))
+
+	_, err = layout.LoadOrCreateIdentity()
+	assert.ErrorContains(t, err, "invalid identity key")
+}
+
+func TestLoadOrCreateIdentity_ConcurrentStartsShareOneKey(t *testing.T) {
+	layout, err := Open(filepath.Join(t.TempDir(), "data"))
+	assert.NoError(t, err)
+
+	keys := make([]ed25519.PrivateKey, 8)
+	var wg sync.WaitGroup
+	for i := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := layout.LoadOrCreateIdentity()
+			assert.NoError(t, err)
+			keys[i] = key
+		}()
+	}
+	wg.Wait()
+
+	for _, key := range keys[1:] {
+		assert.Equal(t, keys[0], key)
+	}
+
+	// Only the key itself is left in the certs directory
+	entries, err := os.ReadDir(layout.Certs())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,95 @@
+package datadir
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// identityKeyFile holds the node's ed25519 identity key, relative to the certs directory
+const identityKeyFile = "identity_key.pem"
+
+// IdentityKey returns the path of the node's identity key
+func (l Layout) IdentityKey() string {
+	return filepath.Join(l.Certs(), identityKeyFile)
+}
+
+// LoadOrCreateIdentity loads the node's ed25519 identity key, generating and
+// persisting one on first run so the node keeps its identity across restarts
+func (l Layout) LoadOrCreateIdentity() (ed25519.PrivateKey, error) {
+	path := l.IdentityKey()
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return parseIdentity(path, data)
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read identity key: %w", err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate identity key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode identity key: %w", err)
+	}
+
+	// The key is written in full to a temporary file and then linked into
+	// place, so a crash can't leave a truncated key behind and an agent
+	// starting at the same time never reads a half-written one. Link fails
+	// if the key exists, so of two agents starting at once only one wins.
+	if err := writeKeyFile(path, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return l.LoadOrCreateIdentity()
+		}
+		return nil, fmt.Errorf("failed to write identity key: %w", err)
+	}
+	return key, nil
+}
+
+// writeKeyFile atomically creates path holding block, failing with
+// os.ErrExist if it already exists
+func writeKeyFile(path string, block *pem.Block) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := pem.Encode(tmp, block); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Link(tmp.Name(), path)
+}
+
+// parseIdentity decodes a PEM-encoded PKCS#8 ed25519 private key
+func parseIdentity(path string, data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("invalid identity key %s: no PRIVATE KEY block", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity key %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid identity key %s: %T is not an ed25519 key", path, parsed)
+	}
+	return key, nil
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package datadir
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkOwner rejects a directory owned by another user
+func checkOwner(info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if uid := os.Getuid(); int(stat.Uid) != uid {
+		return fmt.Errorf("owned by uid %d, not %d", stat.Uid, uid)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+// Package deadline applies default timeouts to operations whose callers set none
+package deadline
+
+import (
+	"context"
+	"time"
+)
+
+// WithDefault bounds ctx by timeout unless ctx already has a deadline, which
+// then takes precedence. A non-positive timeout leaves ctx unbounded.
+func WithDefault(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
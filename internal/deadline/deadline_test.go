@@ -0,0 +1,33 @@
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDefault(t *testing.T) {
+	// A context without a deadline gets the default
+	ctx, cancel := WithDefault(context.Background(), time.Minute)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// The caller's deadline wins, even when it is later
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelParent()
+	ctx, cancel = WithDefault(parent, time.Minute)
+	defer cancel()
+	deadline, _ = ctx.Deadline()
+	want, _ := parent.Deadline()
+	assert.Equal(t, want, deadline)
+
+	// A zero default leaves the context unbounded
+	ctx, cancel = WithDefault(context.Background(), 0)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
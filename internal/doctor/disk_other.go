@@ -0,0 +1,10 @@
+//go:build !unix
+
+package doctor
+
+import "errors"
+
+// freeBytes is not supported on this platform
+func freeBytes(path string) (uint64, error) {
+	return 0, errors.New("free space query not supported on this platform")
+}
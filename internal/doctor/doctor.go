@@ -0,0 +1,330 @@
+// Package doctor implements node self-diagnostics
+package doctor
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/mlkem"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/config"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/quic-go/quic-go"
+)
+
+// Status represents the outcome of a check
+type Status int
+
+const (
+	// Pass means the check succeeded
+	Pass Status = iota
+	// Fail means the check found a problem
+	Fail
+	// Skip means the check could not be performed
+	Skip
+)
+
+// String returns the report label for the status
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "PASS"
+	case Fail:
+		return "FAIL"
+	case Skip:
+		return "SKIP"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Name    string
+	Status  Status
+	Message string
+}
+
+// Check is an independent diagnostic returning a result
+type Check func(ctx context.Context) Result
+
+// MinFreeBytes is the default free space required in the data directory
+const MinFreeBytes = 1024 * 1024 * 1024 // 1 GiB
+
+// MaxClockSkew is the default tolerated clock skew against peers
+const MaxClockSkew = 500 * time.Millisecond
+
+// Run executes the checks in order and returns their results
+func Run(ctx context.Context, checks []Check) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, check := range checks {
+		results = append(results, check(ctx))
+	}
+	return results
+}
+
+// Failed returns the number of failed results
+func Failed(results []Result) int {
+	failed := 0
+	for _, r := range results {
+		if r.Status == Fail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// CheckConfig loads the configuration file and validates it
+func CheckConfig(filename string) (*config.Config, Result) {
+	result := Result{Name: "config"}
+
+	cfg, err := config.LoadConfig(filename)
+	if err != nil {
+		result.Status = Fail
+		result.Message = fmt.Sprintf("failed to load %s: %v", filename, err)
+		return nil, result
+	}
+
+	result.Status = Pass
+	result.Message = fmt.Sprintf("loaded configuration for node %s", cfg.Node.ID)
+	return cfg, result
+}
+
+// CheckCerts verifies that the configured certificate and key can be loaded
+func CheckCerts(certFile, keyFile string) Result {
+	result := Result{Name: "certs"}
+
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+	if os.IsNotExist(certErr) && os.IsNotExist(keyErr) {
+		result.Status = Skip
+		result.Message = "no certificate configured, a self-signed one will be generated"
+		return result
+	}
+
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		result.Status = Fail
+		result.Message = fmt.Sprintf("failed to load certificate: %v", err)
+		return result
+	}
+
+	result.Status = Pass
+	result.Message = fmt.Sprintf("loaded certificate %s", certFile)
+	return result
+}
+
+// CheckListenAddr verifies that the listen address can be bound
+func CheckListenAddr(addr string) Result {
+	result := Result{Name: "listen"}
+
+	// QUIC runs over UDP
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		result.Status = Fail
+		result.Message = fmt.Sprintf("cannot bind %s: %v", addr, err)
+		return result
+	}
+	conn.Close()
+
+	result.Status = Pass
+	result.Message = fmt.Sprintf("%s is bindable", addr)
+	return result
+}
+
+// CheckDiskSpace verifies that the data directory has at least minFree bytes available
+func CheckDiskSpace(dir string, minFree uint64) Result {
+	result := Result{Name: "disk"}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		result.Status = Fail
+		result.Message = fmt.Sprintf("data directory unavailable: %v", err)
+		return result
+	}
+	if !info.IsDir() {
+		result.Status = Fail
+		result.Message = fmt.Sprintf("data directory %s is not a directory", dir)
+		return result
+	}
+
+	free, err := freeBytes(dir)
+	if err != nil {
+		result.Status = Skip
+		result.Message = fmt.Sprintf("cannot determine free space: %v", err)
+		return result
+	}
+
+	if free < minFree {
+		result.Status = Fail
+		result.Message = fmt.Sprintf("only %d MB free in %s, need %d MB", free>>20, dir, minFree>>20)
+		return result
+	}
+
+	result.Status = Pass
+	result.Message = fmt.Sprintf("%d MB free in %s", free>>20, dir)
+	return result
+}
+
+// CheckClockSkew verifies that no peer's measured skew exceeds maxSkew
+func CheckClockSkew(skews map[string]time.Duration, maxSkew time.Duration) Result {
+	result := Result{Name: "clock"}
+
+	if len(skews) == 0 {
+		result.Status = Skip
+		result.Message = "no peer clock samples available"
+		return result
+	}
+
+	for peer, skew := range skews {
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > maxSkew {
+			result.Status = Fail
+			result.Message = fmt.Sprintf("clock skew against %s is %v, exceeds %v", peer, skew, maxSkew)
+			return result
+		}
+	}
+
+	result.Status = Pass
+	result.Message = fmt.Sprintf("clock skew within %v for %d peers", maxSkew, len(skews))
+	return result
+}
+
+// SampleClockSkew estimates the clock skew of each bootstrap node from the
+// send time in the hello it answers a probe hello with. Nodes that can't be
+// reached or refuse the probe, e.g. because they pin peer keys, are left out.
+func SampleClockSkew(ctx context.Context, addrs []string, timeout time.Duration) map[string]time.Duration {
+	skews := make(map[string]time.Duration)
+	for _, addr := range addrs {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		skew, err := probeClock(probeCtx, addr)
+		cancel()
+		if err == nil {
+			skews[addr] = skew
+		}
+	}
+	return skews
+}
+
+// probeNodeID is the node ID clock probes introduce themselves with
+const probeNodeID = "doctor-probe"
+
+// probeClock sends a hello to addr and compares the send time of its reply
+// with the midpoint of the exchange, which cancels out symmetric latency
+func probeClock(ctx context.Context, addr string) (time.Duration, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"holocompute"},
+	}
+	conn, err := quic.DialAddr(ctx, addr, tlsConfig, &quic.Config{})
+	if err != nil {
+		return 0, err
+	}
+	defer conn.CloseWithError(0, "doctor probe")
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+
+	// Agents with PQ enabled only answer hellos offering a signed PQ key,
+	// so the probe offers throwaway ones
+	identityKey, identity, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return 0, err
+	}
+	pqKey, err := mlkem.GenerateKey768()
+	if err != nil {
+		return 0, err
+	}
+	pqPubkey := pqKey.EncapsulationKey().Bytes()
+
+	sent := time.Now()
+	hello, err := hyperbus.EncodeMessage(hyperbus.MsgControlHello, &proto.ControlHello{
+		NodeId:             probeNodeID,
+		SentAtUnixNano:     sent.UnixNano(),
+		ProtocolVersion:    hyperbus.ProtocolVersion,
+		MinProtocolVersion: hyperbus.MinProtocolVersion,
+		Pubkey:             identityKey,
+		PqPubkey:           pqPubkey,
+		PqPubkeySig:        hyperbus.SignPQKey(identity, probeNodeID, pqPubkey),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := stream.Write(append([]byte{byte(hyperbus.ControlStream)}, hello...)); err != nil {
+		return 0, err
+	}
+	stream.Close()
+
+	headerBuf := make([]byte, hyperbus.HeaderSize)
+	if _, err := io.ReadFull(stream, headerBuf); err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	header, err := hyperbus.DecodeHeader(headerBuf)
+	if err != nil {
+		return 0, err
+	}
+	if header.Type != hyperbus.MsgControlHello {
+		return 0, fmt.Errorf("unexpected message type %d in reply to hello", header.Type)
+	}
+	body := make([]byte, header.Size)
+	if _, err := io.ReadFull(stream, body); err != nil {
+		return 0, err
+	}
+
+	var reply proto.ControlHello
+	if err := hyperbus.DecodeMessage(body, &reply); err != nil {
+		return 0, err
+	}
+	if reply.SentAtUnixNano == 0 {
+		return 0, fmt.Errorf("%s sent no clock reading", addr)
+	}
+	midpoint := sent.Add(received.Sub(sent) / 2)
+	return time.Unix(0, reply.SentAtUnixNano).Sub(midpoint), nil
+}
+
+// CheckBootstrap verifies that every bootstrap node accepts a QUIC handshake
+func CheckBootstrap(ctx context.Context, addrs []string, timeout time.Duration) Result {
+	result := Result{Name: "bootstrap"}
+
+	if len(addrs) == 0 {
+		result.Status = Skip
+		result.Message = "no bootstrap nodes configured"
+		return result
+	}
+
+	// Only reachability is probed here, peer identity is verified by the agent
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"holocompute"},
+	}
+
+	for _, addr := range addrs {
+		dialCtx, cancel := context.WithTimeout(ctx, timeout)
+		conn, err := quic.DialAddr(dialCtx, addr, tlsConfig, &quic.Config{})
+		cancel()
+		if err != nil {
+			result.Status = Fail
+			result.Message = fmt.Sprintf("cannot reach %s: %v", addr, err)
+			return result
+		}
+		conn.CloseWithError(0, "doctor probe")
+	}
+
+	result.Status = Pass
+	result.Message = fmt.Sprintf("reached %d bootstrap nodes", len(addrs))
+	return result
+}
@@ -0,0 +1,135 @@
+package doctor
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/mlkem"
+	"crypto/rand"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/config"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConfig(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Save a valid config
+	cfg := config.DefaultConfig()
+	cfg.Node.ID = "test-node"
+	validFile := filepath.Join(tempDir, "valid.yaml")
+	assert.NoError(t, cfg.SaveConfig(validFile))
+
+	loaded, result := CheckConfig(validFile)
+	assert.Equal(t, Pass, result.Status)
+	assert.Equal(t, "test-node", loaded.Node.ID)
+
+	// Malformed YAML should fail to load
+	badFile := filepath.Join(tempDir, "bad.yaml")
+	assert.NoError(t, os.WriteFile(badFile, []byte("node: [unterminated"), 0644))
+
+	_, result = CheckConfig(badFile)
+	assert.Equal(t, Fail, result.Status)
+
+	// An empty node ID should fail validation
+	cfg.Node.ID = ""
+	emptyIDFile := filepath.Join(tempDir, "empty-id.yaml")
+	assert.NoError(t, cfg.SaveConfig(emptyIDFile))
+
+	_, result = CheckConfig(emptyIDFile)
+	assert.Equal(t, Fail, result.Status)
+	assert.Contains(t, result.Message, "node.id")
+
+	// A listen address without a port should fail validation
+	cfg.Node.ID = "test-node"
+	cfg.Network.ListenAddr = "0.0.0.0"
+	noPortFile := filepath.Join(tempDir, "no-port.yaml")
+	assert.NoError(t, cfg.SaveConfig(noPortFile))
+
+	_, result = CheckConfig(noPortFile)
+	assert.Equal(t, Fail, result.Status)
+	assert.Contains(t, result.Message, "listen_addr")
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Any filesystem has at least one free byte
+	result := CheckDiskSpace(tempDir, 1)
+	assert.Equal(t, Pass, result.Status)
+
+	// No filesystem has this much free space
+	result = CheckDiskSpace(tempDir, math.MaxUint64)
+	assert.Equal(t, Fail, result.Status)
+
+	// A missing directory fails
+	result = CheckDiskSpace(filepath.Join(tempDir, "missing"), 1)
+	assert.Equal(t, Fail, result.Status)
+
+	// A regular file is not a data directory
+	file := filepath.Join(tempDir, "file")
+	assert.NoError(t, os.WriteFile(file, nil, 0644))
+	result = CheckDiskSpace(file, 1)
+	assert.Equal(t, Fail, result.Status)
+}
+
+func TestFailed(t *testing.T) {
+	results := []Result{
+		{Name: "a", Status: Pass},
+		{Name: "b", Status: Fail},
+		{Name: "c", Status: Skip},
+		{Name: "d", Status: Fail},
+	}
+	assert.Equal(t, 2, Failed(results))
+}
+
+func TestSampleClockSkew(t *testing.T) {
+	bus, err := hyperbus.NewQUICBus(
+		hyperbus.NodeInfo{ID: "peer", Address: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}},
+		nil,
+		log.New(slog.LevelError),
+	)
+	assert.NoError(t, err)
+	defer bus.Close()
+
+	// A local peer shares our clock, so only latency shows up as skew
+	addr := bus.LocalNode().Address.String()
+	skews := SampleClockSkew(context.Background(), []string{addr, "127.0.0.1:1"}, time.Second)
+	assert.Len(t, skews, 1)
+	assert.Less(t, skews[addr].Abs(), 100*time.Millisecond)
+
+	result := CheckClockSkew(skews, MaxClockSkew)
+	assert.Equal(t, Pass, result.Status)
+}
+
+func TestSampleClockSkew_AgentPeer(t *testing.T) {
+	// Peers set up like an agent: a persistent identity, PQ and a placement hash
+	_, identity, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	pqKey, err := mlkem.GenerateKey768()
+	assert.NoError(t, err)
+
+	bus, err := hyperbus.NewQUICBus(
+		hyperbus.NodeInfo{ID: "peer", Address: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}},
+		nil,
+		log.New(slog.LevelError),
+		hyperbus.WithIdentity(identity),
+	)
+	assert.NoError(t, err)
+	defer bus.Close()
+	bus.SetPQKey(pqKey, identity)
+	bus.SetPlacementHash("xxhash64")
+
+	addr := bus.LocalNode().Address.String()
+	skews := SampleClockSkew(context.Background(), []string{addr}, time.Second)
+	assert.Len(t, skews, 1)
+	assert.Less(t, skews[addr].Abs(), 100*time.Millisecond)
+}
@@ -0,0 +1,189 @@
+package dsm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// ArrayLeases tracks time-bounded holds on whole arrays across the cluster.
+// Holds are gossiped so every node knows whether any member still uses an
+// array; an array is only collected once all holds on it have lapsed.
+type ArrayLeases struct {
+	holders map[ArrayID]map[string]time.Time // holder -> expiry
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// NewArrayLeases creates an empty array lease table
+func NewArrayLeases() *ArrayLeases {
+	return &ArrayLeases{
+		holders: make(map[ArrayID]map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Hold records that holder uses the array for the next ttl, returning the expiry.
+// Holders renew by calling Hold again before the lease lapses.
+func (al *ArrayLeases) Hold(arrayID ArrayID, holder string, ttl time.Duration) time.Time {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	expiresAt := al.now().Add(ttl)
+	al.extendLocked(arrayID, holder, expiresAt)
+	return expiresAt
+}
+
+// Merge applies leases gossiped by other nodes, keeping the latest expiry per holder
+func (al *ArrayLeases) Merge(leases []*proto.ArrayLease) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	for _, lease := range leases {
+		al.extendLocked(ArrayID(lease.ArrayId), lease.Holder, time.Unix(0, lease.ExpiresAtUnixNano))
+	}
+}
+
+// extendLocked sets a holder's expiry unless it already expires later.
+// The caller must hold al.mu.
+func (al *ArrayLeases) extendLocked(arrayID ArrayID, holder string, expiresAt time.Time) {
+	holders, exists := al.holders[arrayID]
+	if !exists {
+		holders = make(map[string]time.Time)
+		al.holders[arrayID] = holders
+	}
+	if current, exists := holders[holder]; !exists || expiresAt.After(current) {
+		holders[holder] = expiresAt
+	}
+}
+
+// Snapshot returns the unexpired leases for gossip
+func (al *ArrayLeases) Snapshot() []*proto.ArrayLease {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	now := al.now()
+	var leases []*proto.ArrayLease
+	for arrayID, holders := range al.holders {
+		for holder, expiresAt := range holders {
+			if !now.Before(expiresAt) {
+				continue
+			}
+			leases = append(leases, &proto.ArrayLease{
+				ArrayId:           string(arrayID),
+				Holder:            holder,
+				ExpiresAtUnixNano: expiresAt.UnixNano(),
+			})
+		}
+	}
+	return leases
+}
+
+// Held returns true if any holder's lease on the array hasn't lapsed
+func (al *ArrayLeases) Held(arrayID ArrayID) bool {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	now := al.now()
+	for _, expiresAt := range al.holders[arrayID] {
+		if now.Before(expiresAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired returns the arrays that have been leased and whose leases have all lapsed
+func (al *ArrayLeases) Expired() []ArrayID {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	now := al.now()
+	var expired []ArrayID
+	for arrayID, holders := range al.holders {
+		held := false
+		for _, expiresAt := range holders {
+			if now.Before(expiresAt) {
+				held = true
+				break
+			}
+		}
+		if !held {
+			expired = append(expired, arrayID)
+		}
+	}
+	return expired
+}
+
+// forgetIfLapsed drops an array's leases if all of them have lapsed, returning true if it did
+func (al *ArrayLeases) forgetIfLapsed(arrayID ArrayID) bool {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	now := al.now()
+	for _, expiresAt := range al.holders[arrayID] {
+		if now.Before(expiresAt) {
+			return false
+		}
+	}
+	delete(al.holders, arrayID)
+	return true
+}
+
+// ContributeState adds the unexpired array leases to outgoing gossip
+func (al *ArrayLeases) ContributeState(state *proto.ClusterState) {
+	state.ArrayLeases = append(state.ArrayLeases, al.Snapshot()...)
+}
+
+// ObserveState merges array leases from incoming gossip
+func (al *ArrayLeases) ObserveState(state *proto.ClusterState) {
+	al.Merge(state.ArrayLeases)
+}
+
+// ArrayLeases returns the cluster-wide array lease table
+func (mm *MemoryManager) ArrayLeases() *ArrayLeases {
+	return mm.arrayLeases
+}
+
+// CollectArrays deletes leased arrays once every holder's lease has lapsed,
+// returning the collected array IDs. Arrays that were never leased are kept.
+func (mm *MemoryManager) CollectArrays(ctx context.Context) []ArrayID {
+	var collected []ArrayID
+	for _, arrayID := range mm.arrayLeases.Expired() {
+		// A holder may have renewed since the scan
+		if !mm.arrayLeases.forgetIfLapsed(arrayID) {
+			continue
+		}
+
+		if err := mm.DeleteArray(ctx, arrayID); err != nil {
+			mm.logger.Debug("skipping collection of unknown array", "array_id", arrayID, "error", err)
+			continue
+		}
+		collected = append(collected, arrayID)
+	}
+	return collected
+}
+
+// RunCollector collects arrays whose leases have all lapsed every interval
+// until ctx is done
+func (mm *MemoryManager) RunCollector(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if collected := mm.CollectArrays(ctx); len(collected) > 0 {
+				mm.logger.Info("collected unleased arrays", "arrays", len(collected))
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package dsm
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestMemoryManager_RemoteArrayLeaseBlocksCollection(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+
+	local := NewMemoryManager(&hyperbus.Bus{}, logger)
+	local.ArrayLeases().now = clock.Now
+
+	remote := NewArrayLeases()
+	remote.now = clock.Now
+
+	array, err := local.CreateArray(context.TODO(), 1000)
+	assert.NoError(t, err)
+
+	// The local client holds the array briefly, the remote one for longer
+	local.ArrayLeases().Hold(array.ID, "local-client", 10*time.Second)
+	remote.Hold(array.ID, "remote-client", time.Minute)
+
+	// The remote lease arrives through gossip
+	state := &proto.ClusterState{}
+	remote.ContributeState(state)
+	local.ArrayLeases().ObserveState(state)
+
+	// The local lease lapses but the remote one still holds the array
+	clock.now = clock.now.Add(30 * time.Second)
+	assert.Empty(t, local.CollectArrays(context.TODO()))
+	_, err = local.GetArray(context.TODO(), array.ID)
+	assert.NoError(t, err)
+
+	// Once the remote lease lapses the array is collected
+	clock.now = clock.now.Add(time.Minute)
+	assert.Equal(t, []ArrayID{array.ID}, local.CollectArrays(context.TODO()))
+	_, err = local.GetArray(context.TODO(), array.ID)
+	assert.Error(t, err)
+}
+
+func TestArrayLeases_Merge(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	al := NewArrayLeases()
+	al.now = clock.Now
+
+	expiresAt := al.Hold("array-1", "client-1", time.Minute)
+
+	// Stale gossip doesn't shorten a lease
+	al.Merge([]*proto.ArrayLease{{
+		ArrayId:           "array-1",
+		Holder:            "client-1",
+		ExpiresAtUnixNano: clock.now.Add(time.Second).UnixNano(),
+	}})
+	snapshot := al.Snapshot()
+	assert.Len(t, snapshot, 1)
+	assert.Equal(t, expiresAt.UnixNano(), snapshot[0].ExpiresAtUnixNano)
+
+	// Lapsed leases are not gossiped
+	clock.now = clock.now.Add(2 * time.Minute)
+	assert.Empty(t, al.Snapshot())
+	assert.False(t, al.Held("array-1"))
+
+	// The lapsed array is reported for collection
+	assert.Equal(t, []ArrayID{"array-1"}, al.Expired())
+}
+
+func TestArrayNames_Register(t *testing.T) {
+	an := NewArrayNames()
+
+	assert.NoError(t, an.Register("weights", "array-1"))
+	assert.NoError(t, an.Register("weights", "array-1"))
+	var taken *ErrNameTaken
+	assert.ErrorAs(t, an.Register("weights", "array-2"), &taken)
+	assert.Equal(t, ArrayID("array-1"), taken.ArrayID)
+	assert.Error(t, an.Register("", "array-2"))
+
+	id, ok := an.Lookup("weights")
+	assert.True(t, ok)
+	assert.Equal(t, ArrayID("array-1"), id)
+	_, ok = an.Lookup("biases")
+	assert.False(t, ok)
+}
+
+func TestArrayNames_MergeKeepsEarliest(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	a, b := NewArrayNames(), NewArrayNames()
+	a.now, b.now = clock.Now, clock.Now
+
+	// Both nodes bind the same name concurrently, a slightly earlier
+	assert.NoError(t, a.Register("weights", "array-a"))
+	clock.now = clock.now.Add(time.Second)
+	assert.NoError(t, b.Register("weights", "array-b"))
+
+	// After exchanging gossip both agree on the earlier binding
+	aState, bState := &proto.ClusterState{}, &proto.ClusterState{}
+	a.ContributeState(aState)
+	b.ContributeState(bState)
+	a.ObserveState(bState)
+	b.ObserveState(aState)
+
+	for _, names := range []*ArrayNames{a, b} {
+		id, ok := names.Lookup("weights")
+		assert.True(t, ok)
+		assert.Equal(t, ArrayID("array-a"), id)
+	}
+}
+
+func TestMemoryManager_DeletesArrayLosingItsName(t *testing.T) {
+	logger := log.New(slog.LevelError)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	a := NewMemoryManager(&hyperbus.Bus{}, logger)
+	b := NewMemoryManager(&hyperbus.Bus{}, logger)
+	a.ArrayNames().now, b.ArrayNames().now = clock.Now, clock.Now
+
+	// Both nodes create an array under the same name, a slightly earlier
+	aArray, err := a.CreateArray(context.TODO(), 1000)
+	assert.NoError(t, err)
+	assert.NoError(t, a.ArrayNames().Register("weights", aArray.ID))
+	clock.now = clock.now.Add(time.Second)
+	bArray, err := b.CreateArray(context.TODO(), 1000)
+	assert.NoError(t, err)
+	assert.NoError(t, b.ArrayNames().Register("weights", bArray.ID))
+
+	aState, bState := &proto.ClusterState{}, &proto.ClusterState{}
+	a.ArrayNames().ContributeState(aState)
+	b.ArrayNames().ContributeState(bState)
+	a.ArrayNames().ObserveState(bState)
+	b.ArrayNames().ObserveState(aState)
+
+	// The winner keeps its array, the loser's is deleted
+	_, err = a.GetArray(context.TODO(), aArray.ID)
+	assert.NoError(t, err)
+	_, err = b.GetArray(context.TODO(), bArray.ID)
+	assert.Error(t, err)
+
+	id, ok := b.ArrayNames().Lookup("weights")
+	assert.True(t, ok)
+	assert.Equal(t, aArray.ID, id)
+}
@@ -0,0 +1,172 @@
+package dsm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// ErrNameTaken is returned when registering a name already bound to another array
+type ErrNameTaken struct {
+	Name    string
+	ArrayID ArrayID
+}
+
+// Error implements the error interface
+func (e *ErrNameTaken) Error() string {
+	return fmt.Sprintf("array name %q is taken by array %s", e.Name, e.ArrayID)
+}
+
+// arrayName is a name's binding and when it was made
+type arrayName struct {
+	arrayID      ArrayID
+	registeredAt time.Time
+	local        bool // registered on this node rather than learned by gossip
+}
+
+// ArrayNames maps human-readable names to arrays. Bindings are gossiped so
+// every node resolves a name to the same array; when two nodes bind a name
+// concurrently, the earlier registration wins everywhere.
+type ArrayNames struct {
+	names map[string]arrayName
+	now   func() time.Time
+	mu    sync.Mutex
+
+	// onDisplaced is called with each array registered here whose only
+	// name went to an earlier registration from another node
+	onDisplaced func(arrayID ArrayID)
+}
+
+// NewArrayNames creates an empty name registry
+func NewArrayNames() *ArrayNames {
+	return &ArrayNames{
+		names: make(map[string]arrayName),
+		now:   time.Now,
+	}
+}
+
+// Register binds name to an array, failing if it is bound to another one
+func (an *ArrayNames) Register(name string, arrayID ArrayID) error {
+	if name == "" {
+		return fmt.Errorf("array name must not be empty")
+	}
+
+	an.mu.Lock()
+	defer an.mu.Unlock()
+
+	if current, exists := an.names[name]; exists {
+		if current.arrayID == arrayID {
+			return nil
+		}
+		return &ErrNameTaken{Name: name, ArrayID: current.arrayID}
+	}
+	an.names[name] = arrayName{arrayID: arrayID, registeredAt: an.now(), local: true}
+	return nil
+}
+
+// Lookup returns the array bound to name
+func (an *ArrayNames) Lookup(name string) (ArrayID, bool) {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+
+	binding, exists := an.names[name]
+	return binding.arrayID, exists
+}
+
+// Merge applies bindings gossiped by other nodes. It returns the arrays
+// registered on this node that lost their last name to an earlier binding.
+func (an *ArrayNames) Merge(names []*proto.ArrayName) []ArrayID {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+
+	var lost []ArrayID
+	for _, name := range names {
+		gossiped := arrayName{
+			arrayID:      ArrayID(name.ArrayId),
+			registeredAt: time.Unix(0, name.RegisteredAtUnixNano),
+		}
+		current, exists := an.names[name.Name]
+		if exists && !gossiped.before(current) {
+			continue
+		}
+		an.names[name.Name] = gossiped
+		if exists && current.local && current.arrayID != gossiped.arrayID {
+			lost = append(lost, current.arrayID)
+		}
+	}
+
+	var displaced []ArrayID
+	for _, arrayID := range lost {
+		if !an.boundLocked(arrayID) {
+			displaced = append(displaced, arrayID)
+		}
+	}
+	return displaced
+}
+
+// boundLocked reports whether any name is bound to the array. The caller
+// must hold an.mu.
+func (an *ArrayNames) boundLocked(arrayID ArrayID) bool {
+	for _, binding := range an.names {
+		if binding.arrayID == arrayID {
+			return true
+		}
+	}
+	return false
+}
+
+// before orders conflicting bindings by registration time, then array ID
+func (b arrayName) before(other arrayName) bool {
+	if !b.registeredAt.Equal(other.registeredAt) {
+		return b.registeredAt.Before(other.registeredAt)
+	}
+	return b.arrayID < other.arrayID
+}
+
+// Snapshot returns every binding for gossip
+func (an *ArrayNames) Snapshot() []*proto.ArrayName {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+
+	names := make([]*proto.ArrayName, 0, len(an.names))
+	for name, binding := range an.names {
+		names = append(names, &proto.ArrayName{
+			Name:                 name,
+			ArrayId:              string(binding.arrayID),
+			RegisteredAtUnixNano: binding.registeredAt.UnixNano(),
+		})
+	}
+	return names
+}
+
+// ContributeState adds the name bindings to outgoing gossip
+func (an *ArrayNames) ContributeState(state *proto.ClusterState) {
+	state.ArrayNames = append(state.ArrayNames, an.Snapshot()...)
+}
+
+// ObserveState merges name bindings from incoming gossip
+func (an *ArrayNames) ObserveState(state *proto.ClusterState) {
+	for _, arrayID := range an.Merge(state.ArrayNames) {
+		if an.onDisplaced != nil {
+			an.onDisplaced(arrayID)
+		}
+	}
+}
+
+// ArrayNames returns the cluster-wide array name registry
+func (mm *MemoryManager) ArrayNames() *ArrayNames {
+	return mm.arrayNames
+}
+
+// deleteDisplacedArray deletes an array created here under a name that
+// another node registered first, as CreateNamedArray does when it loses
+// the name locally
+func (mm *MemoryManager) deleteDisplacedArray(arrayID ArrayID) {
+	mm.logger.Warn("array lost its name to an earlier registration, deleting it", "array_id", arrayID)
+	if err := mm.DeleteArray(context.Background(), arrayID); err != nil {
+		mm.logger.Debug("displaced array already gone", "array_id", arrayID, "error", err)
+	}
+}
@@ -3,6 +3,7 @@ package dsm
 import (
 	"container/list"
 	"sync"
+	"time"
 
 	"github.com/melihxz/holocompute/internal/log"
 )
@@ -14,8 +15,15 @@ type PageCache struct {
 	// Two queues for 2Q algorithm
 	freqList *list.List // Frequently accessed pages
 	onceList *list.List // Pages accessed once
-	logger   *log.Logger
-	mu       sync.RWMutex
+	// Evicted pages go to disk instead of being dropped when spill is set
+	spill          *SpillStore
+	spillThreshold int64 // resident bytes above which pages are spilled
+	hits           int64 // Gets served from memory or spill
+	misses         int64
+	maxAge         time.Duration    // entries older than this need revalidating; 0 never
+	now            func() time.Time // clock stamping entries, replaceable in tests
+	logger         *log.Logger
+	mu             sync.RWMutex
 }
 
 // cacheKey uniquely identifies a cached page
@@ -28,7 +36,8 @@ type cacheKey struct {
 type cacheEntry struct {
 	key      cacheKey
 	page     *Page
-	fromFreq bool // Whether this entry is from the frequent list
+	fromFreq bool      // Whether this entry is from the frequent list
+	cached   time.Time // when the page was last fetched or revalidated
 }
 
 // NewPageCache creates a new page cache with the specified capacity
@@ -38,23 +47,123 @@ func NewPageCache(capacity int, logger *log.Logger) *PageCache {
 		cache:    make(map[cacheKey]*list.Element),
 		freqList: list.New(),
 		onceList: list.New(),
+		now:      time.Now,
 		logger:   logger,
 	}
 }
 
-// Get retrieves a page from the cache
+// SetMaxAge makes entries cached longer than maxAge count as expired until
+// they are revalidated; 0 lets entries live until evicted
+func (pc *PageCache) SetMaxAge(maxAge time.Duration) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.maxAge = maxAge
+}
+
+// Expired reports whether a cached page is older than the max age and must
+// be checked against its owner before being served
+func (pc *PageCache) Expired(arrayID ArrayID, pageID PageID) bool {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	if pc.maxAge <= 0 {
+		return false
+	}
+	element, exists := pc.cache[cacheKey{arrayID: arrayID, pageID: pageID}]
+	if !exists {
+		return false
+	}
+	return pc.now().Sub(element.Value.(*cacheEntry).cached) > pc.maxAge
+}
+
+// Revalidate restarts a cached page's age once its owner confirmed it is current
+func (pc *PageCache) Revalidate(arrayID ArrayID, pageID PageID) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if element, exists := pc.cache[cacheKey{arrayID: arrayID, pageID: pageID}]; exists {
+		element.Value.(*cacheEntry).cached = pc.now()
+	}
+}
+
+// SetSpill makes the cache write evicted pages to store and reload them on
+// Get. Pages are also evicted once resident bytes exceed threshold; a
+// threshold of 0 leaves eviction to the capacity alone.
+func (pc *PageCache) SetSpill(store *SpillStore, threshold int64) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.spill = store
+	pc.spillThreshold = threshold
+}
+
+// cacheRead says what a read does to the page's place in the cache
+type cacheRead int
+
+const (
+	readPromote cacheRead = iota // move the page towards the frequent end
+	readDemote                   // make the page the next to be evicted
+	readPeek                     // leave the page where it is
+)
+
+// Get retrieves a page from the cache, reloading it from disk if it was spilled
 func (pc *PageCache) Get(arrayID ArrayID, pageID PageID) (*Page, bool) {
+	return pc.get(cacheKey{arrayID: arrayID, pageID: pageID}, readPromote)
+}
+
+// GetStreaming retrieves a page that a sequential reader has now consumed.
+// Rather than promoting the page, it is moved to the cold end of the cache
+// so it is evicted before pages not read yet.
+func (pc *PageCache) GetStreaming(arrayID ArrayID, pageID PageID) (*Page, bool) {
+	return pc.get(cacheKey{arrayID: arrayID, pageID: pageID}, readDemote)
+}
+
+// Peek retrieves a page without changing its place in the cache, for further
+// reads of a page already accounted for
+func (pc *PageCache) Peek(arrayID ArrayID, pageID PageID) (*Page, bool) {
+	return pc.get(cacheKey{arrayID: arrayID, pageID: pageID}, readPeek)
+}
+
+// Contains reports whether a page is cached in memory, without counting as an access
+func (pc *PageCache) Contains(arrayID ArrayID, pageID PageID) bool {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	_, exists := pc.cache[cacheKey{arrayID: arrayID, pageID: pageID}]
+	return exists
+}
+
+// get retrieves a page, moving it in the cache as read says
+func (pc *PageCache) get(key cacheKey, read cacheRead) (*Page, bool) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
-	key := cacheKey{arrayID: arrayID, pageID: pageID}
 	element, exists := pc.cache[key]
 	if !exists {
-		return nil, false
+		page, ok := pc.unspill(key)
+		if ok {
+			pc.hits++
+		} else {
+			pc.misses++
+		}
+		return page, ok
 	}
+	pc.hits++
 
 	entry := element.Value.(*cacheEntry)
 
+	switch read {
+	case readPeek:
+		return entry.page, true
+	case readDemote:
+		if entry.fromFreq {
+			pc.freqList.Remove(element)
+		} else {
+			pc.onceList.Remove(element)
+		}
+		entry.fromFreq = false
+		pc.cache[key] = pc.onceList.PushBack(entry)
+		return entry.page, true
+	}
+
 	// Move to frequent list if it's in the once list
 	if !entry.fromFreq {
 		// Remove from once list
@@ -83,6 +192,7 @@ func (pc *PageCache) Put(arrayID ArrayID, pageID PageID, page *Page) {
 	if element, exists := pc.cache[key]; exists {
 		entry := element.Value.(*cacheEntry)
 		entry.page = page
+		entry.cached = pc.now()
 
 		// Move to frequent list if not already there
 		if !entry.fromFreq {
@@ -105,45 +215,96 @@ func (pc *PageCache) Put(arrayID ArrayID, pageID PageID, page *Page) {
 		key:      key,
 		page:     page,
 		fromFreq: false,
+		cached:   pc.now(),
 	}
 	element := pc.onceList.PushFront(entry)
 	pc.cache[key] = element
 
-	// Evict if necessary
+	// A spilled copy is now stale
+	if pc.spill != nil {
+		if err := pc.spill.remove(key); err != nil {
+			pc.logger.Warn("failed to remove stale spilled page", "error", err)
+		}
+	}
+
+	pc.evictOverflow()
+}
+
+// overLimit reports whether the cache holds more than it should in memory
+func (pc *PageCache) overLimit() bool {
 	if len(pc.cache) > pc.capacity {
+		return true
+	}
+	return pc.spill != nil && pc.spillThreshold > 0 && int64(len(pc.cache))*PageSize > pc.spillThreshold
+}
+
+// evictOverflow evicts pages until the cache is within its limits
+func (pc *PageCache) evictOverflow() {
+	for len(pc.cache) > 0 && pc.overLimit() {
 		pc.evict()
 	}
 }
 
-// evict removes the least recently used page from the cache
+// evict removes the least recently used page from the cache, spilling it to
+// disk if spilling is enabled
 func (pc *PageCache) evict() {
-	// First try to evict from once list
-	if pc.onceList.Len() > 0 {
-		element := pc.onceList.Back()
-		if element != nil {
-			entry := pc.onceList.Remove(element).(*cacheEntry)
-			delete(pc.cache, entry.key)
-			return
-		}
+	// First try to evict from once list, then from the freq list
+	queue := pc.onceList
+	if queue.Len() == 0 {
+		queue = pc.freqList
 	}
+	element := queue.Back()
+	if element == nil {
+		return
+	}
+	entry := queue.Remove(element).(*cacheEntry)
+	delete(pc.cache, entry.key)
 
-	// If once list is empty, evict from freq list
-	if pc.freqList.Len() > 0 {
-		element := pc.freqList.Back()
-		if element != nil {
-			entry := pc.freqList.Remove(element).(*cacheEntry)
-			delete(pc.cache, entry.key)
-			return
-		}
+	if pc.spill == nil {
+		return
+	}
+	if err := pc.spill.write(entry.key, entry.page); err != nil {
+		pc.logger.Error("failed to spill evicted page", "error", err)
 	}
 }
 
+// unspill moves a spilled page back into memory
+func (pc *PageCache) unspill(key cacheKey) (*Page, bool) {
+	if pc.spill == nil {
+		return nil, false
+	}
+
+	page, exists, err := pc.spill.read(key)
+	if err != nil {
+		pc.logger.Error("failed to reload spilled page", "error", err)
+		return nil, false
+	}
+	if !exists {
+		return nil, false
+	}
+	if err := pc.spill.remove(key); err != nil {
+		pc.logger.Warn("failed to remove reloaded spilled page", "error", err)
+	}
+
+	// It was used before it was spilled, so it goes straight to the frequent
+	// list. Its age was lost on disk, so it is revalidated before being served.
+	pc.cache[key] = pc.freqList.PushFront(&cacheEntry{key: key, page: page, fromFreq: true})
+	pc.evictOverflow()
+	return page, true
+}
+
 // Remove removes a page from the cache
 func (pc *PageCache) Remove(arrayID ArrayID, pageID PageID) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
 	key := cacheKey{arrayID: arrayID, pageID: pageID}
+	if pc.spill != nil {
+		if err := pc.spill.remove(key); err != nil {
+			pc.logger.Warn("failed to remove spilled page", "error", err)
+		}
+	}
+
 	element, exists := pc.cache[key]
 	if !exists {
 		return
@@ -167,6 +328,13 @@ func (pc *PageCache) Size() int {
 	return len(pc.cache)
 }
 
+// Stats returns the number of Gets that found their page and that didn't
+func (pc *PageCache) Stats() (hits, misses int64) {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return pc.hits, pc.misses
+}
+
 // Capacity returns the maximum capacity of the cache
 func (pc *PageCache) Capacity() int {
 	return pc.capacity
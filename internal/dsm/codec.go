@@ -0,0 +1,102 @@
+package dsm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ElementCustom is the first element type available to custom codecs
+const ElementCustom ElementType = 256
+
+// ErrUnknownElementType is returned for a custom element type with no codec registered
+var ErrUnknownElementType = errors.New("unknown element type")
+
+// ElementCodec encodes the elements of a custom element type in pages
+type ElementCodec interface {
+	// Size returns the encoded size of an element in bytes
+	Size() int
+
+	// Encode writes v into dst, which is Size bytes long, failing if v has
+	// the wrong type
+	Encode(v any, dst []byte) error
+
+	// Decode reads an element from src, which is Size bytes long
+	Decode(src []byte) any
+}
+
+// registeredCodec is a custom element type's codec and name
+type registeredCodec struct {
+	name  string
+	codec ElementCodec
+}
+
+// codecs holds the custom element types registered in this process
+var codecs = struct {
+	types map[ElementType]registeredCodec
+	mu    sync.RWMutex
+}{types: make(map[ElementType]registeredCodec)}
+
+// RegisterElementCodec makes elemType, ElementCustom or above, a custom
+// element type encoded by codec. Every node must register the same types
+// before creating or opening arrays of them, typically at startup.
+func RegisterElementCodec(elemType ElementType, name string, codec ElementCodec) error {
+	if elemType < ElementCustom {
+		return fmt.Errorf("element type %d is reserved for built-in types", int(elemType))
+	}
+	if size := codec.Size(); size <= 0 || size > PageSize {
+		return fmt.Errorf("invalid element size %d for %s", size, name)
+	}
+
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+	if existing, exists := codecs.types[elemType]; exists {
+		return fmt.Errorf("element type %d is already registered as %s", int(elemType), existing.name)
+	}
+	codecs.types[elemType] = registeredCodec{name: name, codec: codec}
+	return nil
+}
+
+// LookupElementCodec returns the codec registered for a custom element type
+func LookupElementCodec(elemType ElementType) (ElementCodec, bool) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+	registered, exists := codecs.types[elemType]
+	return registered.codec, exists
+}
+
+// lookupCodecName returns the name a custom element type was registered with
+func lookupCodecName(elemType ElementType) (string, bool) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+	registered, exists := codecs.types[elemType]
+	return registered.name, exists
+}
+
+// element returns the bytes of the element at index
+func (p *Page) element(codec ElementCodec, elementIndex int) ([]byte, error) {
+	size := codec.Size()
+	offset := elementIndex * size
+	if elementIndex < 0 || offset+size > len(p.storage.data) {
+		return nil, fmt.Errorf("offset out of bounds: %d", offset)
+	}
+	return p.storage.data[offset : offset+size], nil
+}
+
+// GetElement decodes the element at the specified index with codec
+func (p *Page) GetElement(codec ElementCodec, elementIndex int) (any, error) {
+	data, err := p.element(codec, elementIndex)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Decode(data), nil
+}
+
+// SetElement encodes value with codec into the element at the specified index
+func (p *Page) SetElement(codec ElementCodec, elementIndex int, value any) error {
+	data, err := p.element(codec, elementIndex)
+	if err != nil {
+		return err
+	}
+	return codec.Encode(value, data)
+}
@@ -0,0 +1,124 @@
+package dsm
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/scheduler"
+	"golang.org/x/sync/errgroup"
+)
+
+// CompactArray copies the elements of an array for which liveMask returns
+// true into a new, densely packed array, keeping their order. Each page's
+// live elements are counted in parallel and an exclusive prefix sum over the
+// counts gives every page its position in the new array, so pages are then
+// copied in parallel too. Both phases work on at most one page per CPU at a
+// time. liveMask is called concurrently, and twice for each element, so it
+// must be safe for concurrent use and deterministic.
+//
+// The new array has the source's element type and compression, and its pages
+// are owned by the local node, which writes them.
+func (mm *MemoryManager) CompactArray(ctx context.Context, arrayID ArrayID, liveMask func(index int) bool) (*Array, error) {
+	src, err := mm.GetArray(ctx, arrayID)
+	if err != nil {
+		return nil, err
+	}
+
+	length := src.Len()
+	perPage := PageSize / src.ElementSize
+	numPages := src.PageCount()
+
+	// Count the live elements of each page
+	counts := make([]int, numPages)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.NumCPU())
+	for p := 0; p < numPages; p++ {
+		g.Go(func() error {
+			first, last := p*perPage, min((p+1)*perPage, length)
+			for i := first; i < last; i++ {
+				if err := gctx.Err(); err != nil {
+					return err
+				}
+				if liveMask(i) {
+					counts[p]++
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("failed to count live elements: %w", err)
+	}
+
+	offsets := make([]int, numPages)
+	add := func(a, b int) int { return a + b }
+	if err := scheduler.Scan(ctx, mm.logger, counts, add, offsets, scheduler.ExclusiveScan, 0); err != nil {
+		return nil, fmt.Errorf("failed to sum live elements: %w", err)
+	}
+	live := 0
+	if numPages > 0 {
+		live = offsets[numPages-1] + counts[numPages-1]
+	}
+
+	dst, err := mm.CreateArray(ctx, live,
+		WithElementType(src.ElementType),
+		WithCompression(src.Compression),
+		WithPlacement([]hyperbus.NodeID{mm.bus.LocalNode().ID}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compacted array: %w", err)
+	}
+
+	// Materialize the destination pages up front so copies can share them
+	dstPages := make([]*Page, dst.PageCount())
+	for p := range dstPages {
+		if dstPages[p], err = mm.RequestPage(ctx, dst.ID, PageID(p), dst.Version); err != nil {
+			mm.DeleteArray(ctx, dst.ID)
+			return nil, fmt.Errorf("failed to create page %d of compacted array: %w", p, err)
+		}
+	}
+
+	// Copy each page's live elements to their packed positions. Pages write
+	// disjoint element ranges, so they don't need to coordinate.
+	size := src.ElementSize
+	g, gctx = errgroup.WithContext(ctx)
+	g.SetLimit(runtime.NumCPU())
+	for p := 0; p < numPages; p++ {
+		if counts[p] == 0 {
+			continue
+		}
+		g.Go(func() error {
+			page, err := mm.ReadPage(gctx, src.ID, PageID(p), src.Version)
+			if err != nil {
+				return fmt.Errorf("failed to read page %d: %w", p, err)
+			}
+
+			pos := offsets[p]
+			first, last := p*perPage, min((p+1)*perPage, length)
+			for i := first; i < last; i++ {
+				if !liveMask(i) {
+					continue
+				}
+				dstPageID, dstOffset := dst.PageAndOffset(pos)
+				element := page.Bytes()[(i-first)*size : (i-first+1)*size]
+				err := mm.WriteElement(dst.ID, dst.ElementType, dstPages[dstPageID], dstOffset, func(page *Page, index int) error {
+					copy(page.Bytes()[index*size:(index+1)*size], element)
+					return nil
+				})
+				if err != nil {
+					return fmt.Errorf("failed to write element %d of compacted array: %w", pos, err)
+				}
+				pos++
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		mm.DeleteArray(ctx, dst.ID)
+		return nil, fmt.Errorf("failed to compact array %s: %w", arrayID, err)
+	}
+
+	mm.logger.Info("compacted array", "array_id", arrayID, "compacted_id", dst.ID, "length", length, "live", live, "pages", dst.PageCount())
+	return dst, nil
+}
@@ -0,0 +1,55 @@
+package dsm
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryManager_CompactArray(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	mm := NewMemoryManager(bus, logger)
+	ctx := context.Background()
+
+	// Four and a half pages holding their own index
+	perPage := PageSize / 8
+	length := 4*perPage + perPage/2
+	src, err := mm.CreateArray(ctx, length, WithPlacement([]hyperbus.NodeID{"node-1"}))
+	assert.NoError(t, err)
+	for i := 0; i < length; i++ {
+		pageID, offset := src.PageAndOffset(i)
+		page, err := mm.RequestPage(ctx, src.ID, pageID, src.Version)
+		assert.NoError(t, err)
+		assert.NoError(t, page.SetInt64(offset, int64(i)))
+	}
+
+	// Every third element survives
+	dst, err := mm.CompactArray(ctx, src.ID, func(i int) bool { return i%3 == 0 })
+	assert.NoError(t, err)
+	assert.NotEqual(t, src.ID, dst.ID)
+	assert.Equal(t, (length+2)/3, dst.Len())
+	assert.Equal(t, 2, dst.PageCount())
+	assert.Less(t, dst.PageCount(), src.PageCount())
+
+	for i := 0; i < dst.Len(); i++ {
+		pageID, offset := dst.PageAndOffset(i)
+		page, err := mm.ReadPage(ctx, dst.ID, pageID, dst.Version)
+		assert.NoError(t, err)
+		v, err := page.GetInt64(offset)
+		assert.NoError(t, err)
+		if !assert.Equal(t, int64(3*i), v, "element %d", i) {
+			break
+		}
+	}
+
+	// Nothing live leaves an empty array
+	empty, err := mm.CompactArray(ctx, src.ID, func(int) bool { return false })
+	assert.NoError(t, err)
+	assert.Equal(t, 0, empty.Len())
+	assert.Equal(t, 0, empty.PageCount())
+}
@@ -0,0 +1,79 @@
+package dsm
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Shared zstd coders; EncodeAll and DecodeAll are safe for concurrent use
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(PageSize))
+)
+
+// compressPage encodes page contents for transfer. The first byte of the
+// result names the algorithm actually used, so the receiver decodes it
+// regardless of its own defaults. Data that doesn't shrink is sent raw.
+func compressPage(data []byte, encoding proto.Encoding) ([]byte, proto.Encoding, error) {
+	switch encoding {
+	case proto.Encoding_LZ4:
+		out := make([]byte, 1+lz4.CompressBlockBound(len(data)))
+		n, err := lz4.CompressBlock(data, out[1:], nil)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to compress page with lz4: %w", err)
+		}
+		if n > 0 && n < len(data) {
+			out[0] = byte(proto.Encoding_LZ4)
+			return out[:1+n], proto.Encoding_LZ4, nil
+		}
+	case proto.Encoding_ZSTD:
+		out := zstdEncoder.EncodeAll(data, []byte{byte(proto.Encoding_ZSTD)})
+		if len(out)-1 < len(data) {
+			return out, proto.Encoding_ZSTD, nil
+		}
+	case proto.Encoding_RAW:
+	default:
+		return nil, 0, fmt.Errorf("unsupported page encoding: %s", encoding)
+	}
+
+	out := make([]byte, 1+len(data))
+	out[0] = byte(proto.Encoding_RAW)
+	copy(out[1:], data)
+	return out, proto.Encoding_RAW, nil
+}
+
+// decompressPage decodes a payload produced by compressPage
+func decompressPage(payload []byte) ([]byte, error) {
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("empty page payload")
+	}
+
+	var data []byte
+	switch encoding := proto.Encoding(payload[0]); encoding {
+	case proto.Encoding_RAW:
+		data = payload[1:]
+	case proto.Encoding_LZ4:
+		data = make([]byte, PageSize)
+		n, err := lz4.UncompressBlock(payload[1:], data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress lz4 page: %w", err)
+		}
+		data = data[:n]
+	case proto.Encoding_ZSTD:
+		var err error
+		data, err = zstdDecoder.DecodeAll(payload[1:], make([]byte, 0, PageSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress zstd page: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown page encoding: %d", payload[0])
+	}
+
+	if len(data) > PageSize {
+		return nil, fmt.Errorf("page payload too large: %d bytes", len(data))
+	}
+	return data, nil
+}
@@ -0,0 +1,93 @@
+package dsm
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+var encodings = []proto.Encoding{proto.Encoding_RAW, proto.Encoding_LZ4, proto.Encoding_ZSTD}
+
+func TestCompressPage_RoundTrip(t *testing.T) {
+	sparse := make([]byte, PageSize)
+	sparse[100], sparse[PageSize-1] = 7, 9
+
+	random := make([]byte, PageSize)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	for _, encoding := range encodings {
+		payload, used, err := compressPage(sparse, encoding)
+		assert.NoError(t, err)
+		assert.Equal(t, encoding, used)
+		assert.Equal(t, byte(encoding), payload[0])
+		if encoding != proto.Encoding_RAW {
+			assert.Less(t, len(payload), PageSize/10)
+		}
+
+		data, err := decompressPage(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, sparse, data)
+
+		// Incompressible pages fall back to raw
+		payload, used, err = compressPage(random, encoding)
+		assert.NoError(t, err)
+		assert.Equal(t, proto.Encoding_RAW, used)
+		data, err = decompressPage(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, random, data)
+	}
+}
+
+func TestDecompressPage_Invalid(t *testing.T) {
+	_, err := decompressPage(nil)
+	assert.Error(t, err)
+
+	_, err = decompressPage([]byte{9, 1, 2})
+	assert.ErrorContains(t, err, "unknown page encoding")
+
+	_, err = decompressPage(append([]byte{byte(proto.Encoding_LZ4)}, 0xff, 0xff))
+	assert.Error(t, err)
+
+	_, err = decompressPage(append([]byte{byte(proto.Encoding_RAW)}, make([]byte, PageSize+1)...))
+	assert.ErrorContains(t, err, "too large")
+}
+
+func TestMemoryManager_RequestCompressedPage(t *testing.T) {
+	a, b := newConnectedPair()
+
+	for _, encoding := range encodings {
+		array, err := a.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}), WithCompression(encoding))
+		assert.NoError(t, err)
+		share(array, b)
+
+		page, err := a.RequestPage(context.TODO(), array.ID, 0, array.Version)
+		assert.NoError(t, err)
+		assert.NoError(t, page.SetInt64(3, 42))
+
+		remote, err := b.RequestPage(context.TODO(), array.ID, 0, array.Version)
+		assert.NoError(t, err)
+		assert.Equal(t, page.Bytes(), remote.Bytes())
+	}
+}
+
+func BenchmarkCompressPage_ZeroFilled(b *testing.B) {
+	data := make([]byte, PageSize)
+
+	for _, encoding := range encodings {
+		b.Run(encoding.String(), func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				payload, _, err := compressPage(data, encoding)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(payload)
+			}
+			b.ReportMetric(float64(size), "bytes/page")
+		})
+	}
+}
@@ -1,13 +1,19 @@
 package dsm
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/melihxz/holocompute/internal/deadline"
 	"github.com/melihxz/holocompute/internal/hyperbus"
 	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
 )
 
 // ArrayID uniquely identifies a shared array
@@ -19,15 +25,32 @@ type PageID int32
 // Version represents a version of a page
 type Version int64
 
+// Epoch counts the ownership changes of a page. Leases granted in an earlier
+// epoch are no longer valid.
+type Epoch int64
+
 // PageSize is the size of a page in bytes
 const PageSize = 64 * 1024 // 64 KiB
 
+// ErrPageOutOfRange is returned when a page ID is outside an array's bounds
+type ErrPageOutOfRange struct {
+	PageID   PageID
+	NumPages int
+}
+
+// Error implements the error interface
+func (e *ErrPageOutOfRange) Error() string {
+	return fmt.Sprintf("page %d out of range [0, %d)", e.PageID, e.NumPages)
+}
+
 // Page represents a page of data
 type Page struct {
 	ID      PageID
 	Version Version
 	Data    []byte
 	storage *pageStorage
+	dirty   atomic.Bool // written since the last sync
+	commit  sync.Mutex  // held while the page's version is checked and moved on
 }
 
 // NewPage creates a new page
@@ -40,6 +63,36 @@ func NewPage(id PageID, version Version) *Page {
 	}
 }
 
+// MarkDirty records that the page was written since the last sync
+func (p *Page) MarkDirty() {
+	p.dirty.Store(true)
+}
+
+// Dirty returns true if the page was written since the last sync
+func (p *Page) Dirty() bool {
+	return p.dirty.Load()
+}
+
+// ClearDirty marks the page as synced
+func (p *Page) ClearDirty() {
+	p.dirty.Store(false)
+}
+
+// committedVersion returns the version of the page's last commit
+func (p *Page) committedVersion() Version {
+	p.commit.Lock()
+	defer p.commit.Unlock()
+	return p.Version
+}
+
+// snapshot returns the page's version together with a copy of the contents
+// committed at that version, so a concurrent commit is never seen half done
+func (p *Page) snapshot() (Version, []byte) {
+	p.commit.Lock()
+	defer p.commit.Unlock()
+	return p.Version, bytes.Clone(p.Bytes())
+}
+
 // GetInt64 reads a 64-bit integer from the page at the specified element index
 func (p *Page) GetInt64(elementIndex int) (int64, error) {
 	offset := elementIndex * 8
@@ -52,6 +105,18 @@ func (p *Page) SetInt64(elementIndex int, value int64) error {
 	return p.storage.setInt64(offset, value)
 }
 
+// GetInt32 reads a 32-bit integer from the page at the specified element index
+func (p *Page) GetInt32(elementIndex int) (int32, error) {
+	offset := elementIndex * 4
+	return p.storage.getInt32(offset)
+}
+
+// SetInt32 writes a 32-bit integer to the page at the specified element index
+func (p *Page) SetInt32(elementIndex int, value int32) error {
+	offset := elementIndex * 4
+	return p.storage.setInt32(offset, value)
+}
+
 // GetFloat32 reads a 32-bit float from the page at the specified element index
 func (p *Page) GetFloat32(elementIndex int) (float32, error) {
 	offset := elementIndex * 4
@@ -64,27 +129,162 @@ func (p *Page) SetFloat32(elementIndex int, value float32) error {
 	return p.storage.setFloat32(offset, value)
 }
 
+// GetFloat64 reads a 64-bit float from the page at the specified element index
+func (p *Page) GetFloat64(elementIndex int) (float64, error) {
+	offset := elementIndex * 8
+	return p.storage.getFloat64(offset)
+}
+
+// SetFloat64 writes a 64-bit float to the page at the specified element index
+func (p *Page) SetFloat64(elementIndex int, value float64) error {
+	offset := elementIndex * 8
+	return p.storage.setFloat64(offset, value)
+}
+
+// GetFloat16 reads a half precision float from the page at the specified element index
+func (p *Page) GetFloat16(elementIndex int) (float32, error) {
+	h, err := p.storage.getUint16(elementIndex * 2)
+	if err != nil {
+		return 0, err
+	}
+	return float16ToFloat32(h), nil
+}
+
+// SetFloat16 writes a value to the page as a half precision float at the specified element index
+func (p *Page) SetFloat16(elementIndex int, value float32) error {
+	return p.storage.setUint16(elementIndex*2, float32ToFloat16(value))
+}
+
+// GetBFloat16 reads a bfloat16 from the page at the specified element index
+func (p *Page) GetBFloat16(elementIndex int) (float32, error) {
+	b, err := p.storage.getUint16(elementIndex * 2)
+	if err != nil {
+		return 0, err
+	}
+	return bfloat16ToFloat32(b), nil
+}
+
+// SetBFloat16 writes a value to the page as a bfloat16 at the specified element index
+func (p *Page) SetBFloat16(elementIndex int, value float32) error {
+	return p.storage.setUint16(elementIndex*2, float32ToBFloat16(value))
+}
+
+// ElementType identifies how array elements are encoded in pages
+type ElementType int
+
+const (
+	// ElementInt64 is a 64-bit signed integer
+	ElementInt64 ElementType = iota
+	// ElementFloat32 is an IEEE 754 single precision float
+	ElementFloat32
+	// ElementFloat16 is an IEEE 754 half precision float
+	ElementFloat16
+	// ElementBFloat16 is a bfloat16 float
+	ElementBFloat16
+	// ElementFloat64 is an IEEE 754 double precision float
+	ElementFloat64
+	// ElementInt32 is a 32-bit signed integer
+	ElementInt32
+)
+
+// String returns the name of the element type
+func (t ElementType) String() string {
+	switch t {
+	case ElementInt64:
+		return "int64"
+	case ElementFloat32:
+		return "float32"
+	case ElementFloat16:
+		return "float16"
+	case ElementBFloat16:
+		return "bfloat16"
+	case ElementFloat64:
+		return "float64"
+	case ElementInt32:
+		return "int32"
+	}
+	if name, exists := lookupCodecName(t); exists {
+		return name
+	}
+	return fmt.Sprintf("ElementType(%d)", int(t))
+}
+
+// Size returns the encoded size of an element in bytes
+func (t ElementType) Size() int {
+	switch t {
+	case ElementFloat32, ElementInt32:
+		return 4
+	case ElementFloat16, ElementBFloat16:
+		return 2
+	case ElementInt64, ElementFloat64:
+		return 8
+	}
+	if codec, exists := LookupElementCodec(t); exists {
+		return codec.Size()
+	}
+	return 8
+}
+
 // Array represents a distributed shared array
 type Array struct {
 	ID          ArrayID
 	Length      int
 	NumPages    int
+	ElementType ElementType
+	ElementSize int
 	PageMapping map[PageID]hyperbus.NodeID
 	Version     Version
+	ReadOnly    bool                // contents never change once created or sealed
+	Replication int                 // number of copies kept of each page
+	Compression proto.Encoding      // encoding used when transferring pages
+	Sparse      bool                // pages materialize on first write
+	Access      AccessPattern       // how the array's pages are expected to be read
+	hashed      bool                // pages were placed on the ring
+	present     map[PageID]struct{} // pages materialized on this node
+	epochs      map[PageID]Epoch    // ownership epochs of pages that changed owner
+	lastAccess  atomic.Int64        // unix nanoseconds of the last access on this node
+	lastRead    atomic.Int64        // one more than the page last requested on this node, 0 before any
 	mu          sync.RWMutex
 }
 
-// NewArray creates a new array
+// NewArray creates a new array of 8-byte elements
 func NewArray(length int) *Array {
-	pageCount := (length*8 + PageSize - 1) / PageSize // Assuming 8 bytes per element for now
+	return newTypedArray(length, ElementInt64)
+}
+
+// newTypedArray creates a new array sized for the given element type
+func newTypedArray(length int, elemType ElementType) *Array {
+	elementSize := elemType.Size()
+	pageCount := (length*elementSize + PageSize - 1) / PageSize
 
 	return &Array{
 		ID:          ArrayID(uuid.New().String()),
 		Length:      length,
 		NumPages:    pageCount,
+		ElementType: elemType,
+		ElementSize: elementSize,
 		PageMapping: make(map[PageID]hyperbus.NodeID),
 		Version:     1,
+		Replication: 1,
+	}
+}
+
+// LastAccess returns when the array was last accessed on this node, or the
+// zero time if it never was
+func (a *Array) LastAccess() time.Time {
+	if ns := a.lastAccess.Load(); ns != 0 {
+		return time.Unix(0, ns)
 	}
+	return time.Time{}
+}
+
+// readPage records a request for pageID and returns the page requested
+// before it, and whether this request moved the reader onto another page.
+// Element reads go through the page on each access, so caching and prefetch
+// act only when a reader moves between pages.
+func (a *Array) readPage(pageID PageID) (PageID, bool) {
+	last := a.lastRead.Swap(int64(pageID) + 1)
+	return PageID(last - 1), last != int64(pageID)+1
 }
 
 // PageCount returns the number of pages in the array
@@ -94,6 +294,37 @@ func (a *Array) PageCount() int {
 	return a.NumPages
 }
 
+// ResidentPages returns how many of the array's pages are materialized on this node
+func (a *Array) ResidentPages() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.present)
+}
+
+// markPresent records that a page was materialized on this node
+func (a *Array) markPresent(pageID PageID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.present == nil {
+		a.present = make(map[PageID]struct{})
+	}
+	a.present[pageID] = struct{}{}
+}
+
+// Len returns the number of elements in the array
+func (a *Array) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Length
+}
+
+// PageAndOffset returns the page holding element i and the element's index within it
+func (a *Array) PageAndOffset(i int) (PageID, int) {
+	elementsPerPage := PageSize / a.ElementSize
+	return PageID(i / elementsPerPage), i % elementsPerPage
+}
+
 // GetPageOwner returns the node that owns the specified page
 func (a *Array) GetPageOwner(pageID PageID) (hyperbus.NodeID, bool) {
 	a.mu.RLock()
@@ -103,21 +334,226 @@ func (a *Array) GetPageOwner(pageID PageID) (hyperbus.NodeID, bool) {
 	return nodeID, exists
 }
 
-// SetPageOwner sets the owner of the specified page
+// SetPageOwner sets the owner of the specified page, bumping its ownership
+// epoch if the owner changes. Only this node's view changes; use
+// MemoryManager.MovePages to move a page across the cluster.
 func (a *Array) SetPageOwner(pageID PageID, nodeID hyperbus.NodeID) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	if owner, exists := a.PageMapping[pageID]; exists && owner != nodeID {
+		a.bumpEpochLocked(pageID)
+	}
 	a.PageMapping[pageID] = nodeID
 }
 
+// RemapPages applies a batch of ownership changes atomically: fn edits the
+// page mapping under the write lock, so readers see either none or all of
+// its changes. Pages whose owner changed move to a new ownership epoch.
+// Like SetPageOwner it only changes this node's view.
+func (a *Array) RemapPages(fn func(mapping map[PageID]hyperbus.NodeID)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	before := make(map[PageID]hyperbus.NodeID, len(a.PageMapping))
+	for pageID, nodeID := range a.PageMapping {
+		before[pageID] = nodeID
+	}
+	fn(a.PageMapping)
+	for pageID, owner := range before {
+		if a.PageMapping[pageID] != owner {
+			a.bumpEpochLocked(pageID)
+		}
+	}
+}
+
+// applyRemap adopts ownership changes made on another node: each page moves
+// to the owner given for it unless this node already saw its epoch or a
+// later one. It returns the number of pages whose owner changed.
+func (a *Array) applyRemap(owners map[PageID]hyperbus.NodeID, epochs map[PageID]Epoch) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	changed := 0
+	for pageID, owner := range owners {
+		epoch := epochs[pageID]
+		if int(pageID) >= a.NumPages || epoch <= a.epochs[pageID] {
+			continue
+		}
+		if a.epochs == nil {
+			a.epochs = make(map[PageID]Epoch)
+		}
+		a.epochs[pageID] = epoch
+		if a.PageMapping[pageID] != owner {
+			a.PageMapping[pageID] = owner
+			changed++
+		}
+	}
+	return changed
+}
+
+// applyGrowth adopts pages another node appended to the array, with the
+// owners given for them. It reports whether the array grew.
+func (a *Array) applyGrowth(numPages, length int, owners map[PageID]hyperbus.NodeID) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if numPages <= a.NumPages {
+		return false
+	}
+	for pageID := PageID(a.NumPages); int(pageID) < numPages; pageID++ {
+		if owner, exists := owners[pageID]; exists {
+			a.PageMapping[pageID] = owner
+		}
+	}
+	a.NumPages = numPages
+	a.Length = max(a.Length, length)
+	return true
+}
+
+// PageEpoch returns the ownership epoch of a page
+func (a *Array) PageEpoch(pageID PageID) Epoch {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.epochs[pageID]
+}
+
+// bumpEpochLocked moves a page to its next ownership epoch. The caller must
+// hold a.mu.
+func (a *Array) bumpEpochLocked(pageID PageID) {
+	if a.epochs == nil {
+		a.epochs = make(map[PageID]Epoch)
+	}
+	a.epochs[pageID]++
+}
+
+// PageOwners returns a consistent copy of the page mapping
+func (a *Array) PageOwners() map[PageID]hyperbus.NodeID {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	owners := make(map[PageID]hyperbus.NodeID, len(a.PageMapping))
+	for pageID, nodeID := range a.PageMapping {
+		owners[pageID] = nodeID
+	}
+	return owners
+}
+
+// LivenessChecker reports whether cluster nodes are alive
+type LivenessChecker interface {
+	// IsAlive returns true if the node is a known, alive member
+	IsAlive(nodeID hyperbus.NodeID) bool
+}
+
+// ArrayOption configures array creation
+type ArrayOption func(*arrayOptions)
+
+type arrayOptions struct {
+	// Nodes to pin pages to, round-robin
+	placement []hyperbus.NodeID
+
+	// Array whose page owners the new array mirrors
+	affinityWith ArrayID
+
+	// Encoding of the array's elements
+	elemType ElementType
+
+	// Whether the array's contents are fixed after creation
+	readOnly bool
+
+	// Number of copies kept of each page
+	replication int
+
+	// Encoding used when transferring pages
+	compression proto.Encoding
+
+	// Whether pages materialize only on first write
+	sparse bool
+
+	// How the array's pages are expected to be read
+	access AccessPattern
+}
+
+// WithPlacement pins the array's pages to the given nodes round-robin
+func WithPlacement(nodes []hyperbus.NodeID) ArrayOption {
+	return func(o *arrayOptions) {
+		o.placement = nodes
+	}
+}
+
+// WithAffinity co-locates each page with the page of another array, of the
+// same length, that holds its first element
+func WithAffinity(arrayID ArrayID) ArrayOption {
+	return func(o *arrayOptions) {
+		o.affinityWith = arrayID
+	}
+}
+
+// WithElementType sets the element encoding, which determines how many pages the array spans
+func WithElementType(elemType ElementType) ArrayOption {
+	return func(o *arrayOptions) {
+		o.elemType = elemType
+	}
+}
+
+// WithReadOnly marks the array as never written after creation, so copies
+// of its pages stay valid regardless of version
+func WithReadOnly() ArrayOption {
+	return func(o *arrayOptions) {
+		o.readOnly = true
+	}
+}
+
+// WithReplication sets how many copies of each page the cluster keeps
+func WithReplication(n int) ArrayOption {
+	return func(o *arrayOptions) {
+		o.replication = n
+	}
+}
+
+// WithCompression sets the encoding used when transferring the array's pages
+func WithCompression(encoding proto.Encoding) ArrayOption {
+	return func(o *arrayOptions) {
+		o.compression = encoding
+	}
+}
+
+// WithSparse materializes the array's pages only when first written. Reads
+// of pages never written see zeroes without allocating them.
+func WithSparse() ArrayOption {
+	return func(o *arrayOptions) {
+		o.sparse = true
+	}
+}
+
+// remoteFetcher fetches a page from its remote owner
+type remoteFetcher func(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version) (*Page, error)
+
+// versionChecker asks a page's remote owner for its current version
+type versionChecker func(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID) (Version, error)
+
 // MemoryManager manages distributed shared memory
 type MemoryManager struct {
-	arrays map[ArrayID]*Array
-	bus    *hyperbus.Bus
-	logger *log.Logger
-	pages  map[pageKey]*Page // local page storage
-	mu     sync.RWMutex
+	arrays      map[ArrayID]*Array
+	bus         *hyperbus.Bus
+	logger      *log.Logger
+	pages       map[pageKey]*Page // local page storage
+	liveness    LivenessChecker
+	ring        *Ring // default page placement
+	inflight    *inflightLimiter
+	fetchRemote remoteFetcher
+	checkRemote versionChecker
+	arrayLeases *ArrayLeases
+	arrayNames  *ArrayNames
+	cache       *PageCache       // copies of remotely owned pages
+	refs        map[ArrayID]int  // open handles per array
+	repair      *repairLimiter   // nil when read-repair is disabled
+	pageTimeout time.Duration    // default deadline for page requests
+	wal         *WAL             // nil unless writes are logged
+	zero        *Page            // shared by reads of unmaterialized sparse pages
+	prefetching map[pageKey]bool // pages being prefetched
+	now         func() time.Time // clock stamping array accesses, replaceable in tests
+	mu          sync.RWMutex
 }
 
 // pageKey uniquely identifies a page
@@ -128,20 +564,139 @@ type pageKey struct {
 
 // NewMemoryManager creates a new memory manager
 func NewMemoryManager(bus *hyperbus.Bus, logger *log.Logger) *MemoryManager {
-	return &MemoryManager{
-		arrays: make(map[ArrayID]*Array),
-		bus:    bus,
-		logger: logger,
-		pages:  make(map[pageKey]*Page),
+	mm := &MemoryManager{
+		arrays:      make(map[ArrayID]*Array),
+		bus:         bus,
+		logger:      logger,
+		pages:       make(map[pageKey]*Page),
+		inflight:    newInflightLimiter(DefaultMaxInflightPerNode),
+		arrayLeases: NewArrayLeases(),
+		arrayNames:  NewArrayNames(),
+		cache:       NewPageCache(DefaultCacheCapacity, logger),
+		refs:        make(map[ArrayID]int),
+		prefetching: make(map[pageKey]bool),
+		repair:      newRepairLimiter(DefaultReadRepairRate),
+		pageTimeout: DefaultPageRequestTimeout,
+		now:         time.Now,
 	}
+	mm.fetchRemote = mm.requestRemotePage
+	mm.checkRemote = mm.requestRemoteVersion
+	mm.arrayNames.onDisplaced = mm.deleteDisplacedArray
+	return mm
+}
+
+// SetMaxInflightPerNode caps concurrent outstanding page requests to a single owner.
+// Requests beyond the cap queue until a slot frees up.
+func (mm *MemoryManager) SetMaxInflightPerNode(limit int) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.inflight = newInflightLimiter(limit)
+}
+
+// SetLivenessChecker sets the checker used to validate placement targets
+func (mm *MemoryManager) SetLivenessChecker(liveness LivenessChecker) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.liveness = liveness
+}
+
+// SetSpill makes the page cache spill evicted pages to store once it holds
+// more than threshold bytes
+func (mm *MemoryManager) SetSpill(store *SpillStore, threshold int64) {
+	mm.cache.SetSpill(store, threshold)
+}
+
+// SetCacheMaxAge bounds how long a cached remote page is served before its
+// version is checked against the owner, refetching it if that changed; 0
+// serves cached pages until evicted
+func (mm *MemoryManager) SetCacheMaxAge(maxAge time.Duration) {
+	mm.cache.SetMaxAge(maxAge)
+}
+
+// SetRing sets the ring that places pages of arrays created without a placement
+func (mm *MemoryManager) SetRing(ring *Ring) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.ring = ring
 }
 
 // CreateArray creates a new shared array
-func (mm *MemoryManager) CreateArray(ctx context.Context, length int) (*Array, error) {
-	array := NewArray(length)
+func (mm *MemoryManager) CreateArray(ctx context.Context, length int, opts ...ArrayOption) (*Array, error) {
+	var options arrayOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.replication < 0 {
+		return nil, fmt.Errorf("invalid replication factor %d", options.replication)
+	}
+	if _, exists := LookupElementCodec(options.elemType); options.elemType >= ElementCustom && !exists {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownElementType, int(options.elemType))
+	}
+
+	array := newTypedArray(length, options.elemType)
+	array.ReadOnly = options.readOnly
+	array.Compression = options.compression
+	array.Sparse = options.sparse
+	array.Access = options.access
+	if options.replication > 0 {
+		array.Replication = options.replication
+	}
+
+	if len(options.placement) > 0 && options.affinityWith != "" {
+		return nil, fmt.Errorf("placement and affinity are mutually exclusive")
+	}
+
+	// Mirror the owners of the referenced array's pages holding the same
+	// elements, which differ from ours when the element sizes do
+	if options.affinityWith != "" {
+		other, err := mm.GetArray(ctx, options.affinityWith)
+		if err != nil {
+			return nil, fmt.Errorf("invalid affinity: %w", err)
+		}
+		if other.Len() != length {
+			return nil, fmt.Errorf("invalid affinity: array %s has length %d, want %d", other.ID, other.Len(), length)
+		}
+		perPage := PageSize / array.ElementSize
+		for i := 0; i < array.NumPages; i++ {
+			otherPage, _ := other.PageAndOffset(i * perPage)
+			if owner, exists := other.GetPageOwner(otherPage); exists {
+				array.PageMapping[PageID(i)] = owner
+			}
+		}
+	}
+
+	// Pages are placed on the ring unless placement or affinity say otherwise
+	mm.mu.RLock()
+	ring := mm.ring
+	mm.mu.RUnlock()
+	if ring != nil && len(options.placement) == 0 && options.affinityWith == "" {
+		array.hashed = true
+		for i := 0; i < array.NumPages; i++ {
+			if owner, ok := ring.PageOwner(array.ID, PageID(i)); ok {
+				array.PageMapping[PageID(i)] = owner
+			}
+		}
+	}
+
+	// Explicit placement overrides hash-based ownership
+	if len(options.placement) > 0 {
+		if err := mm.validatePlacement(options.placement); err != nil {
+			return nil, err
+		}
+		for i := 0; i < array.NumPages; i++ {
+			array.PageMapping[PageID(i)] = options.placement[i%len(options.placement)]
+		}
+	}
 
+	// The creator holds the first reference
+	array.lastAccess.Store(mm.now().UnixNano())
+	if err := mm.saveArray(array); err != nil {
+		return nil, err
+	}
 	mm.mu.Lock()
 	mm.arrays[array.ID] = array
+	mm.refs[array.ID] = 1
 	mm.mu.Unlock()
 
 	mm.logger.Info("created new array", "array_id", array.ID, "length", length, "pages", array.PageCount)
@@ -149,6 +704,88 @@ func (mm *MemoryManager) CreateArray(ctx context.Context, length int) (*Array, e
 	return array, nil
 }
 
+// AppendIndex extends an array by one element and returns its index. When the
+// array's pages are full it doubles them, so appends rarely grow the array.
+// New pages go on the ring if one is set, and otherwise follow the owners of
+// the existing pages round-robin. Growth is announced to connected peers so
+// the owners of the new pages serve them.
+func (mm *MemoryManager) AppendIndex(ctx context.Context, arrayID ArrayID) (int, error) {
+	array, err := mm.GetArray(ctx, arrayID)
+	if err != nil {
+		return 0, err
+	}
+	if array.ReadOnly {
+		return 0, fmt.Errorf("cannot append to read-only array %s", arrayID)
+	}
+
+	mm.mu.RLock()
+	ring := mm.ring
+	mm.mu.RUnlock()
+
+	array.mu.Lock()
+	index := array.Length
+	capacity := array.NumPages * (PageSize / array.ElementSize)
+	var growth *proto.PageRemap
+	if index >= capacity {
+		oldPages := array.NumPages
+		newPages := max(2*oldPages, 1)
+		growth = &proto.PageRemap{
+			ArrayId:    string(arrayID),
+			PageOwners: make(map[int32]string, newPages-oldPages),
+			NumPages:   int32(newPages),
+			Length:     int64(index + 1),
+		}
+		for i := oldPages; i < newPages; i++ {
+			pageID := PageID(i)
+			owner := mm.bus.LocalNode().ID
+			if ring != nil {
+				if ringOwner, ok := ring.PageOwner(arrayID, pageID); ok {
+					owner = ringOwner
+				}
+			} else if oldPages > 0 {
+				owner = array.PageMapping[PageID(i%oldPages)]
+			}
+			array.PageMapping[pageID] = owner
+			growth.PageOwners[int32(pageID)] = string(owner)
+		}
+		array.NumPages = newPages
+
+		mm.logger.Debug("grew array", "array_id", arrayID, "pages", newPages)
+	}
+	array.Length++
+	array.mu.Unlock()
+
+	// The new pages' owners have to survive a restart
+	if growth != nil {
+		if err := mm.saveArray(array); err != nil {
+			return 0, err
+		}
+		mm.broadcastRemap(ctx, growth)
+	}
+	return index, nil
+}
+
+// validatePlacement checks that every placement target is alive
+func (mm *MemoryManager) validatePlacement(nodes []hyperbus.NodeID) error {
+	mm.mu.RLock()
+	liveness := mm.liveness
+	mm.mu.RUnlock()
+
+	for _, nodeID := range nodes {
+		if nodeID == "" {
+			return fmt.Errorf("invalid placement: empty node ID")
+		}
+		if nodeID == mm.bus.LocalNode().ID {
+			continue
+		}
+		if liveness != nil && !liveness.IsAlive(nodeID) {
+			return fmt.Errorf("invalid placement: node %s is not alive", nodeID)
+		}
+	}
+
+	return nil
+}
+
 // GetArray retrieves an existing array
 func (mm *MemoryManager) GetArray(ctx context.Context, arrayID ArrayID) (*Array, error) {
 	mm.mu.RLock()
@@ -162,6 +799,39 @@ func (mm *MemoryManager) GetArray(ctx context.Context, arrayID ArrayID) (*Array,
 	return array, nil
 }
 
+// touchArray records an access to an array, which cache and GC policies
+// read through LastAccess
+func (mm *MemoryManager) touchArray(arrayID ArrayID) {
+	mm.mu.RLock()
+	array, exists := mm.arrays[arrayID]
+	mm.mu.RUnlock()
+
+	if exists {
+		array.lastAccess.Store(mm.now().UnixNano())
+	}
+}
+
+// TouchArray records an access to an array that was served without a page
+// request, such as a read of a handle's private copy of a page
+func (mm *MemoryManager) TouchArray(arrayID ArrayID) {
+	mm.touchArray(arrayID)
+}
+
+// SealArray makes an array read-only once its contents have been written.
+// Call it before handing the array's ID out, since nodes that already
+// opened the array keep treating it as writable.
+func (mm *MemoryManager) SealArray(ctx context.Context, arrayID ArrayID) error {
+	array, err := mm.GetArray(ctx, arrayID)
+	if err != nil {
+		return err
+	}
+
+	array.mu.Lock()
+	array.ReadOnly = true
+	array.mu.Unlock()
+	return mm.saveArray(array)
+}
+
 // DeleteArray deletes an array
 func (mm *MemoryManager) DeleteArray(ctx context.Context, arrayID ArrayID) error {
 	mm.mu.Lock()
@@ -173,41 +843,146 @@ func (mm *MemoryManager) DeleteArray(ctx context.Context, arrayID ArrayID) error
 	}
 
 	delete(mm.arrays, arrayID)
+	delete(mm.refs, arrayID)
 	mm.logger.Info("deleted array", "array_id", arrayID)
 
+	if mm.wal != nil {
+		return mm.wal.removeArray(arrayID)
+	}
 	return nil
 }
 
-// RequestPage requests a page from the owner
+// RequestPage requests a page from the owner, materializing locally owned
+// pages that don't exist yet
 func (mm *MemoryManager) RequestPage(ctx context.Context, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+	return mm.requestPage(ctx, arrayID, pageID, version, true)
+}
+
+// ReadPage requests a page to read from. Unlike RequestPage it doesn't
+// materialize locally owned pages of sparse arrays; those never written are
+// served as a shared zero page that must not be written to.
+func (mm *MemoryManager) ReadPage(ctx context.Context, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+	return mm.requestPage(ctx, arrayID, pageID, version, false)
+}
+
+// requestPage fetches a page from its owner. Locally owned pages that don't
+// exist yet are created unless the array is sparse and forWrite is false.
+func (mm *MemoryManager) requestPage(ctx context.Context, arrayID ArrayID, pageID PageID, version Version, forWrite bool) (*Page, error) {
+	ctx, cancel := deadline.WithDefault(ctx, mm.requestTimeout())
+	defer cancel()
+
 	// Get the array
 	array, err := mm.GetArray(ctx, arrayID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get array: %w", err)
 	}
+	mm.touchArray(arrayID)
+
+	if pageID < 0 || int(pageID) >= array.PageCount() {
+		return nil, &ErrPageOutOfRange{PageID: pageID, NumPages: array.PageCount()}
+	}
 
 	// Get the owner of the page
 	ownerID, exists := array.GetPageOwner(pageID)
 	if !exists {
 		return nil, fmt.Errorf("page owner not found for page %d in array %s", pageID, arrayID)
 	}
+	previous, moved := array.readPage(pageID)
 
 	// If we're the owner, return the local page
 	if ownerID == mm.bus.LocalNode().ID {
-		return mm.getLocalPage(ctx, arrayID, pageID, version)
+		return mm.getLocalPage(ctx, arrayID, pageID, version, forWrite || !array.Sparse)
 	}
 
-	// Request the page from the owner
-	page, err := mm.requestRemotePage(ctx, ownerID, arrayID, pageID, version)
+	// Sequential readers will want the following pages next
+	if moved && array.streaming(previous, pageID) {
+		defer mm.prefetch(array, pageID)
+	}
+
+	// Serve a cached copy if it is recent enough; read-only pages never go
+	// stale. One cached past the max age is first checked against the owner.
+	cached, ok := mm.getCached(array, pageID, moved)
+	if ok && array.ReadOnly {
+		return cached, nil
+	}
+	current := ok && cached.committedVersion() >= version
+	aged := current && mm.cache.Expired(arrayID, pageID)
+	if current && !aged {
+		return cached, nil
+	}
+
+	if !aged {
+		cached = nil
+	}
+	page, err := mm.fetchFromOwner(ctx, ownerID, arrayID, pageID, version, cached)
 	if err != nil {
-		return nil, fmt.Errorf("failed to request remote page: %w", err)
+		// Replicated pages can still be read from the owner's replicas while
+		// the owner is unreachable
+		replicas := mm.pageReplicas(array, pageID)
+		if len(replicas) < 2 {
+			return nil, fmt.Errorf("failed to request remote page: %w", err)
+		}
+		mm.logger.Debug("reading page from replicas", "array_id", arrayID, "page_id", pageID, "error", err)
+		if page, err = mm.ReadReplicas(ctx, arrayID, pageID, replicas[1:]); err != nil {
+			return nil, err
+		}
 	}
 
+	if page != cached {
+		mm.cache.Put(arrayID, pageID, page)
+	}
 	return page, nil
 }
 
-// getLocalPage retrieves a page from local storage
-func (mm *MemoryManager) getLocalPage(ctx context.Context, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+// fetchFromOwner requests a page from its remote owner. An aged cached copy
+// is returned instead if the owner confirms it is still current.
+func (mm *MemoryManager) fetchFromOwner(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version, aged *Page) (*Page, error) {
+	// Wait for a request slot to the owner
+	mm.mu.RLock()
+	inflight := mm.inflight
+	mm.mu.RUnlock()
+
+	if err := inflight.acquire(ctx, ownerID); err != nil {
+		return nil, fmt.Errorf("failed waiting for request slot to %s: %w", ownerID, err)
+	}
+	defer inflight.release(ownerID)
+
+	if aged != nil {
+		current, err := mm.checkRemote(ctx, ownerID, arrayID, pageID)
+		if err == nil && current == aged.committedVersion() {
+			mm.cache.Revalidate(arrayID, pageID)
+			return aged, nil
+		}
+		if err != nil {
+			mm.logger.Debug("failed to revalidate cached page", "array_id", arrayID, "page_id", pageID, "error", err)
+		}
+	}
+
+	return mm.fetchRemote(ctx, ownerID, arrayID, pageID, version)
+}
+
+// OwnsPages returns true if the local node owns every page from first to last inclusive
+func (mm *MemoryManager) OwnsPages(arrayID ArrayID, first, last PageID) bool {
+	mm.mu.RLock()
+	array, exists := mm.arrays[arrayID]
+	mm.mu.RUnlock()
+	if !exists {
+		return false
+	}
+
+	localID := mm.bus.LocalNode().ID
+	for pageID := first; pageID <= last; pageID++ {
+		owner, exists := array.GetPageOwner(pageID)
+		if !exists || owner != localID {
+			return false
+		}
+	}
+	return true
+}
+
+// getLocalPage retrieves a page from local storage. A page that doesn't
+// exist yet is created if materialize is set, and read as the zero page otherwise.
+func (mm *MemoryManager) getLocalPage(ctx context.Context, arrayID ArrayID, pageID PageID, version Version, materialize bool) (*Page, error) {
 	mm.logger.Debug("retrieving local page", "array_id", arrayID, "page_id", pageID)
 
 	// Check if page exists in local storage
@@ -217,32 +992,157 @@ func (mm *MemoryManager) getLocalPage(ctx context.Context, arrayID ArrayID, page
 	mm.mu.RUnlock()
 
 	if !exists {
-		// Create a new page
-		page = NewPage(pageID, version)
+		if !materialize {
+			return mm.zeroPage(), nil
+		}
 
-		// Store it
 		mm.mu.Lock()
-		mm.pages[key] = page
+		// Another caller may have created the page meanwhile
+		if page, exists = mm.pages[key]; !exists {
+			page = NewPage(pageID, version)
+			mm.putPageLocked(key, page)
+		}
 		mm.mu.Unlock()
 	}
 
 	return page, nil
 }
 
-// requestRemotePage requests a page from a remote node
-func (mm *MemoryManager) requestRemotePage(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
-	mm.logger.Debug("requesting remote page",
-		"owner_id", ownerID,
-		"array_id", arrayID,
-		"page_id", pageID)
+// zeroPage returns the all-zero page shared by reads of unmaterialized pages
+func (mm *MemoryManager) zeroPage() *Page {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
 
-	// Create a PageRequest message
-	// Send it to the owner node
-	// Wait for the PageResponse
-	// Decode and return the page
+	if mm.zero == nil {
+		mm.zero = NewPage(0, 0)
+	}
+	return mm.zero
+}
 
-	// Return a new page for now
-	page := NewPage(pageID, version)
+// putPageLocked stores a local page and marks it present in its array. The
+// caller must hold mm.mu.
+func (mm *MemoryManager) putPageLocked(key pageKey, page *Page) {
+	mm.pages[key] = page
+	if array, exists := mm.arrays[key.arrayID]; exists {
+		array.markPresent(key.pageID)
+	}
+}
+
+// PageEpoch returns the ownership epoch of a page, 0 for unknown arrays
+func (mm *MemoryManager) PageEpoch(arrayID ArrayID, pageID PageID) Epoch {
+	mm.mu.RLock()
+	array, exists := mm.arrays[arrayID]
+	mm.mu.RUnlock()
+	if !exists {
+		return 0
+	}
+	return array.PageEpoch(pageID)
+}
+
+// ErrVersionConflict is returned when a page changed since a writer read it
+var ErrVersionConflict = errors.New("page version conflict")
+
+// PageVersion returns the current version of a locally owned page
+func (mm *MemoryManager) PageVersion(ctx context.Context, arrayID ArrayID, pageID PageID) (Version, error) {
+	array, err := mm.GetArray(ctx, arrayID)
+	if err != nil {
+		return 0, err
+	}
+	if !mm.OwnsPages(arrayID, pageID, pageID) {
+		return 0, fmt.Errorf("page %d of array %s is not owned locally", pageID, arrayID)
+	}
+
+	mm.mu.RLock()
+	page, exists := mm.pages[pageKey{arrayID: arrayID, pageID: pageID}]
+	mm.mu.RUnlock()
+	if exists {
+		return page.committedVersion(), nil
+	}
+	// Pages not yet materialized are at the array's version
+	return array.Version, nil
+}
+
+// CommitPage bumps the version of a locally owned page if it is still at
+// expected, returning ErrVersionConflict if another writer committed first
+func (mm *MemoryManager) CommitPage(ctx context.Context, arrayID ArrayID, pageID PageID, expected Version) (Version, error) {
+	page, err := mm.committedPage(ctx, arrayID, pageID, expected)
+	if err != nil {
+		return 0, err
+	}
+
+	page.commit.Lock()
+	defer page.commit.Unlock()
+
+	if page.Version != expected {
+		return page.Version, fmt.Errorf("%w: page %d is at version %d, expected %d", ErrVersionConflict, pageID, page.Version, expected)
+	}
+	if err := mm.writePage(arrayID, page, nil, true); err != nil {
+		return page.Version, err
+	}
+	return page.Version, nil
+}
+
+// CommitPageData replaces the contents of a locally owned page with data
+// and bumps its version if it is still at expected, returning
+// ErrVersionConflict if another writer committed first. The version check,
+// logging the change to the WAL and the copy happen as one step, so no
+// concurrent commit of the page can slip in between them.
+func (mm *MemoryManager) CommitPageData(ctx context.Context, arrayID ArrayID, pageID PageID, expected Version, data []byte) (Version, error) {
+	if len(data) != PageSize {
+		return 0, fmt.Errorf("page data is %d bytes, want %d", len(data), PageSize)
+	}
+	page, err := mm.committedPage(ctx, arrayID, pageID, expected)
+	if err != nil {
+		return 0, err
+	}
+
+	page.commit.Lock()
+	defer page.commit.Unlock()
+
+	if page.Version != expected {
+		return page.Version, fmt.Errorf("%w: page %d is at version %d, expected %d", ErrVersionConflict, pageID, page.Version, expected)
+	}
+	if err := mm.writePage(arrayID, page, data, true); err != nil {
+		return page.Version, err
+	}
+	return page.Version, nil
+}
+
+// PageContents returns the version of a locally owned page together with a
+// copy of the contents committed at that version
+func (mm *MemoryManager) PageContents(ctx context.Context, arrayID ArrayID, pageID PageID) (Version, []byte, error) {
+	array, err := mm.GetArray(ctx, arrayID)
+	if err != nil {
+		return 0, nil, err
+	}
+	page, err := mm.committedPage(ctx, arrayID, pageID, array.Version)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	version, data := page.snapshot()
+	return version, data, nil
+}
+
+// committedPage returns a locally owned page for a commit, materializing it
+// at version if it doesn't exist yet
+func (mm *MemoryManager) committedPage(ctx context.Context, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+	if _, err := mm.GetArray(ctx, arrayID); err != nil {
+		return nil, err
+	}
+	if !mm.OwnsPages(arrayID, pageID, pageID) {
+		return nil, fmt.Errorf("page %d of array %s is not owned locally", pageID, arrayID)
+	}
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	key := pageKey{arrayID: arrayID, pageID: pageID}
+	page, exists := mm.pages[key]
+	if !exists {
+		page = NewPage(pageID, version)
+		mm.putPageLocked(key, page)
+	}
 	return page, nil
 }
 
@@ -251,7 +1151,7 @@ func (mm *MemoryManager) storePage(ctx context.Context, arrayID ArrayID, pageID
 	key := pageKey{arrayID: arrayID, pageID: pageID}
 
 	mm.mu.Lock()
-	mm.pages[key] = page
+	mm.putPageLocked(key, page)
 	mm.mu.Unlock()
 
 	mm.logger.Debug("stored page locally", "array_id", arrayID, "page_id", pageID)
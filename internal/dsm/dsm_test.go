@@ -2,8 +2,13 @@ package dsm
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/melihxz/holocompute/internal/hyperbus"
 	"github.com/melihxz/holocompute/internal/log"
@@ -28,6 +33,21 @@ func TestArray_PageCount(t *testing.T) {
 	// 10000000 * 8 = 80000000 bytes
 	// 80000000 / (64 * 1024) = 1220.703125, rounded up to 1221
 	assert.Equal(t, 1221, array2.PageCount())
+
+	// A float32 array of the same length needs half the bytes
+	// 10000000 * 4 = 40000000 bytes
+	// 40000000 / (64 * 1024) = 610.3515625, rounded up to 611
+	array3 := newTypedArray(10000000, ElementFloat32)
+	assert.Equal(t, 4, array3.ElementSize)
+	assert.Equal(t, 611, array3.PageCount())
+
+	// Half precision halves it again
+	array4 := newTypedArray(10000000, ElementFloat16)
+	assert.Equal(t, 306, array4.PageCount())
+
+	// Lengths that exactly fill their pages need no extra page
+	array5 := newTypedArray(2*PageSize/4, ElementFloat32)
+	assert.Equal(t, 2, array5.PageCount())
 }
 
 func TestArray_PageOwner(t *testing.T) {
@@ -147,3 +167,592 @@ func TestPageCache_Eviction(t *testing.T) {
 	_, exists := cache.Get(arrayID, 0)
 	assert.False(t, exists)
 }
+
+func TestPageCache_SpillsEvictedPages(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	store, err := NewSpillStore(t.TempDir())
+	assert.NoError(t, err)
+
+	// Room for two pages in memory
+	cache := NewPageCache(2, logger)
+	cache.SetSpill(store, 0)
+
+	arrayID := ArrayID("array-1")
+	pages := make([]*Page, 5)
+	for i := range pages {
+		pages[i] = NewPage(PageID(i), Version(i+1))
+		assert.NoError(t, pages[i].SetInt64(0, int64(i*100)))
+		assert.NoError(t, pages[i].SetInt64(PageSize/8-1, -int64(i)))
+		cache.Put(arrayID, PageID(i), pages[i])
+	}
+	assert.Equal(t, 2, cache.Size())
+
+	// Every page comes back with identical bytes, including those on disk
+	for i, want := range pages {
+		got, exists := cache.Get(arrayID, PageID(i))
+		assert.True(t, exists)
+		assert.Equal(t, want.Version, got.Version)
+		assert.Equal(t, want.Bytes(), got.Bytes())
+		assert.LessOrEqual(t, cache.Size(), 2)
+	}
+
+	// Removed pages are gone from memory and disk
+	cache.Remove(arrayID, 0)
+	cache.Remove(arrayID, 4)
+	_, exists := cache.Get(arrayID, 0)
+	assert.False(t, exists)
+	_, exists = cache.Get(arrayID, 4)
+	assert.False(t, exists)
+}
+
+func TestPageCache_SpillThreshold(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	dir := t.TempDir()
+	store, err := NewSpillStore(dir)
+	assert.NoError(t, err)
+
+	// Capacity allows ten pages but the threshold only three
+	cache := NewPageCache(10, logger)
+	cache.SetSpill(store, 3*PageSize)
+
+	arrayID := ArrayID("array-1")
+	for i := 0; i < 6; i++ {
+		page := NewPage(PageID(i), 1)
+		assert.NoError(t, page.SetInt64(1, int64(i)))
+		cache.Put(arrayID, PageID(i), page)
+	}
+	assert.Equal(t, 3, cache.Size())
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	// Reloading a page takes its file off disk
+	page, exists := cache.Get(arrayID, 0)
+	assert.True(t, exists)
+	v, err := page.GetInt64(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), v)
+	_, err = os.Stat(store.path(cacheKey{arrayID: arrayID, pageID: 0}))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, 3, cache.Size())
+}
+
+func TestMemoryManager_CacheMaxAgeRevalidates(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	mm := NewMemoryManager(&hyperbus.Bus{}, logger)
+	mm.SetCacheMaxAge(time.Minute)
+
+	now := time.Unix(1000, 0)
+	mm.cache.now = func() time.Time { return now }
+
+	// The remote owner's page starts at version 1
+	var fetches, checks int
+	ownerVersion := Version(1)
+	mm.fetchRemote = func(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+		fetches++
+		return NewPage(pageID, ownerVersion), nil
+	}
+	mm.checkRemote = func(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID) (Version, error) {
+		checks++
+		return ownerVersion, nil
+	}
+
+	array, err := mm.CreateArray(context.TODO(), 100, WithPlacement([]hyperbus.NodeID{"remote-node"}))
+	assert.NoError(t, err)
+
+	_, err = mm.RequestPage(context.TODO(), array.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+
+	// A young entry is served without asking the owner
+	now = now.Add(30 * time.Second)
+	_, err = mm.RequestPage(context.TODO(), array.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+	assert.Equal(t, 0, checks)
+
+	// An aged entry whose version still matches is revalidated, not refetched
+	now = now.Add(time.Minute)
+	page, err := mm.RequestPage(context.TODO(), array.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, Version(1), page.Version)
+	assert.Equal(t, 1, fetches)
+	assert.Equal(t, 1, checks)
+
+	// Revalidation restarted its age
+	_, err = mm.RequestPage(context.TODO(), array.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, checks)
+
+	// Once the owner's version moved on, the aged entry is refetched
+	ownerVersion = 2
+	now = now.Add(2 * time.Minute)
+	page, err = mm.RequestPage(context.TODO(), array.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, Version(2), page.Version)
+	assert.Equal(t, 2, checks)
+	assert.Equal(t, 2, fetches)
+}
+
+func TestPageCache_StreamingReadsEvictedFirst(t *testing.T) {
+	cache := NewPageCache(2, log.New(slog.LevelDebug))
+	arrayID := ArrayID("stream")
+
+	cache.Put(arrayID, 0, NewPage(0, 1))
+	cache.Put(arrayID, 1, NewPage(1, 1))
+
+	// Page 1 was consumed, so it goes before the unread page 0
+	_, exists := cache.GetStreaming(arrayID, 1)
+	assert.True(t, exists)
+	cache.Put(arrayID, 2, NewPage(2, 1))
+
+	assert.True(t, cache.Contains(arrayID, 0))
+	assert.False(t, cache.Contains(arrayID, 1))
+	assert.True(t, cache.Contains(arrayID, 2))
+}
+
+func TestMemoryManager_AccessPatternPrefetch(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		pattern  AccessPattern
+		reads    []PageID
+		prefetch bool
+	}{
+		{"sequential", AccessSequential, []PageID{0}, true},
+		{"random", AccessRandom, []PageID{0, 1}, false},
+		{"unspecified", AccessUnspecified, []PageID{0}, false},
+		{"unspecified moving on", AccessUnspecified, []PageID{0, 1}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mm := NewMemoryManager(&hyperbus.Bus{}, log.New(slog.LevelDebug))
+
+			var mu sync.Mutex
+			fetched := make(map[PageID]int)
+			mm.fetchRemote = func(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+				mu.Lock()
+				fetched[pageID]++
+				mu.Unlock()
+				return NewPage(pageID, 1), nil
+			}
+			fetchedCount := func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(fetched)
+			}
+
+			array, err := mm.CreateArray(context.TODO(), 20*PageSize/8,
+				WithPlacement([]hyperbus.NodeID{"remote-node"}), WithAccessPattern(tc.pattern))
+			assert.NoError(t, err)
+
+			for _, pageID := range tc.reads {
+				_, err = mm.RequestPage(context.TODO(), array.ID, pageID, 0)
+				assert.NoError(t, err)
+			}
+			last := tc.reads[len(tc.reads)-1]
+
+			if !tc.prefetch {
+				time.Sleep(50 * time.Millisecond)
+				assert.Equal(t, len(tc.reads), fetchedCount())
+				return
+			}
+
+			// The following pages arrive in the cache without being requested
+			assert.Eventually(t, func() bool { return fetchedCount() == int(last)+1+prefetchDepth }, time.Second, time.Millisecond)
+			assert.Eventually(t, func() bool { return mm.cache.Contains(array.ID, last+prefetchDepth) }, time.Second, time.Millisecond)
+
+			// Reading a prefetched page doesn't fetch it again
+			_, err = mm.RequestPage(context.TODO(), array.ID, last+1, 0)
+			assert.NoError(t, err)
+			mu.Lock()
+			assert.Equal(t, 1, fetched[last+1])
+			mu.Unlock()
+		})
+	}
+}
+
+func TestMemoryManager_PrefetchOncePerPage(t *testing.T) {
+	mm := NewMemoryManager(&hyperbus.Bus{}, log.New(slog.LevelDebug))
+
+	// Only page 0 can be fetched, so every prefetch attempt fails
+	var attempts atomic.Int32
+	mm.fetchRemote = func(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+		if pageID == 0 {
+			return NewPage(pageID, 1), nil
+		}
+		attempts.Add(1)
+		return nil, errors.New("unreachable")
+	}
+
+	array, err := mm.CreateArray(context.TODO(), 20*PageSize/8,
+		WithPlacement([]hyperbus.NodeID{"remote-node"}), WithAccessPattern(AccessSequential))
+	assert.NoError(t, err)
+
+	_, err = mm.RequestPage(context.TODO(), array.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return attempts.Load() == prefetchDepth }, time.Second, time.Millisecond)
+
+	// Further element reads of the same page don't prefetch again
+	for i := 0; i < 10; i++ {
+		_, err = mm.RequestPage(context.TODO(), array.ID, 0, 0)
+		assert.NoError(t, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(prefetchDepth), attempts.Load())
+}
+
+func TestPageCache_PeekKeepsPlace(t *testing.T) {
+	cache := NewPageCache(2, log.New(slog.LevelDebug))
+	arrayID := ArrayID("peek")
+
+	cache.Put(arrayID, 0, NewPage(0, 1))
+	cache.Put(arrayID, 1, NewPage(1, 1))
+
+	// Peeking at page 0 doesn't promote it over page 1
+	_, exists := cache.Peek(arrayID, 0)
+	assert.True(t, exists)
+	cache.Put(arrayID, 2, NewPage(2, 1))
+
+	assert.False(t, cache.Contains(arrayID, 0))
+	assert.True(t, cache.Contains(arrayID, 1))
+	assert.True(t, cache.Contains(arrayID, 2))
+}
+
+// staticLiveness reports a fixed set of nodes as alive
+type staticLiveness map[hyperbus.NodeID]bool
+
+func (s staticLiveness) IsAlive(nodeID hyperbus.NodeID) bool {
+	return s[nodeID]
+}
+
+func TestMemoryManager_CreateArrayWithPlacement(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := &hyperbus.Bus{} // Mock bus
+
+	// Create memory manager
+	mm := NewMemoryManager(bus, logger)
+	mm.SetLivenessChecker(staticLiveness{"node-a": true, "node-b": true, "node-c": true})
+
+	// 5 pages of 8-byte elements, pinned to three nodes
+	length := 5 * PageSize / 8
+	placement := []hyperbus.NodeID{"node-a", "node-b", "node-c"}
+	array, err := mm.CreateArray(context.TODO(), length, WithPlacement(placement))
+	assert.NoError(t, err)
+
+	// Pages are assigned round-robin
+	expected := map[PageID]hyperbus.NodeID{
+		0: "node-a",
+		1: "node-b",
+		2: "node-c",
+		3: "node-a",
+		4: "node-b",
+	}
+	assert.Equal(t, expected, array.PageMapping)
+}
+
+func TestMemoryManager_CreateArrayWithDeadPlacement(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := &hyperbus.Bus{} // Mock bus
+
+	// Create memory manager
+	mm := NewMemoryManager(bus, logger)
+	mm.SetLivenessChecker(staticLiveness{"node-a": true})
+
+	// Placement on a node that isn't alive is rejected
+	_, err := mm.CreateArray(context.TODO(), 1000, WithPlacement([]hyperbus.NodeID{"node-a", "node-dead"}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "node-dead")
+}
+
+func TestMemoryManager_MaxInflightPerNode(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := &hyperbus.Bus{} // Mock bus
+
+	// Create memory manager with a cap of 3 requests per owner
+	mm := NewMemoryManager(bus, logger)
+	mm.SetMaxInflightPerNode(3)
+
+	// Track concurrent fetches to the owner
+	var current, peak int32
+	mm.fetchRemote = func(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		return NewPage(pageID, version), nil
+	}
+
+	// All pages live on one remote owner
+	array, err := mm.CreateArray(context.TODO(), 20*PageSize/8, WithPlacement([]hyperbus.NodeID{"remote-node"}))
+	assert.NoError(t, err)
+
+	// Request every page concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < array.NumPages; i++ {
+		wg.Add(1)
+		go func(pageID PageID) {
+			defer wg.Done()
+			_, err := mm.RequestPage(context.TODO(), array.ID, pageID, 1)
+			assert.NoError(t, err)
+		}(PageID(i))
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(3))
+	assert.Greater(t, atomic.LoadInt32(&peak), int32(0))
+}
+
+func TestMemoryManager_CreateArrayWithAffinity(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := &hyperbus.Bus{} // Mock bus
+
+	// Create memory manager
+	mm := NewMemoryManager(bus, logger)
+
+	// Input array spread over three nodes
+	length := 7 * PageSize / 8
+	input, err := mm.CreateArray(context.TODO(), length, WithPlacement([]hyperbus.NodeID{"node-a", "node-b", "node-c"}))
+	assert.NoError(t, err)
+
+	// Output array co-located with the input
+	output, err := mm.CreateArray(context.TODO(), length, WithAffinity(input.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, input.PageCount(), output.PageCount())
+	for i := 0; i < input.PageCount(); i++ {
+		want, _ := input.GetPageOwner(PageID(i))
+		got, exists := output.GetPageOwner(PageID(i))
+		assert.True(t, exists)
+		assert.Equal(t, want, got)
+	}
+
+	// With smaller elements each page covers two input pages, and follows
+	// the one holding its first element
+	narrow, err := mm.CreateArray(context.TODO(), length, WithAffinity(input.ID), WithElementType(ElementFloat32))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, narrow.PageCount())
+	for i := 0; i < narrow.PageCount(); i++ {
+		want, _ := input.GetPageOwner(PageID(2 * i))
+		got, _ := narrow.GetPageOwner(PageID(i))
+		assert.Equal(t, want, got)
+	}
+
+	// Mismatched lengths are rejected
+	_, err = mm.CreateArray(context.TODO(), length+1, WithAffinity(input.ID))
+	assert.Error(t, err)
+
+	// Unknown arrays are rejected
+	_, err = mm.CreateArray(context.TODO(), length, WithAffinity("missing"))
+	assert.Error(t, err)
+}
+
+func TestMemoryManager_RequestPageOutOfRange(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	mm := NewMemoryManager(bus, logger)
+
+	array, err := mm.CreateArray(context.TODO(), 3*PageSize/8, WithPlacement([]hyperbus.NodeID{"node-1"}))
+	assert.NoError(t, err)
+
+	// The last page is in range
+	_, err = mm.RequestPage(context.TODO(), array.ID, 2, array.Version)
+	assert.NoError(t, err)
+
+	for _, pageID := range []PageID{-1, 3, 100} {
+		_, err = mm.RequestPage(context.TODO(), array.ID, pageID, array.Version)
+
+		var rangeErr *ErrPageOutOfRange
+		assert.ErrorAs(t, err, &rangeErr)
+		assert.Equal(t, pageID, rangeErr.PageID)
+		assert.Equal(t, 3, rangeErr.NumPages)
+	}
+}
+
+func TestMemoryManager_CommitPage(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	mm := NewMemoryManager(bus, logger)
+
+	array, err := mm.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-1"}))
+	assert.NoError(t, err)
+
+	seen, err := mm.PageVersion(context.TODO(), array.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, array.Version, seen)
+
+	committed, err := mm.CommitPage(context.TODO(), array.ID, 0, seen)
+	assert.NoError(t, err)
+	assert.Equal(t, seen+1, committed)
+
+	// A second commit from the same snapshot lost the race
+	_, err = mm.CommitPage(context.TODO(), array.ID, 0, seen)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	current, err := mm.PageVersion(context.TODO(), array.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, committed, current)
+}
+
+func TestMemoryManager_AppendIndex(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-a"}, nil, logger)
+	mm := NewMemoryManager(bus, logger)
+	mm.SetLivenessChecker(staticLiveness{"node-a": true, "node-b": true})
+
+	// One full page on each node
+	elementsPerPage := PageSize / 8
+	array, err := mm.CreateArray(context.TODO(), 2*elementsPerPage, WithPlacement([]hyperbus.NodeID{"node-a", "node-b"}))
+	assert.NoError(t, err)
+
+	// The first append doubles the pages, following the existing owners
+	i, err := mm.AppendIndex(context.TODO(), array.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*elementsPerPage, i)
+	assert.Equal(t, 2*elementsPerPage+1, array.Len())
+	assert.Equal(t, 4, array.PageCount())
+	for pageID, want := range map[PageID]hyperbus.NodeID{2: "node-a", 3: "node-b"} {
+		owner, exists := array.GetPageOwner(pageID)
+		assert.True(t, exists)
+		assert.Equal(t, want, owner)
+	}
+
+	// Later appends fit in the new pages
+	i, err = mm.AppendIndex(context.TODO(), array.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*elementsPerPage+1, i)
+	assert.Equal(t, 4, array.PageCount())
+
+	readOnly, err := mm.CreateArray(context.TODO(), 1, WithReadOnly())
+	assert.NoError(t, err)
+	_, err = mm.AppendIndex(context.TODO(), readOnly.ID)
+	assert.Error(t, err)
+}
+
+func TestMemoryManager_AppendIndexAnnouncesGrowth(t *testing.T) {
+	nodes := newMeshNodes(t, "node-a", "node-b")
+	a, b := nodes["node-a"], nodes["node-b"]
+
+	elementsPerPage := PageSize / 8
+	array, err := a.CreateArray(context.TODO(), 2*elementsPerPage, WithPlacement([]hyperbus.NodeID{"node-a", "node-b"}))
+	assert.NoError(t, err)
+	bArray := shareCopy(t, array, b)
+
+	// node-b learns of the pages node-a appended, including the one it owns
+	i, err := a.AppendIndex(context.TODO(), array.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, bArray.PageCount())
+	assert.Equal(t, i+1, bArray.Len())
+	owner, exists := bArray.GetPageOwner(3)
+	assert.True(t, exists)
+	assert.Equal(t, hyperbus.NodeID("node-b"), owner)
+
+	// and serves it to node-a
+	writeFirst(t, b, array.ID, 3, 42)
+	assert.Equal(t, int64(42), readFirst(t, a, array.ID, 3))
+}
+
+func TestArray_PageAndOffset(t *testing.T) {
+	array := NewArray(3 * PageSize / 8)
+
+	pageID, offset := array.PageAndOffset(0)
+	assert.Equal(t, PageID(0), pageID)
+	assert.Equal(t, 0, offset)
+
+	pageID, offset = array.PageAndOffset(PageSize/8 - 1)
+	assert.Equal(t, PageID(0), pageID)
+	assert.Equal(t, PageSize/8-1, offset)
+
+	pageID, offset = array.PageAndOffset(2*PageSize/8 + 5)
+	assert.Equal(t, PageID(2), pageID)
+	assert.Equal(t, 5, offset)
+}
+
+func TestPageCache_Stats(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	cache := NewPageCache(4, logger)
+	cache.Put("array", 0, NewPage(0, 1))
+
+	cache.Get("array", 0)
+	cache.Get("array", 0)
+	cache.Get("array", 1)
+
+	hits, misses := cache.Stats()
+	assert.Equal(t, int64(2), hits)
+	assert.Equal(t, int64(1), misses)
+}
+
+func TestMemoryManager_TouchArray(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	mm := NewMemoryManager(bus, logger)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	mm.now = clock.Now
+
+	placement := WithPlacement([]hyperbus.NodeID{"node-1"})
+	active, err := mm.CreateArray(context.TODO(), PageSize/8, placement)
+	assert.NoError(t, err)
+	idle, err := mm.CreateArray(context.TODO(), PageSize/8, placement)
+	assert.NoError(t, err)
+	assert.Equal(t, clock.now, active.LastAccess())
+
+	// Every page request counts as access
+	for i := 0; i < 2; i++ {
+		clock.now = clock.now.Add(time.Minute)
+		_, err = mm.RequestPage(context.TODO(), active.ID, 0, active.Version)
+		assert.NoError(t, err)
+		assert.Equal(t, clock.now, active.LastAccess())
+	}
+
+	// The idle array keeps its creation time
+	assert.Equal(t, time.Unix(1000, 0), idle.LastAccess())
+	assert.True(t, idle.LastAccess().Before(active.LastAccess()))
+
+	// Array metadata carries the access time
+	info := arrayToProto(active)
+	assert.Equal(t, clock.now.UnixNano(), info.LastAccess)
+	decoded, err := arrayFromProto(info)
+	assert.NoError(t, err)
+	assert.Equal(t, clock.now, decoded.LastAccess())
+}
+
+func TestMemoryManager_ConcurrentCommitsConflict(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	mm := NewMemoryManager(bus, logger)
+
+	array, err := mm.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-1"}))
+	assert.NoError(t, err)
+
+	for round := 0; round < 50; round++ {
+		seen, err := mm.PageVersion(context.TODO(), array.ID, 0)
+		assert.NoError(t, err)
+
+		// Two writers that read the same version race to commit
+		var wg sync.WaitGroup
+		var conflicts atomic.Int32
+		for writer := 0; writer < 2; writer++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				data := make([]byte, PageSize)
+				data[0] = byte(writer)
+				_, err := mm.CommitPageData(context.TODO(), array.ID, 0, seen, data)
+				if errors.Is(err, ErrVersionConflict) {
+					conflicts.Add(1)
+				} else {
+					assert.NoError(t, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), conflicts.Load())
+		current, err := mm.PageVersion(context.TODO(), array.ID, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, seen+1, current)
+	}
+}
@@ -0,0 +1,99 @@
+package dsm
+
+import "math"
+
+// float32ToFloat16 converts a float32 to IEEE 754 half precision, rounding to nearest even
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	// NaN and infinity
+	if exp == 0xff {
+		if mant != 0 {
+			// Keep the top payload bits and force a quiet NaN
+			return sign | 0x7e00 | uint16(mant>>13)
+		}
+		return sign | 0x7c00
+	}
+
+	e := exp - 127 + 15
+	switch {
+	case e >= 0x1f:
+		// Overflow rounds to infinity
+		return sign | 0x7c00
+
+	case e <= 0:
+		// Too small even for a subnormal
+		if e < -10 {
+			return sign
+		}
+
+		// Subnormal: shift the mantissa, including its implicit bit, into place
+		mant |= 0x800000
+		shift := uint(14 - e)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++ // may carry into the smallest normal
+		}
+		return sign | uint16(half)
+
+	default:
+		half := uint32(e)<<10 | mant>>13
+		rem := mant & 0x1fff
+		if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+			half++ // may carry into the exponent, or up to infinity
+		}
+		return sign | uint16(half)
+	}
+}
+
+// float16ToFloat32 converts an IEEE 754 half precision value to float32 exactly
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+
+		// Normalize the subnormal
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		mant &= 0x3ff
+		return math.Float32frombits(sign | e<<23 | mant<<13)
+
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+
+	default:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+	}
+}
+
+// float32ToBFloat16 converts a float32 to bfloat16, rounding to nearest even
+func float32ToBFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+
+	// Keep NaNs quiet; rounding could otherwise turn them into infinity
+	if bits&0x7fffffff > 0x7f800000 {
+		return uint16(bits>>16) | 0x40
+	}
+
+	bits += 0x7fff + (bits>>16)&1
+	return uint16(bits >> 16)
+}
+
+// bfloat16ToFloat32 converts a bfloat16 value to float32 exactly
+func bfloat16ToFloat32(b uint16) float32 {
+	return math.Float32frombits(uint32(b) << 16)
+}
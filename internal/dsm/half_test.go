@@ -0,0 +1,103 @@
+package dsm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat16_RoundTrip(t *testing.T) {
+	// Values exactly representable in half precision
+	for _, v := range []float32{0, 1, -1, 0.5, 2, 65504, -65504, 0.333251953125, 6.103515625e-05} {
+		assert.Equal(t, v, float16ToFloat32(float32ToFloat16(v)), "value %v", v)
+	}
+
+	// Signed zero is preserved
+	negZero := float32(math.Copysign(0, -1))
+	assert.Equal(t, uint16(0x8000), float32ToFloat16(negZero))
+
+	// Rounding to nearest
+	assert.Equal(t, float32(0.333251953125), float16ToFloat32(float32ToFloat16(1.0/3)))
+	assert.Equal(t, float32(3.140625), float16ToFloat32(float32ToFloat16(3.14159)))
+}
+
+func TestFloat16_Boundaries(t *testing.T) {
+	// Smallest subnormal, largest subnormal, smallest normal
+	assert.Equal(t, uint16(0x0001), float32ToFloat16(5.960464477539063e-08))
+	assert.Equal(t, float32(5.960464477539063e-08), float16ToFloat32(0x0001))
+	assert.Equal(t, float32(6.097555160522461e-05), float16ToFloat32(0x03ff))
+	assert.Equal(t, uint16(0x03ff), float32ToFloat16(6.097555160522461e-05))
+	assert.Equal(t, uint16(0x0400), float32ToFloat16(6.103515625e-05))
+
+	// Half the smallest subnormal rounds to even (zero), slightly more rounds up
+	assert.Equal(t, uint16(0x0000), float32ToFloat16(2.9802322387695312e-08))
+	assert.Equal(t, uint16(0x0001), float32ToFloat16(3.0e-08))
+
+	// Values beyond the largest finite half overflow to infinity
+	assert.Equal(t, uint16(0x7bff), float32ToFloat16(65504))
+	assert.Equal(t, uint16(0x7c00), float32ToFloat16(65520))
+	assert.Equal(t, uint16(0xfc00), float32ToFloat16(-1e10))
+
+	// Infinity and NaN
+	inf := float32(math.Inf(1))
+	assert.Equal(t, uint16(0x7c00), float32ToFloat16(inf))
+	assert.True(t, math.IsInf(float64(float16ToFloat32(0x7c00)), 1))
+	assert.True(t, math.IsInf(float64(float16ToFloat32(0xfc00)), -1))
+
+	nan := float32(math.NaN())
+	assert.True(t, math.IsNaN(float64(float16ToFloat32(float32ToFloat16(nan)))))
+}
+
+func TestBFloat16_RoundTrip(t *testing.T) {
+	// Values exactly representable in bfloat16
+	for _, v := range []float32{0, 1, -1, 0.5, 256, 3.140625, 1e-40 /* subnormal */} {
+		b := float32ToBFloat16(v)
+		assert.Equal(t, math.Float32bits(v)>>16, uint32(b), "value %v", v)
+	}
+	assert.Equal(t, float32(3.140625), bfloat16ToFloat32(float32ToBFloat16(3.14159)))
+
+	// Ties round to even
+	assert.Equal(t, uint16(0x3f80), float32ToBFloat16(math.Float32frombits(0x3f808000)))
+	assert.Equal(t, uint16(0x3f82), float32ToBFloat16(math.Float32frombits(0x3f818000)))
+
+	// The largest finite float32 rounds up to infinity
+	assert.Equal(t, uint16(0x7f80), float32ToBFloat16(math.MaxFloat32))
+
+	// Infinity and NaN
+	inf := float32(math.Inf(-1))
+	assert.True(t, math.IsInf(float64(bfloat16ToFloat32(float32ToBFloat16(inf))), -1))
+	nan := math.Float32frombits(0x7f800001) // a NaN that rounding would turn into infinity
+	assert.True(t, math.IsNaN(float64(bfloat16ToFloat32(float32ToBFloat16(nan)))))
+}
+
+func TestPage_HalfPrecision(t *testing.T) {
+	page := NewPage(0, 1)
+
+	assert.NoError(t, page.SetFloat16(0, 1.5))
+	assert.NoError(t, page.SetBFloat16(1, -2.25))
+
+	f, err := page.GetFloat16(0)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(1.5), f)
+
+	b, err := page.GetBFloat16(1)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(-2.25), b)
+
+	// The last 2-byte element fits, the next one doesn't
+	assert.NoError(t, page.SetFloat16(PageSize/2-1, 1))
+	assert.Error(t, page.SetFloat16(PageSize/2, 1))
+}
+
+func TestArray_HalfPrecisionPageCount(t *testing.T) {
+	array := newTypedArray(10000000, ElementFloat16)
+	assert.Equal(t, 2, array.ElementSize)
+
+	// 10000000 * 2 / 65536 = 305.17578125, rounded up to 306
+	assert.Equal(t, 306, array.PageCount())
+
+	pageID, offset := array.PageAndOffset(PageSize/2 + 3)
+	assert.Equal(t, PageID(1), pageID)
+	assert.Equal(t, 3, offset)
+}
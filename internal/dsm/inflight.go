@@ -0,0 +1,57 @@
+package dsm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+)
+
+// DefaultMaxInflightPerNode is the default cap on outstanding page requests to one owner
+const DefaultMaxInflightPerNode = 64
+
+// inflightLimiter caps concurrent outstanding requests per remote node
+type inflightLimiter struct {
+	limit int
+	sems  map[hyperbus.NodeID]chan struct{}
+	mu    sync.Mutex
+}
+
+// newInflightLimiter creates a limiter allowing limit concurrent requests per node
+func newInflightLimiter(limit int) *inflightLimiter {
+	if limit <= 0 {
+		limit = DefaultMaxInflightPerNode
+	}
+	return &inflightLimiter{
+		limit: limit,
+		sems:  make(map[hyperbus.NodeID]chan struct{}),
+	}
+}
+
+// semaphore returns the semaphore for a node, creating it if needed
+func (l *inflightLimiter) semaphore(nodeID hyperbus.NodeID) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sem, exists := l.sems[nodeID]
+	if !exists {
+		sem = make(chan struct{}, l.limit)
+		l.sems[nodeID] = sem
+	}
+	return sem
+}
+
+// acquire blocks until a request slot for the node is free or ctx is done
+func (l *inflightLimiter) acquire(ctx context.Context, nodeID hyperbus.NodeID) error {
+	select {
+	case l.semaphore(nodeID) <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a request slot for the node
+func (l *inflightLimiter) release(nodeID hyperbus.NodeID) {
+	<-l.semaphore(nodeID)
+}
@@ -2,14 +2,29 @@ package dsm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/melihxz/holocompute/internal/deadline"
+	"github.com/melihxz/holocompute/internal/hyperbus"
 	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
 )
 
+// DefaultLeaseTTL is how long a lease lasts unless renewed
+const DefaultLeaseTTL = 30 * time.Second
+
+// DefaultLeaseSweepInterval is how often the sweeper reclaims expired leases
+const DefaultLeaseSweepInterval = 10 * time.Second
+
+// DefaultLeaseAcquireTimeout bounds AcquireLeaseWait when the caller sets no deadline
+const DefaultLeaseAcquireTimeout = 30 * time.Second
+
 // LeaseID uniquely identifies a lease
 type LeaseID string
 
@@ -32,14 +47,100 @@ type Lease struct {
 	Owner     string // Node or client ID
 	ExpiresAt time.Time
 	Version   Version
+	Epoch     Epoch // ownership epoch of the page when the lease was granted
+}
+
+// ErrStaleLease is returned for a lease granted before its page changed owner
+var ErrStaleLease = errors.New("lease from a previous ownership epoch")
+
+// EpochSource reports the current ownership epoch of pages
+type EpochSource interface {
+	// PageEpoch returns the page's ownership epoch, 0 if it never changed owner
+	PageEpoch(arrayID ArrayID, pageID PageID) Epoch
+}
+
+// String returns the name of the lease type
+func (t LeaseType) String() string {
+	switch t {
+	case ReadLease:
+		return "read"
+	case WriteLease:
+		return "write"
+	default:
+		return fmt.Sprintf("LeaseType(%d)", int(t))
+	}
+}
+
+// ToProto converts a Lease to a protobuf LeaseInfo
+func (l *Lease) ToProto() *proto.LeaseInfo {
+	kind := proto.LeaseRequest_READ
+	if l.Type == WriteLease {
+		kind = proto.LeaseRequest_WRITE
+	}
+	return &proto.LeaseInfo{
+		LeaseId:           string(l.ID),
+		ArrayId:           string(l.ArrayID),
+		PageId:            int32(l.PageID),
+		Kind:              kind,
+		Owner:             l.Owner,
+		ExpiresAtUnixNano: l.ExpiresAt.UnixNano(),
+		Version:           int64(l.Version),
+		Epoch:             int64(l.Epoch),
+	}
+}
+
+// LeaseFromProto converts a protobuf LeaseInfo to a Lease
+func LeaseFromProto(p *proto.LeaseInfo) *Lease {
+	leaseType := ReadLease
+	if p.Kind == proto.LeaseRequest_WRITE {
+		leaseType = WriteLease
+	}
+	return &Lease{
+		ID:        LeaseID(p.LeaseId),
+		ArrayID:   ArrayID(p.ArrayId),
+		PageID:    PageID(p.PageId),
+		Type:      leaseType,
+		Owner:     p.Owner,
+		ExpiresAt: time.Unix(0, p.ExpiresAtUnixNano),
+		Version:   Version(p.Version),
+		Epoch:     Epoch(p.Epoch),
+	}
+}
+
+// ErrSharedReadLease is returned when upgrading a read lease other owners also hold
+type ErrSharedReadLease struct {
+	ArrayID ArrayID
+	PageID  PageID
+	Readers []string // the other owners, sorted
+}
+
+// Error implements the error interface
+func (e *ErrSharedReadLease) Error() string {
+	return fmt.Sprintf("cannot upgrade lease on page %d in array %s: also read by %s", e.PageID, e.ArrayID, strings.Join(e.Readers, ", "))
 }
 
 // LeaseManager manages page leases
 type LeaseManager struct {
-	leases map[leaseKey]*Lease
-	ttl    time.Duration
-	logger *log.Logger
-	mu     sync.RWMutex
+	leases  map[leaseKey]*Lease
+	byID    map[LeaseID]leaseKey             // index of leases by ID
+	readers map[leaseKey]map[string]*Lease   // each owner's own lease on pages leased for reading
+	waiters map[leaseKey][]*leaseWaiter      // blocked AcquireLeaseWait calls, oldest first
+	ttl     time.Duration
+	timeout time.Duration   // default deadline for AcquireLeaseWait
+	epochs  EpochSource     // nil if pages never change owner
+	nodeID  hyperbus.NodeID // named in the reports the manager answers queries with
+	logger  *log.Logger
+	mu      sync.RWMutex
+
+	// Background sweeper, if started
+	sweepCancel context.CancelFunc
+	sweepWG     sync.WaitGroup
+	sweepMu     sync.Mutex
+}
+
+// leaseWaiter is an AcquireLeaseWait call queued for a page
+type leaseWaiter struct {
+	wake chan struct{} // signalled when the waiter should retry
 }
 
 // leaseKey uniquely identifies a leased page
@@ -51,10 +152,53 @@ type leaseKey struct {
 // NewLeaseManager creates a new lease manager
 func NewLeaseManager(ttl time.Duration, logger *log.Logger) *LeaseManager {
 	return &LeaseManager{
-		leases: make(map[leaseKey]*Lease),
-		ttl:    ttl,
-		logger: logger,
+		leases:  make(map[leaseKey]*Lease),
+		byID:    make(map[LeaseID]leaseKey),
+		readers: make(map[leaseKey]map[string]*Lease),
+		waiters: make(map[leaseKey][]*leaseWaiter),
+		ttl:     ttl,
+		timeout: DefaultLeaseAcquireTimeout,
+		logger:  logger,
+	}
+}
+
+// SetAcquireTimeout sets the deadline applied to AcquireLeaseWait calls
+// whose context has none. Zero lets them wait indefinitely.
+func (lm *LeaseManager) SetAcquireTimeout(timeout time.Duration) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	lm.timeout = timeout
+}
+
+// SetEpochSource sets where page ownership epochs are read from. Leases are
+// tagged with their page's epoch when granted and become invalid once the
+// page changes owner, so a new owner never has to honour them.
+func (lm *LeaseManager) SetEpochSource(epochs EpochSource) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	lm.epochs = epochs
+}
+
+// SetNodeID sets the node the manager's leases are held on, which answers
+// lease queries on its behalf
+func (lm *LeaseManager) SetNodeID(nodeID hyperbus.NodeID) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	lm.nodeID = nodeID
+}
+
+// epochLocked returns the current ownership epoch of a page. The caller must hold lm.mu.
+func (lm *LeaseManager) epochLocked(key leaseKey) Epoch {
+	if lm.epochs == nil {
+		return 0
 	}
+	return lm.epochs.PageEpoch(key.arrayID, key.pageID)
+}
+
+// validLocked reports whether a lease is unexpired and from its page's
+// current ownership epoch. The caller must hold lm.mu.
+func (lm *LeaseManager) validLocked(key leaseKey, lease *Lease, now time.Time) bool {
+	return !now.After(lease.ExpiresAt) && lease.Epoch >= lm.epochLocked(key)
 }
 
 // AcquireLease attempts to acquire a lease on a page
@@ -62,7 +206,112 @@ func (lm *LeaseManager) AcquireLease(ctx context.Context, arrayID ArrayID, pageI
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
+	return lm.acquireLocked(arrayID, pageID, leaseType, owner, version)
+}
+
+// AcquireLeaseWait acquires a lease on a page like AcquireLease, but on
+// contention waits until the conflicting lease is released, revoked,
+// expires or is cleaned up, or until ctx is done. Waiters on a page are served in FIFO order.
+func (lm *LeaseManager) AcquireLeaseWait(ctx context.Context, arrayID ArrayID, pageID PageID, leaseType LeaseType, owner string, version Version) (*Lease, error) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	ctx, cancel := deadline.WithDefault(ctx, lm.timeout)
+	defer cancel()
+
+	key := leaseKey{arrayID: arrayID, pageID: pageID}
+	waiter := &leaseWaiter{wake: make(chan struct{}, 1)}
+	lm.waiters[key] = append(lm.waiters[key], waiter)
+
+	for {
+		// Only the oldest waiter competes for the lease
+		var expiry *time.Timer
+		var expired <-chan time.Time
+		if lm.waiters[key][0] == waiter {
+			lease, err := lm.acquireLocked(arrayID, pageID, leaseType, owner, version)
+			if err == nil {
+				lm.dequeueLocked(key, waiter)
+				// Readers behind us may be able to share the lease
+				lm.wakeLocked(key)
+				return lease, nil
+			}
+
+			// A holder that never releases still gives way when its lease expires
+			if holder, exists := lm.leases[key]; exists {
+				expiry = time.NewTimer(time.Until(holder.ExpiresAt))
+				expired = expiry.C
+			}
+		}
+
+		lm.mu.Unlock()
+		select {
+		case <-waiter.wake:
+		case <-expired:
+		case <-ctx.Done():
+		}
+		if expiry != nil {
+			expiry.Stop()
+		}
+		lm.mu.Lock()
+
+		if ctx.Err() != nil {
+			lm.dequeueLocked(key, waiter)
+			lm.wakeLocked(key)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// dequeueLocked removes a waiter from a page's queue
+func (lm *LeaseManager) dequeueLocked(key leaseKey, waiter *leaseWaiter) {
+	queue := lm.waiters[key]
+	for i, w := range queue {
+		if w == waiter {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) == 0 {
+		delete(lm.waiters, key)
+		return
+	}
+	lm.waiters[key] = queue
+}
+
+// wakeLocked tells the oldest waiter on a page to retry
+func (lm *LeaseManager) wakeLocked(key leaseKey) {
+	if queue := lm.waiters[key]; len(queue) > 0 {
+		select {
+		case queue[0].wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// acquireLocked grants a lease if it doesn't conflict with the current one
+func (lm *LeaseManager) acquireLocked(arrayID ArrayID, pageID PageID, leaseType LeaseType, owner string, version Version) (*Lease, error) {
 	key := leaseKey{arrayID: arrayID, pageID: pageID}
+	epoch := lm.epochLocked(key)
+
+	// Leases granted before the page changed owner no longer count
+	if existingLease, exists := lm.leases[key]; exists && existingLease.Epoch < epoch {
+		lm.removeLocked(key)
+		lm.logger.Debug("dropped lease from previous ownership epoch",
+			"lease_id", existingLease.ID,
+			"array_id", arrayID,
+			"page_id", pageID,
+			"epoch", existingLease.Epoch,
+			"current_epoch", epoch)
+	}
+
+	// Nor do expired ones the sweeper hasn't cleaned up yet
+	if existingLease, exists := lm.leases[key]; exists && time.Now().After(existingLease.ExpiresAt) {
+		lm.removeLocked(key)
+		lm.logger.Debug("dropped expired lease",
+			"lease_id", existingLease.ID,
+			"array_id", arrayID,
+			"page_id", pageID)
+	}
 
 	// Check if there's an existing lease
 	if existingLease, exists := lm.leases[key]; exists {
@@ -76,11 +325,35 @@ func (lm *LeaseManager) AcquireLease(ctx context.Context, arrayID ArrayID, pageI
 			return nil, fmt.Errorf("read lease exists, cannot acquire write lease for page %d in array %s", pageID, arrayID)
 		}
 
-		// If it's a read lease and we're requesting a read lease, allow (multi-reader)
+		// If it's a read lease and we're requesting a read lease, allow
+		// (multi-reader). Each reader gets a lease of its own, so releasing
+		// it leaves the others' in place.
 		if existingLease.Type == ReadLease && leaseType == ReadLease {
-			// Extend the existing lease
-			existingLease.ExpiresAt = time.Now().Add(lm.ttl)
-			return existingLease, nil
+			expiresAt := time.Now().Add(lm.ttl)
+			existingLease.ExpiresAt = expiresAt
+			if lease, holds := lm.readers[key][owner]; holds {
+				lease.ExpiresAt = expiresAt
+				return lease, nil
+			}
+
+			lease := &Lease{
+				ID:        LeaseID(uuid.New().String()),
+				ArrayID:   arrayID,
+				PageID:    pageID,
+				Type:      ReadLease,
+				Owner:     owner,
+				ExpiresAt: expiresAt,
+				Version:   version,
+				Epoch:     existingLease.Epoch,
+			}
+			lm.readers[key][owner] = lease
+			lm.byID[lease.ID] = key
+			lm.logger.Debug("shared read lease",
+				"lease_id", lease.ID,
+				"array_id", arrayID,
+				"page_id", pageID,
+				"owner", owner)
+			return lease, nil
 		}
 	}
 
@@ -93,9 +366,15 @@ func (lm *LeaseManager) AcquireLease(ctx context.Context, arrayID ArrayID, pageI
 		Owner:     owner,
 		ExpiresAt: time.Now().Add(lm.ttl),
 		Version:   version,
+		Epoch:     epoch,
 	}
 
+	lm.removeLocked(key)
 	lm.leases[key] = lease
+	lm.byID[lease.ID] = key
+	if leaseType == ReadLease {
+		lm.readers[key] = map[string]*Lease{owner: lease}
+	}
 	lm.logger.Debug("acquired lease",
 		"lease_id", lease.ID,
 		"array_id", arrayID,
@@ -106,24 +385,79 @@ func (lm *LeaseManager) AcquireLease(ctx context.Context, arrayID ArrayID, pageI
 	return lease, nil
 }
 
+// UpgradeLease atomically turns owner's read lease on a page into a write
+// lease. It fails with ErrSharedReadLease if other owners hold the read lease
+// too, so no other writer can slip in between releasing and reacquiring.
+func (lm *LeaseManager) UpgradeLease(ctx context.Context, arrayID ArrayID, pageID PageID, owner string) (*Lease, error) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	key := leaseKey{arrayID: arrayID, pageID: pageID}
+	lease, exists := lm.leases[key]
+	if !exists || !lm.validLocked(key, lease, time.Now()) {
+		return nil, fmt.Errorf("no lease to upgrade for page %d in array %s", pageID, arrayID)
+	}
+	if lease.Type == WriteLease {
+		if lease.Owner == owner {
+			return lease, nil
+		}
+		return nil, fmt.Errorf("write lease already exists for page %d in array %s", pageID, arrayID)
+	}
+
+	readers := lm.readers[key]
+	held, holds := readers[owner]
+	if !holds {
+		return nil, fmt.Errorf("%s holds no read lease for page %d in array %s", owner, pageID, arrayID)
+	}
+	if len(readers) > 1 {
+		others := make([]string, 0, len(readers)-1)
+		for reader := range readers {
+			if reader != owner {
+				others = append(others, reader)
+			}
+		}
+		sort.Strings(others)
+		return nil, &ErrSharedReadLease{ArrayID: arrayID, PageID: pageID, Readers: others}
+	}
+
+	// Keep the lease ID so the holder's handle stays valid
+	lease = held
+	lease.Type = WriteLease
+	lease.ExpiresAt = time.Now().Add(lm.ttl)
+	lm.leases[key] = lease
+	delete(lm.readers, key)
+
+	lm.logger.Debug("upgraded lease",
+		"lease_id", lease.ID,
+		"array_id", arrayID,
+		"page_id", pageID,
+		"owner", owner)
+
+	return lease, nil
+}
+
 // ReleaseLease releases a lease
 func (lm *LeaseManager) ReleaseLease(ctx context.Context, leaseID LeaseID) error {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
-	// Find the lease by ID
-	for key, lease := range lm.leases {
-		if lease.ID == leaseID {
-			delete(lm.leases, key)
-			lm.logger.Debug("released lease",
-				"lease_id", leaseID,
-				"array_id", lease.ArrayID,
-				"page_id", lease.PageID)
-			return nil
-		}
+	key, exists := lm.byID[leaseID]
+	if !exists {
+		return fmt.Errorf("lease not found: %s", leaseID)
 	}
 
-	return fmt.Errorf("lease not found: %s", leaseID)
+	// A reader sharing the page gives up only its own lease
+	var lease *Lease
+	if len(lm.readers[key]) > 1 {
+		lease = lm.releaseReaderLocked(key, leaseID)
+	} else {
+		lease = lm.removeLocked(key)
+	}
+	lm.logger.Debug("released lease",
+		"lease_id", leaseID,
+		"array_id", lease.ArrayID,
+		"page_id", lease.PageID)
+	return nil
 }
 
 // ValidateLease checks if a lease is still valid
@@ -131,18 +465,153 @@ func (lm *LeaseManager) ValidateLease(ctx context.Context, leaseID LeaseID) (*Le
 	lm.mu.RLock()
 	defer lm.mu.RUnlock()
 
-	// Find the lease by ID
-	for _, lease := range lm.leases {
-		if lease.ID == leaseID {
-			// Check if expired
-			if time.Now().After(lease.ExpiresAt) {
-				return nil, fmt.Errorf("lease expired: %s", leaseID)
+	key, exists := lm.byID[leaseID]
+	if !exists {
+		return nil, fmt.Errorf("lease not found: %s", leaseID)
+	}
+
+	// Check if expired
+	lease := lm.leaseLocked(key, leaseID)
+	if time.Now().After(lease.ExpiresAt) {
+		return nil, fmt.Errorf("lease expired: %s", leaseID)
+	}
+	if epoch := lm.epochLocked(key); lease.Epoch < epoch {
+		return nil, fmt.Errorf("%w: lease %s was granted in epoch %d, page %d of array %s is at epoch %d", ErrStaleLease, leaseID, lease.Epoch, key.pageID, key.arrayID, epoch)
+	}
+	return lease, nil
+}
+
+// LeasesForArray returns copies of the unexpired leases on an array's pages, ordered by page
+func (lm *LeaseManager) LeasesForArray(arrayID ArrayID) []*Lease {
+	lm.mu.RLock()
+	defer lm.mu.RUnlock()
+
+	now := time.Now()
+	var leases []*Lease
+	for key, lease := range lm.leases {
+		if key.arrayID != arrayID || !lm.validLocked(key, lease, now) {
+			continue
+		}
+		if lease.Type == WriteLease {
+			copied := *lease
+			leases = append(leases, &copied)
+			continue
+		}
+		for _, reader := range lm.readers[key] {
+			if lm.validLocked(key, reader, now) {
+				copied := *reader
+				leases = append(leases, &copied)
 			}
-			return lease, nil
 		}
 	}
 
-	return nil, fmt.Errorf("lease not found: %s", leaseID)
+	sortLeases(leases)
+	return leases
+}
+
+// Report returns the node's unexpired leases on an array for cluster-wide aggregation
+func (lm *LeaseManager) Report(nodeID string, arrayID ArrayID) *proto.LeaseReport {
+	report := &proto.LeaseReport{NodeId: nodeID}
+	for _, lease := range lm.LeasesForArray(arrayID) {
+		report.Leases = append(report.Leases, lease.ToProto())
+	}
+	return report
+}
+
+// HandleMessage answers a LeaseQuery with the node's report on the array
+func (lm *LeaseManager) HandleMessage(ctx context.Context, conn hyperbus.Connection, stream hyperbus.Stream, data []byte) error {
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+	if header.Type != hyperbus.MsgLeaseQuery {
+		return fmt.Errorf("unexpected message type %d", header.Type)
+	}
+
+	var query proto.LeaseQuery
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &query); err != nil {
+		return err
+	}
+
+	lm.mu.RLock()
+	nodeID := lm.nodeID
+	lm.mu.RUnlock()
+
+	msg, err := hyperbus.EncodeReply(data, hyperbus.MsgLeaseReport, lm.Report(string(nodeID), ArrayID(query.ArrayId)))
+	if err != nil {
+		return fmt.Errorf("failed to encode lease report: %w", err)
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// QueryLeases asks a node for its report on the leases held on an array
+func QueryLeases(ctx context.Context, bus *hyperbus.Bus, nodeID hyperbus.NodeID, arrayID ArrayID) (*proto.LeaseReport, error) {
+	data, err := bus.Call(ctx, nodeID, hyperbus.MsgLeaseQuery, &proto.LeaseQuery{ArrayId: string(arrayID)})
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.Type != hyperbus.MsgLeaseReport {
+		return nil, fmt.Errorf("unexpected message type %d in reply to lease query", header.Type)
+	}
+
+	var report proto.LeaseReport
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// MergeLeaseReports combines lease reports from several nodes into a single
+// list ordered by page, dropping leases that have expired since reporting.
+// When nodes report leases on a page from different ownership epochs, as
+// happens while a rebalance moves it, only those from the latest epoch are kept.
+func MergeLeaseReports(reports ...*proto.LeaseReport) []*Lease {
+	now := time.Now()
+	var leases []*Lease
+	latest := make(map[leaseKey]Epoch)
+	for _, report := range reports {
+		for _, info := range report.Leases {
+			lease := LeaseFromProto(info)
+			if now.After(lease.ExpiresAt) {
+				continue
+			}
+			leases = append(leases, lease)
+
+			key := leaseKey{arrayID: lease.ArrayID, pageID: lease.PageID}
+			if epoch, seen := latest[key]; !seen || lease.Epoch > epoch {
+				latest[key] = lease.Epoch
+			}
+		}
+	}
+
+	current := leases[:0]
+	for _, lease := range leases {
+		if lease.Epoch == latest[leaseKey{arrayID: lease.ArrayID, pageID: lease.PageID}] {
+			current = append(current, lease)
+		}
+	}
+
+	sortLeases(current)
+	return current
+}
+
+// sortLeases orders leases by array, page, then owner
+func sortLeases(leases []*Lease) {
+	sort.Slice(leases, func(i, j int) bool {
+		a, b := leases[i], leases[j]
+		if a.ArrayID != b.ArrayID {
+			return a.ArrayID < b.ArrayID
+		}
+		if a.PageID != b.PageID {
+			return a.PageID < b.PageID
+		}
+		return a.Owner < b.Owner
+	})
 }
 
 // HasWriteLease checks if there's a write lease on a page
@@ -156,8 +625,8 @@ func (lm *LeaseManager) HasWriteLease(ctx context.Context, arrayID ArrayID, page
 		return false
 	}
 
-	// Check if expired
-	if time.Now().After(lease.ExpiresAt) {
+	// Check if expired or granted under a previous owner
+	if !lm.validLocked(key, lease, time.Now()) {
 		return false
 	}
 
@@ -175,7 +644,7 @@ func (lm *LeaseManager) RevokeLease(ctx context.Context, arrayID ArrayID, pageID
 		return nil // No lease to revoke
 	}
 
-	delete(lm.leases, key)
+	lm.removeLocked(key)
 	lm.logger.Debug("revoked lease",
 		"lease_id", lease.ID,
 		"array_id", arrayID,
@@ -184,7 +653,8 @@ func (lm *LeaseManager) RevokeLease(ctx context.Context, arrayID ArrayID, pageID
 	return nil
 }
 
-// CleanupExpiredLeases removes expired leases
+// CleanupExpiredLeases removes expired leases and those granted before
+// their page changed owner
 func (lm *LeaseManager) CleanupExpiredLeases(ctx context.Context) {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
@@ -193,15 +663,132 @@ func (lm *LeaseManager) CleanupExpiredLeases(ctx context.Context) {
 	var expired []leaseKey
 
 	for key, lease := range lm.leases {
-		if now.After(lease.ExpiresAt) {
+		if !lm.validLocked(key, lease, now) {
 			expired = append(expired, key)
+			continue
+		}
+
+		// Readers that stopped renewing give up their share
+		for _, reader := range lm.readers[key] {
+			if len(lm.readers[key]) > 1 && !lm.validLocked(key, reader, now) {
+				lm.releaseReaderLocked(key, reader.ID)
+				lm.logger.Debug("cleaned up expired read lease",
+					"lease_id", reader.ID,
+					"array_id", key.arrayID,
+					"page_id", key.pageID,
+					"owner", reader.Owner)
+			}
 		}
 	}
 
 	for _, key := range expired {
-		delete(lm.leases, key)
+		lm.removeLocked(key)
 		lm.logger.Debug("cleaned up expired lease",
 			"array_id", key.arrayID,
 			"page_id", key.pageID)
 	}
 }
+
+// StartSweeper reclaims expired leases every interval until ctx is done or
+// StopSweeper is called. Starting a running sweeper restarts it.
+func (lm *LeaseManager) StartSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultLeaseSweepInterval
+	}
+
+	lm.StopSweeper()
+
+	lm.sweepMu.Lock()
+	defer lm.sweepMu.Unlock()
+
+	ctx, lm.sweepCancel = context.WithCancel(ctx)
+	lm.sweepWG.Add(1)
+	go func() {
+		defer lm.sweepWG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				lm.CleanupExpiredLeases(ctx)
+			}
+		}
+	}()
+}
+
+// StopSweeper stops the sweeper and waits for it to exit
+func (lm *LeaseManager) StopSweeper() {
+	lm.sweepMu.Lock()
+	defer lm.sweepMu.Unlock()
+
+	if lm.sweepCancel != nil {
+		lm.sweepCancel()
+		lm.sweepCancel = nil
+	}
+	lm.sweepWG.Wait()
+}
+
+// removeLocked removes the lease on a page from both maps, returning it.
+// The caller must hold lm.mu.
+func (lm *LeaseManager) removeLocked(key leaseKey) *Lease {
+	lease, exists := lm.leases[key]
+	if !exists {
+		return nil
+	}
+	delete(lm.leases, key)
+	delete(lm.byID, lease.ID)
+	for _, reader := range lm.readers[key] {
+		delete(lm.byID, reader.ID)
+	}
+	delete(lm.readers, key)
+	lm.wakeLocked(key)
+	return lease
+}
+
+// releaseReaderLocked removes one reader's lease from a page other readers
+// still share, returning it. The caller must hold lm.mu.
+func (lm *LeaseManager) releaseReaderLocked(key leaseKey, leaseID LeaseID) *Lease {
+	readers := lm.readers[key]
+	var released *Lease
+	for owner, lease := range readers {
+		if lease.ID == leaseID {
+			released = lease
+			delete(readers, owner)
+			break
+		}
+	}
+	if released == nil {
+		return nil
+	}
+	delete(lm.byID, leaseID)
+
+	// The page's lease lasts as long as the longest held by its readers
+	if lm.leases[key] == released {
+		var next *Lease
+		for _, lease := range readers {
+			if next == nil || lease.ExpiresAt.After(next.ExpiresAt) {
+				next = lease
+			}
+		}
+		lm.leases[key] = next
+	}
+	return released
+}
+
+// leaseLocked returns the lease with an ID on a page, the page's own lease
+// or that of one of its readers. The caller must hold lm.mu.
+func (lm *LeaseManager) leaseLocked(key leaseKey, leaseID LeaseID) *Lease {
+	if lease := lm.leases[key]; lease.ID == leaseID {
+		return lease
+	}
+	for _, lease := range lm.readers[key] {
+		if lease.ID == leaseID {
+			return lease
+		}
+	}
+	return nil
+}
@@ -2,11 +2,14 @@ package dsm
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"testing"
 	"time"
 
+	"github.com/melihxz/holocompute/internal/hyperbus"
 	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -118,6 +121,53 @@ func TestLeaseManager_ExpiredLease(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestLeaseManager_SweeperReclaimsExpiredWriteLease(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(20*time.Millisecond, logger)
+
+	const interval = 50 * time.Millisecond
+	lm.StartSweeper(context.Background(), interval)
+	defer lm.StopSweeper()
+
+	lease, err := lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-1", 1)
+	assert.NoError(t, err)
+
+	// The lease expires without being released; the sweeper reclaims it
+	// within one interval of expiring, with no explicit cleanup
+	assert.Eventually(t, func() bool {
+		return lm.held() == 0
+	}, 20*time.Millisecond+2*interval, 5*time.Millisecond)
+	_, err = lm.ValidateLease(context.Background(), lease.ID)
+	assert.Error(t, err)
+
+	_, err = lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-2", 1)
+	assert.NoError(t, err)
+}
+
+func TestLeaseManager_StopSweeper(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(time.Millisecond, logger)
+
+	// Stopping before starting or twice is harmless
+	lm.StopSweeper()
+	lm.StartSweeper(context.Background(), 10*time.Millisecond)
+	lm.StopSweeper()
+	lm.StopSweeper()
+
+	// Nothing reclaims leases once stopped
+	_, err := lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-1", 1)
+	assert.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 1, lm.held())
+}
+
+// held returns the number of leases stored, expired or not
+func (lm *LeaseManager) held() int {
+	lm.mu.RLock()
+	defer lm.mu.RUnlock()
+	return len(lm.leases)
+}
+
 func TestLeaseManager_RevokeLease(t *testing.T) {
 	logger := log.New(slog.LevelDebug)
 	lm := NewLeaseManager(time.Minute, logger)
@@ -134,3 +184,477 @@ func TestLeaseManager_RevokeLease(t *testing.T) {
 	_, err = lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-2", 1)
 	assert.NoError(t, err)
 }
+
+func TestLeaseManager_ManyLeasesByID(t *testing.T) {
+	logger := log.New(slog.LevelError)
+	lm := NewLeaseManager(time.Minute, logger)
+
+	const n = 1000
+	leases := make([]*Lease, n)
+	for i := range leases {
+		lease, err := lm.AcquireLease(context.Background(), "array-1", PageID(i), WriteLease, "client-1", 1)
+		assert.NoError(t, err)
+		leases[i] = lease
+	}
+
+	for _, lease := range leases {
+		validated, err := lm.ValidateLease(context.Background(), lease.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, lease, validated)
+	}
+
+	// Release every other lease
+	for i := 0; i < n; i += 2 {
+		assert.NoError(t, lm.ReleaseLease(context.Background(), leases[i].ID))
+	}
+	for i, lease := range leases {
+		_, err := lm.ValidateLease(context.Background(), lease.ID)
+		if i%2 == 0 {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	// Revoked leases are gone from the index too
+	assert.NoError(t, lm.RevokeLease(context.Background(), "array-1", 1))
+	assert.Error(t, lm.ReleaseLease(context.Background(), leases[1].ID))
+
+	// The freed page can be leased again
+	lease, err := lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-2", 1)
+	assert.NoError(t, err)
+	assert.Len(t, lm.byID, len(lm.leases))
+	assert.NoError(t, lm.ReleaseLease(context.Background(), lease.ID))
+}
+
+func BenchmarkLeaseManager_ValidateLease(b *testing.B) {
+	logger := log.New(slog.LevelError)
+	lm := NewLeaseManager(time.Minute, logger)
+
+	const n = 10000
+	ids := make([]LeaseID, n)
+	for i := range ids {
+		lease, err := lm.AcquireLease(context.Background(), "array-1", PageID(i), ReadLease, "client-1", 1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids[i] = lease.ID
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := lm.ValidateLease(context.Background(), ids[i%n]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestLeaseManager_LeasesForArray(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(time.Minute, logger)
+	ctx := context.Background()
+
+	before := time.Now()
+	_, err := lm.AcquireLease(ctx, "array-1", 2, WriteLease, "client-2", 1)
+	assert.NoError(t, err)
+	_, err = lm.AcquireLease(ctx, "array-1", 0, ReadLease, "client-1", 1)
+	assert.NoError(t, err)
+	_, err = lm.AcquireLease(ctx, "array-1", 1, WriteLease, "client-1", 1)
+	assert.NoError(t, err)
+	_, err = lm.AcquireLease(ctx, "array-2", 0, WriteLease, "client-3", 1)
+	assert.NoError(t, err)
+
+	leases := lm.LeasesForArray("array-1")
+	assert.Len(t, leases, 3)
+
+	expected := []struct {
+		page  PageID
+		typ   LeaseType
+		owner string
+	}{
+		{0, ReadLease, "client-1"},
+		{1, WriteLease, "client-1"},
+		{2, WriteLease, "client-2"},
+	}
+	for i, e := range expected {
+		assert.Equal(t, ArrayID("array-1"), leases[i].ArrayID)
+		assert.Equal(t, e.page, leases[i].PageID)
+		assert.Equal(t, e.typ, leases[i].Type)
+		assert.Equal(t, e.owner, leases[i].Owner)
+		assert.True(t, leases[i].ExpiresAt.After(before))
+	}
+
+	// The enumeration is a snapshot
+	leases[0].Owner = "mutated"
+	assert.Equal(t, "client-1", lm.LeasesForArray("array-1")[0].Owner)
+
+	assert.Empty(t, lm.LeasesForArray("array-3"))
+}
+
+func TestMergeLeaseReports(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ctx := context.Background()
+
+	local := NewLeaseManager(time.Minute, logger)
+	_, err := local.AcquireLease(ctx, "array-1", 1, WriteLease, "node-a", 1)
+	assert.NoError(t, err)
+
+	remote := NewLeaseManager(time.Minute, logger)
+	_, err = remote.AcquireLease(ctx, "array-1", 0, ReadLease, "node-b", 1)
+	assert.NoError(t, err)
+
+	// An expired lease is dropped from the aggregate
+	stale := &proto.LeaseReport{NodeId: "node-c", Leases: []*proto.LeaseInfo{{
+		LeaseId:           "stale",
+		ArrayId:           "array-1",
+		PageId:            3,
+		Kind:              proto.LeaseRequest_WRITE,
+		ExpiresAtUnixNano: time.Now().Add(-time.Second).UnixNano(),
+	}}}
+
+	// Remote reports arrive over the wire
+	msg, err := hyperbus.EncodeMessage(hyperbus.MsgLeaseReport, remote.Report("node-b", "array-1"))
+	assert.NoError(t, err)
+	var remoteReport proto.LeaseReport
+	assert.NoError(t, hyperbus.DecodeMessage(msg[hyperbus.HeaderSize:], &remoteReport))
+
+	leases := MergeLeaseReports(local.Report("node-a", "array-1"), &remoteReport, stale)
+	assert.Len(t, leases, 2)
+	assert.Equal(t, PageID(0), leases[0].PageID)
+	assert.Equal(t, ReadLease, leases[0].Type)
+	assert.Equal(t, "node-b", leases[0].Owner)
+	assert.Equal(t, PageID(1), leases[1].PageID)
+	assert.Equal(t, WriteLease, leases[1].Type)
+	assert.Equal(t, "node-a", leases[1].Owner)
+}
+
+func TestQueryLeases(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	remote := NewLeaseManager(time.Minute, logger)
+	remote.SetNodeID("node-b")
+	_, err := remote.AcquireLease(ctx, "array-1", 2, WriteLease, "client-1", 1)
+	assert.NoError(t, err)
+	_, err = remote.AcquireLease(ctx, "array-2", 0, WriteLease, "client-1", 1)
+	assert.NoError(t, err)
+
+	mux := hyperbus.NewMux()
+	mux.Handle(hyperbus.MsgLeaseQuery, remote)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-a"}, hyperbus.NewMux(), logger)
+	hyperbus.ConnectMemory(bus, hyperbus.New(hyperbus.NodeInfo{ID: "node-b"}, mux, logger))
+
+	// Only the leases on the queried array are reported
+	report, err := QueryLeases(ctx, bus, "node-b", "array-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "node-b", report.NodeId)
+	leases := MergeLeaseReports(report)
+	if assert.Len(t, leases, 1) {
+		assert.Equal(t, PageID(2), leases[0].PageID)
+		assert.Equal(t, "client-1", leases[0].Owner)
+	}
+}
+
+func TestLeaseManager_UpgradeSoleReader(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(time.Minute, logger)
+
+	read, err := lm.AcquireLease(context.Background(), "array-1", 0, ReadLease, "client-1", 1)
+	assert.NoError(t, err)
+
+	lease, err := lm.UpgradeLease(context.Background(), "array-1", 0, "client-1")
+	assert.NoError(t, err)
+	assert.Equal(t, read.ID, lease.ID)
+	assert.Equal(t, WriteLease, lease.Type)
+	assert.True(t, lm.HasWriteLease(context.Background(), "array-1", 0))
+
+	// The write lease now keeps other writers and readers out
+	_, err = lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-2", 1)
+	assert.Error(t, err)
+	_, err = lm.AcquireLease(context.Background(), "array-1", 0, ReadLease, "client-2", 1)
+	assert.Error(t, err)
+
+	// Upgrading again is a no-op for the holder and an error for anyone else
+	_, err = lm.UpgradeLease(context.Background(), "array-1", 0, "client-1")
+	assert.NoError(t, err)
+	_, err = lm.UpgradeLease(context.Background(), "array-1", 0, "client-2")
+	assert.Error(t, err)
+}
+
+func TestLeaseManager_UpgradeSharedReadLease(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(time.Minute, logger)
+
+	for _, owner := range []string{"client-1", "client-3", "client-2"} {
+		_, err := lm.AcquireLease(context.Background(), "array-1", 0, ReadLease, owner, 1)
+		assert.NoError(t, err)
+	}
+
+	_, err := lm.UpgradeLease(context.Background(), "array-1", 0, "client-1")
+	var shared *ErrSharedReadLease
+	assert.ErrorAs(t, err, &shared)
+	assert.Equal(t, []string{"client-2", "client-3"}, shared.Readers)
+	assert.ErrorContains(t, err, "also read by client-2, client-3")
+	assert.False(t, lm.HasWriteLease(context.Background(), "array-1", 0))
+
+	// Owners without a read lease can't upgrade, nor can anyone without a lease
+	_, err = lm.UpgradeLease(context.Background(), "array-1", 0, "client-4")
+	assert.ErrorContains(t, err, "holds no read lease")
+	_, err = lm.UpgradeLease(context.Background(), "array-1", 1, "client-1")
+	assert.ErrorContains(t, err, "no lease to upgrade")
+}
+
+func TestLeaseManager_ReleaseSharedReadLease(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(time.Minute, logger)
+
+	a, err := lm.AcquireLease(context.Background(), "array-1", 0, ReadLease, "client-a", 1)
+	assert.NoError(t, err)
+	b, err := lm.AcquireLease(context.Background(), "array-1", 0, ReadLease, "client-b", 1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, a.ID, b.ID)
+
+	// A leaving doesn't take B's lease with it
+	assert.NoError(t, lm.ReleaseLease(context.Background(), a.ID))
+	_, err = lm.ValidateLease(context.Background(), a.ID)
+	assert.Error(t, err)
+	validated, err := lm.ValidateLease(context.Background(), b.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "client-b", validated.Owner)
+
+	_, err = lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-c", 1)
+	assert.Error(t, err)
+	if leases := lm.LeasesForArray("array-1"); assert.Len(t, leases, 1) {
+		assert.Equal(t, "client-b", leases[0].Owner)
+	}
+
+	// B is now the sole reader and may write
+	lease, err := lm.UpgradeLease(context.Background(), "array-1", 0, "client-b")
+	assert.NoError(t, err)
+	assert.Equal(t, b.ID, lease.ID)
+	assert.True(t, lm.HasWriteLease(context.Background(), "array-1", 0))
+
+	assert.NoError(t, lm.ReleaseLease(context.Background(), b.ID))
+	assert.Empty(t, lm.byID)
+	_, err = lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-c", 1)
+	assert.NoError(t, err)
+}
+
+func TestLeaseManager_AcquireLeaseWaitUnblockedByRelease(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(time.Minute, logger)
+
+	held, err := lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-1", 1)
+	assert.NoError(t, err)
+
+	granted := make(chan *Lease)
+	go func() {
+		lease, err := lm.AcquireLeaseWait(context.Background(), "array-1", 0, WriteLease, "client-2", 1)
+		assert.NoError(t, err)
+		granted <- lease
+	}()
+
+	// The waiter blocks while the write lease is held
+	select {
+	case <-granted:
+		t.Fatal("lease granted while another write lease was held")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.NoError(t, lm.ReleaseLease(context.Background(), held.ID))
+	select {
+	case lease := <-granted:
+		assert.Equal(t, "client-2", lease.Owner)
+		assert.Equal(t, WriteLease, lease.Type)
+	case <-time.After(time.Second):
+		t.Fatal("waiter not unblocked by release")
+	}
+}
+
+func TestLeaseManager_AcquireLeaseWaitFIFO(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(time.Minute, logger)
+
+	held, err := lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-0", 1)
+	assert.NoError(t, err)
+
+	// Queue waiters one at a time so their order is known
+	granted := make(chan *Lease)
+	for i := 1; i <= 3; i++ {
+		owner := fmt.Sprintf("client-%d", i)
+		go func() {
+			lease, err := lm.AcquireLeaseWait(context.Background(), "array-1", 0, WriteLease, owner, 1)
+			assert.NoError(t, err)
+			granted <- lease
+		}()
+		assert.Eventually(t, func() bool {
+			lm.mu.RLock()
+			defer lm.mu.RUnlock()
+			return len(lm.waiters[leaseKey{arrayID: "array-1", pageID: 0}]) == i
+		}, time.Second, time.Millisecond)
+	}
+
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, lm.RevokeLease(context.Background(), "array-1", 0))
+		lease := <-granted
+		assert.Equal(t, fmt.Sprintf("client-%d", i), lease.Owner)
+	}
+	_, err = lm.ValidateLease(context.Background(), held.ID)
+	assert.Error(t, err)
+}
+
+func TestLeaseManager_AcquireLeaseWaitCancelled(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(time.Minute, logger)
+
+	_, err := lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-1", 1)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = lm.AcquireLeaseWait(ctx, "array-1", 0, WriteLease, "client-2", 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The cancelled waiter left the queue
+	lm.mu.RLock()
+	assert.Empty(t, lm.waiters)
+	lm.mu.RUnlock()
+}
+
+func TestLeaseManager_AcquireLeaseWaitUnblockedByExpiry(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(50*time.Millisecond, logger)
+
+	// The holder never releases and no sweeper is running
+	_, err := lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-1", 1)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	lease, err := lm.AcquireLeaseWait(ctx, "array-1", 0, WriteLease, "client-2", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "client-2", lease.Owner)
+}
+
+func TestLeaseManager_AcquireLeaseWaitDefaultTimeout(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	lm := NewLeaseManager(time.Minute, logger)
+	lm.SetAcquireTimeout(20 * time.Millisecond)
+
+	_, err := lm.AcquireLease(context.Background(), "array-1", 0, WriteLease, "client-1", 1)
+	assert.NoError(t, err)
+
+	// A background context still gives up after the configured default
+	_, err = lm.AcquireLeaseWait(context.Background(), "array-1", 0, WriteLease, "client-2", 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLeaseManager_OwnershipTransferInvalidatesLease(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ctx := context.Background()
+	nodes := newMeshNodes(t, "node-a", "node-b")
+	mm := nodes["node-b"]
+	array, err := mm.CreateArray(ctx, PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+	shareCopy(t, array, nodes["node-a"])
+
+	lm := NewLeaseManager(time.Minute, logger)
+	lm.SetEpochSource(mm)
+
+	// The old owner granted a write lease
+	stale, err := lm.AcquireLease(ctx, array.ID, 0, WriteLease, "writer-1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, Epoch(0), stale.Epoch)
+
+	// Draining node-a hands the page to node-b in a new epoch
+	assert.Equal(t, 1, mm.DrainNode(ctx, "node-a"))
+	assert.Equal(t, Epoch(1), array.PageEpoch(0))
+
+	_, err = lm.ValidateLease(ctx, stale.ID)
+	assert.ErrorIs(t, err, ErrStaleLease)
+	assert.False(t, lm.HasWriteLease(ctx, array.ID, 0))
+	assert.Empty(t, lm.LeasesForArray(array.ID))
+
+	// The new owner grants a write lease despite the outstanding one
+	current, err := lm.AcquireLease(ctx, array.ID, 0, WriteLease, "writer-2", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, Epoch(1), current.Epoch)
+	_, err = lm.ValidateLease(ctx, stale.ID)
+	assert.Error(t, err)
+
+	// Reports from both owners only keep the lease of the current epoch
+	old := &proto.LeaseReport{NodeId: "node-a", Leases: []*proto.LeaseInfo{stale.ToProto()}}
+	merged := MergeLeaseReports(old, lm.Report("node-b", array.ID))
+	if assert.Len(t, merged, 1) {
+		assert.Equal(t, "writer-2", merged[0].Owner)
+	}
+}
+
+func TestLeaseManager_RemoteMoveInvalidatesLease(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ctx := context.Background()
+	nodes := newMeshNodes(t, "node-a", "node-b")
+	a, b := nodes["node-a"], nodes["node-b"]
+
+	// node-b owns the page and grants leases on it from its own view
+	array, err := a.CreateArray(ctx, PageSize/8, WithPlacement([]hyperbus.NodeID{"node-b"}))
+	assert.NoError(t, err)
+	bArray := shareCopy(t, array, b)
+
+	aLeases := NewLeaseManager(time.Minute, logger)
+	aLeases.SetEpochSource(a)
+	bLeases := NewLeaseManager(time.Minute, logger)
+	bLeases.SetEpochSource(b)
+
+	stale, err := bLeases.AcquireLease(ctx, array.ID, 0, WriteLease, "writer-1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, Epoch(0), stale.Epoch)
+
+	// node-a takes the page over; the broadcast moves node-b to the new epoch
+	moved, err := a.MovePages(ctx, array.ID, map[PageID]hyperbus.NodeID{0: "node-a"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, moved)
+	assert.Equal(t, Epoch(1), bArray.PageEpoch(0))
+	owner, _ := bArray.GetPageOwner(0)
+	assert.Equal(t, hyperbus.NodeID("node-a"), owner)
+
+	_, err = bLeases.ValidateLease(ctx, stale.ID)
+	assert.ErrorIs(t, err, ErrStaleLease)
+	assert.False(t, bLeases.HasWriteLease(ctx, array.ID, 0))
+
+	// The new owner grants in the new epoch, and merged reports agree
+	current, err := aLeases.AcquireLease(ctx, array.ID, 0, WriteLease, "writer-2", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, Epoch(1), current.Epoch)
+	old := &proto.LeaseReport{NodeId: "node-b", Leases: []*proto.LeaseInfo{stale.ToProto()}}
+	merged := MergeLeaseReports(old, aLeases.Report("node-a", array.ID))
+	if assert.Len(t, merged, 1) {
+		assert.Equal(t, "writer-2", merged[0].Owner)
+	}
+
+	// Replaying the remap doesn't move the page to yet another epoch
+	assert.Equal(t, 0, bArray.applyRemap(map[PageID]hyperbus.NodeID{0: "node-a"}, map[PageID]Epoch{0: 1}))
+	assert.Equal(t, Epoch(1), bArray.PageEpoch(0))
+
+	_, err = a.MovePages(ctx, array.ID, map[PageID]hyperbus.NodeID{1: "node-a"})
+	var outOfRange *ErrPageOutOfRange
+	assert.ErrorAs(t, err, &outOfRange)
+}
+
+func TestArray_SetPageOwnerBumpsEpoch(t *testing.T) {
+	array := NewArray(PageSize / 8)
+	array.SetPageOwner(0, "node-a")
+	assert.Equal(t, Epoch(0), array.PageEpoch(0))
+
+	// Only actual ownership changes move the epoch
+	array.SetPageOwner(0, "node-a")
+	assert.Equal(t, Epoch(0), array.PageEpoch(0))
+	array.SetPageOwner(0, "node-b")
+	assert.Equal(t, Epoch(1), array.PageEpoch(0))
+
+	// Epochs survive the wire
+	decoded, err := arrayFromProto(arrayToProto(array))
+	assert.NoError(t, err)
+	assert.Equal(t, Epoch(1), decoded.PageEpoch(0))
+}
@@ -0,0 +1,17 @@
+package dsm
+
+import "github.com/melihxz/holocompute/pkg/proto"
+
+// CollectMetrics adds the memory manager's arrays, local pages and cache
+// statistics to m
+func (mm *MemoryManager) CollectMetrics(m *proto.NodeMetrics) {
+	mm.mu.RLock()
+	m.Arrays = int64(len(mm.arrays))
+	m.LocalPages = int64(len(mm.pages))
+	mm.mu.RUnlock()
+
+	m.CachePages = int64(mm.cache.Size())
+	m.CacheCapacity = int64(mm.cache.Capacity())
+	m.CacheHits, m.CacheMisses = mm.cache.Stats()
+	m.MemoryBytes += (m.LocalPages + m.CachePages) * PageSize
+}
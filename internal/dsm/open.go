@@ -0,0 +1,212 @@
+package dsm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/melihxz/holocompute/internal/deadline"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// ListArrays returns the IDs of the arrays known to this node in sorted order
+func (mm *MemoryManager) ListArrays() []ArrayID {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	ids := make([]ArrayID, 0, len(mm.arrays))
+	for id := range mm.arrays {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// OpenArray returns an existing array and takes a reference on it. Arrays
+// unknown to this node are looked up on connected peers and registered
+// locally, so their pages can then be fetched from their owners.
+func (mm *MemoryManager) OpenArray(ctx context.Context, arrayID ArrayID) (*Array, error) {
+	array, err := mm.GetArray(ctx, arrayID)
+	if err != nil {
+		array, err = mm.lookupArray(ctx, arrayID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	// Another caller may have registered the array meanwhile
+	if existing, exists := mm.arrays[arrayID]; exists {
+		array = existing
+	} else {
+		mm.arrays[arrayID] = array
+	}
+	mm.refs[arrayID]++
+	array.lastAccess.Store(mm.now().UnixNano())
+
+	return array, nil
+}
+
+// ReleaseArray drops a reference taken by CreateArray or OpenArray and
+// returns the number of references left
+func (mm *MemoryManager) ReleaseArray(arrayID ArrayID) int {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if mm.refs[arrayID] > 0 {
+		mm.refs[arrayID]--
+	}
+	return mm.refs[arrayID]
+}
+
+// RefCount returns the number of open references to an array
+func (mm *MemoryManager) RefCount(arrayID ArrayID) int {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	return mm.refs[arrayID]
+}
+
+// PageCounts returns how many pages of the arrays known to this node each
+// node owns
+func (mm *MemoryManager) PageCounts() map[hyperbus.NodeID]int {
+	counts := make(map[hyperbus.NodeID]int)
+	for _, array := range mm.snapshotArrays() {
+		for _, nodeID := range array.PageOwners() {
+			counts[nodeID]++
+		}
+	}
+	return counts
+}
+
+// lookupArray asks connected peers in turn for an array's metadata
+func (mm *MemoryManager) lookupArray(ctx context.Context, arrayID ArrayID) (*Array, error) {
+	for _, nodeID := range mm.bus.Peers() {
+		info, err := mm.requestArrayInfo(ctx, nodeID, arrayID)
+		if err != nil {
+			mm.logger.Debug("array lookup failed", "node_id", nodeID, "array_id", arrayID, "error", err)
+			continue
+		}
+		if info.Found {
+			return arrayFromProto(info)
+		}
+	}
+	return nil, fmt.Errorf("array not found: %s", arrayID)
+}
+
+// requestArrayInfo queries one node for an array's metadata
+func (mm *MemoryManager) requestArrayInfo(ctx context.Context, nodeID hyperbus.NodeID, arrayID ArrayID) (*proto.ArrayInfo, error) {
+	ctx, cancel := deadline.WithDefault(ctx, mm.requestTimeout())
+	defer cancel()
+
+	data, err := mm.bus.Call(ctx, nodeID, hyperbus.MsgArrayQuery, &proto.ArrayQuery{ArrayId: string(arrayID)})
+	if err != nil {
+		return nil, fmt.Errorf("array query to %s failed: %w", nodeID, err)
+	}
+
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.Type != hyperbus.MsgArrayInfo {
+		return nil, fmt.Errorf("unexpected message type %d in reply to array query", header.Type)
+	}
+
+	var info proto.ArrayInfo
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// serveArrayQuery answers an array query from the local array table
+func (mm *MemoryManager) serveArrayQuery(ctx context.Context, stream hyperbus.Stream, data []byte) error {
+	var req proto.ArrayQuery
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &req); err != nil {
+		return err
+	}
+
+	info := &proto.ArrayInfo{ArrayId: req.ArrayId}
+	mm.mu.RLock()
+	array, exists := mm.arrays[ArrayID(req.ArrayId)]
+	mm.mu.RUnlock()
+	if exists {
+		info = arrayToProto(array)
+	}
+
+	msg, err := hyperbus.EncodeReply(data, hyperbus.MsgArrayInfo, info)
+	if err != nil {
+		return fmt.Errorf("failed to encode array info: %w", err)
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// arrayToProto converts an array's metadata to its wire form
+func arrayToProto(array *Array) *proto.ArrayInfo {
+	array.mu.RLock()
+	defer array.mu.RUnlock()
+
+	owners := make(map[int32]string, len(array.PageMapping))
+	for pageID, nodeID := range array.PageMapping {
+		owners[int32(pageID)] = string(nodeID)
+	}
+	var epochs map[int32]int64
+	if len(array.epochs) > 0 {
+		epochs = make(map[int32]int64, len(array.epochs))
+		for pageID, epoch := range array.epochs {
+			epochs[int32(pageID)] = int64(epoch)
+		}
+	}
+	return &proto.ArrayInfo{
+		ArrayId:       string(array.ID),
+		Found:         true,
+		Length:        int64(array.Length),
+		NumPages:      int32(array.NumPages),
+		ElementType:   int32(array.ElementType),
+		Version:       int64(array.Version),
+		ReadOnly:      array.ReadOnly,
+		Replication:   int32(array.Replication),
+		Compression:   array.Compression,
+		PageOwners:    owners,
+		Sparse:        array.Sparse,
+		PageEpochs:    epochs,
+		AccessPattern: int32(array.Access),
+		LastAccess:    array.lastAccess.Load(),
+	}
+}
+
+// arrayFromProto rebuilds an array's metadata from its wire form, failing
+// for a custom element type with no codec registered on this node
+func arrayFromProto(info *proto.ArrayInfo) (*Array, error) {
+	elemType := ElementType(info.ElementType)
+	if _, exists := LookupElementCodec(elemType); elemType >= ElementCustom && !exists {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownElementType, int(elemType))
+	}
+	array := &Array{
+		ID:          ArrayID(info.ArrayId),
+		Length:      int(info.Length),
+		NumPages:    int(info.NumPages),
+		ElementType: elemType,
+		ElementSize: elemType.Size(),
+		PageMapping: make(map[PageID]hyperbus.NodeID, len(info.PageOwners)),
+		Version:     Version(info.Version),
+		ReadOnly:    info.ReadOnly,
+		Replication: int(info.Replication),
+		Compression: info.Compression,
+		Sparse:      info.Sparse,
+		Access:      AccessPattern(info.AccessPattern),
+	}
+	array.lastAccess.Store(info.LastAccess)
+	for pageID, nodeID := range info.PageOwners {
+		array.PageMapping[PageID(pageID)] = hyperbus.NodeID(nodeID)
+	}
+	if len(info.PageEpochs) > 0 {
+		array.epochs = make(map[PageID]Epoch, len(info.PageEpochs))
+		for pageID, epoch := range info.PageEpochs {
+			array.epochs[PageID(pageID)] = Epoch(epoch)
+		}
+	}
+	return array, nil
+}
@@ -0,0 +1,113 @@
+package dsm
+
+import (
+	"context"
+
+	"github.com/melihxz/holocompute/internal/deadline"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+)
+
+// prefetchDepth is how many pages ahead of a sequential reader are fetched
+const prefetchDepth = 8
+
+// AccessPattern hints how an array's pages will be read, tuning caching and prefetch
+type AccessPattern int
+
+const (
+	// AccessUnspecified gives no hint: pages are cached by 2Q, and prefetched
+	// once a reader is seen moving on to the next page
+	AccessUnspecified AccessPattern = iota
+
+	// AccessSequential arrays are streamed through: the pages after each read
+	// are prefetched, and pages already read are evicted first
+	AccessSequential
+
+	// AccessRandom arrays are read in no predictable order: nothing is
+	// prefetched, and pages read repeatedly are retained over those read once
+	AccessRandom
+)
+
+// WithAccessPattern hints how the array's pages will be read
+func WithAccessPattern(pattern AccessPattern) ArrayOption {
+	return func(o *arrayOptions) {
+		o.access = pattern
+	}
+}
+
+// streaming reports whether a reader that moved from page previous to
+// pageID is streaming through the array, so the pages after it will be read next
+func (a *Array) streaming(previous, pageID PageID) bool {
+	switch a.Access {
+	case AccessSequential:
+		return true
+	case AccessUnspecified:
+		return previous >= 0 && previous+1 == pageID
+	default:
+		return false
+	}
+}
+
+// getCached returns a cached copy of a remotely owned page, read the way the
+// array's access pattern suggests. Only a reader that moved onto the page
+// changes its place in the cache; further reads of it leave it in place.
+func (mm *MemoryManager) getCached(array *Array, pageID PageID, moved bool) (*Page, bool) {
+	switch {
+	case !moved:
+		return mm.cache.Peek(array.ID, pageID)
+	case array.Access == AccessSequential:
+		return mm.cache.GetStreaming(array.ID, pageID)
+	default:
+		return mm.cache.Get(array.ID, pageID)
+	}
+}
+
+// prefetch starts fetching the remotely owned pages following pageID that
+// aren't cached or being fetched already
+func (mm *MemoryManager) prefetch(array *Array, pageID PageID) {
+	localID := mm.bus.LocalNode().ID
+	last := min(int(pageID)+prefetchDepth, array.PageCount()-1)
+	for next := pageID + 1; int(next) <= last; next++ {
+		ownerID, exists := array.GetPageOwner(next)
+		if !exists || ownerID == localID || mm.cache.Contains(array.ID, next) {
+			continue
+		}
+
+		key := pageKey{arrayID: array.ID, pageID: next}
+		mm.mu.Lock()
+		if mm.prefetching[key] {
+			mm.mu.Unlock()
+			continue
+		}
+		mm.prefetching[key] = true
+		inflight := mm.inflight
+		mm.mu.Unlock()
+
+		go mm.prefetchPage(inflight, ownerID, key)
+	}
+}
+
+// prefetchPage fetches a page into the cache unless a read cached it meanwhile
+func (mm *MemoryManager) prefetchPage(inflight *inflightLimiter, ownerID hyperbus.NodeID, key pageKey) {
+	defer func() {
+		mm.mu.Lock()
+		delete(mm.prefetching, key)
+		mm.mu.Unlock()
+	}()
+
+	ctx, cancel := deadline.WithDefault(context.Background(), mm.requestTimeout())
+	defer cancel()
+
+	if err := inflight.acquire(ctx, ownerID); err != nil {
+		return
+	}
+	defer inflight.release(ownerID)
+
+	page, err := mm.fetchRemote(ctx, ownerID, key.arrayID, key.pageID, 0)
+	if err != nil {
+		mm.logger.Debug("failed to prefetch page", "array_id", key.arrayID, "page_id", key.pageID, "error", err)
+		return
+	}
+	if !mm.cache.Contains(key.arrayID, key.pageID) {
+		mm.cache.Put(key.arrayID, key.pageID, page)
+	}
+}
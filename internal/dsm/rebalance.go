@@ -0,0 +1,290 @@
+package dsm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/melihxz/holocompute/internal/deadline"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// Rebalance moves the pages of arrays placed on the ring to the owners the
+// ring now assigns them, e.g. after nodes joined or left. It returns the
+// number of pages that changed owner; see movePages for how they move.
+func (mm *MemoryManager) Rebalance(ctx context.Context) int {
+	mm.mu.RLock()
+	ring := mm.ring
+	mm.mu.RUnlock()
+	if ring == nil {
+		return 0
+	}
+
+	moved := 0
+	for _, array := range mm.snapshotArrays() {
+		if !array.hashed {
+			continue
+		}
+		moves := make(map[PageID]hyperbus.NodeID)
+		for pageID, owner := range array.PageOwners() {
+			if newOwner, ok := ring.PageOwner(array.ID, pageID); ok && newOwner != owner {
+				moves[pageID] = newOwner
+			}
+		}
+		moved += mm.movePages(ctx, array, moves)
+	}
+
+	mm.logger.Info("rebalanced pages", "moved", moved)
+	return moved
+}
+
+// DrainNode moves every page owned by nodeID to other nodes and takes it off
+// the ring. Pages placed on the ring go to their new ring owner; others are
+// spread over the array's remaining owners, or the local node if it has
+// none. It returns the number of pages that changed owner; see movePages
+// for how they move.
+func (mm *MemoryManager) DrainNode(ctx context.Context, nodeID hyperbus.NodeID) int {
+	mm.mu.RLock()
+	ring := mm.ring
+	mm.mu.RUnlock()
+	if ring != nil {
+		ring.Remove(nodeID)
+	}
+
+	moved := 0
+	for _, array := range mm.snapshotArrays() {
+		mapping := array.PageOwners()
+
+		var others []hyperbus.NodeID
+		seen := make(map[hyperbus.NodeID]bool)
+		for pageID := PageID(0); int(pageID) < array.NumPages; pageID++ {
+			if owner, exists := mapping[pageID]; exists && owner != nodeID && !seen[owner] {
+				seen[owner] = true
+				others = append(others, owner)
+			}
+		}
+		if len(others) == 0 {
+			others = []hyperbus.NodeID{mm.bus.LocalNode().ID}
+		}
+
+		moves := make(map[PageID]hyperbus.NodeID)
+		next := 0
+		for pageID := PageID(0); int(pageID) < array.NumPages; pageID++ {
+			if mapping[pageID] != nodeID {
+				continue
+			}
+			newOwner, ok := hyperbus.NodeID(""), false
+			if array.hashed && ring != nil {
+				newOwner, ok = ring.PageOwner(array.ID, pageID)
+			}
+			if !ok {
+				newOwner = others[next%len(others)]
+				next++
+			}
+			moves[pageID] = newOwner
+		}
+		moved += mm.movePages(ctx, array, moves)
+	}
+
+	mm.logger.Info("drained node", "node_id", nodeID, "moved", moved)
+	return moved
+}
+
+// MovePages hands pages of an array to the owners given for them and
+// announces the new owners and ownership epochs to connected peers, whose
+// leases granted in the pages' earlier epochs become stale. It returns the
+// number of pages that changed owner; see movePages for how they move.
+func (mm *MemoryManager) MovePages(ctx context.Context, arrayID ArrayID, moves map[PageID]hyperbus.NodeID) (int, error) {
+	array, err := mm.GetArray(ctx, arrayID)
+	if err != nil {
+		return 0, err
+	}
+
+	owners := array.PageOwners()
+	pending := make(map[PageID]hyperbus.NodeID, len(moves))
+	for pageID, newOwner := range moves {
+		owner, exists := owners[pageID]
+		if !exists {
+			return 0, &ErrPageOutOfRange{PageID: pageID, NumPages: array.PageCount()}
+		}
+		if owner != newOwner {
+			pending[pageID] = newOwner
+		}
+	}
+	return mm.movePages(ctx, array, pending), nil
+}
+
+// movePages hands each page in moves from its current owner to the new
+// owner given for it, then remaps the pages whose handoff was acknowledged
+// in one batch and broadcasts their new owners and epochs to connected
+// peers. Pages that couldn't be handed off keep their owner. It returns
+// the number of pages that changed owner.
+func (mm *MemoryManager) movePages(ctx context.Context, array *Array, moves map[PageID]hyperbus.NodeID) int {
+	if len(moves) == 0 {
+		return 0
+	}
+
+	owners := array.PageOwners()
+	for pageID, newOwner := range moves {
+		if err := mm.handOffPage(ctx, array.ID, pageID, owners[pageID], newOwner); err != nil {
+			mm.logger.Warn("failed to move page, keeping its owner",
+				"array_id", array.ID,
+				"page_id", pageID,
+				"owner", owners[pageID],
+				"new_owner", newOwner,
+				"error", err)
+			delete(moves, pageID)
+		}
+	}
+
+	// Pages remapped meanwhile are left alone
+	array.RemapPages(func(mapping map[PageID]hyperbus.NodeID) {
+		for pageID, newOwner := range moves {
+			if mapping[pageID] != owners[pageID] {
+				delete(moves, pageID)
+				continue
+			}
+			mapping[pageID] = newOwner
+		}
+	})
+	if len(moves) == 0 {
+		return 0
+	}
+
+	if err := mm.saveArray(array); err != nil {
+		mm.logger.Error("failed to save remapped array", "array_id", array.ID, "error", err)
+	}
+	remap := &proto.PageRemap{
+		ArrayId:    string(array.ID),
+		PageOwners: make(map[int32]string, len(moves)),
+		PageEpochs: make(map[int32]int64, len(moves)),
+	}
+	for pageID, owner := range moves {
+		remap.PageOwners[int32(pageID)] = string(owner)
+		remap.PageEpochs[int32(pageID)] = int64(array.PageEpoch(pageID))
+	}
+	mm.broadcastRemap(ctx, remap)
+	return len(moves)
+}
+
+// handOffPage copies a page from its owner to the node taking it over and
+// waits for the new owner to store it. Pages the local owner never
+// materialized have nothing to copy.
+func (mm *MemoryManager) handOffPage(ctx context.Context, arrayID ArrayID, pageID PageID, from, to hyperbus.NodeID) error {
+	ctx, cancel := deadline.WithDefault(ctx, mm.requestTimeout())
+	defer cancel()
+
+	local := mm.bus.LocalNode().ID
+	var version Version
+	var data []byte
+	if from == local {
+		mm.mu.RLock()
+		page, exists := mm.pages[pageKey{arrayID: arrayID, pageID: pageID}]
+		mm.mu.RUnlock()
+		if !exists {
+			return nil
+		}
+		version, data = page.snapshot()
+	} else {
+		page, err := mm.fetchRemote(ctx, from, arrayID, pageID, 0)
+		if err != nil {
+			return fmt.Errorf("failed to fetch page from %s: %w", from, err)
+		}
+		version, data = page.Version, page.Bytes()
+	}
+
+	if to == local {
+		mm.storeCopy(arrayID, pageID, version, data)
+		return nil
+	}
+	return mm.pushPage(ctx, to, arrayID, pageID, version, data, true)
+}
+
+// broadcastRemap tells every connected peer about pages that changed owner,
+// so they stop sending requests to the old owners and invalidate leases
+// granted in the pages' previous epochs, or about pages appended to an array
+func (mm *MemoryManager) broadcastRemap(ctx context.Context, remap *proto.PageRemap) {
+	var wg sync.WaitGroup
+	for _, nodeID := range mm.bus.Peers() {
+		wg.Add(1)
+		go func(nodeID hyperbus.NodeID) {
+			defer wg.Done()
+			if err := mm.sendRemap(ctx, nodeID, remap); err != nil {
+				mm.logger.Warn("failed to announce remapped pages", "node_id", nodeID, "array_id", remap.ArrayId, "error", err)
+			}
+		}(nodeID)
+	}
+	wg.Wait()
+}
+
+// sendRemap sends a remap to one node and waits for it to be applied
+func (mm *MemoryManager) sendRemap(ctx context.Context, nodeID hyperbus.NodeID, remap *proto.PageRemap) error {
+	ctx, cancel := deadline.WithDefault(ctx, mm.requestTimeout())
+	defer cancel()
+
+	reply, err := mm.bus.Call(ctx, nodeID, hyperbus.MsgPageRemap, remap)
+	if err != nil {
+		return err
+	}
+	var resp proto.PageResponse
+	if err := hyperbus.DecodeMessage(reply[hyperbus.HeaderSize:], &resp); err != nil {
+		return err
+	}
+	if resp.Status != proto.PageResponse_OK && resp.Status != proto.PageResponse_NOT_FOUND {
+		return fmt.Errorf("%s returned %s for the remap", nodeID, resp.Status)
+	}
+	return nil
+}
+
+// servePageRemap adopts the page owners and growth another node announced
+// for an array known here
+func (mm *MemoryManager) servePageRemap(ctx context.Context, stream hyperbus.Stream, data []byte) error {
+	var remap proto.PageRemap
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &remap); err != nil {
+		return err
+	}
+
+	resp := &proto.PageResponse{Status: proto.PageResponse_NOT_FOUND}
+	mm.mu.RLock()
+	array, exists := mm.arrays[ArrayID(remap.ArrayId)]
+	mm.mu.RUnlock()
+	if exists {
+		owners := make(map[PageID]hyperbus.NodeID, len(remap.PageOwners))
+		for pageID, owner := range remap.PageOwners {
+			owners[PageID(pageID)] = hyperbus.NodeID(owner)
+		}
+		epochs := make(map[PageID]Epoch, len(remap.PageEpochs))
+		for pageID, epoch := range remap.PageEpochs {
+			epochs[PageID(pageID)] = Epoch(epoch)
+		}
+
+		grew := remap.NumPages > 0 && array.applyGrowth(int(remap.NumPages), int(remap.Length), owners)
+		changed := array.applyRemap(owners, epochs)
+		if grew || changed > 0 {
+			mm.logger.Debug("adopted remapped pages", "array_id", array.ID, "pages", changed, "grew", grew)
+			if err := mm.saveArray(array); err != nil {
+				mm.logger.Error("failed to save remapped array", "array_id", array.ID, "error", err)
+			}
+		}
+		resp.Status = proto.PageResponse_OK
+	}
+
+	msg, err := hyperbus.EncodeReply(data, hyperbus.MsgPageResponse, resp)
+	if err != nil {
+		return fmt.Errorf("failed to encode remap reply: %w", err)
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// snapshotArrays returns the arrays known to this node
+func (mm *MemoryManager) snapshotArrays() []*Array {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	arrays := make([]*Array, 0, len(mm.arrays))
+	for _, array := range mm.arrays {
+		arrays = append(arrays, array)
+	}
+	return arrays
+}
@@ -0,0 +1,203 @@
+package dsm
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArray_RemapPagesAtomic(t *testing.T) {
+	array := NewArray(16 * PageSize / 8)
+	for i := 0; i < array.NumPages; i++ {
+		array.SetPageOwner(PageID(i), "node-a")
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	// Readers check that every snapshot has all pages on one node
+	torn := make(chan map[PageID]hyperbus.NodeID, 1)
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				array.GetPageOwner(PageID(r))
+				owners := array.PageOwners()
+				for _, owner := range owners {
+					if owner != owners[0] {
+						select {
+						case torn <- owners:
+						default:
+						}
+						break
+					}
+				}
+			}
+		}()
+	}
+
+	// The rebalancer moves every page back and forth in one batch
+	for i := 0; i < 1000; i++ {
+		to := hyperbus.NodeID("node-b")
+		if i%2 == 1 {
+			to = "node-a"
+		}
+		array.RemapPages(func(mapping map[PageID]hyperbus.NodeID) {
+			for pageID := range mapping {
+				mapping[pageID] = to
+			}
+		})
+	}
+	close(stop)
+	wg.Wait()
+
+	select {
+	case owners := <-torn:
+		t.Fatalf("reader saw a half-remapped mapping: %v", owners)
+	default:
+	}
+}
+
+// newMeshNodes creates memory managers on fully connected buses, each
+// serving page requests, pushes, remaps and array queries
+func newMeshNodes(t *testing.T, ids ...hyperbus.NodeID) map[hyperbus.NodeID]*MemoryManager {
+	logger := log.New(slog.LevelError)
+
+	nodes := make(map[hyperbus.NodeID]*MemoryManager)
+	var buses []*hyperbus.Bus
+	for _, id := range ids {
+		mux := hyperbus.NewMux()
+		bus := hyperbus.New(hyperbus.NodeInfo{ID: id}, mux, logger)
+		mm := NewMemoryManager(bus, logger)
+		for _, msgType := range []hyperbus.MessageType{hyperbus.MsgPageRequest, hyperbus.MsgPagePush, hyperbus.MsgPageRemap, hyperbus.MsgArrayQuery} {
+			mux.Handle(msgType, mm)
+		}
+		for _, other := range buses {
+			hyperbus.ConnectMemory(other, bus)
+		}
+		nodes[id] = mm
+		buses = append(buses, bus)
+	}
+	return nodes
+}
+
+// shareCopy makes a copy of an array's metadata known to another memory
+// manager, which only learns of remaps through their broadcast
+func shareCopy(t *testing.T, array *Array, mm *MemoryManager) *Array {
+	copied, err := arrayFromProto(arrayToProto(array))
+	assert.NoError(t, err)
+	share(copied, mm)
+	return copied
+}
+
+// writeFirst sets the first element of a page through its owner
+func writeFirst(t *testing.T, owner *MemoryManager, arrayID ArrayID, pageID PageID, value int64) {
+	page, err := owner.RequestPage(context.TODO(), arrayID, pageID, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, page.SetInt64(0, value))
+}
+
+// readFirst returns the first element of a page as read through mm
+func readFirst(t *testing.T, mm *MemoryManager, arrayID ArrayID, pageID PageID) int64 {
+	page, err := mm.ReadPage(context.TODO(), arrayID, pageID, 0)
+	assert.NoError(t, err)
+	v, err := page.GetInt64(0)
+	assert.NoError(t, err)
+	return v
+}
+
+func TestMemoryManager_DrainNode(t *testing.T) {
+	nodes := newMeshNodes(t, "node-a", "node-b", "node-c")
+	a := nodes["node-a"]
+
+	array, err := a.CreateArray(context.TODO(), 6*PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a", "node-b", "node-c"}))
+	assert.NoError(t, err)
+	copies := map[hyperbus.NodeID]*Array{
+		"node-b": shareCopy(t, array, nodes["node-b"]),
+		"node-c": shareCopy(t, array, nodes["node-c"]),
+	}
+	for pageID, owner := range array.PageOwners() {
+		writeFirst(t, nodes[owner], array.ID, pageID, int64(pageID)+100)
+	}
+
+	// node-b's two pages are handed to the remaining owners
+	assert.Equal(t, 2, a.DrainNode(context.TODO(), "node-b"))
+	counts := a.PageCounts()
+	assert.Equal(t, 0, counts["node-b"])
+	assert.Equal(t, 3, counts["node-a"])
+	assert.Equal(t, 3, counts["node-c"])
+	assert.Len(t, array.PageOwners(), 6)
+
+	// The other nodes adopted the new owners, and the pages kept their data
+	for _, copied := range copies {
+		assert.Equal(t, array.PageOwners(), copied.PageOwners())
+	}
+	for pageID := range array.PageOwners() {
+		assert.Equal(t, int64(pageID)+100, readFirst(t, nodes["node-c"], array.ID, pageID))
+		assert.Equal(t, array.PageEpoch(pageID), copies["node-c"].PageEpoch(pageID))
+	}
+}
+
+func TestMemoryManager_DrainKeepsUnreachablePages(t *testing.T) {
+	nodes := newMeshNodes(t, "node-a")
+	a := nodes["node-a"]
+
+	// node-b holds the page's data but can't be reached
+	array, err := a.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-b"}))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, a.DrainNode(context.TODO(), "node-b"))
+	owner, _ := array.GetPageOwner(0)
+	assert.Equal(t, hyperbus.NodeID("node-b"), owner)
+	assert.Equal(t, Epoch(0), array.PageEpoch(0))
+}
+
+func TestMemoryManager_Rebalance(t *testing.T) {
+	nodes := newMeshNodes(t, "node-0", "node-1", "node-2")
+	mm := nodes["node-0"]
+	ring := newTestRing(t, "xxhash64", 2)
+	mm.SetRing(ring)
+
+	hashed, err := mm.CreateArray(context.TODO(), 64*PageSize/8)
+	assert.NoError(t, err)
+	pinned, err := mm.CreateArray(context.TODO(), 4*PageSize/8, WithPlacement([]hyperbus.NodeID{"node-0"}))
+	assert.NoError(t, err)
+	shareCopy(t, hashed, nodes["node-1"])
+	joined := shareCopy(t, hashed, nodes["node-2"])
+	for pageID, owner := range hashed.PageOwners() {
+		writeFirst(t, nodes[owner], hashed.ID, pageID, int64(pageID)+100)
+	}
+
+	// A joining node takes over its share of the hashed array only
+	ring.Add("node-2")
+	moved := mm.Rebalance(context.TODO())
+	assert.Greater(t, moved, 0)
+
+	for pageID, owner := range hashed.PageOwners() {
+		want, _ := ring.PageOwner(hashed.ID, pageID)
+		assert.Equal(t, want, owner)
+	}
+	for _, owner := range pinned.PageOwners() {
+		assert.Equal(t, hyperbus.NodeID("node-0"), owner)
+	}
+	assert.Equal(t, 0, mm.Rebalance(context.TODO()))
+
+	// The joining node serves the pages it took over from its own copies
+	assert.Equal(t, hashed.PageOwners(), joined.PageOwners())
+	for pageID, owner := range joined.PageOwners() {
+		if owner == "node-2" {
+			assert.Equal(t, int64(pageID)+100, readFirst(t, nodes["node-2"], hashed.ID, pageID))
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package dsm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/deadline"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// DefaultPageRequestTimeout bounds a page request when the caller sets no deadline
+const DefaultPageRequestTimeout = 10 * time.Second
+
+// DefaultCacheCapacity is the default number of remote pages cached locally
+const DefaultCacheCapacity = 1024
+
+// Bytes returns the page's contents
+func (p *Page) Bytes() []byte {
+	return p.storage.data
+}
+
+// SetPageRequestTimeout sets the deadline applied to page requests whose
+// context has none. Zero leaves them unbounded.
+func (mm *MemoryManager) SetPageRequestTimeout(timeout time.Duration) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.pageTimeout = timeout
+}
+
+// requestTimeout returns the default page request deadline
+func (mm *MemoryManager) requestTimeout() time.Duration {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	return mm.pageTimeout
+}
+
+// requestRemotePage requests a page from a remote node
+func (mm *MemoryManager) requestRemotePage(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+	mm.logger.Debug("requesting remote page",
+		"owner_id", ownerID,
+		"array_id", arrayID,
+		"page_id", pageID)
+
+	ctx, cancel := deadline.WithDefault(ctx, mm.requestTimeout())
+	defer cancel()
+
+	data, err := mm.bus.Call(ctx, ownerID, hyperbus.MsgPageRequest, &proto.PageRequest{
+		ArrayId:     string(arrayID),
+		PageId:      int32(pageID),
+		WantVersion: int64(version),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("page request to %s failed: %w", ownerID, err)
+	}
+
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.Type != hyperbus.MsgPageResponse {
+		return nil, fmt.Errorf("unexpected message type %d in reply to page request", header.Type)
+	}
+
+	var resp proto.PageResponse
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.PageResponse_OK {
+		return nil, fmt.Errorf("owner %s returned %s for page %d in array %s", ownerID, resp.Status, pageID, arrayID)
+	}
+	payload, err := decompressPage(resp.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	page := NewPage(pageID, Version(resp.Version))
+	copy(page.storage.data, payload)
+	return page, nil
+}
+
+// requestRemoteVersion asks a page's owner for its current version without
+// transferring the page
+func (mm *MemoryManager) requestRemoteVersion(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID) (Version, error) {
+	ctx, cancel := deadline.WithDefault(ctx, mm.requestTimeout())
+	defer cancel()
+
+	data, err := mm.bus.Call(ctx, ownerID, hyperbus.MsgPageRequest, &proto.PageRequest{
+		ArrayId:     string(arrayID),
+		PageId:      int32(pageID),
+		VersionOnly: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("page version request to %s failed: %w", ownerID, err)
+	}
+
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return 0, err
+	}
+	if header.Type != hyperbus.MsgPageResponse {
+		return 0, fmt.Errorf("unexpected message type %d in reply to page version request", header.Type)
+	}
+
+	var resp proto.PageResponse
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &resp); err != nil {
+		return 0, err
+	}
+	if resp.Status != proto.PageResponse_OK {
+		return 0, fmt.Errorf("owner %s returned %s for page %d in array %s", ownerID, resp.Status, pageID, arrayID)
+	}
+	return Version(resp.Version), nil
+}
+
+// HandleMessage serves page requests for pages owned or replicated by this
+// node, queries for the metadata of arrays it knows, and pushed replica pages
+func (mm *MemoryManager) HandleMessage(ctx context.Context, conn hyperbus.Connection, stream hyperbus.Stream, data []byte) error {
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+	switch header.Type {
+	case hyperbus.MsgPageRequest:
+	case hyperbus.MsgArrayQuery:
+		return mm.serveArrayQuery(ctx, stream, data)
+	case hyperbus.MsgPagePush:
+		return mm.servePagePush(ctx, stream, data)
+	case hyperbus.MsgPageRemap:
+		return mm.servePageRemap(ctx, stream, data)
+	default:
+		return fmt.Errorf("unexpected message type %d", header.Type)
+	}
+
+	var req proto.PageRequest
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &req); err != nil {
+		return err
+	}
+
+	var resp *proto.PageResponse
+	if req.VersionOnly {
+		resp = mm.servePageVersion(ArrayID(req.ArrayId), PageID(req.PageId))
+	} else {
+		resp = mm.servePage(ctx, ArrayID(req.ArrayId), PageID(req.PageId))
+	}
+
+	msg, err := hyperbus.EncodeReply(data, hyperbus.MsgPageResponse, resp)
+	if err != nil {
+		return fmt.Errorf("failed to encode page response: %w", err)
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// servePageVersion answers a version-only page request without
+// materializing or encoding the page
+func (mm *MemoryManager) servePageVersion(arrayID ArrayID, pageID PageID) *proto.PageResponse {
+	mm.mu.RLock()
+	array, exists := mm.arrays[arrayID]
+	page, materialized := mm.pages[pageKey{arrayID: arrayID, pageID: pageID}]
+	mm.mu.RUnlock()
+
+	if !exists {
+		return &proto.PageResponse{Status: proto.PageResponse_NOT_FOUND}
+	}
+	if materialized {
+		return &proto.PageResponse{Status: proto.PageResponse_OK, Version: int64(page.committedVersion())}
+	}
+	// The owner's pages not yet materialized are at the array's version
+	if owner, exists := array.GetPageOwner(pageID); exists && owner == mm.bus.LocalNode().ID {
+		return &proto.PageResponse{Status: proto.PageResponse_OK, Version: int64(array.Version)}
+	}
+	return &proto.PageResponse{Status: proto.PageResponse_NOT_FOUND}
+}
+
+// servePage builds the response to a page request. Pages the owner hasn't
+// materialized yet are created at the array's version, whatever version the
+// requester wants, so they can't skip ahead of commits.
+func (mm *MemoryManager) servePage(ctx context.Context, arrayID ArrayID, pageID PageID) *proto.PageResponse {
+	mm.mu.RLock()
+	array, exists := mm.arrays[arrayID]
+	mm.mu.RUnlock()
+	if !exists {
+		return &proto.PageResponse{Status: proto.PageResponse_NOT_FOUND}
+	}
+	mm.touchArray(arrayID)
+
+	// The owner serves a page, and replicas serve the copies pushed to them
+	var page *Page
+	owner, exists := array.GetPageOwner(pageID)
+	if exists && owner == mm.bus.LocalNode().ID {
+		var err error
+		if page, err = mm.getLocalPage(ctx, arrayID, pageID, array.Version, !array.Sparse); err != nil {
+			return &proto.PageResponse{Status: proto.PageResponse_NOT_FOUND}
+		}
+	} else {
+		mm.mu.RLock()
+		page, exists = mm.pages[pageKey{arrayID: arrayID, pageID: pageID}]
+		mm.mu.RUnlock()
+		if !exists {
+			return &proto.PageResponse{Status: proto.PageResponse_NOT_FOUND}
+		}
+	}
+
+	// The bytes sent are the ones committed at the version sent
+	pageVersion, data := page.snapshot()
+	payload, encoding, err := compressPage(data, array.Compression)
+	if err != nil {
+		mm.logger.Error("failed to compress page", "array_id", arrayID, "page_id", pageID, "error", err)
+		payload, encoding, _ = compressPage(data, proto.Encoding_RAW)
+	}
+
+	return &proto.PageResponse{
+		Status:   proto.PageResponse_OK,
+		Version:  int64(pageVersion),
+		Encoding: encoding,
+		Payload:  payload,
+	}
+}
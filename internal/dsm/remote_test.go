@@ -0,0 +1,234 @@
+package dsm
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// newConnectedPair creates two memory managers connected in memory, each serving its own pages
+func newConnectedPair() (*MemoryManager, *MemoryManager) {
+	logger := log.New(slog.LevelDebug)
+
+	aMux, bMux := hyperbus.NewMux(), hyperbus.NewMux()
+	aBus := hyperbus.New(hyperbus.NodeInfo{ID: "node-a"}, aMux, logger)
+	bBus := hyperbus.New(hyperbus.NodeInfo{ID: "node-b"}, bMux, logger)
+	hyperbus.ConnectMemory(aBus, bBus)
+
+	a := NewMemoryManager(aBus, logger)
+	b := NewMemoryManager(bBus, logger)
+	aMux.Handle(hyperbus.MsgPageRequest, a)
+	bMux.Handle(hyperbus.MsgPageRequest, b)
+	aMux.Handle(hyperbus.MsgArrayQuery, a)
+	bMux.Handle(hyperbus.MsgArrayQuery, b)
+
+	alive := staticLiveness{"node-a": true, "node-b": true}
+	a.SetLivenessChecker(alive)
+	b.SetLivenessChecker(alive)
+
+	return a, b
+}
+
+// share makes an array created on one memory manager known to another
+func share(array *Array, mm *MemoryManager) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.arrays[array.ID] = array
+}
+
+func TestMemoryManager_RequestRemotePage(t *testing.T) {
+	a, b := newConnectedPair()
+
+	// Node a owns every page
+	array, err := a.CreateArray(context.TODO(), 2*PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+	share(array, b)
+
+	page, err := a.RequestPage(context.TODO(), array.ID, 1, array.Version)
+	assert.NoError(t, err)
+	assert.NoError(t, page.SetInt64(0, 42))
+	assert.NoError(t, page.SetInt64(PageSize/8-1, -7))
+
+	// Node b fetches the page from its owner
+	remote, err := b.RequestPage(context.TODO(), array.ID, 1, array.Version)
+	assert.NoError(t, err)
+
+	v, err := remote.GetInt64(0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+	v, err = remote.GetInt64(PageSize/8 - 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-7), v)
+
+	// The fetched page is cached
+	cached, ok := b.cache.Get(array.ID, 1)
+	assert.True(t, ok)
+	assert.Same(t, remote, cached)
+}
+
+func TestMemoryManager_RequestRemotePageErrors(t *testing.T) {
+	a, b := newConnectedPair()
+
+	// A page the peer doesn't know about
+	array, err := b.CreateArray(context.TODO(), 1000, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+
+	_, err = b.RequestPage(context.TODO(), array.ID, 0, array.Version)
+	assert.ErrorContains(t, err, "NOT_FOUND")
+
+	// An owner with no connection
+	a.SetLivenessChecker(staticLiveness{"node-c": true})
+	orphan, err := a.CreateArray(context.TODO(), 1000, WithPlacement([]hyperbus.NodeID{"node-c"}))
+	assert.NoError(t, err)
+
+	_, err = a.RequestPage(context.TODO(), orphan.ID, 0, orphan.Version)
+	assert.ErrorIs(t, err, hyperbus.ErrNoConnection)
+}
+
+func TestMemoryManager_ReadOnlyPagesStayCached(t *testing.T) {
+	a, b := newConnectedPair()
+
+	array, err := a.CreateArray(context.TODO(), 1000, WithPlacement([]hyperbus.NodeID{"node-a"}), WithReadOnly())
+	assert.NoError(t, err)
+	assert.True(t, array.ReadOnly)
+	share(array, b)
+
+	fetches := 0
+	fetch := b.fetchRemote
+	b.fetchRemote = func(ctx context.Context, ownerID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version) (*Page, error) {
+		fetches++
+		return fetch(ctx, ownerID, arrayID, pageID, version)
+	}
+
+	_, err = b.RequestPage(context.TODO(), array.ID, 0, array.Version)
+	assert.NoError(t, err)
+
+	// A newer version would normally force a refetch
+	_, err = b.RequestPage(context.TODO(), array.ID, 0, array.Version+1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+}
+
+func TestMemoryManager_OpenArray(t *testing.T) {
+	a, b := newConnectedPair()
+
+	array, err := a.CreateArray(context.TODO(), 3*PageSize/4, WithPlacement([]hyperbus.NodeID{"node-a", "node-b"}), WithElementType(ElementFloat32))
+	assert.NoError(t, err)
+	assert.Equal(t, []ArrayID{array.ID}, a.ListArrays())
+	assert.Empty(t, b.ListArrays())
+	assert.Equal(t, 1, a.RefCount(array.ID))
+
+	// Node b learns the array's metadata from node a
+	opened, err := b.OpenArray(context.TODO(), array.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, array.Len(), opened.Len())
+	assert.Equal(t, array.PageCount(), opened.PageCount())
+	assert.Equal(t, ElementFloat32, opened.ElementType)
+	assert.Equal(t, array.PageMapping, opened.PageMapping)
+	assert.Equal(t, []ArrayID{array.ID}, b.ListArrays())
+	assert.Equal(t, 1, b.RefCount(array.ID))
+
+	// Opening again reuses the registered array
+	again, err := b.OpenArray(context.TODO(), array.ID)
+	assert.NoError(t, err)
+	assert.Same(t, opened, again)
+	assert.Equal(t, 2, b.RefCount(array.ID))
+
+	assert.Equal(t, 1, b.ReleaseArray(array.ID))
+	assert.Equal(t, 0, b.ReleaseArray(array.ID))
+	assert.Equal(t, 0, b.ReleaseArray(array.ID))
+
+	_, err = b.OpenArray(context.TODO(), "missing")
+	assert.ErrorContains(t, err, "array not found")
+}
+
+func TestMemoryManager_OpenArrayUnknownElementType(t *testing.T) {
+	a, b := newConnectedPair()
+
+	array, err := a.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+
+	// A custom type node b has no codec for can't be sized there
+	array.ElementType = ElementCustom + 1000
+	_, err = b.OpenArray(context.TODO(), array.ID)
+	assert.ErrorIs(t, err, ErrUnknownElementType)
+	assert.Empty(t, b.ListArrays())
+}
+
+func TestMemoryManager_RequestRemoteVersion(t *testing.T) {
+	a, b := newConnectedPair()
+
+	array, err := a.CreateArray(context.TODO(), 2*PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}), WithSparse())
+	assert.NoError(t, err)
+	_, err = b.OpenArray(context.TODO(), array.ID)
+	assert.NoError(t, err)
+
+	// Asking for the version of a page never written doesn't materialize it
+	version, err := b.requestRemoteVersion(context.TODO(), "node-a", array.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, array.Version, version)
+	assert.Equal(t, 0, array.ResidentPages())
+
+	page, err := a.RequestPage(context.TODO(), array.ID, 0, array.Version)
+	assert.NoError(t, err)
+	_, err = a.CommitPage(context.TODO(), array.ID, 0, page.Version)
+	assert.NoError(t, err)
+
+	version, err = b.requestRemoteVersion(context.TODO(), "node-a", array.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, array.Version+1, version)
+}
+
+func TestMemoryManager_RemoteReadsDuringCommits(t *testing.T) {
+	a, b := newConnectedPair()
+
+	array, err := a.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+	share(array, b)
+
+	// Every commit fills the page with the low byte of the version it makes
+	const commits = 200
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		data := make([]byte, PageSize)
+		for version := array.Version; version < array.Version+commits; version++ {
+			for i := range data {
+				data[i] = byte(version + 1)
+			}
+			_, err := a.CommitPageData(context.TODO(), array.ID, 0, version, data)
+			assert.NoError(t, err)
+		}
+	}()
+
+	// Node b keeps asking for a newer version than it has, so every read
+	// goes to the owner, and never sees bytes from another version
+	seen := array.Version
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		page, err := b.RequestPage(context.TODO(), array.ID, 0, seen+1)
+		assert.NoError(t, err)
+		version, data := page.snapshot()
+		if version > array.Version {
+			for i := range data {
+				if data[i] != byte(version) {
+					t.Fatalf("page at version %d holds byte %d at %d", version, data[i], i)
+				}
+			}
+		}
+		assert.GreaterOrEqual(t, version, seen)
+		seen = version
+
+		_, err = b.requestRemoteVersion(context.TODO(), "node-a", array.ID, 0)
+		assert.NoError(t, err)
+	}
+}
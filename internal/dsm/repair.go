@@ -0,0 +1,332 @@
+package dsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/deadline"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// DefaultReadRepairRate is the default cap on stale replicas repaired per second
+const DefaultReadRepairRate = 100
+
+// repairLimiter admits up to rate repairs per second. Repairs beyond that
+// are skipped; a later read of the page finds the replica stale again.
+type repairLimiter struct {
+	rate   int
+	window time.Time
+	used   int
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// newRepairLimiter creates a limiter admitting rate repairs per second
+func newRepairLimiter(rate int) *repairLimiter {
+	return &repairLimiter{rate: rate, now: time.Now}
+}
+
+// allow reports whether another repair fits in the current second
+func (l *repairLimiter) allow() bool {
+	if l == nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.window) >= time.Second {
+		l.window, l.used = now, 0
+	}
+	if l.used >= l.rate {
+		return false
+	}
+	l.used++
+	return true
+}
+
+// SetReadRepairRate caps how many stale replicas are repaired per second.
+// Zero disables read-repair.
+func (mm *MemoryManager) SetReadRepairRate(rate int) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.repair = nil
+	if rate > 0 {
+		mm.repair = newRepairLimiter(rate)
+	}
+}
+
+// replicaRead is one replica's answer to a quorum read
+type replicaRead struct {
+	nodeID hyperbus.NodeID
+	page   *Page
+	err    error
+}
+
+// ReadReplicas reads a page from each of its replicas in parallel and
+// returns the freshest copy, failing unless a majority answered. Replicas
+// that returned an older version are repaired in the background by pushing
+// them the freshest copy.
+func (mm *MemoryManager) ReadReplicas(ctx context.Context, arrayID ArrayID, pageID PageID, replicas []hyperbus.NodeID) (*Page, error) {
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("no replicas given for page %d of array %s", pageID, arrayID)
+	}
+
+	reads := make([]replicaRead, len(replicas))
+	var wg sync.WaitGroup
+	for i, nodeID := range replicas {
+		wg.Add(1)
+		go func(i int, nodeID hyperbus.NodeID) {
+			defer wg.Done()
+			page, err := mm.readReplica(ctx, nodeID, arrayID, pageID)
+			reads[i] = replicaRead{nodeID: nodeID, page: page, err: err}
+		}(i, nodeID)
+	}
+	wg.Wait()
+
+	var freshest *Page
+	answered := 0
+	for _, read := range reads {
+		if read.err != nil {
+			mm.logger.Debug("replica read failed", "node_id", read.nodeID, "array_id", arrayID, "page_id", pageID, "error", read.err)
+			continue
+		}
+		answered++
+		if freshest == nil || read.page.Version > freshest.Version {
+			freshest = read.page
+		}
+	}
+	if quorum := len(replicas)/2 + 1; answered < quorum {
+		return nil, fmt.Errorf("read quorum not reached for page %d of array %s: %d of %d replicas answered", pageID, arrayID, answered, len(replicas))
+	}
+
+	for _, read := range reads {
+		if read.err == nil && read.page.Version < freshest.Version {
+			mm.repairReplica(read.nodeID, arrayID, pageID, freshest, read.page.Version)
+		}
+	}
+	return freshest, nil
+}
+
+// readReplica reads one replica's copy of a page
+func (mm *MemoryManager) readReplica(ctx context.Context, nodeID hyperbus.NodeID, arrayID ArrayID, pageID PageID) (*Page, error) {
+	if nodeID == mm.bus.LocalNode().ID {
+		mm.mu.RLock()
+		page, exists := mm.pages[pageKey{arrayID: arrayID, pageID: pageID}]
+		mm.mu.RUnlock()
+		if !exists {
+			return nil, fmt.Errorf("no local copy of page %d of array %s", pageID, arrayID)
+		}
+		return page, nil
+	}
+
+	mm.mu.RLock()
+	inflight := mm.inflight
+	mm.mu.RUnlock()
+
+	if err := inflight.acquire(ctx, nodeID); err != nil {
+		return nil, fmt.Errorf("failed waiting for request slot to %s: %w", nodeID, err)
+	}
+	defer inflight.release(nodeID)
+
+	return mm.fetchRemote(ctx, nodeID, arrayID, pageID, 0)
+}
+
+// repairReplica pushes the freshest copy of a page to a stale replica in
+// the background, unless the repair rate limit is reached
+func (mm *MemoryManager) repairReplica(nodeID hyperbus.NodeID, arrayID ArrayID, pageID PageID, page *Page, stale Version) {
+	mm.mu.RLock()
+	limiter := mm.repair
+	mm.mu.RUnlock()
+
+	if !limiter.allow() {
+		mm.logger.Debug("skipping read-repair", "node_id", nodeID, "array_id", arrayID, "page_id", pageID)
+		return
+	}
+
+	version := page.Version
+	data := append([]byte(nil), page.Bytes()...)
+
+	mm.logger.Debug("repairing stale replica",
+		"node_id", nodeID,
+		"array_id", arrayID,
+		"page_id", pageID,
+		"stale_version", stale,
+		"version", version)
+
+	go func() {
+		if nodeID == mm.bus.LocalNode().ID {
+			if _, err := mm.storeReplica(arrayID, pageID, version, data); err != nil {
+				mm.logger.Warn("read-repair failed", "node_id", nodeID, "array_id", arrayID, "page_id", pageID, "error", err)
+			}
+			return
+		}
+
+		ctx, cancel := deadline.WithDefault(context.Background(), mm.requestTimeout())
+		defer cancel()
+
+		if err := mm.pushPage(ctx, nodeID, arrayID, pageID, version, data, false); err != nil {
+			mm.logger.Warn("read-repair failed", "node_id", nodeID, "array_id", arrayID, "page_id", pageID, "error", err)
+		}
+	}()
+}
+
+// pushPage sends a copy of a page to a replica, or hands the page to its
+// new owner if handoff is set
+func (mm *MemoryManager) pushPage(ctx context.Context, nodeID hyperbus.NodeID, arrayID ArrayID, pageID PageID, version Version, data []byte, handoff bool) error {
+	encoding := proto.Encoding_RAW
+	if array, err := mm.GetArray(ctx, arrayID); err == nil {
+		encoding = array.Compression
+	}
+	payload, _, err := compressPage(data, encoding)
+	if err != nil {
+		return err
+	}
+
+	reply, err := mm.bus.Call(ctx, nodeID, hyperbus.MsgPagePush, &proto.PagePush{
+		ArrayId: string(arrayID),
+		PageId:  int32(pageID),
+		Version: int64(version),
+		Payload: payload,
+		Handoff: handoff,
+	})
+	if err != nil {
+		return fmt.Errorf("page push to %s failed: %w", nodeID, err)
+	}
+	var resp proto.PageResponse
+	if err := hyperbus.DecodeMessage(reply[hyperbus.HeaderSize:], &resp); err != nil {
+		return err
+	}
+	if resp.Status != proto.PageResponse_OK {
+		return fmt.Errorf("replica %s returned %s for page %d in array %s", nodeID, resp.Status, pageID, arrayID)
+	}
+	return nil
+}
+
+// servePagePush stores a page pushed by another node and acknowledges it
+func (mm *MemoryManager) servePagePush(ctx context.Context, stream hyperbus.Stream, data []byte) error {
+	var push proto.PagePush
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &push); err != nil {
+		return err
+	}
+
+	resp := &proto.PageResponse{Status: proto.PageResponse_NOT_FOUND}
+	if _, err := mm.GetArray(ctx, ArrayID(push.ArrayId)); err == nil {
+		payload, err := decompressPage(push.Payload)
+		if err != nil {
+			return err
+		}
+		var version Version
+		if push.Handoff {
+			version = mm.storeCopy(ArrayID(push.ArrayId), PageID(push.PageId), Version(push.Version), payload)
+		} else {
+			version, err = mm.storeReplica(ArrayID(push.ArrayId), PageID(push.PageId), Version(push.Version), payload)
+		}
+		switch {
+		case errors.Is(err, ErrOwnedPage):
+			resp = &proto.PageResponse{Status: proto.PageResponse_OWNED}
+		case err == nil:
+			resp = &proto.PageResponse{Status: proto.PageResponse_OK, Version: int64(version)}
+		}
+	}
+
+	msg, err := hyperbus.EncodeReply(data, hyperbus.MsgPageResponse, resp)
+	if err != nil {
+		return fmt.Errorf("failed to encode page push reply: %w", err)
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// ErrOwnedPage is returned when a copy of a page is pushed to its owner,
+// whose page only changes through its own commits
+var ErrOwnedPage = errors.New("page is owned by this node")
+
+// storeReplica stores a copy of a page unless the local copy is already at
+// least as new, returning the version now held. Copies of pages this node
+// owns are rejected with ErrOwnedPage.
+func (mm *MemoryManager) storeReplica(arrayID ArrayID, pageID PageID, version Version, data []byte) (Version, error) {
+	if mm.OwnsPages(arrayID, pageID, pageID) {
+		return 0, fmt.Errorf("%w: page %d of array %s", ErrOwnedPage, pageID, arrayID)
+	}
+	return mm.storeCopy(arrayID, pageID, version, data), nil
+}
+
+// storeCopy stores a copy of a page unless the local copy is already at
+// least as new, returning the version now held
+func (mm *MemoryManager) storeCopy(arrayID ArrayID, pageID PageID, version Version, data []byte) Version {
+	key := pageKey{arrayID: arrayID, pageID: pageID}
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if current, exists := mm.pages[key]; exists && current.Version >= version {
+		return current.Version
+	}
+	page := NewPage(pageID, version)
+	copy(page.Bytes(), data)
+	mm.putPageLocked(key, page)
+	mm.cache.Remove(arrayID, pageID)
+
+	mm.logger.Debug("stored page copy", "array_id", arrayID, "page_id", pageID, "version", version)
+	return version
+}
+
+// pageReplicas returns the nodes keeping copies of a page of an array with
+// a replication factor above 1: its owner, then the nodes following it on
+// the ring. Other arrays have no replicas.
+func (mm *MemoryManager) pageReplicas(array *Array, pageID PageID) []hyperbus.NodeID {
+	mm.mu.RLock()
+	ring := mm.ring
+	mm.mu.RUnlock()
+
+	owner, exists := array.GetPageOwner(pageID)
+	if ring == nil || !exists || array.Replication <= 1 {
+		return nil
+	}
+
+	replicas := []hyperbus.NodeID{owner}
+	for _, nodeID := range ring.PageReplicas(array.ID, pageID, array.Replication+1) {
+		if nodeID != owner && len(replicas) < array.Replication {
+			replicas = append(replicas, nodeID)
+		}
+	}
+	return replicas
+}
+
+// ReplicatePage pushes the local copy of a locally owned page to the page's
+// other replicas, so reads can fall back to them if this node fails
+func (mm *MemoryManager) ReplicatePage(ctx context.Context, arrayID ArrayID, pageID PageID) error {
+	array, err := mm.GetArray(ctx, arrayID)
+	if err != nil {
+		return err
+	}
+	replicas := mm.pageReplicas(array, pageID)
+	if len(replicas) < 2 {
+		return nil
+	}
+
+	version, data, err := mm.PageContents(ctx, arrayID, pageID)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(replicas))
+	var wg sync.WaitGroup
+	for i, nodeID := range replicas[1:] {
+		wg.Add(1)
+		go func(i int, nodeID hyperbus.NodeID) {
+			defer wg.Done()
+			if err := mm.pushPage(ctx, nodeID, arrayID, pageID, version, data, false); err != nil {
+				errs[i] = fmt.Errorf("failed to replicate page %d to %s: %w", pageID, nodeID, err)
+			}
+		}(i, nodeID)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,191 @@
+package dsm
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// newReplicaSet returns a reader and two replicas, all connected, sharing
+// one array owned by the reader
+func newReplicaSet(t *testing.T) (*Array, *MemoryManager, *MemoryManager, *MemoryManager) {
+	logger := log.New(slog.LevelDebug)
+
+	var managers []*MemoryManager
+	var buses []*hyperbus.Bus
+	for _, id := range []hyperbus.NodeID{"node-a", "node-b", "node-c"} {
+		mux := hyperbus.NewMux()
+		bus := hyperbus.New(hyperbus.NodeInfo{ID: id}, mux, logger)
+		mm := NewMemoryManager(bus, logger)
+		mux.Handle(hyperbus.MsgPageRequest, mm)
+		mux.Handle(hyperbus.MsgPagePush, mm)
+		managers = append(managers, mm)
+		buses = append(buses, bus)
+	}
+	hyperbus.ConnectMemory(buses[0], buses[1])
+	hyperbus.ConnectMemory(buses[0], buses[2])
+	hyperbus.ConnectMemory(buses[1], buses[2])
+
+	array, err := managers[0].CreateArray(context.TODO(), 1000, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+	share(array, managers[1])
+	share(array, managers[2])
+	return array, managers[0], managers[1], managers[2]
+}
+
+// replicaPage stores a replica of page 0 holding value at element 0
+func replicaPage(mm *MemoryManager, array *Array, version Version, value int64) {
+	page := NewPage(0, version)
+	page.SetInt64(0, value)
+	_, _ = mm.storeReplica(array.ID, 0, version, page.Bytes())
+}
+
+// storedVersion returns the version of a node's copy of page 0
+func storedVersion(mm *MemoryManager, array *Array) Version {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	if page, exists := mm.pages[pageKey{arrayID: array.ID, pageID: 0}]; exists {
+		return page.Version
+	}
+	return 0
+}
+
+func TestMemoryManager_ReadRepair(t *testing.T) {
+	array, a, b, c := newReplicaSet(t)
+	replicaPage(b, array, 3, 42)
+	replicaPage(c, array, 1, 7)
+
+	// The read returns the freshest copy
+	page, err := a.ReadReplicas(context.TODO(), array.ID, 0, []hyperbus.NodeID{"node-b", "node-c"})
+	assert.NoError(t, err)
+	assert.Equal(t, Version(3), page.Version)
+	v, err := page.GetInt64(0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+
+	// and the stale replica is repaired in the background
+	assert.Eventually(t, func() bool {
+		return storedVersion(c, array) == 3
+	}, time.Second, time.Millisecond)
+	repaired, err := c.readReplica(context.TODO(), "node-c", array.ID, 0)
+	assert.NoError(t, err)
+	v, err = repaired.GetInt64(0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+	assert.Equal(t, Version(3), storedVersion(b, array))
+}
+
+func TestMemoryManager_ReadRepairDisabled(t *testing.T) {
+	array, a, b, c := newReplicaSet(t)
+	a.SetReadRepairRate(0)
+	replicaPage(b, array, 3, 42)
+	replicaPage(c, array, 1, 7)
+
+	page, err := a.ReadReplicas(context.TODO(), array.ID, 0, []hyperbus.NodeID{"node-b", "node-c"})
+	assert.NoError(t, err)
+	assert.Equal(t, Version(3), page.Version)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, Version(1), storedVersion(c, array))
+}
+
+func TestMemoryManager_ReadReplicasQuorum(t *testing.T) {
+	array, a, b, _ := newReplicaSet(t)
+	replicaPage(b, array, 2, 1)
+
+	// Only one of three replicas holds the page
+	_, err := a.ReadReplicas(context.TODO(), array.ID, 0, []hyperbus.NodeID{"node-b", "node-c", "node-d"})
+	assert.ErrorContains(t, err, "read quorum not reached")
+
+	_, err = a.ReadReplicas(context.TODO(), array.ID, 0, nil)
+	assert.Error(t, err)
+}
+
+func TestRepairLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	limiter := newRepairLimiter(2)
+	limiter.now = clock.Now
+
+	assert.True(t, limiter.allow())
+	assert.True(t, limiter.allow())
+	assert.False(t, limiter.allow())
+
+	// The budget refills each second
+	clock.now = clock.now.Add(time.Second)
+	assert.True(t, limiter.allow())
+
+	var disabled *repairLimiter
+	assert.False(t, disabled.allow())
+}
+
+func TestMemoryManager_PushToOwnerRejected(t *testing.T) {
+	array, a, b, _ := newReplicaSet(t)
+
+	page := NewPage(0, 5)
+	page.SetInt64(0, 42)
+	err := b.pushPage(context.TODO(), "node-a", array.ID, 0, 5, page.Bytes(), false)
+	assert.ErrorContains(t, err, "OWNED")
+
+	_, err = a.storeReplica(array.ID, 0, 5, page.Bytes())
+	assert.ErrorIs(t, err, ErrOwnedPage)
+	assert.Equal(t, Version(0), storedVersion(a, array))
+}
+
+func TestMemoryManager_ReadFallsBackToReplicas(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ring := newTestRing(t, "xxhash64", 0)
+	nodes := make(map[hyperbus.NodeID]*MemoryManager)
+	buses := make(map[hyperbus.NodeID]*hyperbus.Bus)
+	for _, id := range []hyperbus.NodeID{"node-a", "node-b", "node-c", "node-d"} {
+		mux := hyperbus.NewMux()
+		bus := hyperbus.New(hyperbus.NodeInfo{ID: id}, mux, logger)
+		mm := NewMemoryManager(bus, logger)
+		mm.SetRing(ring)
+		mux.Handle(hyperbus.MsgPageRequest, mm)
+		mux.Handle(hyperbus.MsgPagePush, mm)
+		nodes[id], buses[id] = mm, bus
+	}
+	for _, id := range []hyperbus.NodeID{"node-a", "node-b", "node-c"} {
+		ring.Add(id)
+	}
+
+	// The reader, node-d, can reach the replicas but not the owner
+	hyperbus.ConnectMemory(buses["node-a"], buses["node-b"])
+	hyperbus.ConnectMemory(buses["node-a"], buses["node-c"])
+	hyperbus.ConnectMemory(buses["node-d"], buses["node-b"])
+	hyperbus.ConnectMemory(buses["node-d"], buses["node-c"])
+
+	a := nodes["node-a"]
+	array, err := a.CreateArray(context.TODO(), 1000, WithPlacement([]hyperbus.NodeID{"node-a"}), WithReplication(3))
+	assert.NoError(t, err)
+	for _, id := range []hyperbus.NodeID{"node-b", "node-c", "node-d"} {
+		share(array, nodes[id])
+	}
+
+	page := NewPage(0, 0)
+	page.SetInt64(0, 42)
+	version, err := a.CommitPageData(context.TODO(), array.ID, 0, 0, page.Bytes())
+	assert.NoError(t, err)
+	assert.NoError(t, a.ReplicatePage(context.TODO(), array.ID, 0))
+	assert.Equal(t, version, storedVersion(nodes["node-b"], array))
+	assert.Equal(t, version, storedVersion(nodes["node-c"], array))
+
+	read, err := nodes["node-d"].ReadPage(context.TODO(), array.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, version, read.Version)
+	v, err := read.GetInt64(0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+
+	// Without replication the owner's failure fails the read
+	single, err := a.CreateArray(context.TODO(), 1000, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+	share(single, nodes["node-d"])
+	_, err = nodes["node-d"].ReadPage(context.TODO(), single.ID, 0, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package dsm
+
+import (
+	"fmt"
+	"hash/crc64"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+)
+
+// DefaultHash is the hash used by the ring unless configured otherwise
+const DefaultHash = "xxhash64"
+
+// DefaultVirtualNodes is how many points each node gets on the ring
+const DefaultVirtualNodes = 64
+
+// HashFunc hashes ring keys. It must give the same result on every node and
+// in every client, so only stable, specified hashes belong here.
+type HashFunc func(data []byte) uint64
+
+var crc64Table = crc64.MakeTable(crc64.ISO)
+
+// hashFuncs maps configurable hash names to their functions
+var hashFuncs = map[string]HashFunc{
+	"xxhash64": xxhash.Sum64,
+	"fnv1a64": func(data []byte) uint64 {
+		h := fnv.New64a()
+		h.Write(data)
+		return h.Sum64()
+	},
+	"crc64-iso": func(data []byte) uint64 {
+		return crc64.Checksum(data, crc64Table)
+	},
+}
+
+// LookupHash returns the hash function with the given name
+func LookupHash(name string) (HashFunc, error) {
+	if name == "" {
+		name = DefaultHash
+	}
+	hash, exists := hashFuncs[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown hash function: %s", name)
+	}
+	return hash, nil
+}
+
+// ringPoint is one virtual node on the ring
+type ringPoint struct {
+	hash   uint64
+	nodeID hyperbus.NodeID
+}
+
+// Ring assigns keys to nodes by consistent hashing
+type Ring struct {
+	hash   HashFunc
+	vnodes int
+	points []ringPoint // sorted by hash
+	nodes  map[hyperbus.NodeID]struct{}
+	mu     sync.RWMutex
+}
+
+// NewRing creates an empty ring. A nil hash uses DefaultHash.
+func NewRing(hash HashFunc, vnodes int) *Ring {
+	if hash == nil {
+		hash = hashFuncs[DefaultHash]
+	}
+	if vnodes <= 0 {
+		vnodes = DefaultVirtualNodes
+	}
+	return &Ring{
+		hash:   hash,
+		vnodes: vnodes,
+		nodes:  make(map[hyperbus.NodeID]struct{}),
+	}
+}
+
+// Add places a node on the ring
+func (r *Ring) Add(nodeID hyperbus.NodeID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.nodes[nodeID]; exists {
+		return
+	}
+	r.nodes[nodeID] = struct{}{}
+
+	for i := 0; i < r.vnodes; i++ {
+		key := string(nodeID) + "#" + strconv.Itoa(i)
+		r.points = append(r.points, ringPoint{hash: r.hash([]byte(key)), nodeID: nodeID})
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash != r.points[j].hash {
+			return r.points[i].hash < r.points[j].hash
+		}
+		// Break collisions the same way on every node
+		return r.points[i].nodeID < r.points[j].nodeID
+	})
+}
+
+// Remove takes a node off the ring
+func (r *Ring) Remove(nodeID hyperbus.NodeID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.nodes[nodeID]; !exists {
+		return
+	}
+	delete(r.nodes, nodeID)
+
+	points := r.points[:0]
+	for _, p := range r.points {
+		if p.nodeID != nodeID {
+			points = append(points, p)
+		}
+	}
+	r.points = points
+}
+
+// Owner returns the node owning a key, or false if the ring is empty
+func (r *Ring) Owner(key []byte) (hyperbus.NodeID, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.points) == 0 {
+		return "", false
+	}
+
+	h := r.hash(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].nodeID, true
+}
+
+// PageOwner returns the node owning a page of an array
+func (r *Ring) PageOwner(arrayID ArrayID, pageID PageID) (hyperbus.NodeID, bool) {
+	return r.Owner([]byte(string(arrayID) + "/" + strconv.FormatInt(int64(pageID), 10)))
+}
+
+// PageReplicas returns up to n distinct nodes holding copies of a page of an
+// array: its owner first, then the next nodes clockwise on the ring
+func (r *Ring) PageReplicas(arrayID ArrayID, pageID PageID, n int) []hyperbus.NodeID {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.points) == 0 || n <= 0 {
+		return nil
+	}
+
+	h := r.hash([]byte(string(arrayID) + "/" + strconv.FormatInt(int64(pageID), 10)))
+	start := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+
+	replicas := make([]hyperbus.NodeID, 0, min(n, len(r.nodes)))
+	seen := make(map[hyperbus.NodeID]bool, n)
+	for i := 0; i < len(r.points) && len(replicas) < n; i++ {
+		nodeID := r.points[(start+i)%len(r.points)].nodeID
+		if !seen[nodeID] {
+			seen[nodeID] = true
+			replicas = append(replicas, nodeID)
+		}
+	}
+	return replicas
+}
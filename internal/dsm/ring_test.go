@@ -0,0 +1,120 @@
+package dsm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRing creates a ring with the given hash over nodes node-0 to node-(n-1)
+func newTestRing(t *testing.T, hashName string, n int) *Ring {
+	hash, err := LookupHash(hashName)
+	assert.NoError(t, err)
+	ring := NewRing(hash, 0)
+	for i := 0; i < n; i++ {
+		ring.Add(hyperbus.NodeID(fmt.Sprintf("node-%d", i)))
+	}
+	return ring
+}
+
+// placement returns the owner of each of the first count pages of an array
+func placement(ring *Ring, arrayID ArrayID, count int) []hyperbus.NodeID {
+	owners := make([]hyperbus.NodeID, count)
+	for i := range owners {
+		owners[i], _ = ring.PageOwner(arrayID, PageID(i))
+	}
+	return owners
+}
+
+func TestRing_SameHashAgrees(t *testing.T) {
+	a := newTestRing(t, "xxhash64", 5)
+	b := newTestRing(t, "", 5)
+
+	assert.Equal(t, placement(a, "array-1", 1000), placement(b, "array-1", 1000))
+
+	// Every node gets a share of the pages
+	counts := make(map[hyperbus.NodeID]int)
+	for _, owner := range placement(a, "array-1", 1000) {
+		counts[owner]++
+	}
+	assert.Len(t, counts, 5)
+}
+
+func TestRing_HashChangesPlacementDeterministically(t *testing.T) {
+	xx := placement(newTestRing(t, "xxhash64", 5), "array-1", 1000)
+	fnv := placement(newTestRing(t, "fnv1a64", 5), "array-1", 1000)
+	assert.NotEqual(t, xx, fnv)
+
+	// The alternative hash is just as stable
+	assert.Equal(t, fnv, placement(newTestRing(t, "fnv1a64", 5), "array-1", 1000))
+
+	// Pinned values guard against a hash changing between versions
+	for name, want := range map[string]uint64{
+		"xxhash64":  0xa01adbc3956cd054,
+		"fnv1a64":   0x242914512e63302e,
+		"crc64-iso": 0x17611955af5c377c,
+	} {
+		hash, err := LookupHash(name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, hash([]byte("holocompute")), name)
+	}
+
+	_, err := LookupHash("md5")
+	assert.Error(t, err)
+}
+
+func TestRing_RemoveMovesOnlyItsPages(t *testing.T) {
+	ring := newTestRing(t, "xxhash64", 4)
+	before := placement(ring, "array-1", 1000)
+
+	ring.Remove("node-3")
+	after := placement(ring, "array-1", 1000)
+	for i := range before {
+		if before[i] != "node-3" {
+			assert.Equal(t, before[i], after[i], "page %d", i)
+		}
+		assert.NotEqual(t, hyperbus.NodeID("node-3"), after[i])
+	}
+}
+
+func TestMemoryManager_CreateArrayOnRing(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-0"}, nil, logger)
+	mm := NewMemoryManager(bus, logger)
+	ring := newTestRing(t, "xxhash64", 3)
+	mm.SetRing(ring)
+
+	array, err := mm.CreateArray(context.TODO(), 10*PageSize/8)
+	assert.NoError(t, err)
+	for i := 0; i < array.NumPages; i++ {
+		owner, exists := array.GetPageOwner(PageID(i))
+		assert.True(t, exists)
+		want, _ := ring.PageOwner(array.ID, PageID(i))
+		assert.Equal(t, want, owner)
+	}
+}
+
+func TestRing_PageReplicas(t *testing.T) {
+	ring := newTestRing(t, "xxhash64", 5)
+
+	for i := 0; i < 100; i++ {
+		replicas := ring.PageReplicas("array-1", PageID(i), 3)
+		assert.Len(t, replicas, 3)
+		owner, _ := ring.PageOwner("array-1", PageID(i))
+		assert.Equal(t, owner, replicas[0])
+
+		distinct := make(map[hyperbus.NodeID]bool)
+		for _, nodeID := range replicas {
+			distinct[nodeID] = true
+		}
+		assert.Len(t, distinct, 3)
+	}
+
+	// There are never more replicas than nodes
+	assert.Len(t, ring.PageReplicas("array-1", 0, 10), 5)
+}
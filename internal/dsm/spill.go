@@ -0,0 +1,127 @@
+package dsm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// spillHeaderSize is the size of the version stored ahead of a spilled page's data
+const spillHeaderSize = 8
+
+// SpillStore keeps pages evicted from memory as one file per page
+type SpillStore struct {
+	dir string
+
+	// Sync each page to disk before it replaces the earlier copy
+	durable bool
+}
+
+// NewSpillStore creates a spill store in dir, creating the directory if needed
+func NewSpillStore(dir string) (*SpillStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	return &SpillStore{dir: dir}, nil
+}
+
+// path returns the file holding a spilled page
+func (s *SpillStore) path(key cacheKey) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%d.page", key.arrayID, key.pageID))
+}
+
+// write stores a page on disk, replacing any earlier copy
+func (s *SpillStore) write(key cacheKey, page *Page) error {
+	data := make([]byte, spillHeaderSize+PageSize)
+	binary.LittleEndian.PutUint64(data, uint64(page.Version))
+	copy(data[spillHeaderSize:], page.Bytes())
+
+	// Write to a temporary file first so a crash never leaves a torn page
+	path := s.path(key)
+	tmp := path + ".tmp"
+	if err := s.writeFile(tmp, data); err != nil {
+		return fmt.Errorf("failed to spill page %d of array %s: %w", key.pageID, key.arrayID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to spill page %d of array %s: %w", key.pageID, key.arrayID, err)
+	}
+	return nil
+}
+
+// writeFile writes data to path, syncing it if the store is durable
+func (s *SpillStore) writeFile(path string, data []byte) error {
+	if !s.durable {
+		return os.WriteFile(path, data, 0600)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// keys lists the pages held in the store
+func (s *SpillStore) keys() ([]cacheKey, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spilled pages: %w", err)
+	}
+
+	var keys []cacheKey
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".page")
+		if !ok {
+			continue
+		}
+		// Array IDs may themselves contain dashes
+		sep := strings.LastIndex(name, "-")
+		if sep < 0 {
+			continue
+		}
+		pageID, err := strconv.ParseInt(name[sep+1:], 10, 32)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, cacheKey{arrayID: ArrayID(name[:sep]), pageID: PageID(pageID)})
+	}
+	return keys, nil
+}
+
+// read loads a spilled page, returning false if it isn't on disk
+func (s *SpillStore) read(key cacheKey) (*Page, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read spilled page %d of array %s: %w", key.pageID, key.arrayID, err)
+	}
+	if len(data) != spillHeaderSize+PageSize {
+		return nil, false, fmt.Errorf("spilled page %d of array %s is %d bytes", key.pageID, key.arrayID, len(data))
+	}
+
+	page := NewPage(key.pageID, Version(binary.LittleEndian.Uint64(data)))
+	copy(page.Bytes(), data[spillHeaderSize:])
+	return page, true, nil
+}
+
+// remove deletes a spilled page, if any
+func (s *SpillStore) remove(key cacheKey) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove spilled page %d of array %s: %w", key.pageID, key.arrayID, err)
+	}
+	return nil
+}
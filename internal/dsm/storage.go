@@ -3,6 +3,7 @@ package dsm
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 )
 
 // pageStorage handles the actual storage of page data
@@ -22,7 +23,7 @@ func (ps *pageStorage) getInt64(offset int) (int64, error) {
 	if offset < 0 || offset+8 > len(ps.data) {
 		return 0, fmt.Errorf("offset out of bounds: %d", offset)
 	}
-	
+
 	return int64(binary.LittleEndian.Uint64(ps.data[offset : offset+8])), nil
 }
 
@@ -31,7 +32,7 @@ func (ps *pageStorage) setInt64(offset int, value int64) error {
 	if offset < 0 || offset+8 > len(ps.data) {
 		return fmt.Errorf("offset out of bounds: %d", offset)
 	}
-	
+
 	binary.LittleEndian.PutUint64(ps.data[offset:offset+8], uint64(value))
 	return nil
 }
@@ -41,8 +42,8 @@ func (ps *pageStorage) getFloat32(offset int) (float32, error) {
 	if offset < 0 || offset+4 > len(ps.data) {
 		return 0, fmt.Errorf("offset out of bounds: %d", offset)
 	}
-	
-	return float32(binary.LittleEndian.Uint32(ps.data[offset : offset+4])), nil
+
+	return math.Float32frombits(binary.LittleEndian.Uint32(ps.data[offset : offset+4])), nil
 }
 
 // setFloat32 writes a 32-bit float to the page
@@ -50,7 +51,64 @@ func (ps *pageStorage) setFloat32(offset int, value float32) error {
 	if offset < 0 || offset+4 > len(ps.data) {
 		return fmt.Errorf("offset out of bounds: %d", offset)
 	}
-	
+
+	binary.LittleEndian.PutUint32(ps.data[offset:offset+4], math.Float32bits(value))
+	return nil
+}
+
+// getFloat64 reads a 64-bit float from the page
+func (ps *pageStorage) getFloat64(offset int) (float64, error) {
+	if offset < 0 || offset+8 > len(ps.data) {
+		return 0, fmt.Errorf("offset out of bounds: %d", offset)
+	}
+
+	return math.Float64frombits(binary.LittleEndian.Uint64(ps.data[offset : offset+8])), nil
+}
+
+// setFloat64 writes a 64-bit float to the page
+func (ps *pageStorage) setFloat64(offset int, value float64) error {
+	if offset < 0 || offset+8 > len(ps.data) {
+		return fmt.Errorf("offset out of bounds: %d", offset)
+	}
+
+	binary.LittleEndian.PutUint64(ps.data[offset:offset+8], math.Float64bits(value))
+	return nil
+}
+
+// getUint16 reads a 16-bit value from the page
+func (ps *pageStorage) getUint16(offset int) (uint16, error) {
+	if offset < 0 || offset+2 > len(ps.data) {
+		return 0, fmt.Errorf("offset out of bounds: %d", offset)
+	}
+
+	return binary.LittleEndian.Uint16(ps.data[offset : offset+2]), nil
+}
+
+// setUint16 writes a 16-bit value to the page
+func (ps *pageStorage) setUint16(offset int, value uint16) error {
+	if offset < 0 || offset+2 > len(ps.data) {
+		return fmt.Errorf("offset out of bounds: %d", offset)
+	}
+
+	binary.LittleEndian.PutUint16(ps.data[offset:offset+2], value)
+	return nil
+}
+
+// getInt32 reads a 32-bit integer from the page
+func (ps *pageStorage) getInt32(offset int) (int32, error) {
+	if offset < 0 || offset+4 > len(ps.data) {
+		return 0, fmt.Errorf("offset out of bounds: %d", offset)
+	}
+
+	return int32(binary.LittleEndian.Uint32(ps.data[offset : offset+4])), nil
+}
+
+// setInt32 writes a 32-bit integer to the page
+func (ps *pageStorage) setInt32(offset int, value int32) error {
+	if offset < 0 || offset+4 > len(ps.data) {
+		return fmt.Errorf("offset out of bounds: %d", offset)
+	}
+
 	binary.LittleEndian.PutUint32(ps.data[offset:offset+4], uint32(value))
 	return nil
-}
\ No newline at end of file
+}
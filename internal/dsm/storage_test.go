@@ -30,6 +30,21 @@ func TestPageStorage(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, float32(3.0), fvalue)
 
+	// Fractional floats round-trip exactly
+	err = storage.setFloat32(12, 3.14)
+	assert.NoError(t, err)
+
+	fvalue, err = storage.getFloat32(12)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(3.14), fvalue)
+
+	err = storage.setFloat64(16, 3.14)
+	assert.NoError(t, err)
+
+	dvalue, err := storage.getFloat64(16)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.14, dvalue)
+
 	// Test bounds checking
 	err = storage.setInt64(PageSize-7, 42)
 	assert.Error(t, err)
@@ -57,6 +72,18 @@ func TestPage(t *testing.T) {
 	fvalue, err := page.GetFloat32(1)
 	assert.NoError(t, err)
 	assert.Equal(t, float32(2.0), fvalue)
+
+	// Test writing and reading a float64 at element index 2
+	err = page.SetFloat64(2, -0.1)
+	assert.NoError(t, err)
+
+	dvalue, err := page.GetFloat64(2)
+	assert.NoError(t, err)
+	assert.Equal(t, -0.1, dvalue)
+
+	// The last element fits, the next one doesn't
+	assert.NoError(t, page.SetFloat64(PageSize/8-1, 1))
+	assert.Error(t, page.SetFloat64(PageSize/8, 1))
 }
 
 func TestMemoryManager(t *testing.T) {
@@ -72,7 +99,7 @@ func TestMemoryManager(t *testing.T) {
 	assert.NotNil(t, array)
 
 	// Test getting a local page
-	page, err := mm.getLocalPage(context.Background(), array.ID, 0, 1)
+	page, err := mm.getLocalPage(context.Background(), array.ID, 0, 1, true)
 	assert.NoError(t, err)
 	assert.NotNil(t, page)
 
@@ -80,7 +107,7 @@ func TestMemoryManager(t *testing.T) {
 	err = mm.storePage(context.Background(), array.ID, 0, page)
 	assert.NoError(t, err)
 
-	page2, err := mm.getLocalPage(context.Background(), array.ID, 0, 1)
+	page2, err := mm.getLocalPage(context.Background(), array.ID, 0, 1, true)
 	assert.NoError(t, err)
 	assert.Equal(t, page, page2)
 }
@@ -0,0 +1,490 @@
+package dsm
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/pkg/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// walFile holds the log, relative to the WAL directory
+const walFile = "pages.wal"
+
+// walCheckpointDir holds the pages flushed by the last checkpoint, relative to the WAL directory
+const walCheckpointDir = "checkpoint"
+
+// walArraysDir holds the metadata of the arrays, relative to the WAL directory
+const walArraysDir = "arrays"
+
+// DefaultCheckpointInterval is how often RunCheckpointer flushes pages unless told otherwise
+const DefaultCheckpointInterval = time.Minute
+
+// walRecordHeaderSize is the size of a record's checksum and payload length
+const walRecordHeaderSize = 8
+
+// walRecord is one page write in the log
+type walRecord struct {
+	arrayID ArrayID
+	pageID  PageID
+	version Version // of the page once the write is applied
+	offset  int     // byte offset within the page
+	data    []byte
+}
+
+// WAL is a write-ahead log of writes to locally owned pages. Writes are
+// logged before they're applied, so pages can be recovered after a crash
+// from the last checkpoint plus the log.
+type WAL struct {
+	file       *os.File
+	checkpoint *SpillStore
+	arrays     string // directory holding one metadata file per array
+
+	// Held across logging and applying a write, and during checkpoints
+	mu sync.Mutex
+}
+
+// OpenWAL opens the write-ahead log in dir, creating it if needed
+func OpenWAL(dir string) (*WAL, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+	checkpoint, err := NewSpillStore(filepath.Join(dir, walCheckpointDir))
+	if err != nil {
+		return nil, err
+	}
+	checkpoint.durable = true
+
+	arrays := filepath.Join(dir, walArraysDir)
+	if err := os.MkdirAll(arrays, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create WAL array directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+	return &WAL{file: file, checkpoint: checkpoint, arrays: arrays}, nil
+}
+
+// arrayPath returns the file holding an array's metadata
+func (w *WAL) arrayPath(arrayID ArrayID) string {
+	return filepath.Join(w.arrays, string(arrayID)+".array")
+}
+
+// saveArray durably writes an array's metadata, replacing any earlier copy
+func (w *WAL) saveArray(array *Array) error {
+	data, err := protobuf.Marshal(arrayToProto(array))
+	if err != nil {
+		return fmt.Errorf("failed to encode array %s: %w", array.ID, err)
+	}
+
+	// Write to a temporary file first so a crash never leaves torn metadata
+	path := w.arrayPath(array.ID)
+	tmp := path + ".tmp"
+	if err := w.checkpoint.writeFile(tmp, data); err != nil {
+		return fmt.Errorf("failed to save array %s: %w", array.ID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save array %s: %w", array.ID, err)
+	}
+	return nil
+}
+
+// removeArray deletes an array's metadata, if any
+func (w *WAL) removeArray(arrayID ArrayID) error {
+	if err := os.Remove(w.arrayPath(arrayID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove array %s: %w", arrayID, err)
+	}
+	return nil
+}
+
+// loadArrays reads the metadata of every saved array
+func (w *WAL) loadArrays() ([]*Array, error) {
+	entries, err := os.ReadDir(w.arrays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved arrays: %w", err)
+	}
+
+	var arrays []*Array
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".array") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(w.arrays, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read saved array: %w", err)
+		}
+		var info proto.ArrayInfo
+		if err := protobuf.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("failed to decode saved array %s: %w", entry.Name(), err)
+		}
+		array, err := arrayFromProto(&info)
+		if err != nil {
+			return nil, err
+		}
+		arrays = append(arrays, array)
+	}
+	return arrays, nil
+}
+
+// Close closes the log without checkpointing
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// appendLocked writes a record and syncs it to disk
+func (w *WAL) appendLocked(rec walRecord) error {
+	payload := make([]byte, 2+len(rec.arrayID)+16, 2+len(rec.arrayID)+16+len(rec.data))
+	binary.LittleEndian.PutUint16(payload, uint16(len(rec.arrayID)))
+	n := 2 + copy(payload[2:], rec.arrayID)
+	binary.LittleEndian.PutUint32(payload[n:], uint32(rec.pageID))
+	binary.LittleEndian.PutUint32(payload[n+4:], uint32(rec.offset))
+	binary.LittleEndian.PutUint64(payload[n+8:], uint64(rec.version))
+	payload = append(payload, rec.data...)
+
+	buf := make([]byte, walRecordHeaderSize, walRecordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf, crc32.ChecksumIEEE(payload))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(payload)))
+	buf = append(buf, payload...)
+
+	if _, err := w.file.Write(buf); err != nil {
+		return fmt.Errorf("failed to append to WAL: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	return nil
+}
+
+// replayLocked calls fn for every intact record in the log. A torn record
+// at the tail, left by a crash mid-append, ends the replay and is cut off so
+// later records follow the last intact one.
+func (w *WAL) replayLocked(fn func(walRecord)) (int, error) {
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to read WAL: %w", err)
+	}
+	reader := bufio.NewReader(w.file)
+
+	count := 0
+	var intact int64
+	header := make([]byte, walRecordHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return count, fmt.Errorf("failed to read WAL: %w", err)
+		}
+
+		payload := make([]byte, binary.LittleEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return count, fmt.Errorf("failed to read WAL: %w", err)
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header) {
+			break
+		}
+
+		rec, ok := decodeWALRecord(payload)
+		if !ok {
+			break
+		}
+		fn(rec)
+		count++
+		intact += int64(walRecordHeaderSize + len(payload))
+	}
+
+	if err := w.file.Truncate(intact); err != nil {
+		return count, fmt.Errorf("failed to truncate torn WAL record: %w", err)
+	}
+	return count, nil
+}
+
+// truncateLocked empties the log once its writes are checkpointed
+func (w *WAL) truncateLocked() error {
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate WAL: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	return nil
+}
+
+// decodeWALRecord parses a record payload, returning false if it is malformed
+func decodeWALRecord(payload []byte) (walRecord, bool) {
+	if len(payload) < 2 {
+		return walRecord{}, false
+	}
+	n := 2 + int(binary.LittleEndian.Uint16(payload))
+	if len(payload) < n+16 {
+		return walRecord{}, false
+	}
+
+	rec := walRecord{
+		arrayID: ArrayID(payload[2:n]),
+		pageID:  PageID(binary.LittleEndian.Uint32(payload[n:])),
+		offset:  int(binary.LittleEndian.Uint32(payload[n+4:])),
+		version: Version(binary.LittleEndian.Uint64(payload[n+8:])),
+		data:    payload[n+16:],
+	}
+	if rec.offset+len(rec.data) > PageSize {
+		return walRecord{}, false
+	}
+	return rec, true
+}
+
+// SetWAL makes writes to locally owned pages go through the write-ahead
+// log w. Call Recover before serving writes to restore pages it holds.
+func (mm *MemoryManager) SetWAL(w *WAL) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.wal = w
+}
+
+// saveArray saves an array's metadata to the WAL, if one is set, so Recover
+// can bring the array back along with its pages
+func (mm *MemoryManager) saveArray(array *Array) error {
+	mm.mu.RLock()
+	w := mm.wal
+	mm.mu.RUnlock()
+	if w == nil {
+		return nil
+	}
+	return w.saveArray(array)
+}
+
+// WriteElement applies write to the element at index of a page of an array.
+// For locally owned pages with a WAL set, the element's new bytes are logged
+// first and the write fails without being applied if logging fails.
+func (mm *MemoryManager) WriteElement(arrayID ArrayID, elemType ElementType, page *Page, index int, write func(page *Page, index int) error) error {
+	mm.mu.RLock()
+	w := mm.wal
+	mm.mu.RUnlock()
+	if w == nil || !mm.OwnsPages(arrayID, page.ID, page.ID) {
+		return write(page, index)
+	}
+
+	// Out of range writes fail without reaching the log
+	size := elemType.Size()
+	if index < 0 || (index+1)*size > len(page.Bytes()) {
+		return write(page, index)
+	}
+
+	// Encode the element on its own to learn the bytes to log
+	scratch := &Page{ID: page.ID, storage: newPageStorage(size)}
+	if err := write(scratch, 0); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rec := walRecord{arrayID: arrayID, pageID: page.ID, version: page.Version, offset: index * size, data: scratch.Bytes()}
+	if err := w.appendLocked(rec); err != nil {
+		return err
+	}
+	return write(page, index)
+}
+
+// WritePageData copies data over the start of a page. A locally owned
+// page's change is logged to the WAL as a single record, however many
+// elements it spans.
+func (mm *MemoryManager) WritePageData(arrayID ArrayID, page *Page, data []byte) error {
+	if len(data) > len(page.Bytes()) {
+		return fmt.Errorf("page data is %d bytes, page holds %d", len(data), len(page.Bytes()))
+	}
+	if !mm.OwnsPages(arrayID, page.ID, page.ID) {
+		copy(page.Bytes(), data)
+		return nil
+	}
+	return mm.writePage(arrayID, page, data, false)
+}
+
+// writePage replaces the start of a locally owned page's contents with
+// data, moving the page on to its next version if commit is set. With a WAL
+// set, the span of bytes that changed is logged first as a single record
+// together with the page's version, and the page is left as it was if
+// logging fails. The version changes under the WAL's lock, so a checkpoint
+// never sees a commit's data without its version.
+func (mm *MemoryManager) writePage(arrayID ArrayID, page *Page, data []byte, commit bool) error {
+	mm.mu.RLock()
+	w := mm.wal
+	mm.mu.RUnlock()
+	if w == nil {
+		copy(page.Bytes(), data)
+		if commit {
+			page.Version++
+		}
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current := page.Bytes()
+	first := 0
+	for first < len(data) && data[first] == current[first] {
+		first++
+	}
+	last := len(data)
+	for last > first && data[last-1] == current[last-1] {
+		last--
+	}
+
+	// A commit changing no bytes still logs its version
+	version := page.Version
+	if commit {
+		version++
+	} else if first == last {
+		return nil
+	}
+
+	rec := walRecord{arrayID: arrayID, pageID: page.ID, version: version, offset: first, data: data[first:last]}
+	if err := w.appendLocked(rec); err != nil {
+		return err
+	}
+	copy(current[first:last], data[first:last])
+	page.Version = version
+	return nil
+}
+
+// Recover restores the saved arrays and their locally owned pages from the
+// WAL's last checkpoint and replays the writes logged since, returning the
+// number replayed
+func (mm *MemoryManager) Recover() (int, error) {
+	mm.mu.RLock()
+	w := mm.wal
+	mm.mu.RUnlock()
+	if w == nil {
+		return 0, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	arrays, err := w.loadArrays()
+	if err != nil {
+		return 0, err
+	}
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	for _, array := range arrays {
+		if _, exists := mm.arrays[array.ID]; !exists {
+			mm.arrays[array.ID] = array
+		}
+	}
+
+	keys, err := w.checkpoint.keys()
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		page, exists, err := w.checkpoint.read(key)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			mm.putPageLocked(pageKey{arrayID: key.arrayID, pageID: key.pageID}, page)
+		}
+	}
+
+	replayed, err := w.replayLocked(func(rec walRecord) {
+		key := pageKey{arrayID: rec.arrayID, pageID: rec.pageID}
+		page, exists := mm.pages[key]
+		if !exists {
+			page = NewPage(rec.pageID, rec.version)
+			mm.putPageLocked(key, page)
+		}
+		copy(page.Bytes()[rec.offset:], rec.data)
+		page.Version = rec.version
+	})
+	if err != nil {
+		return replayed, err
+	}
+
+	mm.logger.Info("recovered pages from WAL", "arrays", len(arrays), "checkpointed", len(keys), "replayed", replayed)
+	return replayed, nil
+}
+
+// Checkpoint durably flushes the metadata of every array and every locally
+// owned page, then truncates the WAL
+func (mm *MemoryManager) Checkpoint() error {
+	mm.mu.RLock()
+	w := mm.wal
+	mm.mu.RUnlock()
+	if w == nil {
+		return nil
+	}
+
+	// No write is logged or applied while the pages are flushed
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	mm.mu.RLock()
+	arrays := make([]*Array, 0, len(mm.arrays))
+	for _, array := range mm.arrays {
+		arrays = append(arrays, array)
+	}
+	pages := make(map[pageKey]*Page, len(mm.pages))
+	for key, page := range mm.pages {
+		pages[key] = page
+	}
+	mm.mu.RUnlock()
+
+	for _, array := range arrays {
+		if err := w.saveArray(array); err != nil {
+			return fmt.Errorf("failed to checkpoint array: %w", err)
+		}
+	}
+	for key, page := range pages {
+		if err := w.checkpoint.write(cacheKey{arrayID: key.arrayID, pageID: key.pageID}, page); err != nil {
+			return fmt.Errorf("failed to checkpoint page: %w", err)
+		}
+	}
+	if err := w.truncateLocked(); err != nil {
+		return err
+	}
+
+	mm.logger.Debug("checkpointed pages", "pages", len(pages))
+	return nil
+}
+
+// RunCheckpointer checkpoints every interval until ctx is done, so the WAL
+// doesn't grow without bound
+func (mm *MemoryManager) RunCheckpointer(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := mm.Checkpoint(); err != nil {
+				mm.logger.Error("failed to checkpoint pages", "error", err)
+			}
+		}
+	}
+}
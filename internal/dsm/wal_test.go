@@ -0,0 +1,285 @@
+package dsm
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// newWALManager creates a memory manager for node-a logging writes to the WAL in dir
+func newWALManager(t *testing.T, dir string) (*MemoryManager, *WAL) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-a"}, nil, logger)
+	mm := NewMemoryManager(bus, logger)
+
+	wal, err := OpenWAL(dir)
+	assert.NoError(t, err)
+	mm.SetWAL(wal)
+	return mm, wal
+}
+
+// setInt64 writes v at index of a page of array through the WAL
+func setInt64(t *testing.T, mm *MemoryManager, array *Array, pageID PageID, index int, v int64) {
+	page, err := mm.RequestPage(context.TODO(), array.ID, pageID, array.Version)
+	assert.NoError(t, err)
+	assert.NoError(t, mm.WriteElement(array.ID, ElementInt64, page, index, func(page *Page, index int) error {
+		return page.SetInt64(index, v)
+	}))
+}
+
+// recoveredInt64 reads index of a page restored by Recover
+func recoveredInt64(t *testing.T, mm *MemoryManager, arrayID ArrayID, pageID PageID, index int) int64 {
+	mm.mu.RLock()
+	page, exists := mm.pages[pageKey{arrayID: arrayID, pageID: pageID}]
+	mm.mu.RUnlock()
+	if !assert.True(t, exists, "page %d not recovered", pageID) {
+		return 0
+	}
+	v, err := page.GetInt64(index)
+	assert.NoError(t, err)
+	return v
+}
+
+func TestMemoryManager_WALRecover(t *testing.T) {
+	dir := t.TempDir()
+	mm, wal := newWALManager(t, dir)
+
+	array, err := mm.CreateArray(context.TODO(), 2*PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+
+	// One write is checkpointed, the next two are only in the log
+	setInt64(t, mm, array, 0, 3, 42)
+	assert.NoError(t, mm.Checkpoint())
+	setInt64(t, mm, array, 0, 4, 43)
+	setInt64(t, mm, array, 1, 0, 44)
+
+	// Crash: stop without flushing
+	assert.NoError(t, wal.Close())
+
+	restarted, wal := newWALManager(t, dir)
+	defer wal.Close()
+	replayed, err := restarted.Recover()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+
+	assert.Equal(t, int64(42), recoveredInt64(t, restarted, array.ID, 0, 3))
+	assert.Equal(t, int64(43), recoveredInt64(t, restarted, array.ID, 0, 4))
+	assert.Equal(t, int64(44), recoveredInt64(t, restarted, array.ID, 1, 0))
+
+	// The array itself came back too
+	recovered, err := restarted.GetArray(context.TODO(), array.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, array.Len(), recovered.Len())
+	assert.Equal(t, array.NumPages, recovered.NumPages)
+	owner, _ := recovered.GetPageOwner(1)
+	assert.Equal(t, hyperbus.NodeID("node-a"), owner)
+
+	// A checkpoint flushes the recovered pages and empties the log
+	assert.NoError(t, restarted.Checkpoint())
+	info, err := os.Stat(filepath.Join(dir, walFile))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
+
+func TestMemoryManager_CommitPageDataLogged(t *testing.T) {
+	dir := t.TempDir()
+	mm, wal := newWALManager(t, dir)
+
+	array, err := mm.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+
+	data := make([]byte, PageSize)
+	data[100], data[200] = 1, 2
+	committed, err := mm.CommitPageData(context.TODO(), array.ID, 0, array.Version, data)
+	assert.NoError(t, err)
+	assert.Equal(t, array.Version+1, committed)
+
+	// A commit that lost the race leaves the page and the log alone
+	stale := make([]byte, PageSize)
+	stale[300] = 3
+	_, err = mm.CommitPageData(context.TODO(), array.ID, 0, array.Version, stale)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	version, contents, err := mm.PageContents(context.TODO(), array.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, committed, version)
+	assert.Equal(t, data, contents)
+
+	assert.NoError(t, wal.Close())
+
+	// The changed span was logged as one record
+	restarted, wal := newWALManager(t, dir)
+	defer wal.Close()
+	replayed, err := restarted.Recover()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	restarted.mu.RLock()
+	page := restarted.pages[pageKey{arrayID: array.ID, pageID: 0}]
+	restarted.mu.RUnlock()
+	assert.Equal(t, data, page.Bytes())
+}
+
+func TestMemoryManager_WritePageDataLogged(t *testing.T) {
+	dir := t.TempDir()
+	mm, wal := newWALManager(t, dir)
+
+	array, err := mm.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+	page, err := mm.RequestPage(context.TODO(), array.ID, 0, array.Version)
+	assert.NoError(t, err)
+
+	// Many elements written at once make one record
+	data := make([]byte, 64*8)
+	for i := range data {
+		data[i] = byte(i%255 + 1)
+	}
+	assert.NoError(t, mm.WritePageData(array.ID, page, data))
+	assert.Error(t, mm.WritePageData(array.ID, page, make([]byte, PageSize+1)))
+	assert.NoError(t, wal.Close())
+
+	restarted, wal := newWALManager(t, dir)
+	defer wal.Close()
+	replayed, err := restarted.Recover()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	restarted.mu.RLock()
+	recovered := restarted.pages[pageKey{arrayID: array.ID, pageID: 0}]
+	restarted.mu.RUnlock()
+	assert.Equal(t, data, recovered.Bytes()[:len(data)])
+}
+
+func TestMemoryManager_WALTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	mm, wal := newWALManager(t, dir)
+
+	array, err := mm.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+	setInt64(t, mm, array, 0, 0, 7)
+	assert.NoError(t, wal.Close())
+
+	// A crash mid-append leaves half a record at the tail
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte{0xde, 0xad, 0xbe, 0xef, 0xff})
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	restarted, wal := newWALManager(t, dir)
+	replayed, err := restarted.Recover()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.Equal(t, int64(7), recoveredInt64(t, restarted, array.ID, 0, 0))
+
+	// Writes after recovery follow the last intact record
+	setInt64(t, restarted, array, 0, 1, 8)
+	assert.NoError(t, wal.Close())
+
+	again, wal := newWALManager(t, dir)
+	defer wal.Close()
+	replayed, err = again.Recover()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Equal(t, int64(8), recoveredInt64(t, again, array.ID, 0, 1))
+}
+
+func TestMemoryManager_WALArrayMetadata(t *testing.T) {
+	dir := t.TempDir()
+	mm, wal := newWALManager(t, dir)
+
+	kept, err := mm.CreateArray(context.TODO(), PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}), WithElementType(ElementFloat64))
+	assert.NoError(t, err)
+	assert.NoError(t, mm.SealArray(context.TODO(), kept.ID))
+	deleted, err := mm.CreateArray(context.TODO(), PageSize/8)
+	assert.NoError(t, err)
+	assert.NoError(t, mm.DeleteArray(context.TODO(), deleted.ID))
+	assert.NoError(t, wal.Close())
+
+	restarted, wal := newWALManager(t, dir)
+	defer wal.Close()
+	_, err = restarted.Recover()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []ArrayID{kept.ID}, restarted.ListArrays())
+	recovered, err := restarted.GetArray(context.TODO(), kept.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ElementFloat64, recovered.ElementType)
+	assert.True(t, recovered.ReadOnly)
+}
+
+func TestMemoryManager_CompactArrayLogged(t *testing.T) {
+	dir := t.TempDir()
+	mm, wal := newWALManager(t, dir)
+
+	array, err := mm.CreateArray(context.TODO(), 10, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		setInt64(t, mm, array, 0, i, int64(i))
+	}
+	compacted, err := mm.CompactArray(context.TODO(), array.ID, func(index int) bool { return index%2 == 1 })
+	assert.NoError(t, err)
+	assert.NoError(t, wal.Close())
+
+	// The compacted array and its elements are recovered from the log alone
+	restarted, wal := newWALManager(t, dir)
+	defer wal.Close()
+	_, err = restarted.Recover()
+	assert.NoError(t, err)
+	recovered, err := restarted.GetArray(context.TODO(), compacted.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, recovered.Len())
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, int64(2*i+1), recoveredInt64(t, restarted, compacted.ID, 0, i))
+	}
+}
+
+func TestMemoryManager_WALRecoverVersions(t *testing.T) {
+	dir := t.TempDir()
+	mm, wal := newWALManager(t, dir)
+
+	array, err := mm.CreateArray(context.TODO(), 2*PageSize/8, WithPlacement([]hyperbus.NodeID{"node-a"}))
+	assert.NoError(t, err)
+
+	// Page 0 is committed twice, once before the checkpoint and once after;
+	// page 1 is committed without changing its bytes
+	data := make([]byte, PageSize)
+	data[0] = 1
+	first, err := mm.CommitPageData(context.TODO(), array.ID, 0, array.Version, data)
+	assert.NoError(t, err)
+	assert.NoError(t, mm.Checkpoint())
+	data[1] = 2
+	second, err := mm.CommitPageData(context.TODO(), array.ID, 0, first, data)
+	assert.NoError(t, err)
+	bumped, err := mm.CommitPage(context.TODO(), array.ID, 1, array.Version)
+	assert.NoError(t, err)
+
+	// Crash: stop without flushing
+	assert.NoError(t, wal.Close())
+
+	restarted, wal := newWALManager(t, dir)
+	defer wal.Close()
+	_, err = restarted.Recover()
+	assert.NoError(t, err)
+
+	// The pages came back at the versions they were committed at, so
+	// writers holding an older version still lose
+	version, err := restarted.PageVersion(context.TODO(), array.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, second, version)
+	version, err = restarted.PageVersion(context.TODO(), array.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, bumped, version)
+
+	_, err = restarted.CommitPageData(context.TODO(), array.ID, 0, first, data)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	_, err = restarted.CommitPage(context.TODO(), array.ID, 1, array.Version)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	committed, err := restarted.CommitPageData(context.TODO(), array.ID, 0, second, data)
+	assert.NoError(t, err)
+	assert.Equal(t, second+1, committed)
+}
@@ -0,0 +1,197 @@
+package hyperbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// callStream is the data stream shared by every call to one node
+type callStream struct {
+	stream Stream
+	mu     sync.Mutex // serializes writes from concurrent calls
+}
+
+// write sends a request on the shared stream
+func (cs *callStream) write(ctx context.Context, msg []byte) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.stream.WriteMessage(ctx, msg)
+}
+
+// callWaiter receives the response to one call. The channel is closed
+// without a response if the stream carrying the call fails.
+type callWaiter struct {
+	stream   *callStream
+	response chan []byte
+}
+
+// Call sends a request to a node and blocks until the response carrying
+// the same request ID arrives, returning it with its header. The handler
+// serving msgType must answer with EncodeReply. Calls to a node share one
+// stream, and responses are matched to their calls by request ID.
+func (b *Bus) Call(ctx context.Context, nodeID NodeID, msgType MessageType, pb proto.Message) ([]byte, error) {
+	requestID := b.nextRequestID.Add(1)
+	msg, err := EncodeMessageWithID(msgType, requestID, pb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	cs, err := b.callStream(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	waiter := b.addWaiter(requestID, cs)
+	defer b.removeWaiter(requestID)
+
+	if err := cs.write(ctx, msg); err != nil {
+		b.dropCallStream(nodeID, cs)
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	select {
+	case resp, ok := <-waiter:
+		if !ok {
+			return nil, fmt.Errorf("stream to %s closed before request %d was answered", nodeID, requestID)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no response from %s to request %d: %w", nodeID, requestID, ctx.Err())
+	}
+}
+
+// callStream returns the stream shared by calls to a node, opening it and
+// starting to route its responses on first use
+func (b *Bus) callStream(ctx context.Context, nodeID NodeID) (*callStream, error) {
+	b.callMu.Lock()
+	cs, exists := b.callStreams[nodeID]
+	b.callMu.Unlock()
+	if exists {
+		return cs, nil
+	}
+
+	stream, err := b.OpenStream(ctx, nodeID, DataStream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data stream to %s: %w", nodeID, err)
+	}
+
+	// Another call may have opened one meanwhile
+	b.callMu.Lock()
+	if existing, exists := b.callStreams[nodeID]; exists {
+		b.callMu.Unlock()
+		stream.Close()
+		return existing, nil
+	}
+	cs = &callStream{stream: stream}
+	b.callStreams[nodeID] = cs
+	b.callMu.Unlock()
+
+	go b.routeResponses(nodeID, cs)
+	return cs, nil
+}
+
+// dropCallStream closes a node's shared stream and fails the calls still
+// waiting on it; the next call opens a new one
+func (b *Bus) dropCallStream(nodeID NodeID, cs *callStream) {
+	b.callMu.Lock()
+	if b.callStreams[nodeID] == cs {
+		delete(b.callStreams, nodeID)
+	}
+	for requestID, waiter := range b.calls {
+		if waiter.stream == cs {
+			delete(b.calls, requestID)
+			close(waiter.response)
+		}
+	}
+	b.callMu.Unlock()
+
+	cs.stream.Close()
+}
+
+// addWaiter registers a channel receiving the response to requestID, sent on cs
+func (b *Bus) addWaiter(requestID uint64, cs *callStream) <-chan []byte {
+	b.callMu.Lock()
+	defer b.callMu.Unlock()
+
+	waiter := &callWaiter{stream: cs, response: make(chan []byte, 1)}
+	b.calls[requestID] = waiter
+	return waiter.response
+}
+
+// removeWaiter forgets the waiter for requestID once its call returns
+func (b *Bus) removeWaiter(requestID uint64) {
+	b.callMu.Lock()
+	defer b.callMu.Unlock()
+	delete(b.calls, requestID)
+}
+
+// routeResponses hands each response read from a shared stream to the call
+// waiting on its request ID, until the stream ends
+func (b *Bus) routeResponses(nodeID NodeID, cs *callStream) {
+	defer b.dropCallStream(nodeID, cs)
+
+	for {
+		data, err := cs.stream.ReadMessage(context.Background())
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, ErrStreamClosed) {
+				b.logger.Debug("failed to read response", "node_id", nodeID, "error", err)
+			}
+			return
+		}
+
+		header, err := DecodeHeader(data)
+		if err != nil {
+			b.logger.Warn("dropping malformed response", "node_id", nodeID, "error", err)
+			continue
+		}
+		if !b.deliverResponse(header.RequestID, data) {
+			b.logger.Debug("dropping response without a waiter", "node_id", nodeID, "request_id", header.RequestID, "type", header.Type)
+		}
+	}
+}
+
+// deliverResponse passes a response to the call waiting on requestID,
+// reporting false if none is
+func (b *Bus) deliverResponse(requestID uint64, data []byte) bool {
+	b.callMu.Lock()
+	waiter, exists := b.calls[requestID]
+	delete(b.calls, requestID)
+	b.callMu.Unlock()
+
+	if !exists {
+		return false
+	}
+	waiter.response <- data
+	return true
+}
+
+// closeCallStreams closes every shared stream, failing the calls waiting on them
+func (b *Bus) closeCallStreams() {
+	b.callMu.Lock()
+	streams := b.callStreams
+	b.callStreams = make(map[NodeID]*callStream)
+	b.callMu.Unlock()
+
+	for nodeID, cs := range streams {
+		b.dropCallStream(nodeID, cs)
+	}
+}
+
+// lockedStream serializes writes to a stream shared by concurrently served
+// requests
+type lockedStream struct {
+	Stream
+	mu *sync.Mutex
+}
+
+// WriteMessage writes a message once no other request is writing
+func (s lockedStream) WriteMessage(ctx context.Context, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Stream.WriteMessage(ctx, data)
+}
@@ -0,0 +1,193 @@
+package hyperbus
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowEchoServer answers page requests with the page ID as version, taking
+// longer for lower page IDs so later requests are answered first
+type slowEchoServer struct {
+	correlate bool // echo the request ID
+}
+
+func (s *slowEchoServer) HandleMessage(ctx context.Context, conn Connection, stream Stream, data []byte) error {
+	var req proto.PageRequest
+	if err := DecodeMessage(data[HeaderSize:], &req); err != nil {
+		return err
+	}
+	time.Sleep(time.Duration(32-req.PageId) * time.Millisecond)
+
+	resp := &proto.PageResponse{Status: proto.PageResponse_OK, Version: int64(req.PageId)}
+	msg, err := EncodeMessage(MsgPageResponse, resp)
+	if s.correlate {
+		msg, err = EncodeReply(data, MsgPageResponse, resp)
+	}
+	if err != nil {
+		return err
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// newCallPair connects a client bus to a bus serving page requests with server
+func newCallPair(server MessageHandler) *Bus {
+	logger := log.New(slog.LevelDebug)
+	mux := NewMux()
+	mux.Handle(MsgPageRequest, server)
+
+	client := New(NodeInfo{ID: "client"}, NewMux(), logger)
+	ConnectMemory(client, New(NodeInfo{ID: "server"}, mux, logger))
+	return client
+}
+
+func TestBus_CallConcurrent(t *testing.T) {
+	client := newCallPair(&slowEchoServer{correlate: true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Responses come back in the reverse order of the requests
+	var wg sync.WaitGroup
+	for pageID := int32(0); pageID < 32; pageID++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			data, err := client.Call(ctx, "server", MsgPageRequest, &proto.PageRequest{PageId: pageID})
+			if !assert.NoError(t, err) {
+				return
+			}
+			header, err := DecodeHeader(data)
+			assert.NoError(t, err)
+			assert.Equal(t, MsgPageResponse, header.Type)
+			assert.NotZero(t, header.RequestID)
+
+			var resp proto.PageResponse
+			assert.NoError(t, DecodeMessage(data[HeaderSize:], &resp))
+			assert.Equal(t, int64(pageID), resp.Version)
+		}()
+	}
+	wg.Wait()
+
+	// Every waiter is gone once its call returns
+	client.callMu.Lock()
+	assert.Empty(t, client.calls)
+	client.callMu.Unlock()
+}
+
+func TestBus_CallUncorrelatedResponse(t *testing.T) {
+	client := newCallPair(&slowEchoServer{})
+
+	// A response without the request's ID never reaches the caller
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := client.Call(ctx, "server", MsgPageRequest, &proto.PageRequest{PageId: 31})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// streamRecorder answers page requests and records the streams they came on
+type streamRecorder struct {
+	mu      sync.Mutex
+	streams map[*sync.Mutex]bool
+}
+
+func (s *streamRecorder) HandleMessage(ctx context.Context, conn Connection, stream Stream, data []byte) error {
+	s.mu.Lock()
+	s.streams[stream.(lockedStream).mu] = true
+	s.mu.Unlock()
+
+	msg, err := EncodeReply(data, MsgPageResponse, &proto.PageResponse{Status: proto.PageResponse_OK})
+	if err != nil {
+		return err
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// count returns the number of distinct streams seen
+func (s *streamRecorder) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams)
+}
+
+func TestBus_CallSharesStream(t *testing.T) {
+	server := &streamRecorder{streams: make(map[*sync.Mutex]bool)}
+	client := newCallPair(server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Call(ctx, "server", MsgPageRequest, &proto.PageRequest{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, server.count())
+
+	// Once the shared stream is gone the next call opens another
+	client.callMu.Lock()
+	cs := client.callStreams["server"]
+	client.callMu.Unlock()
+	client.dropCallStream("server", cs)
+	_, err := client.Call(ctx, "server", MsgPageRequest, &proto.PageRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, server.count())
+}
+
+// silentServer never answers
+type silentServer struct{}
+
+func (silentServer) HandleMessage(ctx context.Context, conn Connection, stream Stream, data []byte) error {
+	return nil
+}
+
+func TestBus_CallFailsWithItsStream(t *testing.T) {
+	client := newCallPair(silentServer{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Call(ctx, "server", MsgPageRequest, &proto.PageRequest{})
+		done <- err
+	}()
+	assert.Eventually(t, func() bool {
+		client.callMu.Lock()
+		defer client.callMu.Unlock()
+		return len(client.calls) == 1
+	}, time.Second, time.Millisecond)
+
+	// Closing the bus fails the waiting call without waiting out its deadline
+	assert.NoError(t, client.Close())
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "closed before request")
+	case <-time.After(time.Second):
+		t.Fatal("call still waiting after its stream closed")
+	}
+}
+
+func TestEncodeReply(t *testing.T) {
+	request, err := EncodeMessageWithID(MsgPageRequest, 42, &proto.PageRequest{PageId: 1})
+	assert.NoError(t, err)
+
+	reply, err := EncodeReply(request, MsgPageResponse, &proto.PageResponse{})
+	assert.NoError(t, err)
+	header, err := DecodeHeader(reply)
+	assert.NoError(t, err)
+	assert.Equal(t, MsgPageResponse, header.Type)
+	assert.Equal(t, uint64(42), header.RequestID)
+}
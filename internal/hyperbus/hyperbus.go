@@ -3,16 +3,32 @@ package hyperbus
 import (
 	"context"
 	"crypto/ed25519"
+	"crypto/mlkem"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/melihxz/holocompute/internal/log"
 	"github.com/melihxz/holocompute/pkg/proto"
+	"golang.org/x/sync/singleflight"
 )
 
 // NodeID represents a unique identifier for a node
 type NodeID string
 
+// NodeIDFromKey derives a stable node ID from a node's identity key
+func NodeIDFromKey(pub ed25519.PublicKey) NodeID {
+	sum := sha256.Sum256(pub)
+	return NodeID("node-" + hex.EncodeToString(sum[:8]))
+}
+
 // NodeInfo contains information about a node
 type NodeInfo struct {
 	ID           NodeID
@@ -62,12 +78,76 @@ type MessageHandler interface {
 	HandleMessage(ctx context.Context, conn Connection, stream Stream, data []byte) error
 }
 
+// PeerObserver is notified when a peer completes the handshake
+type PeerObserver interface {
+	// OnPeerHello is called with the peer's ControlHello and its local receive time
+	OnPeerHello(nodeID NodeID, hello *proto.ControlHello, receivedAt time.Time)
+}
+
+// PeerDirectory resolves nodes the bus may connect to on demand
+type PeerDirectory interface {
+	// LookupPeer returns the node's info, or ErrNodeDead if it is known to be dead
+	LookupPeer(nodeID NodeID) (NodeInfo, error)
+}
+
+// Dialer establishes a connection to a node and registers it with the bus
+type Dialer func(ctx context.Context, node NodeInfo) error
+
+// ErrNoConnection is returned when there is no connection to a node
+var ErrNoConnection = errors.New("no connection to node")
+
+// ErrNoAddress is returned when listening on or dialing a node without an address
+var ErrNoAddress = errors.New("node has no address")
+
+// ErrNodeDead is returned when sending to a node that membership reports dead
+var ErrNodeDead = errors.New("node is dead")
+
+// Connect attempts made by SendControlMessage before giving up on a node
+const (
+	maxConnectAttempts = 3
+	connectBackoff     = 50 * time.Millisecond
+)
+
 // Bus represents the hyperbus network layer
 type Bus struct {
 	localNode   NodeInfo
 	connections map[NodeID]Connection
+	sessions    map[Connection]*session      // what each connection's handshake established
+	usage       map[Connection]*atomic.Int64 // when each connection was last used
+	connMu      sync.RWMutex                 // guards connections, sessions and usage
+	dials       singleflight.Group           // concurrent connects to a node share one dial
 	handler     MessageHandler
-	logger      *log.Logger
+	observer    PeerObserver
+	peers       PeerDirectory
+	dialer      Dialer
+	listener    net.Listener // set by ListenTCP
+	pqKey       *mlkem.DecapsulationKey768
+	identity    ed25519.PrivateKey // signs the PQ handshake
+
+	// Calls waiting for a response, by request ID
+	calls         map[uint64]*callWaiter
+	callStreams   map[NodeID]*callStream // one shared stream per node for calls
+	callMu        sync.Mutex             // guards calls and callStreams
+	nextRequestID atomic.Uint64
+
+	// Handshake settings
+	minVersion       uint32
+	maxVersion       uint32
+	handshakeTimeout time.Duration
+	placementHash    string
+
+	// Keepalive settings
+	keepaliveInterval time.Duration
+	idleTimeout       time.Duration
+
+	// Largest message body accepted from peers
+	maxMessageSize uint32
+
+	// Connections unused for this long are closed; 0 keeps them
+	connIdleTimeout time.Duration
+
+	now    func() time.Time
+	logger *log.Logger
 }
 
 // New creates a new hyperbus
@@ -75,8 +155,22 @@ func New(localNode NodeInfo, handler MessageHandler, logger *log.Logger) *Bus {
 	return &Bus{
 		localNode:   localNode,
 		connections: make(map[NodeID]Connection),
+		sessions:    make(map[Connection]*session),
+		usage:       make(map[Connection]*atomic.Int64),
+		calls:       make(map[uint64]*callWaiter),
+		callStreams: make(map[NodeID]*callStream),
 		handler:     handler,
+		now:         time.Now,
 		logger:      logger,
+
+		minVersion:       MinProtocolVersion,
+		maxVersion:       ProtocolVersion,
+		handshakeTimeout: DefaultHandshakeTimeout,
+
+		keepaliveInterval: DefaultKeepaliveInterval,
+		idleTimeout:       DefaultIdleTimeout,
+
+		maxMessageSize: DefaultMaxMessageSize,
 	}
 }
 
@@ -85,28 +179,157 @@ func (b *Bus) LocalNode() NodeInfo {
 	return b.localNode
 }
 
-// Connect establishes a connection to a remote node
-func (b *Bus) Connect(ctx context.Context, node NodeInfo) error {
-	// TODO: Implement connection logic
-	b.logger.Info("connecting to node", "node_id", node.ID, "address", node.Address)
-	return nil
+// Peers returns the IDs of connected nodes in sorted order
+func (b *Bus) Peers() []NodeID {
+	b.connMu.RLock()
+	peers := make([]NodeID, 0, len(b.connections))
+	for nodeID := range b.connections {
+		peers = append(peers, nodeID)
+	}
+	b.connMu.RUnlock()
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	return peers
 }
 
-// SendControlMessage sends a control message to a specific node
-func (b *Bus) SendControlMessage(ctx context.Context, nodeID NodeID, msg []byte) error {
-	// Get the connection
+// getConnection returns the connection held to a node, if any
+func (b *Bus) getConnection(nodeID NodeID) (Connection, bool) {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+
 	conn, exists := b.connections[nodeID]
+	return conn, exists
+}
+
+// putConnection records the connection to a node, replacing any earlier one
+func (b *Bus) putConnection(nodeID NodeID, conn Connection) {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+
+	if old, exists := b.connections[nodeID]; exists && old != conn {
+		delete(b.usage, old)
+	}
+	b.connections[nodeID] = conn
+
+	used := &atomic.Int64{}
+	used.Store(b.now().UnixNano())
+	b.usage[conn] = used
+}
+
+// removeConnection forgets the connection to a node if it is still conn
+func (b *Bus) removeConnection(nodeID NodeID, conn Connection) {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+
+	if b.connections[nodeID] == conn {
+		delete(b.connections, nodeID)
+	}
+	delete(b.sessions, conn)
+	delete(b.usage, conn)
+}
+
+// liveConnection reports whether a connection to the node is held and not
+// known to be closed
+func (b *Bus) liveConnection(nodeID NodeID) bool {
+	conn, exists := b.getConnection(nodeID)
 	if !exists {
-		return fmt.Errorf("no connection to node %s", nodeID)
+		return false
+	}
+	if lc, ok := conn.(interface{ live() bool }); ok {
+		return lc.live()
+	}
+	return true
+}
+
+// SetMaxMessageSize bounds the body of messages read from peers. Larger
+// messages are rejected before their body is read; 0 restores the default.
+func (b *Bus) SetMaxMessageSize(size uint32) {
+	if size == 0 {
+		size = DefaultMaxMessageSize
 	}
+	b.maxMessageSize = size
+}
+
+// SetPeerObserver sets the observer notified of peer handshakes
+func (b *Bus) SetPeerObserver(observer PeerObserver) {
+	b.observer = observer
+}
+
+// SetPeerDirectory sets where the bus looks up nodes it isn't connected to
+func (b *Bus) SetPeerDirectory(peers PeerDirectory) {
+	b.peers = peers
+}
+
+// SetDialer sets how the bus connects to nodes on demand
+func (b *Bus) SetDialer(dialer Dialer) {
+	b.dialer = dialer
+}
 
+// Connect establishes a connection to a remote node with the configured
+// dialer, or over TCP if none is set. A live connection to the node is
+// reused, and concurrent calls for the same node share a single dial.
+func (b *Bus) Connect(ctx context.Context, node NodeInfo) error {
+	if b.liveConnection(node.ID) {
+		return nil
+	}
+
+	_, err, _ := b.dials.Do(string(node.ID), func() (interface{}, error) {
+		// A dial that just finished may have connected already
+		if b.liveConnection(node.ID) {
+			return nil, nil
+		}
+
+		b.logger.Info("connecting to node", "node_id", node.ID, "address", node.Address)
+		if b.dialer == nil {
+			return nil, b.ConnectTCP(ctx, node)
+		}
+		return nil, b.dialer(ctx, node)
+	})
+	return err
+}
+
+// OpenStream opens a stream of the specified type to a node. A node without
+// a connection, e.g. because it was closed unused, is connected to on demand
+// if a peer directory is set.
+func (b *Bus) OpenStream(ctx context.Context, nodeID NodeID, streamType StreamType) (Stream, error) {
+	if _, exists := b.getConnection(nodeID); !exists {
+		if err := b.redial(ctx, nodeID); err != nil {
+			return nil, err
+		}
+	}
+	return b.openStream(ctx, nodeID, streamType)
+}
+
+// openStream opens a stream on the connection held to a node
+func (b *Bus) openStream(ctx context.Context, nodeID NodeID, streamType StreamType) (Stream, error) {
+	conn, exists := b.getConnection(nodeID)
+	if !exists {
+		return nil, fmt.Errorf("%w %s", ErrNoConnection, nodeID)
+	}
+	b.markUsed(conn)
+	return conn.OpenStream(ctx, streamType)
+}
+
+// SendControlMessage sends a control message to a specific node. A node
+// without a usable connection is connected to on demand unless membership
+// reports it dead.
+func (b *Bus) SendControlMessage(ctx context.Context, nodeID NodeID, msg []byte) error {
 	// Open a control stream
-	stream, err := conn.OpenStream(ctx, ControlStream)
+	stream, err := b.openControlStream(ctx, nodeID)
 	if err != nil {
 		return fmt.Errorf("failed to open control stream: %w", err)
 	}
 	defer stream.Close()
 
+	// Connections that ran the PQ handshake authenticate control messages
+	if conn, exists := b.getConnection(nodeID); exists {
+		if s, ok := b.pqSession(conn); ok {
+			if msg, err = s.seal(msg); err != nil {
+				return fmt.Errorf("failed to seal message: %w", err)
+			}
+		}
+	}
+
 	// Send the message
 	if err := stream.WriteMessage(ctx, msg); err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
@@ -116,6 +339,150 @@ func (b *Bus) SendControlMessage(ctx context.Context, nodeID NodeID, msg []byte)
 	return nil
 }
 
+// openControlStream opens a control stream, (re)connecting to the node up to
+// maxConnectAttempts times
+func (b *Bus) openControlStream(ctx context.Context, nodeID NodeID) (Stream, error) {
+	stream, err := b.openStream(ctx, nodeID, ControlStream)
+	for attempt := 1; err != nil && attempt <= maxConnectAttempts; attempt++ {
+		if b.peers == nil || b.dialer == nil {
+			return nil, err
+		}
+
+		node, lookupErr := b.peers.LookupPeer(nodeID)
+		if lookupErr != nil {
+			return nil, fmt.Errorf("%w: %w", err, lookupErr)
+		}
+
+		// A connection that can't open streams is stale
+		if conn, exists := b.getConnection(nodeID); exists {
+			b.removeConnection(nodeID, conn)
+			conn.Close()
+		}
+
+		if attempt > 1 {
+			select {
+			case <-time.After(time.Duration(attempt-1) * connectBackoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		b.logger.Debug("connecting on demand", "node_id", nodeID, "attempt", attempt, "error", err)
+		if err = b.Connect(ctx, node); err != nil {
+			continue
+		}
+		stream, err = b.openStream(ctx, nodeID, ControlStream)
+	}
+	return stream, err
+}
+
+// sendControlHello sends a ControlHello message to establish the connection,
+// returning the control stream so replies can be read from it
+func (b *Bus) sendControlHello(ctx context.Context, conn Connection) (Stream, error) {
+	// Open control stream
+	stream, err := conn.OpenStream(ctx, ControlStream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open control stream: %w", err)
+	}
+
+	// Encode and send the message
+	data, err := b.helloMessage()
+	if err != nil {
+		stream.Close()
+		return nil, fmt.Errorf("failed to encode ControlHello: %w", err)
+	}
+
+	if err := stream.WriteMessage(ctx, data); err != nil {
+		stream.Close()
+		return nil, fmt.Errorf("failed to send ControlHello: %w", err)
+	}
+
+	// Closing only ends our side; the peer still replies
+	stream.Close()
+
+	b.logger.Debug("sent ControlHello", "remote_node", conn.NodeID())
+	return stream, nil
+}
+
+// handshake sends our hello on a new connection and waits for the peer's
+// reply, negotiating the protocol version and, with PQ enabled, the
+// control message MAC key. It returns the ID the peer replied with. The
+// exchange is bounded by the handshake timeout.
+func (b *Bus) handshake(ctx context.Context, conn Connection) (NodeID, error) {
+	if deadline := b.handshakeDeadline(); !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	stream, err := b.sendControlHello(ctx, conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to send ControlHello: %w", err)
+	}
+
+	reply, err := b.readHelloReply(ctx, stream)
+	if err != nil {
+		return "", fmt.Errorf("handshake with %s failed: %w", conn.NodeID(), err)
+	}
+
+	var macKey []byte
+	if b.pqKey != nil {
+		if macKey, err = b.completeKeyExchange(ctx, stream, reply.peerKey); err != nil {
+			return "", fmt.Errorf("handshake with %s failed: %w", conn.NodeID(), err)
+		}
+	}
+
+	b.setSession(conn, reply.version, macKey, reply.peerKey, true)
+	b.logger.Debug("completed handshake", "remote_node", conn.NodeID(), "version", reply.version, "pq", macKey != nil)
+	return reply.nodeID, nil
+}
+
+// serveStream hands every message read from an inbound stream to the handler
+// until the stream is closed. Control messages on a connection that ran the
+// PQ handshake must carry a valid MAC. Keepalive pings only mark the peer active.
+func (b *Bus) serveStream(ctx context.Context, conn Connection, stream Stream, streamType StreamType) {
+	defer stream.Close()
+
+	// Calls share one stream per peer, so requests carrying an ID are served
+	// concurrently and may be answered out of order
+	shared := lockedStream{Stream: stream, mu: new(sync.Mutex)}
+
+	for {
+		data, err := stream.ReadMessage(ctx)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				b.logger.Debug("stream read failed", "node_id", conn.NodeID(), "error", err)
+			}
+			return
+		}
+
+		if streamType == ControlStream {
+			if data, err = b.verifyControlMessage(conn, data); err != nil {
+				b.logger.Warn("rejected control message", "node_id", conn.NodeID(), "error", err)
+				return
+			}
+		}
+
+		b.touch(conn)
+		if streamType == ControlStream && isPing(data) {
+			continue
+		}
+		b.markUsed(conn)
+
+		if header, err := DecodeHeader(data); err == nil && header.RequestID != 0 && streamType == DataStream {
+			go func() {
+				if err := b.handler.HandleMessage(ctx, conn, shared, data); err != nil {
+					b.logger.Warn("failed to handle message", "node_id", conn.NodeID(), "error", err)
+				}
+			}()
+			continue
+		}
+		if err := b.handler.HandleMessage(ctx, conn, shared, data); err != nil {
+			b.logger.Warn("failed to handle message", "node_id", conn.NodeID(), "error", err)
+		}
+	}
+}
+
 // BroadcastControlMessage sends a control message to all connected nodes
 func (b *Bus) BroadcastControlMessage(ctx context.Context, msg []byte) error {
 	// TODO: Implement broadcasting control messages
@@ -125,7 +492,23 @@ func (b *Bus) BroadcastControlMessage(ctx context.Context, msg []byte) error {
 
 // Close closes the hyperbus and all connections
 func (b *Bus) Close() error {
-	// TODO: Implement closing logic
 	b.logger.Info("closing hyperbus")
-	return nil
+
+	var err error
+	if b.listener != nil {
+		err = b.listener.Close()
+	}
+
+	b.connMu.Lock()
+	connections := b.connections
+	b.connections = make(map[NodeID]Connection)
+	b.sessions = make(map[Connection]*session)
+	b.usage = make(map[Connection]*atomic.Int64)
+	b.connMu.Unlock()
+
+	for _, conn := range connections {
+		conn.Close()
+	}
+	b.closeCallStreams()
+	return err
 }
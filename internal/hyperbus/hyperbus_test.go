@@ -3,6 +3,7 @@ package hyperbus
 import (
 	"context"
 	"crypto/ed25519"
+	"errors"
 	"log/slog"
 	"net"
 	"testing"
@@ -63,19 +64,105 @@ func TestBus_Connect(t *testing.T) {
 	handler := &mockHandler{}
 	bus := New(localNode, handler, logger)
 
-	// Create remote node info
-	remoteNode := NodeInfo{
+	// Create a remote bus listening on a free loopback port
+	remote := New(NodeInfo{
 		ID:        "remote-node",
-		Address:   &net.TCPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 8443},
+		Address:   &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)},
 		PublicKey: ed25519.PublicKey("remote-public-key"),
-	}
+	}, handler, logger)
+	assert.NoError(t, remote.ListenTCP())
+	defer remote.Close()
+	defer bus.Close()
 
-	// Connect to remote node (this is a mock, so it should not error)
-	err := bus.Connect(context.TODO(), remoteNode)
+	// Without a dialer the bus connects over TCP
+	err := bus.Connect(context.TODO(), remote.LocalNode())
 	assert.NoError(t, err)
+	assert.Equal(t, []NodeID{"remote-node"}, bus.Peers())
+}
+
+// recordingHandler passes every message it receives to a channel
+type recordingHandler chan []byte
+
+func (h recordingHandler) HandleMessage(ctx context.Context, conn Connection, stream Stream, data []byte) error {
+	h <- data
+	return nil
+}
+
+// peerMap is a PeerDirectory backed by a map; nodes mapped to false are dead
+type peerMap map[NodeID]bool
+
+func (p peerMap) LookupPeer(nodeID NodeID) (NodeInfo, error) {
+	alive, exists := p[nodeID]
+	if !exists {
+		return NodeInfo{}, errors.New("unknown node")
+	}
+	if !alive {
+		return NodeInfo{}, ErrNodeDead
+	}
+	return NodeInfo{ID: nodeID}, nil
+}
+
+func TestBus_SendControlMessageConnectsOnDemand(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	received := make(recordingHandler, 1)
+	local := New(NodeInfo{ID: "node-a"}, &mockHandler{}, logger)
+	remote := New(NodeInfo{ID: "node-b"}, received, logger)
+
+	var dials int
+	local.SetPeerDirectory(peerMap{"node-b": true, "node-c": false})
+	local.SetDialer(func(ctx context.Context, node NodeInfo) error {
+		dials++
+		assert.Equal(t, NodeID("node-b"), node.ID)
+		ConnectMemory(local, remote)
+		return nil
+	})
+
+	// The alive node has no connection yet, so sending connects first
+	assert.NoError(t, local.SendControlMessage(context.TODO(), "node-b", []byte("hello")))
+	assert.Equal(t, []byte("hello"), <-received)
+	assert.Equal(t, 1, dials)
+
+	// The connection is reused afterwards
+	assert.NoError(t, local.SendControlMessage(context.TODO(), "node-b", []byte("again")))
+	assert.Equal(t, []byte("again"), <-received)
+	assert.Equal(t, 1, dials)
+
+	// Dead and unknown nodes fail without dialing
+	assert.ErrorIs(t, local.SendControlMessage(context.TODO(), "node-c", nil), ErrNodeDead)
+	assert.ErrorIs(t, local.SendControlMessage(context.TODO(), "node-d", nil), ErrNoConnection)
+	assert.Equal(t, 1, dials)
+}
+
+func TestBus_SendControlMessageBoundedRetry(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := New(NodeInfo{ID: "node-a"}, &mockHandler{}, logger)
+
+	var dials int
+	bus.SetPeerDirectory(peerMap{"node-b": true})
+	bus.SetDialer(func(ctx context.Context, node NodeInfo) error {
+		dials++
+		return errors.New("connection refused")
+	})
+
+	err := bus.SendControlMessage(context.TODO(), "node-b", []byte("hello"))
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Equal(t, maxConnectAttempts, dials)
 }
 
 func TestNodeID_String(t *testing.T) {
 	nodeID := NodeID("test-node")
 	assert.Equal(t, "test-node", string(nodeID))
 }
+
+func TestNodeIDFromKey(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	pub := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+
+	id := NodeIDFromKey(pub)
+	assert.Equal(t, id, NodeIDFromKey(append(ed25519.PublicKey(nil), pub...)))
+	assert.Len(t, string(id), len("node-")+16)
+
+	seed[0] = 1
+	other := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	assert.NotEqual(t, id, NodeIDFromKey(other))
+}
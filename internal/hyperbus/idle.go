@@ -0,0 +1,99 @@
+package hyperbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultConnectionIdleTimeout is how long a connection may go unused before
+// RunIdleSweeper closes it, unless changed with SetConnectionIdleTimeout
+const DefaultConnectionIdleTimeout = 5 * time.Minute
+
+// SetConnectionIdleTimeout sets how long a connection may go unused before
+// RunIdleSweeper closes it. A connection is used by every stream opened on
+// it and every message other than a keepalive ping received on it, so the
+// connections to members that gossip regularly are kept. 0 keeps every
+// connection.
+func (b *Bus) SetConnectionIdleTimeout(timeout time.Duration) {
+	b.connIdleTimeout = timeout
+}
+
+// markUsed records that conn carried application traffic
+func (b *Bus) markUsed(conn Connection) {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+
+	if used, exists := b.usage[conn]; exists {
+		used.Store(b.now().UnixNano())
+	}
+}
+
+// RunIdleSweeper closes connections unused for longer than the connection
+// idle timeout, checking every half timeout until ctx is done. Closed
+// connections are dialed again when next needed.
+func (b *Bus) RunIdleSweeper(ctx context.Context) {
+	if b.connIdleTimeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(b.connIdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.sweepIdleConnections()
+		}
+	}
+}
+
+// sweepIdleConnections closes connections unused for longer than the
+// connection idle timeout and returns how many it closed
+func (b *Bus) sweepIdleConnections() int {
+	if b.connIdleTimeout <= 0 {
+		return 0
+	}
+	now := b.now()
+
+	idle := make(map[NodeID]Connection)
+	b.connMu.RLock()
+	for nodeID, conn := range b.connections {
+		if used, exists := b.usage[conn]; exists && now.Sub(time.Unix(0, used.Load())) > b.connIdleTimeout {
+			idle[nodeID] = conn
+		}
+	}
+	b.connMu.RUnlock()
+
+	for nodeID, conn := range idle {
+		b.logger.Info("closing unused connection", "node_id", nodeID, "idle_timeout", b.connIdleTimeout)
+		b.removeConnection(nodeID, conn)
+		if cc, ok := conn.(interface {
+			CloseWithCode(code CloseCode, reason string) error
+		}); ok {
+			cc.CloseWithCode(CloseIdleTimeout, "connection unused")
+		} else {
+			conn.Close()
+		}
+	}
+	return len(idle)
+}
+
+// redial connects on demand to a node without a connection, using the
+// dialer or TCP as Connect does
+func (b *Bus) redial(ctx context.Context, nodeID NodeID) error {
+	if b.peers == nil {
+		return fmt.Errorf("%w %s", ErrNoConnection, nodeID)
+	}
+
+	node, err := b.peers.LookupPeer(nodeID)
+	if err != nil {
+		return fmt.Errorf("%w %s: %w", ErrNoConnection, nodeID, err)
+	}
+	if err := b.Connect(ctx, node); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", nodeID, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package hyperbus
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus_SweepIdleConnections(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	received := make(recordingHandler, 2)
+	local := New(NodeInfo{ID: "node-a"}, &mockHandler{}, logger)
+	busy := New(NodeInfo{ID: "node-b"}, received, logger)
+	idle := New(NodeInfo{ID: "node-c"}, received, logger)
+
+	now := time.Unix(1000, 0)
+	local.now = func() time.Time { return now }
+	local.SetConnectionIdleTimeout(time.Minute)
+
+	var dials int
+	local.SetPeerDirectory(peerMap{"node-b": true, "node-c": true})
+	local.SetDialer(func(ctx context.Context, node NodeInfo) error {
+		dials++
+		ConnectMemory(local, idle)
+		return nil
+	})
+	ConnectMemory(local, busy)
+	ConnectMemory(local, idle)
+
+	// Only one of the peers is talked to within the timeout
+	now = now.Add(40 * time.Second)
+	assert.NoError(t, local.SendControlMessage(context.TODO(), "node-b", []byte("busy")))
+	assert.Equal(t, []byte("busy"), <-received)
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, 1, local.sweepIdleConnections())
+	assert.Equal(t, []NodeID{"node-b"}, local.Peers())
+
+	// The closed connection is dialed again when next needed
+	stream, err := local.OpenStream(context.TODO(), "node-c", DataStream)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.WriteMessage(context.TODO(), []byte("again")))
+	assert.Equal(t, []byte("again"), <-received)
+	assert.Equal(t, 1, dials)
+	assert.Equal(t, []NodeID{"node-b", "node-c"}, local.Peers())
+
+	// Without a timeout nothing is swept
+	local.SetConnectionIdleTimeout(0)
+	now = now.Add(time.Hour)
+	assert.Zero(t, local.sweepIdleConnections())
+}
@@ -0,0 +1,148 @@
+package hyperbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// Keepalive settings used unless changed with SetKeepalive
+const (
+	DefaultKeepaliveInterval = 10 * time.Second
+	DefaultIdleTimeout       = 30 * time.Second
+)
+
+// ErrIdleTimeout is reported when a peer sent nothing for longer than the idle timeout
+var ErrIdleTimeout = errors.New("connection idle timeout")
+
+// PeerLossObserver is implemented by peer observers that also want to know
+// when the bus drops a connection to a peer
+type PeerLossObserver interface {
+	// OnPeerLost is called after the connection to the node was closed and removed
+	OnPeerLost(nodeID NodeID, err error)
+}
+
+// SetKeepalive sets how often RunKeepalive pings each peer and how long a
+// peer may stay silent before its connection is closed. An idle timeout of
+// 0 never evicts connections.
+func (b *Bus) SetKeepalive(interval, idleTimeout time.Duration) {
+	b.keepaliveInterval = interval
+	b.idleTimeout = idleTimeout
+}
+
+// IdleTimeout returns how long a peer may stay silent before it is evicted
+func (b *Bus) IdleTimeout() time.Duration {
+	return b.idleTimeout
+}
+
+// touch records that a message arrived on conn
+func (b *Bus) touch(conn Connection) {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+
+	if s, exists := b.sessions[conn]; exists {
+		s.lastSeen.Store(time.Now().UnixNano())
+	}
+}
+
+// RunKeepalive pings every handshaken connection each keepalive interval and
+// evicts those whose peer sent nothing within the idle timeout, until ctx is done
+func (b *Bus) RunKeepalive(ctx context.Context) {
+	interval := b.keepaliveInterval
+	if interval <= 0 {
+		interval = DefaultKeepaliveInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.keepalive(ctx, interval)
+		}
+	}
+}
+
+// keepalive evicts idle connections and pings the rest
+func (b *Bus) keepalive(ctx context.Context, interval time.Duration) {
+	now := time.Now()
+
+	var idle, active []Connection
+	b.connMu.RLock()
+	for _, conn := range b.connections {
+		s, exists := b.sessions[conn]
+		if !exists {
+			continue
+		}
+		if b.idleTimeout > 0 && now.Sub(time.Unix(0, s.lastSeen.Load())) > b.idleTimeout {
+			idle = append(idle, conn)
+		} else {
+			active = append(active, conn)
+		}
+	}
+	b.connMu.RUnlock()
+
+	for _, conn := range idle {
+		b.evictIdle(conn)
+	}
+	for _, conn := range active {
+		go func() {
+			pingCtx, cancel := context.WithTimeout(ctx, interval)
+			defer cancel()
+			if err := b.sendPing(pingCtx, conn); err != nil {
+				b.logger.Debug("failed to ping peer", "node_id", conn.NodeID(), "error", err)
+			}
+		}()
+	}
+}
+
+// evictIdle closes and forgets a connection whose peer went silent
+func (b *Bus) evictIdle(conn Connection) {
+	nodeID := conn.NodeID()
+	b.logger.Warn("evicting idle connection", "node_id", nodeID, "idle_timeout", b.idleTimeout)
+
+	b.removeConnection(nodeID, conn)
+	if cc, ok := conn.(interface {
+		CloseWithCode(code CloseCode, reason string) error
+	}); ok {
+		cc.CloseWithCode(CloseIdleTimeout, "idle timeout")
+	} else {
+		conn.Close()
+	}
+
+	if lo, ok := b.observer.(PeerLossObserver); ok {
+		lo.OnPeerLost(nodeID, fmt.Errorf("%w: no message from %s in %s", ErrIdleTimeout, nodeID, b.idleTimeout))
+	}
+}
+
+// sendPing sends a Ping on a fresh control stream of conn
+func (b *Bus) sendPing(ctx context.Context, conn Connection) error {
+	msg, err := EncodeMessage(MsgPing, &proto.Ping{SentAtUnixNano: time.Now().UnixNano()})
+	if err != nil {
+		return fmt.Errorf("failed to encode Ping: %w", err)
+	}
+	if s, ok := b.pqSession(conn); ok {
+		if msg, err = s.seal(msg); err != nil {
+			return fmt.Errorf("failed to seal Ping: %w", err)
+		}
+	}
+
+	stream, err := conn.OpenStream(ctx, ControlStream)
+	if err != nil {
+		return fmt.Errorf("failed to open control stream: %w", err)
+	}
+	defer stream.Close()
+	return stream.WriteMessage(ctx, msg)
+}
+
+// isPing reports whether data is a keepalive Ping, which the bus consumes itself
+func isPing(data []byte) bool {
+	header, err := DecodeHeader(data)
+	return err == nil && header.Type == MsgPing
+}
@@ -0,0 +1,107 @@
+package hyperbus
+
+import (
+	"context"
+	"crypto/ed25519"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// lossObserver passes the errors of lost peers to a channel
+type lossObserver chan error
+
+func (o lossObserver) OnPeerHello(nodeID NodeID, hello *proto.ControlHello, receivedAt time.Time) {}
+
+func (o lossObserver) OnPeerLost(nodeID NodeID, err error) {
+	o <- err
+}
+
+// withKeepalive configures fast keepalives for tests
+func withKeepalive(b *Bus) {
+	b.SetKeepalive(50*time.Millisecond, 250*time.Millisecond)
+}
+
+func TestBus_KeepaliveEvictsStalledPeer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lost := make(lossObserver, 1)
+	client := newTCPBus(t, "client", &mockHandler{}, withKeepalive, func(b *Bus) { b.SetPeerObserver(lost) })
+
+	// The server never runs its keepalive, so it looks stalled to the client
+	server := newTCPBus(t, "server", &mockHandler{})
+	go client.RunKeepalive(ctx)
+
+	assert.NoError(t, client.Connect(context.TODO(), server.LocalNode()))
+	assert.Equal(t, []NodeID{"server"}, client.Peers())
+
+	select {
+	case err := <-lost:
+		assert.ErrorIs(t, err, ErrIdleTimeout)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stalled peer not evicted")
+	}
+	assert.Empty(t, client.Peers())
+}
+
+func TestBus_KeepaliveKeepsActivePeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lost := make(lossObserver, 2)
+	hellos := make(helloObserver, 1)
+	server := newTCPBus(t, "server", &mockHandler{}, withKeepalive, func(b *Bus) { b.SetPeerObserver(hellos) })
+	client := newTCPBus(t, "client", &mockHandler{}, withKeepalive, func(b *Bus) { b.SetPeerObserver(lost) })
+	go server.RunKeepalive(ctx)
+	go client.RunKeepalive(ctx)
+
+	assert.NoError(t, client.Connect(context.TODO(), server.LocalNode()))
+	<-hellos
+
+	// Pings from each side keep the connection alive well past the idle timeout
+	time.Sleep(4 * client.IdleTimeout())
+	assert.Empty(t, lost)
+	assert.Equal(t, []NodeID{"server"}, client.Peers())
+	assert.Equal(t, []NodeID{"client"}, server.Peers())
+}
+
+func TestQUICBus_KeepaliveKeepsActivePeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverKey, clientKey := newIdentity(t), newIdentity(t)
+	newQUICBus := func(identity ed25519.PrivateKey, trusted ed25519.PrivateKey) *QUICBus {
+		bus, err := NewQUICBus(
+			NodeInfo{ID: NodeIDFromKey(identity.Public().(ed25519.PublicKey)), Address: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}},
+			&mockHandler{},
+			log.New(slog.LevelDebug),
+			WithIdentity(identity),
+			WithTrustedKeys(NewTrustedKeys(trusted.Public().(ed25519.PublicKey))),
+			WithIdleTimeout(time.Second),
+		)
+		assert.NoError(t, err)
+		bus.SetKeepalive(50*time.Millisecond, 250*time.Millisecond)
+		t.Cleanup(func() { bus.Close() })
+		return bus
+	}
+	server := newQUICBus(serverKey, clientKey)
+	client := newQUICBus(clientKey, serverKey)
+
+	lost := make(lossObserver, 2)
+	client.SetPeerObserver(lost)
+	go server.RunKeepalive(ctx)
+	go client.RunKeepalive(ctx)
+
+	assert.NoError(t, client.Connect(context.TODO(), server.LocalNode()))
+
+	// Pings travel on streams the peer opens after the handshake
+	time.Sleep(time.Second)
+	assert.Empty(t, lost)
+	assert.Equal(t, []NodeID{server.LocalNode().ID}, client.Peers())
+}
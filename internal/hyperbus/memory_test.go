@@ -0,0 +1,148 @@
+package hyperbus
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// pageServer answers every page request with a fixed payload
+type pageServer struct {
+	payload []byte
+}
+
+func (s *pageServer) HandleMessage(ctx context.Context, conn Connection, stream Stream, data []byte) error {
+	var req proto.PageRequest
+	if err := DecodeMessage(data[HeaderSize:], &req); err != nil {
+		return err
+	}
+	msg, err := EncodeMessage(MsgPageResponse, &proto.PageResponse{
+		Status:  proto.PageResponse_OK,
+		Version: req.WantVersion,
+		Payload: s.payload,
+	})
+	if err != nil {
+		return err
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// newPagePair connects a client bus to a bus serving pages of the given size
+func newPagePair(pageSize int) *Bus {
+	logger := log.New(slog.LevelError)
+	mux := NewMux()
+	mux.Handle(MsgPageRequest, &pageServer{payload: make([]byte, pageSize)})
+
+	client := New(NodeInfo{ID: "client"}, NewMux(), logger)
+	server := New(NodeInfo{ID: "server"}, mux, logger)
+	ConnectMemory(client, server)
+	return client
+}
+
+// requestPage fetches one page over a fresh data stream, as the memory manager does
+func requestPage(ctx context.Context, bus *Bus, pageID int32) (*proto.PageResponse, error) {
+	msg, err := EncodeMessage(MsgPageRequest, &proto.PageRequest{ArrayId: "bench", PageId: pageID, WantVersion: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := bus.OpenStream(ctx, "server", DataStream)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	if err := stream.WriteMessage(ctx, msg); err != nil {
+		return nil, err
+	}
+	data, err := stream.ReadMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp proto.PageResponse
+	if err := DecodeMessage(data[HeaderSize:], &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func TestMemoryBus_PageRequest(t *testing.T) {
+	bus := newPagePair(4096)
+
+	resp, err := requestPage(context.TODO(), bus, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, proto.PageResponse_OK, resp.Status)
+	assert.Len(t, resp.Payload, 4096)
+}
+
+// BenchmarkPageTransfer measures page request/response throughput and
+// latency over the in-process transport, as a baseline for transport work
+func BenchmarkPageTransfer(b *testing.B) {
+	for _, pageSize := range []int{4 << 10, 64 << 10, 256 << 10} {
+		for _, concurrency := range []int{1, 4, 16} {
+			b.Run(fmt.Sprintf("size=%dKiB/concurrency=%d", pageSize>>10, concurrency), func(b *testing.B) {
+				benchmarkPageTransfer(b, pageSize, concurrency)
+			})
+		}
+	}
+}
+
+func benchmarkPageTransfer(b *testing.B, pageSize, concurrency int) {
+	bus := newPagePair(pageSize)
+	ctx := context.Background()
+
+	latencies := make([][]time.Duration, concurrency)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+
+	b.ResetTimer()
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				i := next.Add(1) - 1
+				if i >= int64(b.N) {
+					return
+				}
+				began := time.Now()
+				resp, err := requestPage(ctx, bus, int32(i))
+				if err != nil || len(resp.Payload) != pageSize {
+					b.Errorf("page request %d failed: %v", i, err)
+					return
+				}
+				latencies[w] = append(latencies[w], time.Since(began))
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	b.StopTimer()
+
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	if len(all) == 0 {
+		return
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	percentile := func(p float64) float64 {
+		return float64(all[int(p*float64(len(all)-1))].Microseconds())
+	}
+
+	b.ReportMetric(float64(len(all)*pageSize)/(1<<20)/elapsed.Seconds(), "MB/s")
+	b.ReportMetric(percentile(0.50), "p50-us")
+	b.ReportMetric(percentile(0.99), "p99-us")
+}
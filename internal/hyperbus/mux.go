@@ -0,0 +1,45 @@
+package hyperbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Mux dispatches incoming messages to handlers by message type
+type Mux struct {
+	handlers map[MessageType]MessageHandler
+	mu       sync.RWMutex
+}
+
+// NewMux creates a new message multiplexer
+func NewMux() *Mux {
+	return &Mux{
+		handlers: make(map[MessageType]MessageHandler),
+	}
+}
+
+// Handle registers the handler for a message type, replacing any previous one
+func (m *Mux) Handle(msgType MessageType, handler MessageHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[msgType] = handler
+}
+
+// HandleMessage implements MessageHandler
+func (m *Mux) HandleMessage(ctx context.Context, conn Connection, stream Stream, data []byte) error {
+	header, err := DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	handler, exists := m.handlers[header.Type]
+	m.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("no handler for message type %d", header.Type)
+	}
+
+	return handler.HandleMessage(ctx, conn, stream, data)
+}
@@ -0,0 +1,336 @@
+package hyperbus
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/mlkem"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// HKDF labels separating the keys derived from a PQ shared secret
+const (
+	pqMACLabel     = "holocompute control mac"
+	pqConfirmLabel = "holocompute key confirmation"
+)
+
+// Prefixes separating what the identity key signs during the PQ handshake
+const (
+	pqKeySigLabel        = "holocompute pq key\x00"
+	pqCiphertextSigLabel = "holocompute pq ciphertext\x00"
+)
+
+// Directions of a connection's control messages, covered by their MACs so a
+// message can't be reflected back to its sender
+const (
+	pqFromInitiator byte = iota
+	pqFromAcceptor
+)
+
+// replayWindowSize is how far behind the newest control message an older
+// one may arrive, reordered across streams, and still be accepted
+const replayWindowSize = 64
+
+var (
+	// ErrPQHandshake is returned when the post-quantum key exchange with a peer fails
+	ErrPQHandshake = errors.New("post-quantum handshake failed")
+
+	// ErrUnauthenticated is returned when a control message fails HMAC verification
+	ErrUnauthenticated = errors.New("control message failed authentication")
+)
+
+// SetPQKey enables the post-quantum handshake. The key's encapsulation key
+// is offered in every ControlHello, signed with the node's identity key,
+// and becomes the local node's PQPublicKey; identity's public half becomes
+// its PublicKey. Peers must then complete an ML-KEM key exchange whose
+// ciphertext they sign in turn, and control messages on the connection are
+// authenticated with an HMAC keyed by the shared secret. Without a key,
+// connections use the classical handshake only.
+func (b *Bus) SetPQKey(key *mlkem.DecapsulationKey768, identity ed25519.PrivateKey) {
+	b.pqKey = key
+	b.identity = identity
+	b.localNode.PQPublicKey = key.EncapsulationKey().Bytes()
+	b.localNode.PublicKey = identity.Public().(ed25519.PublicKey)
+}
+
+// pqSession returns a connection's session if the PQ handshake ran on it
+func (b *Bus) pqSession(conn Connection) (*session, bool) {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+
+	s, exists := b.sessions[conn]
+	if !exists || s.macKey == nil {
+		return nil, false
+	}
+	return s, true
+}
+
+// sessionKey returns the control message MAC key for a connection, if the
+// PQ handshake ran on it
+func (b *Bus) sessionKey(conn Connection) ([]byte, bool) {
+	s, ok := b.pqSession(conn)
+	if !ok {
+		return nil, false
+	}
+	return s.macKey, true
+}
+
+// pqKeySignature signs the local node's PQ key for its hello
+func (b *Bus) pqKeySignature() []byte {
+	if b.pqKey == nil || b.identity == nil {
+		return nil
+	}
+	return SignPQKey(b.identity, b.localNode.ID, b.localNode.PQPublicKey)
+}
+
+// SignPQKey signs the PQ key a node offers in its hello with its identity key
+func SignPQKey(identity ed25519.PrivateKey, nodeID NodeID, pqKey []byte) []byte {
+	return ed25519.Sign(identity, pqKeySigned(nodeID, pqKey))
+}
+
+// pqKeySigned returns the bytes a node signs to vouch for its PQ key
+func pqKeySigned(nodeID NodeID, pqKey []byte) []byte {
+	return append([]byte(pqKeySigLabel+string(nodeID)+"\x00"), pqKey...)
+}
+
+// pqCiphertextSigned returns the bytes the accepting node signs to vouch for
+// the ciphertext it encapsulated to the dialing node's PQ key
+func pqCiphertextSigned(ciphertext, peerPQKey []byte) []byte {
+	signed := append([]byte(pqCiphertextSigLabel), ciphertext...)
+	return append(signed, peerPQKey...)
+}
+
+// verifyIdentitySignature checks sig over signed against an identity key
+// taken from a hello
+func verifyIdentitySignature(pub ed25519.PublicKey, signed, sig []byte) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: peer offered no identity key", ErrPQHandshake)
+	}
+	if !ed25519.Verify(pub, signed, sig) {
+		return fmt.Errorf("%w: invalid identity signature", ErrPQHandshake)
+	}
+	return nil
+}
+
+// acceptKeyExchange answers the PQ part of an inbound hello. It returns the
+// KeyExchange reply and the connection's MAC key, both nil when PQ is
+// disabled, or an error if the peer's key must be rejected.
+func (b *Bus) acceptKeyExchange(hello *proto.ControlHello) (reply, macKey []byte, err error) {
+	if b.pqKey == nil {
+		return nil, nil, nil
+	}
+	if len(hello.PqPubkey) == 0 {
+		return nil, nil, fmt.Errorf("%w: peer offered no PQ key", ErrPQHandshake)
+	}
+	if err := verifyIdentitySignature(hello.Pubkey, pqKeySigned(NodeID(hello.NodeId), hello.PqPubkey), hello.PqPubkeySig); err != nil {
+		return nil, nil, err
+	}
+
+	peerKey, err := mlkem.NewEncapsulationKey768(hello.PqPubkey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid PQ key: %v", ErrPQHandshake, err)
+	}
+	secret, ciphertext := peerKey.Encapsulate()
+
+	macKey, confirmation, err := derivePQKeys(secret, ciphertext)
+	if err != nil {
+		return nil, nil, err
+	}
+	reply, err = EncodeMessage(MsgKeyExchange, &proto.KeyExchange{
+		Ciphertext:   ciphertext,
+		Confirmation: confirmation,
+		Signature:    ed25519.Sign(b.identity, pqCiphertextSigned(ciphertext, hello.PqPubkey)),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode key exchange: %w", err)
+	}
+	return reply, macKey, nil
+}
+
+// completeKeyExchange reads the peer's KeyExchange reply to our hello,
+// checks its signature by peerKey, the identity key from the peer's hello,
+// and its confirmation, and returns the connection's MAC key
+func (b *Bus) completeKeyExchange(ctx context.Context, stream Stream, peerKey ed25519.PublicKey) ([]byte, error) {
+	data, err := stream.ReadMessage(ctx)
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: peer did not answer the key exchange", ErrPQHandshake)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key exchange: %w", err)
+	}
+
+	header, err := DecodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.Type != MsgKeyExchange {
+		return nil, fmt.Errorf("%w: unexpected message type %d", ErrPQHandshake, header.Type)
+	}
+
+	var exchange proto.KeyExchange
+	if err := DecodeMessage(data[HeaderSize:], &exchange); err != nil {
+		return nil, err
+	}
+	if err := verifyIdentitySignature(peerKey, pqCiphertextSigned(exchange.Ciphertext, b.localNode.PQPublicKey), exchange.Signature); err != nil {
+		return nil, err
+	}
+	secret, err := b.pqKey.Decapsulate(exchange.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPQHandshake, err)
+	}
+	macKey, confirmation, err := derivePQKeys(secret, exchange.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(confirmation, exchange.Confirmation) {
+		return nil, fmt.Errorf("%w: key confirmation mismatch", ErrPQHandshake)
+	}
+	return macKey, nil
+}
+
+// derivePQKeys derives the control message MAC key and the key
+// confirmation tag from an ML-KEM shared secret
+func derivePQKeys(secret, ciphertext []byte) (macKey, confirmation []byte, err error) {
+	macKey, err = hkdf.Key(sha256.New, secret, nil, pqMACLabel, sha256.Size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive MAC key: %w", err)
+	}
+	confirmKey, err := hkdf.Key(sha256.New, secret, nil, pqConfirmLabel, sha256.Size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive confirmation key: %w", err)
+	}
+	return macKey, computeMAC(confirmKey, ciphertext), nil
+}
+
+// computeMAC returns the HMAC-SHA256 of data under key
+func computeMAC(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// messageMAC returns the MAC of a control message sent in direction with
+// the given sequence number
+func messageMAC(key []byte, direction byte, counter uint64, msg []byte) []byte {
+	data := make([]byte, 9, 9+len(msg))
+	data[0] = direction
+	binary.BigEndian.PutUint64(data[1:], counter)
+	return computeMAC(key, append(data, msg...))
+}
+
+// sealMessage wraps a control message sent in direction with its sequence
+// number and HMAC
+func sealMessage(key []byte, direction byte, counter uint64, msg []byte) ([]byte, error) {
+	return EncodeMessage(MsgAuthenticated, &proto.AuthenticatedMessage{
+		Message: msg,
+		Mac:     messageMAC(key, direction, counter, msg),
+		Counter: counter,
+	})
+}
+
+// openMessage verifies a sealed control message sent in direction and
+// returns the message inside with its sequence number
+func openMessage(key []byte, direction byte, data []byte) ([]byte, uint64, error) {
+	header, err := DecodeHeader(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if header.Type != MsgAuthenticated {
+		return nil, 0, fmt.Errorf("%w: message type %d is not authenticated", ErrUnauthenticated, header.Type)
+	}
+
+	var sealed proto.AuthenticatedMessage
+	if err := DecodeMessage(data[HeaderSize:], &sealed); err != nil {
+		return nil, 0, err
+	}
+	if !hmac.Equal(messageMAC(key, direction, sealed.Counter, sealed.Message), sealed.Mac) {
+		return nil, 0, ErrUnauthenticated
+	}
+	return sealed.Message, sealed.Counter, nil
+}
+
+// replayWindow accepts each sequence number once. Control messages travel
+// on separate streams and may arrive slightly out of order, so numbers up to
+// replayWindowSize behind the highest seen are still accepted once.
+type replayWindow struct {
+	mu      sync.Mutex
+	highest uint64
+	seen    uint64 // bit i is set once highest-i was accepted
+}
+
+// accept reports whether counter is new, recording it if so
+func (w *replayWindow) accept(counter uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch {
+	case counter == 0:
+		return false
+	case counter > w.highest:
+		if shift := counter - w.highest; shift < replayWindowSize {
+			w.seen <<= shift
+		} else {
+			w.seen = 0
+		}
+		w.seen |= 1
+		w.highest = counter
+		return true
+	case w.highest-counter >= replayWindowSize:
+		return false
+	}
+
+	bit := uint64(1) << (w.highest - counter)
+	if w.seen&bit != 0 {
+		return false
+	}
+	w.seen |= bit
+	return true
+}
+
+// seal authenticates a control message sent on the session's connection
+func (s *session) seal(msg []byte) ([]byte, error) {
+	direction := pqFromAcceptor
+	if s.initiator {
+		direction = pqFromInitiator
+	}
+	return sealMessage(s.macKey, direction, s.sent.Add(1), msg)
+}
+
+// open verifies a control message received on the session's connection,
+// rejecting any replay of an earlier one
+func (s *session) open(data []byte) ([]byte, error) {
+	direction := pqFromInitiator
+	if s.initiator {
+		direction = pqFromAcceptor
+	}
+	msg, counter, err := openMessage(s.macKey, direction, data)
+	if err != nil {
+		return nil, err
+	}
+	if !s.received.accept(counter) {
+		return nil, fmt.Errorf("%w: replayed control message %d", ErrUnauthenticated, counter)
+	}
+	return msg, nil
+}
+
+// verifyControlMessage unwraps a control message read from conn, requiring
+// a valid MAC when the connection ran the PQ handshake
+func (b *Bus) verifyControlMessage(conn Connection, data []byte) ([]byte, error) {
+	if s, ok := b.pqSession(conn); ok {
+		return s.open(data)
+	}
+
+	header, err := DecodeHeader(data)
+	if err == nil && header.Type == MsgAuthenticated {
+		return nil, fmt.Errorf("%w: no PQ session with peer", ErrUnauthenticated)
+	}
+	return data, nil
+}
@@ -0,0 +1,173 @@
+package hyperbus
+
+import (
+	"context"
+	"crypto/mlkem"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPQKey generates an ML-KEM key for a test bus
+func newPQKey(t *testing.T) *mlkem.DecapsulationKey768 {
+	key, err := mlkem.GenerateKey768()
+	assert.NoError(t, err)
+	return key
+}
+
+// withPQ enables the PQ handshake on a test bus
+func withPQ(t *testing.T) func(*Bus) {
+	return func(b *Bus) { b.SetPQKey(newPQKey(t), newIdentity(t)) }
+}
+
+func TestBus_PQHandshake(t *testing.T) {
+	tests := []struct {
+		name string
+		pq   bool
+	}{
+		{name: "classical", pq: false},
+		{name: "post-quantum", pq: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(recordingHandler, 1)
+			hellos := make(helloObserver, 1)
+			configure := func(b *Bus) {
+				if tt.pq {
+					withPQ(t)(b)
+				}
+			}
+			server := newTCPBus(t, "server", received, configure)
+			server.SetPeerObserver(hellos)
+			client := newTCPBus(t, "client", &mockHandler{}, configure)
+
+			assert.NoError(t, client.Connect(context.TODO(), server.LocalNode()))
+			<-hellos
+
+			conn, exists := client.getConnection("server")
+			assert.True(t, exists)
+			_, authenticated := client.sessionKey(conn)
+			assert.Equal(t, tt.pq, authenticated)
+
+			// The handler sees the message as sent, whichever the mode
+			msg, err := EncodeMessage(MsgClusterState, &proto.ClusterState{})
+			assert.NoError(t, err)
+			assert.NoError(t, client.SendControlMessage(context.TODO(), "server", msg))
+
+			select {
+			case data := <-received:
+				assert.Equal(t, msg, data)
+			case <-time.After(time.Second):
+				t.Fatal("control message not received")
+			}
+		})
+	}
+}
+
+func TestBus_PQHandshakeRejectsMissingKey(t *testing.T) {
+	server := newTCPBus(t, "server", &mockHandler{}, withPQ(t))
+	client := newTCPBus(t, "client", &mockHandler{})
+
+	err := client.Connect(context.TODO(), server.LocalNode())
+	assert.ErrorIs(t, err, ErrHandshakeRejected)
+	assert.ErrorContains(t, err, "peer offered no PQ key")
+	assert.Empty(t, client.Peers())
+	assert.Empty(t, server.Peers())
+}
+
+func TestBus_PQHandshakeRejectsClassicalPeer(t *testing.T) {
+	server := newTCPBus(t, "server", &mockHandler{})
+	client := newTCPBus(t, "client", &mockHandler{}, withPQ(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.Connect(ctx, server.LocalNode())
+	assert.ErrorIs(t, err, ErrPQHandshake)
+}
+
+func TestBus_PQHandshakeRejectsUnsignedKey(t *testing.T) {
+	server := newTCPBus(t, "server", &mockHandler{}, withPQ(t))
+
+	// The client's PQ key is signed by a key other than the one it presents
+	client := newTCPBus(t, "client", &mockHandler{}, withPQ(t), func(b *Bus) { b.identity = newIdentity(t) })
+
+	err := client.Connect(context.TODO(), server.LocalNode())
+	assert.ErrorIs(t, err, ErrHandshakeRejected)
+	assert.ErrorContains(t, err, "invalid identity signature")
+	assert.Empty(t, server.Peers())
+}
+
+func TestBus_PQHandshakeRejectsUnsignedCiphertext(t *testing.T) {
+	// The server signs its ciphertext with a key other than the one it presents
+	server := newTCPBus(t, "server", &mockHandler{}, withPQ(t), func(b *Bus) { b.identity = newIdentity(t) })
+	client := newTCPBus(t, "client", &mockHandler{}, withPQ(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.Connect(ctx, server.LocalNode())
+	assert.ErrorIs(t, err, ErrPQHandshake)
+	assert.ErrorContains(t, err, "invalid identity signature")
+}
+
+func TestOpenMessage(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	msg, err := EncodeMessage(MsgClusterState, &proto.ClusterState{})
+	assert.NoError(t, err)
+
+	sealed, err := sealMessage(key, pqFromInitiator, 7, msg)
+	assert.NoError(t, err)
+	opened, counter, err := openMessage(key, pqFromInitiator, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, opened)
+	assert.Equal(t, uint64(7), counter)
+
+	// A different key or direction, or an unsealed message fails verification
+	_, _, err = openMessage([]byte("another key"), pqFromInitiator, sealed)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	_, _, err = openMessage(key, pqFromAcceptor, sealed)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	_, _, err = openMessage(key, pqFromInitiator, msg)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestSession_RejectsReplays(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	dialer := &session{macKey: key, initiator: true}
+	acceptor := &session{macKey: key}
+	msg, err := EncodeMessage(MsgClusterState, &proto.ClusterState{})
+	assert.NoError(t, err)
+
+	first, err := dialer.seal(msg)
+	assert.NoError(t, err)
+	second, err := dialer.seal(msg)
+	assert.NoError(t, err)
+
+	// Messages reordered across streams are still accepted, once each
+	_, err = acceptor.open(second)
+	assert.NoError(t, err)
+	_, err = acceptor.open(first)
+	assert.NoError(t, err)
+	_, err = acceptor.open(first)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	// A message reflected back to its sender fails
+	_, err = dialer.open(first)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestReplayWindow(t *testing.T) {
+	var w replayWindow
+	assert.False(t, w.accept(0))
+	assert.True(t, w.accept(100))
+	assert.True(t, w.accept(100-replayWindowSize+1))
+	assert.False(t, w.accept(100-replayWindowSize))
+	assert.False(t, w.accept(100))
+
+	// Moving the window forward forgets the oldest numbers
+	assert.True(t, w.accept(100+replayWindowSize))
+	assert.False(t, w.accept(100))
+	assert.True(t, w.accept(101))
+}
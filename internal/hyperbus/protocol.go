@@ -3,7 +3,9 @@ package hyperbus
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	
 	"google.golang.org/protobuf/proto"
 )
@@ -23,16 +25,78 @@ const (
 	MsgPageResponse
 	MsgTaskSubmit
 	MsgTaskResult
+	MsgTaskCancel
+	MsgLeaseQuery
+	MsgLeaseReport
+	MsgError
+	MsgArrayQuery
+	MsgArrayInfo
+	MsgPagePush
+	MsgKeyExchange
+	MsgAuthenticated
+	MsgPing
+	MsgMetricsQuery
+	MsgMetrics
+	MsgTaskLog
+	MsgProbePing
+	MsgProbePingReq
+	MsgProbeAck
+	MsgPageRemap
+	MsgRangeRun
+	MsgRangeProgress
+	MsgRangeResult
+	MsgConfigQuery
+	MsgConfigDocument
+	MsgConfigUpdate
+	MsgConfigAck
 )
 
+// HeaderSize is the encoded size of a MessageHeader in bytes
+const HeaderSize = 14
+
+// DefaultMaxMessageSize bounds message bodies read from peers unless changed with SetMaxMessageSize
+const DefaultMaxMessageSize = 8 * 1024 * 1024 // 8 MiB
+
+// ErrMessageTooLarge is returned when a peer announces a message above the size limit
+var ErrMessageTooLarge = errors.New("message too large")
+
+// checkMessageSize rejects a message body of size bytes if it exceeds limit,
+// or DefaultMaxMessageSize if limit is 0, before anything is allocated for it
+func checkMessageSize(size, limit uint32) error {
+	if limit == 0 {
+		limit = DefaultMaxMessageSize
+	}
+	if size > limit {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrMessageTooLarge, size, limit)
+	}
+	return nil
+}
+
 // MessageHeader is the header for all messages
 type MessageHeader struct {
 	Type MessageType
 	Size uint32
+	
+	// RequestID matches a response to its request; 0 if uncorrelated
+	RequestID uint64
 }
 
 // EncodeMessage encodes a protobuf message with header
 func EncodeMessage(msgType MessageType, pb proto.Message) ([]byte, error) {
+	return EncodeMessageWithID(msgType, 0, pb)
+}
+
+// EncodeReply encodes a response to request, carrying over its request ID
+func EncodeReply(request []byte, msgType MessageType, pb proto.Message) ([]byte, error) {
+	header, err := DecodeHeader(request)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeMessageWithID(msgType, header.RequestID, pb)
+}
+
+// EncodeMessageWithID encodes a protobuf message with a header carrying requestID
+func EncodeMessageWithID(msgType MessageType, requestID uint64, pb proto.Message) ([]byte, error) {
 	// Serialize the protobuf message
 	data, err := proto.Marshal(pb)
 	if err != nil {
@@ -41,8 +105,9 @@ func EncodeMessage(msgType MessageType, pb proto.Message) ([]byte, error) {
 	
 	// Create header
 	header := MessageHeader{
-		Type: msgType,
-		Size: uint32(len(data)),
+		Type:      msgType,
+		Size:      uint32(len(data)),
+		RequestID: requestID,
 	}
 	
 	// Encode header and message
@@ -74,4 +139,66 @@ func DecodeMessage(data []byte, pb proto.Message) error {
 		return fmt.Errorf("failed to unmarshal protobuf: %w", err)
 	}
 	return nil
+}
+
+// MaxDelimitedSize is the largest message accepted by ReadDelimited
+const MaxDelimitedSize = 64 * 1024 * 1024 // 64 MiB
+
+// WriteDelimited writes a protobuf message prefixed with its varint-encoded length
+func WriteDelimited(w io.Writer, pb proto.Message) error {
+	data, err := proto.Marshal(pb)
+	if err != nil {
+		return fmt.Errorf("failed to marshal protobuf: %w", err)
+	}
+	
+	// Write prefix and body together so a message is never split across writes
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(data))
+	n := binary.PutUvarint(buf, uint64(len(data)))
+	buf = append(buf[:n], data...)
+	
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("failed to write delimited message: %w", err)
+	}
+	return nil
+}
+
+// ReadDelimited reads a varint length-prefixed protobuf message.
+// It consumes exactly one message so it can be called repeatedly on a stream.
+func ReadDelimited(r io.Reader, pb proto.Message) error {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = &singleByteReader{r: r}
+	}
+	
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		if err == io.EOF {
+			return io.EOF
+		}
+		return fmt.Errorf("failed to read message length: %w", err)
+	}
+	if size > MaxDelimitedSize {
+		return fmt.Errorf("delimited message too large: %d bytes (max %d)", size, MaxDelimitedSize)
+	}
+	
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return fmt.Errorf("failed to read delimited message: %w", err)
+	}
+	
+	return DecodeMessage(data, pb)
+}
+
+// singleByteReader reads one byte at a time without buffering past the varint
+type singleByteReader struct {
+	r   io.Reader
+	buf [1]byte
+}
+
+// ReadByte implements io.ByteReader
+func (s *singleByteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(s.r, s.buf[:]); err != nil {
+		return 0, err
+	}
+	return s.buf[0], nil
 }
\ No newline at end of file
@@ -2,13 +2,17 @@ package hyperbus
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/melihxz/holocompute/internal/log"
@@ -16,12 +20,68 @@ import (
 	"github.com/quic-go/quic-go"
 )
 
+// CloseCode identifies why a connection was closed
+type CloseCode uint64
+
+const (
+	// CloseGracefulShutdown is used when a node shuts down normally
+	CloseGracefulShutdown CloseCode = iota
+	// CloseProtocolError is used when the remote violated the protocol
+	CloseProtocolError
+	// CloseAuthFailure is used when the remote failed authentication
+	CloseAuthFailure
+	// CloseIdleTimeout is used when the connection was idle for too long
+	CloseIdleTimeout
+	// CloseIncompatibleVersion is used when the nodes share no protocol version
+	CloseIncompatibleVersion
+	// CloseDuplicateNodeID is used when the remote claims the ID of another node
+	CloseDuplicateNodeID
+	// ClosePlacementMismatch is used when the remote places pages with another hash
+	ClosePlacementMismatch
+)
+
+// String returns the name of the close code
+func (c CloseCode) String() string {
+	switch c {
+	case CloseGracefulShutdown:
+		return "graceful-shutdown"
+	case CloseProtocolError:
+		return "protocol-error"
+	case CloseAuthFailure:
+		return "auth-failure"
+	case CloseIdleTimeout:
+		return "idle-timeout"
+	case CloseIncompatibleVersion:
+		return "incompatible-version"
+	case CloseDuplicateNodeID:
+		return "duplicate-node-id"
+	case ClosePlacementMismatch:
+		return "placement-mismatch"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint64(c))
+	}
+}
+
+// CloseReason extracts the close code and reason a remote sent when it
+// closed the connection. ok is false if err isn't a remote application close.
+func CloseReason(err error) (code CloseCode, reason string, ok bool) {
+	var appErr *quic.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.Remote {
+		return 0, "", false
+	}
+	return CloseCode(appErr.ErrorCode), appErr.ErrorMessage, true
+}
+
 // QUICConnection implements the Connection interface using QUIC
 type QUICConnection struct {
 	nodeID  NodeID
 	conn    *quic.Conn
 	logger  *log.Logger
 	streams map[quic.StreamID]*quic.Stream
+	mu      sync.Mutex // guards streams
+
+	// Largest message body its streams accept; 0 means DefaultMaxMessageSize
+	maxMessageSize uint32
 }
 
 // NodeID returns the ID of the remote node
@@ -44,36 +104,53 @@ func (c *QUICConnection) OpenStream(ctx context.Context, streamType StreamType)
 	}
 
 	stream := &QUICStream{
-		stream: qstream,
-		logger: c.logger.With("stream_id", qstream.StreamID()),
+		stream:         qstream,
+		logger:         c.logger.With("stream_id", qstream.StreamID()),
+		maxMessageSize: c.maxMessageSize,
 	}
 
+	c.mu.Lock()
 	c.streams[qstream.StreamID()] = qstream
+	c.mu.Unlock()
 	return stream, nil
 }
 
-// Close closes the connection
+// Close closes the connection gracefully
 func (c *QUICConnection) Close() error {
-	c.logger.Info("closing connection", "node_id", c.nodeID)
-	return c.conn.CloseWithError(0, "connection closed")
+	return c.CloseWithCode(CloseGracefulShutdown, "connection closed")
+}
+
+// live reports whether the connection is still open
+func (c *QUICConnection) live() bool {
+	return c.conn.Context().Err() == nil
+}
+
+// CloseWithCode closes the connection, sending code and reason to the remote
+func (c *QUICConnection) CloseWithCode(code CloseCode, reason string) error {
+	c.logger.Info("closing connection", "node_id", c.nodeID, "code", code, "reason", reason)
+	return c.conn.CloseWithError(quic.ApplicationErrorCode(code), reason)
 }
 
 // QUICStream implements the Stream interface using QUIC streams
 type QUICStream struct {
 	stream *quic.Stream
 	logger *log.Logger
+
+	// Largest message body accepted; 0 means DefaultMaxMessageSize
+	maxMessageSize uint32
 }
 
 // ReadMessage reads a message from the stream
 func (s *QUICStream) ReadMessage(ctx context.Context) ([]byte, error) {
-	// Read the header (6 bytes: 2 for type + 4 for size)
-	headerBuf := make([]byte, 6)
-	n, err := s.stream.Read(headerBuf)
-	if err != nil {
-		return nil, err
+	if deadline, ok := ctx.Deadline(); ok {
+		s.stream.SetReadDeadline(deadline)
+		defer s.stream.SetReadDeadline(time.Time{})
 	}
-	if n != 6 {
-		return nil, fmt.Errorf("incomplete header read: expected 6 bytes, got %d", n)
+
+	// Read the header
+	headerBuf := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(s.stream, headerBuf); err != nil {
+		return nil, err
 	}
 
 	// Decode header to get message size
@@ -81,21 +158,20 @@ func (s *QUICStream) ReadMessage(ctx context.Context) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode header: %w", err)
 	}
-
-	// Read the message body
-	bodyBuf := make([]byte, header.Size)
-	n, err = s.stream.Read(bodyBuf)
-	if err != nil {
+	if err := checkMessageSize(header.Size, s.maxMessageSize); err != nil {
 		return nil, err
 	}
-	if uint32(n) != header.Size {
-		return nil, fmt.Errorf("incomplete message body read: expected %d bytes, got %d", header.Size, n)
+
+	// Read the message body; QUIC may deliver it across several reads
+	bodyBuf := make([]byte, header.Size)
+	if _, err := io.ReadFull(s.stream, bodyBuf); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
 	}
 
 	// Combine header and body
-	result := make([]byte, 6+len(bodyBuf))
-	copy(result[:6], headerBuf)
-	copy(result[6:], bodyBuf)
+	result := make([]byte, HeaderSize+len(bodyBuf))
+	copy(result[:HeaderSize], headerBuf)
+	copy(result[HeaderSize:], bodyBuf)
 
 	return result, nil
 }
@@ -115,28 +191,96 @@ func (s *QUICStream) Close() error {
 // QUICBus implements the Bus interface using QUIC
 type QUICBus struct {
 	*Bus
-	listener *quic.Listener
+	listener   *quic.Listener
+	tlsConfig  *tls.Config  // shared by the listener and dialer
+	quicConfig *quic.Config // likewise
+	trusted    *TrustedKeys // keys peers may present, if pinned
+}
+
+// QUICOption configures a QUIC bus
+type QUICOption func(*quicOptions)
+
+// quicOptions holds the settings applied by QUICOptions
+type quicOptions struct {
+	identity    ed25519.PrivateKey
+	trusted     *TrustedKeys
+	idleTimeout time.Duration
+}
+
+// WithIdentity presents key in the bus's TLS certificate and hellos, so
+// peers pinning it can authenticate the node. Without it an ephemeral key
+// is used.
+func WithIdentity(key ed25519.PrivateKey) QUICOption {
+	return func(o *quicOptions) {
+		o.identity = key
+	}
+}
+
+// WithTrustedKeys accepts only peers, dialed or accepted, whose certificate
+// holds one of keys
+func WithTrustedKeys(keys *TrustedKeys) QUICOption {
+	return func(o *quicOptions) {
+		o.trusted = keys
+	}
+}
+
+// WithIdleTimeout closes connections whose peer sends nothing for timeout,
+// both in QUIC itself and in RunKeepalive. It defaults to DefaultIdleTimeout.
+func WithIdleTimeout(timeout time.Duration) QUICOption {
+	return func(o *quicOptions) {
+		o.idleTimeout = timeout
+	}
+}
+
+// newQUICConfig returns the QUIC settings for an idle timeout, keeping
+// quiet connections open with QUIC keepalives well within it
+func newQUICConfig(idleTimeout time.Duration) *quic.Config {
+	return &quic.Config{
+		MaxIdleTimeout:  idleTimeout,
+		KeepAlivePeriod: idleTimeout / 3,
+	}
 }
 
 // NewQUICBus creates a new QUIC-based hyperbus
-func NewQUICBus(localNode NodeInfo, handler MessageHandler, logger *log.Logger) (*QUICBus, error) {
+func NewQUICBus(localNode NodeInfo, handler MessageHandler, logger *log.Logger, opts ...QUICOption) (*QUICBus, error) {
+	if localNode.Address == nil {
+		return nil, fmt.Errorf("cannot listen: %w: %s", ErrNoAddress, localNode.ID)
+	}
+
+	options := quicOptions{idleTimeout: DefaultIdleTimeout}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.identity != nil {
+		localNode.PublicKey = options.identity.Public().(ed25519.PublicKey)
+	}
+
 	// Generate TLS certificate for QUIC
-	tlsConfig, err := generateTLSConfig()
+	tlsConfig, err := generateTLSConfig(options.identity, options.trusted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate TLS config: %w", err)
 	}
 
 	// Create QUIC listener
 	addr := localNode.Address.String()
-	listener, err := quic.ListenAddr(addr, tlsConfig, nil)
+	quicConfig := newQUICConfig(options.idleTimeout)
+	listener, err := quic.ListenAddr(addr, tlsConfig, quicConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create QUIC listener: %w", err)
 	}
 
 	bus := &QUICBus{
-		Bus:      New(localNode, handler, logger),
-		listener: listener,
+		Bus:        New(localNode, handler, logger),
+		listener:   listener,
+		tlsConfig:  tlsConfig,
+		quicConfig: quicConfig,
+		trusted:    options.trusted,
 	}
+	bus.SetDialer(bus.Connect)
+	bus.SetKeepalive(DefaultKeepaliveInterval, options.idleTimeout)
+
+	// Advertise the bound address, which differs when listening on port 0
+	bus.localNode.Address = listener.Addr()
 
 	// Start accepting connections
 	go bus.acceptLoop()
@@ -144,6 +288,15 @@ func NewQUICBus(localNode NodeInfo, handler MessageHandler, logger *log.Logger)
 	return bus, nil
 }
 
+// Close stops accepting connections and closes the bus
+func (b *QUICBus) Close() error {
+	err := b.listener.Close()
+	if busErr := b.Bus.Close(); busErr != nil {
+		err = busErr
+	}
+	return err
+}
+
 // acceptLoop accepts incoming connections
 func (b *QUICBus) acceptLoop() {
 	for {
@@ -161,57 +314,95 @@ func (b *QUICBus) acceptLoop() {
 func (b *QUICBus) handleConnection(conn *quic.Conn) {
 	b.logger.Info("handling new connection", "remote_addr", conn.RemoteAddr())
 
+	// The whole handshake must finish within the handshake timeout
+	deadline := b.handshakeDeadline()
+	ctx := context.Background()
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
 	// Accept the first stream which should be the control stream
-	stream, err := conn.AcceptStream(context.Background())
+	stream, err := conn.AcceptStream(ctx)
 	if err != nil {
 		b.logger.Error("failed to accept control stream", "error", err)
+		b.dropHandshake(conn, err)
 		return
 	}
 	defer stream.Close()
+	stream.SetDeadline(deadline)
 
 	// Read the stream type
 	streamTypeBuf := make([]byte, 1)
-	if _, err := stream.Read(streamTypeBuf); err != nil {
+	if _, err := io.ReadFull(stream, streamTypeBuf); err != nil {
 		b.logger.Error("failed to read stream type", "error", err)
+		b.dropHandshake(conn, err)
 		return
 	}
 
 	streamType := StreamType(streamTypeBuf[0])
 	if streamType != ControlStream {
 		b.logger.Error("expected control stream", "received_type", streamType)
+		b.rejectHello(conn, stream, CloseProtocolError, "expected control stream")
 		return
 	}
 
 	// Read the ControlHello message
 	// First read the header
-	headerBuf := make([]byte, 6) // 2 bytes for type + 4 bytes for size
-	if _, err := stream.Read(headerBuf); err != nil {
+	headerBuf := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(stream, headerBuf); err != nil {
 		b.logger.Error("failed to read message header", "error", err)
+		b.dropHandshake(conn, err)
 		return
 	}
 
 	header, err := DecodeHeader(headerBuf)
 	if err != nil {
 		b.logger.Error("failed to decode message header", "error", err)
+		b.rejectHello(conn, stream, CloseProtocolError, fmt.Sprintf("malformed message header: %v", err))
 		return
 	}
 
 	if header.Type != MsgControlHello {
 		b.logger.Error("expected ControlHello message", "received_type", header.Type)
+		b.rejectHello(conn, stream, CloseProtocolError, "expected ControlHello")
+		return
+	}
+	if err := checkMessageSize(header.Size, b.maxMessageSize); err != nil {
+		b.logger.Error("rejected oversized ControlHello", "error", err)
+		b.rejectHello(conn, stream, CloseProtocolError, err.Error())
 		return
 	}
 
 	// Read the message body
 	bodyBuf := make([]byte, header.Size)
-	if _, err := stream.Read(bodyBuf); err != nil {
+	if _, err := io.ReadFull(stream, bodyBuf); err != nil {
 		b.logger.Error("failed to read message body", "error", err)
+		b.dropHandshake(conn, err)
 		return
 	}
 
 	// Decode the ControlHello message
+	receivedAt := time.Now()
 	var hello proto.ControlHello
 	if err := DecodeMessage(bodyBuf, &hello); err != nil {
 		b.logger.Error("failed to decode ControlHello", "error", err)
+		b.rejectHello(conn, stream, CloseProtocolError, fmt.Sprintf("malformed ControlHello: %v", err))
+		return
+	}
+
+	// The hello must come from the holder of the certificate's key
+	if err := checkPeerCertificate(conn.ConnectionState().TLS, b.trusted, NodeID(hello.NodeId), hello.Pubkey); err != nil {
+		b.logger.Error("rejected ControlHello", "node_id", hello.NodeId, "error", err)
+		b.rejectHello(conn, stream, CloseAuthFailure, err.Error())
+		return
+	}
+
+	accepted, code, err := b.acceptHello(&hello)
+	if err != nil {
+		b.logger.Error("rejected ControlHello", "node_id", hello.NodeId, "error", err)
+		b.rejectHello(conn, stream, code, err.Error())
 		return
 	}
 
@@ -221,84 +412,200 @@ func (b *QUICBus) handleConnection(conn *quic.Conn) {
 		conn:    conn,
 		logger:  b.logger.With("remote_node", hello.NodeId),
 		streams: make(map[quic.StreamID]*quic.Stream),
+
+		maxMessageSize: b.maxMessageSize,
+	}
+
+	b.setSession(qconn, accepted.version, accepted.macKey, hello.Pubkey, false)
+
+	// Answer the hello; the deferred close ends our side of the stream
+	for _, reply := range accepted.replies {
+		if _, err := stream.Write(reply); err != nil {
+			b.logger.Error("failed to answer ControlHello", "error", err)
+			qconn.Close()
+			return
+		}
 	}
 
 	// Store connection
-	b.connections[NodeID(hello.NodeId)] = qconn
+	b.putConnection(NodeID(hello.NodeId), qconn)
+
+	go b.serveStreams(qconn)
+
+	if b.observer != nil {
+		b.observer.OnPeerHello(NodeID(hello.NodeId), &hello, receivedAt)
+	}
 
 	b.logger.Info("established connection with node", "node_id", hello.NodeId)
 }
 
-// Connect establishes a connection to a remote node using QUIC
+// serveStreams serves the streams the peer opens on an established
+// connection until it closes
+func (b *QUICBus) serveStreams(qconn *QUICConnection) {
+	for {
+		qstream, err := qconn.conn.AcceptStream(qconn.conn.Context())
+		if err != nil {
+			qconn.logger.Debug("stopped accepting streams", "error", err)
+			return
+		}
+
+		go func() {
+			// Read the stream type
+			streamTypeBuf := make([]byte, 1)
+			if _, err := io.ReadFull(qstream, streamTypeBuf); err != nil {
+				qconn.logger.Debug("failed to read stream type", "error", err)
+				qstream.Close()
+				return
+			}
+
+			stream := &QUICStream{
+				stream:         qstream,
+				logger:         qconn.logger.With("stream_id", qstream.StreamID()),
+				maxMessageSize: qconn.maxMessageSize,
+			}
+			b.serveStream(context.Background(), qconn, stream, StreamType(streamTypeBuf[0]))
+		}()
+	}
+}
+
+// helloRejectLinger is how long a rejected peer has to read the error before the connection closes
+const helloRejectLinger = time.Second
+
+// dropHandshake closes a connection whose hello never arrived intact
+func (b *QUICBus) dropHandshake(conn *quic.Conn, err error) {
+	reason := "handshake failed"
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		reason = "handshake timed out"
+	}
+	conn.CloseWithError(quic.ApplicationErrorCode(CloseProtocolError), reason)
+}
+
+// rejectHello tells the peer why its hello was refused, then closes the connection
+func (b *QUICBus) rejectHello(conn *quic.Conn, stream *quic.Stream, code CloseCode, reason string) {
+	data, err := EncodeMessage(MsgError, &proto.ProtocolError{
+		Code:   uint64(code),
+		Reason: reason,
+	})
+	if err == nil {
+		if _, err := stream.Write(data); err != nil {
+			b.logger.Debug("failed to send protocol error", "error", err)
+		}
+		stream.Close()
+	}
+
+	// The peer usually closes first once it has read the error
+	select {
+	case <-conn.Context().Done():
+	case <-time.After(helloRejectLinger):
+	}
+	conn.CloseWithError(quic.ApplicationErrorCode(code), reason)
+}
+
+// Connect establishes a connection to a remote node using QUIC, reusing a
+// live connection to it if one is held
 func (b *QUICBus) Connect(ctx context.Context, node NodeInfo) error {
-	// Generate TLS config
-	tlsConfig, err := generateTLSConfig()
-	if err != nil {
-		return fmt.Errorf("failed to generate TLS config: %w", err)
+	if b.liveConnection(node.ID) {
+		return nil
+	}
+	if node.Address == nil {
+		return fmt.Errorf("cannot dial: %w: %s", ErrNoAddress, node.ID)
+	}
+
+	_, err := b.dial(ctx, node.ID, node.Address.String())
+	return err
+}
+
+// Dial connects to the node listening at addr, whose ID isn't known until
+// its hello reply names it, and returns that ID. Bootstrap peers are only
+// known by address.
+func (b *QUICBus) Dial(ctx context.Context, addr string) (NodeID, error) {
+	return b.dial(ctx, "", addr)
+}
+
+// dial connects to addr and runs the handshake. A node dialed without an
+// ID is stored under the ID it replies with.
+func (b *QUICBus) dial(ctx context.Context, nodeID NodeID, addr string) (NodeID, error) {
+	tlsConfig := b.tlsConfig
+	if tlsConfig == nil {
+		var err error
+		if tlsConfig, err = generateTLSConfig(nil, nil); err != nil {
+			return "", fmt.Errorf("failed to generate TLS config: %w", err)
+		}
+	}
+
+	quicConfig := b.quicConfig
+	if quicConfig == nil {
+		quicConfig = newQUICConfig(b.idleTimeout)
 	}
 
 	// Connect to remote node
-	conn, err := quic.DialAddr(ctx, node.Address.String(), tlsConfig, &quic.Config{})
+	conn, err := quic.DialAddr(ctx, addr, tlsConfig.Clone(), quicConfig.Clone())
 	if err != nil {
-		return fmt.Errorf("failed to dial remote node: %w", err)
+		return "", fmt.Errorf("failed to dial remote node: %w", err)
 	}
 
 	// Create connection wrapper
 	qconn := &QUICConnection{
-		nodeID:  node.ID,
+		nodeID:  nodeID,
 		conn:    conn,
-		logger:  b.logger.With("remote_node", node.ID),
+		logger:  b.logger.With("remote_node", nodeID),
 		streams: make(map[quic.StreamID]*quic.Stream),
+
+		maxMessageSize: b.maxMessageSize,
 	}
 
 	// Store connection
-	b.connections[node.ID] = qconn
+	if nodeID != "" {
+		b.putConnection(nodeID, qconn)
+	}
 
 	// Send ControlHello message
-	if err := b.sendControlHello(ctx, qconn); err != nil {
-		qconn.Close()
-		return fmt.Errorf("failed to send ControlHello: %w", err)
+	peerID, err := b.handshake(ctx, qconn)
+	if err == nil && nodeID == "" {
+		if peerID == "" {
+			err = fmt.Errorf("%w: %s did not name itself", ErrHandshakeRejected, addr)
+		} else {
+			nodeID = peerID
+			qconn.nodeID = peerID
+			qconn.logger = b.logger.With("remote_node", peerID)
+			b.putConnection(nodeID, qconn)
+		}
 	}
-
-	return nil
-}
-
-// sendControlHello sends a ControlHello message to establish the connection
-func (b *QUICBus) sendControlHello(ctx context.Context, conn *QUICConnection) error {
-	// Open control stream
-	stream, err := conn.OpenStream(ctx, ControlStream)
 	if err != nil {
-		return fmt.Errorf("failed to open control stream: %w", err)
-	}
-	defer stream.Close()
-
-	// Create ControlHello message
-	hello := &proto.ControlHello{
-		NodeId: string(b.localNode.ID),
-		Caps:   b.localNode.Capabilities,
-		Pubkey: b.localNode.PublicKey,
+		if nodeID != "" {
+			b.removeConnection(nodeID, qconn)
+		}
+		qconn.Close()
+		return "", err
 	}
 
-	// Encode and send the message
-	data, err := EncodeMessage(MsgControlHello, hello)
-	if err != nil {
-		return fmt.Errorf("failed to encode ControlHello: %w", err)
+	// The node dialed must hold the key of the certificate it presented
+	var peerKey ed25519.PublicKey
+	b.connMu.RLock()
+	if s, exists := b.sessions[qconn]; exists {
+		peerKey = s.peerKey
 	}
-
-	if err := stream.WriteMessage(ctx, data); err != nil {
-		return fmt.Errorf("failed to send ControlHello: %w", err)
+	b.connMu.RUnlock()
+	if err := checkPeerCertificate(conn.ConnectionState().TLS, b.trusted, nodeID, peerKey); err != nil {
+		b.removeConnection(nodeID, qconn)
+		qconn.CloseWithCode(CloseAuthFailure, err.Error())
+		return "", err
 	}
 
-	b.logger.Debug("sent ControlHello", "remote_node", conn.NodeID())
-	return nil
+	go b.serveStreams(qconn)
+	return nodeID, nil
 }
 
-// generateTLSConfig generates a self-signed TLS certificate for QUIC
-func generateTLSConfig() (*tls.Config, error) {
-	// Generate key pair
-	key, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return nil, err
+// generateTLSConfig generates a self-signed TLS certificate for QUIC from
+// the identity key, or an ephemeral key if identity is nil. The self-signed
+// chain is never verified; with trusted set, both sides must present a
+// certificate whose key it contains.
+func generateTLSConfig(identity ed25519.PrivateKey, trusted *TrustedKeys) (*tls.Config, error) {
+	if identity == nil {
+		var err error
+		if _, identity, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			return nil, err
+		}
 	}
 
 	// Create certificate template
@@ -309,7 +616,7 @@ func generateTLSConfig() (*tls.Config, error) {
 		},
 		NotBefore: time.Now(),
 		NotAfter:  time.Now().Add(time.Hour * 24 * 365),
-		KeyUsage:  x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		KeyUsage:  x509.KeyUsageDigitalSignature,
 		ExtKeyUsage: []x509.ExtKeyUsage{
 			x509.ExtKeyUsageServerAuth,
 			x509.ExtKeyUsageClientAuth,
@@ -318,7 +625,7 @@ func generateTLSConfig() (*tls.Config, error) {
 	}
 
 	// Create self-signed certificate
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, identity.Public(), identity)
 	if err != nil {
 		return nil, err
 	}
@@ -326,11 +633,21 @@ func generateTLSConfig() (*tls.Config, error) {
 	// Create TLS certificate
 	cert := tls.Certificate{
 		Certificate: [][]byte{derBytes},
-		PrivateKey:  key,
+		PrivateKey:  identity,
 	}
 
-	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		NextProtos:   []string{"holocompute"},
-	}, nil
+	// Certificates are self-signed, so no chain can be verified; peers are
+	// authenticated by pinning their key instead. Clients are still asked
+	// for theirs so hellos can be checked against it.
+	config := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		NextProtos:         []string{"holocompute"},
+		InsecureSkipVerify: true,
+		ClientAuth:         tls.RequestClientCert,
+	}
+	if trusted != nil {
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyPeerCertificate = trusted.verifyPeerCertificate
+	}
+	return config, nil
 }
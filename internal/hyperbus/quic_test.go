@@ -1,9 +1,18 @@
 package hyperbus
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"io"
+	"log/slog"
 	"testing"
+	"time"
 
+	"github.com/melihxz/holocompute/internal/log"
 	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/quic-go/quic-go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,19 +43,268 @@ func TestMessageEncoding(t *testing.T) {
 	data, err := EncodeMessage(MsgControlHello, hello)
 	assert.NoError(t, err)
 	assert.NotNil(t, data)
-	assert.Greater(t, len(data), 6) // At least header size
+	assert.Greater(t, len(data), HeaderSize)
 
 	// Decode the header
-	header, err := DecodeHeader(data[:6])
+	header, err := DecodeHeader(data[:HeaderSize])
 	assert.NoError(t, err)
 	assert.Equal(t, MsgControlHello, header.Type)
-	assert.Equal(t, uint32(len(data)-6), header.Size)
+	assert.Equal(t, uint32(len(data)-HeaderSize), header.Size)
 
 	// Decode the message
 	var decoded proto.ControlHello
-	err = DecodeMessage(data[6:], &decoded)
+	err = DecodeMessage(data[HeaderSize:], &decoded)
 	assert.NoError(t, err)
 	assert.Equal(t, hello.NodeId, decoded.NodeId)
 	assert.Equal(t, hello.Caps.CpuCores, decoded.Caps.CpuCores)
 	assert.Equal(t, hello.Pubkey, decoded.Pubkey)
 }
+
+func TestDelimitedFraming(t *testing.T) {
+	var buf bytes.Buffer
+
+	// Write several messages of varying size to one stream
+	requests := []*proto.PageRequest{
+		{ArrayId: "array-1", PageId: 0, WantVersion: 1},
+		{ArrayId: "array-2", PageId: 42, WantVersion: 7},
+		{},
+		{ArrayId: string(bytes.Repeat([]byte("x"), 300)), PageId: 3},
+	}
+	for _, req := range requests {
+		assert.NoError(t, WriteDelimited(&buf, req))
+	}
+
+	// Read them back in order through a reader without ReadByte
+	r := struct{ io.Reader }{&buf}
+	for _, want := range requests {
+		var got proto.PageRequest
+		assert.NoError(t, ReadDelimited(r, &got))
+		assert.Equal(t, want.ArrayId, got.ArrayId)
+		assert.Equal(t, want.PageId, got.PageId)
+		assert.Equal(t, want.WantVersion, got.WantVersion)
+	}
+
+	// The stream is exhausted
+	var extra proto.PageRequest
+	assert.Equal(t, io.EOF, ReadDelimited(r, &extra))
+}
+
+// quicPair returns both ends of a loopback QUIC connection
+func quicPair(t *testing.T, ctx context.Context) (client, server *quic.Conn) {
+	serverTLS, err := generateTLSConfig(nil, nil)
+	assert.NoError(t, err)
+	listener, err := quic.ListenAddr("127.0.0.1:0", serverTLS, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	accepted := make(chan *quic.Conn, 1)
+	go func() {
+		conn, err := listener.Accept(ctx)
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	clientTLS := &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"holocompute"}}
+	client, err = quic.DialAddr(ctx, listener.Addr().String(), clientTLS, nil)
+	assert.NoError(t, err)
+
+	select {
+	case server = <-accepted:
+	case <-ctx.Done():
+		t.Fatal("timed out accepting connection")
+	}
+	return client, server
+}
+
+func TestQUICConnection_CloseWithCode(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, server := quicPair(t, ctx)
+
+	qconn := &QUICConnection{
+		nodeID:  "client",
+		conn:    server,
+		logger:  log.New(slog.LevelDebug),
+		streams: make(map[quic.StreamID]*quic.Stream),
+	}
+	assert.NoError(t, qconn.CloseWithCode(CloseAuthFailure, "untrusted public key"))
+
+	// The client sees the code and reason once the close arrives
+	_, err := client.AcceptStream(ctx)
+	code, reason, ok := CloseReason(err)
+	assert.True(t, ok)
+	assert.Equal(t, CloseAuthFailure, code)
+	assert.Equal(t, "untrusted public key", reason)
+	assert.Equal(t, "auth-failure", code.String())
+}
+
+func TestCloseReason_NotApplicationError(t *testing.T) {
+	_, _, ok := CloseReason(io.EOF)
+	assert.False(t, ok)
+}
+
+func TestQUICStream_LargeMessage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, server := quicPair(t, ctx)
+	defer client.CloseWithError(0, "")
+
+	sender := &QUICConnection{
+		nodeID:  "client",
+		conn:    server,
+		logger:  log.New(slog.LevelDebug),
+		streams: make(map[quic.StreamID]*quic.Stream),
+	}
+	defer sender.Close()
+
+	// A body far larger than a single packet arrives in many fragments
+	payload := bytes.Repeat([]byte("holocompute"), 64*1024)
+	data, err := EncodeMessage(MsgPageResponse, &proto.PageResponse{Payload: payload})
+	assert.NoError(t, err)
+
+	go func() {
+		stream, err := sender.OpenStream(ctx, DataStream)
+		if err != nil {
+			return
+		}
+		stream.WriteMessage(ctx, data)
+		stream.Close()
+	}()
+
+	qstream, err := client.AcceptStream(ctx)
+	assert.NoError(t, err)
+	streamType := make([]byte, 1)
+	_, err = io.ReadFull(qstream, streamType)
+	assert.NoError(t, err)
+	assert.Equal(t, DataStream, StreamType(streamType[0]))
+
+	stream := &QUICStream{stream: qstream, logger: log.New(slog.LevelDebug)}
+	received, err := stream.ReadMessage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, data, received)
+
+	var response proto.PageResponse
+	assert.NoError(t, DecodeMessage(received[HeaderSize:], &response))
+	assert.Equal(t, payload, response.Payload)
+}
+
+func TestQUICBus_BadHelloGetsError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, server := quicPair(t, ctx)
+
+	bus := &QUICBus{Bus: New(NodeInfo{ID: "server"}, nil, log.New(slog.LevelDebug))}
+	go bus.handleConnection(server)
+
+	qstream, err := client.OpenStreamSync(ctx)
+	assert.NoError(t, err)
+
+	// A ControlHello header followed by a body that isn't valid protobuf
+	body := []byte{0xff, 0xff, 0xff}
+	header := []byte{byte(ControlStream), 0, byte(MsgControlHello), 0, 0, 0, byte(len(body)), 0, 0, 0, 0, 0, 0, 0, 0}
+	_, err = qstream.Write(append(header, body...))
+	assert.NoError(t, err)
+
+	stream := &QUICStream{stream: qstream, logger: log.New(slog.LevelDebug)}
+	data, err := stream.ReadMessage(ctx)
+	assert.NoError(t, err)
+
+	decoded, err := DecodeHeader(data)
+	assert.NoError(t, err)
+	assert.Equal(t, MsgError, decoded.Type)
+
+	var protoErr proto.ProtocolError
+	assert.NoError(t, DecodeMessage(data[HeaderSize:], &protoErr))
+	assert.Equal(t, uint64(CloseProtocolError), protoErr.Code)
+	assert.Contains(t, protoErr.Reason, "malformed ControlHello")
+
+	// The connection is then closed with the same reason
+	_, err = client.AcceptStream(ctx)
+	code, reason, ok := CloseReason(err)
+	assert.True(t, ok)
+	assert.Equal(t, CloseProtocolError, code)
+	assert.Equal(t, protoErr.Reason, reason)
+}
+
+func TestQUICStream_RejectsOversizedMessage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, server := quicPair(t, ctx)
+	defer client.CloseWithError(0, "")
+
+	qstream, err := client.OpenStreamSync(ctx)
+	assert.NoError(t, err)
+
+	// Only a header announcing a 4 GiB body is ever sent
+	header := []byte{byte(MsgPageResponse >> 8), byte(MsgPageResponse), 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0}
+	_, err = qstream.Write(header)
+	assert.NoError(t, err)
+
+	accepted, err := server.AcceptStream(ctx)
+	assert.NoError(t, err)
+
+	stream := &QUICStream{stream: accepted, logger: log.New(slog.LevelDebug), maxMessageSize: 1024}
+	data, err := stream.ReadMessage(ctx)
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+	assert.ErrorContains(t, err, "1024 byte limit")
+	assert.Nil(t, data)
+}
+
+func TestQUICBus_OversizedHelloRejected(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, server := quicPair(t, ctx)
+
+	bus := &QUICBus{Bus: New(NodeInfo{ID: "server"}, nil, log.New(slog.LevelDebug))}
+	bus.SetMaxMessageSize(1024)
+	go bus.handleConnection(server)
+
+	qstream, err := client.OpenStreamSync(ctx)
+	assert.NoError(t, err)
+
+	// A ControlHello header announcing a body above the limit, with no body
+	header := []byte{byte(ControlStream), 0, byte(MsgControlHello), 0, 0, 0x08, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	_, err = qstream.Write(header)
+	assert.NoError(t, err)
+
+	stream := &QUICStream{stream: qstream, logger: log.New(slog.LevelDebug)}
+	data, err := stream.ReadMessage(ctx)
+	assert.NoError(t, err)
+
+	var protoErr proto.ProtocolError
+	assert.NoError(t, DecodeMessage(data[HeaderSize:], &protoErr))
+	assert.Equal(t, uint64(CloseProtocolError), protoErr.Code)
+	assert.Contains(t, protoErr.Reason, ErrMessageTooLarge.Error())
+}
+
+func TestQUICBus_NilAddress(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	bus, err := NewQUICBus(NodeInfo{ID: "node-1"}, nil, logger)
+	assert.ErrorIs(t, err, ErrNoAddress)
+	assert.Nil(t, bus)
+
+	bus = &QUICBus{Bus: New(NodeInfo{ID: "node-1"}, nil, logger)}
+	err = bus.Connect(context.TODO(), NodeInfo{ID: "node-2"})
+	assert.ErrorIs(t, err, ErrNoAddress)
+	assert.ErrorContains(t, err, "node-2")
+}
+
+func TestQUICBus_DialLearnsNodeID(t *testing.T) {
+	serverKey, clientKey := newIdentity(t), newIdentity(t)
+	server := newPinnedQUICBus(t, serverKey, clientKey.Public().(ed25519.PublicKey))
+	client := newPinnedQUICBus(t, clientKey, serverKey.Public().(ed25519.PublicKey))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	nodeID, err := client.Dial(ctx, server.LocalNode().Address.String())
+	assert.NoError(t, err)
+	assert.Equal(t, server.LocalNode().ID, nodeID)
+	assert.Equal(t, []NodeID{nodeID}, client.Peers())
+
+	// The connection is reused by ID afterwards
+	assert.NoError(t, client.Connect(ctx, server.LocalNode()))
+	assert.Equal(t, []NodeID{nodeID}, client.Peers())
+}
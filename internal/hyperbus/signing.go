@@ -0,0 +1,165 @@
+package hyperbus
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// NonceSize is the size of an envelope nonce in bytes
+const NonceSize = 16
+
+var (
+	// ErrInvalidSignature is returned when an envelope signature does not verify
+	ErrInvalidSignature = errors.New("invalid envelope signature")
+
+	// ErrReplayedMessage is returned when an envelope nonce was already seen
+	ErrReplayedMessage = errors.New("replayed message")
+
+	// ErrStaleMessage is returned when an envelope falls outside the replay window
+	ErrStaleMessage = errors.New("message outside replay window")
+
+	// ErrNonceCacheFull is returned when no more nonces can be tracked until
+	// the oldest ones age out of the replay window
+	ErrNonceCacheFull = errors.New("nonce cache full")
+)
+
+// SignEnvelope wraps a payload in an envelope signed with the sender's key
+func SignEnvelope(sender NodeID, key ed25519.PrivateKey, msgType MessageType, payload []byte) (*proto.SignedEnvelope, error) {
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	env := &proto.SignedEnvelope{
+		SenderId:       string(sender),
+		Nonce:          nonce,
+		SentAtUnixNano: time.Now().UnixNano(),
+		MsgType:        uint32(msgType),
+		Payload:        payload,
+	}
+	env.Signature = ed25519.Sign(key, signedBytes(env))
+
+	return env, nil
+}
+
+// VerifyEnvelope checks the envelope signature against the sender's public key
+func VerifyEnvelope(env *proto.SignedEnvelope, pub ed25519.PublicKey) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key size: %d", len(pub))
+	}
+	if !ed25519.Verify(pub, signedBytes(env), env.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signedBytes returns the canonical encoding covered by the signature
+func signedBytes(env *proto.SignedEnvelope) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.BigEndian, uint16(len(env.SenderId)))
+	buf.WriteString(env.SenderId)
+	binary.Write(buf, binary.BigEndian, uint16(len(env.Nonce)))
+	buf.Write(env.Nonce)
+	binary.Write(buf, binary.BigEndian, env.SentAtUnixNano)
+	binary.Write(buf, binary.BigEndian, env.MsgType)
+	buf.Write(env.Payload)
+	return buf.Bytes()
+}
+
+// NonceCache rejects envelopes whose nonce was already seen within a time window.
+// Envelopes older than the window are rejected outright, so entries can be
+// evicted once they age out without reopening a replay hole. Live entries are
+// never evicted: a full cache rejects new envelopes instead.
+type NonceCache struct {
+	window     time.Duration
+	maxEntries int
+	seen       map[nonceKey]*list.Element
+	order      *list.List // oldest first
+	now        func() time.Time
+	mu         sync.Mutex
+}
+
+// nonceKey identifies a nonce from a specific sender
+type nonceKey struct {
+	sender NodeID
+	nonce  string
+}
+
+// nonceEntry records when a nonce can be forgotten
+type nonceEntry struct {
+	key       nonceKey
+	expiresAt time.Time
+}
+
+// NewNonceCache creates a nonce cache with the given window and entry bound
+func NewNonceCache(window time.Duration, maxEntries int) *NonceCache {
+	return &NonceCache{
+		window:     window,
+		maxEntries: maxEntries,
+		seen:       make(map[nonceKey]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Check accepts an envelope the first time its nonce is seen within the window
+func (c *NonceCache) Check(env *proto.SignedEnvelope) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.evictExpired(now)
+
+	// Reject envelopes we can no longer prove are fresh
+	sentAt := time.Unix(0, env.SentAtUnixNano)
+	if now.Sub(sentAt) > c.window || sentAt.Sub(now) > c.window {
+		return ErrStaleMessage
+	}
+
+	key := nonceKey{sender: NodeID(env.SenderId), nonce: string(env.Nonce)}
+	if _, exists := c.seen[key]; exists {
+		return ErrReplayedMessage
+	}
+
+	// Forgetting a live nonce would let it be replayed, so a flood of fresh
+	// nonces is refused until the oldest age out
+	if c.maxEntries > 0 && c.order.Len() >= c.maxEntries {
+		return ErrNonceCacheFull
+	}
+
+	// Once the send time leaves the window the staleness check rejects it
+	c.seen[key] = c.order.PushBack(&nonceEntry{key: key, expiresAt: sentAt.Add(c.window)})
+	return nil
+}
+
+// Len returns the number of tracked nonces
+func (c *NonceCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// evictExpired drops nonces from the front of the queue that have aged out
+func (c *NonceCache) evictExpired(now time.Time) {
+	for element := c.order.Front(); element != nil; element = c.order.Front() {
+		if !now.After(element.Value.(*nonceEntry).expiresAt) {
+			return
+		}
+		c.remove(element)
+	}
+}
+
+// remove drops a nonce entry
+func (c *NonceCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*nonceEntry)
+	delete(c.seen, entry.key)
+}
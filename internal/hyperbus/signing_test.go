@@ -0,0 +1,109 @@
+package hyperbus
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignEnvelope(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	// Sign and verify an envelope
+	env, err := SignEnvelope("node-1", priv, MsgClusterState, []byte("payload"))
+	assert.NoError(t, err)
+	assert.Len(t, env.Nonce, NonceSize)
+	assert.NoError(t, VerifyEnvelope(env, pub))
+
+	// Tampering with the payload breaks the signature
+	env.Payload = []byte("tampered")
+	assert.ErrorIs(t, VerifyEnvelope(env, pub), ErrInvalidSignature)
+
+	// A different key does not verify
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	env.Payload = []byte("payload")
+	assert.ErrorIs(t, VerifyEnvelope(env, otherPub), ErrInvalidSignature)
+}
+
+func TestNonceCache_RejectsReplay(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	cache := NewNonceCache(time.Minute, 100)
+
+	env, err := SignEnvelope("node-1", priv, MsgClusterState, []byte("payload"))
+	assert.NoError(t, err)
+
+	// First delivery is accepted
+	assert.NoError(t, VerifyEnvelope(env, pub))
+	assert.NoError(t, cache.Check(env))
+
+	// Replaying the identical signed message is rejected
+	assert.NoError(t, VerifyEnvelope(env, pub))
+	assert.ErrorIs(t, cache.Check(env), ErrReplayedMessage)
+
+	// A freshly signed message is accepted
+	env2, err := SignEnvelope("node-1", priv, MsgClusterState, []byte("payload"))
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Check(env2))
+}
+
+func TestNonceCache_EvictsByAge(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	cache := NewNonceCache(time.Minute, 100)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	env, err := SignEnvelope("node-1", priv, MsgClusterState, nil)
+	assert.NoError(t, err)
+	env.SentAtUnixNano = now.UnixNano()
+	assert.NoError(t, cache.Check(env))
+	assert.Equal(t, 1, cache.Len())
+
+	// After the window the nonce is forgotten and the replay is stale
+	now = now.Add(2 * time.Minute)
+	assert.ErrorIs(t, cache.Check(env), ErrStaleMessage)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestNonceCache_Bounded(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	cache := NewNonceCache(time.Minute, 10)
+	cache.now = func() time.Time { return now }
+
+	var first *proto.SignedEnvelope
+	for i := 0; i < 10; i++ {
+		env, err := SignEnvelope("node-1", priv, MsgClusterState, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, cache.Check(env))
+		if first == nil {
+			first = env
+		}
+	}
+
+	// A full cache refuses new envelopes rather than forget live nonces
+	env, err := SignEnvelope("node-1", priv, MsgClusterState, nil)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, cache.Check(env), ErrNonceCacheFull)
+	assert.ErrorIs(t, cache.Check(first), ErrReplayedMessage)
+	assert.Equal(t, 10, cache.Len())
+
+	// Room frees up as nonces age out of the window
+	now = now.Add(2 * time.Minute)
+	env, err = SignEnvelope("node-1", priv, MsgClusterState, nil)
+	assert.NoError(t, err)
+	env.SentAtUnixNano = now.UnixNano()
+	assert.NoError(t, cache.Check(env))
+	assert.Equal(t, 1, cache.Len())
+}
@@ -0,0 +1,494 @@
+package hyperbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// Frame kinds multiplexing streams over one TCP connection
+const (
+	// frameOpen opens a stream; its payload is the stream type
+	frameOpen byte = iota
+	// frameData carries one message on a stream
+	frameData
+	// frameClose tells the peer no more data follows on a stream
+	frameClose
+)
+
+// tcpFrameHeaderSize is the size of a frame's stream ID, kind and payload length
+const tcpFrameHeaderSize = 9
+
+// ErrConnectionClosed is returned when using a closed TCP connection
+var ErrConnectionClosed = errors.New("connection closed")
+
+// writeFrame writes one frame to w
+func writeFrame(w io.Writer, streamID uint32, kind byte, payload []byte) error {
+	buf := make([]byte, tcpFrameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], streamID)
+	buf[4] = kind
+	binary.BigEndian.PutUint32(buf[5:9], uint32(len(payload)))
+	copy(buf[tcpFrameHeaderSize:], payload)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFrame reads one frame from r, rejecting payloads that would carry a
+// message body larger than maxMessageSize
+func readFrame(r io.Reader, maxMessageSize uint32) (streamID uint32, kind byte, payload []byte, err error) {
+	header := make([]byte, tcpFrameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[5:9])
+	if size > HeaderSize {
+		if err := checkMessageSize(size-HeaderSize, maxMessageSize); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to read frame payload: %w", err)
+	}
+	return binary.BigEndian.Uint32(header[0:4]), header[4], payload, nil
+}
+
+// TCPConnection implements the Connection interface over a single TCP
+// connection, multiplexing streams as framed messages
+type TCPConnection struct {
+	nodeID  NodeID
+	conn    net.Conn
+	reader  *bufio.Reader
+	bus     *Bus
+	logger  *log.Logger
+	streams map[uint32]*TCPStream
+	nextID  uint32 // dialers use odd stream IDs, acceptors even ones
+	closed  chan struct{}
+	once    sync.Once
+	writeMu sync.Mutex
+	mu      sync.Mutex
+}
+
+// newTCPConnection wraps an established TCP connection to nodeID
+func newTCPConnection(bus *Bus, conn net.Conn, reader *bufio.Reader, nodeID NodeID, dialed bool) *TCPConnection {
+	c := &TCPConnection{
+		nodeID:  nodeID,
+		conn:    conn,
+		reader:  reader,
+		bus:     bus,
+		logger:  bus.logger.With("remote_node", nodeID),
+		streams: make(map[uint32]*TCPStream),
+		nextID:  2,
+		closed:  make(chan struct{}),
+	}
+	if dialed {
+		c.nextID = 1
+	}
+	return c
+}
+
+// NodeID returns the ID of the remote node
+func (c *TCPConnection) NodeID() NodeID {
+	return c.nodeID
+}
+
+// OpenStream opens a new stream of the specified type
+func (c *TCPConnection) OpenStream(ctx context.Context, streamType StreamType) (Stream, error) {
+	c.mu.Lock()
+	select {
+	case <-c.closed:
+		c.mu.Unlock()
+		return nil, ErrConnectionClosed
+	default:
+	}
+	stream := c.newStreamLocked(c.nextID)
+	c.nextID += 2
+	c.mu.Unlock()
+
+	if err := c.writeFrame(ctx, stream.id, frameOpen, []byte{byte(streamType)}); err != nil {
+		c.removeStream(stream.id)
+		return nil, fmt.Errorf("failed to open TCP stream: %w", err)
+	}
+	return stream, nil
+}
+
+// newStreamLocked registers a stream with the given ID
+func (c *TCPConnection) newStreamLocked(id uint32) *TCPStream {
+	stream := &TCPStream{id: id, conn: c, ready: make(chan struct{}, 1)}
+	c.streams[id] = stream
+	return stream
+}
+
+// stream returns the open stream with the given ID, if any
+func (c *TCPConnection) stream(id uint32) *TCPStream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.streams[id]
+}
+
+// removeStream forgets a finished stream
+func (c *TCPConnection) removeStream(id uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.streams, id)
+}
+
+// writeFrame writes a frame, honouring ctx's deadline
+func (c *TCPConnection) writeFrame(ctx context.Context, streamID uint32, kind byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	return writeFrame(c.conn, streamID, kind, payload)
+}
+
+// readLoop routes incoming frames to their streams until the connection fails
+func (c *TCPConnection) readLoop() {
+	defer c.Close()
+
+	for {
+		id, kind, payload, err := readFrame(c.reader, c.bus.maxMessageSize)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.logger.Debug("connection read failed", "error", err)
+			}
+			return
+		}
+
+		switch kind {
+		case frameOpen:
+			if len(payload) != 1 {
+				c.logger.Warn("malformed stream open", "stream_id", id)
+				return
+			}
+			c.mu.Lock()
+			stream := c.newStreamLocked(id)
+			c.mu.Unlock()
+			go c.bus.serveStream(context.Background(), c, stream, StreamType(payload[0]))
+		case frameData:
+			if stream := c.stream(id); stream != nil {
+				stream.deliver(payload)
+			}
+		case frameClose:
+			if stream := c.stream(id); stream != nil {
+				stream.finish()
+			}
+		default:
+			c.logger.Warn("unknown frame kind", "stream_id", id, "kind", kind)
+			return
+		}
+	}
+}
+
+// live reports whether the connection is still open
+func (c *TCPConnection) live() bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close closes the connection and every stream on it
+func (c *TCPConnection) Close() error {
+	var err error
+	c.once.Do(func() {
+		c.logger.Info("closing connection", "node_id", c.nodeID)
+		close(c.closed)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// TCPStream implements the Stream interface as a stream multiplexed on a
+// TCPConnection
+type TCPStream struct {
+	id     uint32
+	conn   *TCPConnection
+	queue  [][]byte
+	ready  chan struct{} // signalled when a message arrives or the peer finishes
+	eof    bool          // the peer will send no more
+	closed bool          // we will send no more
+	mu     sync.Mutex
+}
+
+// deliver queues a message received from the peer
+func (s *TCPStream) deliver(data []byte) {
+	s.mu.Lock()
+	s.queue = append(s.queue, data)
+	s.mu.Unlock()
+	s.signal()
+}
+
+// finish records that the peer closed its side of the stream
+func (s *TCPStream) finish() {
+	s.mu.Lock()
+	s.eof = true
+	done := s.closed
+	s.mu.Unlock()
+
+	if done {
+		s.conn.removeStream(s.id)
+	}
+	s.signal()
+}
+
+// signal wakes a blocked reader
+func (s *TCPStream) signal() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// ReadMessage reads a message from the stream, returning io.EOF once the
+// peer has closed its side and every message it sent has been read
+func (s *TCPStream) ReadMessage(ctx context.Context) ([]byte, error) {
+	for {
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			data := s.queue[0]
+			s.queue = s.queue[1:]
+			s.mu.Unlock()
+			return data, nil
+		}
+		eof := s.eof
+		s.mu.Unlock()
+
+		if eof {
+			return nil, io.EOF
+		}
+
+		select {
+		case <-s.ready:
+		case <-s.conn.closed:
+			// Messages may have arrived just before the close
+			s.mu.Lock()
+			pending := len(s.queue) > 0
+			s.mu.Unlock()
+			if !pending {
+				return nil, ErrConnectionClosed
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// WriteMessage writes a message to the stream
+func (s *TCPStream) WriteMessage(ctx context.Context, data []byte) error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return ErrStreamClosed
+	}
+	return s.conn.writeFrame(ctx, s.id, frameData, data)
+}
+
+// Close ends our side of the stream; the peer's replies can still be read
+func (s *TCPStream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	done := s.eof
+	s.mu.Unlock()
+
+	if done {
+		s.conn.removeStream(s.id)
+	}
+	if err := s.conn.writeFrame(context.Background(), s.id, frameClose, nil); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// ListenTCP accepts TCP connections on the local node's address. The local
+// node's address is updated to the one bound, so port 0 picks a free port.
+func (b *Bus) ListenTCP() error {
+	if b.localNode.Address == nil {
+		return fmt.Errorf("cannot listen: %w: %s", ErrNoAddress, b.localNode.ID)
+	}
+
+	listener, err := net.Listen("tcp", b.localNode.Address.String())
+	if err != nil {
+		return fmt.Errorf("failed to create TCP listener: %w", err)
+	}
+	b.listener = listener
+	b.localNode.Address = listener.Addr()
+
+	go b.acceptTCP(listener)
+	return nil
+}
+
+// acceptTCP accepts incoming TCP connections until the listener is closed
+func (b *Bus) acceptTCP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				b.logger.Error("failed to accept connection", "error", err)
+			}
+			return
+		}
+
+		go b.handleTCPConnection(conn)
+	}
+}
+
+// handleTCPConnection performs the ControlHello handshake on an inbound TCP
+// connection and registers it
+func (b *Bus) handleTCPConnection(conn net.Conn) {
+	b.logger.Info("handling new connection", "remote_addr", conn.RemoteAddr())
+
+	reader := bufio.NewReader(conn)
+	conn.SetDeadline(b.handshakeDeadline())
+
+	// The first stream must be a control stream carrying the ControlHello
+	id, kind, payload, err := readFrame(reader, b.maxMessageSize)
+	if err != nil {
+		b.logger.Error("failed to read control stream", "error", err)
+		conn.Close()
+		return
+	}
+	if kind != frameOpen || len(payload) != 1 || StreamType(payload[0]) != ControlStream {
+		b.logger.Error("expected control stream", "stream_id", id)
+		b.rejectTCPHello(conn, id, CloseProtocolError, "expected control stream")
+		return
+	}
+
+	_, kind, data, err := readFrame(reader, b.maxMessageSize)
+	if err != nil {
+		b.logger.Error("failed to read message", "error", err)
+		conn.Close()
+		return
+	}
+
+	receivedAt := time.Now()
+	header, err := DecodeHeader(data)
+	if err != nil || kind != frameData {
+		b.logger.Error("malformed message on control stream", "error", err)
+		b.rejectTCPHello(conn, id, CloseProtocolError, "malformed message header")
+		return
+	}
+	if header.Type != MsgControlHello {
+		b.logger.Error("expected ControlHello message", "received_type", header.Type)
+		b.rejectTCPHello(conn, id, CloseProtocolError, "expected ControlHello")
+		return
+	}
+
+	var hello proto.ControlHello
+	if err := DecodeMessage(data[HeaderSize:], &hello); err != nil {
+		b.logger.Error("failed to decode ControlHello", "error", err)
+		b.rejectTCPHello(conn, id, CloseProtocolError, fmt.Sprintf("malformed ControlHello: %v", err))
+		return
+	}
+	accepted, code, err := b.acceptHello(&hello)
+	if err != nil {
+		b.logger.Error("rejected ControlHello", "node_id", hello.NodeId, "error", err)
+		b.rejectTCPHello(conn, id, code, err.Error())
+		return
+	}
+
+	// Answer the hello, then end our side of the hello stream
+	for _, reply := range accepted.replies {
+		if err := writeFrame(conn, id, frameData, reply); err != nil {
+			b.logger.Error("failed to answer ControlHello", "error", err)
+			conn.Close()
+			return
+		}
+	}
+	if err := writeFrame(conn, id, frameClose, nil); err != nil {
+		b.logger.Error("failed to answer ControlHello", "error", err)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	// Create connection wrapper
+	tconn := newTCPConnection(b, conn, reader, NodeID(hello.NodeId), false)
+	b.setSession(tconn, accepted.version, accepted.macKey, hello.Pubkey, false)
+	go tconn.readLoop()
+
+	// Store connection
+	b.putConnection(NodeID(hello.NodeId), tconn)
+
+	if b.observer != nil {
+		b.observer.OnPeerHello(NodeID(hello.NodeId), &hello, receivedAt)
+	}
+
+	b.logger.Info("established connection with node", "node_id", hello.NodeId)
+}
+
+// rejectTCPHello tells the peer why its hello was refused, then closes the connection
+func (b *Bus) rejectTCPHello(conn net.Conn, streamID uint32, code CloseCode, reason string) {
+	defer conn.Close()
+
+	data, err := EncodeMessage(MsgError, &proto.ProtocolError{
+		Code:   uint64(code),
+		Reason: reason,
+	})
+	if err != nil {
+		return
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(helloRejectLinger))
+	if err := writeFrame(conn, streamID, frameData, data); err != nil {
+		b.logger.Debug("failed to send protocol error", "error", err)
+		return
+	}
+	writeFrame(conn, streamID, frameClose, nil)
+
+	// The peer usually closes first once it has read the error
+	conn.SetReadDeadline(time.Now().Add(helloRejectLinger))
+	io.Copy(io.Discard, conn)
+}
+
+// ConnectTCP establishes a TCP connection to a remote node and performs the
+// ControlHello handshake
+func (b *Bus) ConnectTCP(ctx context.Context, node NodeInfo) error {
+	if node.Address == nil {
+		return fmt.Errorf("cannot dial: %w: %s", ErrNoAddress, node.ID)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", node.Address.String())
+	if err != nil {
+		return fmt.Errorf("failed to dial remote node: %w", err)
+	}
+
+	// Create connection wrapper
+	tconn := newTCPConnection(b, conn, bufio.NewReader(conn), node.ID, true)
+	go tconn.readLoop()
+
+	// Store connection
+	b.putConnection(node.ID, tconn)
+
+	// Send ControlHello message
+	if _, err := b.handshake(ctx, tconn); err != nil {
+		b.removeConnection(node.ID, tconn)
+		tconn.Close()
+		return err
+	}
+
+	return nil
+}
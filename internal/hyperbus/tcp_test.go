@@ -0,0 +1,144 @@
+package hyperbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// helloObserver passes the IDs of nodes completing the handshake to a channel
+type helloObserver chan NodeID
+
+func (o helloObserver) OnPeerHello(nodeID NodeID, hello *proto.ControlHello, receivedAt time.Time) {
+	o <- nodeID
+}
+
+// newTCPBus creates a bus listening on a free loopback port, applying
+// configure before it starts listening
+func newTCPBus(t *testing.T, id NodeID, handler MessageHandler, configure ...func(*Bus)) *Bus {
+	bus := New(NodeInfo{ID: id, Address: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}}, handler, log.New(slog.LevelDebug))
+	for _, fn := range configure {
+		fn(bus)
+	}
+	assert.NoError(t, bus.ListenTCP())
+	t.Cleanup(func() { bus.Close() })
+	return bus
+}
+
+func TestTCPBus_SendControlMessage(t *testing.T) {
+	received := make(recordingHandler, 1)
+	hellos := make(helloObserver, 1)
+	server := newTCPBus(t, "server", received)
+	server.SetPeerObserver(hellos)
+	client := newTCPBus(t, "client", &mockHandler{})
+
+	assert.NoError(t, client.Connect(context.TODO(), server.LocalNode()))
+	assert.Equal(t, NodeID("client"), <-hellos)
+
+	msg, err := EncodeMessage(MsgClusterState, &proto.ClusterState{})
+	assert.NoError(t, err)
+	assert.NoError(t, client.SendControlMessage(context.TODO(), "server", msg))
+
+	select {
+	case data := <-received:
+		assert.Equal(t, msg, data)
+	case <-time.After(time.Second):
+		t.Fatal("control message not received")
+	}
+}
+
+func TestTCPBus_RequestResponse(t *testing.T) {
+	mux := NewMux()
+	mux.Handle(MsgPageRequest, &pageServer{payload: make([]byte, 4096)})
+	hellos := make(helloObserver, 1)
+	server := newTCPBus(t, "server", mux)
+	server.SetPeerObserver(hellos)
+	client := newTCPBus(t, "client", NewMux())
+
+	assert.NoError(t, client.Connect(context.TODO(), server.LocalNode()))
+	<-hellos
+
+	// Streams opened concurrently are kept apart on the one connection
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		go func(i int) {
+			resp, err := requestPage(context.TODO(), client, int32(i))
+			if err == nil {
+				assert.Len(t, resp.Payload, 4096)
+			}
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < 8; i++ {
+		assert.NoError(t, <-errs)
+	}
+}
+
+func TestTCPBus_ConcurrentConnectAndSend(t *testing.T) {
+	const senders = 8
+	received := make(recordingHandler, senders)
+	hellos := make(helloObserver, senders)
+	server := newTCPBus(t, "server", received)
+	server.SetPeerObserver(hellos)
+	client := newTCPBus(t, "client", &mockHandler{})
+
+	msg, err := EncodeMessage(MsgClusterState, &proto.ClusterState{})
+	assert.NoError(t, err)
+
+	// Every sender connects first, as the CLI does per request
+	errs := make(chan error, senders)
+	for i := 0; i < senders; i++ {
+		go func() {
+			if err := client.Connect(context.TODO(), server.LocalNode()); err != nil {
+				errs <- err
+				return
+			}
+			errs <- client.SendControlMessage(context.TODO(), "server", msg)
+		}()
+	}
+	for i := 0; i < senders; i++ {
+		assert.NoError(t, <-errs)
+	}
+	for i := 0; i < senders; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("control message not received")
+		}
+	}
+
+	// All senders shared a single connection
+	assert.Len(t, hellos, 1)
+	assert.Equal(t, []NodeID{"server"}, client.Peers())
+}
+
+func TestTCPBus_NilAddress(t *testing.T) {
+	bus := New(NodeInfo{ID: "node-1"}, &mockHandler{}, log.New(slog.LevelDebug))
+	assert.ErrorIs(t, bus.ListenTCP(), ErrNoAddress)
+	assert.ErrorIs(t, bus.ConnectTCP(context.TODO(), NodeInfo{ID: "node-2"}), ErrNoAddress)
+}
+
+func TestReadFrame_RejectsOversizedMessage(t *testing.T) {
+	// A frame header announcing a payload far above the limit, with no payload
+	header := make([]byte, tcpFrameHeaderSize)
+	binary.BigEndian.PutUint32(header[5:9], 1<<31)
+
+	_, _, payload, err := readFrame(bytes.NewReader(header), 1024)
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+	assert.Nil(t, payload)
+
+	// Frames within the limit are still read
+	frame := make([]byte, tcpFrameHeaderSize+HeaderSize+16)
+	binary.BigEndian.PutUint32(frame[5:9], HeaderSize+16)
+	_, _, payload, err = readFrame(bytes.NewReader(frame), 1024)
+	assert.NoError(t, err)
+	assert.Len(t, payload, HeaderSize+16)
+}
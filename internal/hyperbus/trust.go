@@ -0,0 +1,180 @@
+package hyperbus
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// trustedNodeHeader names the node holding a trusted key in its PEM block
+const trustedNodeHeader = "Node-Id"
+
+var (
+	// ErrUntrustedKey is returned when a peer presents a key missing from the trusted set
+	ErrUntrustedKey = errors.New("untrusted peer key")
+
+	// ErrCertificateMismatch is returned when a peer's hello doesn't match its TLS certificate
+	ErrCertificateMismatch = errors.New("hello does not match peer certificate")
+)
+
+// TrustedKeys is the set of Ed25519 public keys peers may present, each
+// with the ID of the node holding it, if one is named
+type TrustedKeys struct {
+	keys map[string]NodeID
+}
+
+// NewTrustedKeys creates a trusted set holding keys
+func NewTrustedKeys(keys ...ed25519.PublicKey) *TrustedKeys {
+	t := &TrustedKeys{keys: make(map[string]NodeID)}
+	for _, key := range keys {
+		t.Add(key)
+	}
+	return t
+}
+
+// LoadTrustedKeys reads a trusted keys file of PEM "PUBLIC KEY" blocks. A
+// block may name the node holding its key in a Node-Id header; keys without
+// one are held by the node whose ID NodeIDFromKey derives from them.
+func LoadTrustedKeys(path string) (*TrustedKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+
+	keys, err := ParseTrustedKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted keys %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ParseTrustedKeys decodes PEM "PUBLIC KEY" blocks holding PKIX Ed25519 keys
+func ParseTrustedKeys(data []byte) (*TrustedKeys, error) {
+	t := NewTrustedKeys()
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return t, nil
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unexpected %s block", block.Type)
+		}
+
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%T is not an ed25519 key", parsed)
+		}
+		t.AddNode(NodeID(block.Headers[trustedNodeHeader]), key)
+	}
+}
+
+// EncodeTrustedKey returns key as a PEM "PUBLIC KEY" block for a trusted
+// keys file, naming nodeID as its holder unless it is empty
+func EncodeTrustedKey(nodeID NodeID, key ed25519.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	block := &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	if nodeID != "" {
+		block.Headers = map[string]string{trustedNodeHeader: string(nodeID)}
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// Add trusts key, held by the node whose ID is derived from it
+func (t *TrustedKeys) Add(key ed25519.PublicKey) {
+	t.AddNode("", key)
+}
+
+// AddNode trusts key, held by nodeID; an empty nodeID stands for the ID
+// derived from the key
+func (t *TrustedKeys) AddNode(nodeID NodeID, key ed25519.PublicKey) {
+	t.keys[string(key)] = nodeID
+}
+
+// checkNode rejects nodeID unless it is the node holding key
+func (t *TrustedKeys) checkNode(nodeID NodeID, key ed25519.PublicKey) error {
+	holder, exists := t.keys[string(key)]
+	if !exists {
+		return fmt.Errorf("%w: node %s", ErrUntrustedKey, NodeIDFromKey(key))
+	}
+	if holder == "" {
+		holder = NodeIDFromKey(key)
+	}
+	if nodeID != holder {
+		return fmt.Errorf("%w: node %s presented the key of %s", ErrCertificateMismatch, nodeID, holder)
+	}
+	return nil
+}
+
+// Contains reports whether key is trusted
+func (t *TrustedKeys) Contains(key ed25519.PublicKey) bool {
+	_, exists := t.keys[string(key)]
+	return exists
+}
+
+// Len returns the number of trusted keys
+func (t *TrustedKeys) Len() int {
+	return len(t.keys)
+}
+
+// verifyPeerCertificate is a tls.Config.VerifyPeerCertificate callback
+// accepting only leaf certificates whose Ed25519 key is trusted
+func (t *TrustedKeys) verifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("%w: peer presented no certificate", ErrUntrustedKey)
+	}
+
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return fmt.Errorf("invalid peer certificate: %w", err)
+	}
+	key, ok := cert.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: peer certificate holds a %T, not an ed25519 key", ErrUntrustedKey, cert.PublicKey)
+	}
+	if !t.Contains(key) {
+		return fmt.Errorf("%w: node %s", ErrUntrustedKey, NodeIDFromKey(key))
+	}
+	return nil
+}
+
+// peerCertificateKey returns the Ed25519 key of the certificate a peer presented, if any
+func peerCertificateKey(state tls.ConnectionState) (ed25519.PublicKey, bool) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, false
+	}
+	key, ok := state.PeerCertificates[0].PublicKey.(ed25519.PublicKey)
+	return key, ok
+}
+
+// checkPeerCertificate rejects a peer whose hello, naming nodeID and key,
+// doesn't match the certificate it presented. With trusted set, the
+// certificate is required and nodeID must hold its key.
+func checkPeerCertificate(state tls.ConnectionState, trusted *TrustedKeys, nodeID NodeID, key ed25519.PublicKey) error {
+	certKey, ok := peerCertificateKey(state)
+	if !ok {
+		if trusted != nil {
+			return fmt.Errorf("%w: %s presented no ed25519 certificate", ErrCertificateMismatch, nodeID)
+		}
+		return nil
+	}
+	if (trusted != nil || len(key) > 0) && !bytes.Equal(key, certKey) {
+		return fmt.Errorf("%w: %s sent a key other than its certificate's", ErrCertificateMismatch, nodeID)
+	}
+	if trusted != nil {
+		return trusted.checkNode(nodeID, certKey)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package hyperbus
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// newIdentity generates an ed25519 identity key
+func newIdentity(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	return key
+}
+
+// newPinnedQUICBus creates a QUIC bus on a free loopback port presenting
+// identity and trusting only trusted
+func newPinnedQUICBus(t *testing.T, identity ed25519.PrivateKey, trusted ...ed25519.PublicKey) *QUICBus {
+	id := NodeIDFromKey(identity.Public().(ed25519.PublicKey))
+	bus, err := NewQUICBus(
+		NodeInfo{ID: id, Address: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}},
+		&mockHandler{},
+		log.New(slog.LevelDebug),
+		WithIdentity(identity),
+		WithTrustedKeys(NewTrustedKeys(trusted...)),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { bus.Close() })
+	return bus
+}
+
+func TestLoadTrustedKeys(t *testing.T) {
+	first := newIdentity(t).Public().(ed25519.PublicKey)
+	second := newIdentity(t).Public().(ed25519.PublicKey)
+
+	// The first key's holder is named, the second's derived from the key
+	var data []byte
+	for nodeID, key := range map[NodeID]ed25519.PublicKey{"node-1": first, "": second} {
+		block, err := EncodeTrustedKey(nodeID, key)
+		assert.NoError(t, err)
+		data = append(data, block...)
+	}
+	path := filepath.Join(t.TempDir(), "trusted_keys.pem")
+	assert.NoError(t, os.WriteFile(path, data, 0600))
+
+	keys, err := LoadTrustedKeys(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, keys.Len())
+	assert.True(t, keys.Contains(first))
+	assert.True(t, keys.Contains(second))
+	assert.False(t, keys.Contains(newIdentity(t).Public().(ed25519.PublicKey)))
+
+	assert.NoError(t, keys.checkNode("node-1", first))
+	assert.ErrorIs(t, keys.checkNode(NodeIDFromKey(first), first), ErrCertificateMismatch)
+	assert.NoError(t, keys.checkNode(NodeIDFromKey(second), second))
+	assert.ErrorIs(t, keys.checkNode("node-1", second), ErrCertificateMismatch)
+
+	_, err = ParseTrustedKeys([]byte("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"))
+	assert.ErrorContains(t, err, "unexpected CERTIFICATE block")
+}
+
+func TestQUICBus_TrustedKeys(t *testing.T) {
+	serverKey, trustedKey, untrustedKey := newIdentity(t), newIdentity(t), newIdentity(t)
+	serverPub := serverKey.Public().(ed25519.PublicKey)
+	server := newPinnedQUICBus(t, serverKey, trustedKey.Public().(ed25519.PublicKey))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("trusted", func(t *testing.T) {
+		client := newPinnedQUICBus(t, trustedKey, serverPub)
+		assert.NoError(t, client.Connect(ctx, server.LocalNode()))
+	})
+
+	t.Run("client not trusted by server", func(t *testing.T) {
+		client := newPinnedQUICBus(t, untrustedKey, serverPub)
+		err := client.Connect(ctx, server.LocalNode())
+		assert.ErrorContains(t, err, "bad certificate")
+		assert.Empty(t, client.Peers())
+	})
+
+	t.Run("server not trusted by client", func(t *testing.T) {
+		client := newPinnedQUICBus(t, trustedKey)
+		err := client.Connect(ctx, server.LocalNode())
+		assert.ErrorContains(t, err, ErrUntrustedKey.Error())
+		assert.Empty(t, client.Peers())
+	})
+}
+
+func TestQUICBus_HelloMatchesCertificate(t *testing.T) {
+	serverKey, clientKey := newIdentity(t), newIdentity(t)
+	clientPub := clientKey.Public().(ed25519.PublicKey)
+	server := newPinnedQUICBus(t, serverKey, clientPub)
+	serverPub := serverKey.Public().(ed25519.PublicKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("key other than the certificate's", func(t *testing.T) {
+		client := newPinnedQUICBus(t, clientKey, serverPub)
+		client.localNode.PublicKey = newIdentity(t).Public().(ed25519.PublicKey)
+		err := client.Connect(ctx, server.LocalNode())
+		assert.ErrorIs(t, err, ErrHandshakeRejected)
+		assert.ErrorContains(t, err, "other than its certificate's")
+		assert.Empty(t, server.Peers())
+	})
+
+	t.Run("node ID not holding the key", func(t *testing.T) {
+		client := newPinnedQUICBus(t, clientKey, serverPub)
+		client.localNode.ID = "impostor"
+		err := client.Connect(ctx, server.LocalNode())
+		assert.ErrorIs(t, err, ErrHandshakeRejected)
+		assert.ErrorContains(t, err, "impostor presented the key of")
+		assert.Empty(t, server.Peers())
+	})
+
+	t.Run("dialed node not holding the key", func(t *testing.T) {
+		client := newPinnedQUICBus(t, clientKey, serverPub)
+		node := server.LocalNode()
+		node.ID = "someone-else"
+		err := client.Connect(ctx, node)
+		assert.ErrorIs(t, err, ErrCertificateMismatch)
+		assert.Empty(t, client.Peers())
+	})
+}
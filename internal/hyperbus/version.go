@@ -0,0 +1,277 @@
+package hyperbus
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// Protocol versions spoken by this build. Version 2 added request IDs to
+// the message header, so version 1 peers can't parse its messages.
+const (
+	ProtocolVersion    uint32 = 2
+	MinProtocolVersion uint32 = 2
+)
+
+// DefaultHandshakeTimeout bounds the ControlHello exchange unless changed with SetHandshakeTimeout
+const DefaultHandshakeTimeout = 10 * time.Second
+
+var (
+	// ErrIncompatibleVersion is returned when two nodes share no protocol version
+	ErrIncompatibleVersion = errors.New("incompatible protocol version")
+
+	// ErrHandshakeRejected is returned when the peer refuses our hello
+	ErrHandshakeRejected = errors.New("peer rejected handshake")
+
+	// ErrDuplicateNodeID is returned when a node claims an ID another node holds
+	ErrDuplicateNodeID = errors.New("duplicate node ID")
+
+	// ErrPlacementMismatch is returned when two nodes place pages with different hashes
+	ErrPlacementMismatch = errors.New("placement hash mismatch")
+)
+
+// session holds what the handshake established for a connection
+type session struct {
+	version uint32
+	macKey  []byte            // set when the PQ key exchange ran
+	peerKey ed25519.PublicKey // identity key from the peer's hello, if any
+
+	// Control message sequence numbers when the PQ handshake ran
+	initiator bool          // the local node dialed the connection
+	sent      atomic.Uint64 // last number sent
+	received  replayWindow
+
+	// When a message last arrived from the peer, in Unix nanoseconds
+	lastSeen atomic.Int64
+}
+
+// SetProtocolVersions sets the range of protocol versions the bus accepts
+func (b *Bus) SetProtocolVersions(minVersion, maxVersion uint32) {
+	b.minVersion = minVersion
+	b.maxVersion = maxVersion
+}
+
+// SetHandshakeTimeout bounds the ControlHello exchange on new connections.
+// Connections whose peer doesn't complete it in time are dropped; 0 leaves
+// the handshake unbounded.
+func (b *Bus) SetHandshakeTimeout(timeout time.Duration) {
+	b.handshakeTimeout = timeout
+}
+
+// SetPlacementHash sets the name of the hash placing pages on the ring,
+// sent in every hello. Nodes placing pages with different hashes would
+// disagree on page owners, so their handshakes fail. An empty name, for
+// nodes placing no pages, matches any peer.
+func (b *Bus) SetPlacementHash(name string) {
+	b.placementHash = name
+}
+
+// checkPlacementHash rejects a hello from a node placing pages with another hash
+func (b *Bus) checkPlacementHash(hello *proto.ControlHello) error {
+	if b.placementHash == "" || hello.PlacementHash == "" || hello.PlacementHash == b.placementHash {
+		return nil
+	}
+	return fmt.Errorf("%w: local places pages with %s, peer %s with %s",
+		ErrPlacementMismatch, b.placementHash, hello.NodeId, hello.PlacementHash)
+}
+
+// handshakeDeadline returns when a handshake starting now must finish, or
+// the zero time if handshakes are unbounded
+func (b *Bus) handshakeDeadline() time.Time {
+	if b.handshakeTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(b.handshakeTimeout)
+}
+
+// PeerVersion returns the protocol version negotiated with a connected node
+func (b *Bus) PeerVersion(nodeID NodeID) (uint32, bool) {
+	conn, exists := b.getConnection(nodeID)
+	if !exists {
+		return 0, false
+	}
+
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+
+	s, exists := b.sessions[conn]
+	if !exists {
+		return 0, false
+	}
+	return s.version, true
+}
+
+// setSession records the outcome of the handshake on a connection, which
+// the local node dialed if initiator is true
+func (b *Bus) setSession(conn Connection, version uint32, macKey []byte, peerKey ed25519.PublicKey, initiator bool) {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	s := &session{version: version, macKey: macKey, peerKey: peerKey, initiator: initiator}
+	s.lastSeen.Store(time.Now().UnixNano())
+	b.sessions[conn] = s
+}
+
+// negotiateVersion picks the highest version both the bus and the hello's
+// sender support. Hellos without versions predate negotiation and speak version 1.
+func (b *Bus) negotiateVersion(hello *proto.ControlHello) (uint32, error) {
+	peerMin, peerMax := hello.MinProtocolVersion, hello.ProtocolVersion
+	if peerMax == 0 {
+		peerMin, peerMax = 1, 1
+	}
+
+	version := min(b.maxVersion, peerMax)
+	if version < max(b.minVersion, peerMin) {
+		return 0, fmt.Errorf("%w: local supports %d-%d, peer %s supports %d-%d",
+			ErrIncompatibleVersion, b.minVersion, b.maxVersion, hello.NodeId, peerMin, peerMax)
+	}
+	return version, nil
+}
+
+// helloMessage encodes the ControlHello this bus sends
+func (b *Bus) helloMessage() ([]byte, error) {
+	return EncodeMessage(MsgControlHello, &proto.ControlHello{
+		NodeId:             string(b.localNode.ID),
+		Caps:               b.localNode.Capabilities,
+		Pubkey:             b.localNode.PublicKey,
+		PqPubkey:           b.localNode.PQPublicKey,
+		PqPubkeySig:        b.pqKeySignature(),
+		PlacementHash:      b.placementHash,
+		SentAtUnixNano:     time.Now().UnixNano(),
+		ProtocolVersion:    b.maxVersion,
+		MinProtocolVersion: b.minVersion,
+	})
+}
+
+// helloReply is what the dialing side of a handshake learned from the peer
+type helloReply struct {
+	version uint32
+	nodeID  NodeID            // empty if the peer predates negotiation
+	peerKey ed25519.PublicKey // likewise
+}
+
+// readHelloReply reads the peer's answer to our hello and returns the
+// negotiated version and the peer's ID and identity key. A peer that closes
+// the stream without answering predates negotiation.
+func (b *Bus) readHelloReply(ctx context.Context, stream Stream) (*helloReply, error) {
+	data, err := stream.ReadMessage(ctx)
+	if errors.Is(err, io.EOF) {
+		version, err := b.negotiateVersion(&proto.ControlHello{})
+		return &helloReply{version: version}, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hello reply: %w", err)
+	}
+
+	header, err := DecodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	switch header.Type {
+	case MsgControlHello:
+		var hello proto.ControlHello
+		if err := DecodeMessage(data[HeaderSize:], &hello); err != nil {
+			return nil, fmt.Errorf("failed to decode hello reply: %w", err)
+		}
+		if err := b.checkPlacementHash(&hello); err != nil {
+			return nil, err
+		}
+		version, err := b.negotiateVersion(&hello)
+		return &helloReply{version: version, nodeID: NodeID(hello.NodeId), peerKey: hello.Pubkey}, err
+	case MsgError:
+		var protoErr proto.ProtocolError
+		if err := DecodeMessage(data[HeaderSize:], &protoErr); err != nil {
+			return nil, err
+		}
+		switch CloseCode(protoErr.Code) {
+		case CloseIncompatibleVersion:
+			return nil, fmt.Errorf("%w: %s", ErrIncompatibleVersion, protoErr.Reason)
+		case CloseDuplicateNodeID:
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateNodeID, protoErr.Reason)
+		case ClosePlacementMismatch:
+			return nil, fmt.Errorf("%w: %s", ErrPlacementMismatch, protoErr.Reason)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrHandshakeRejected, protoErr.Reason)
+	default:
+		return nil, fmt.Errorf("%w: unexpected message type %d", ErrHandshakeRejected, header.Type)
+	}
+}
+
+// helloAcceptance is what the accepting side of a handshake established
+type helloAcceptance struct {
+	version uint32
+	macKey  []byte
+
+	// Messages to send back on the hello stream
+	replies [][]byte
+}
+
+// acceptHello negotiates an inbound hello. It returns the replies to send
+// back, or the close code and error to reject the hello with.
+func (b *Bus) acceptHello(hello *proto.ControlHello) (*helloAcceptance, CloseCode, error) {
+	if err := b.checkNodeID(hello); err != nil {
+		b.logger.Error("refusing node with a duplicate ID; check node.id in its config",
+			"node_id", hello.NodeId, "peer_key", fmt.Sprintf("%x", hello.Pubkey), "error", err)
+		return nil, CloseDuplicateNodeID, err
+	}
+
+	version, err := b.negotiateVersion(hello)
+	if err != nil {
+		return nil, CloseIncompatibleVersion, err
+	}
+
+	if err := b.checkPlacementHash(hello); err != nil {
+		return nil, ClosePlacementMismatch, err
+	}
+
+	exchange, macKey, err := b.acceptKeyExchange(hello)
+	if err != nil {
+		return nil, CloseAuthFailure, err
+	}
+
+	reply, err := b.helloMessage()
+	if err != nil {
+		return nil, CloseProtocolError, fmt.Errorf("failed to encode ControlHello: %w", err)
+	}
+
+	accepted := &helloAcceptance{version: version, macKey: macKey, replies: [][]byte{reply}}
+	if exchange != nil {
+		accepted.replies = append(accepted.replies, exchange)
+	}
+	return accepted, 0, nil
+}
+
+// checkNodeID rejects a hello claiming this node's ID without its key, or
+// the ID of a connected node that presented a different key. Keys are only
+// compared when both the hello and the connected node carry one.
+func (b *Bus) checkNodeID(hello *proto.ControlHello) error {
+	nodeID := NodeID(hello.NodeId)
+	if nodeID == b.localNode.ID {
+		if len(b.localNode.PublicKey) == 0 || !bytes.Equal(hello.Pubkey, b.localNode.PublicKey) {
+			return fmt.Errorf("%w: %s is the ID of the local node", ErrDuplicateNodeID, nodeID)
+		}
+		return nil
+	}
+
+	if !b.liveConnection(nodeID) || len(hello.Pubkey) == 0 {
+		return nil
+	}
+	conn, exists := b.getConnection(nodeID)
+	if !exists {
+		return nil
+	}
+
+	b.connMu.RLock()
+	s, exists := b.sessions[conn]
+	b.connMu.RUnlock()
+	if !exists || len(s.peerKey) == 0 || bytes.Equal(s.peerKey, hello.Pubkey) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is held by a connected node with a different key", ErrDuplicateNodeID, nodeID)
+}
@@ -0,0 +1,188 @@
+package hyperbus
+
+import (
+	"context"
+	"crypto/ed25519"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		local      [2]uint32
+		peer       [2]uint32
+		expected   uint32
+		compatible bool
+	}{
+		{name: "same range", local: [2]uint32{1, 2}, peer: [2]uint32{1, 2}, expected: 2, compatible: true},
+		{name: "peer newer", local: [2]uint32{1, 2}, peer: [2]uint32{1, 3}, expected: 2, compatible: true},
+		{name: "peer older", local: [2]uint32{1, 3}, peer: [2]uint32{1, 2}, expected: 2, compatible: true},
+		{name: "legacy peer", local: [2]uint32{1, 2}, peer: [2]uint32{0, 0}, expected: 1, compatible: true},
+		{name: "no overlap", local: [2]uint32{3, 4}, peer: [2]uint32{1, 2}, compatible: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := New(NodeInfo{ID: "local"}, &mockHandler{}, log.New(slog.LevelDebug))
+			bus.SetProtocolVersions(tt.local[0], tt.local[1])
+
+			version, err := bus.negotiateVersion(&proto.ControlHello{
+				NodeId:             "peer",
+				MinProtocolVersion: tt.peer[0],
+				ProtocolVersion:    tt.peer[1],
+			})
+			if !tt.compatible {
+				assert.ErrorIs(t, err, ErrIncompatibleVersion)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, version)
+		})
+	}
+}
+
+func TestBus_VersionNegotiation(t *testing.T) {
+	hellos := make(helloObserver, 1)
+	server := newTCPBus(t, "server", &mockHandler{}, func(b *Bus) {
+		b.SetPeerObserver(hellos)
+		b.SetProtocolVersions(1, 3)
+	})
+	client := newTCPBus(t, "client", &mockHandler{}, func(b *Bus) { b.SetProtocolVersions(1, 2) })
+
+	assert.NoError(t, client.Connect(context.TODO(), server.LocalNode()))
+	<-hellos
+
+	// Both sides settle on the highest version they share
+	version, ok := client.PeerVersion("server")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), version)
+	version, ok = server.PeerVersion("client")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), version)
+}
+
+func TestBus_IncompatibleVersion(t *testing.T) {
+	server := newTCPBus(t, "server", &mockHandler{}, func(b *Bus) { b.SetProtocolVersions(3, 3) })
+	client := newTCPBus(t, "client", &mockHandler{}, func(b *Bus) { b.SetProtocolVersions(1, 2) })
+
+	err := client.Connect(context.TODO(), server.LocalNode())
+	assert.ErrorIs(t, err, ErrIncompatibleVersion)
+	assert.Empty(t, client.Peers())
+	assert.Empty(t, server.Peers())
+}
+
+func TestBus_HandshakeTimeout(t *testing.T) {
+	t.Run("stalled server", func(t *testing.T) {
+		// A listener that accepts but never answers the hello
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				defer conn.Close()
+				io.Copy(io.Discard, conn)
+			}
+		}()
+
+		client := newTCPBus(t, "client", &mockHandler{}, func(b *Bus) { b.SetHandshakeTimeout(100 * time.Millisecond) })
+
+		start := time.Now()
+		err = client.Connect(context.TODO(), NodeInfo{ID: "server", Address: listener.Addr()})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.Empty(t, client.Peers())
+	})
+
+	t.Run("stalled client", func(t *testing.T) {
+		server := newTCPBus(t, "server", &mockHandler{}, func(b *Bus) { b.SetHandshakeTimeout(100 * time.Millisecond) })
+
+		// A peer that connects but never sends its hello is dropped
+		conn, err := net.Dial("tcp", server.LocalNode().Address.String())
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, err = conn.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Empty(t, server.Peers())
+	})
+}
+
+func TestBus_DuplicateNodeID(t *testing.T) {
+	withKey := func(b *Bus) {
+		pub, _, err := ed25519.GenerateKey(nil)
+		assert.NoError(t, err)
+		b.localNode.PublicKey = pub
+	}
+
+	hellos := make(helloObserver, 1)
+	server := newTCPBus(t, "server", &mockHandler{}, withKey, func(b *Bus) { b.SetPeerObserver(hellos) })
+	worker := newTCPBus(t, "worker", &mockHandler{}, withKey)
+	assert.NoError(t, worker.Connect(context.TODO(), server.LocalNode()))
+	assert.Equal(t, NodeID("worker"), <-hellos)
+
+	// A second node configured with the worker's ID is refused
+	impostor := newTCPBus(t, "worker", &mockHandler{}, withKey)
+	err := impostor.Connect(context.TODO(), server.LocalNode())
+	assert.ErrorIs(t, err, ErrDuplicateNodeID)
+	assert.Empty(t, impostor.Peers())
+
+	// So is one claiming the server's own ID
+	twin := newTCPBus(t, "server", &mockHandler{}, withKey)
+	err = twin.Connect(context.TODO(), server.LocalNode())
+	assert.ErrorIs(t, err, ErrDuplicateNodeID)
+
+	// The worker keeps its connection
+	assert.Equal(t, []NodeID{"worker"}, server.Peers())
+	msg, err := EncodeMessage(MsgClusterState, &proto.ClusterState{})
+	assert.NoError(t, err)
+	assert.NoError(t, worker.SendControlMessage(context.TODO(), "server", msg))
+}
+
+func TestBus_PlacementHashMismatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		server, client string
+		compatible     bool
+	}{
+		{name: "same hash", server: "xxhash64", client: "xxhash64", compatible: true},
+		{name: "client places no pages", server: "xxhash64", client: "", compatible: true},
+		{name: "different hashes", server: "xxhash64", client: "fnv1a64", compatible: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTCPBus(t, "server", &mockHandler{}, func(b *Bus) { b.SetPlacementHash(tt.server) })
+			client := newTCPBus(t, "client", &mockHandler{}, func(b *Bus) { b.SetPlacementHash(tt.client) })
+
+			err := client.Connect(context.TODO(), server.LocalNode())
+			if tt.compatible {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrPlacementMismatch)
+			assert.ErrorContains(t, err, "fnv1a64")
+			assert.Empty(t, client.Peers())
+			assert.Empty(t, server.Peers())
+		})
+	}
+}
+
+func TestBus_CheckPlacementHashOnReply(t *testing.T) {
+	bus := New(NodeInfo{ID: "local"}, &mockHandler{}, log.New(slog.LevelDebug))
+	bus.SetPlacementHash("xxhash64")
+
+	// The dialing side checks the accepting node's hello too
+	err := bus.checkPlacementHash(&proto.ControlHello{NodeId: "peer", PlacementHash: "crc64-iso"})
+	assert.ErrorIs(t, err, ErrPlacementMismatch)
+	assert.NoError(t, bus.checkPlacementHash(&proto.ControlHello{NodeId: "peer"}))
+}
@@ -2,6 +2,8 @@ package log
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 )
@@ -11,6 +13,28 @@ type Logger struct {
 	*slog.Logger
 }
 
+// Format selects how log records are encoded
+type Format string
+
+const (
+	// FormatJSON writes one JSON object per record
+	FormatJSON Format = "json"
+	// FormatText writes human-readable key=value lines
+	FormatText Format = "text"
+)
+
+// Options configures a logger
+type Options struct {
+	// Level is the minimum level logged
+	Level slog.Level
+
+	// Format is the record encoding, JSON if empty
+	Format Format
+
+	// Output is where records are written, stdout if nil
+	Output io.Writer
+}
+
 // New creates a new logger with the specified level
 func New(level slog.Level) *Logger {
 	opts := &slog.HandlerOptions{
@@ -20,6 +44,28 @@ func New(level slog.Level) *Logger {
 	return &Logger{slog.New(handler)}
 }
 
+// NewWithOptions creates a logger writing in the given format and destination
+func NewWithOptions(options Options) (*Logger, error) {
+	output := options.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	opts := &slog.HandlerOptions{
+		Level: options.Level,
+	}
+
+	var handler slog.Handler
+	switch options.Format {
+	case FormatJSON, "":
+		handler = slog.NewJSONHandler(output, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(output, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format: %q", options.Format)
+	}
+	return &Logger{slog.New(handler)}, nil
+}
+
 type contextKey string
 
 // FromContext retrieves a logger from context, or returns the default logger
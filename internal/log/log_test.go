@@ -0,0 +1,47 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithOptions_Text(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewWithOptions(Options{Level: slog.LevelInfo, Format: FormatText, Output: &buf})
+	assert.NoError(t, err)
+
+	logger.Info("page fetched", "page_id", 7)
+	logger.Debug("below the level")
+
+	line := strings.TrimSpace(buf.String())
+	assert.NotContains(t, line, "\n")
+	assert.Contains(t, line, "level=INFO")
+	assert.Contains(t, line, `msg="page fetched"`)
+	assert.Contains(t, line, "page_id=7")
+	assert.False(t, json.Valid([]byte(line)))
+}
+
+func TestNewWithOptions_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewWithOptions(Options{Level: slog.LevelDebug, Format: FormatJSON, Output: &buf})
+	assert.NoError(t, err)
+
+	logger.With("node_id", "node-1").Debug("joined", "members", 3)
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "DEBUG", record["level"])
+	assert.Equal(t, "joined", record["msg"])
+	assert.Equal(t, "node-1", record["node_id"])
+	assert.Equal(t, float64(3), record["members"])
+}
+
+func TestNewWithOptions_UnknownFormat(t *testing.T) {
+	_, err := NewWithOptions(Options{Format: "xml"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package membership
+
+import (
+	"time"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// DefaultMaxClockSkew is the skew above which a peer's clock is reported
+const DefaultMaxClockSkew = 500 * time.Millisecond
+
+// SetMaxClockSkew sets the skew threshold above which a warning is logged
+func (m *Membership) SetMaxClockSkew(maxSkew time.Duration) {
+	m.skewMu.Lock()
+	defer m.skewMu.Unlock()
+	m.maxSkew = maxSkew
+}
+
+// ObserveClock records a peer's clock reading taken when one of its messages was sent.
+// The estimate ignores one-way network latency, which is small relative to lease and
+// suspect timeouts. It returns true if the skew exceeds the threshold.
+func (m *Membership) ObserveClock(nodeID hyperbus.NodeID, remote, local time.Time) bool {
+	skew := remote.Sub(local)
+
+	m.skewMu.Lock()
+	m.skews[nodeID] = skew
+	maxSkew := m.maxSkew
+	m.skewMu.Unlock()
+
+	if abs(skew) <= maxSkew {
+		return false
+	}
+
+	m.logger.Warn("clock skew exceeds threshold",
+		"member_id", nodeID,
+		"skew", skew,
+		"threshold", maxSkew)
+	return true
+}
+
+// ClockSkew returns the last estimated skew of a peer's clock relative to ours.
+// A positive value means the peer's clock is ahead.
+func (m *Membership) ClockSkew(nodeID hyperbus.NodeID) time.Duration {
+	m.skewMu.RLock()
+	defer m.skewMu.RUnlock()
+	return m.skews[nodeID]
+}
+
+// ClockSkews returns the skew estimates for all observed peers
+func (m *Membership) ClockSkews() map[hyperbus.NodeID]time.Duration {
+	m.skewMu.RLock()
+	defer m.skewMu.RUnlock()
+
+	skews := make(map[hyperbus.NodeID]time.Duration, len(m.skews))
+	for nodeID, skew := range m.skews {
+		skews[nodeID] = skew
+	}
+	return skews
+}
+
+// OnPeerHello implements hyperbus.PeerObserver
+func (m *Membership) OnPeerHello(nodeID hyperbus.NodeID, hello *proto.ControlHello, receivedAt time.Time) {
+	if hello.SentAtUnixNano == 0 {
+		return
+	}
+	m.ObserveClock(nodeID, time.Unix(0, hello.SentAtUnixNano), receivedAt)
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,80 @@
+package membership
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMembership_ClockSkew(t *testing.T) {
+	// Capture log output to check for the warning
+	var buf bytes.Buffer
+	logger := &log.Logger{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+
+	// Create local member
+	localMember := &Member{
+		ID:       "local-node",
+		Address:  &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8443},
+		LastSeen: time.Now(),
+		Status:   Alive,
+	}
+
+	// Create membership manager
+	membership := NewMembership(localMember, logger)
+
+	// A peer within the threshold is recorded without a warning
+	now := time.Now()
+	hello := &proto.ControlHello{
+		NodeId:         "close-node",
+		SentAtUnixNano: now.Add(100 * time.Millisecond).UnixNano(),
+	}
+	membership.OnPeerHello("close-node", hello, now)
+	assert.Equal(t, 100*time.Millisecond, membership.ClockSkew("close-node"))
+	assert.NotContains(t, buf.String(), "clock skew exceeds threshold")
+
+	// A peer two seconds behind triggers a warning
+	hello = &proto.ControlHello{
+		NodeId:         "skewed-node",
+		SentAtUnixNano: now.Add(-2 * time.Second).UnixNano(),
+	}
+	membership.OnPeerHello("skewed-node", hello, now)
+	assert.Equal(t, -2*time.Second, membership.ClockSkew("skewed-node"))
+	assert.Contains(t, buf.String(), "clock skew exceeds threshold")
+	assert.Contains(t, buf.String(), "skewed-node")
+
+	// Unknown peers have no skew
+	assert.Equal(t, time.Duration(0), membership.ClockSkew("unknown-node"))
+	assert.Len(t, membership.ClockSkews(), 2)
+}
+
+func TestSWIM_GossipClockSkew(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	// Create local member
+	localMember := &Member{
+		ID:       "local-node",
+		Address:  &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8443},
+		LastSeen: time.Now(),
+		Status:   Alive,
+	}
+
+	// Create SWIM instance
+	membership := NewMembership(localMember, logger)
+	swim := NewSWIM(membership, nil, DefaultSWIMConfig(), logger)
+
+	// Gossip stamped an hour in the future
+	swim.HandleGossipMessage(context.Background(), &proto.ClusterState{
+		SenderId:       "remote-node",
+		SentAtUnixNano: time.Now().Add(time.Hour).UnixNano(),
+	})
+
+	skew := membership.ClockSkew("remote-node")
+	assert.InDelta(t, float64(time.Hour), float64(skew), float64(time.Second))
+}
@@ -2,18 +2,254 @@ package membership
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"time"
 
+	"github.com/melihxz/holocompute/internal/hyperbus"
 	"github.com/melihxz/holocompute/pkg/proto"
 )
 
+// GossipParticipant piggybacks its own state on membership gossip
+type GossipParticipant interface {
+	// ContributeState adds local state to an outgoing gossip message
+	ContributeState(state *proto.ClusterState)
+
+	// ObserveState applies state from an incoming gossip message
+	ObserveState(state *proto.ClusterState)
+}
+
+// AddGossipParticipant registers state to be exchanged with gossip
+func (s *SWIM) AddGossipParticipant(participant GossipParticipant) {
+	s.participants = append(s.participants, participant)
+}
+
+// localState builds the outgoing gossip message
+func (s *SWIM) localState() *proto.ClusterState {
+	now := time.Now()
+	state := &proto.ClusterState{
+		SenderId:       string(s.localMember.ID),
+		SentAtUnixNano: now.UnixNano(),
+	}
+
+	// We vouch for ourselves as of now, and pass on what we know of the rest
+	s.mu.RLock()
+	self := *s.localMember
+	self.Status, self.LastSeen = Alive, now
+	state.Members = append(state.Members, memberToProto(&self))
+	for _, member := range s.members {
+		if member.ID != s.localMember.ID {
+			state.Members = append(state.Members, memberToProto(member))
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, participant := range s.participants {
+		participant.ContributeState(state)
+	}
+	return state
+}
+
+// memberToProto encodes a member for gossip
+func memberToProto(member *Member) *proto.MemberState {
+	state := &proto.MemberState{
+		NodeId:           string(member.ID),
+		Status:           int32(member.Status),
+		LastSeenUnixNano: member.LastSeen.UnixNano(),
+		Caps:             member.Capabilities,
+		Incarnation:      member.Incarnation,
+	}
+	if member.Address != nil {
+		state.Address = member.Address.String()
+	}
+	return state
+}
+
+// memberFromProto decodes a gossiped member
+func memberFromProto(state *proto.MemberState) *Member {
+	member := &Member{
+		ID:           hyperbus.NodeID(state.NodeId),
+		LastSeen:     time.Unix(0, state.LastSeenUnixNano),
+		Status:       MemberStatus(state.Status),
+		Capabilities: state.Caps,
+		Incarnation:  state.Incarnation,
+	}
+	if addr, err := net.ResolveTCPAddr("tcp", state.Address); err == nil && state.Address != "" {
+		member.Address = addr
+	}
+	return member
+}
+
+// HandleMessage answers gossip from other members with our own state
+func (s *SWIM) HandleMessage(ctx context.Context, conn hyperbus.Connection, stream hyperbus.Stream, data []byte) error {
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+	if header.Type != hyperbus.MsgClusterState {
+		return fmt.Errorf("unexpected message type %d", header.Type)
+	}
+
+	var msg proto.ClusterState
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &msg); err != nil {
+		return err
+	}
+	s.HandleGossipMessage(ctx, &msg)
+	if msg.Reply {
+		s.replied(hyperbus.NodeID(msg.SenderId))
+		return nil
+	}
+
+	// Push-pull: the sender learns what we know in return
+	reply := s.localState()
+	reply.Reply = true
+	out, err := hyperbus.EncodeMessage(hyperbus.MsgClusterState, reply)
+	if err != nil {
+		return fmt.Errorf("failed to encode gossip reply: %w", err)
+	}
+	return s.bus.SendControlMessage(ctx, conn.NodeID(), out)
+}
+
+// PushPull sends our state to a member and waits until its reply has been
+// merged, so the local view includes everything the member knew
+func (s *SWIM) PushPull(ctx context.Context, target hyperbus.NodeID) error {
+	s.repliesMu.Lock()
+	reply, waiting := s.replies[target]
+	if !waiting {
+		reply = make(chan struct{})
+		s.replies[target] = reply
+	}
+	s.repliesMu.Unlock()
+
+	if err := s.sendState(ctx, target); err != nil {
+		return fmt.Errorf("failed to gossip with %s: %w", target, err)
+	}
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("no gossip reply from %s: %w", target, ctx.Err())
+	}
+}
+
+// replied wakes the PushPull calls waiting on a member's reply
+func (s *SWIM) replied(nodeID hyperbus.NodeID) {
+	s.repliesMu.Lock()
+	defer s.repliesMu.Unlock()
+
+	if reply, waiting := s.replies[nodeID]; waiting {
+		delete(s.replies, nodeID)
+		close(reply)
+	}
+}
+
+// Dialer connects to the node listening at an address and returns its ID
+type Dialer func(ctx context.Context, addr string) (hyperbus.NodeID, error)
+
+// bootstrapTimeout bounds dialing and exchanging state with one bootstrap node
+const bootstrapTimeout = 5 * time.Second
+
+// Bootstrap dials the nodes at addrs and pushes and pulls state with each
+// one reached, joining the cluster they belong to. It fails only if none of
+// them could be reached.
+func (s *SWIM) Bootstrap(ctx context.Context, dial Dialer, addrs []string) error {
+	var lastErr error
+	joined := 0
+	for _, addr := range addrs {
+		if err := s.bootstrapFrom(ctx, dial, addr); err != nil {
+			s.logger.Warn("failed to join through bootstrap node", "address", addr, "error", err)
+			lastErr = err
+			continue
+		}
+		joined++
+	}
+
+	if joined == 0 && lastErr != nil {
+		return fmt.Errorf("no bootstrap node reachable: %w", lastErr)
+	}
+	return nil
+}
+
+// bootstrapFrom joins the cluster through the node at addr
+func (s *SWIM) bootstrapFrom(ctx context.Context, dial Dialer, addr string) error {
+	ctx, cancel := context.WithTimeout(ctx, bootstrapTimeout)
+	defer cancel()
+
+	nodeID, err := dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	return s.PushPull(ctx, nodeID)
+}
+
 // HandleGossipMessage handles an incoming gossip message
 func (s *SWIM) HandleGossipMessage(ctx context.Context, msg *proto.ClusterState) {
-	// Update our membership based on the received information
-	// This is a simplified implementation - in reality, we would:
-	// 1. Check for new members
-	// 2. Update existing member statuses
-	// 3. Handle suspected members
-	// 4. Disseminate updated information
-
-	s.logger.Debug("handling gossip message", "member_count", len(msg.ShardAssignments))
+	s.logger.Debug("handling gossip message", "sender_id", msg.SenderId, "member_count", len(msg.Members))
+
+	var events []MemberEvent
+	s.mu.Lock()
+	for _, state := range msg.Members {
+		events = append(events, s.mergeMemberLocked(memberFromProto(state))...)
+	}
+	s.mu.Unlock()
+	s.notify(events)
+
+	for _, participant := range s.participants {
+		participant.ObserveState(msg)
+	}
+
+	// Estimate the sender's clock skew from the send timestamp
+	if msg.SenderId != "" && msg.SentAtUnixNano != 0 {
+		s.ObserveClock(hyperbus.NodeID(msg.SenderId), time.Unix(0, msg.SentAtUnixNano), time.Now())
+	}
+}
+
+// mergeMemberLocked adds an unknown member, or adopts the status of a known
+// one if the gossiped view is more recent than ours. A higher incarnation
+// always wins; within an incarnation the later sighting does. The caller
+// must hold s.mu.
+func (s *SWIM) mergeMemberLocked(gossiped *Member) []MemberEvent {
+	if gossiped.ID == "" {
+		return nil
+	}
+	if gossiped.ID == s.localMember.ID {
+		s.refuteLocked(gossiped)
+		return nil
+	}
+
+	member, exists := s.members[gossiped.ID]
+	if !exists {
+		return s.joinLocked(gossiped)
+	}
+	switch {
+	case gossiped.Incarnation > member.Incarnation:
+	case gossiped.Incarnation < member.Incarnation:
+		return nil
+	case !gossiped.LastSeen.After(member.LastSeen):
+		return nil
+	}
+
+	if gossiped.Address != nil {
+		member.Address = gossiped.Address
+	}
+	if gossiped.Capabilities != nil {
+		member.Capabilities = gossiped.Capabilities
+	}
+	events := s.setStatusLocked(member, gossiped.Status)
+	member.LastSeen = gossiped.LastSeen
+	member.Incarnation = gossiped.Incarnation
+	return events
+}
+
+// refuteLocked raises the local incarnation past a report suspecting us, so
+// the Alive status we gossip next overrides it. The caller must hold s.mu.
+func (s *SWIM) refuteLocked(gossiped *Member) {
+	if gossiped.Status == Alive || gossiped.Incarnation < s.localMember.Incarnation {
+		return
+	}
+
+	s.localMember.Incarnation = gossiped.Incarnation + 1
+	s.logger.Info("refuting suspicion",
+		"reported_status", gossiped.Status,
+		"incarnation", s.localMember.Incarnation)
 }
@@ -2,12 +2,16 @@ package membership
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/melihxz/holocompute/internal/hyperbus"
 	"github.com/melihxz/holocompute/internal/log"
 	"github.com/melihxz/holocompute/pkg/proto"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 // Member represents a cluster member
@@ -17,6 +21,10 @@ type Member struct {
 	LastSeen     time.Time
 	Status       MemberStatus
 	Capabilities *proto.NodeCapabilities
+
+	// Incarnation is raised by the member itself to refute suspicion;
+	// reports with a higher incarnation supersede older ones
+	Incarnation uint64
 }
 
 // MemberStatus represents the status of a member
@@ -31,11 +39,30 @@ const (
 	Dead
 )
 
+// String returns the name of the status
+func (s MemberStatus) String() string {
+	switch s {
+	case Alive:
+		return "alive"
+	case Suspect:
+		return "suspect"
+	case Dead:
+		return "dead"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
 // Membership manages cluster membership using SWIM protocol
 type Membership struct {
 	localMember   *Member
 	members       map[hyperbus.NodeID]*Member
 	eventHandlers []EventHandler
+	subscribers   map[<-chan MemberEvent]chan MemberEvent
+	mu            sync.RWMutex // guards members, their fields and the local member
+	skews         map[hyperbus.NodeID]time.Duration
+	maxSkew       time.Duration
+	skewMu        sync.RWMutex
 	logger        *log.Logger
 }
 
@@ -51,88 +78,272 @@ type EventHandler interface {
 	OnMemberStatusChange(member *Member, oldStatus, newStatus MemberStatus)
 }
 
-// NewMembership creates a new membership manager
+// NewMembership creates a new membership manager. It keeps a copy of
+// localMember, so later changes to it have no effect.
 func NewMembership(localMember *Member, logger *log.Logger) *Membership {
+	local := localMember.clone()
 	return &Membership{
-		localMember: localMember,
+		localMember: &local,
 		members:     make(map[hyperbus.NodeID]*Member),
+		subscribers: make(map[<-chan MemberEvent]chan MemberEvent),
+		skews:       make(map[hyperbus.NodeID]time.Duration),
+		maxSkew:     DefaultMaxClockSkew,
 		logger:      logger,
 	}
 }
 
-// LocalMember returns the local member
+// LocalMember returns a copy of the local member
 func (m *Membership) LocalMember() *Member {
-	return m.localMember
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	local := m.localMember.clone()
+	return &local
 }
 
-// Members returns all known members
+// Members returns copies of all known members keyed by ID
 func (m *Membership) Members() map[hyperbus.NodeID]*Member {
-	return m.members
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	members := make(map[hyperbus.NodeID]*Member, len(m.members))
+	for id, member := range m.members {
+		copied := member.clone()
+		members[id] = &copied
+	}
+	return members
+}
+
+// Snapshot returns deep copies of all known members sorted by ID, safe to
+// read while membership keeps changing
+func (m *Membership) Snapshot() []Member {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	members := make([]Member, 0, len(m.members))
+	for _, member := range m.members {
+		members = append(members, member.clone())
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members
+}
+
+// clone returns a deep copy of the member
+func (member *Member) clone() Member {
+	copied := *member
+	if member.Capabilities != nil {
+		copied.Capabilities = protobuf.Clone(member.Capabilities).(*proto.NodeCapabilities)
+	}
+	return copied
+}
+
+// IsAlive returns true if the node is the local member or a known alive member
+func (m *Membership) IsAlive(nodeID hyperbus.NodeID) bool {
+	if m.localMember != nil && m.localMember.ID == nodeID {
+		return true
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	member, exists := m.members[nodeID]
+	return exists && member.Status == Alive
+}
+
+// LookupPeer returns how to reach a member, failing for unknown or dead members
+func (m *Membership) LookupPeer(nodeID hyperbus.NodeID) (hyperbus.NodeInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	member, exists := m.members[nodeID]
+	if !exists {
+		return hyperbus.NodeInfo{}, fmt.Errorf("unknown member %s", nodeID)
+	}
+	if member.Status == Dead {
+		return hyperbus.NodeInfo{}, fmt.Errorf("%w: %s", hyperbus.ErrNodeDead, nodeID)
+	}
+	return hyperbus.NodeInfo{
+		ID:           member.ID,
+		Address:      member.Address,
+		Capabilities: member.Capabilities,
+	}, nil
 }
 
 // AddEventHandler adds an event handler
 func (m *Membership) AddEventHandler(handler EventHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.eventHandlers = append(m.eventHandlers, handler)
 }
 
-// Join adds a member to the cluster
+// MemberEventType identifies a membership change
+type MemberEventType int
+
+const (
+	// MemberJoined means a member joined the cluster
+	MemberJoined MemberEventType = iota
+	// MemberLeft means a member left the cluster
+	MemberLeft
+	// MemberStatusChanged means a member's status changed
+	MemberStatusChanged
+)
+
+// MemberEvent is a membership change. OldStatus and NewStatus are only set
+// for MemberStatusChanged.
+type MemberEvent struct {
+	Type      MemberEventType
+	Member    Member
+	OldStatus MemberStatus
+	NewStatus MemberStatus
+}
+
+// subscriberBuffer is the number of events queued for a slow subscriber
+// before further events are dropped
+const subscriberBuffer = 64
+
+// Subscribe returns a channel receiving every later membership change
+func (m *Membership) Subscribe() <-chan MemberEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch := make(chan MemberEvent, subscriberBuffer)
+	m.subscribers[ch] = ch
+	return ch
+}
+
+// Unsubscribe stops delivery to a channel returned by Subscribe and closes it
+func (m *Membership) Unsubscribe(sub <-chan MemberEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ch, exists := m.subscribers[sub]; exists {
+		delete(m.subscribers, sub)
+		close(ch)
+	}
+}
+
+// notify reports events to the event handlers and subscribers. Handlers run
+// after the lock is released so they may call back into membership; the
+// caller must not hold m.mu.
+func (m *Membership) notify(events []MemberEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	m.mu.RLock()
+	handlers := m.eventHandlers
+	for _, event := range events {
+		for _, ch := range m.subscribers {
+			select {
+			case ch <- event:
+			default:
+				m.logger.Warn("dropping membership event, subscriber queue full", "member_id", event.Member.ID)
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, event := range events {
+		member := event.Member
+		for _, handler := range handlers {
+			switch event.Type {
+			case MemberJoined:
+				handler.OnMemberJoin(&member)
+			case MemberLeft:
+				handler.OnMemberLeave(&member)
+			case MemberStatusChanged:
+				handler.OnMemberStatusChange(&member, event.OldStatus, event.NewStatus)
+			}
+		}
+	}
+}
+
+// Join adds a copy of member to the cluster
 func (m *Membership) Join(ctx context.Context, member *Member) {
+	m.mu.Lock()
+	events := m.joinLocked(member)
+	m.mu.Unlock()
+
+	m.notify(events)
+}
+
+// joinLocked adds or replaces a member with a copy of member. The caller
+// must hold m.mu.
+func (m *Membership) joinLocked(member *Member) []MemberEvent {
 	m.logger.Info("member joining", "member_id", member.ID)
 
+	copied := member.clone()
 	oldMember, exists := m.members[member.ID]
-	m.members[member.ID] = member
+	m.members[member.ID] = &copied
 
 	if !exists {
 		// New member
-		for _, handler := range m.eventHandlers {
-			handler.OnMemberJoin(member)
-		}
-	} else {
+		return []MemberEvent{{Type: MemberJoined, Member: member.clone()}}
+	}
+	if oldMember.Status != member.Status {
 		// Existing member status update
-		if oldMember.Status != member.Status {
-			for _, handler := range m.eventHandlers {
-				handler.OnMemberStatusChange(member, oldMember.Status, member.Status)
-			}
-		}
+		return []MemberEvent{{Type: MemberStatusChanged, Member: member.clone(), OldStatus: oldMember.Status, NewStatus: member.Status}}
 	}
+	return nil
 }
 
 // Leave removes a member from the cluster
 func (m *Membership) Leave(ctx context.Context, memberID hyperbus.NodeID) {
+	m.mu.Lock()
 	member, exists := m.members[memberID]
 	if !exists {
+		m.mu.Unlock()
 		return
 	}
 
 	m.logger.Info("member leaving", "member_id", memberID)
 	delete(m.members, memberID)
+	m.mu.Unlock()
 
-	for _, handler := range m.eventHandlers {
-		handler.OnMemberLeave(member)
-	}
+	m.notify([]MemberEvent{{Type: MemberLeft, Member: member.clone()}})
 }
 
 // UpdateMemberStatus updates the status of a member
 func (m *Membership) UpdateMemberStatus(memberID hyperbus.NodeID, status MemberStatus) {
-	member, exists := m.members[memberID]
-	if !exists {
-		return
+	m.mu.Lock()
+	var events []MemberEvent
+	if member, exists := m.members[memberID]; exists {
+		events = m.setStatusLocked(member, status)
 	}
+	m.mu.Unlock()
 
+	m.notify(events)
+}
+
+// OnPeerLost implements hyperbus.PeerLossObserver. A live member whose
+// connection was dropped becomes a suspect for SWIM to confirm or clear.
+func (m *Membership) OnPeerLost(nodeID hyperbus.NodeID, err error) {
+	m.mu.Lock()
+	var events []MemberEvent
+	if member, exists := m.members[nodeID]; exists && member.Status == Alive {
+		m.logger.Info("lost connection to member", "member_id", nodeID, "error", err)
+		events = m.setStatusLocked(member, Suspect)
+	}
+	m.mu.Unlock()
+
+	m.notify(events)
+}
+
+// setStatusLocked changes a member's status, returning the change to
+// report. The caller must hold m.mu.
+func (m *Membership) setStatusLocked(member *Member, status MemberStatus) []MemberEvent {
 	oldStatus := member.Status
 	if oldStatus == status {
-		return
+		return nil
 	}
 
 	member.Status = status
 	member.LastSeen = time.Now()
 
 	m.logger.Debug("member status updated",
-		"member_id", memberID,
+		"member_id", member.ID,
 		"old_status", oldStatus,
 		"new_status", status)
 
-	for _, handler := range m.eventHandlers {
-		handler.OnMemberStatusChange(member, oldStatus, status)
-	}
+	return []MemberEvent{{Type: MemberStatusChanged, Member: member.clone(), OldStatus: oldStatus, NewStatus: status}}
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/melihxz/holocompute/internal/hyperbus"
 	"github.com/melihxz/holocompute/internal/log"
 	"github.com/melihxz/holocompute/pkg/proto"
 	"github.com/stretchr/testify/assert"
@@ -73,6 +74,25 @@ func TestMembership_Join(t *testing.T) {
 	mockHandler.AssertExpectations(t)
 }
 
+func TestMembership_StoresAndReturnsCopies(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	local := &Member{ID: "local-node", Status: Alive, Capabilities: &proto.NodeCapabilities{CpuCores: 4}}
+	membership := NewMembership(local, logger)
+
+	remote := &Member{ID: "remote-node", Status: Alive}
+	membership.Join(context.TODO(), remote)
+
+	// Changing the caller's members or a returned copy doesn't reach membership
+	remote.Status = Dead
+	local.Incarnation = 7
+	membership.LocalMember().Capabilities.CpuCores = 1
+
+	assert.Equal(t, Alive, membership.Members()["remote-node"].Status)
+	assert.Equal(t, uint64(0), membership.LocalMember().Incarnation)
+	assert.Equal(t, int32(4), membership.LocalMember().Capabilities.CpuCores)
+}
+
 func TestMembership_Leave(t *testing.T) {
 	logger := log.New(slog.LevelDebug)
 
@@ -163,3 +183,112 @@ func TestMembership_UpdateMemberStatus(t *testing.T) {
 	// Verify the event handler was called
 	mockHandler.AssertExpectations(t)
 }
+
+func TestMembership_LookupPeer(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	m := NewMembership(&Member{ID: "local-node"}, logger)
+
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 8443}
+	m.Join(context.TODO(), &Member{ID: "alive-node", Address: addr, Status: Alive})
+	m.Join(context.TODO(), &Member{ID: "suspect-node", Status: Suspect})
+	m.Join(context.TODO(), &Member{ID: "dead-node", Status: Dead})
+
+	node, err := m.LookupPeer("alive-node")
+	assert.NoError(t, err)
+	assert.Equal(t, hyperbus.NodeID("alive-node"), node.ID)
+	assert.Equal(t, addr, node.Address)
+
+	// Suspect members may still be reachable
+	_, err = m.LookupPeer("suspect-node")
+	assert.NoError(t, err)
+
+	_, err = m.LookupPeer("dead-node")
+	assert.ErrorIs(t, err, hyperbus.ErrNodeDead)
+
+	_, err = m.LookupPeer("missing-node")
+	assert.Error(t, err)
+}
+
+func TestMembership_Snapshot(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	membership := NewMembership(&Member{ID: "local-node", Status: Alive}, logger)
+
+	for _, id := range []hyperbus.NodeID{"node-c", "node-a", "node-b"} {
+		membership.Join(context.TODO(), &Member{
+			ID:           id,
+			Status:       Alive,
+			Capabilities: &proto.NodeCapabilities{CpuCores: 4},
+		})
+	}
+
+	snapshot := membership.Snapshot()
+	assert.Len(t, snapshot, 3)
+	assert.Equal(t, hyperbus.NodeID("node-a"), snapshot[0].ID)
+	assert.Equal(t, hyperbus.NodeID("node-c"), snapshot[2].ID)
+
+	// The snapshot is a deep copy
+	snapshot[0].Status = Dead
+	snapshot[0].Capabilities.CpuCores = 1
+	member := membership.Members()["node-a"]
+	assert.Equal(t, Alive, member.Status)
+	assert.Equal(t, int32(4), member.Capabilities.CpuCores)
+
+	// Later changes don't show through it either
+	membership.UpdateMemberStatus("node-b", Suspect)
+	assert.Equal(t, Alive, snapshot[1].Status)
+}
+
+func TestMembership_Subscribe(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	membership := NewMembership(&Member{ID: "local-node", Status: Alive}, logger)
+
+	first := membership.Subscribe()
+	second := membership.Subscribe()
+
+	membership.Join(context.TODO(), &Member{ID: "remote-node", Status: Alive})
+	for _, sub := range []<-chan MemberEvent{first, second} {
+		select {
+		case event := <-sub:
+			assert.Equal(t, MemberJoined, event.Type)
+			assert.Equal(t, hyperbus.NodeID("remote-node"), event.Member.ID)
+		case <-time.After(time.Second):
+			t.Fatal("subscriber never received the join")
+		}
+	}
+
+	// An unsubscribed channel is closed and gets nothing more
+	membership.Unsubscribe(first)
+	_, open := <-first
+	assert.False(t, open)
+	membership.Unsubscribe(first)
+
+	membership.UpdateMemberStatus("remote-node", Suspect)
+	membership.Leave(context.TODO(), "remote-node")
+
+	event := <-second
+	assert.Equal(t, MemberStatusChanged, event.Type)
+	assert.Equal(t, Alive, event.OldStatus)
+	assert.Equal(t, Suspect, event.NewStatus)
+	event = <-second
+	assert.Equal(t, MemberLeft, event.Type)
+	assert.Equal(t, hyperbus.NodeID("remote-node"), event.Member.ID)
+}
+
+func TestMembership_OnPeerLost(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	membership := NewMembership(&Member{ID: "local-node", Status: Alive}, logger)
+	membership.Join(context.TODO(), &Member{ID: "remote-node", Status: Alive})
+	membership.Join(context.TODO(), &Member{ID: "dead-node", Status: Dead})
+
+	events := membership.Subscribe()
+	membership.OnPeerLost("remote-node", hyperbus.ErrIdleTimeout)
+	membership.OnPeerLost("dead-node", hyperbus.ErrIdleTimeout)
+
+	// A lost live member becomes a suspect; a dead one stays dead
+	event := <-events
+	assert.Equal(t, MemberStatusChanged, event.Type)
+	assert.Equal(t, hyperbus.NodeID("remote-node"), event.Member.ID)
+	assert.Equal(t, Suspect, event.NewStatus)
+	assert.Equal(t, Dead, membership.Members()["dead-node"].Status)
+	assert.Empty(t, events)
+}
@@ -0,0 +1,283 @@
+package membership
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/pkg/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// ackBuffer is the number of acks that can be queued between suspect checks
+const ackBuffer = 64
+
+// Prober reaches members directly and through other members
+type Prober interface {
+	// Ping returns nil if target answered a direct probe
+	Ping(ctx context.Context, target hyperbus.NodeID) error
+
+	// PingReq returns nil if via reached target
+	PingReq(ctx context.Context, via, target hyperbus.NodeID) error
+}
+
+// BusProber probes members with ping and ping-req messages over the bus. It
+// also answers the probes other members send, and must be registered on the
+// mux for MsgProbePing and MsgProbePingReq.
+type BusProber struct {
+	bus     *hyperbus.Bus
+	timeout time.Duration // bounds the probes sent on another member's behalf
+}
+
+// NewBusProber creates a prober sending probes over bus
+func NewBusProber(bus *hyperbus.Bus, timeout time.Duration) *BusProber {
+	return &BusProber{bus: bus, timeout: timeout}
+}
+
+// Ping implements Prober
+func (p *BusProber) Ping(ctx context.Context, target hyperbus.NodeID) error {
+	return p.call(ctx, target, hyperbus.MsgProbePing, &proto.ProbePing{})
+}
+
+// PingReq implements Prober
+func (p *BusProber) PingReq(ctx context.Context, via, target hyperbus.NodeID) error {
+	err := p.call(ctx, via, hyperbus.MsgProbePingReq, &proto.ProbePingReq{TargetId: string(target)})
+	if err != nil {
+		return fmt.Errorf("probe via %s: %w", via, err)
+	}
+	return nil
+}
+
+// call sends a probe and waits for an ack reporting its target was reached
+func (p *BusProber) call(ctx context.Context, nodeID hyperbus.NodeID, msgType hyperbus.MessageType, pb protobuf.Message) error {
+	data, err := p.bus.Call(ctx, nodeID, msgType, pb)
+	if err != nil {
+		return err
+	}
+
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+	if header.Type != hyperbus.MsgProbeAck {
+		return fmt.Errorf("unexpected message type %d in reply to probe", header.Type)
+	}
+
+	var ack proto.ProbeAck
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &ack); err != nil {
+		return err
+	}
+	if !ack.Reached {
+		return fmt.Errorf("%s got no answer from its target", nodeID)
+	}
+	return nil
+}
+
+// HandleMessage acks a ping, or probes the target of a ping-req and acks
+// whether it answered
+func (p *BusProber) HandleMessage(ctx context.Context, conn hyperbus.Connection, stream hyperbus.Stream, data []byte) error {
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	ack := &proto.ProbeAck{Reached: true}
+	switch header.Type {
+	case hyperbus.MsgProbePing:
+	case hyperbus.MsgProbePingReq:
+		var req proto.ProbePingReq
+		if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &req); err != nil {
+			return err
+		}
+		if p.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, p.timeout)
+			defer cancel()
+		}
+		ack.Reached = p.Ping(ctx, hyperbus.NodeID(req.TargetId)) == nil
+	default:
+		return fmt.Errorf("unexpected message type %d", header.Type)
+	}
+
+	msg, err := hyperbus.EncodeReply(data, hyperbus.MsgProbeAck, ack)
+	if err != nil {
+		return fmt.Errorf("failed to encode probe ack: %w", err)
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// SetProber sets the prober used to detect failed members
+func (s *SWIM) SetProber(prober Prober) {
+	s.prober = prober
+}
+
+// probeLoop periodically probes a random member
+func (s *SWIM) probeLoop(ctx context.Context) {
+	if s.probeInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.probe(ctx)
+		}
+	}
+}
+
+// probe pings a random alive member. When the direct ping fails, up to
+// indirectK other members are asked to ping it, and the member is only
+// suspected if none of them reach it either.
+func (s *SWIM) probe(ctx context.Context) {
+	if s.prober == nil {
+		return
+	}
+
+	s.mu.RLock()
+	var targets []hyperbus.NodeID
+	for _, member := range s.members {
+		if member.ID != s.localMember.ID && member.Status == Alive {
+			targets = append(targets, member.ID)
+		}
+	}
+	s.mu.RUnlock()
+	if len(targets) == 0 {
+		return
+	}
+	target := targets[rand.Intn(len(targets))]
+
+	pingCtx, cancel := context.WithTimeout(ctx, s.probeTimeout)
+	err := s.prober.Ping(pingCtx, target)
+	cancel()
+	if err == nil {
+		s.touch(target)
+		return
+	}
+
+	s.logger.Debug("direct probe failed", "member_id", target, "error", err)
+
+	pingCtx, cancel = context.WithTimeout(ctx, s.probeTimeout)
+	reached := s.pingReq(pingCtx, target)
+	cancel()
+	if reached {
+		s.touch(target)
+		return
+	}
+
+	s.logger.Info("member failed direct and indirect probes", "member_id", target)
+	s.UpdateMemberStatus(target, Suspect)
+}
+
+// touch records that a member was just heard from
+func (s *SWIM) touch(nodeID hyperbus.NodeID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if member, exists := s.members[nodeID]; exists {
+		member.LastSeen = time.Now()
+	}
+}
+
+// pingReq asks up to indirectK random alive members to ping target and
+// reports whether any of them reached it
+func (s *SWIM) pingReq(ctx context.Context, target hyperbus.NodeID) bool {
+	s.mu.RLock()
+	var helpers []hyperbus.NodeID
+	for _, member := range s.members {
+		if member.ID != s.localMember.ID && member.ID != target && member.Status == Alive {
+			helpers = append(helpers, member.ID)
+		}
+	}
+	s.mu.RUnlock()
+	rand.Shuffle(len(helpers), func(i, j int) {
+		helpers[i], helpers[j] = helpers[j], helpers[i]
+	})
+	if len(helpers) > s.indirectK {
+		helpers = helpers[:s.indirectK]
+	}
+
+	s.logger.Debug("probing member indirectly", "member_id", target, "helpers", len(helpers))
+
+	results := make(chan bool, len(helpers))
+	for _, via := range helpers {
+		go func(via hyperbus.NodeID) {
+			err := s.prober.PingReq(ctx, via, target)
+			if err != nil {
+				s.logger.Debug("indirect probe failed", "member_id", target, "via", via, "error", err)
+			}
+			results <- err == nil
+		}(via)
+	}
+
+	reached := false
+	for range helpers {
+		if <-results {
+			reached = true
+		}
+	}
+	return reached
+}
+
+// Ack records that a member answered a probe. A suspect in its grace
+// phase returns to alive on the next suspect check.
+func (s *SWIM) Ack(nodeID hyperbus.NodeID) {
+	select {
+	case s.acks <- nodeID:
+	default:
+		s.logger.Warn("dropping probe ack, queue full", "member_id", nodeID)
+	}
+}
+
+// processAcks clears the suspicion of every member that acked since the last check
+func (s *SWIM) processAcks() {
+	for {
+		select {
+		case nodeID := <-s.acks:
+			s.mu.Lock()
+			member, exists := s.members[nodeID]
+			if !exists || member.Status != Suspect {
+				s.mu.Unlock()
+				continue
+			}
+			delete(s.confirming, nodeID)
+			s.logger.Info("suspect answered indirect probe", "member_id", nodeID)
+			events := s.setStatusLocked(member, Alive)
+			s.mu.Unlock()
+			s.notify(events)
+		default:
+			return
+		}
+	}
+}
+
+// probeIndirect asks other members to probe a suspect in the background,
+// acking it if any of them reach it
+func (s *SWIM) probeIndirect(target hyperbus.NodeID) {
+	if s.prober == nil {
+		return
+	}
+
+	parent := s.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx, cancel := context.WithTimeout(parent, s.suspectGrace)
+		defer cancel()
+
+		if s.pingReq(ctx, target) {
+			s.Ack(target)
+		}
+	}()
+}
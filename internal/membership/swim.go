@@ -2,7 +2,10 @@ package membership
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/melihxz/holocompute/internal/hyperbus"
@@ -15,14 +18,56 @@ type SWIM struct {
 	bus           *hyperbus.Bus
 	gossipPeriod  time.Duration
 	suspectPeriod time.Duration
+	suspectGrace  time.Duration
+	probeInterval time.Duration
+	probeTimeout  time.Duration
+	indirectK     int
+	prober        Prober
+	participants  []GossipParticipant
+	confirming    map[hyperbus.NodeID]time.Time // suspects in their grace phase
+	acks          chan hyperbus.NodeID
+	replies       map[hyperbus.NodeID]chan struct{} // closed once the member's gossip reply is merged
+	repliesMu     sync.Mutex
 	logger        *log.Logger
+	ctx           context.Context
 	cancel        context.CancelFunc
+	done          chan struct{} // closed once the loops of the last Start exit
+	wg            sync.WaitGroup
 }
 
 // SWIMConfig contains configuration for SWIM
 type SWIMConfig struct {
 	GossipPeriod  time.Duration
 	SuspectPeriod time.Duration
+
+	// SuspectGrace is how long a timed-out suspect gets to answer indirect
+	// probes before it is declared dead. Zero declares it dead immediately.
+	SuspectGrace time.Duration
+
+	// Probe configures the failure detector's direct and indirect probes
+	Probe ProbeConfig
+}
+
+// ProbeConfig configures how members are probed for failure
+type ProbeConfig struct {
+	// Interval is the time between probes of a random member
+	Interval time.Duration
+
+	// Timeout bounds each direct probe and each round of indirect probes
+	Timeout time.Duration
+
+	// IndirectFanout is the number of members asked to probe a target the
+	// local node failed to reach directly
+	IndirectFanout int
+}
+
+// DefaultProbeConfig returns the default probe configuration
+func DefaultProbeConfig() ProbeConfig {
+	return ProbeConfig{
+		Interval:       time.Second,
+		Timeout:        500 * time.Millisecond,
+		IndirectFanout: 3,
+	}
 }
 
 // DefaultSWIMConfig returns the default SWIM configuration
@@ -30,6 +75,8 @@ func DefaultSWIMConfig() SWIMConfig {
 	return SWIMConfig{
 		GossipPeriod:  time.Second,
 		SuspectPeriod: 5 * time.Second,
+		SuspectGrace:  2 * time.Second,
+		Probe:         DefaultProbeConfig(),
 	}
 }
 
@@ -40,26 +87,66 @@ func NewSWIM(membership *Membership, bus *hyperbus.Bus, config SWIMConfig, logge
 		bus:           bus,
 		gossipPeriod:  config.GossipPeriod,
 		suspectPeriod: config.SuspectPeriod,
+		suspectGrace:  config.SuspectGrace,
+		probeInterval: config.Probe.Interval,
+		probeTimeout:  config.Probe.Timeout,
+		indirectK:     config.Probe.IndirectFanout,
+		confirming:    make(map[hyperbus.NodeID]time.Time),
+		acks:          make(chan hyperbus.NodeID, ackBuffer),
+		replies:       make(map[hyperbus.NodeID]chan struct{}),
 		logger:        logger,
 	}
 }
 
 // Start starts the SWIM protocol
 func (s *SWIM) Start(ctx context.Context) {
-	ctx, s.cancel = context.WithCancel(ctx)
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	ctx = s.ctx
+
+	done := make(chan struct{})
+	s.done = done
+
+	var loops sync.WaitGroup
+	loops.Add(3)
 
 	// Start gossip loop
-	go s.gossipLoop(ctx)
+	go func() {
+		defer loops.Done()
+		s.gossipLoop(ctx)
+	}()
 
 	// Start suspect timeout loop
-	go s.suspectLoop(ctx)
+	go func() {
+		defer loops.Done()
+		s.suspectLoop(ctx)
+	}()
+
+	// Start failure detection loop
+	go func() {
+		defer loops.Done()
+		s.probeLoop(ctx)
+	}()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		loops.Wait()
+		close(done)
+	}()
+}
+
+// Done returns a channel closed once the loops of the last Start have
+// exited, whether through Stop or the cancellation of its context
+func (s *SWIM) Done() <-chan struct{} {
+	return s.done
 }
 
-// Stop stops the SWIM protocol
+// Stop stops the SWIM protocol and waits for its loops to exit
 func (s *SWIM) Stop() {
 	if s.cancel != nil {
 		s.cancel()
 	}
+	s.wg.Wait()
 }
 
 // gossipLoop periodically gossips with random members
@@ -80,12 +167,14 @@ func (s *SWIM) gossipLoop(ctx context.Context) {
 // gossip exchanges membership information with a random member
 func (s *SWIM) gossip(ctx context.Context) {
 	// Get all alive members except ourselves
-	members := make([]*Member, 0, len(s.members))
+	s.mu.RLock()
+	members := make([]hyperbus.NodeID, 0, len(s.members))
 	for _, member := range s.members {
 		if member.ID != s.localMember.ID && member.Status == Alive {
-			members = append(members, member)
+			members = append(members, member.ID)
 		}
 	}
+	s.mu.RUnlock()
 
 	if len(members) == 0 {
 		return
@@ -94,12 +183,25 @@ func (s *SWIM) gossip(ctx context.Context) {
 	// Select a random member to gossip with
 	target := members[rand.Intn(len(members))]
 
-	// Create a gossip message with our membership information
-	// Send it to the target member
-	// Wait for a response
-	// Update our membership based on the response
+	s.logger.Debug("gossiping with member", "target_id", target)
+	if err := s.sendState(ctx, target); err != nil {
+		s.logger.Debug("failed to gossip with member", "target_id", target, "error", err)
+	}
+}
+
+// sendState sends our membership information to a member. The target
+// merges it and replies with its own state, which comes back through
+// HandleMessage.
+func (s *SWIM) sendState(ctx context.Context, target hyperbus.NodeID) error {
+	if s.bus == nil {
+		return errors.New("no bus to gossip on")
+	}
 
-	s.logger.Debug("gossiping with member", "target_id", target.ID)
+	msg, err := hyperbus.EncodeMessage(hyperbus.MsgClusterState, s.localState())
+	if err != nil {
+		return fmt.Errorf("failed to encode gossip: %w", err)
+	}
+	return s.bus.SendControlMessage(ctx, target, msg)
 }
 
 // suspectLoop handles suspect timeouts
@@ -119,13 +221,46 @@ func (s *SWIM) suspectLoop(ctx context.Context) {
 
 // checkSuspects checks if any suspects have timed out
 func (s *SWIM) checkSuspects() {
+	s.processAcks()
+
 	now := time.Now()
 
+	var events []MemberEvent
+	var confirm []hyperbus.NodeID
+	s.mu.Lock()
 	for _, member := range s.members {
-		if member.Status == Suspect && now.Sub(member.LastSeen) > s.suspectPeriod {
+		if member.Status != Suspect {
+			delete(s.confirming, member.ID)
+			continue
+		}
+		if now.Sub(member.LastSeen) <= s.suspectPeriod {
+			continue
+		}
+
+		if s.suspectGrace <= 0 {
 			// Suspect timeout, mark as dead
-			s.UpdateMemberStatus(member.ID, Dead)
+			events = append(events, s.setStatusLocked(member, Dead)...)
+			continue
+		}
+
+		// Get a second opinion before declaring the suspect dead
+		started, confirming := s.confirming[member.ID]
+		if !confirming {
+			s.confirming[member.ID] = now
+			confirm = append(confirm, member.ID)
+			continue
 		}
+
+		if now.Sub(started) > s.suspectGrace {
+			delete(s.confirming, member.ID)
+			events = append(events, s.setStatusLocked(member, Dead)...)
+		}
+	}
+	s.mu.Unlock()
+
+	s.notify(events)
+	for _, nodeID := range confirm {
+		s.probeIndirect(nodeID)
 	}
 }
 
@@ -2,11 +2,13 @@ package membership
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/melihxz/holocompute/internal/hyperbus"
 	"github.com/melihxz/holocompute/internal/log"
 	"github.com/melihxz/holocompute/pkg/proto"
 	"github.com/stretchr/testify/assert"
@@ -49,6 +51,196 @@ func TestSWIM_Gossip(t *testing.T) {
 	swim.gossip(context.Background())
 }
 
+// joinNotifier reports the members that join on a channel
+type joinNotifier chan *Member
+
+func (j joinNotifier) OnMemberJoin(member *Member)  { j <- member }
+func (j joinNotifier) OnMemberLeave(member *Member) {}
+func (j joinNotifier) OnMemberStatusChange(member *Member, oldStatus, newStatus MemberStatus) {
+}
+
+// newGossipNode creates a SWIM node whose bus delivers gossip to it
+func newGossipNode(id hyperbus.NodeID, logger *log.Logger) (*SWIM, *hyperbus.Bus) {
+	mux := hyperbus.NewMux()
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: id}, mux, logger)
+	membership := NewMembership(&Member{ID: id, LastSeen: time.Now(), Status: Alive}, logger)
+	swim := NewSWIM(membership, bus, DefaultSWIMConfig(), logger)
+	mux.Handle(hyperbus.MsgClusterState, swim)
+	return swim, bus
+}
+
+func TestSWIM_GossipExchange(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	a, aBus := newGossipNode("node-a", logger)
+	b, bBus := newGossipNode("node-b", logger)
+	hyperbus.ConnectMemory(aBus, bBus)
+
+	// Each knows the other, and only b knows about c
+	a.Join(context.TODO(), &Member{ID: "node-b", LastSeen: time.Now(), Status: Alive})
+	b.Join(context.TODO(), &Member{ID: "node-a", LastSeen: time.Now(), Status: Alive})
+	third := &Member{
+		ID:           "node-c",
+		Address:      &net.TCPAddr{IP: net.IPv4(127, 0, 0, 3), Port: 8443},
+		LastSeen:     time.Now(),
+		Status:       Alive,
+		Capabilities: &proto.NodeCapabilities{CpuCores: 8},
+	}
+	b.Join(context.TODO(), third)
+
+	joined := make(joinNotifier, 1)
+	a.AddEventHandler(joined)
+
+	// a gossips with its only peer and learns about c from the reply
+	a.gossip(context.TODO())
+	select {
+	case member := <-joined:
+		assert.Equal(t, hyperbus.NodeID("node-c"), member.ID)
+		assert.Equal(t, Alive, member.Status)
+		assert.Equal(t, third.Address.String(), member.Address.String())
+		assert.Equal(t, int32(8), member.Capabilities.CpuCores)
+		assert.True(t, third.LastSeen.Equal(member.LastSeen))
+	case <-time.After(time.Second):
+		t.Fatal("node-a never learned about node-c")
+	}
+}
+
+func TestSWIM_Bootstrap(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	a, aBus := newGossipNode("node-a", logger)
+	b, bBus := newGossipNode("node-b", logger)
+	hyperbus.ConnectMemory(aBus, bBus)
+	b.Join(context.TODO(), &Member{ID: "node-c", LastSeen: time.Now(), Status: Alive})
+
+	// a only knows b's address; dialing it names the node
+	dial := func(ctx context.Context, addr string) (hyperbus.NodeID, error) {
+		if addr != "b:8443" {
+			return "", errors.New("unreachable")
+		}
+		return "node-b", nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, a.Bootstrap(ctx, dial, []string{"x:8443", "b:8443"}))
+	assert.True(t, a.IsAlive("node-b"))
+	assert.True(t, a.IsAlive("node-c"))
+	assert.True(t, b.IsAlive("node-a"))
+
+	assert.ErrorContains(t, a.Bootstrap(ctx, dial, []string{"x:8443"}), "no bootstrap node reachable")
+}
+
+func TestSWIM_SnapshotDuringGossip(t *testing.T) {
+	logger := log.New(slog.LevelError)
+	a, aBus := newGossipNode("node-a", logger)
+	b, bBus := newGossipNode("node-b", logger)
+	hyperbus.ConnectMemory(aBus, bBus)
+
+	a.Join(context.TODO(), &Member{ID: "node-b", LastSeen: time.Now(), Status: Alive})
+	b.Join(context.TODO(), &Member{ID: "node-a", LastSeen: time.Now(), Status: Alive})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			a.gossip(context.TODO())
+			b.gossip(context.TODO())
+			a.UpdateMemberStatus("node-b", MemberStatus(i%2))
+		}
+	}()
+
+	// Reading snapshots while gossip mutates membership must not race
+	for {
+		select {
+		case <-done:
+			assert.Len(t, a.Snapshot(), 1)
+			return
+		default:
+			for _, member := range append(a.Snapshot(), b.Snapshot()...) {
+				_ = member.Status
+				_ = member.LastSeen
+			}
+		}
+	}
+}
+
+func TestSWIM_MergeAdoptsNewerStatus(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	swim := NewSWIM(NewMembership(&Member{ID: "node-a"}, logger), nil, DefaultSWIMConfig(), logger)
+
+	seen := time.Now()
+	swim.Join(context.TODO(), &Member{ID: "node-b", LastSeen: seen, Status: Alive})
+
+	// An older report is ignored
+	swim.HandleGossipMessage(context.TODO(), &proto.ClusterState{Members: []*proto.MemberState{
+		{NodeId: "node-b", Status: int32(Dead), LastSeenUnixNano: seen.Add(-time.Second).UnixNano()},
+	}})
+	assert.Equal(t, Alive, swim.Members()["node-b"].Status)
+
+	// A newer one is adopted, and reports about ourselves are ignored
+	swim.HandleGossipMessage(context.TODO(), &proto.ClusterState{Members: []*proto.MemberState{
+		{NodeId: "node-b", Status: int32(Suspect), LastSeenUnixNano: seen.Add(time.Second).UnixNano()},
+		{NodeId: "node-a", Status: int32(Dead), LastSeenUnixNano: seen.Add(time.Second).UnixNano()},
+	}})
+	assert.Equal(t, Suspect, swim.Members()["node-b"].Status)
+	assert.True(t, seen.Add(time.Second).Equal(swim.Members()["node-b"].LastSeen))
+	assert.NotContains(t, swim.Members(), hyperbus.NodeID("node-a"))
+}
+
+func TestSWIM_RefuteSuspicion(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	a := NewSWIM(NewMembership(&Member{ID: "node-a", Status: Alive}, logger), nil, DefaultSWIMConfig(), logger)
+	b := NewSWIM(NewMembership(&Member{ID: "node-b", Status: Alive}, logger), nil, DefaultSWIMConfig(), logger)
+
+	// b wrongly suspects a, with a sighting newer than any a will report
+	a.Join(context.TODO(), &Member{ID: "node-b", LastSeen: time.Now(), Status: Alive})
+	b.Join(context.TODO(), &Member{ID: "node-a", LastSeen: time.Now().Add(time.Hour), Status: Suspect})
+
+	// a hears of the suspicion and bumps its incarnation
+	a.HandleGossipMessage(context.TODO(), b.localState())
+	assert.Equal(t, uint64(1), a.LocalMember().Incarnation)
+	assert.Equal(t, Alive, a.LocalMember().Status)
+
+	// a's next gossip carries the refutation, which overrides the stale suspicion
+	b.HandleGossipMessage(context.TODO(), a.localState())
+	member := b.Members()["node-a"]
+	assert.Equal(t, Alive, member.Status)
+	assert.Equal(t, uint64(1), member.Incarnation)
+
+	// A stale suspicion from before the refutation doesn't bump it again
+	b.Join(context.TODO(), &Member{ID: "node-a", LastSeen: time.Now().Add(time.Hour), Status: Suspect})
+	a.HandleGossipMessage(context.TODO(), b.localState())
+	assert.Equal(t, uint64(1), a.LocalMember().Incarnation)
+}
+
+func TestSWIM_MergePrefersHigherIncarnation(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	swim := NewSWIM(NewMembership(&Member{ID: "node-a"}, logger), nil, DefaultSWIMConfig(), logger)
+
+	seen := time.Now()
+	swim.Join(context.TODO(), &Member{ID: "node-b", LastSeen: seen, Status: Suspect, Incarnation: 2})
+
+	// A later sighting from an older incarnation is stale
+	swim.HandleGossipMessage(context.TODO(), &proto.ClusterState{Members: []*proto.MemberState{
+		{NodeId: "node-b", Status: int32(Alive), LastSeenUnixNano: seen.Add(time.Second).UnixNano(), Incarnation: 1},
+	}})
+	assert.Equal(t, Suspect, swim.Members()["node-b"].Status)
+
+	// An earlier sighting from a newer incarnation wins
+	swim.HandleGossipMessage(context.TODO(), &proto.ClusterState{Members: []*proto.MemberState{
+		{NodeId: "node-b", Status: int32(Alive), LastSeenUnixNano: seen.Add(-time.Second).UnixNano(), Incarnation: 3},
+	}})
+	assert.Equal(t, Alive, swim.Members()["node-b"].Status)
+	assert.Equal(t, uint64(3), swim.Members()["node-b"].Incarnation)
+
+	// Suspicion of an incarnation we already refuted is ignored
+	swim.localMember.Incarnation = 5
+	swim.HandleGossipMessage(context.TODO(), &proto.ClusterState{Members: []*proto.MemberState{
+		{NodeId: "node-a", Status: int32(Suspect), Incarnation: 4},
+	}})
+	assert.Equal(t, uint64(5), swim.LocalMember().Incarnation)
+}
+
 func TestSWIM_SuspectHandling(t *testing.T) {
 	logger := log.New(slog.LevelDebug)
 
@@ -67,6 +259,7 @@ func TestSWIM_SuspectHandling(t *testing.T) {
 	// Create SWIM instance
 	config := DefaultSWIMConfig()
 	config.SuspectPeriod = time.Millisecond * 10 // Short period for testing
+	config.SuspectGrace = 0                      // Declare dead without confirmation
 	swim := NewSWIM(membership, nil, config, logger)
 
 	// Add a remote member with suspect status
@@ -89,3 +282,203 @@ func TestSWIM_SuspectHandling(t *testing.T) {
 	assert.True(t, exists)
 	assert.Equal(t, Dead, member.Status)
 }
+
+func TestSWIM_StartStop(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	localMember := &Member{
+		ID:       "local-node",
+		Address:  &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8443},
+		LastSeen: time.Now(),
+		Status:   Alive,
+	}
+	membership := NewMembership(localMember, logger)
+
+	config := DefaultSWIMConfig()
+	config.GossipPeriod = time.Millisecond
+	swim := NewSWIM(membership, nil, config, logger)
+
+	// Stopping before starting is a no-op
+	swim.Stop()
+
+	for i := 0; i < 50; i++ {
+		swim.Start(context.Background())
+		done := swim.Done()
+		time.Sleep(time.Millisecond)
+		swim.Stop()
+
+		// No SWIM loop outlives Stop
+		select {
+		case <-done:
+		default:
+			t.Fatal("SWIM loops still running after Stop")
+		}
+	}
+
+	// Cancelling the context passed to Start stops the loops as well
+	ctx, cancel := context.WithCancel(context.Background())
+	swim.Start(ctx)
+	cancel()
+	select {
+	case <-swim.Done():
+	case <-time.After(time.Second):
+		t.Fatal("SWIM loops still running after their context was cancelled")
+	}
+	swim.Stop()
+}
+
+// scriptedProber answers probes from fixed sets of directly and indirectly
+// reachable targets
+type scriptedProber struct {
+	direct    map[hyperbus.NodeID]bool
+	reachable map[hyperbus.NodeID]bool
+}
+
+func (p *scriptedProber) Ping(ctx context.Context, target hyperbus.NodeID) error {
+	if !p.direct[target] {
+		return errors.New("no ack")
+	}
+	return nil
+}
+
+func (p *scriptedProber) PingReq(ctx context.Context, via, target hyperbus.NodeID) error {
+	if via != "helper-node" || !p.reachable[target] {
+		return errors.New("no ack")
+	}
+	return nil
+}
+
+func TestSWIM_SuspectGrace(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	localMember := &Member{ID: "local-node", LastSeen: time.Now(), Status: Alive}
+	membership := NewMembership(localMember, logger)
+
+	config := DefaultSWIMConfig()
+	config.SuspectPeriod = time.Millisecond * 10
+	config.SuspectGrace = time.Millisecond * 200
+	swim := NewSWIM(membership, nil, config, logger)
+	swim.SetProber(&scriptedProber{reachable: map[hyperbus.NodeID]bool{"flaky-node": true}})
+
+	// A helper to probe through, and two timed-out suspects
+	membership.Join(context.Background(), &Member{ID: "helper-node", LastSeen: time.Now(), Status: Alive})
+	for _, id := range []hyperbus.NodeID{"flaky-node", "dead-node"} {
+		membership.Join(context.Background(), &Member{
+			ID:       id,
+			LastSeen: time.Now().Add(-time.Millisecond * 20),
+			Status:   Suspect,
+		})
+	}
+
+	// The timeout starts the grace phase instead of declaring death
+	swim.checkSuspects()
+	members := membership.Members()
+	assert.Equal(t, Suspect, members["flaky-node"].Status)
+	assert.Equal(t, Suspect, members["dead-node"].Status)
+
+	// The indirect ack clears the suspicion
+	assert.Eventually(t, func() bool {
+		swim.checkSuspects()
+		return membership.Members()["flaky-node"].Status == Alive
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, Suspect, membership.Members()["dead-node"].Status)
+
+	// The unreachable suspect is declared dead once the grace expires
+	time.Sleep(config.SuspectGrace)
+	swim.checkSuspects()
+	members = membership.Members()
+	assert.Equal(t, Alive, members["flaky-node"].Status)
+	assert.Equal(t, Dead, members["dead-node"].Status)
+}
+
+func TestSWIM_ProbeIndirect(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	membership := NewMembership(&Member{ID: "local-node", LastSeen: time.Now(), Status: Alive}, logger)
+	config := DefaultSWIMConfig()
+	config.Probe.IndirectFanout = 2
+	swim := NewSWIM(membership, nil, config, logger)
+
+	// Direct probes to flaky-node are dropped, but the helper still reaches it
+	swim.SetProber(&scriptedProber{
+		direct:    map[hyperbus.NodeID]bool{"helper-node": true},
+		reachable: map[hyperbus.NodeID]bool{"flaky-node": true},
+	})
+	for _, id := range []hyperbus.NodeID{"helper-node", "flaky-node", "dead-node"} {
+		membership.Join(context.Background(), &Member{ID: id, LastSeen: time.Now(), Status: Alive})
+	}
+
+	for i := 0; i < 100; i++ {
+		swim.probe(context.Background())
+	}
+
+	members := membership.Members()
+	assert.Equal(t, Alive, members["helper-node"].Status)
+	assert.Equal(t, Alive, members["flaky-node"].Status)
+	assert.Equal(t, Suspect, members["dead-node"].Status)
+}
+
+// recordingParticipant gossips a fixed epoch and records what it observes
+type recordingParticipant struct {
+	epoch    uint64
+	observed []uint64
+}
+
+func (p *recordingParticipant) ContributeState(state *proto.ClusterState) {
+	state.Epoch = p.epoch
+}
+
+func (p *recordingParticipant) ObserveState(state *proto.ClusterState) {
+	p.observed = append(p.observed, state.Epoch)
+}
+
+func TestSWIM_GossipParticipants(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	newSWIM := func(id string) (*SWIM, *recordingParticipant) {
+		membership := NewMembership(&Member{ID: hyperbus.NodeID(id), LastSeen: time.Now(), Status: Alive}, logger)
+		swim := NewSWIM(membership, nil, DefaultSWIMConfig(), logger)
+		participant := &recordingParticipant{epoch: uint64(len(id))}
+		swim.AddGossipParticipant(participant)
+		return swim, participant
+	}
+
+	sender, _ := newSWIM("node-a")
+	receiver, observer := newSWIM("node-bb")
+
+	state := sender.localState()
+	assert.Equal(t, "node-a", state.SenderId)
+	assert.Equal(t, uint64(6), state.Epoch)
+
+	receiver.HandleGossipMessage(context.Background(), state)
+	assert.Equal(t, []uint64{6}, observer.observed)
+}
+
+// newProbeNode creates a bus answering probes through a BusProber
+func newProbeNode(id hyperbus.NodeID, logger *log.Logger) (*BusProber, *hyperbus.Bus) {
+	mux := hyperbus.NewMux()
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: id}, mux, logger)
+	prober := NewBusProber(bus, time.Second)
+	mux.Handle(hyperbus.MsgProbePing, prober)
+	mux.Handle(hyperbus.MsgProbePingReq, prober)
+	return prober, bus
+}
+
+func TestBusProber(t *testing.T) {
+	logger := log.New(slog.LevelError)
+	a, aBus := newProbeNode("node-a", logger)
+	_, bBus := newProbeNode("node-b", logger)
+	_, cBus := newProbeNode("node-c", logger)
+
+	// node-a only reaches node-c through node-b
+	hyperbus.ConnectMemory(aBus, bBus)
+	hyperbus.ConnectMemory(bBus, cBus)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	assert.NoError(t, a.Ping(ctx, "node-b"))
+	assert.Error(t, a.Ping(ctx, "node-c"))
+	assert.NoError(t, a.PingReq(ctx, "node-b", "node-c"))
+	assert.Error(t, a.PingReq(ctx, "node-b", "node-d"))
+}
@@ -0,0 +1,72 @@
+// Package metrics serves a node's local metrics to the rest of the cluster
+package metrics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// Source adds one component's figures to a node's metrics
+type Source func(m *proto.NodeMetrics)
+
+// Server answers metrics queries with the figures of its sources
+type Server struct {
+	nodeID  hyperbus.NodeID
+	sources []Source
+}
+
+// NewServer creates a server reporting the metrics of sources for nodeID
+func NewServer(nodeID hyperbus.NodeID, sources ...Source) *Server {
+	return &Server{nodeID: nodeID, sources: sources}
+}
+
+// Collect gathers the node's current metrics
+func (s *Server) Collect() *proto.NodeMetrics {
+	m := &proto.NodeMetrics{NodeId: string(s.nodeID)}
+	for _, source := range s.sources {
+		source(m)
+	}
+	return m
+}
+
+// HandleMessage answers a MetricsQuery
+func (s *Server) HandleMessage(ctx context.Context, conn hyperbus.Connection, stream hyperbus.Stream, data []byte) error {
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+	if header.Type != hyperbus.MsgMetricsQuery {
+		return fmt.Errorf("unexpected message type %d", header.Type)
+	}
+
+	msg, err := hyperbus.EncodeReply(data, hyperbus.MsgMetrics, s.Collect())
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+	return stream.WriteMessage(ctx, msg)
+}
+
+// Query asks a node for its local metrics
+func Query(ctx context.Context, bus *hyperbus.Bus, nodeID hyperbus.NodeID) (*proto.NodeMetrics, error) {
+	data, err := bus.Call(ctx, nodeID, hyperbus.MsgMetricsQuery, &proto.MetricsQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.Type != hyperbus.MsgMetrics {
+		return nil, fmt.Errorf("unexpected message type %d in reply to metrics query", header.Type)
+	}
+
+	var m proto.NodeMetrics
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	// Each source adds its own figures
+	server := NewServer("server",
+		func(m *proto.NodeMetrics) { m.Arrays = 3 },
+		func(m *proto.NodeMetrics) { m.TasksRunning = 2 },
+	)
+	mux := hyperbus.NewMux()
+	mux.Handle(hyperbus.MsgMetricsQuery, server)
+
+	client := hyperbus.New(hyperbus.NodeInfo{ID: "client"}, hyperbus.NewMux(), logger)
+	hyperbus.ConnectMemory(client, hyperbus.New(hyperbus.NodeInfo{ID: "server"}, mux, logger))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	m, err := Query(ctx, client, "server")
+	assert.NoError(t, err)
+	assert.Equal(t, "server", m.NodeId)
+	assert.Equal(t, int64(3), m.Arrays)
+	assert.Equal(t, int64(2), m.TasksRunning)
+}
@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// samplesPerWorker is how many evenly spaced indices each worker times
+	// before the rest of the range is split
+	samplesPerWorker = 4
+
+	// chunksPerWorker is how many cost-balanced chunks are cut per worker,
+	// so workers that finish early can pick up more
+	chunksPerWorker = 4
+
+	// cancelCheckInterval is how many iterations a chunk runs between cancellation checks
+	cancelCheckInterval = 1024
+)
+
+// chunk is a half-open range of indices
+type chunk struct {
+	begin, end int
+}
+
+// AdaptiveParallelFor executes fn for indices 0 to n-1 on the given number of
+// workers. A sampling phase times evenly spaced indices to estimate how the
+// per-index cost varies over the range; the remaining indices are then split
+// into chunks of roughly equal estimated cost that workers pull as they go.
+func AdaptiveParallelFor(ctx context.Context, n int, fn func(i int) error, workers int) error {
+	_, err := adaptiveParallelFor(ctx, n, fn, workers, timed(fn))
+	return err
+}
+
+// timed returns a sampler running fn and measuring how long it took
+func timed(fn func(i int) error) func(i int) (time.Duration, error) {
+	return func(i int) (time.Duration, error) {
+		start := time.Now()
+		err := fn(i)
+		return time.Since(start), err
+	}
+}
+
+// adaptiveParallelFor runs AdaptiveParallelFor, running the sampled indices
+// through sample to learn their cost, and returns the chunks it planned
+func adaptiveParallelFor(ctx context.Context, n int, fn func(i int) error, workers int, sample func(i int) (time.Duration, error)) ([]chunk, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	workers = max(1, min(workers, n))
+
+	// Sample every stride-th index; sampled indices are real iterations
+	// and are skipped when the chunks run
+	samples := min(n, workers*samplesPerWorker)
+	stride := n / samples
+	costs := make([]time.Duration, samples)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(workers)
+	for k := 0; k < samples; k++ {
+		g.Go(func() error {
+			if gctx.Err() != nil {
+				return gctx.Err()
+			}
+			cost, err := sample(k * stride)
+			if err != nil {
+				return err
+			}
+			// Never let a sample estimate zero cost
+			costs[k] = max(cost, time.Nanosecond)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	chunks := planChunks(n, stride, costs, workers*chunksPerWorker)
+	sampled := func(i int) bool {
+		return i%stride == 0 && i/stride < samples
+	}
+
+	var next atomic.Int64
+	g, gctx = errgroup.WithContext(ctx)
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for {
+				c := int(next.Add(1)) - 1
+				if c >= len(chunks) {
+					return nil
+				}
+				for i := chunks[c].begin; i < chunks[c].end; i++ {
+					// Stop early once another worker has failed
+					if (i-chunks[c].begin)%cancelCheckInterval == 0 && gctx.Err() != nil {
+						return gctx.Err()
+					}
+					if sampled(i) {
+						continue
+					}
+					if err := fn(i); err != nil {
+						return err
+					}
+				}
+			}
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// planChunks splits 0 to n-1 into about count chunks of equal estimated cost.
+// Index i is estimated to cost as much as the sample at or before it.
+func planChunks(n, stride int, costs []time.Duration, count int) []chunk {
+	estimate := func(i int) time.Duration {
+		return costs[min(i/stride, len(costs)-1)]
+	}
+
+	var total time.Duration
+	for i := 0; i < n; i++ {
+		total += estimate(i)
+	}
+	target := max(total/time.Duration(count), time.Nanosecond)
+
+	chunks := make([]chunk, 0, count+1)
+	begin := 0
+	var acc time.Duration
+	for i := 0; i < n; i++ {
+		acc += estimate(i)
+		if acc >= target {
+			chunks = append(chunks, chunk{begin, i + 1})
+			begin = i + 1
+			acc = 0
+		}
+	}
+	if begin < n {
+		chunks = append(chunks, chunk{begin, n})
+	}
+	return chunks
+}
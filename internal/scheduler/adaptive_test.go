@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/errgroup"
+)
+
+// fixedParallelFor splits 0 to n-1 into one contiguous chunk per worker
+func fixedParallelFor(ctx context.Context, n int, fn func(i int) error, workers int) error {
+	workers = min(workers, n)
+	chunkSize := (n + workers - 1) / workers
+
+	g, ctx := errgroup.WithContext(ctx)
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for i := w * chunkSize; i < min((w+1)*chunkSize, n); i++ {
+				if err := fn(i); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// skewedCost makes the first quarter of the range expensive and the rest free
+func skewedCost(n int) func(i int) error {
+	return func(i int) error {
+		if i < n/4 {
+			time.Sleep(200 * time.Microsecond)
+		}
+		return nil
+	}
+}
+
+func TestAdaptiveParallelFor_VisitsEveryIndexOnce(t *testing.T) {
+	for _, n := range []int{1, 3, 17, 1000, 4099} {
+		visits := make([]atomic.Int32, n)
+		err := AdaptiveParallelFor(context.Background(), n, func(i int) error {
+			visits[i].Add(1)
+			return nil
+		}, 4)
+		assert.NoError(t, err)
+		for i := range visits {
+			assert.Equal(t, int32(1), visits[i].Load(), "index %d of %d", i, n)
+		}
+	}
+}
+
+func TestAdaptiveParallelFor_Error(t *testing.T) {
+	failure := errors.New("boom")
+	err := AdaptiveParallelFor(context.Background(), 10000, func(i int) error {
+		if i == 7777 {
+			return failure
+		}
+		return nil
+	}, 4)
+	assert.ErrorIs(t, err, failure)
+}
+
+func TestAdaptiveParallelFor_SkewedCostIsBalanced(t *testing.T) {
+	const n, workers = 2000, 4
+
+	// The first quarter of the range reports 9x the cost of the rest
+	var visits atomic.Int64
+	fn := func(i int) error {
+		visits.Add(1)
+		return nil
+	}
+	sample := func(i int) (time.Duration, error) {
+		if i < n/4 {
+			return 9 * time.Microsecond, fn(i)
+		}
+		return time.Microsecond, fn(i)
+	}
+
+	chunks, err := adaptiveParallelFor(context.Background(), n, fn, workers, sample)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(n), visits.Load())
+
+	// Fixed chunking would give the expensive quarter one chunk of four; the
+	// plan cuts it into more chunks than the cheap remainder
+	var expensive, cheap int
+	for _, c := range chunks {
+		if c.begin < n/4 {
+			expensive++
+		} else {
+			cheap++
+		}
+	}
+	assert.Greater(t, expensive, cheap)
+}
+
+func TestPlanChunks_BalancesEstimatedCost(t *testing.T) {
+	// Four samples over 400 indices; the first segment is 9x as expensive
+	costs := []time.Duration{9, 1, 1, 1}
+	chunks := planChunks(400, 100, costs, 4)
+
+	assert.Equal(t, 0, chunks[0].begin)
+	assert.Equal(t, 400, chunks[len(chunks)-1].end)
+	for i := 1; i < len(chunks); i++ {
+		assert.Equal(t, chunks[i-1].end, chunks[i].begin)
+	}
+
+	// The expensive segment is cut into more, smaller chunks
+	assert.Less(t, chunks[0].end-chunks[0].begin, 100)
+	assert.Greater(t, chunks[len(chunks)-1].end-chunks[len(chunks)-1].begin, 100)
+}
+
+func BenchmarkParallelFor_SkewedCost(b *testing.B) {
+	const n, workers = 2000, 4
+	fn := skewedCost(n)
+
+	b.Run("adaptive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := AdaptiveParallelFor(context.Background(), n, fn, workers); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("fixed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := fixedParallelFor(context.Background(), n, fn, workers); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultDoneRetention is how many finished tasks no held task depends on
+// have their outcome remembered for tasks submitted later
+const DefaultDoneRetention = 1024
+
+// DefaultDependencyTimeout is how long a held task waits for a dependency
+// that was never submitted
+const DefaultDependencyTimeout = time.Minute
+
+// ErrDependencyCycle is returned when submitting a task whose dependencies
+// lead back to itself
+var ErrDependencyCycle = errors.New("task dependency cycle")
+
+// ErrDependencyFailed is the result of a task whose dependency failed
+var ErrDependencyFailed = errors.New("task dependency failed")
+
+// ErrUnknownDependency is the result of a held task one of whose dependencies
+// was neither submitted within the dependency timeout nor remembered as finished
+var ErrUnknownDependency = errors.New("unknown task dependency")
+
+// SetDoneRetention sets how many finished tasks that no held task depends on
+// keep their outcome; 0 restores the default. A task submitted later that
+// depends on a forgotten one waits for it like for one never submitted.
+func (s *Scheduler) SetDoneRetention(n int) {
+	if n <= 0 {
+		n = DefaultDoneRetention
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doneRetention = n
+	s.trimDoneLocked()
+}
+
+// SetDependencyTimeout sets how long a held task waits for a dependency that
+// is neither submitted nor finished before failing with
+// ErrUnknownDependency; 0 restores the default. It applies to tasks
+// submitted afterwards.
+func (s *Scheduler) SetDependencyTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultDependencyTimeout
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.depTimeout = timeout
+}
+
+// findCycleLocked returns the dependency path from task back to itself
+// through the tasks already submitted, or nil if there is none. The caller
+// must hold s.mu.
+func (s *Scheduler) findCycleLocked(task *Task) []string {
+	visited := make(map[string]bool)
+	var visit func(id string, path []string) []string
+	visit = func(id string, path []string) []string {
+		path = append(path, id)
+		if id == task.ID {
+			return path
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+
+		if dep, exists := s.tasks[id]; exists {
+			for _, next := range dep.DependsOn {
+				if cycle := visit(next, path); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, id := range task.DependsOn {
+		if cycle := visit(id, []string{task.ID}); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// admitLocked decides what happens to a newly submitted task: it is ready
+// if all its dependencies succeeded, failed if one of them failed, and held
+// otherwise. The caller must hold s.mu.
+func (s *Scheduler) admitLocked(task *Task) (ready bool, err error) {
+	waiting := false
+	for _, id := range task.DependsOn {
+		depErr, finished := s.done[id]
+		if !finished {
+			waiting = true
+			continue
+		}
+		if depErr != nil {
+			return false, fmt.Errorf("%w: task %s depends on %s: %w", ErrDependencyFailed, task.ID, id, depErr)
+		}
+	}
+	if !waiting {
+		return true, nil
+	}
+
+	s.held[task.ID] = task
+	unknown := false
+	for _, id := range task.DependsOn {
+		s.needed[id]++
+		if _, finished := s.done[id]; !finished {
+			s.dependents[id] = append(s.dependents[id], task.ID)
+			if _, submitted := s.tasks[id]; !submitted {
+				unknown = true
+			}
+		}
+	}
+
+	// Dependencies may be submitted after their dependents, but not never
+	if unknown {
+		task.depTimer = time.AfterFunc(s.depTimeout, func() { s.expireHeld(task) })
+	}
+	return false, nil
+}
+
+// releaseLocked takes a task out of the held set, forgetting outcomes of its
+// dependencies that nothing needs anymore. The caller must hold s.mu.
+func (s *Scheduler) releaseLocked(task *Task) {
+	delete(s.held, task.ID)
+	if task.depTimer != nil {
+		task.depTimer.Stop()
+	}
+	for _, id := range task.DependsOn {
+		if s.needed[id]--; s.needed[id] <= 0 {
+			delete(s.needed, id)
+		}
+	}
+	s.trimDoneLocked()
+}
+
+// trimDoneLocked forgets the outcomes of the oldest retired tasks beyond
+// doneRetention, skipping those a held task still depends on. The caller
+// must hold s.mu.
+func (s *Scheduler) trimDoneLocked() {
+	for n := len(s.retired); n > 0 && len(s.retired) > s.doneRetention; n-- {
+		id := s.retired[0]
+		s.retired = s.retired[1:]
+		if s.needed[id] > 0 {
+			s.retired = append(s.retired, id)
+			continue
+		}
+		delete(s.done, id)
+	}
+}
+
+// expireHeld fails a held task that still depends on a task which was never
+// submitted, along with the tasks depending on it
+func (s *Scheduler) expireHeld(task *Task) {
+	s.mu.Lock()
+	if s.held[task.ID] != task {
+		s.mu.Unlock()
+		return
+	}
+
+	var missing []string
+	for _, id := range task.DependsOn {
+		_, finished := s.done[id]
+		_, submitted := s.tasks[id]
+		if !finished && !submitted {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		s.mu.Unlock()
+		return
+	}
+
+	err := fmt.Errorf("%w: task %s depends on %s", ErrUnknownDependency, task.ID, strings.Join(missing, ", "))
+	s.releaseLocked(task)
+	ready := s.finishLocked(task.ID, err)
+	s.mu.Unlock()
+
+	s.sendResult(task, err)
+	s.logger.Debug("task dependency never submitted", "task_id", task.ID, "missing", missing)
+	s.pushReady(ready)
+}
+
+// finishLocked records a task's outcome and settles the held tasks depending
+// on it, returning those now ready to run. Dependents of a failed task fail
+// in turn, and their results are sent. The caller must hold s.mu.
+func (s *Scheduler) finishLocked(id string, err error) []*Task {
+	if task, exists := s.tasks[id]; exists && task.Cancel != nil {
+		task.Cancel()
+	}
+	s.done[id] = err
+	s.retired = append(s.retired, id)
+	delete(s.tasks, id)
+
+	waiting := s.dependents[id]
+	delete(s.dependents, id)
+
+	var ready []*Task
+	for _, depID := range waiting {
+		task, held := s.held[depID]
+		if !held {
+			continue
+		}
+
+		if err != nil {
+			s.releaseLocked(task)
+			failure := fmt.Errorf("%w: task %s depends on %s: %w", ErrDependencyFailed, depID, id, err)
+			s.sendResult(task, failure)
+			ready = append(ready, s.finishLocked(depID, failure)...)
+			continue
+		}
+
+		if s.dependenciesDoneLocked(task) {
+			s.releaseLocked(task)
+			ready = append(ready, task)
+		}
+	}
+	s.trimDoneLocked()
+	return ready
+}
+
+// dependenciesDoneLocked reports whether every dependency of a task
+// finished successfully. The caller must hold s.mu.
+func (s *Scheduler) dependenciesDoneLocked(task *Task) bool {
+	for _, id := range task.DependsOn {
+		if err, finished := s.done[id]; !finished || err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// cycleError describes a dependency cycle
+func cycleError(path []string) error {
+	return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(path, " -> "))
+}
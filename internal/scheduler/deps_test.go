@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitResults collects n results, failing the test if they take too long
+func waitResults(t *testing.T, results chan error, n int) []error {
+	var errs []error
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-results:
+			errs = append(errs, err)
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of %d tasks finished", i, n)
+		}
+	}
+	return errs
+}
+
+func TestScheduler_DiamondDependencies(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	var mu sync.Mutex
+	var order []string
+	results := make(chan error, 4)
+	task := func(id string, deps ...string) *Task {
+		return &Task{
+			ID: id,
+			Function: func(ctx context.Context) error {
+				mu.Lock()
+				order = append(order, id)
+				mu.Unlock()
+				return nil
+			},
+			Result:    results,
+			DependsOn: deps,
+		}
+	}
+
+	// The sink is submitted first and waits for both branches, which wait for the source
+	assert.NoError(t, s.SubmitTask(ctx, task("sink", "left", "right")))
+	assert.NoError(t, s.SubmitTask(ctx, task("left", "source")))
+	assert.NoError(t, s.SubmitTask(ctx, task("right", "source")))
+	assert.NoError(t, s.SubmitTask(ctx, task("source")))
+
+	for _, err := range waitResults(t, results, 4) {
+		assert.NoError(t, err)
+	}
+	if assert.Len(t, order, 4) {
+		assert.Equal(t, "source", order[0])
+		assert.ElementsMatch(t, []string{"left", "right"}, order[1:3])
+		assert.Equal(t, "sink", order[3])
+	}
+}
+
+func TestScheduler_DependencyFailure(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	boom := errors.New("boom")
+	failed, middle, last, late := make(chan error, 1), make(chan error, 1), make(chan error, 1), make(chan error, 1)
+	ran := false
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "last", Function: func(ctx context.Context) error { ran = true; return nil }, Result: last, DependsOn: []string{"middle"}}))
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "middle", Function: func(ctx context.Context) error { ran = true; return nil }, Result: middle, DependsOn: []string{"failed"}}))
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "failed", Function: func(ctx context.Context) error { return boom }, Result: failed}))
+
+	// The failure cascades through every dependent without running them
+	assert.ErrorIs(t, waitResults(t, failed, 1)[0], boom)
+	err := waitResults(t, middle, 1)[0]
+	assert.ErrorIs(t, err, ErrDependencyFailed)
+	assert.ErrorIs(t, err, boom)
+	assert.ErrorIs(t, waitResults(t, last, 1)[0], ErrDependencyFailed)
+	assert.False(t, ran)
+
+	// Tasks submitted after the failure fail straight away
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "late", Function: func(ctx context.Context) error { return nil }, Result: late, DependsOn: []string{"failed"}}))
+	assert.ErrorIs(t, waitResults(t, late, 1)[0], ErrDependencyFailed)
+}
+
+func TestScheduler_DependencyCycle(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	ctx := context.Background()
+	noop := func(ctx context.Context) error { return nil }
+
+	// a waits on b, b on c; c closing the loop is rejected
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "a", Function: noop, Result: make(chan error, 1), DependsOn: []string{"b"}}))
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "b", Function: noop, Result: make(chan error, 1), DependsOn: []string{"c"}}))
+	err := s.SubmitTask(ctx, &Task{ID: "c", Function: noop, Result: make(chan error, 1), DependsOn: []string{"a"}})
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+	assert.ErrorContains(t, err, "c -> a -> b -> c")
+
+	// So is a task depending on itself
+	err = s.SubmitTask(ctx, &Task{ID: "self", Function: noop, Result: make(chan error, 1), DependsOn: []string{"self"}})
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+}
+
+func TestScheduler_UnknownDependencyTimesOut(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	s.SetDependencyTimeout(20 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	noop := func(ctx context.Context) error { return nil }
+	orphan, dependent, late := make(chan error, 1), make(chan error, 1), make(chan error, 1)
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "orphan", Function: noop, Result: orphan, DependsOn: []string{"missing"}}))
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "dependent", Function: noop, Result: dependent, DependsOn: []string{"orphan"}}))
+
+	// A dependency submitted within the timeout is waited for
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "late", Function: noop, Result: late, DependsOn: []string{"later"}}))
+	assert.NoError(t, s.SubmitTask(ctx, &Task{ID: "later", Function: func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}, Result: make(chan error, 1)}))
+
+	err := waitResults(t, orphan, 1)[0]
+	assert.ErrorIs(t, err, ErrUnknownDependency)
+	assert.ErrorContains(t, err, "missing")
+	assert.ErrorIs(t, waitResults(t, dependent, 1)[0], ErrUnknownDependency)
+	assert.NoError(t, waitResults(t, late, 1)[0])
+}
+
+func TestScheduler_DoneRetention(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	s.SetDoneRetention(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	results := make(chan error, 10)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		assert.NoError(t, s.SubmitTask(ctx, &Task{ID: id, Function: func(ctx context.Context) error { return nil }, Result: results}))
+		waitResults(t, results, 1)
+	}
+
+	// Only the newest outcomes are remembered
+	s.mu.RLock()
+	assert.Len(t, s.done, 2)
+	assert.Contains(t, s.done, "c")
+	assert.Contains(t, s.done, "d")
+	s.mu.RUnlock()
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// deque is a worker's task queue. Tasks are taken highest priority first
+// and, within a priority, in the order they were queued, both by the owner
+// and by idle workers stealing from it.
+type deque struct {
+	tasks taskHeap
+	mu    sync.Mutex
+}
+
+// push queues a task; seq orders it after the tasks pushed before it
+func (d *deque) push(task *Task, seq uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	heap.Push(&d.tasks, queuedTask{task: task, seq: seq})
+}
+
+// pop takes the next task, or nil if the deque is empty
+func (d *deque) pop() *Task {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.tasks) == 0 {
+		return nil
+	}
+	return heap.Pop(&d.tasks).(queuedTask).task
+}
+
+// pool is a fixed set of task deques, one per worker. Queued counts the
+// tasks across all deques that no worker has claimed yet; a worker claims
+// one before looking for it, so a claim always finds a task.
+type pool struct {
+	deques []*deque
+	queued int
+	closed bool
+	next   int    // deque the next pushed task goes to
+	seq    uint64 // push order, breaking ties between equal priorities
+	mu     sync.Mutex
+	work   *sync.Cond // signalled when a task is queued
+}
+
+// newPool creates a pool with a deque for each of n workers
+func newPool(n int) *pool {
+	p := &pool{deques: make([]*deque, n)}
+	for i := range p.deques {
+		p.deques[i] = &deque{}
+	}
+	p.work = sync.NewCond(&p.mu)
+	return p
+}
+
+// push queues a task without waiting for a worker to be free, spreading
+// tasks over the deques round-robin, and wakes an idle worker
+func (p *pool) push(task *Task) {
+	p.mu.Lock()
+	d := p.deques[p.next]
+	p.next = (p.next + 1) % len(p.deques)
+	seq := p.seq
+	p.seq++
+	p.mu.Unlock()
+
+	d.push(task, seq)
+
+	p.mu.Lock()
+	p.queued++
+	p.mu.Unlock()
+	p.work.Signal()
+}
+
+// close wakes every worker; they exit once the queued tasks are done
+func (p *pool) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.work.Broadcast()
+}
+
+// take returns the next task for worker id, blocking while none is queued.
+// It prefers the worker's own deque and otherwise steals from the others.
+// It returns nil once the pool is closed and drained.
+func (p *pool) take(id int) *Task {
+	p.mu.Lock()
+	for p.queued == 0 && !p.closed {
+		p.work.Wait()
+	}
+	if p.queued == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	p.queued--
+	p.mu.Unlock()
+
+	for {
+		if task := p.deques[id].pop(); task != nil {
+			return task
+		}
+		for i := 1; i < len(p.deques); i++ {
+			if task := p.deques[(id+i)%len(p.deques)].pop(); task != nil {
+				return task
+			}
+		}
+	}
+}
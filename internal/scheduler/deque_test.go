@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeque_Order(t *testing.T) {
+	var d deque
+	for seq, task := range []*Task{{ID: "a"}, {ID: "b"}, {ID: "c", Priority: 1}, {ID: "d"}} {
+		d.push(task, uint64(seq))
+	}
+
+	// Higher priorities first, then oldest first
+	for _, id := range []string{"c", "a", "b", "d"} {
+		assert.Equal(t, id, d.pop().ID)
+	}
+	assert.Nil(t, d.pop())
+}
+
+func TestPool_IdleWorkerSteals(t *testing.T) {
+	p := newPool(2)
+
+	// Both tasks land on separate deques; worker 0 takes its own, then steals
+	p.push(&Task{ID: "first"})
+	p.push(&Task{ID: "second"})
+	assert.Equal(t, "first", p.take(0).ID)
+	assert.Equal(t, "second", p.take(0).ID)
+
+	p.close()
+	assert.Nil(t, p.take(1))
+}
+
+func TestScheduler_BoundedWorkers(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	s := NewScheduler(logger)
+	s.SetWorkers(3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	const n = 200
+	var running, peak atomic.Int64
+	results := make(chan error, n)
+	for i := 0; i < n; i++ {
+		err := s.SubmitTask(ctx, &Task{
+			ID: fmt.Sprintf("task-%d", i),
+			Function: func(ctx context.Context) error {
+				now := running.Add(1)
+				defer running.Add(-1)
+				for {
+					max := peak.Load()
+					if now <= max || peak.CompareAndSwap(max, now) {
+						break
+					}
+				}
+				time.Sleep(100 * time.Microsecond)
+				return nil
+			},
+			Result: results,
+		})
+		assert.NoError(t, err)
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-results:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d tasks completed", i, n)
+		}
+	}
+	assert.LessOrEqual(t, peak.Load(), int64(3))
+
+	s.Stop()
+}
+
+func TestScheduler_QueuesAheadOfBusyWorkers(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	s.SetWorkers(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	// Keep both workers busy
+	const n = 8
+	results := make(chan error, n)
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, s.SubmitTask(ctx, &Task{
+			ID:       fmt.Sprintf("blocker-%d", i),
+			Function: func(ctx context.Context) error { started <- struct{}{}; <-release; return nil },
+			Result:   results,
+		}))
+	}
+	<-started
+	<-started
+
+	// The rest reach the deques anyway, where either worker can take them
+	for i := 2; i < n; i++ {
+		assert.NoError(t, s.SubmitTask(ctx, &Task{ID: fmt.Sprintf("task-%d", i), Function: func(ctx context.Context) error { return nil }, Result: results}))
+	}
+	assert.Eventually(t, func() bool {
+		s.pool.mu.Lock()
+		defer s.pool.mu.Unlock()
+		return s.pool.queued == n-2
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	for i := 0; i < n; i++ {
+		assert.NoError(t, <-results)
+	}
+}
+
+// goroutinePerTask runs tasks the way the scheduler did before it had a
+// worker pool, starting a goroutine for each
+func goroutinePerTask(s *Scheduler, tasks <-chan *Task, done chan<- struct{}) {
+	var wg sync.WaitGroup
+	for task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.executeTask(task)
+		}()
+	}
+	wg.Wait()
+	close(done)
+}
+
+// benchmarkTasks submits b.N trivial tasks through submit and reports the
+// peak number of goroutines seen while they ran
+func benchmarkTasks(b *testing.B, submit func(task *Task)) {
+	var peak atomic.Int64
+	results := make(chan error, b.N)
+	fn := func(ctx context.Context) error {
+		g := int64(runtime.NumGoroutine())
+		for {
+			max := peak.Load()
+			if g <= max || peak.CompareAndSwap(max, g) {
+				return nil
+			}
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		submit(&Task{ID: fmt.Sprintf("task-%d", i), Function: fn, Result: results})
+	}
+	for i := 0; i < b.N; i++ {
+		<-results
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(peak.Load()), "peak-goroutines")
+}
+
+// BenchmarkScheduler_SubmitTask compares the worker pool against a goroutine
+// per task; run with -benchtime=100000x for 100k tasks
+func BenchmarkScheduler_SubmitTask(b *testing.B) {
+	logger := log.New(slog.LevelError)
+
+	b.Run("work-stealing", func(b *testing.B) {
+		s := NewScheduler(logger)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s.Start(ctx)
+		defer s.Stop()
+
+		benchmarkTasks(b, func(task *Task) { s.SubmitTask(ctx, task) })
+	})
+
+	b.Run("goroutine-per-task", func(b *testing.B) {
+		s := NewScheduler(logger)
+		tasks := make(chan *Task, 100)
+		done := make(chan struct{})
+		go goroutinePerTask(s, tasks, done)
+		defer func() {
+			close(tasks)
+			<-done
+		}()
+
+		benchmarkTasks(b, func(task *Task) {
+			task.ctx, task.Cancel = context.WithCancel(s.ctx)
+			s.mu.Lock()
+			s.tasks[task.ID] = task
+			s.mu.Unlock()
+			tasks <- task
+		})
+	})
+}
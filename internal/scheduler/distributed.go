@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+)
+
+// DefaultProgressInterval is how often a worker reports progress on its range
+const DefaultProgressInterval = 100 * time.Millisecond
+
+// RangeRunner runs part of a distributed ParallelFor on a worker node
+type RangeRunner interface {
+	// RunRange runs indices begin to end-1 in order on the worker. progress
+	// is called with the number of indices completed so far, counted from
+	// begin. An error wrapping an *IterationError means the loop body failed
+	// and ends the loop; any other error means the worker dropped out of it.
+	RunRange(ctx context.Context, worker hyperbus.NodeID, begin, end int, progress func(done int)) error
+}
+
+// IterationError is returned by RunRange when the loop body fails at an index
+type IterationError struct {
+	Index int
+	Err   error
+}
+
+// Error implements the error interface
+func (e *IterationError) Error() string {
+	return fmt.Sprintf("index %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the loop body's error
+func (e *IterationError) Unwrap() error {
+	return e.Err
+}
+
+// ProgressFunc receives the number of indices completed across the cluster.
+// Calls are serialized and done never decreases.
+type ProgressFunc func(done, total int)
+
+// subRange is a part of the loop assigned to one worker
+type subRange struct {
+	begin, end int
+	done       int // indices completed from begin
+}
+
+// DistributedFor splits 0 to n-1 into one contiguous range per worker and
+// runs them through runner, summing the progress workers report into a
+// cluster-wide count. When a worker fails, the rest of its range past its
+// last progress report is rescheduled on the remaining workers, so indices it
+// finished without reporting may run again. When the loop body fails the
+// other ranges are cancelled and its *IterationError is returned.
+func DistributedFor(ctx context.Context, logger *log.Logger, n int, workers []hyperbus.NodeID, runner RangeRunner, onProgress ProgressFunc) error {
+	if n <= 0 {
+		return nil
+	}
+	if len(workers) == 0 {
+		return errors.New("no workers available")
+	}
+
+	d := &distributedFor{
+		logger:     logger,
+		total:      n,
+		runner:     runner,
+		onProgress: onProgress,
+		alive:      append([]hyperbus.NodeID(nil), workers...),
+	}
+
+	size := (n + len(workers) - 1) / len(workers)
+	var ranges []*subRange
+	for begin := 0; begin < n; begin += size {
+		ranges = append(ranges, &subRange{begin: begin, end: min(begin+size, n)})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	d.cancel = cancel
+
+	for i, r := range ranges {
+		d.wg.Add(1)
+		go d.run(ctx, workers[i], r)
+	}
+	d.wg.Wait()
+
+	if d.err != nil {
+		return d.err
+	}
+	return ctx.Err()
+}
+
+// distributedFor is the coordinator state of one DistributedFor call
+type distributedFor struct {
+	logger     *log.Logger
+	total      int
+	runner     RangeRunner
+	onProgress ProgressFunc
+	cancel     context.CancelFunc // stops every range once the loop failed
+	alive      []hyperbus.NodeID
+	next       int // round-robin position in alive for rescheduling
+	done       int // indices completed across all ranges
+	err        error
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+}
+
+// run runs a range on a worker, rescheduling what's left if the worker fails
+func (d *distributedFor) run(ctx context.Context, worker hyperbus.NodeID, r *subRange) {
+	defer d.wg.Done()
+
+	err := d.runner.RunRange(ctx, worker, r.begin, r.end, func(done int) {
+		d.report(r, done)
+	})
+	if err == nil {
+		// Count anything the worker finished without reporting
+		d.report(r, r.end-r.begin)
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	// A failing loop body fails the loop wherever it runs
+	var iterErr *IterationError
+	if errors.As(err, &iterErr) {
+		d.mu.Lock()
+		if d.err == nil {
+			d.err = err
+		}
+		d.mu.Unlock()
+		d.cancel()
+		return
+	}
+
+	d.mu.Lock()
+	rest := &subRange{begin: r.begin + r.done, end: r.end}
+	replacement, ok := d.dropLocked(worker)
+	if !ok {
+		if d.err == nil {
+			d.err = fmt.Errorf("no workers left to run indices %d to %d: %w", rest.begin, rest.end-1, err)
+		}
+		d.mu.Unlock()
+		return
+	}
+	d.mu.Unlock()
+
+	d.logger.Warn("worker dropped out of parallel loop, rescheduling",
+		"worker", worker,
+		"replacement", replacement,
+		"begin", rest.begin,
+		"end", rest.end,
+		"error", err)
+
+	d.wg.Add(1)
+	go d.run(ctx, replacement, rest)
+}
+
+// dropLocked removes a failed worker and picks another to take over its range
+func (d *distributedFor) dropLocked(worker hyperbus.NodeID) (hyperbus.NodeID, bool) {
+	for i, w := range d.alive {
+		if w == worker {
+			d.alive = append(d.alive[:i], d.alive[i+1:]...)
+			break
+		}
+	}
+	if len(d.alive) == 0 {
+		return "", false
+	}
+	d.next = (d.next + 1) % len(d.alive)
+	return d.alive[d.next], true
+}
+
+// report records a worker's progress on a range and publishes the new total
+func (d *distributedFor) report(r *subRange, done int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	done = min(done, r.end-r.begin)
+	if done <= r.done {
+		return
+	}
+	d.done += done - r.done
+	r.done = done
+
+	// Called under the lock so totals are published in order
+	if d.onProgress != nil {
+		d.onProgress(d.done, d.total)
+	}
+}
+
+// RunRange runs fn over begin to end-1 in order on the local node, calling
+// progress with the number of completed indices at most once per interval
+// and once at the end. Workers use it to serve their part of a DistributedFor.
+// An error fn returns is wrapped in an *IterationError.
+func RunRange(ctx context.Context, begin, end int, fn func(i int) error, progress func(done int), interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	lastReport := time.Now()
+	for i := begin; i < end; i++ {
+		if (i-begin)%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := fn(i); err != nil {
+			progress(i - begin)
+			return &IterationError{Index: i, Err: err}
+		}
+		if time.Since(lastReport) >= interval {
+			progress(i - begin + 1)
+			lastReport = time.Now()
+		}
+	}
+	progress(end - begin)
+	return nil
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// localRunner runs every worker's range in-process, failing chosen workers midway
+type localRunner struct {
+	visits []atomic.Int32
+	failAt map[hyperbus.NodeID]int // index at which a worker drops out
+	ran    map[hyperbus.NodeID]int
+	mu     sync.Mutex
+}
+
+func (r *localRunner) RunRange(ctx context.Context, worker hyperbus.NodeID, begin, end int, progress func(done int)) error {
+	failAt, fails := r.failAt[worker]
+	if fails && failAt >= begin && failAt < end {
+		end = failAt
+	} else {
+		fails = false
+	}
+
+	err := RunRange(ctx, begin, end, func(i int) error {
+		r.visits[i].Add(1)
+		r.mu.Lock()
+		r.ran[worker]++
+		r.mu.Unlock()
+		return nil
+	}, progress, time.Nanosecond)
+	if err == nil && fails {
+		return errors.New("worker lost")
+	}
+	return err
+}
+
+func TestDistributedFor_ProgressAndRescheduling(t *testing.T) {
+	const n = 1000
+	runner := &localRunner{
+		visits: make([]atomic.Int32, n),
+		failAt: map[hyperbus.NodeID]int{"worker-2": 700},
+		ran:    make(map[hyperbus.NodeID]int),
+	}
+
+	var reports []int
+	err := DistributedFor(context.Background(), log.New(slog.LevelDebug), n,
+		[]hyperbus.NodeID{"worker-1", "worker-2"}, runner,
+		func(done, total int) {
+			assert.Equal(t, n, total)
+			reports = append(reports, done)
+		})
+	assert.NoError(t, err)
+
+	// Progress only grows and ends at the total
+	assert.NotEmpty(t, reports)
+	assert.IsIncreasing(t, reports)
+	assert.Equal(t, n, reports[len(reports)-1])
+
+	// Every index ran exactly once; worker-1 picked up worker-2's unfinished range
+	for i := range runner.visits {
+		assert.Equal(t, int32(1), runner.visits[i].Load(), "index %d", i)
+	}
+	assert.Equal(t, 200, runner.ran["worker-2"])
+	assert.Equal(t, 800, runner.ran["worker-1"])
+}
+
+func TestDistributedFor_AllWorkersFail(t *testing.T) {
+	const n = 100
+	runner := &localRunner{
+		visits: make([]atomic.Int32, n),
+		failAt: map[hyperbus.NodeID]int{"worker-1": 10, "worker-2": 60},
+		ran:    make(map[hyperbus.NodeID]int),
+	}
+
+	err := DistributedFor(context.Background(), log.New(slog.LevelDebug), n,
+		[]hyperbus.NodeID{"worker-1", "worker-2"}, runner, nil)
+	assert.ErrorContains(t, err, "no workers left")
+}
+
+// failingRunner runs ranges in-process with a loop body that fails at one index
+type failingRunner struct {
+	failAt int
+	calls  atomic.Int32
+}
+
+func (r *failingRunner) RunRange(ctx context.Context, worker hyperbus.NodeID, begin, end int, progress func(done int)) error {
+	r.calls.Add(1)
+	return RunRange(ctx, begin, end, func(i int) error {
+		if i == r.failAt {
+			return errors.New("bad input")
+		}
+		return nil
+	}, progress, time.Nanosecond)
+}
+
+func TestDistributedFor_BodyErrorReturned(t *testing.T) {
+	runner := &failingRunner{failAt: 70}
+	err := DistributedFor(context.Background(), log.New(slog.LevelDebug), 100,
+		[]hyperbus.NodeID{"worker-1", "worker-2"}, runner, nil)
+
+	// The error reaches the caller instead of passing the range to another worker
+	var iterErr *IterationError
+	assert.ErrorAs(t, err, &iterErr)
+	assert.Equal(t, 70, iterErr.Index)
+	assert.EqualError(t, iterErr.Err, "bad input")
+	assert.Equal(t, int32(2), runner.calls.Load())
+}
@@ -2,7 +2,9 @@ package scheduler
 
 import (
 	"context"
+	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"github.com/melihxz/holocompute/internal/log"
 	"golang.org/x/sync/errgroup"
@@ -35,43 +37,127 @@ func ParallelFor(ctx context.Context, logger *log.Logger, n int, fn func(i int)
 	return g.Wait()
 }
 
-// Map applies a function to each element of a slice and stores the result in another slice
+// ChunkedParallelFor executes a function in parallel for indices 0 to n-1
+// like ParallelFor, but splits the range into maxConcurrency contiguous
+// chunks, one per CPU if 0, each run by a single goroutine. This avoids a
+// goroutine per index when fn is cheap.
+func ChunkedParallelFor(ctx context.Context, logger *log.Logger, n int, fn func(i int) error, maxConcurrency int) error {
+	return runChunks(ctx, n, maxConcurrency, func(ctx context.Context, _ int, r chunk) error {
+		done := ctx.Done()
+		for i := r.begin; i < r.end; i++ {
+			select {
+			case <-done:
+				return ctx.Err()
+			default:
+			}
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// runChunks splits [0,n) with splitRange into parts chunks, one per CPU if
+// 0, and runs fn on every chunk c in a goroutine of its own. The first error
+// cancels the ctx passed to the other chunks and is returned; fn is not
+// started once ctx is done.
+func runChunks(ctx context.Context, n, parts int, fn func(ctx context.Context, c int, r chunk) error) error {
+	if parts <= 0 {
+		parts = runtime.NumCPU()
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	for c, r := range splitRange(n, parts) {
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			return fn(gctx, c, r)
+		})
+	}
+	return g.Wait()
+}
+
+// splitRange splits [0,n) into at most parts contiguous chunks whose sizes
+// differ by at most one
+func splitRange(n, parts int) []chunk {
+	parts = min(parts, n)
+	if parts <= 0 {
+		return nil
+	}
+
+	chunks := make([]chunk, parts)
+	size, rest := n/parts, n%parts
+	begin := 0
+	for c := range chunks {
+		end := begin + size
+		if c < rest {
+			end++
+		}
+		chunks[c] = chunk{begin: begin, end: end}
+		begin = end
+	}
+	return chunks
+}
+
+// mapChunkSize is the most consecutive elements a Map worker takes at a time
+const mapChunkSize = 4096
+
+// Map applies a function to each element of a slice and stores the result in another slice.
+// out[i] always holds fn(in[i]), whatever the concurrency. maxConcurrency
+// workers, one per CPU if 0, take consecutive chunks of in in turn and write
+// the results straight into out, so the memory and goroutines Map uses
+// beyond the two slices don't grow with their length.
 func Map[T, U any](ctx context.Context, logger *log.Logger, in []T, fn func(T) (U, error), out []U, maxConcurrency int) error {
 	if len(in) != len(out) {
 		return ErrSliceLengthMismatch
 	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = runtime.NumCPU()
+	}
+
+	// Small inputs are cut finer so every worker gets a share
+	n := len(in)
+	size := min(mapChunkSize, max(1, n/(maxConcurrency*chunksPerWorker)))
+	workers := min(maxConcurrency, (n+size-1)/size)
 
 	// Create an error group
 	g, ctx := errgroup.WithContext(ctx)
+	done := ctx.Done()
+	var next atomic.Int64
 
-	// Set the maximum number of goroutines
-	if maxConcurrency > 0 {
-		g.SetLimit(maxConcurrency)
-	}
-
-	// Submit tasks for each element
-	for i := 0; i < len(in); i++ {
-		i := i // Capture loop variable
+	// Each worker claims the next chunk until none are left
+	for w := 0; w < workers; w++ {
 		g.Go(func() error {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-				result, err := fn(in[i])
-				if err != nil {
-					return err
+			for {
+				begin := int(next.Add(int64(size))) - size
+				if begin >= n {
+					return nil
+				}
+				select {
+				case <-done:
+					return ctx.Err()
+				default:
+				}
+				for i := begin; i < min(begin+size, n); i++ {
+					result, err := fn(in[i])
+					if err != nil {
+						return err
+					}
+					out[i] = result
 				}
-				out[i] = result
-				return nil
 			}
 		})
 	}
 
-	// Wait for all tasks to complete
+	// Wait for all workers to complete
 	return g.Wait()
 }
 
-// Reduce applies a reduction function to a slice
+// Reduce applies a reduction function to a slice. The combines run one at a
+// time in no particular order; TreeReduce runs them in parallel when
+// reduceFn is associative.
 func Reduce[T, U any](ctx context.Context, logger *log.Logger, in []T, mapFn func(T) (U, error), reduceFn func(U, U) U, result *U, maxConcurrency int) error {
 	// First, map all elements
 	mapped := make([]U, len(in))
@@ -119,6 +205,97 @@ func Reduce[T, U any](ctx context.Context, logger *log.Logger, in []T, mapFn fun
 	return g.Wait()
 }
 
+// Filter returns the elements of in for which pred is true, in their input
+// order. pred runs over the input with ChunkedParallelFor, recording which
+// elements to keep; they are then copied out in order. The first error pred
+// returns stops the other chunks and is returned.
+func Filter[T any](ctx context.Context, logger *log.Logger, in []T, pred func(T) (bool, error), maxConcurrency int) ([]T, error) {
+	keep := make([]bool, len(in))
+	err := ChunkedParallelFor(ctx, logger, len(in), func(i int) error {
+		ok, err := pred(in[i])
+		keep[i] = ok
+		return err
+	}, maxConcurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, ok := range keep {
+		if ok {
+			total++
+		}
+	}
+	out := make([]T, 0, total)
+	for i, ok := range keep {
+		if ok {
+			out = append(out, in[i])
+		}
+	}
+	return out, nil
+}
+
+// TreeReduce is Reduce for an associative reduceFn. The mapped elements are
+// split into maxConcurrency contiguous chunks, one per CPU if 0, reduced in
+// parallel; the partial results are then combined pairwise, level by level.
+// Elements are combined in index order, so reduceFn needn't be commutative,
+// but a non-associative reduceFn gives different results than Reduce.
+func TreeReduce[T, U any](ctx context.Context, logger *log.Logger, in []T, mapFn func(T) (U, error), reduceFn func(U, U) U, result *U, maxConcurrency int) error {
+	if maxConcurrency <= 0 {
+		maxConcurrency = runtime.NumCPU()
+	}
+
+	// First, map all elements
+	mapped := make([]U, len(in))
+	if err := Map(ctx, logger, in, mapFn, mapped, maxConcurrency); err != nil {
+		return err
+	}
+	if len(mapped) == 0 {
+		var zero U
+		*result = zero
+		return nil
+	}
+
+	// Reduce each chunk sequentially, all chunks in parallel
+	partials := make([]U, len(splitRange(len(mapped), maxConcurrency)))
+	err := runChunks(ctx, len(mapped), maxConcurrency, func(ctx context.Context, c int, r chunk) error {
+		acc := mapped[r.begin]
+		for i := r.begin + 1; i < r.end; i++ {
+			acc = reduceFn(acc, mapped[i])
+		}
+		partials[c] = acc
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Combine neighbouring partial results until one is left
+	for len(partials) > 1 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		next := make([]U, (len(partials)+1)/2)
+		var wg sync.WaitGroup
+		for i := range next {
+			if 2*i+1 == len(partials) {
+				next[i] = partials[2*i]
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				next[i] = reduceFn(partials[2*i], partials[2*i+1])
+			}()
+		}
+		wg.Wait()
+		partials = next
+	}
+
+	*result = partials[0]
+	return nil
+}
+
 // ErrSliceLengthMismatch is returned when input and output slices have different lengths
 var ErrSliceLengthMismatch = &errSliceLengthMismatch{}
 
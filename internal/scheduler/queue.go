@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+)
+
+// ErrStopped is returned when submitting a task to or starting a stopped scheduler
+var ErrStopped = errors.New("scheduler stopped")
+
+// ErrStarted is returned when starting a scheduler that is already running
+var ErrStarted = errors.New("scheduler already started")
+
+// queuedTask is a task waiting in the queue
+type queuedTask struct {
+	task *Task
+	seq  uint64 // submission order, breaking ties between equal priorities
+}
+
+// taskHeap orders queued tasks highest priority first, then by submission
+type taskHeap []queuedTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].task.Priority != h[j].task.Priority {
+		return h[i].task.Priority > h[j].task.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x any) { *h = append(*h, x.(queuedTask)) }
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = queuedTask{}
+	*h = old[:n-1]
+	return item
+}
+
+// taskQueue holds submitted tasks until the dispatcher hands them to a worker
+type taskQueue struct {
+	tasks  taskHeap
+	seq    uint64
+	closed bool
+	mu     sync.Mutex
+	cond   *sync.Cond
+}
+
+// newTaskQueue creates an empty task queue
+func newTaskQueue() *taskQueue {
+	q := &taskQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues a task, failing once the queue is closed
+func (q *taskQueue) push(task *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrStopped
+	}
+	heap.Push(&q.tasks, queuedTask{task: task, seq: q.seq})
+	q.seq++
+	q.cond.Signal()
+	return nil
+}
+
+// pop takes the highest-priority task, blocking while the queue is empty.
+// It returns nil once the queue is closed and drained.
+func (q *taskQueue) pop() *Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.tasks) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.tasks) == 0 {
+		return nil
+	}
+	return heap.Pop(&q.tasks).(queuedTask).task
+}
+
+// close stops the queue accepting tasks. Queued tasks are still popped
+// unless discard is set, in which case they are dropped.
+func (q *taskQueue) close(discard bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	if discard {
+		q.tasks = nil
+	}
+	q.cond.Broadcast()
+}
@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultRetryDelay is the delay before a task's first retry unless the task sets its own
+const DefaultRetryDelay = 100 * time.Millisecond
+
+// MaxRetryDelay caps the backoff between retries however many were made
+const MaxRetryDelay = time.Minute
+
+// ErrPermanent marks task failures that retrying can't fix; wrap it in the
+// error a task returns to fail without retries
+var ErrPermanent = errors.New("permanent failure")
+
+// backoff returns the delay before retry number retries+1, doubling from
+// base and capped at MaxRetryDelay
+func backoff(base time.Duration, retries int) time.Duration {
+	if base <= 0 {
+		base = DefaultRetryDelay
+	}
+	delay := min(base, MaxRetryDelay)
+	for ; retries > 0 && delay < MaxRetryDelay; retries-- {
+		delay *= 2
+	}
+	return min(delay, MaxRetryDelay)
+}
+
+// retry queues a failed task again after its backoff delay, unless the
+// failure is permanent or the task used up its retries or was cancelled. It
+// reports whether the task will be retried.
+func (s *Scheduler) retry(task *Task, err error) bool {
+	if errors.Is(err, ErrPermanent) || task.retries >= task.RetryLimit || task.ctx.Err() != nil {
+		return false
+	}
+
+	delay := backoff(task.RetryDelay, task.retries)
+	task.retries++
+	s.logger.Debug("retrying failed task", "task_id", task.ID, "retry", task.retries, "delay", delay, "error", err)
+
+	// Cancelling the task, or stopping the scheduler, abandons the wait; a
+	// retry that can't be queued any more reports the failure it was retrying
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-task.ctx.Done():
+			s.complete(task, fmt.Errorf("%w (retry abandoned: %w)", err, task.ctx.Err()))
+			return
+		}
+		if pushErr := s.queue.push(task); pushErr != nil {
+			s.complete(task, fmt.Errorf("%w (retry abandoned: %w)", err, pushErr))
+		}
+	}()
+	return true
+}
+
+// Retrying wraps fn so that a failing call is retried up to limit times,
+// backing off exponentially from delay, DefaultRetryDelay if 0. Failures
+// wrapping ErrPermanent are returned at once, as is ctx's error if it is
+// done while waiting to retry.
+func Retrying(ctx context.Context, fn func(i int) error, limit int, delay time.Duration) func(i int) error {
+	if limit <= 0 {
+		return fn
+	}
+	return func(i int) error {
+		for retries := 0; ; retries++ {
+			err := fn(i)
+			if err == nil || errors.Is(err, ErrPermanent) || retries >= limit {
+				return err
+			}
+
+			timer := time.NewTimer(backoff(delay, retries))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%w (retry abandoned: %w)", err, ctx.Err())
+			}
+		}
+	}
+}
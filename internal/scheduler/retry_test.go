@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_RetryFailedTask(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	// Fails twice, then succeeds
+	var calls atomic.Int32
+	task := &Task{
+		ID: "flaky",
+		Function: func(ctx context.Context) error {
+			if calls.Add(1) <= 2 {
+				return errors.New("transient")
+			}
+			return nil
+		},
+		Result:     make(chan error, 1),
+		RetryLimit: 5,
+		RetryDelay: time.Millisecond,
+	}
+	assert.NoError(t, s.SubmitTask(ctx, task))
+
+	assert.NoError(t, waitResults(t, task.Result, 1)[0])
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, 2, task.retries)
+}
+
+func TestScheduler_RetryLimitAndPermanentErrors(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	// Retries stop at the limit and the last failure is reported
+	var transientCalls atomic.Int32
+	transient := &Task{
+		ID: "transient",
+		Function: func(ctx context.Context) error {
+			return fmt.Errorf("attempt %d failed", transientCalls.Add(1))
+		},
+		Result:     make(chan error, 1),
+		RetryLimit: 2,
+		RetryDelay: time.Millisecond,
+	}
+	assert.NoError(t, s.SubmitTask(ctx, transient))
+	assert.EqualError(t, waitResults(t, transient.Result, 1)[0], "attempt 3 failed")
+
+	// Permanent failures aren't retried at all
+	var permanentCalls atomic.Int32
+	permanent := &Task{
+		ID: "permanent",
+		Function: func(ctx context.Context) error {
+			permanentCalls.Add(1)
+			return fmt.Errorf("bad input: %w", ErrPermanent)
+		},
+		Result:     make(chan error, 1),
+		RetryLimit: 2,
+		RetryDelay: time.Millisecond,
+	}
+	assert.NoError(t, s.SubmitTask(ctx, permanent))
+	assert.ErrorIs(t, waitResults(t, permanent.Result, 1)[0], ErrPermanent)
+	assert.Equal(t, int32(1), permanentCalls.Load())
+}
+
+func TestBackoff_Capped(t *testing.T) {
+	assert.Equal(t, DefaultRetryDelay, backoff(0, 0))
+	assert.Equal(t, 4*time.Millisecond, backoff(time.Millisecond, 2))
+	assert.Equal(t, MaxRetryDelay, backoff(time.Second, 63))
+	assert.Equal(t, MaxRetryDelay, backoff(time.Second, 1000))
+}
+
+func TestScheduler_StopAbandonsPendingRetry(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	// The retry would wait an hour
+	var calls atomic.Int32
+	task := &Task{
+		ID: "slow-retry",
+		Function: func(ctx context.Context) error {
+			calls.Add(1)
+			return errors.New("transient")
+		},
+		Result:     make(chan error, 1),
+		RetryLimit: 1,
+		RetryDelay: time.Hour,
+	}
+	assert.NoError(t, s.SubmitTask(ctx, task))
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop waited for the retry delay")
+	}
+
+	err := waitResults(t, task.Result, 1)[0]
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "transient")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetrying(t *testing.T) {
+	var calls atomic.Int32
+	fn := Retrying(context.Background(), func(i int) error {
+		if calls.Add(1) <= 2 {
+			return errors.New("transient")
+		}
+		return nil
+	}, 3, time.Millisecond)
+	assert.NoError(t, fn(0))
+	assert.Equal(t, int32(3), calls.Load())
+
+	// Permanent failures and the limit end the retries
+	calls.Store(0)
+	fn = Retrying(context.Background(), func(i int) error {
+		calls.Add(1)
+		return ErrPermanent
+	}, 3, time.Millisecond)
+	assert.ErrorIs(t, fn(0), ErrPermanent)
+	assert.Equal(t, int32(1), calls.Load())
+
+	calls.Store(0)
+	fn = Retrying(context.Background(), func(i int) error {
+		calls.Add(1)
+		return errors.New("always")
+	}, 2, time.Millisecond)
+	assert.EqualError(t, fn(0), "always")
+	assert.Equal(t, int32(3), calls.Load())
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/melihxz/holocompute/internal/log"
+)
+
+// ScanMode selects whether a scan's outputs include their own input
+type ScanMode int
+
+const (
+	// InclusiveScan sets out[i] to in[0] combined with everything up to in[i]
+	InclusiveScan ScanMode = iota
+
+	// ExclusiveScan sets out[i] to everything before in[i], and out[0] to the
+	// zero value of T, which must be the identity of fn
+	ExclusiveScan
+)
+
+// Scan computes the prefix combinations of in with the associative function
+// fn, storing them in out, which may be in itself. The input is split into
+// maxConcurrency blocks, one per CPU if 0: an up-sweep reduces every block
+// in parallel, the block totals are scanned, and a down-sweep then scans
+// every block in parallel starting from the total of the blocks before it.
+func Scan[T any](ctx context.Context, logger *log.Logger, in []T, fn func(T, T) T, out []T, mode ScanMode, maxConcurrency int) error {
+	if len(in) != len(out) {
+		return ErrSliceLengthMismatch
+	}
+	if maxConcurrency <= 0 {
+		maxConcurrency = runtime.NumCPU()
+	}
+	blocks := splitRange(len(in), maxConcurrency)
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	// Up-sweep: reduce every block but the last, whose total nobody needs
+	totals := make([]T, len(blocks))
+	err := runChunks(ctx, len(in), maxConcurrency, func(ctx context.Context, b int, r chunk) error {
+		if b == len(blocks)-1 {
+			return nil
+		}
+		acc := in[r.begin]
+		for i := r.begin + 1; i < r.end; i++ {
+			acc = fn(acc, in[i])
+		}
+		totals[b] = acc
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Each block starts from the combined totals of the blocks before it
+	offsets := make([]T, len(blocks))
+	for b := 1; b < len(blocks); b++ {
+		if b == 1 {
+			offsets[b] = totals[0]
+		} else {
+			offsets[b] = fn(offsets[b-1], totals[b-1])
+		}
+	}
+
+	// Down-sweep: scan every block from its offset
+	return runChunks(ctx, len(in), maxConcurrency, func(ctx context.Context, b int, r chunk) error {
+		acc, started := offsets[b], b > 0
+		for i := r.begin; i < r.end; i++ {
+			x := in[i]
+			if mode == ExclusiveScan {
+				out[i] = acc
+			}
+			if started {
+				acc = fn(acc, x)
+			} else {
+				acc, started = x, true
+			}
+			if mode == InclusiveScan {
+				out[i] = acc
+			}
+		}
+		return nil
+	})
+}
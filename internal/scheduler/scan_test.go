@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// naiveScan is the sequential prefix sum Scan must agree with
+func naiveScan(in []int, mode ScanMode) []int {
+	out := make([]int, len(in))
+	acc := 0
+	for i, x := range in {
+		if mode == ExclusiveScan {
+			out[i] = acc
+		}
+		acc += x
+		if mode == InclusiveScan {
+			out[i] = acc
+		}
+	}
+	return out
+}
+
+func TestScan_MatchesSequential(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	sum := func(a, b int) int { return a + b }
+
+	for _, n := range []int{0, 1, 2, 7, 100, 1023, 10007} {
+		in := make([]int, n)
+		for i := range in {
+			in[i] = i*7%13 - 6
+		}
+		for _, mode := range []ScanMode{InclusiveScan, ExclusiveScan} {
+			for _, concurrency := range []int{0, 1, 3, 16, 20000} {
+				out := make([]int, n)
+				assert.NoError(t, Scan(context.Background(), logger, in, sum, out, mode, concurrency))
+				assert.Equal(t, naiveScan(in, mode), out, "n=%d mode=%d concurrency=%d", n, mode, concurrency)
+			}
+		}
+	}
+}
+
+func TestScan_InPlaceAndOrder(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	// Scanning in place gives the same result
+	in := []int{3, 1, 4, 1, 5, 9, 2, 6, 5}
+	want := naiveScan(in, ExclusiveScan)
+	assert.NoError(t, Scan(context.Background(), logger, in, func(a, b int) int { return a + b }, in, ExclusiveScan, 4))
+	assert.Equal(t, want, in)
+
+	// Inputs combine in index order, so non-commutative functions work
+	words := []string{"a", "b", "c", "d", "e"}
+	out := make([]string, len(words))
+	assert.NoError(t, Scan(context.Background(), logger, words, func(a, b string) string { return a + b }, out, InclusiveScan, 2))
+	assert.Equal(t, []string{"a", "ab", "abc", "abcd", "abcde"}, out)
+
+	assert.ErrorIs(t, Scan(context.Background(), logger, words, func(a, b string) string { return a + b }, out[:2], InclusiveScan, 2), ErrSliceLengthMismatch)
+}
@@ -2,7 +2,9 @@ package scheduler
 
 import (
 	"context"
+	"runtime"
 	"sync"
+	"time"
 
 	"github.com/melihxz/holocompute/internal/log"
 )
@@ -10,94 +12,274 @@ import (
 // Task represents a unit of work to be executed
 type Task struct {
 	ID       string
-	Function func() error
+	Function func(ctx context.Context) error
 	Result   chan error
-	Cancel   context.CancelFunc
+	Cancel   context.CancelFunc // cancels ctx; set by SubmitTask
+
+	// Priority orders queued tasks; higher runs first, equal in submission order
+	Priority int
+
+	// DependsOn lists the IDs of tasks that must succeed before this one runs
+	DependsOn []string
+
+	// RetryLimit is how many times a failed task is retried; failures
+	// wrapping ErrPermanent never are. Retries back off exponentially from
+	// RetryDelay, DefaultRetryDelay if 0.
+	RetryLimit int
+	RetryDelay time.Duration
+
+	retries  int             // retries made so far
+	ctx      context.Context // passed to Function, done once the task is cancelled
+	depTimer *time.Timer     // fails the held task if a dependency never shows up
 }
 
-// Scheduler manages task execution
+// Scheduler manages task execution. Submitted tasks are held until their
+// dependencies succeeded, then pass through a priority queue onto the deques
+// of a fixed pool of workers, where idle workers steal tasks from the others.
 type Scheduler struct {
-	tasks    map[string]*Task
-	taskChan chan *Task
-	logger   *log.Logger
-	wg       sync.WaitGroup
-	mu       sync.RWMutex
+	tasks map[string]*Task // submitted tasks that haven't finished
+	queue *taskQueue
+
+	// Dependency tracking: outcomes of finished tasks, tasks held for their
+	// dependencies, and the held tasks waiting on each task
+	done       map[string]error
+	held       map[string]*Task
+	dependents map[string][]string
+
+	// Finished tasks no held task depends on, oldest first; their outcomes
+	// are forgotten beyond doneRetention. needed counts the held tasks
+	// depending on each task.
+	retired       []string
+	needed        map[string]int
+	doneRetention int
+
+	// How long a held task waits for a dependency that was never submitted
+	depTimeout time.Duration
+
+	// Every task's context derives from ctx, so cancel stops them all
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	logger  *log.Logger
+	workers int
+	pool    *pool
+	started bool
+	stopped bool
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
 }
 
-// NewScheduler creates a new task scheduler
+// NewScheduler creates a new task scheduler with one worker per CPU
 func NewScheduler(logger *log.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
-		tasks:    make(map[string]*Task),
-		taskChan: make(chan *Task, 100),
-		logger:   logger,
+		tasks:      make(map[string]*Task),
+		queue:      newTaskQueue(),
+		done:       make(map[string]error),
+		held:       make(map[string]*Task),
+		dependents: make(map[string][]string),
+		ctx:        ctx,
+		cancel:     cancel,
+		logger:     logger,
+		workers:    runtime.NumCPU(),
+
+		needed:        make(map[string]int),
+		doneRetention: DefaultDoneRetention,
+		depTimeout:    DefaultDependencyTimeout,
+	}
+}
+
+// SetWorkers sets the number of workers running tasks; 0 uses one per CPU.
+// It must be called before Start.
+func (s *Scheduler) SetWorkers(n int) {
+	if n <= 0 {
+		n = runtime.NumCPU()
 	}
+	s.workers = n
 }
 
-// Start starts the scheduler
-func (s *Scheduler) Start(ctx context.Context) {
+// Start starts the scheduler and its workers. A scheduler runs once: Start
+// fails with ErrStarted if it was already started and with ErrStopped after
+// Stop; create a new scheduler instead.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.stopped:
+		return ErrStopped
+	case s.started:
+		return ErrStarted
+	}
+	s.started = true
+
+	s.pool = newPool(s.workers)
+	for id := 0; id < s.workers; id++ {
+		s.wg.Add(1)
+		go s.work(id)
+	}
+
 	s.wg.Add(1)
 	go s.run(ctx)
+	return nil
 }
 
-// Stop stops the scheduler
+// Stop stops accepting tasks, cancels the running and queued ones, and
+// waits for them to finish. It may be called whether or not the scheduler
+// was started, and more than once.
 func (s *Scheduler) Stop() {
-	close(s.taskChan)
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.queue.close(false)
+	s.cancel()
 	s.wg.Wait()
 }
 
-// SubmitTask submits a task for execution
+// SubmitTask queues a task for execution at its priority. The task runs with
+// its own context, which CancelTask and Stop cancel. A task with
+// dependencies is held until they all succeeded, and fails with
+// ErrDependencyFailed if one of them fails. Tasks whose dependencies lead
+// back to themselves are rejected with ErrDependencyCycle.
 func (s *Scheduler) SubmitTask(ctx context.Context, task *Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
+	if cycle := s.findCycleLocked(task); cycle != nil {
+		s.mu.Unlock()
+		return cycleError(cycle)
+	}
+	task.ctx, task.Cancel = context.WithCancel(s.ctx)
 	s.tasks[task.ID] = task
+	ready, err := s.admitLocked(task)
+	if err != nil {
+		s.sendResult(task, err)
+		s.finishLocked(task.ID, err)
+	}
 	s.mu.Unlock()
-
-	select {
-	case s.taskChan <- task:
+	if !ready {
 		return nil
-	case <-ctx.Done():
-		return ctx.Err()
 	}
+
+	if err := s.queue.push(task); err != nil {
+		s.mu.Lock()
+		delete(s.tasks, task.ID)
+		s.mu.Unlock()
+		task.Cancel()
+		return err
+	}
+	return nil
 }
 
-// run executes tasks from the task channel
+// SubmitTaskWithPriority queues a task for execution at the given priority
+func (s *Scheduler) SubmitTaskWithPriority(ctx context.Context, task *Task, priority int) error {
+	task.Priority = priority
+	return s.SubmitTask(ctx, task)
+}
+
+// run moves queued tasks onto the workers' deques as they are queued,
+// highest priority first, until the queue is closed and drained or ctx is
+// done, which drops the queued tasks. Once it returns the workers finish the
+// tasks already handed to them and exit.
 func (s *Scheduler) run(ctx context.Context) {
 	defer s.wg.Done()
+	defer s.pool.close()
 
-	for {
-		select {
-		case task := <-s.taskChan:
-			if task == nil {
-				return // Channel closed
-			}
-
-			// Execute the task in a goroutine
-			go s.executeTask(task)
+	stop := context.AfterFunc(ctx, func() { s.queue.close(true) })
+	defer stop()
 
-		case <-ctx.Done():
+	for {
+		task := s.queue.pop()
+		if task == nil {
 			return
 		}
+		s.pool.push(task)
 	}
 }
 
-// executeTask executes a single task
+// work runs tasks taken from the pool until it is closed and drained
+func (s *Scheduler) work(id int) {
+	defer s.wg.Done()
+
+	for task := s.pool.take(id); task != nil; task = s.pool.take(id) {
+		s.executeTask(task)
+	}
+}
+
+// executeTask executes a single task, unless it was cancelled while queued
 func (s *Scheduler) executeTask(task *Task) {
+	if err := task.ctx.Err(); err != nil {
+		s.complete(task, err)
+		return
+	}
 	s.logger.Debug("executing task", "task_id", task.ID)
 
 	// Execute the task function
-	err := task.Function()
+	err := task.Function(task.ctx)
+	if err != nil && s.retry(task, err) {
+		return
+	}
+	s.complete(task, err)
+}
 
-	// Send the result
+// complete records a task's outcome and sends its result, queueing the tasks
+// its success released or failing those depending on it
+func (s *Scheduler) complete(task *Task, err error) {
+	s.mu.Lock()
+	ready := s.finishLocked(task.ID, err)
+	s.mu.Unlock()
+
+	s.sendResult(task, err)
+	s.logger.Debug("task completed", "task_id", task.ID, "error", err)
+
+	s.pushReady(ready)
+}
+
+// pushReady queues tasks whose dependencies all succeeded
+func (s *Scheduler) pushReady(ready []*Task) {
+	for _, next := range ready {
+		if err := s.queue.push(next); err != nil {
+			s.sendResult(next, err)
+		}
+	}
+}
+
+// CancelTask cancels a submitted task's context, interrupting it if it is
+// running; a queued or held task finishes with context.Canceled without
+// running. It reports whether the task was still unfinished.
+func (s *Scheduler) CancelTask(id string) bool {
+	s.mu.Lock()
+	task, exists := s.tasks[id]
+	if !exists {
+		s.mu.Unlock()
+		return false
+	}
+	task.Cancel()
+
+	// Held tasks never reach a worker, so they are finished here
+	_, held := s.held[id]
+	if !held {
+		s.mu.Unlock()
+		return true
+	}
+	s.releaseLocked(task)
+	ready := s.finishLocked(id, context.Canceled)
+	s.mu.Unlock()
+
+	s.sendResult(task, context.Canceled)
+	s.pushReady(ready)
+	return true
+}
+
+// sendResult delivers a task's result without blocking
+func (s *Scheduler) sendResult(task *Task, err error) {
 	select {
 	case task.Result <- err:
 	default:
 		// Result channel is full or closed
 		s.logger.Warn("task result channel is full or closed", "task_id", task.ID)
 	}
-
-	// Remove the task from the map
-	s.mu.Lock()
-	delete(s.tasks, task.ID)
-	s.mu.Unlock()
-
-	s.logger.Debug("task completed", "task_id", task.ID, "error", err)
 }
@@ -2,7 +2,10 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -23,7 +26,7 @@ func TestScheduler_SubmitTask(t *testing.T) {
 	// Create a task
 	task := &Task{
 		ID:       "test-task",
-		Function: func() error { time.Sleep(time.Millisecond * 10); return nil },
+		Function: func(ctx context.Context) error { time.Sleep(time.Millisecond * 10); return nil },
 		Result:   make(chan error, 1),
 	}
 
@@ -84,6 +87,34 @@ func TestMap(t *testing.T) {
 	assert.Equal(t, expected, out)
 }
 
+func TestMap_LargeInputBoundedMemory(t *testing.T) {
+	logger := log.New(slog.LevelError)
+	const n = 1 << 22
+
+	in := make([]int64, n)
+	for i := range in {
+		in[i] = int64(i)
+	}
+	out := make([]int64, n)
+	fn := func(x int64) (int64, error) { return x*x + 1, nil }
+
+	assert.NoError(t, Map(context.Background(), logger, in, fn, out, 8))
+	for i := range out {
+		if !assert.Equal(t, int64(i)*int64(i)+1, out[i], "index %d", i) {
+			break
+		}
+	}
+
+	// A few allocations per worker, however long the input; a goroutine
+	// per element would allocate millions
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	assert.NoError(t, Map(context.Background(), logger, in, fn, out, 8))
+	runtime.ReadMemStats(&after)
+	assert.Less(t, after.Mallocs-before.Mallocs, uint64(1000))
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+}
+
 func TestReduce(t *testing.T) {
 	logger := log.New(slog.LevelDebug)
 	ctx := context.Background()
@@ -106,3 +137,337 @@ func TestReduce(t *testing.T) {
 	// Verify result
 	assert.Equal(t, 15, result) // 1+2+3+4+5 = 15
 }
+
+func TestMap_PreservesOrder(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	const n = 10000
+
+	in := make([]int, n)
+	for i := range in {
+		in[i] = i
+	}
+
+	// Later inputs finish first, so completion order differs from index order
+	fn := func(x int) (int, error) {
+		if x%7 == 0 {
+			runtime.Gosched()
+		}
+		return x*3 + 1, nil
+	}
+
+	for _, concurrency := range []int{0, 1, 64} {
+		out := make([]int, n)
+		assert.NoError(t, Map(context.Background(), logger, in, fn, out, concurrency))
+		for i := range out {
+			if !assert.Equal(t, i*3+1, out[i], "index %d at concurrency %d", i, concurrency) {
+				break
+			}
+		}
+	}
+}
+
+func TestScheduler_Priority(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	s := NewScheduler(logger)
+	s.SetWorkers(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	// Saturate the only worker
+	started, release := make(chan struct{}), make(chan struct{})
+	results := make(chan error, 3)
+	assert.NoError(t, s.SubmitTask(ctx, &Task{
+		ID:       "blocker",
+		Function: func(ctx context.Context) error { close(started); <-release; return nil },
+		Result:   results,
+	}))
+	<-started
+
+	// The high-priority task is submitted last but runs first
+	var order []string
+	record := func(id string) func(ctx context.Context) error {
+		return func(ctx context.Context) error { order = append(order, id); return nil }
+	}
+	assert.NoError(t, s.SubmitTaskWithPriority(ctx, &Task{ID: "low", Function: record("low"), Result: results}, 0))
+	assert.NoError(t, s.SubmitTaskWithPriority(ctx, &Task{ID: "high", Function: record("high"), Result: results}, 10))
+	close(release)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-results:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("tasks did not complete within timeout")
+		}
+	}
+	assert.Equal(t, []string{"high", "low"}, order)
+}
+
+func TestTaskQueue_EqualPrioritiesKeepOrder(t *testing.T) {
+	q := newTaskQueue()
+	for _, task := range []*Task{{ID: "a"}, {ID: "b", Priority: 1}, {ID: "c"}, {ID: "d", Priority: 1}} {
+		assert.NoError(t, q.push(task))
+	}
+	q.close(false)
+
+	var order []string
+	for task := q.pop(); task != nil; task = q.pop() {
+		order = append(order, task.ID)
+	}
+	assert.Equal(t, []string{"b", "d", "a", "c"}, order)
+	assert.ErrorIs(t, q.push(&Task{ID: "e"}), ErrStopped)
+}
+
+func TestScheduler_CancelTask(t *testing.T) {
+	s := NewScheduler(log.New(slog.LevelDebug))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	started := make(chan struct{})
+	running := &Task{
+		ID: "long",
+		Function: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Result: make(chan error, 1),
+	}
+	assert.NoError(t, s.SubmitTask(ctx, running))
+	<-started
+
+	assert.True(t, s.CancelTask("long"))
+	select {
+	case err := <-running.Result:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("cancelled task did not return")
+	}
+	assert.False(t, s.CancelTask("long"))
+
+	// Stop interrupts tasks still running
+	stopped := &Task{
+		ID: "until-stop",
+		Function: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Result: make(chan error, 1),
+	}
+	assert.NoError(t, s.SubmitTask(ctx, stopped))
+	s.Stop()
+	assert.ErrorIs(t, <-stopped.Result, context.Canceled)
+}
+
+func TestChunkedParallelFor(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	// Every index runs exactly once, whatever the chunk count
+	for _, concurrency := range []int{0, 1, 3, 7, 200} {
+		counts := make([]int32, 100)
+		err := ChunkedParallelFor(context.Background(), logger, len(counts), func(i int) error {
+			atomic.AddInt32(&counts[i], 1)
+			return nil
+		}, concurrency)
+		assert.NoError(t, err)
+		for i, c := range counts {
+			assert.Equal(t, int32(1), c, "index %d at concurrency %d", i, concurrency)
+		}
+	}
+
+	// The first error stops the other chunks and is returned
+	boom := errors.New("boom")
+	var calls atomic.Int64
+	err := ChunkedParallelFor(context.Background(), logger, 1_000_000, func(i int) error {
+		calls.Add(1)
+		if i == 10 {
+			return boom
+		}
+		return nil
+	}, 4)
+	assert.ErrorIs(t, err, boom)
+	assert.Less(t, calls.Load(), int64(1_000_000))
+
+	// A cancelled context runs nothing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = ChunkedParallelFor(ctx, logger, 100, func(i int) error { return nil }, 4)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func BenchmarkParallelFor_CheapBody(b *testing.B) {
+	logger := log.New(slog.LevelError)
+	const n = 10_000_000
+	out := make([]int, n)
+	fn := func(i int) error {
+		out[i] = i * 2
+		return nil
+	}
+
+	b.Run("per-index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := ParallelFor(context.Background(), logger, n, fn, runtime.NumCPU()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("chunked", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := ChunkedParallelFor(context.Background(), logger, n, fn, runtime.NumCPU()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMap_Large(b *testing.B) {
+	logger := log.New(slog.LevelError)
+	const n = 10_000_000
+	in := make([]int64, n)
+	out := make([]int64, n)
+	fn := func(x int64) (int64, error) { return x * 2, nil }
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Map(context.Background(), logger, in, fn, out, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTreeReduce(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	const n = 1_000_000
+
+	in := make([]int, n)
+	for i := range in {
+		in[i] = i + 1
+	}
+	identity := func(x int) (int, error) { return x, nil }
+	sum := func(a, b int) int { return a + b }
+
+	for _, concurrency := range []int{0, 1, 3, 64} {
+		var result int
+		assert.NoError(t, TreeReduce(context.Background(), logger, in, identity, sum, &result, concurrency))
+		assert.Equal(t, n*(n+1)/2, result, "concurrency %d", concurrency)
+	}
+
+	// Elements combine in index order, so associative but non-commutative functions work
+	words := []string{"a", "b", "c", "d", "e", "f", "g"}
+	var joined string
+	concat := func(a, b string) string { return a + b }
+	assert.NoError(t, TreeReduce(context.Background(), logger, words, func(s string) (string, error) { return s, nil }, concat, &joined, 3))
+	assert.Equal(t, "abcdefg", joined)
+
+	// An empty input reduces to the zero value
+	result := 42
+	assert.NoError(t, TreeReduce(context.Background(), logger, nil, identity, sum, &result, 4))
+	assert.Equal(t, 0, result)
+}
+
+func BenchmarkReduce_Sum(b *testing.B) {
+	logger := log.New(slog.LevelError)
+	const n = 1_000_000
+
+	in := make([]int, n)
+	for i := range in {
+		in[i] = i
+	}
+	identity := func(x int) (int, error) { return x, nil }
+	sum := func(a, b int) int { return a + b }
+
+	b.Run("mutex", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var result int
+			if err := Reduce(context.Background(), logger, in, identity, sum, &result, runtime.NumCPU()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("tree", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var result int
+			if err := TreeReduce(context.Background(), logger, in, identity, sum, &result, runtime.NumCPU()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestScheduler_StopWithoutStart(t *testing.T) {
+	ctx := context.Background()
+
+	// Stopping a scheduler that never started, twice, is harmless, and it
+	// can't be started afterwards
+	s := NewScheduler(log.New(slog.LevelDebug))
+	s.Stop()
+	s.Stop()
+	assert.ErrorIs(t, s.Start(ctx), ErrStopped)
+	assert.ErrorIs(t, s.SubmitTask(ctx, &Task{ID: "late", Function: func(ctx context.Context) error { return nil }, Result: make(chan error, 1)}), ErrStopped)
+
+	// A running scheduler can't be started again, and stops twice
+	s = NewScheduler(log.New(slog.LevelDebug))
+	assert.NoError(t, s.Start(ctx))
+	assert.ErrorIs(t, s.Start(ctx), ErrStarted)
+	s.Stop()
+	s.Stop()
+	assert.ErrorIs(t, s.Start(ctx), ErrStopped)
+}
+
+func TestFilter(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ctx := context.Background()
+
+	in := make([]int, 10007)
+	for i := range in {
+		in[i] = i
+	}
+	even := func(x int) (bool, error) { return x%2 == 0, nil }
+
+	// Survivors keep their input order whatever the chunking
+	for _, concurrency := range []int{0, 1, 3, 64} {
+		out, err := Filter(ctx, logger, in, even, concurrency)
+		assert.NoError(t, err)
+		assert.Len(t, out, 5004)
+		for i, x := range out {
+			if !assert.Equal(t, 2*i, x, "concurrency %d", concurrency) {
+				break
+			}
+		}
+	}
+
+	// Empty input
+	out, err := Filter(ctx, logger, []int{}, even, 4)
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+
+	// All pass
+	out, err = Filter(ctx, logger, in, func(int) (bool, error) { return true, nil }, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, in, out)
+
+	// None pass
+	out, err = Filter(ctx, logger, in, func(int) (bool, error) { return false, nil }, 4)
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+
+	// The first predicate error stops the filter
+	boom := errors.New("boom")
+	var calls atomic.Int64
+	out, err = Filter(ctx, logger, make([]int, 1_000_000), func(x int) (bool, error) {
+		if calls.Add(1) == 100 {
+			return false, boom
+		}
+		return true, nil
+	}, 4)
+	assert.ErrorIs(t, err, boom)
+	assert.Nil(t, out)
+	assert.Less(t, calls.Load(), int64(1_000_000))
+}
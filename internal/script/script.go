@@ -0,0 +1,289 @@
+// Package script executes holo run scripts against the memory manager
+package script
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+)
+
+// Script syntax, one directive per line ('#' starts a comment):
+//
+//	alloc <name> <length>        allocate an int64 array
+//	fill <name> <start> <step>   set element i to start + i*step
+//	reduce <sum|min|max> <name>  reduce the array to a single value
+//	free <name>                  delete the array
+
+// elementsPerPage is the number of int64 elements in a page
+const elementsPerPage = dsm.PageSize / 8
+
+// Directive is a single parsed script line
+type Directive struct {
+	Line int
+	Op   string
+	Args []string
+}
+
+// String returns the directive as written
+func (d Directive) String() string {
+	return strings.Join(append([]string{d.Op}, d.Args...), " ")
+}
+
+// DirectiveResult is the outcome of executing one directive
+type DirectiveResult struct {
+	Line      int    `json:"line"`
+	Directive string `json:"directive"`
+	ArrayID   string `json:"array_id,omitempty"`
+	Value     *int64 `json:"value,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Skipped   bool   `json:"skipped,omitempty"`
+}
+
+// ScriptResult holds the per-directive outcomes of a script run
+type ScriptResult struct {
+	Directives []DirectiveResult `json:"directives"`
+	Failed     int               `json:"failed"`
+}
+
+// OK returns true if every directive succeeded
+func (r *ScriptResult) OK() bool {
+	return r.Failed == 0
+}
+
+// WriteText renders the result as human-readable text
+func (r *ScriptResult) WriteText(w io.Writer) error {
+	for _, d := range r.Directives {
+		var status string
+		switch {
+		case d.Skipped:
+			status = "skipped"
+		case d.Error != "":
+			status = "error: " + d.Error
+		case d.Value != nil:
+			status = fmt.Sprintf("value=%d", *d.Value)
+		case d.ArrayID != "":
+			status = "array_id=" + d.ArrayID
+		default:
+			status = "ok"
+		}
+		if _, err := fmt.Fprintf(w, "%4d  %-40s %s\n", d.Line, d.Directive, status); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d directives, %d failed\n", len(r.Directives), r.Failed)
+	return err
+}
+
+// WriteJSON renders the result as indented JSON
+func (r *ScriptResult) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Parse reads directives from a script
+func Parse(r io.Reader) ([]Directive, error) {
+	var directives []Directive
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		directives = append(directives, Directive{Line: line, Op: fields[0], Args: fields[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	return directives, nil
+}
+
+// Executor runs script directives against a memory manager
+type Executor struct {
+	mm     *dsm.MemoryManager
+	local  hyperbus.NodeID
+	arrays map[string]*dsm.Array
+	logger *log.Logger
+
+	// ContinueOnError keeps executing after a directive fails
+	ContinueOnError bool
+}
+
+// NewExecutor creates a new script executor
+func NewExecutor(mm *dsm.MemoryManager, local hyperbus.NodeID, logger *log.Logger) *Executor {
+	return &Executor{
+		mm:     mm,
+		local:  local,
+		arrays: make(map[string]*dsm.Array),
+		logger: logger,
+	}
+}
+
+// Execute runs the directives in order and collects their results
+func (e *Executor) Execute(ctx context.Context, directives []Directive) *ScriptResult {
+	result := &ScriptResult{Directives: make([]DirectiveResult, 0, len(directives))}
+	halted := false
+
+	for _, d := range directives {
+		dr := DirectiveResult{Line: d.Line, Directive: d.String()}
+		if halted {
+			dr.Skipped = true
+			result.Directives = append(result.Directives, dr)
+			continue
+		}
+
+		if err := e.execute(ctx, d, &dr); err != nil {
+			e.logger.Debug("script directive failed", "line", d.Line, "error", err)
+			dr.Error = err.Error()
+			result.Failed++
+			halted = !e.ContinueOnError
+		}
+		result.Directives = append(result.Directives, dr)
+	}
+
+	return result
+}
+
+// execute runs a single directive
+func (e *Executor) execute(ctx context.Context, d Directive, dr *DirectiveResult) error {
+	switch d.Op {
+	case "alloc":
+		if len(d.Args) != 2 {
+			return fmt.Errorf("usage: alloc <name> <length>")
+		}
+		if _, exists := e.arrays[d.Args[0]]; exists {
+			return fmt.Errorf("array %s already exists", d.Args[0])
+		}
+		length, err := strconv.Atoi(d.Args[1])
+		if err != nil || length <= 0 {
+			return fmt.Errorf("invalid length: %s", d.Args[1])
+		}
+		array, err := e.mm.CreateArray(ctx, length, dsm.WithPlacement([]hyperbus.NodeID{e.local}))
+		if err != nil {
+			return err
+		}
+		e.arrays[d.Args[0]] = array
+		dr.ArrayID = string(array.ID)
+		return nil
+
+	case "fill":
+		if len(d.Args) != 3 {
+			return fmt.Errorf("usage: fill <name> <start> <step>")
+		}
+		array, err := e.array(d.Args[0])
+		if err != nil {
+			return err
+		}
+		start, err := strconv.ParseInt(d.Args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid start: %s", d.Args[1])
+		}
+		step, err := strconv.ParseInt(d.Args[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid step: %s", d.Args[2])
+		}
+		dr.ArrayID = string(array.ID)
+		return e.forEach(ctx, array, func(page *dsm.Page, index, offset int) error {
+			return e.mm.WriteElement(array.ID, dsm.ElementInt64, page, offset, func(page *dsm.Page, offset int) error {
+				return page.SetInt64(offset, start+int64(index)*step)
+			})
+		})
+
+	case "reduce":
+		if len(d.Args) != 2 {
+			return fmt.Errorf("usage: reduce <sum|min|max> <name>")
+		}
+		array, err := e.array(d.Args[1])
+		if err != nil {
+			return err
+		}
+		var acc int64
+		var combine func(a, b int64) int64
+		switch d.Args[0] {
+		case "sum":
+			combine = func(a, b int64) int64 { return a + b }
+		case "min":
+			acc = math.MaxInt64
+			combine = func(a, b int64) int64 { return min(a, b) }
+		case "max":
+			acc = math.MinInt64
+			combine = func(a, b int64) int64 { return max(a, b) }
+		default:
+			return fmt.Errorf("unknown reduction: %s", d.Args[0])
+		}
+		err = e.forEach(ctx, array, func(page *dsm.Page, index, offset int) error {
+			v, err := page.GetInt64(offset)
+			if err != nil {
+				return err
+			}
+			acc = combine(acc, v)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		dr.ArrayID = string(array.ID)
+		dr.Value = &acc
+		return nil
+
+	case "free":
+		if len(d.Args) != 1 {
+			return fmt.Errorf("usage: free <name>")
+		}
+		array, err := e.array(d.Args[0])
+		if err != nil {
+			return err
+		}
+		if err := e.mm.DeleteArray(ctx, array.ID); err != nil {
+			return err
+		}
+		delete(e.arrays, d.Args[0])
+		dr.ArrayID = string(array.ID)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown directive: %s", d.Op)
+	}
+}
+
+// array looks up an array allocated by the script
+func (e *Executor) array(name string) (*dsm.Array, error) {
+	array, exists := e.arrays[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown array: %s", name)
+	}
+	return array, nil
+}
+
+// forEach visits every element of an array page by page
+func (e *Executor) forEach(ctx context.Context, array *dsm.Array, fn func(page *dsm.Page, index, offset int) error) error {
+	for p := 0; p < array.PageCount(); p++ {
+		page, err := e.mm.RequestPage(ctx, array.ID, dsm.PageID(p), array.Version)
+		if err != nil {
+			return err
+		}
+		first := p * elementsPerPage
+		last := min(first+elementsPerPage, array.Len())
+		for i := first; i < last; i++ {
+			if err := fn(page, i, i-first); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const testScript = `
+# allocate two arrays spanning several pages
+alloc a 20000
+fill a 1 1
+reduce sum a
+reduce max a
+alloc b 10
+bogus b
+fill b 5 0
+reduce min b
+free a
+`
+
+func newTestExecutor() *Executor {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "local-node"}, nil, logger)
+	mm := dsm.NewMemoryManager(bus, logger)
+	return NewExecutor(mm, "local-node", logger)
+}
+
+func TestParse(t *testing.T) {
+	directives, err := Parse(strings.NewReader(testScript))
+	assert.NoError(t, err)
+	assert.Len(t, directives, 9)
+	assert.Equal(t, Directive{Line: 3, Op: "alloc", Args: []string{"a", "20000"}}, directives[0])
+}
+
+func TestExecutor_ContinueOnError(t *testing.T) {
+	directives, err := Parse(strings.NewReader(testScript))
+	assert.NoError(t, err)
+
+	executor := newTestExecutor()
+	executor.ContinueOnError = true
+	result := executor.Execute(context.Background(), directives)
+
+	assert.Len(t, result.Directives, 9)
+	assert.Equal(t, 1, result.Failed)
+	assert.False(t, result.OK())
+
+	// alloc reports the array ID
+	arrayID := result.Directives[0].ArrayID
+	assert.NotEmpty(t, arrayID)
+
+	// sum of 1..20000
+	assert.Equal(t, int64(20000*20001/2), *result.Directives[2].Value)
+	assert.Equal(t, int64(20000), *result.Directives[3].Value)
+
+	// the unknown directive fails, execution continues
+	assert.Contains(t, result.Directives[5].Error, "unknown directive")
+	assert.Equal(t, int64(5), *result.Directives[7].Value)
+
+	// free reports the freed array
+	assert.Equal(t, arrayID, result.Directives[8].ArrayID)
+	assert.Empty(t, result.Directives[8].Error)
+
+	// JSON output is parseable and carries the values
+	var buf bytes.Buffer
+	assert.NoError(t, result.WriteJSON(&buf))
+	var decoded ScriptResult
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, *result.Directives[2].Value, *decoded.Directives[2].Value)
+	assert.Equal(t, 1, decoded.Failed)
+}
+
+func TestExecutor_HaltOnError(t *testing.T) {
+	directives, err := Parse(strings.NewReader(testScript))
+	assert.NoError(t, err)
+
+	executor := newTestExecutor()
+	result := executor.Execute(context.Background(), directives)
+
+	assert.Equal(t, 1, result.Failed)
+	assert.NotEmpty(t, result.Directives[5].Error)
+
+	// Everything after the failure is skipped
+	for _, d := range result.Directives[6:] {
+		assert.True(t, d.Skipped)
+		assert.Nil(t, d.Value)
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, result.WriteText(&buf))
+	assert.Contains(t, buf.String(), "skipped")
+	assert.Contains(t, buf.String(), "9 directives, 1 failed")
+}
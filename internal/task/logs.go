@@ -0,0 +1,143 @@
+package task
+
+import (
+	"context"
+	"sync"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// logRelayBuffer is how many log lines a task can write ahead of the relay
+// before further lines are dropped from the stream
+const logRelayBuffer = 256
+
+// logRelay sends the lines a task logs to its submitter in order, from its
+// own goroutine so a slow submitter never blocks the task
+type logRelay struct {
+	sender    Sender
+	submitter hyperbus.NodeID
+	taskID    string
+	lines     chan string
+	done      chan struct{}
+	sent      uint64 // lines delivered, and the seq of the next one
+	dropped   int
+	logger    *log.Logger
+}
+
+// newLogRelay starts relaying a task's log lines to its submitter
+func newLogRelay(sender Sender, submitter hyperbus.NodeID, taskID string, logger *log.Logger) *logRelay {
+	r := &logRelay{
+		sender:    sender,
+		submitter: submitter,
+		taskID:    taskID,
+		lines:     make(chan string, logRelayBuffer),
+		done:      make(chan struct{}),
+		logger:    logger,
+	}
+	go r.run()
+	return r
+}
+
+// emit queues a line without blocking, dropping it if the relay is behind.
+// The result carries the whole log anyway.
+func (r *logRelay) emit(line string) {
+	select {
+	case r.lines <- line:
+	default:
+		r.dropped++
+	}
+}
+
+// flush waits until every queued line was sent and returns how many were
+// delivered. The task must have stopped logging.
+func (r *logRelay) flush() uint64 {
+	close(r.lines)
+	<-r.done
+	if r.dropped > 0 {
+		r.logger.Warn("dropped task log lines the submitter could not keep up with", "task_id", r.taskID, "dropped", r.dropped)
+	}
+	return r.sent
+}
+
+// run sends queued lines one at a time, numbering those delivered
+func (r *logRelay) run() {
+	defer close(r.done)
+
+	for line := range r.lines {
+		msg, err := hyperbus.EncodeMessage(hyperbus.MsgTaskLog, &proto.TaskLog{TaskId: r.taskID, Line: line, Seq: r.sent})
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultCancelGrace)
+			err = r.sender.SendControlMessage(ctx, r.submitter, msg)
+			cancel()
+		}
+		if err != nil {
+			r.logger.Debug("failed to relay task log", "task_id", r.taskID, "error", err)
+			continue
+		}
+		r.sent++
+	}
+}
+
+// logStream delivers a task's relayed lines to its handler in seq order,
+// holding back lines that overtook earlier ones
+type logStream struct {
+	onLog   func(line string)
+	next    uint64            // seq of the next line to deliver
+	early   map[uint64]string // lines received ahead of next
+	want    uint64            // lines the result says were relayed
+	known   bool              // whether want was set
+	flushed chan struct{}     // closed once want lines were delivered
+	closed  bool
+	mu      sync.Mutex
+}
+
+// newLogStream creates the stream of a task whose lines go to onLog
+func newLogStream(onLog func(line string)) *logStream {
+	return &logStream{
+		onLog:   onLog,
+		early:   make(map[uint64]string),
+		flushed: make(chan struct{}),
+	}
+}
+
+// deliver passes a line, and any held back lines it unblocks, to the handler
+func (s *logStream) deliver(seq uint64, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if seq < s.next {
+		return
+	}
+	s.early[seq] = line
+	for {
+		line, exists := s.early[s.next]
+		if !exists {
+			break
+		}
+		delete(s.early, s.next)
+		s.next++
+		s.onLog(line)
+	}
+	s.checkFlushedLocked()
+}
+
+// expect records how many lines were relayed in all and returns a channel
+// closed once they were delivered
+func (s *logStream) expect(lines uint64) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.want, s.known = lines, true
+	s.checkFlushedLocked()
+	return s.flushed
+}
+
+// checkFlushedLocked closes flushed once the expected lines were delivered.
+// The caller must hold mu.
+func (s *logStream) checkFlushedLocked() {
+	if s.known && !s.closed && s.next >= s.want {
+		close(s.flushed)
+		s.closed = true
+	}
+}
@@ -0,0 +1,190 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/deadline"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/scheduler"
+	"github.com/melihxz/holocompute/pkg/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// RangeExecutor runs a task's function for each index of a range on the local node
+type RangeExecutor interface {
+	// ExecuteRange calls the function with each index from begin to end-1
+	// in order, reporting progress like scheduler.RunRange. A failing call
+	// is returned as a *scheduler.IterationError.
+	ExecuteRange(ctx context.Context, submit *proto.TaskSubmit, begin, end int, progress func(done int), interval time.Duration) error
+}
+
+// pendingRange is a range run waiting for its worker's result
+type pendingRange struct {
+	progress func(done int)
+	result   chan *proto.RangeResult
+}
+
+// RunRange sends part of a distributed loop to a worker and blocks until it
+// reports a result, passing the progress it reports to progress. If ctx is
+// done first the worker is told to stop and ctx.Err() is returned.
+func (c *Client) RunRange(ctx context.Context, nodeID hyperbus.NodeID, run *proto.RangeRun, progress func(done int)) (*proto.RangeResult, error) {
+	c.mu.Lock()
+	timeout := c.timeout
+	c.mu.Unlock()
+
+	ctx, cancel := deadline.WithDefault(ctx, timeout)
+	defer cancel()
+
+	run = protobuf.Clone(run).(*proto.RangeRun)
+	if deadline, ok := ctx.Deadline(); ok {
+		run.Task.DeadlineUnixNano = deadline.UnixNano()
+		run.Task.TimeoutNanos = max(int64(time.Until(deadline)), 1)
+	}
+
+	taskID := run.Task.TaskId
+	pending := &pendingRange{progress: progress, result: make(chan *proto.RangeResult, 1)}
+	c.mu.Lock()
+	if _, exists := c.ranges[taskID]; exists {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("range already pending: %s", taskID)
+	}
+	c.ranges[taskID] = pending
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.ranges, taskID)
+		c.mu.Unlock()
+	}()
+
+	msg, err := hyperbus.EncodeMessage(hyperbus.MsgRangeRun, run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode range: %w", err)
+	}
+	if err := c.sender.SendControlMessage(ctx, nodeID, msg); err != nil {
+		return nil, fmt.Errorf("failed to send range: %w", err)
+	}
+
+	select {
+	case result := <-pending.result:
+		return result, nil
+	case <-ctx.Done():
+	}
+
+	cancelCtx, cancelSend := context.WithTimeout(context.Background(), c.cancelGrace)
+	defer cancelSend()
+	if err := c.sendCancel(cancelCtx, nodeID, taskID, ctx.Err().Error()); err != nil {
+		c.logger.Warn("failed to cancel remote range", "task_id", taskID, "error", err)
+	}
+	return nil, ctx.Err()
+}
+
+// handleRangeProgress passes a worker's progress to its range's callback
+func (c *Client) handleRangeProgress(data []byte) error {
+	var progress proto.RangeProgress
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &progress); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	pending, exists := c.ranges[progress.TaskId]
+	c.mu.Unlock()
+
+	if exists && pending.progress != nil {
+		pending.progress(int(progress.Done))
+	}
+	return nil
+}
+
+// handleRangeResult delivers a range's result to its waiting run
+func (c *Client) handleRangeResult(data []byte) error {
+	var result proto.RangeResult
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &result); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	pending, exists := c.ranges[result.TaskId]
+	c.mu.Unlock()
+
+	if !exists {
+		c.logger.Debug("dropping result for unknown range", "task_id", result.TaskId)
+		return nil
+	}
+	select {
+	case pending.result <- &result:
+	default:
+		c.logger.Warn("duplicate range result", "task_id", result.TaskId)
+	}
+	return nil
+}
+
+// startRange runs part of a distributed loop in the background. A range
+// the executor can't run fails at once, so the submitter can move it.
+func (w *Worker) startRange(submitter hyperbus.NodeID, run *proto.RangeRun) error {
+	if run.Task == nil {
+		return errors.New("range run without a task")
+	}
+	executor, ok := w.executor.(RangeExecutor)
+	if !ok {
+		go w.sendRangeResult(submitter, &proto.RangeResult{TaskId: run.Task.TaskId, Error: "executor can't run ranges"})
+		return nil
+	}
+
+	ctx, err := w.track(submitter, run.Task)
+	if err != nil {
+		return err
+	}
+	go w.runRange(ctx, executor, submitter, run)
+	return nil
+}
+
+// runRange executes a range, relaying its progress, and reports its result
+// to the submitter
+func (w *Worker) runRange(ctx context.Context, executor RangeExecutor, submitter hyperbus.NodeID, run *proto.RangeRun) {
+	taskID := run.Task.TaskId
+	progress := func(done int) {
+		msg, err := hyperbus.EncodeMessage(hyperbus.MsgRangeProgress, &proto.RangeProgress{TaskId: taskID, Done: int64(done)})
+		if err == nil {
+			err = w.sender.SendControlMessage(ctx, submitter, msg)
+		}
+		if err != nil {
+			w.logger.Debug("failed to report range progress", "task_id", taskID, "error", err)
+		}
+	}
+
+	w.logger.Debug("running range", "task_id", taskID, "begin", run.Begin, "end", run.End, "submitter", submitter)
+	err := executor.ExecuteRange(ctx, run.Task, int(run.Begin), int(run.End), progress, time.Duration(run.ProgressIntervalNanos))
+	w.untrack(taskID)
+
+	result := &proto.RangeResult{TaskId: taskID}
+	if err != nil {
+		result.Error = err.Error()
+		var iterErr *scheduler.IterationError
+		if errors.As(err, &iterErr) {
+			result.IterationFailed = true
+			result.FailedIndex = int64(iterErr.Index)
+			result.Error = iterErr.Err.Error()
+		}
+	}
+	w.sendRangeResult(submitter, result)
+}
+
+// sendRangeResult reports a range's result to its submitter
+func (w *Worker) sendRangeResult(submitter hyperbus.NodeID, result *proto.RangeResult) {
+	msg, err := hyperbus.EncodeMessage(hyperbus.MsgRangeResult, result)
+	if err != nil {
+		w.logger.Error("failed to encode range result", "task_id", result.TaskId, "error", err)
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(context.Background(), DefaultCancelGrace)
+	defer cancel()
+
+	if err := w.sender.SendControlMessage(sendCtx, submitter, msg); err != nil {
+		w.logger.Error("failed to send range result", "task_id", result.TaskId, "error", err)
+	}
+}
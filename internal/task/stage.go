@@ -0,0 +1,34 @@
+package task
+
+import (
+	"context"
+	"fmt"
+)
+
+// Stage is a step in a task's lifecycle
+type Stage string
+
+const (
+	// StageSchedule covers handing the task to a worker
+	StageSchedule Stage = "scheduling"
+	// StageModuleFetch covers the worker obtaining the task's module
+	StageModuleFetch Stage = "fetching module"
+	// StageExecute covers running the module
+	StageExecute Stage = "executing"
+	// StageResultTransfer covers reporting the result back to the submitter
+	StageResultTransfer Stage = "transferring result"
+)
+
+// StageTimeoutError is returned when a task's deadline passes during a stage
+type StageTimeoutError struct {
+	Stage Stage
+}
+
+func (e *StageTimeoutError) Error() string {
+	return fmt.Sprintf("timed out while %s", e.Stage)
+}
+
+// Unwrap lets callers match the error against context.DeadlineExceeded
+func (e *StageTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
@@ -0,0 +1,531 @@
+// Package task implements remote task submission and execution
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/deadline"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// DefaultCancelGrace is how long a submitter waits for a worker to confirm cancellation
+const DefaultCancelGrace = 5 * time.Second
+
+// DefaultSubmitTimeout bounds a submitted task when the caller sets no deadline
+const DefaultSubmitTimeout = 10 * time.Minute
+
+// Sender sends encoded messages to other nodes
+type Sender interface {
+	// SendControlMessage sends a message to a specific node
+	SendControlMessage(ctx context.Context, nodeID hyperbus.NodeID, msg []byte) error
+}
+
+// Executor runs a task's module on the local node
+type Executor interface {
+	// Execute runs the task until it completes or ctx is cancelled
+	Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error)
+}
+
+// logHandlerKey is the context key of the handler a task's log lines go to
+type logHandlerKey struct{}
+
+// WithLogHandler returns a context under which executors pass each line the
+// task logs to handle as soon as it's written
+func WithLogHandler(ctx context.Context, handle func(line string)) context.Context {
+	return context.WithValue(ctx, logHandlerKey{}, handle)
+}
+
+// LogHandler returns the handler set with WithLogHandler, or nil
+func LogHandler(ctx context.Context) func(line string) {
+	handle, _ := ctx.Value(logHandlerKey{}).(func(line string))
+	return handle
+}
+
+// Client submits tasks to remote workers and waits for their results
+type Client struct {
+	sender      Sender
+	pending     map[string]chan *proto.TaskResult
+	logs        map[string]*logStream // log streams of pending tasks
+	ranges      map[string]*pendingRange
+	cancelGrace time.Duration
+	timeout     time.Duration // default deadline for Submit
+	logger      *log.Logger
+	mu          sync.Mutex
+}
+
+// NewClient creates a new task client
+func NewClient(sender Sender, logger *log.Logger) *Client {
+	return &Client{
+		sender:      sender,
+		pending:     make(map[string]chan *proto.TaskResult),
+		logs:        make(map[string]*logStream),
+		ranges:      make(map[string]*pendingRange),
+		cancelGrace: DefaultCancelGrace,
+		timeout:     DefaultSubmitTimeout,
+		logger:      logger,
+	}
+}
+
+// SetSubmitTimeout sets the deadline applied to submissions whose context
+// has none. Zero lets them run indefinitely.
+func (c *Client) SetSubmitTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+}
+
+// Submit sends a task to a worker and blocks until it reports a result.
+// If ctx is cancelled first, the worker is told to cancel the task and the
+// cancelled result is returned together with ctx.Err(). The deadline of ctx
+// bounds the whole lifecycle; when it passes a *StageTimeoutError names the
+// stage the task was in.
+func (c *Client) Submit(ctx context.Context, nodeID hyperbus.NodeID, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	return c.SubmitStreaming(ctx, nodeID, submit, nil)
+}
+
+// SubmitStreaming is Submit, also passing each line the task logs to onLog
+// as the worker relays it. The result still carries the whole log.
+func (c *Client) SubmitStreaming(ctx context.Context, nodeID hyperbus.NodeID, submit *proto.TaskSubmit, onLog func(line string)) (*proto.TaskResult, error) {
+	c.mu.Lock()
+	timeout := c.timeout
+	c.mu.Unlock()
+
+	ctx, cancel := deadline.WithDefault(ctx, timeout)
+	defer cancel()
+
+	results := make(chan *proto.TaskResult, 1)
+
+	// The deadline fields are filled in on a copy, leaving the caller's submit as it was
+	submit = protobuf.Clone(submit).(*proto.TaskSubmit)
+	if deadline, ok := ctx.Deadline(); ok {
+		submit.DeadlineUnixNano = deadline.UnixNano()
+		submit.TimeoutNanos = max(int64(time.Until(deadline)), 1)
+	}
+
+	c.mu.Lock()
+	if _, exists := c.pending[submit.TaskId]; exists {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("task already pending: %s", submit.TaskId)
+	}
+	c.pending[submit.TaskId] = results
+	var stream *logStream
+	if onLog != nil {
+		stream = newLogStream(onLog)
+		c.logs[submit.TaskId] = stream
+	}
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, submit.TaskId)
+		delete(c.logs, submit.TaskId)
+		c.mu.Unlock()
+	}()
+
+	msg, err := hyperbus.EncodeMessage(hyperbus.MsgTaskSubmit, submit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task: %w", err)
+	}
+	if err := c.sender.SendControlMessage(ctx, nodeID, msg); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, &StageTimeoutError{Stage: StageSchedule}
+		}
+		return nil, fmt.Errorf("failed to submit task: %w", err)
+	}
+
+	c.logger.Debug("submitted task", "task_id", submit.TaskId, "node_id", nodeID)
+
+	select {
+	case result := <-results:
+		c.awaitLogs(stream, result)
+
+		// The worker may hit the deadline before we notice it
+		if result.Status == proto.TaskStatus_TIMEOUT {
+			return result, &StageTimeoutError{Stage: resultStage(result)}
+		}
+		return result, nil
+	case <-ctx.Done():
+	}
+
+	// The submit context is gone, so cancellation runs on its own deadline
+	cancelCtx, cancel := context.WithTimeout(context.Background(), c.cancelGrace)
+	defer cancel()
+
+	if err := c.sendCancel(cancelCtx, nodeID, submit.TaskId, ctx.Err().Error()); err != nil {
+		c.logger.Warn("failed to cancel remote task", "task_id", submit.TaskId, "error", err)
+	}
+
+	var result *proto.TaskResult
+	select {
+	case result = <-results:
+		c.awaitLogs(stream, result)
+	case <-cancelCtx.Done():
+		c.logger.Warn("worker did not confirm cancellation", "task_id", submit.TaskId, "node_id", nodeID)
+		result = &proto.TaskResult{TaskId: submit.TaskId, Status: proto.TaskStatus_CANCELLED}
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, &StageTimeoutError{Stage: resultStage(result)}
+	}
+	return result, ctx.Err()
+}
+
+// awaitLogs waits, for at most the cancel grace, until the lines the worker
+// relayed before its result were passed to the log handler, so none arrive
+// after Submit returns
+func (c *Client) awaitLogs(stream *logStream, result *proto.TaskResult) {
+	if stream == nil {
+		return
+	}
+
+	timer := time.NewTimer(c.cancelGrace)
+	defer timer.Stop()
+
+	select {
+	case <-stream.expect(result.LogLines):
+	case <-timer.C:
+		c.logger.Warn("relayed task logs incomplete", "task_id", result.TaskId, "want", result.LogLines)
+	}
+}
+
+// resultStage returns the stage a worker reported a task stopped in
+func resultStage(result *proto.TaskResult) Stage {
+	if result.Stage == "" {
+		return StageExecute
+	}
+	return Stage(result.Stage)
+}
+
+// sendCancel asks a worker to cancel a task
+func (c *Client) sendCancel(ctx context.Context, nodeID hyperbus.NodeID, taskID, reason string) error {
+	msg, err := hyperbus.EncodeMessage(hyperbus.MsgTaskCancel, &proto.TaskCancel{
+		TaskId: taskID,
+		Reason: reason,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode cancel: %w", err)
+	}
+	return c.sender.SendControlMessage(ctx, nodeID, msg)
+}
+
+// HandleMessage delivers task results, log lines and range progress to
+// waiting submitters
+func (c *Client) HandleMessage(ctx context.Context, conn hyperbus.Connection, stream hyperbus.Stream, data []byte) error {
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+	switch header.Type {
+	case hyperbus.MsgTaskResult:
+	case hyperbus.MsgTaskLog:
+		return c.handleLog(data)
+	case hyperbus.MsgRangeProgress:
+		return c.handleRangeProgress(data)
+	case hyperbus.MsgRangeResult:
+		return c.handleRangeResult(data)
+	default:
+		return fmt.Errorf("unexpected message type %d", header.Type)
+	}
+
+	var result proto.TaskResult
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &result); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	results, exists := c.pending[result.TaskId]
+	c.mu.Unlock()
+
+	if !exists {
+		c.logger.Debug("dropping result for unknown task", "task_id", result.TaskId)
+		return nil
+	}
+
+	select {
+	case results <- &result:
+	default:
+		c.logger.Warn("duplicate task result", "task_id", result.TaskId)
+	}
+	return nil
+}
+
+// handleLog passes a relayed log line to its task's handler
+func (c *Client) handleLog(data []byte) error {
+	var line proto.TaskLog
+	if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &line); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	stream, exists := c.logs[line.TaskId]
+	c.mu.Unlock()
+
+	if exists {
+		stream.deliver(line.Seq, line.Line)
+	}
+	return nil
+}
+
+// CollectMetrics adds the number of submitted tasks awaiting a result to m
+func (c *Client) CollectMetrics(m *proto.NodeMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m.TasksPending += int64(len(c.pending))
+}
+
+// ModuleFetcher obtains a module's bytecode by hash when a task doesn't carry it
+type ModuleFetcher interface {
+	// FetchModule returns the module with the given SHA256 hash
+	FetchModule(ctx context.Context, sha256 []byte) ([]byte, error)
+}
+
+// Worker executes tasks submitted by remote nodes
+type Worker struct {
+	sender   Sender
+	executor Executor
+	modules  ModuleFetcher
+	running  map[string]*runningTask
+	timeout  time.Duration // execute deadline for tasks submitted without one
+	logger   *log.Logger
+	mu       sync.Mutex
+}
+
+// runningTask is a task the worker is executing
+type runningTask struct {
+	submitter hyperbus.NodeID
+	cancel    context.CancelFunc
+}
+
+// NewWorker creates a new task worker
+func NewWorker(sender Sender, executor Executor, logger *log.Logger) *Worker {
+	return &Worker{
+		sender:   sender,
+		executor: executor,
+		running:  make(map[string]*runningTask),
+		timeout:  DefaultSubmitTimeout,
+		logger:   logger,
+	}
+}
+
+// SetExecuteTimeout sets the deadline applied to tasks submitted without
+// one. Zero lets them run indefinitely.
+func (w *Worker) SetExecuteTimeout(timeout time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timeout = timeout
+}
+
+// SetModuleFetcher sets how modules are obtained for tasks submitted without bytecode
+func (w *Worker) SetModuleFetcher(modules ModuleFetcher) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.modules = modules
+}
+
+// HandleMessage handles task submissions, range runs and cancellations
+func (w *Worker) HandleMessage(ctx context.Context, conn hyperbus.Connection, stream hyperbus.Stream, data []byte) error {
+	header, err := hyperbus.DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	switch header.Type {
+	case hyperbus.MsgTaskSubmit:
+		var submit proto.TaskSubmit
+		if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &submit); err != nil {
+			return err
+		}
+		return w.start(conn.NodeID(), &submit)
+
+	case hyperbus.MsgRangeRun:
+		var run proto.RangeRun
+		if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &run); err != nil {
+			return err
+		}
+		return w.startRange(conn.NodeID(), &run)
+
+	case hyperbus.MsgTaskCancel:
+		var cancel proto.TaskCancel
+		if err := hyperbus.DecodeMessage(data[hyperbus.HeaderSize:], &cancel); err != nil {
+			return err
+		}
+		// Only the node that submitted a task may cancel it
+		if err := w.cancelFrom(conn.NodeID(), cancel.TaskId); err != nil {
+			w.logger.Warn("rejected task cancel", "task_id", cancel.TaskId, "node_id", conn.NodeID(), "error", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unexpected message type %d", header.Type)
+	}
+}
+
+// Cancel interrupts a running task, returning false if it isn't running
+func (w *Worker) Cancel(taskID string) bool {
+	w.mu.Lock()
+	task, exists := w.running[taskID]
+	w.mu.Unlock()
+
+	if !exists {
+		w.logger.Debug("cancel for unknown task", "task_id", taskID)
+		return false
+	}
+
+	w.logger.Info("cancelling task", "task_id", taskID)
+	task.cancel()
+	return true
+}
+
+// cancelFrom interrupts a running task on behalf of a remote node, which
+// must be the task's submitter
+func (w *Worker) cancelFrom(nodeID hyperbus.NodeID, taskID string) error {
+	w.mu.Lock()
+	task, exists := w.running[taskID]
+	w.mu.Unlock()
+
+	if !exists {
+		w.logger.Debug("cancel for unknown task", "task_id", taskID)
+		return nil
+	}
+	if task.submitter != nodeID {
+		return fmt.Errorf("task %s was submitted by %s", taskID, task.submitter)
+	}
+
+	w.logger.Info("cancelling task", "task_id", taskID, "node_id", nodeID)
+	task.cancel()
+	return nil
+}
+
+// CollectMetrics adds the number of tasks running on the worker to m
+func (w *Worker) CollectMetrics(m *proto.NodeMetrics) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	m.TasksRunning += int64(len(w.running))
+}
+
+// start runs a submitted task in the background
+func (w *Worker) start(submitter hyperbus.NodeID, submit *proto.TaskSubmit) error {
+	ctx, err := w.track(submitter, submit)
+	if err != nil {
+		return err
+	}
+	go w.run(ctx, submitter, submit)
+	return nil
+}
+
+// track registers a task as running on behalf of submitter, returning the
+// context it runs under
+func (w *Worker) track(submitter hyperbus.NodeID, submit *proto.TaskSubmit) (context.Context, error) {
+	w.mu.Lock()
+	timeout := w.timeout
+	w.mu.Unlock()
+
+	// The submitter's deadline bounds the whole run. Its remaining time is
+	// measured on our clock, so skew between the nodes doesn't shift it.
+	var ctx context.Context
+	var cancel context.CancelFunc
+	switch {
+	case submit.TimeoutNanos > 0:
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(submit.TimeoutNanos))
+	case submit.DeadlineUnixNano != 0:
+		ctx, cancel = context.WithDeadline(context.Background(), time.Unix(0, submit.DeadlineUnixNano))
+	default:
+		ctx, cancel = deadline.WithDefault(context.Background(), timeout)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, exists := w.running[submit.TaskId]; exists {
+		cancel()
+		return nil, fmt.Errorf("task already running: %s", submit.TaskId)
+	}
+	w.running[submit.TaskId] = &runningTask{submitter: submitter, cancel: cancel}
+	return ctx, nil
+}
+
+// untrack forgets a finished task, releasing its context
+func (w *Worker) untrack(taskID string) {
+	w.mu.Lock()
+	task := w.running[taskID]
+	delete(w.running, taskID)
+	w.mu.Unlock()
+	task.cancel()
+}
+
+// run executes a task and reports its result to the submitter
+func (w *Worker) run(ctx context.Context, submitter hyperbus.NodeID, submit *proto.TaskSubmit) {
+	w.mu.Lock()
+	modules := w.modules
+	w.mu.Unlock()
+
+	var result *proto.TaskResult
+	var err error
+
+	stage := StageModuleFetch
+	if len(submit.WasmModule) == 0 && modules != nil {
+		w.logger.Debug("fetching task module", "task_id", submit.TaskId, "sha256", fmt.Sprintf("%x", submit.WasmModSha))
+		submit.WasmModule, err = modules.FetchModule(ctx, submit.WasmModSha)
+		if err != nil {
+			err = fmt.Errorf("failed to fetch module: %w", err)
+		}
+	}
+
+	var logLines uint64
+	if err == nil {
+		stage = StageExecute
+		w.logger.Debug("executing task", "task_id", submit.TaskId, "submitter", submitter)
+		relay := newLogRelay(w.sender, submitter, submit.TaskId, w.logger)
+		result, err = w.executor.Execute(WithLogHandler(ctx, relay.emit), submit)
+		logLines = relay.flush()
+	}
+	stopped := ctx.Err()
+
+	// The task is finished before its result is reported
+	w.untrack(submit.TaskId)
+
+	// Whatever the task wrote is kept as its logs; failures are reported apart
+	var logs string
+	if result != nil {
+		logs = result.Logs
+	}
+
+	switch {
+	case errors.Is(stopped, context.DeadlineExceeded):
+		timeout := &StageTimeoutError{Stage: stage}
+		result = &proto.TaskResult{Status: proto.TaskStatus_TIMEOUT, Stage: string(stage), Logs: logs, Error: timeout.Error()}
+	case stopped != nil:
+		result = &proto.TaskResult{Status: proto.TaskStatus_CANCELLED, Stage: string(stage), Logs: logs}
+	case err != nil:
+		result = &proto.TaskResult{Status: proto.TaskStatus_FAILED, Logs: logs, Error: err.Error()}
+	case result == nil:
+		result = &proto.TaskResult{Status: proto.TaskStatus_SUCCESS}
+	}
+	result.TaskId = submit.TaskId
+	result.LogLines = logLines
+
+	msg, err := hyperbus.EncodeMessage(hyperbus.MsgTaskResult, result)
+	if err != nil {
+		w.logger.Error("failed to encode task result", "task_id", submit.TaskId, "error", err)
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(context.Background(), DefaultCancelGrace)
+	defer cancel()
+
+	if err := w.sender.SendControlMessage(sendCtx, submitter, msg); err != nil {
+		if errors.Is(sendCtx.Err(), context.DeadlineExceeded) {
+			err = &StageTimeoutError{Stage: StageResultTransfer}
+		}
+		w.logger.Error("failed to send task result", "task_id", submit.TaskId, "error", err)
+		return
+	}
+
+	w.logger.Debug("task finished", "task_id", submit.TaskId, "status", result.Status)
+}
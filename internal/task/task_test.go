@@ -0,0 +1,440 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/internal/scheduler"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn identifies the sending node to a handler
+type fakeConn struct {
+	nodeID hyperbus.NodeID
+}
+
+func (c *fakeConn) NodeID() hyperbus.NodeID { return c.nodeID }
+
+func (c *fakeConn) OpenStream(ctx context.Context, streamType hyperbus.StreamType) (hyperbus.Stream, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+// fakeNetwork delivers messages between handlers in memory
+type fakeNetwork struct {
+	handlers map[hyperbus.NodeID]hyperbus.MessageHandler
+	mu       sync.Mutex
+}
+
+func newFakeNetwork() *fakeNetwork {
+	return &fakeNetwork{handlers: make(map[hyperbus.NodeID]hyperbus.MessageHandler)}
+}
+
+// sender returns a Sender sending on behalf of a node
+func (n *fakeNetwork) sender(from hyperbus.NodeID) Sender {
+	return senderFunc(func(ctx context.Context, to hyperbus.NodeID, msg []byte) error {
+		n.mu.Lock()
+		handler, exists := n.handlers[to]
+		n.mu.Unlock()
+		if !exists {
+			return errors.New("no connection")
+		}
+		go handler.HandleMessage(context.Background(), &fakeConn{nodeID: from}, nil, msg)
+		return nil
+	})
+}
+
+func (n *fakeNetwork) register(nodeID hyperbus.NodeID, handler hyperbus.MessageHandler) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.handlers[nodeID] = handler
+}
+
+type senderFunc func(ctx context.Context, nodeID hyperbus.NodeID, msg []byte) error
+
+func (f senderFunc) SendControlMessage(ctx context.Context, nodeID hyperbus.NodeID, msg []byte) error {
+	return f(ctx, nodeID, msg)
+}
+
+// blockingExecutor runs until its context is cancelled
+type blockingExecutor struct {
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func (e *blockingExecutor) Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	close(e.started)
+	<-ctx.Done()
+	close(e.stopped)
+	return nil, ctx.Err()
+}
+
+// echoExecutor completes immediately
+type echoExecutor struct{}
+
+func (echoExecutor) Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	return &proto.TaskResult{Status: proto.TaskStatus_SUCCESS, Logs: "ran " + submit.FuncName}, nil
+}
+
+// newTestPair wires a client on "client" and a worker on "worker"
+func newTestPair(executor Executor) (*Client, *Worker) {
+	logger := log.New(slog.LevelDebug)
+	network := newFakeNetwork()
+
+	client := NewClient(network.sender("client"), logger)
+	worker := NewWorker(network.sender("worker"), executor, logger)
+
+	clientMux := hyperbus.NewMux()
+	clientMux.Handle(hyperbus.MsgTaskResult, client)
+	clientMux.Handle(hyperbus.MsgTaskLog, client)
+	clientMux.Handle(hyperbus.MsgRangeProgress, client)
+	clientMux.Handle(hyperbus.MsgRangeResult, client)
+	network.register("client", clientMux)
+
+	workerMux := hyperbus.NewMux()
+	workerMux.Handle(hyperbus.MsgTaskSubmit, worker)
+	workerMux.Handle(hyperbus.MsgTaskCancel, worker)
+	workerMux.Handle(hyperbus.MsgRangeRun, worker)
+	network.register("worker", workerMux)
+
+	return client, worker
+}
+
+func TestClient_Submit(t *testing.T) {
+	client, _ := newTestPair(echoExecutor{})
+
+	result, err := client.Submit(context.Background(), "worker", &proto.TaskSubmit{TaskId: "task-1", FuncName: "vec_add"})
+	assert.NoError(t, err)
+	assert.Equal(t, "task-1", result.TaskId)
+	assert.Equal(t, proto.TaskStatus_SUCCESS, result.Status)
+	assert.Equal(t, "ran vec_add", result.Logs)
+}
+
+func TestClient_SubmitLeavesCallerSubmitUnchanged(t *testing.T) {
+	client, _ := newTestPair(echoExecutor{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	submit := &proto.TaskSubmit{TaskId: "task-1", FuncName: "vec_add"}
+	_, err := client.Submit(ctx, "worker", submit)
+	assert.NoError(t, err)
+
+	// Resubmitting the same message later mustn't inherit this deadline
+	assert.Zero(t, submit.DeadlineUnixNano)
+	assert.Zero(t, submit.TimeoutNanos)
+}
+
+// failingExecutor writes some output, then fails
+type failingExecutor struct{}
+
+func (failingExecutor) Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	return &proto.TaskResult{Logs: "step 1 done\n"}, errors.New("division by zero")
+}
+
+// loggingExecutor logs three lines, then waits to be released
+type loggingExecutor struct {
+	release chan struct{}
+}
+
+func (e *loggingExecutor) Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	lines := []string{"loading", "computing", "storing"}
+	for _, line := range lines {
+		LogHandler(ctx)(line)
+	}
+	<-e.release
+	return &proto.TaskResult{Status: proto.TaskStatus_SUCCESS, Logs: strings.Join(lines, "\n") + "\n"}, nil
+}
+
+func TestClient_SubmitStreamingLogs(t *testing.T) {
+	executor := &loggingExecutor{release: make(chan struct{})}
+	client, _ := newTestPair(executor)
+
+	// The task only finishes once all three lines reached the submitter
+	var mu sync.Mutex
+	var streamed []string
+	onLog := func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		streamed = append(streamed, line)
+		if len(streamed) == 3 {
+			close(executor.release)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := client.SubmitStreaming(ctx, "worker", &proto.TaskSubmit{TaskId: "task-1"}, onLog)
+	assert.NoError(t, err)
+	assert.Equal(t, proto.TaskStatus_SUCCESS, result.Status)
+	assert.Equal(t, "loading\ncomputing\nstoring\n", result.Logs)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"loading", "computing", "storing"}, streamed)
+}
+
+func TestLogStream_HoldsBackLinesThatOvertook(t *testing.T) {
+	var delivered []string
+	stream := newLogStream(func(line string) { delivered = append(delivered, line) })
+
+	stream.deliver(2, "storing")
+	stream.deliver(0, "loading")
+	assert.Equal(t, []string{"loading"}, delivered)
+
+	flushed := stream.expect(3)
+	select {
+	case <-flushed:
+		t.Fatal("stream flushed with a line missing")
+	default:
+	}
+
+	stream.deliver(1, "computing")
+	stream.deliver(1, "computing")
+	assert.Equal(t, []string{"loading", "computing", "storing"}, delivered)
+	<-flushed
+}
+
+func TestClient_SubmitFailureError(t *testing.T) {
+	client, _ := newTestPair(failingExecutor{})
+
+	result, err := client.Submit(context.Background(), "worker", &proto.TaskSubmit{TaskId: "task-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, proto.TaskStatus_FAILED, result.Status)
+	assert.Equal(t, "division by zero", result.Error)
+	assert.Equal(t, "step 1 done\n", result.Logs)
+}
+
+func TestClient_SubmitCancel(t *testing.T) {
+	executor := &blockingExecutor{started: make(chan struct{}), stopped: make(chan struct{})}
+	client, worker := newTestPair(executor)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// Cancel once the remote task is running
+		<-executor.started
+		cancel()
+	}()
+
+	result, err := client.Submit(ctx, "worker", &proto.TaskSubmit{TaskId: "task-1"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "task-1", result.TaskId)
+	assert.Equal(t, proto.TaskStatus_CANCELLED, result.Status)
+
+	// The remote execution was interrupted
+	select {
+	case <-executor.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("remote task was not stopped")
+	}
+
+	// The worker no longer tracks the task
+	assert.False(t, worker.Cancel("task-1"))
+}
+
+func TestWorker_IgnoresCancelFromOtherNodes(t *testing.T) {
+	executor := &blockingExecutor{started: make(chan struct{}), stopped: make(chan struct{})}
+	_, worker := newTestPair(executor)
+
+	assert.NoError(t, worker.start("client", &proto.TaskSubmit{TaskId: "task-1"}))
+	<-executor.started
+
+	cancel, err := hyperbus.EncodeMessage(hyperbus.MsgTaskCancel, &proto.TaskCancel{TaskId: "task-1"})
+	assert.NoError(t, err)
+
+	// Another node can't cancel the client's task
+	assert.NoError(t, worker.HandleMessage(context.Background(), &fakeConn{nodeID: "intruder"}, nil, cancel))
+	select {
+	case <-executor.stopped:
+		t.Fatal("task was cancelled by a node that didn't submit it")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The submitter can
+	assert.NoError(t, worker.HandleMessage(context.Background(), &fakeConn{nodeID: "client"}, nil, cancel))
+	select {
+	case <-executor.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("submitter's cancel did not stop the task")
+	}
+}
+
+// stallingFetcher blocks until its context is done
+type stallingFetcher struct{}
+
+func (stallingFetcher) FetchModule(ctx context.Context, sha256 []byte) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// staticFetcher serves a fixed module
+type staticFetcher []byte
+
+func (f staticFetcher) FetchModule(ctx context.Context, sha256 []byte) ([]byte, error) {
+	return f, nil
+}
+
+// moduleExecutor reports the module it was given
+type moduleExecutor struct{}
+
+func (moduleExecutor) Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	return &proto.TaskResult{Status: proto.TaskStatus_SUCCESS, Logs: string(submit.WasmModule)}, nil
+}
+
+func TestClient_SubmitDeadlineDuringModuleFetch(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	network := newFakeNetwork()
+
+	// The client's cancel races the worker's own deadline, so it is dropped
+	// and the worker stops only when its deadline passes
+	send := network.sender("client")
+	dropCancels := senderFunc(func(ctx context.Context, nodeID hyperbus.NodeID, msg []byte) error {
+		if header, err := hyperbus.DecodeHeader(msg); err == nil && header.Type == hyperbus.MsgTaskCancel {
+			return nil
+		}
+		return send.SendControlMessage(ctx, nodeID, msg)
+	})
+	client := NewClient(dropCancels, logger)
+	worker := NewWorker(network.sender("worker"), echoExecutor{}, logger)
+	worker.SetModuleFetcher(stallingFetcher{})
+	network.register("client", client)
+	network.register("worker", worker)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := client.Submit(ctx, "worker", &proto.TaskSubmit{TaskId: "task-1", WasmModSha: []byte{1}})
+	var timeout *StageTimeoutError
+	assert.ErrorAs(t, err, &timeout)
+	assert.Equal(t, StageModuleFetch, timeout.Stage)
+	assert.EqualError(t, err, "timed out while fetching module")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, proto.TaskStatus_TIMEOUT, result.Status)
+	assert.Equal(t, "timed out while fetching module", result.Error)
+	assert.Empty(t, result.Logs)
+}
+
+func TestClient_SubmitDeadlineDuringExecution(t *testing.T) {
+	executor := &blockingExecutor{started: make(chan struct{}), stopped: make(chan struct{})}
+	client, _ := newTestPair(executor)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Submit(ctx, "worker", &proto.TaskSubmit{TaskId: "task-1"})
+	assert.EqualError(t, err, "timed out while executing")
+}
+
+func TestClient_SubmitDeadlineDuringScheduling(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	stalled := senderFunc(func(ctx context.Context, nodeID hyperbus.NodeID, msg []byte) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	client := NewClient(stalled, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Submit(ctx, "worker", &proto.TaskSubmit{TaskId: "task-1"})
+	assert.EqualError(t, err, "timed out while scheduling")
+}
+
+func TestClient_SubmitDefaultTimeout(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	stalled := senderFunc(func(ctx context.Context, nodeID hyperbus.NodeID, msg []byte) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	client := NewClient(stalled, logger)
+	client.SetSubmitTimeout(20 * time.Millisecond)
+
+	// A background context still times out after the configured default
+	_, err := client.Submit(context.Background(), "worker", &proto.TaskSubmit{TaskId: "task-1"})
+	assert.EqualError(t, err, "timed out while scheduling")
+}
+
+func TestWorker_FetchesMissingModule(t *testing.T) {
+	client, worker := newTestPair(moduleExecutor{})
+	worker.SetModuleFetcher(staticFetcher("module bytes"))
+
+	result, err := client.Submit(context.Background(), "worker", &proto.TaskSubmit{TaskId: "task-1", WasmModSha: []byte{1}})
+	assert.NoError(t, err)
+	assert.Equal(t, "module bytes", result.Logs)
+}
+
+func TestWorker_AppliesSubmitTimeout(t *testing.T) {
+	executor := &blockingExecutor{started: make(chan struct{}), stopped: make(chan struct{})}
+	_, worker := newTestPair(executor)
+
+	// The submitter's clock is an hour ahead, but the remaining time still applies
+	assert.NoError(t, worker.start("client", &proto.TaskSubmit{
+		TaskId:           "task-1",
+		DeadlineUnixNano: time.Now().Add(time.Hour).UnixNano(),
+		TimeoutNanos:     int64(20 * time.Millisecond),
+	}))
+	select {
+	case <-executor.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("task outlived its submit timeout")
+	}
+}
+
+func TestWorker_DefaultExecuteTimeout(t *testing.T) {
+	executor := &blockingExecutor{started: make(chan struct{}), stopped: make(chan struct{})}
+	_, worker := newTestPair(executor)
+	worker.SetExecuteTimeout(20 * time.Millisecond)
+
+	// A task submitted without a deadline still stops after the default
+	assert.NoError(t, worker.start("client", &proto.TaskSubmit{TaskId: "task-1"}))
+	select {
+	case <-executor.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("task outlived the default execute timeout")
+	}
+}
+
+// rangeExecutor runs ranges of a loop body failing at index 7
+type rangeExecutor struct {
+	echoExecutor
+}
+
+func (rangeExecutor) ExecuteRange(ctx context.Context, submit *proto.TaskSubmit, begin, end int, progress func(done int), interval time.Duration) error {
+	return scheduler.RunRange(ctx, begin, end, func(i int) error {
+		if i == 7 {
+			return errors.New("bad element")
+		}
+		return nil
+	}, progress, interval)
+}
+
+func TestClient_RunRange(t *testing.T) {
+	client, _ := newTestPair(rangeExecutor{})
+
+	result, err := client.RunRange(context.Background(), "worker", &proto.RangeRun{Task: &proto.TaskSubmit{TaskId: "range-1"}, Begin: 0, End: 5}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "range-1", result.TaskId)
+	assert.Empty(t, result.Error)
+
+	// A failing index is reported apart from the worker failing
+	result, err = client.RunRange(context.Background(), "worker", &proto.RangeRun{Task: &proto.TaskSubmit{TaskId: "range-2"}, Begin: 5, End: 10}, nil)
+	assert.NoError(t, err)
+	assert.True(t, result.IterationFailed)
+	assert.Equal(t, int64(7), result.FailedIndex)
+	assert.Equal(t, "bad element", result.Error)
+
+	// Executors that only run whole tasks turn ranges down
+	echo, _ := newTestPair(echoExecutor{})
+	result, err = echo.RunRange(context.Background(), "worker", &proto.RangeRun{Task: &proto.TaskSubmit{TaskId: "range-3"}, End: 1}, nil)
+	assert.NoError(t, err)
+	assert.False(t, result.IterationFailed)
+	assert.Equal(t, "executor can't run ranges", result.Error)
+}
@@ -0,0 +1,437 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/internal/scheduler"
+	"github.com/melihxz/holocompute/internal/task"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// ErrModuleHash is returned when a module's bytecode doesn't match its SHA256
+var ErrModuleHash = errors.New("module hash mismatch")
+
+// Executor runs task modules with wazero. A task's function is called with
+// an (offset, length) pair of i32 parameters for each of its arrays, inputs
+// then outputs, each ordered by name: the array's elements are copied into
+// the module's memory at offset, and length is their number. Once the
+// function returns, the output arrays are copied back. Modules may import
+// env.log(ptr, len i32) to write a line to the task's logs, which is also
+// passed to the context's task.LogHandler as it's written. Other modules
+// it imports are instantiated for each task from the bytecode the task
+// supplies under their name, compiled once through the cache.
+type Executor struct {
+	runtime wazero.Runtime
+	modules *ImportCache // compiled task modules and their imports by hash
+	mm      *dsm.MemoryManager
+	logger  *log.Logger
+}
+
+// NewExecutor creates an executor running tasks against the arrays of mm
+func NewExecutor(ctx context.Context, mm *dsm.MemoryManager, logger *log.Logger) (*Executor, error) {
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+
+	_, err := runtime.NewHostModuleBuilder("env").
+		NewFunctionBuilder().WithFunc(hostLog).Export("log").
+		Instantiate(ctx)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate host module: %w", err)
+	}
+
+	compile := func(ctx context.Context, module []byte) (CompiledModule, error) {
+		return runtime.CompileModule(ctx, module)
+	}
+	return &Executor{
+		runtime: runtime,
+		modules: NewImportCache(DefaultImportCacheSize, compile, logger),
+		mm:      mm,
+		logger:  logger,
+	}, nil
+}
+
+// Close releases the compiled modules and the runtime
+func (e *Executor) Close(ctx context.Context) error {
+	if err := e.modules.Close(ctx); err != nil {
+		e.logger.Warn("failed to close compiled modules", "error", err)
+	}
+	return e.runtime.Close(ctx)
+}
+
+// binding is an array copied into a module instance's memory
+type binding struct {
+	array  *dsm.Array
+	offset uint32
+}
+
+// size returns the number of bytes the array takes in memory
+func (b binding) size() int {
+	return b.array.Len() * b.array.ElementSize
+}
+
+// Execute runs a task's function and returns its logs
+func (e *Executor) Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	p, err := e.prepare(ctx, submit, 0)
+	defer p.close()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.fn.Call(p.ctx, p.params()...); err != nil {
+		return &proto.TaskResult{Logs: p.logs.String()}, fmt.Errorf("task function failed: %w", err)
+	}
+
+	if err := e.copyOut(p.ctx, p.memory, p.outputs); err != nil {
+		return &proto.TaskResult{Logs: p.logs.String()}, err
+	}
+	return &proto.TaskResult{Status: proto.TaskStatus_SUCCESS, Logs: p.logs.String()}, nil
+}
+
+// ExecuteRange calls a task's function with each index from begin to end-1
+// in one instance, the index as an i32 parameter ahead of the arrays'. Tasks
+// run in ranges can't have outputs: ranges run on several nodes, and each
+// would write back the whole array.
+func (e *Executor) ExecuteRange(ctx context.Context, submit *proto.TaskSubmit, begin, end int, progress func(done int), interval time.Duration) error {
+	if len(submit.OutputsRef) > 0 {
+		return errors.New("tasks run in ranges can't have outputs")
+	}
+
+	p, err := e.prepare(ctx, submit, 1)
+	defer p.close()
+	if err != nil {
+		return err
+	}
+
+	params := p.params(0)
+	return scheduler.RunRange(ctx, begin, end, func(i int) error {
+		params[0] = api.EncodeI32(int32(i))
+		_, err := p.fn.Call(p.ctx, params...)
+		return err
+	}, progress, interval)
+}
+
+// prepared is a task's module instantiated with its arrays copied in
+type prepared struct {
+	ctx     context.Context // collects the task's logs
+	fn      api.Function
+	memory  api.Memory
+	arrays  []binding
+	outputs []binding
+	logs    *taskLogs
+	cleanup []func()
+}
+
+// close releases what prepare took, in reverse order
+func (p *prepared) close() {
+	for i := len(p.cleanup) - 1; i >= 0; i-- {
+		p.cleanup[i]()
+	}
+}
+
+// params returns leading followed by the (offset, length) pair of each array
+func (p *prepared) params(leading ...uint64) []uint64 {
+	params := append(make([]uint64, 0, len(leading)+2*len(p.arrays)), leading...)
+	for _, b := range p.arrays {
+		params = append(params, api.EncodeU32(b.offset), api.EncodeU32(uint32(b.array.Len())))
+	}
+	return params
+}
+
+// prepare checks a task's module, instantiates it with its imports and
+// copies its arrays in. The function must take leading parameters ahead of
+// the arrays'. The result is returned even on error so it can be closed.
+func (e *Executor) prepare(ctx context.Context, submit *proto.TaskSubmit, leading int) (*prepared, error) {
+	p := &prepared{logs: &taskLogs{emit: task.LogHandler(ctx)}}
+	if hash := HashModule(submit.WasmModule); !bytes.Equal(hash[:], submit.WasmModSha) {
+		return p, fmt.Errorf("%w: bytecode hashes to %s", ErrModuleHash, hash)
+	}
+
+	inputs, err := e.bindings(ctx, submit.InputsRef)
+	p.cleanup = append(p.cleanup, func() { e.release(inputs) })
+	if err != nil {
+		return p, err
+	}
+	outputs, err := e.bindings(ctx, submit.OutputsRef)
+	p.cleanup = append(p.cleanup, func() { e.release(outputs) })
+	if err != nil {
+		return p, err
+	}
+	p.arrays = append(inputs, outputs...)
+	p.outputs = p.arrays[len(inputs):]
+
+	compiled, release, err := e.modules.Resolve(ctx, submit.WasmModule)
+	if err != nil {
+		return p, err
+	}
+	p.cleanup = append(p.cleanup, release)
+
+	p.ctx = context.WithValue(ctx, taskLogsKey{}, p.logs)
+
+	imports := &linker{executor: e, sources: submit.Imports, instances: make(map[string]api.Module)}
+	p.cleanup = append(p.cleanup, func() { imports.close(p.ctx) })
+	if err := imports.link(p.ctx, compiled.(wazero.CompiledModule)); err != nil {
+		return p, err
+	}
+
+	// Anonymous instances let tasks of the same module run concurrently
+	instance, err := e.runtime.InstantiateModule(imports.context(p.ctx), compiled.(wazero.CompiledModule), wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return p, fmt.Errorf("failed to instantiate module: %w", err)
+	}
+	p.cleanup = append(p.cleanup, func() { instance.Close(p.ctx) })
+
+	p.fn = instance.ExportedFunction(submit.FuncName)
+	if p.fn == nil {
+		return p, fmt.Errorf("module exports no function %q", submit.FuncName)
+	}
+	if got, want := len(p.fn.Definition().ParamTypes()), leading+2*len(p.arrays); got != want {
+		return p, fmt.Errorf("function %q takes %d parameters, want %d for %d arrays", submit.FuncName, got, want, len(p.arrays))
+	}
+
+	p.memory = instance.Memory()
+	if p.memory == nil {
+		return p, errors.New("module has no memory")
+	}
+	if err := e.copyIn(p.ctx, p.memory, p.arrays); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// linker instantiates the modules a task imports, once per task. Like the
+// task's own instance they are anonymous, so tasks importing modules of
+// the same name run concurrently.
+type linker struct {
+	executor  *Executor
+	sources   map[string][]byte     // bytecode by module name
+	instances map[string]api.Module // nil while being linked
+	releases  []func()
+}
+
+// link instantiates the modules compiled imports that the task supplies,
+// after linking their own imports. Imports it doesn't supply, such as env,
+// are left to the runtime.
+func (l *linker) link(ctx context.Context, compiled wazero.CompiledModule) error {
+	for _, name := range importedModules(compiled) {
+		source, supplied := l.sources[name]
+		if _, linked := l.instances[name]; linked || !supplied {
+			continue
+		}
+		l.instances[name] = nil
+
+		imported, release, err := l.executor.modules.Resolve(ctx, source)
+		if err != nil {
+			return fmt.Errorf("failed to resolve import %s: %w", name, err)
+		}
+		l.releases = append(l.releases, release)
+		if err := l.link(ctx, imported.(wazero.CompiledModule)); err != nil {
+			return err
+		}
+
+		instance, err := l.executor.runtime.InstantiateModule(l.context(ctx), imported.(wazero.CompiledModule), wazero.NewModuleConfig().WithName(""))
+		if err != nil {
+			return fmt.Errorf("failed to instantiate import %s: %w", name, err)
+		}
+		l.instances[name] = instance
+	}
+	return nil
+}
+
+// context returns ctx resolving imports to the modules linked so far
+func (l *linker) context(ctx context.Context) context.Context {
+	return experimental.WithImportResolver(ctx, func(name string) api.Module {
+		return l.instances[name]
+	})
+}
+
+// close closes the imported instances and releases their compiled modules
+func (l *linker) close(ctx context.Context) {
+	for _, instance := range l.instances {
+		if instance != nil {
+			instance.Close(ctx)
+		}
+	}
+	for _, release := range l.releases {
+		release()
+	}
+}
+
+// importedModules returns the names of the modules a module imports from
+func importedModules(compiled wazero.CompiledModule) []string {
+	var names []string
+	for _, fn := range compiled.ImportedFunctions() {
+		name, _, _ := fn.Import()
+		names = append(names, name)
+	}
+	for _, memory := range compiled.ImportedMemories() {
+		name, _, _ := memory.Import()
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+// bindings opens the arrays a task names, ordered by name. Arrays created
+// on the submitting node are looked up there. The arrays opened are
+// returned even on error so they can be released.
+func (e *Executor) bindings(ctx context.Context, refs map[string]string) ([]binding, error) {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bindings := make([]binding, 0, len(names))
+	for _, name := range names {
+		array, err := e.mm.OpenArray(ctx, dsm.ArrayID(refs[name]))
+		if err != nil {
+			return bindings, fmt.Errorf("failed to open array %s: %w", name, err)
+		}
+		bindings = append(bindings, binding{array: array})
+	}
+	return bindings, nil
+}
+
+// release drops the references bindings took on their arrays
+func (e *Executor) release(bindings []binding) {
+	for _, b := range bindings {
+		e.mm.ReleaseArray(b.array.ID)
+	}
+}
+
+// copyIn grows memory past what the module uses to fit the arrays, lays
+// them out there and copies their elements in
+func (e *Executor) copyIn(ctx context.Context, memory api.Memory, arrays []binding) error {
+	base := uint64(memory.Size())
+	end := base
+	for i := range arrays {
+		arrays[i].offset = uint32(end)
+		end += uint64(arrays[i].size())
+	}
+	if end > math.MaxUint32 {
+		return fmt.Errorf("task arrays need %d bytes of memory, more than 4GiB", end)
+	}
+
+	const wasmPageSize = 65536
+	if grow := (end - base + wasmPageSize - 1) / wasmPageSize; grow > 0 {
+		if _, ok := memory.Grow(uint32(grow)); !ok {
+			return fmt.Errorf("failed to grow memory by %d pages", grow)
+		}
+	}
+
+	for _, b := range arrays {
+		err := e.forEachPage(ctx, b.array, false, func(page *dsm.Page, first, n int) error {
+			size := b.array.ElementSize
+			if !memory.Write(b.offset+uint32(first*size), page.Bytes()[:n*size]) {
+				return fmt.Errorf("failed to copy array %s into memory", b.array.ID)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyOut writes the output arrays back from memory
+func (e *Executor) copyOut(ctx context.Context, memory api.Memory, outputs []binding) error {
+	for _, b := range outputs {
+		size := b.array.ElementSize
+		data, ok := memory.Read(b.offset, uint32(b.size()))
+		if !ok {
+			return fmt.Errorf("failed to read array %s from memory", b.array.ID)
+		}
+
+		// Each page is written back whole, one WAL record rather than one per element
+		err := e.forEachPage(ctx, b.array, true, func(page *dsm.Page, first, n int) error {
+			if err := e.mm.WritePageData(b.array.ID, page, data[first*size:(first+n)*size]); err != nil {
+				return err
+			}
+			page.MarkDirty()
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write array %s: %w", b.array.ID, err)
+		}
+	}
+	return nil
+}
+
+// forEachPage visits an array's pages with the index of their first element
+// and the number of elements they hold
+func (e *Executor) forEachPage(ctx context.Context, array *dsm.Array, forWrite bool, fn func(page *dsm.Page, first, n int) error) error {
+	request := e.mm.ReadPage
+	if forWrite {
+		request = e.mm.RequestPage
+	}
+
+	perPage := dsm.PageSize / array.ElementSize
+	for p := 0; p < array.PageCount(); p++ {
+		page, err := request(ctx, array.ID, dsm.PageID(p), array.Version)
+		if err != nil {
+			return fmt.Errorf("failed to request page: %w", err)
+		}
+		first := p * perPage
+		if err := fn(page, first, min(perPage, array.Len()-first)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskLogsKey is the context key of the running task's logs
+type taskLogsKey struct{}
+
+// taskLogs collects the lines a task logs
+type taskLogs struct {
+	lines []string
+	emit  func(line string) // streams each line as it's added, if set
+	mu    sync.Mutex
+}
+
+// add appends a line and streams it
+func (l *taskLogs) add(line string) {
+	l.mu.Lock()
+	l.lines = append(l.lines, line)
+	l.mu.Unlock()
+
+	if l.emit != nil {
+		l.emit(line)
+	}
+}
+
+// String returns the lines logged so far, one per line
+func (l *taskLogs) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.lines) == 0 {
+		return ""
+	}
+	return strings.Join(l.lines, "\n") + "\n"
+}
+
+// hostLog implements env.log, appending the string at ptr to the task's logs
+func hostLog(ctx context.Context, m api.Module, ptr, length uint32) {
+	logs, ok := ctx.Value(taskLogsKey{}).(*taskLogs)
+	if !ok {
+		return
+	}
+	if data, ok := m.Memory().Read(ptr, length); ok {
+		logs.add(string(data))
+	}
+}
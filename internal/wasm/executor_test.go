@@ -0,0 +1,230 @@
+package wasm
+
+import (
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/internal/scheduler"
+	"github.com/melihxz/holocompute/internal/task"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestExecutor creates an executor over arrays held by a single local node
+func newTestExecutor(t *testing.T) (*Executor, *dsm.MemoryManager) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	mm := dsm.NewMemoryManager(bus, logger)
+
+	executor, err := NewExecutor(context.Background(), mm, logger)
+	assert.NoError(t, err)
+	t.Cleanup(func() { executor.Close(context.Background()) })
+	return executor, mm
+}
+
+// newFloat32Array creates a local float32 array holding values
+func newFloat32Array(t *testing.T, mm *dsm.MemoryManager, values []float32) *dsm.Array {
+	ctx := context.Background()
+	array, err := mm.CreateArray(ctx, len(values), dsm.WithElementType(dsm.ElementFloat32), dsm.WithPlacement([]hyperbus.NodeID{"node-1"}))
+	assert.NoError(t, err)
+
+	perPage := dsm.PageSize / 4
+	for i, v := range values {
+		page, err := mm.RequestPage(ctx, array.ID, dsm.PageID(i/perPage), array.Version)
+		assert.NoError(t, err)
+		binary.LittleEndian.PutUint32(page.Bytes()[(i%perPage)*4:], math.Float32bits(v))
+	}
+	return array
+}
+
+// readFloat32Array returns the values of a float32 array
+func readFloat32Array(t *testing.T, mm *dsm.MemoryManager, array *dsm.Array) []float32 {
+	perPage := dsm.PageSize / 4
+	values := make([]float32, array.Len())
+	for i := range values {
+		page, err := mm.ReadPage(context.Background(), array.ID, dsm.PageID(i/perPage), array.Version)
+		assert.NoError(t, err)
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(page.Bytes()[(i%perPage)*4:]))
+	}
+	return values
+}
+
+// vectorAddTask submits the vector_add fixture for c = a + b
+func vectorAddTask(t *testing.T, a, b, c *dsm.Array) *proto.TaskSubmit {
+	module, err := os.ReadFile("testdata/vector_add.wasm")
+	assert.NoError(t, err)
+	hash := HashModule(module)
+
+	return &proto.TaskSubmit{
+		TaskId:     "task-1",
+		WasmModule: module,
+		WasmModSha: hash[:],
+		FuncName:   "add",
+		InputsRef:  map[string]string{"a": string(a.ID), "b": string(b.ID)},
+		OutputsRef: map[string]string{"c": string(c.ID)},
+	}
+}
+
+func TestExecutor_VectorAdd(t *testing.T) {
+	executor, mm := newTestExecutor(t)
+
+	// Spanning pages checks that arrays are laid out contiguously
+	n := dsm.PageSize/4 + 100
+	aValues := make([]float32, n)
+	bValues := make([]float32, n)
+	for i := range aValues {
+		aValues[i] = float32(i)
+		bValues[i] = float32(2*i) + 0.5
+	}
+	a := newFloat32Array(t, mm, aValues)
+	b := newFloat32Array(t, mm, bValues)
+	c := newFloat32Array(t, mm, make([]float32, n))
+
+	// Log lines are streamed as well as returned
+	var streamed []string
+	ctx := task.WithLogHandler(context.Background(), func(line string) { streamed = append(streamed, line) })
+	result, err := executor.Execute(ctx, vectorAddTask(t, a, b, c))
+	assert.NoError(t, err)
+	assert.Equal(t, proto.TaskStatus_SUCCESS, result.Status)
+	assert.Equal(t, "vector add\n", result.Logs)
+	assert.Equal(t, []string{"vector add"}, streamed)
+
+	got := readFloat32Array(t, mm, c)
+	for i := range got {
+		if !assert.Equal(t, float32(3*i)+0.5, got[i], "element %d", i) {
+			break
+		}
+	}
+
+	// The compiled module is reused by the next task
+	_, err = executor.Execute(context.Background(), vectorAddTask(t, a, b, c))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, executor.modules.Len())
+}
+
+func TestExecutor_RejectsHashMismatch(t *testing.T) {
+	executor, mm := newTestExecutor(t)
+	a := newFloat32Array(t, mm, []float32{1})
+	b := newFloat32Array(t, mm, []float32{2})
+	c := newFloat32Array(t, mm, []float32{0})
+
+	submit := vectorAddTask(t, a, b, c)
+	submit.WasmModSha[0] ^= 0xff
+	_, err := executor.Execute(context.Background(), submit)
+	assert.ErrorIs(t, err, ErrModuleHash)
+
+	submit.WasmModSha = nil
+	_, err = executor.Execute(context.Background(), submit)
+	assert.ErrorIs(t, err, ErrModuleHash)
+	assert.Equal(t, []float32{0}, readFloat32Array(t, mm, c))
+}
+
+func TestExecutor_WrongSignature(t *testing.T) {
+	executor, mm := newTestExecutor(t)
+	a := newFloat32Array(t, mm, []float32{1})
+	c := newFloat32Array(t, mm, []float32{0})
+
+	// add takes three arrays, not two
+	submit := vectorAddTask(t, a, a, c)
+	delete(submit.InputsRef, "b")
+	_, err := executor.Execute(context.Background(), submit)
+	assert.ErrorContains(t, err, "takes 6 parameters, want 4")
+}
+
+// importTask submits a testdata kernel importing helper.wasm as "helper"
+func importTask(t *testing.T, kernel, fn string, inputs, outputs map[string]string) *proto.TaskSubmit {
+	module, err := os.ReadFile("testdata/" + kernel)
+	assert.NoError(t, err)
+	helper, err := os.ReadFile("testdata/helper.wasm")
+	assert.NoError(t, err)
+	hash := HashModule(module)
+
+	return &proto.TaskSubmit{
+		TaskId:     "task-1",
+		WasmModule: module,
+		WasmModSha: hash[:],
+		FuncName:   fn,
+		InputsRef:  inputs,
+		OutputsRef: outputs,
+		Imports:    map[string][]byte{"helper": helper},
+	}
+}
+
+func TestExecutor_SharedImportCompilesOnce(t *testing.T) {
+	executor, mm := newTestExecutor(t)
+
+	// Count compilations by module
+	compiles := make(map[ModuleHash]int)
+	executor.modules = NewImportCache(DefaultImportCacheSize, func(ctx context.Context, module []byte) (CompiledModule, error) {
+		compiles[HashModule(module)]++
+		return executor.runtime.CompileModule(ctx, module)
+	}, executor.logger)
+
+	a := newFloat32Array(t, mm, []float32{1, 2, 3})
+	b := newFloat32Array(t, mm, []float32{0.5, 0.5, 0.5})
+	c := newFloat32Array(t, mm, make([]float32, 3))
+	d := newFloat32Array(t, mm, make([]float32, 3))
+
+	// Two kernels link the same helper
+	add := importTask(t, "vector_add_import.wasm", "add", map[string]string{"a": string(a.ID), "b": string(b.ID)}, map[string]string{"c": string(c.ID)})
+	result, err := executor.Execute(context.Background(), add)
+	assert.NoError(t, err)
+	assert.Equal(t, proto.TaskStatus_SUCCESS, result.Status)
+	assert.Equal(t, []float32{1.5, 2.5, 3.5}, readFloat32Array(t, mm, c))
+
+	double := importTask(t, "vector_double_import.wasm", "double", map[string]string{"a": string(a.ID)}, map[string]string{"c": string(d.ID)})
+	result, err = executor.Execute(context.Background(), double)
+	assert.NoError(t, err)
+	assert.Equal(t, proto.TaskStatus_SUCCESS, result.Status)
+	assert.Equal(t, []float32{2, 4, 6}, readFloat32Array(t, mm, d))
+
+	assert.Equal(t, 3, executor.modules.Len())
+	assert.Equal(t, 1, compiles[HashModule(add.Imports["helper"])])
+
+	// Without the helper the kernel can't be linked
+	add.Imports = nil
+	_, err = executor.Execute(context.Background(), add)
+	assert.ErrorContains(t, err, "failed to instantiate module")
+}
+
+func TestExecutor_ExecuteRange(t *testing.T) {
+	executor, mm := newTestExecutor(t)
+	a := newFloat32Array(t, mm, []float32{1, 2, 3, -4, 5})
+
+	module, err := os.ReadFile("testdata/check_index.wasm")
+	assert.NoError(t, err)
+	hash := HashModule(module)
+	submit := &proto.TaskSubmit{
+		TaskId:     "task-1",
+		WasmModule: module,
+		WasmModSha: hash[:],
+		FuncName:   "check",
+		InputsRef:  map[string]string{"a": string(a.ID)},
+	}
+
+	var reported []int
+	progress := func(done int) { reported = append(reported, done) }
+	assert.NoError(t, executor.ExecuteRange(context.Background(), submit, 0, 3, progress, time.Hour))
+	assert.Equal(t, []int{3}, reported)
+
+	// The function trapping at an index fails the range there
+	reported = nil
+	err = executor.ExecuteRange(context.Background(), submit, 2, 5, progress, time.Hour)
+	var iterErr *scheduler.IterationError
+	if assert.ErrorAs(t, err, &iterErr) {
+		assert.Equal(t, 3, iterErr.Index)
+	}
+	assert.Equal(t, []int{1}, reported)
+
+	// Ranges can't write outputs
+	submit.OutputsRef = map[string]string{"c": string(a.ID)}
+	assert.ErrorContains(t, executor.ExecuteRange(context.Background(), submit, 0, 1, progress, time.Hour), "can't have outputs")
+}
@@ -0,0 +1,208 @@
+// Package wasm manages WASM modules used by tasks
+package wasm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/melihxz/holocompute/internal/log"
+)
+
+// DefaultImportCacheSize is the default number of compiled imports kept
+const DefaultImportCacheSize = 32
+
+// ModuleHash is the SHA256 hash of a module's bytecode
+type ModuleHash [sha256.Size]byte
+
+// HashModule returns the hash of a module's bytecode
+func HashModule(module []byte) ModuleHash {
+	return sha256.Sum256(module)
+}
+
+// String returns the hash in hex
+func (h ModuleHash) String() string {
+	return fmt.Sprintf("%x", h[:])
+}
+
+// CompiledModule is a compiled module that can be linked into instances
+type CompiledModule interface {
+	// Close releases the compiled module
+	Close(ctx context.Context) error
+}
+
+// CompileFunc compiles a module's bytecode
+type CompileFunc func(ctx context.Context, module []byte) (CompiledModule, error)
+
+// ImportCache keeps recently used compiled imports so they are compiled once
+// and linked into every new instance that imports them. Resolved modules are
+// reference counted, so one evicted while in use is closed once released.
+type ImportCache struct {
+	capacity int
+	compile  CompileFunc
+	entries  map[ModuleHash]*list.Element
+	lru      *list.List
+	logger   *log.Logger
+	mu       sync.Mutex
+}
+
+// importEntry holds a compiled import, or the compilation in progress
+type importEntry struct {
+	hash     ModuleHash
+	compiled CompiledModule
+	err      error
+	ready    chan struct{}
+
+	// Guarded by the cache's mu
+	refs    int  // resolutions not yet released
+	evicted bool // no longer cached, closed when refs drops to 0
+}
+
+// NewImportCache creates an import cache holding at most capacity modules
+func NewImportCache(capacity int, compile CompileFunc, logger *log.Logger) *ImportCache {
+	if capacity <= 0 {
+		capacity = DefaultImportCacheSize
+	}
+	return &ImportCache{
+		capacity: capacity,
+		compile:  compile,
+		entries:  make(map[ModuleHash]*list.Element),
+		lru:      list.New(),
+		logger:   logger,
+	}
+}
+
+// Resolve returns the compiled form of an imported module, compiling it
+// only if it isn't cached. Concurrent resolutions of the same module share
+// a single compilation. The module stays open until release is called.
+func (c *ImportCache) Resolve(ctx context.Context, module []byte) (compiled CompiledModule, release func(), err error) {
+	hash := HashModule(module)
+
+	c.mu.Lock()
+	if element, exists := c.entries[hash]; exists {
+		c.lru.MoveToFront(element)
+		entry := element.Value.(*importEntry)
+		entry.refs++
+		c.mu.Unlock()
+
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			c.release(entry)
+			return nil, nil, ctx.Err()
+		}
+		if entry.err != nil {
+			c.release(entry)
+			return nil, nil, entry.err
+		}
+		return entry.compiled, func() { c.release(entry) }, nil
+	}
+
+	entry := &importEntry{hash: hash, ready: make(chan struct{}), refs: 1}
+	c.entries[hash] = c.lru.PushFront(entry)
+	c.mu.Unlock()
+
+	c.logger.Debug("compiling imported module", "hash", hash)
+	entry.compiled, entry.err = c.compile(ctx, module)
+	if entry.err != nil {
+		entry.err = fmt.Errorf("failed to compile import %s: %w", hash, entry.err)
+	}
+	close(entry.ready)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry.err != nil {
+		// Failed compilations are not cached
+		if element, exists := c.entries[hash]; exists && element.Value == entry {
+			c.lru.Remove(element)
+			delete(c.entries, hash)
+		}
+		entry.refs--
+		return nil, nil, entry.err
+	}
+
+	c.evict()
+	return entry.compiled, func() { c.release(entry) }, nil
+}
+
+// release drops a reference to an entry, closing it if it was evicted and
+// this was the last one
+func (c *ImportCache) release(entry *importEntry) {
+	c.mu.Lock()
+	entry.refs--
+	closing := entry.evicted && entry.refs == 0
+	c.mu.Unlock()
+
+	if closing {
+		c.closeEntry(entry)
+	}
+}
+
+// evict removes least recently used modules until within capacity, closing
+// those not in use. The caller must hold mu.
+func (c *ImportCache) evict() {
+	for element := c.lru.Back(); element != nil && c.lru.Len() > c.capacity; {
+		entry := element.Value.(*importEntry)
+		prev := element.Prev()
+
+		// Modules still compiling are skipped; they are evicted later
+		select {
+		case <-entry.ready:
+			c.lru.Remove(element)
+			delete(c.entries, entry.hash)
+			entry.evicted = true
+			if entry.refs == 0 {
+				c.closeEntry(entry)
+			}
+		default:
+		}
+		element = prev
+	}
+}
+
+// closeEntry closes an evicted entry's module
+func (c *ImportCache) closeEntry(entry *importEntry) {
+	if entry.compiled == nil {
+		return
+	}
+	if err := entry.compiled.Close(context.Background()); err != nil {
+		c.logger.Warn("failed to close evicted import", "hash", entry.hash, "error", err)
+	}
+}
+
+// Len returns the number of cached modules
+func (c *ImportCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Close releases every cached module. Modules still in use are closed when
+// their last user releases them.
+func (c *ImportCache) Close(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for element := c.lru.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*importEntry)
+		select {
+		case <-entry.ready:
+		default:
+			continue
+		}
+		entry.evicted = true
+		if entry.refs > 0 || entry.compiled == nil {
+			continue
+		}
+		if err := entry.compiled.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	c.entries = make(map[ModuleHash]*list.Element)
+	c.lru.Init()
+	return firstErr
+}
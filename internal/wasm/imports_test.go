@@ -0,0 +1,150 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeModule records whether it has been closed
+type fakeModule struct {
+	source string
+	closed atomic.Bool
+}
+
+func (m *fakeModule) Close(ctx context.Context) error {
+	m.closed.Store(true)
+	return nil
+}
+
+// countingCompiler counts compilations per module
+type countingCompiler struct {
+	counts map[string]int
+	mu     sync.Mutex
+}
+
+func (c *countingCompiler) compile(ctx context.Context, module []byte) (CompiledModule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[string(module)]++
+	return &fakeModule{source: string(module)}, nil
+}
+
+func (c *countingCompiler) count(module string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[module]
+}
+
+func TestImportCache_SharedImportCompilesOnce(t *testing.T) {
+	compiler := &countingCompiler{counts: make(map[string]int)}
+	cache := NewImportCache(4, compiler.compile, log.New(slog.LevelDebug))
+
+	// Several tasks importing the same helper module
+	helper := []byte("helper")
+	var wg sync.WaitGroup
+	modules := make([]CompiledModule, 8)
+	for i := range modules {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			module, release, err := cache.Resolve(context.Background(), helper)
+			assert.NoError(t, err)
+			defer release()
+			modules[i] = module
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, compiler.count("helper"))
+	for _, module := range modules {
+		assert.Same(t, modules[0], module)
+	}
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestImportCache_Eviction(t *testing.T) {
+	compiler := &countingCompiler{counts: make(map[string]int)}
+	cache := NewImportCache(2, compiler.compile, log.New(slog.LevelDebug))
+	ctx := context.Background()
+
+	// resolve resolves a module and releases it straight away
+	resolve := func(module string) CompiledModule {
+		compiled, release, err := cache.Resolve(ctx, []byte(module))
+		assert.NoError(t, err)
+		release()
+		return compiled
+	}
+
+	a := resolve("a")
+	b := resolve("b")
+
+	// Touch a so b becomes least recently used
+	resolve("a")
+
+	resolve("c")
+	assert.Equal(t, 2, cache.Len())
+	assert.False(t, a.(*fakeModule).closed.Load())
+	assert.True(t, b.(*fakeModule).closed.Load())
+
+	// a stayed cached, b was evicted and compiles again
+	resolve("a")
+	resolve("b")
+	assert.Equal(t, 1, compiler.count("a"))
+	assert.Equal(t, 2, compiler.count("b"))
+
+	assert.NoError(t, cache.Close(ctx))
+	assert.True(t, a.(*fakeModule).closed.Load())
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestImportCache_FailedCompileNotCached(t *testing.T) {
+	calls := 0
+	compile := func(ctx context.Context, module []byte) (CompiledModule, error) {
+		calls++
+		return nil, errors.New("invalid module")
+	}
+	cache := NewImportCache(2, compile, log.New(slog.LevelDebug))
+
+	_, _, err := cache.Resolve(context.Background(), []byte("bad"))
+	assert.Error(t, err)
+	_, _, err = cache.Resolve(context.Background(), []byte("bad"))
+	assert.Error(t, err)
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestImportCache_EvictionWaitsForRelease(t *testing.T) {
+	compiler := &countingCompiler{counts: make(map[string]int)}
+	cache := NewImportCache(1, compiler.compile, log.New(slog.LevelDebug))
+	ctx := context.Background()
+
+	a, releaseA, err := cache.Resolve(ctx, []byte("a"))
+	assert.NoError(t, err)
+	_, releaseB, err := cache.Resolve(ctx, []byte("b"))
+	assert.NoError(t, err)
+
+	// a is evicted but still in use, so it stays open
+	assert.Equal(t, 1, cache.Len())
+	assert.False(t, a.(*fakeModule).closed.Load())
+
+	// It is closed once its last user releases it
+	releaseA()
+	assert.True(t, a.(*fakeModule).closed.Load())
+
+	// Closing the cache leaves modules in use open until released
+	b, releaseAgain, err := cache.Resolve(ctx, []byte("b"))
+	assert.NoError(t, err)
+	releaseAgain()
+	assert.NoError(t, cache.Close(ctx))
+	assert.False(t, b.(*fakeModule).closed.Load())
+	releaseB()
+	assert.True(t, b.(*fakeModule).closed.Load())
+}
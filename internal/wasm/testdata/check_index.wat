@@ -0,0 +1,11 @@
+;; Source of check_index.wasm: traps if a[i] is negative, for running over
+;; index ranges of a float32 array
+(module
+  (memory (export "memory") 1)
+  (func (export "check")
+    (param $i i32)
+    (param $a i32) (param $aLen i32)
+    (if (f32.lt
+          (f32.load (i32.add (local.get $a) (i32.shl (local.get $i) (i32.const 2))))
+          (f32.const 0))
+      (then unreachable))))
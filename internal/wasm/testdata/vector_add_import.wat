@@ -0,0 +1,20 @@
+;; Source of vector_add_import.wasm: c[i] = helper.add(a[i], b[i]) over
+;; float32 arrays
+(module
+  (import "helper" "add" (func $add (param f32 f32) (result f32)))
+  (memory (export "memory") 1)
+  (func (export "add")
+    (param $a i32) (param $aLen i32)
+    (param $b i32) (param $bLen i32)
+    (param $c i32) (param $cLen i32)
+    (local $i i32)
+    (block $done
+      (loop $next
+        (br_if $done (i32.ge_u (local.get $i) (local.get $cLen)))
+        (f32.store
+          (i32.add (local.get $c) (i32.shl (local.get $i) (i32.const 2)))
+          (call $add
+            (f32.load (i32.add (local.get $a) (i32.shl (local.get $i) (i32.const 2))))
+            (f32.load (i32.add (local.get $b) (i32.shl (local.get $i) (i32.const 2))))))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $next)))))
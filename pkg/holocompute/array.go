@@ -1,55 +1,488 @@
 package holocompute
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"sync"
 
 	"github.com/melihxz/holocompute/internal/dsm"
 )
 
+// ErrReadOnly is returned when writing to a read-only array
+var ErrReadOnly = errors.New("array is read-only")
+
+// ErrElementType is returned when a value doesn't match an array's element type
+var ErrElementType = errors.New("element type mismatch")
+
+// ErrRemotePage is returned when writing to a page owned by another node.
+// Writes can't be flushed back to a page's owner, so only the owner writes it.
+var ErrRemotePage = errors.New("page owned by another node")
+
+// ErrWriteConflict is returned by Sync under OptimisticWrite when pages were
+// committed by another writer after this one read them. The handle's writes
+// to those pages are discarded.
+type ErrWriteConflict struct {
+	ArrayID ArrayID
+	PageIDs []PageID
+
+	// Err is the first error a ConflictResolver returned, if any
+	Err error
+}
+
+// Error implements the error interface
+func (e *ErrWriteConflict) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("write conflict on pages %v of array %s: %v", e.PageIDs, e.ArrayID, e.Err)
+	}
+	return fmt.Sprintf("write conflict on pages %v of array %s", e.PageIDs, e.ArrayID)
+}
+
+// Unwrap returns the resolver's error
+func (e *ErrWriteConflict) Unwrap() error {
+	return e.Err
+}
+
+// ConflictResolver merges a page written under OptimisticWrite with the
+// contents another writer committed since it was read. base is the page as
+// first read, mine the page with this writer's changes and theirs the page
+// as now committed; the merged page is committed in their place. Returning
+// an error aborts the commit of the page.
+type ConflictResolver func(base, mine, theirs []byte) ([]byte, error)
+
+// maxResolveAttempts bounds how often a page is merged when other writers
+// keep committing it while it's being resolved
+const maxResolveAttempts = 3
+
+// resolverError is a ConflictResolver's error
+type resolverError struct {
+	err error
+}
+
+// Error implements the error interface
+func (e *resolverError) Error() string {
+	return fmt.Sprintf("failed to resolve conflict: %v", e.err)
+}
+
 // sharedArray implements the SharedArray interface
 type sharedArray struct {
 	cluster *Cluster
 	array   *dsm.Array
+
+	// How concurrent writers are kept apart, and how optimistic writes are
+	// merged on conflict
+	write    WritePolicy
+	resolver ConflictResolver
+
+	// State of the pages written through this handle. mu only guards the
+	// map; each page has its own lock, so reads, writes and syncs of
+	// different pages don't wait on each other.
+	pages map[dsm.PageID]*pageState
+
+	// Set once Close has released the array reference
+	closed bool
+	mu     sync.Mutex
+}
+
+// pageState tracks a page written through a handle since the last sync
+type pageState struct {
+	// Write lease held on the page, and the page written under it
+	lease *dsm.Lease
+	dirty *dsm.Page
+
+	// Page version seen before the first optimistic write
+	snapshot dsm.Version
+	snapped  bool
+
+	// Page contents seen before the first optimistic write; dirty then
+	// holds a private copy that only replaces the node's page once committed
+	base []byte
+
+	// Set once a sync dropped the state from the handle; writers that
+	// raced with it look the page's state up again
+	retired bool
+
+	mu sync.Mutex
+}
+
+// ID returns the cluster-wide identifier of the array
+func (sa *sharedArray) ID() ArrayID {
+	return sa.array.ID
 }
 
 // Len returns the length of the array
 func (sa *sharedArray) Len() int {
-	return sa.array.Length
+	return sa.array.Len()
+}
+
+// page fetches the page holding element i and returns the element's offset
+// within it. Pages of sparse arrays are only materialized for writes.
+// Reads see this handle's private copies.
+func (sa *sharedArray) page(i int, forWrite bool) (*dsm.Page, int, error) {
+	if i < 0 || i >= sa.array.Len() {
+		return nil, 0, fmt.Errorf("index out of bounds: %d", i)
+	}
+
+	pageID, offset := sa.array.PageAndOffset(i)
+	if forWrite && !sa.cluster.memoryManager.OwnsPages(sa.array.ID, pageID, pageID) {
+		return nil, 0, fmt.Errorf("%w: page %d of array %s", ErrRemotePage, pageID, sa.array.ID)
+	}
+
+	request := sa.cluster.memoryManager.RequestPage
+	if !forWrite {
+		if page := sa.privateCopy(pageID); page != nil {
+			return page, offset, nil
+		}
+		request = sa.cluster.memoryManager.ReadPage
+	}
+
+	page, err := request(context.Background(), sa.array.ID, pageID, sa.array.Version)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to request page: %w", err)
+	}
+	return page, offset, nil
 }
 
 // Get retrieves the element at index i
 func (sa *sharedArray) Get(i int) (interface{}, error) {
-	if i < 0 || i >= sa.array.Length {
-		return nil, fmt.Errorf("index out of bounds: %d", i)
+	page, offset, err := sa.page(i, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if sa.array.ElementType >= dsm.ElementCustom {
+		codec, err := sa.codec()
+		if err != nil {
+			return nil, err
+		}
+		return page.GetElement(codec, offset)
+	}
+
+	switch sa.array.ElementType {
+	case dsm.ElementInt32:
+		return page.GetInt32(offset)
+	case dsm.ElementFloat32:
+		return page.GetFloat32(offset)
+	case dsm.ElementFloat64:
+		return page.GetFloat64(offset)
+	case dsm.ElementFloat16:
+		return page.GetFloat16(offset)
+	case dsm.ElementBFloat16:
+		return page.GetBFloat16(offset)
+	default:
+		return page.GetInt64(offset)
+	}
+}
+
+// Set sets the element at index i to value v, which must match the array's element type
+func (sa *sharedArray) Set(i int, v interface{}) error {
+	// Reject values the array can't hold before touching any page
+	store, err := sa.storeFor(v)
+	if err != nil {
+		return err
 	}
+	return sa.writeAt(i, store)
+}
 
-	// Request the page
-	page, err := sa.cluster.memoryManager.RequestPage(context.Background(), sa.array.ID, 0, sa.array.Version)
+// Append adds v after the last element, growing the array as needed, and
+// returns its index. Concurrent appends get distinct indices.
+func (sa *sharedArray) Append(v interface{}) (int, error) {
+	store, err := sa.storeFor(v)
 	if err != nil {
-		return nil, fmt.Errorf("failed to request page: %w", err)
+		return 0, err
+	}
+	if sa.array.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	i, err := sa.cluster.memoryManager.AppendIndex(context.Background(), sa.array.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to grow array: %w", err)
+	}
+	return i, sa.writeAt(i, store)
+}
+
+// writeAt applies store to element i under its page's lock, so it doesn't
+// interleave with a sync of the page
+func (sa *sharedArray) writeAt(i int, store func(page *dsm.Page, offset int) error) error {
+	if sa.array.ReadOnly {
+		return ErrReadOnly
+	}
+
+	page, offset, err := sa.page(i, true)
+	if err != nil {
+		return err
+	}
+
+	ps := sa.lockState(page.ID)
+	defer ps.mu.Unlock()
+
+	if page, err = sa.prepareWriteLocked(ps, page); err != nil {
+		return err
+	}
+
+	// Private copies are logged to the WAL when committed
+	if ps.base != nil {
+		return store(page, offset)
+	}
+	return sa.cluster.memoryManager.WriteElement(sa.array.ID, sa.array.ElementType, page, offset, store)
+}
+
+// privateCopy returns the private copy of a page written since the last
+// sync, or nil if writes to it go to the node's page
+func (sa *sharedArray) privateCopy(pageID dsm.PageID) *dsm.Page {
+	if sa.write != OptimisticWrite {
+		return nil
+	}
+
+	sa.mu.Lock()
+	ps := sa.pages[pageID]
+	sa.mu.Unlock()
+	if ps == nil {
+		return nil
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.base == nil {
+		return nil
 	}
+	sa.cluster.memoryManager.TouchArray(sa.array.ID)
+	return ps.dirty
+}
+
+// storeFor returns a function writing v into a page, or ErrElementType if
+// the array can't hold it
+func (sa *sharedArray) storeFor(v interface{}) (func(page *dsm.Page, offset int) error, error) {
+	if sa.array.ElementType >= dsm.ElementCustom {
+		codec, err := sa.codec()
+		if err != nil {
+			return nil, err
+		}
+		if err := codec.Encode(v, make([]byte, codec.Size())); err != nil {
+			return nil, fmt.Errorf("%w: cannot store %T in %s array: %w", ErrElementType, v, sa.array.ElementType, err)
+		}
+		return func(page *dsm.Page, offset int) error { return page.SetElement(codec, offset, v) }, nil
+	}
+
+	var store func(page *dsm.Page, offset int) error
+	switch n := v.(type) {
+	case int64:
+		if sa.array.ElementType == dsm.ElementInt64 {
+			store = func(page *dsm.Page, offset int) error { return page.SetInt64(offset, n) }
+		}
+	case int32:
+		if sa.array.ElementType == dsm.ElementInt32 {
+			store = func(page *dsm.Page, offset int) error { return page.SetInt32(offset, n) }
+		}
+	case int:
+		switch sa.array.ElementType {
+		case dsm.ElementInt64:
+			store = func(page *dsm.Page, offset int) error { return page.SetInt64(offset, int64(n)) }
+		case dsm.ElementInt32:
+			if n >= math.MinInt32 && n <= math.MaxInt32 {
+				store = func(page *dsm.Page, offset int) error { return page.SetInt32(offset, int32(n)) }
+			}
+		}
+	case float32:
+		switch sa.array.ElementType {
+		case dsm.ElementFloat32:
+			store = func(page *dsm.Page, offset int) error { return page.SetFloat32(offset, n) }
+		case dsm.ElementFloat16:
+			store = func(page *dsm.Page, offset int) error { return page.SetFloat16(offset, n) }
+		case dsm.ElementBFloat16:
+			store = func(page *dsm.Page, offset int) error { return page.SetBFloat16(offset, n) }
+		}
+	case float64:
+		if sa.array.ElementType == dsm.ElementFloat64 {
+			store = func(page *dsm.Page, offset int) error { return page.SetFloat64(offset, n) }
+		}
+	}
+	if store == nil {
+		return nil, fmt.Errorf("%w: cannot store %T in %s array", ErrElementType, v, sa.array.ElementType)
+	}
+	return store, nil
+}
+
+// codec returns the codec of an array of a custom element type
+func (sa *sharedArray) codec() (ElementCodec, error) {
+	codec, exists := dsm.LookupElementCodec(sa.array.ElementType)
+	if !exists {
+		return nil, fmt.Errorf("%w: %d", dsm.ErrUnknownElementType, int(sa.array.ElementType))
+	}
+	return codec, nil
+}
+
+// state returns the state of a page, tracking it from its first write
+func (sa *sharedArray) state(pageID dsm.PageID) *pageState {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	ps, exists := sa.pages[pageID]
+	if !exists {
+		if sa.pages == nil {
+			sa.pages = make(map[dsm.PageID]*pageState)
+		}
+		ps = &pageState{}
+		sa.pages[pageID] = ps
+	}
+	return ps
+}
+
+// lockState returns the state of a page with its lock held
+func (sa *sharedArray) lockState(pageID dsm.PageID) *pageState {
+	for {
+		ps := sa.state(pageID)
+		ps.mu.Lock()
+		if !ps.retired {
+			return ps
+		}
+		ps.mu.Unlock()
+	}
+}
+
+// states returns the pages written through the handle and their states
+func (sa *sharedArray) states() ([]dsm.PageID, []*pageState) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
 
-	// Return the page
-	// A complete implementation would deserialize the element from page.Data
+	pageIDs := make([]dsm.PageID, 0, len(sa.pages))
+	states := make([]*pageState, 0, len(sa.pages))
+	for pageID, ps := range sa.pages {
+		pageIDs = append(pageIDs, pageID)
+		states = append(states, ps)
+	}
+	return pageIDs, states
+}
+
+// prepareWriteLocked holds a write lease on a page, or records its version
+// for optimistic writes, and marks it dirty. It returns the page to write
+// to, which for optimistic writes is a private copy, so a write that loses
+// a conflict never reaches the node's page. The caller must hold ps.mu.
+func (sa *sharedArray) prepareWriteLocked(ps *pageState, page *dsm.Page) (*dsm.Page, error) {
+	if sa.write == OptimisticWrite {
+		if err := sa.snapshotLocked(ps, page); err != nil {
+			return nil, err
+		}
+		page = copyPageLocked(ps, page)
+	} else if err := sa.holdWriteLeaseLocked(ps, page.ID); err != nil {
+		return nil, err
+	}
+
+	page.MarkDirty()
+	ps.dirty = page
 	return page, nil
 }
 
-// Set sets the element at index i to value v
-func (sa *sharedArray) Set(i int, v interface{}) error {
-	if i < 0 || i >= sa.array.Length {
-		return fmt.Errorf("index out of bounds: %d", i)
+// holdWriteLeaseLocked makes sure a valid write lease is held on a page.
+// The caller must hold ps.mu.
+func (sa *sharedArray) holdWriteLeaseLocked(ps *pageState, pageID dsm.PageID) error {
+	leases := sa.cluster.leases
+	if leases == nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	if ps.lease != nil {
+		if _, err := leases.ValidateLease(ctx, ps.lease.ID); err == nil {
+			return nil
+		}
+		ps.lease = nil
+	}
+
+	lease, err := leases.AcquireLease(ctx, sa.array.ID, pageID, dsm.WriteLease, string(sa.cluster.localNode), sa.array.Version)
+	if err != nil {
+		return fmt.Errorf("failed to acquire write lease: %w", err)
 	}
+	ps.lease = lease
+	return nil
+}
 
-	// Acquire a write lease for the page
-	// Fetch the page if needed
-	// Modify the page
-	// Mark the page as dirty
+// snapshotLocked records the version of a page the first time it is written
+// since the last sync. The caller must hold ps.mu.
+func (sa *sharedArray) snapshotLocked(ps *pageState, page *dsm.Page) error {
+	if ps.snapped {
+		return nil
+	}
 
-	// Return nil for now
+	version := page.Version
+	mm := sa.cluster.memoryManager
+	if mm.OwnsPages(sa.array.ID, page.ID, page.ID) {
+		var err error
+		if version, err = mm.PageVersion(context.Background(), sa.array.ID, page.ID); err != nil {
+			return fmt.Errorf("failed to read page version: %w", err)
+		}
+	}
+
+	ps.snapshot, ps.snapped = version, true
 	return nil
 }
 
+// copyPageLocked returns the private copy optimistic writes to a page go to,
+// keeping its contents as first read for a resolver. The caller must hold
+// ps.mu.
+func copyPageLocked(ps *pageState, page *dsm.Page) *dsm.Page {
+	if ps.base != nil {
+		return ps.dirty
+	}
+
+	ps.base = bytes.Clone(page.Bytes())
+	private := dsm.NewPage(page.ID, page.Version)
+	copy(private.Bytes(), ps.base)
+	return private
+}
+
+// commitCopyLocked commits a privately written page into the node's page.
+// If another writer committed the page meanwhile, the resolver merges their
+// contents with the copy and the merge is committed instead; without a
+// resolver the conflict is returned and the copy never published. The
+// caller must hold ps.mu.
+func (sa *sharedArray) commitCopyLocked(ctx context.Context, pageID dsm.PageID, ps *pageState) error {
+	mm := sa.cluster.memoryManager
+	base, data := ps.base, ps.dirty.Bytes()
+	expected := ps.snapshot
+	for attempt := 0; ; attempt++ {
+		_, err := mm.CommitPageData(ctx, sa.array.ID, pageID, expected, data)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, dsm.ErrVersionConflict) || sa.resolver == nil || attempt == maxResolveAttempts {
+			return err
+		}
+
+		// Merge against what the winner committed at the version it committed
+		var theirs []byte
+		if expected, theirs, err = mm.PageContents(ctx, sa.array.ID, pageID); err != nil {
+			return fmt.Errorf("failed to read page: %w", err)
+		}
+
+		merged, err := sa.resolver(base, data, theirs)
+		if err != nil {
+			return &resolverError{err: err}
+		}
+		if len(merged) != len(theirs) {
+			return &resolverError{err: fmt.Errorf("merged page is %d bytes, want %d", len(merged), len(theirs))}
+		}
+
+		// A further conflict merges against what's committed by then
+		base, data = theirs, merged
+	}
+}
+
+// releaseLeaseLocked releases the write lease held on a page, if any. The
+// caller must hold ps.mu.
+func (sa *sharedArray) releaseLeaseLocked(ps *pageState) error {
+	if ps.lease == nil {
+		return nil
+	}
+	err := sa.cluster.leases.ReleaseLease(context.Background(), ps.lease.ID)
+	ps.lease = nil
+	return err
+}
+
 // Slice returns a sub-array
 func (sa *sharedArray) Slice(begin, end int) SharedArray {
 	// Create a view of the array
@@ -57,21 +490,138 @@ func (sa *sharedArray) Slice(begin, end int) SharedArray {
 	return sa
 }
 
-// Sync synchronizes the array, flushing writes and revoking leases
+// Sync synchronizes the array, flushing writes and revoking leases. Each
+// page is synced under its own lock, leaving the others free to read and
+// write meanwhile. A page failing to sync doesn't stop the others from
+// being synced and their leases released; the errors are returned together.
 func (sa *sharedArray) Sync() error {
-	// Flush all dirty pages
-	// Revoke all write leases
-	// Bump the array version
+	// Read-only arrays have nothing to flush and hold no leases
+	if sa.array.ReadOnly {
+		return nil
+	}
 
-	// Return nil for now
-	return nil
+	var remote int
+	var conflicts []PageID
+	var resolveErr error
+	var errs []error
+	pageIDs, states := sa.states()
+	for i, ps := range states {
+		isRemote, err := sa.syncPage(pageIDs[i], ps)
+
+		var failed *resolverError
+		switch {
+		case errors.As(err, &failed):
+			conflicts = append(conflicts, pageIDs[i])
+			if resolveErr == nil {
+				resolveErr = failed.err
+			}
+		case errors.Is(err, dsm.ErrVersionConflict):
+			conflicts = append(conflicts, pageIDs[i])
+		case err != nil:
+			errs = append(errs, err)
+		case isRemote:
+			remote++
+		}
+	}
+
+	if remote > 0 {
+		errs = append(errs, fmt.Errorf("%w: writes to %d pages that moved to other nodes before the sync were dropped", ErrRemotePage, remote))
+	}
+	if len(conflicts) > 0 {
+		sort.Slice(conflicts, func(i, j int) bool { return conflicts[i] < conflicts[j] })
+		errs = append(errs, &ErrWriteConflict{ArrayID: sa.array.ID, PageIDs: conflicts, Err: resolveErr})
+	}
+	return errors.Join(errs...)
+}
+
+// syncPage flushes a page written since the last sync and releases its write
+// lease, whether or not the flush succeeded. It returns true if the page
+// moved to another node since it was written, dropping the writes, and the
+// page's write conflict if it lost one.
+func (sa *sharedArray) syncPage(pageID dsm.PageID, ps *pageState) (bool, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	// Pages owned here were written in place or to copies committed below.
+	// Only owned pages are written, but a rebalance may have moved one since;
+	// its writes can't reach the new owner, so they are dropped rather than
+	// kept for a commit that can never happen.
+	mm := sa.cluster.memoryManager
+	remote := ps.dirty != nil && !mm.OwnsPages(sa.array.ID, pageID, pageID)
+	if remote {
+		ps.dirty.ClearDirty()
+		ps.dirty, ps.snapped, ps.base = nil, false, nil
+	}
+
+	var conflict, failed error
+	if ps.dirty != nil && !remote {
+		// Optimistic writes commit only if nobody else committed the page
+		// meanwhile; a copy that lost is dropped
+		if sa.write == OptimisticWrite {
+			err := sa.commitCopyLocked(context.Background(), pageID, ps)
+
+			var resolveErr *resolverError
+			if errors.As(err, &resolveErr) || errors.Is(err, dsm.ErrVersionConflict) {
+				conflict = err
+			} else if err != nil {
+				failed = fmt.Errorf("failed to commit page %d: %w", pageID, err)
+			}
+		}
+	}
+
+	// A page that failed to commit keeps its writes for the next sync
+	if ps.dirty != nil && !remote && failed == nil {
+		if sa.write == OptimisticWrite {
+			ps.snapped, ps.base = false, nil
+		}
+		ps.dirty.ClearDirty()
+		ps.dirty = nil
+
+		// Replicas take the flushed page so it stays readable if this node fails
+		if conflict == nil {
+			if err := mm.ReplicatePage(context.Background(), sa.array.ID, pageID); err != nil {
+				sa.cluster.logger.Warn("failed to replicate page", "array_id", sa.array.ID, "page_id", pageID, "error", err)
+			}
+		}
+	}
+
+	if err := sa.releaseLeaseLocked(ps); err != nil {
+		failed = errors.Join(failed, fmt.Errorf("failed to release leases: %w", err))
+	}
+
+	// A clean page holding no lease has nothing left to track until it's
+	// written again
+	if ps.dirty == nil && !ps.snapped {
+		ps.retired = true
+		sa.mu.Lock()
+		if sa.pages[pageID] == ps {
+			delete(sa.pages, pageID)
+		}
+		sa.mu.Unlock()
+	}
+	if failed != nil {
+		return remote, failed
+	}
+	return remote, conflict
 }
 
 // Close releases resources associated with the array
 func (sa *sharedArray) Close() error {
-	// Release all leases
-	// Remove from local cache
+	_, states := sa.states()
+	for _, ps := range states {
+		ps.mu.Lock()
+		err := sa.releaseLeaseLocked(ps)
+		ps.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to release leases: %w", err)
+		}
+	}
 
-	// Return nil for now
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	if !sa.closed {
+		sa.closed = true
+		sa.cluster.memoryManager.ReleaseArray(sa.array.ID)
+	}
 	return nil
 }
@@ -0,0 +1,825 @@
+package holocompute
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestArray creates an array held entirely by a single local node
+func newTestArray(t *testing.T, length int) *sharedArray {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	mm := dsm.NewMemoryManager(bus, logger)
+
+	array, err := mm.CreateArray(context.TODO(), length, dsm.WithPlacement([]NodeID{"node-1"}))
+	assert.NoError(t, err)
+
+	c := &Cluster{localNode: "node-1", memoryManager: mm, leases: dsm.NewLeaseManager(time.Minute, logger), logger: logger}
+	return &sharedArray{cluster: c, array: array}
+}
+
+// newConnectedClusters returns cluster handles on two nodes joined in process
+func newConnectedClusters() (*Cluster, *Cluster) {
+	logger := log.New(slog.LevelDebug)
+	var clusters []*Cluster
+	var buses []*hyperbus.Bus
+	for _, id := range []NodeID{"node-1", "node-2"} {
+		mux := hyperbus.NewMux()
+		bus := hyperbus.New(hyperbus.NodeInfo{ID: id}, mux, logger)
+		mm := dsm.NewMemoryManager(bus, logger)
+		mux.Handle(hyperbus.MsgPageRequest, mm)
+		mux.Handle(hyperbus.MsgArrayQuery, mm)
+
+		buses = append(buses, bus)
+		clusters = append(clusters, &Cluster{localNode: id, memoryManager: mm, leases: dsm.NewLeaseManager(time.Minute, logger), logger: logger})
+	}
+	hyperbus.ConnectMemory(buses[0], buses[1])
+	return clusters[0], clusters[1]
+}
+
+func TestSharedArray_GetHighIndex(t *testing.T) {
+	elementsPerPage := dsm.PageSize / 8
+	sa := newTestArray(t, 3*elementsPerPage)
+
+	// Write the last element of the last page directly
+	page, err := sa.cluster.memoryManager.RequestPage(context.TODO(), sa.array.ID, 2, sa.array.Version)
+	assert.NoError(t, err)
+	assert.NoError(t, page.SetInt64(elementsPerPage-1, 42))
+
+	v, err := sa.Get(3*elementsPerPage - 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+
+	// The same offset on page 0 is untouched
+	v, err = sa.Get(elementsPerPage - 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), v)
+
+	_, err = sa.Get(3 * elementsPerPage)
+	assert.Error(t, err)
+}
+
+func TestSharedArray_GetAcrossPages(t *testing.T) {
+	elementsPerPage := dsm.PageSize / 8
+	sa := newTestArray(t, 4*elementsPerPage)
+
+	// Distinct values at the edges of every page
+	var indices []int
+	for p := 0; p < 4; p++ {
+		indices = append(indices, p*elementsPerPage, p*elementsPerPage+1, (p+1)*elementsPerPage-1)
+	}
+	for _, i := range indices {
+		assert.NoError(t, sa.Set(i, int64(i)*10+7))
+	}
+
+	for _, i := range indices {
+		v, err := sa.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i)*10+7, v, "index %d", i)
+	}
+}
+
+func TestSharedArray_ReadOnly(t *testing.T) {
+	c := newTestCluster()
+	data := make([]int64, 100)
+	data[5] = 42
+
+	// The contents are written before the array is sealed
+	p := local
+	p.ReadOnly = true
+	ta, err := NewTypedArrayFrom(c, data, p)
+	assert.NoError(t, err)
+	sa := ta.sa
+	assert.True(t, sa.array.ReadOnly)
+
+	assert.ErrorIs(t, sa.Set(5, int64(7)), ErrReadOnly)
+	_, err = sa.Append(int64(7))
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	v, err := sa.Get(5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+
+	// The rejected write left the page untouched, reads took no leases and
+	// there is nothing to sync
+	page, err := c.memoryManager.RequestPage(context.TODO(), sa.array.ID, 0, sa.array.Version)
+	assert.NoError(t, err)
+	v, err = page.GetInt64(5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+	assert.Empty(t, c.leases.LeasesForArray(sa.array.ID))
+	assert.False(t, page.Dirty())
+	assert.NoError(t, sa.Sync())
+}
+
+func TestNewSharedArray(t *testing.T) {
+	c, err := Connect(context.TODO(), Options{})
+	assert.NoError(t, err)
+
+	arr, err := c.NewSharedArray(1000, Policy{Replication: 2, Compression: LZ4Compression})
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, arr.Len())
+
+	sa := arr.(*sharedArray)
+	assert.Same(t, c, sa.cluster)
+	assert.Equal(t, 2, sa.array.Replication)
+	assert.Equal(t, proto.Encoding_LZ4, sa.array.Compression)
+
+	// Pages default to the local node, so reads and writes work right away
+	assert.NoError(t, arr.Set(999, int64(5)))
+	v, err := arr.Get(999)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), v)
+
+	_, err = c.NewSharedArray(10, Policy{Replication: -1})
+	assert.Error(t, err)
+}
+
+func TestSharedArray_SetRoundTrip(t *testing.T) {
+	sa := newTestArray(t, 100)
+	leases := sa.cluster.leases
+
+	assert.NoError(t, sa.Set(5, 42))
+	v, err := sa.Get(5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+
+	// The write is covered by a lease and left the page dirty
+	held := leases.LeasesForArray(sa.array.ID)
+	assert.Len(t, held, 1)
+	assert.Equal(t, dsm.WriteLease, held[0].Type)
+	page, err := sa.cluster.memoryManager.RequestPage(context.TODO(), sa.array.ID, 0, sa.array.Version)
+	assert.NoError(t, err)
+	assert.True(t, page.Dirty())
+
+	// Later writes to the page reuse the lease
+	assert.NoError(t, sa.Set(6, 43))
+	assert.Len(t, leases.LeasesForArray(sa.array.ID), 1)
+
+	// Sync releases the lease and marks the page clean
+	assert.NoError(t, sa.Sync())
+	assert.Empty(t, leases.LeasesForArray(sa.array.ID))
+	assert.False(t, page.Dirty())
+
+	v, err = sa.Get(6)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(43), v)
+}
+
+func TestSharedArray_SetConflictingLease(t *testing.T) {
+	sa := newTestArray(t, 100)
+
+	// Another writer holds page 0
+	_, err := sa.cluster.leases.AcquireLease(context.TODO(), sa.array.ID, 0, dsm.WriteLease, "node-2", sa.array.Version)
+	assert.NoError(t, err)
+
+	assert.ErrorContains(t, sa.Set(5, 42), "failed to acquire write lease")
+}
+
+func TestSharedArray_OptimisticWriteConflict(t *testing.T) {
+	first := newTestArray(t, 2*dsm.PageSize/8)
+	first.write = OptimisticWrite
+	second := &sharedArray{cluster: first.cluster, array: first.array, write: OptimisticWrite}
+	elementsPerPage := dsm.PageSize / 8
+
+	// Both writers read page 0, only the second touches page 1
+	assert.NoError(t, first.Set(0, 1))
+	assert.NoError(t, second.Set(1, 2))
+	assert.NoError(t, second.Set(elementsPerPage, 3))
+
+	// Optimistic writes take no leases
+	assert.Empty(t, first.cluster.leases.LeasesForArray(first.array.ID))
+
+	// The first to sync wins, the second is told which pages it lost
+	assert.NoError(t, first.Sync())
+	err := second.Sync()
+	var conflict *ErrWriteConflict
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, first.array.ID, conflict.ArrayID)
+	assert.Equal(t, []PageID{0}, conflict.PageIDs)
+
+	// The losing write never reached the page, while the uncontested page committed
+	reader := &sharedArray{cluster: first.cluster, array: first.array}
+	for i, want := range map[int]int64{0: 1, 1: 0, elementsPerPage: 3} {
+		v, err := reader.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, want, v, "element %d", i)
+	}
+
+	// A fresh read after the conflict commits cleanly
+	assert.NoError(t, second.Set(1, 2))
+	assert.NoError(t, second.Sync())
+}
+
+func TestSharedArray_OptimisticWriteDisjointPages(t *testing.T) {
+	first := newTestArray(t, 2*dsm.PageSize/8)
+	first.write = OptimisticWrite
+	second := &sharedArray{cluster: first.cluster, array: first.array, write: OptimisticWrite}
+
+	assert.NoError(t, first.Set(0, 1))
+	assert.NoError(t, second.Set(dsm.PageSize/8, 2))
+	assert.NoError(t, first.Sync())
+	assert.NoError(t, second.Sync())
+}
+
+// mergeChanges resolves conflicts by taking the bytes this writer changed
+// and the committed bytes elsewhere
+func mergeChanges(base, mine, theirs []byte) ([]byte, error) {
+	merged := make([]byte, len(theirs))
+	for i := range merged {
+		if mine[i] != base[i] {
+			merged[i] = mine[i]
+		} else {
+			merged[i] = theirs[i]
+		}
+	}
+	return merged, nil
+}
+
+func TestSharedArray_OptimisticWriteResolver(t *testing.T) {
+	first := newTestArray(t, dsm.PageSize/8)
+	first.write = OptimisticWrite
+	first.resolver = mergeChanges
+	second := &sharedArray{cluster: first.cluster, array: first.array, write: OptimisticWrite, resolver: mergeChanges}
+	mm := first.cluster.memoryManager
+	before, err := mm.PageVersion(context.Background(), first.array.ID, 0)
+	assert.NoError(t, err)
+
+	// Both writers change a different element of page 0
+	assert.NoError(t, first.Set(0, 1))
+	assert.NoError(t, second.Set(1, 2))
+
+	// Writes stay private to their handle until synced
+	v, err := second.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), v)
+	v, err = first.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), v)
+
+	// The second writer's conflict is merged rather than reported
+	assert.NoError(t, first.Sync())
+	assert.NoError(t, second.Sync())
+
+	reader := &sharedArray{cluster: first.cluster, array: first.array}
+	for i, want := range []int64{1, 2} {
+		v, err := reader.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, want, v)
+	}
+
+	// Each commit bumped the version
+	after, err := mm.PageVersion(context.Background(), first.array.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, before+2, after)
+}
+
+func TestSharedArray_OptimisticWriteResolversRace(t *testing.T) {
+	first := newTestArray(t, dsm.PageSize/8)
+	first.write, first.resolver = OptimisticWrite, mergeChanges
+	second := &sharedArray{cluster: first.cluster, array: first.array, write: OptimisticWrite, resolver: mergeChanges}
+	reader := &sharedArray{cluster: first.cluster, array: first.array}
+
+	// Each round both writers change their own element and sync at once;
+	// neither may lose the other's committed update while merging
+	for round := 1; round <= 50; round++ {
+		assert.NoError(t, first.Set(0, int64(round)))
+		assert.NoError(t, second.Set(1, int64(-round)))
+
+		var wg sync.WaitGroup
+		for _, sa := range []*sharedArray{first, second} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, sa.Sync())
+			}()
+		}
+		wg.Wait()
+
+		for i, want := range []int64{int64(round), int64(-round)} {
+			v, err := reader.Get(i)
+			assert.NoError(t, err)
+			assert.Equal(t, want, v, "round %d, element %d", round, i)
+		}
+	}
+}
+
+func TestSharedArray_OptimisticWriteResolverFails(t *testing.T) {
+	errUnmergeable := errors.New("unmergeable")
+	first := newTestArray(t, dsm.PageSize/8)
+	first.write = OptimisticWrite
+	second := &sharedArray{cluster: first.cluster, array: first.array, write: OptimisticWrite,
+		resolver: func(base, mine, theirs []byte) ([]byte, error) { return nil, errUnmergeable }}
+
+	assert.NoError(t, first.Set(0, 1))
+	assert.NoError(t, second.Set(1, 2))
+	assert.NoError(t, first.Sync())
+
+	// The resolver's error aborts the commit
+	err := second.Sync()
+	var conflict *ErrWriteConflict
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, []PageID{0}, conflict.PageIDs)
+	assert.ErrorIs(t, err, errUnmergeable)
+
+	v, err := first.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), v)
+}
+
+func TestSharedArray_SyncForgetsCleanPages(t *testing.T) {
+	elementsPerPage := dsm.PageSize / 8
+	for _, policy := range []WritePolicy{ExclusiveWrite, OptimisticWrite} {
+		sa := newTestArray(t, 8*elementsPerPage)
+		sa.write = policy
+
+		// A handle writing across a large array doesn't keep state for
+		// every page it ever touched
+		for p := 0; p < 8; p++ {
+			assert.NoError(t, sa.Set(p*elementsPerPage, int64(p+1)))
+		}
+		assert.Len(t, sa.pages, 8)
+		assert.NoError(t, sa.Sync())
+		assert.Empty(t, sa.pages)
+		assert.Empty(t, sa.cluster.leases.LeasesForArray(sa.array.ID))
+
+		// Pages written again are tracked afresh
+		assert.NoError(t, sa.Set(1, 9))
+		assert.NoError(t, sa.Sync())
+		v, err := sa.Get(1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(9), v)
+	}
+}
+
+func TestSharedArray_SyncPastFailingPage(t *testing.T) {
+	elementsPerPage := dsm.PageSize / 8
+	sa := newTestArray(t, 4*elementsPerPage)
+	for p := 0; p < 4; p++ {
+		assert.NoError(t, sa.Set(p*elementsPerPage, int64(p+1)))
+	}
+
+	// The lease on page 2 is revoked behind the handle's back, so releasing
+	// it fails, whichever order the pages are synced in
+	assert.NoError(t, sa.cluster.leases.RevokeLease(context.TODO(), sa.array.ID, 2))
+	assert.ErrorContains(t, sa.Sync(), "failed to release leases")
+
+	// The other pages were still flushed and their leases released
+	assert.Empty(t, sa.cluster.leases.LeasesForArray(sa.array.ID))
+	assert.Empty(t, sa.pages)
+	other := &sharedArray{cluster: sa.cluster, array: sa.array}
+	assert.NoError(t, other.Set(elementsPerPage+1, 5))
+	assert.NoError(t, other.Sync())
+}
+
+func TestSharedArray_SetRemotePage(t *testing.T) {
+	c1, c2 := newConnectedClusters()
+	c1.memoryManager.SetLivenessChecker(aliveNodes{"node-1": true, "node-2": true})
+	elementsPerPage := dsm.PageSize / 8
+
+	arr, err := c1.createArray(2*elementsPerPage, Policy{Placement: []NodeID{"node-1", "node-2"}}, dsm.ElementInt64)
+	assert.NoError(t, err)
+	_, err = c2.OpenArray(context.TODO(), arr.ID())
+	assert.NoError(t, err)
+
+	// The write to node-2's page is refused up front instead of being lost at Sync
+	assert.NoError(t, arr.Set(1, int64(1)))
+	assert.ErrorIs(t, arr.Set(elementsPerPage+1, int64(2)), ErrRemotePage)
+	assert.Len(t, arr.pages, 1)
+	assert.NoError(t, arr.Sync())
+
+	v, err := arr.Get(elementsPerPage + 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), v)
+}
+
+func TestSharedArray_SyncLeavesOtherPagesFree(t *testing.T) {
+	elementsPerPage := dsm.PageSize / 8
+	other := newTestArray(t, 2*elementsPerPage)
+	other.write = OptimisticWrite
+
+	merging := make(chan struct{})
+	release := make(chan struct{})
+	sa := &sharedArray{cluster: other.cluster, array: other.array, write: OptimisticWrite,
+		resolver: func(base, mine, theirs []byte) ([]byte, error) {
+			close(merging)
+			<-release
+			return mergeChanges(base, mine, theirs)
+		}}
+
+	// Another writer commits page 1 first, so syncing it needs a merge
+	assert.NoError(t, sa.Set(elementsPerPage, 1))
+	assert.NoError(t, other.Set(elementsPerPage+1, 2))
+	assert.NoError(t, other.Sync())
+
+	synced := make(chan error)
+	go func() { synced <- sa.Sync() }()
+	<-merging
+
+	// Page 0 stays readable and writable while page 1 is being synced
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, sa.Set(0, 3))
+		v, err := sa.Get(0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), v)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("page 0 blocked by the sync of page 1")
+	}
+
+	close(release)
+	assert.NoError(t, <-synced)
+	assert.NoError(t, sa.Sync())
+}
+
+func TestSharedArray_ConcurrentReadersDuringSync(t *testing.T) {
+	elementsPerPage := dsm.PageSize / 8
+	writer := newTestArray(t, 2*elementsPerPage)
+	leases := writer.cluster.leases
+	for i := 0; i < elementsPerPage; i++ {
+		assert.NoError(t, writer.Set(i, int64(i)))
+	}
+	assert.NoError(t, writer.Sync())
+
+	// Readers hold read leases on page 0 while the writer holds page 1
+	const readers = 4
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for r := 0; r < readers; r++ {
+		lease, err := leases.AcquireLease(context.Background(), writer.array.ID, 0, dsm.ReadLease, fmt.Sprintf("reader-%d", r), writer.array.Version)
+		assert.NoError(t, err)
+		reader := &sharedArray{cluster: writer.cluster, array: writer.array}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i = (i + 1) % elementsPerPage {
+				select {
+				case <-stop:
+					_, err := leases.ValidateLease(context.Background(), lease.ID)
+					assert.NoError(t, err)
+					return
+				default:
+				}
+
+				// Readers share the writer's handle as well as their own
+				for _, sa := range []*sharedArray{reader, writer} {
+					v, err := sa.Get(i)
+					assert.NoError(t, err)
+					assert.Equal(t, int64(i), v)
+				}
+			}
+		}()
+	}
+
+	for round := 0; round < 100; round++ {
+		for i := elementsPerPage; i < elementsPerPage+16; i++ {
+			assert.NoError(t, writer.Set(i, int64(round)))
+		}
+		assert.NoError(t, writer.Sync())
+	}
+	close(stop)
+	wg.Wait()
+
+	v, err := writer.Get(elementsPerPage)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(99), v)
+}
+
+func TestSharedArray_AccessUpdatesLastAccess(t *testing.T) {
+	sa := newTestArray(t, dsm.PageSize/8)
+	idle := newTestArray(t, dsm.PageSize/8)
+
+	// Writes and reads both stamp the array
+	before := time.Now()
+	assert.NoError(t, sa.Set(0, 1))
+	assert.False(t, sa.array.LastAccess().Before(before))
+
+	before = time.Now()
+	_, err := sa.Get(0)
+	assert.NoError(t, err)
+	assert.False(t, sa.array.LastAccess().Before(before))
+
+	// So do reads served from a handle's private copy
+	sa.write = OptimisticWrite
+	assert.NoError(t, sa.Set(1, 2))
+	assert.NotNil(t, sa.privateCopy(0))
+	before = time.Now()
+	_, err = sa.Get(1)
+	assert.NoError(t, err)
+	assert.False(t, sa.array.LastAccess().Before(before))
+
+	assert.True(t, idle.array.LastAccess().Before(sa.array.LastAccess()))
+}
+
+func TestSharedArray_ConcurrentAppend(t *testing.T) {
+	sa := newTestArray(t, 0)
+	sa.write = OptimisticWrite // writers share pages without leases
+
+	const goroutines, perGoroutine = 8, 2500
+	indices := make([][]int, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for k := 0; k < perGoroutine; k++ {
+				i, err := sa.Append(int64(g*perGoroutine + k))
+				assert.NoError(t, err)
+				indices[g] = append(indices[g], i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// Every append got its own index and the value landed there
+	total := goroutines * perGoroutine
+	assert.Equal(t, total, sa.Len())
+	seen := make(map[int]bool)
+	for g, own := range indices {
+		for k, i := range own {
+			assert.False(t, seen[i], "index %d returned twice", i)
+			seen[i] = true
+
+			v, err := sa.Get(i)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(g*perGoroutine+k), v)
+		}
+	}
+	assert.Len(t, seen, total)
+
+	// Pages doubled rather than growing one append at a time
+	elementsPerPage := dsm.PageSize / 8
+	assert.Equal(t, 4, sa.array.PageCount())
+	assert.Greater(t, sa.array.PageCount()*elementsPerPage, total)
+}
+
+func TestSharedArray_AppendTypeMismatch(t *testing.T) {
+	sa := newTestArray(t, 10)
+
+	_, err := sa.Append(1.5)
+	assert.ErrorIs(t, err, ErrElementType)
+	assert.Equal(t, 10, sa.Len())
+
+	i, err := sa.Append(int64(7))
+	assert.NoError(t, err)
+	assert.Equal(t, 10, i)
+}
+
+func TestSharedArray_GetElementMapping(t *testing.T) {
+	sa := newTestArray(t, 30000)
+
+	// 8192 int64 elements fit in a page, so element 20000 is in page 2
+	pageID, offset := sa.array.PageAndOffset(20000)
+	assert.Equal(t, dsm.PageID(2), pageID)
+	assert.Equal(t, 20000-2*8192, offset)
+
+	page, err := sa.cluster.memoryManager.RequestPage(context.TODO(), sa.array.ID, 2, sa.array.Version)
+	assert.NoError(t, err)
+	assert.NoError(t, page.SetInt64(offset, 777))
+
+	v, err := sa.Get(20000)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(777), v)
+
+	// The same offset in pages 0 and 1 is untouched
+	for _, i := range []int{offset, 8192 + offset} {
+		v, err := sa.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), v)
+	}
+}
+
+func TestSharedArray_GetElementWidth(t *testing.T) {
+	c := newTestCluster()
+
+	// 16384 float32 elements fit in a page, twice as many as int64
+	f32, err := c.createArray(40000, local, dsm.ElementFloat32)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, f32.array.NumPages)
+
+	for _, i := range []int{0, 16383, 16384, 20000, 32768, 39999} {
+		assert.NoError(t, f32.Set(i, float32(i)+0.5))
+	}
+	for _, i := range []int{0, 16383, 16384, 20000, 32768, 39999} {
+		v, err := f32.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(i)+0.5, v, "index %d", i)
+	}
+
+	// Element 20000 is on the second page for float32
+	pageID, _ := f32.array.PageAndOffset(20000)
+	assert.Equal(t, dsm.PageID(1), pageID)
+}
+
+func TestCluster_OpenArray(t *testing.T) {
+	c1, c2 := newConnectedClusters()
+
+	created, err := c1.NewSharedArray(100, Policy{})
+	assert.NoError(t, err)
+	for i := 0; i < created.Len(); i++ {
+		assert.NoError(t, created.Set(i, int64(i*i)))
+	}
+	assert.NoError(t, created.Sync())
+
+	// The other node opens the array by ID and reads the same data
+	opened, err := c2.OpenArray(context.TODO(), created.ID())
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID(), opened.ID())
+	assert.Equal(t, created.Len(), opened.Len())
+	for i := 0; i < opened.Len(); i++ {
+		v, err := opened.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i*i), v)
+	}
+	assert.Equal(t, 1, c2.memoryManager.RefCount(created.ID()))
+
+	// Closing releases the reference once
+	assert.NoError(t, opened.Close())
+	assert.NoError(t, opened.Close())
+	assert.Equal(t, 0, c2.memoryManager.RefCount(created.ID()))
+	assert.Equal(t, 1, c1.memoryManager.RefCount(created.ID()))
+
+	_, err = c2.OpenArray(context.TODO(), "missing")
+	assert.ErrorContains(t, err, "array not found")
+}
+
+func TestCluster_NamedArray(t *testing.T) {
+	c1, c2 := newConnectedClusters()
+
+	created, err := c1.CreateNamedArray(context.TODO(), "squares", 10, Policy{})
+	assert.NoError(t, err)
+	assert.NoError(t, created.Set(3, int64(9)))
+
+	// The name reaches the other node through gossip
+	state := &proto.ClusterState{}
+	c1.memoryManager.ArrayNames().ContributeState(state)
+	c2.memoryManager.ArrayNames().ObserveState(state)
+
+	opened, err := c2.OpenNamedArray(context.TODO(), "squares")
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID(), opened.ID())
+	v, err := opened.Get(3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), v)
+
+	// Duplicate names are rejected on either node
+	var taken *dsm.ErrNameTaken
+	_, err = c1.CreateNamedArray(context.TODO(), "squares", 10, Policy{})
+	assert.ErrorAs(t, err, &taken)
+	_, err = c2.CreateNamedArray(context.TODO(), "squares", 10, Policy{})
+	assert.ErrorAs(t, err, &taken)
+	assert.Equal(t, created.ID(), taken.ArrayID)
+	assert.Len(t, c2.memoryManager.ListArrays(), 1)
+
+	_, err = c2.OpenNamedArray(context.TODO(), "cubes")
+	assert.ErrorContains(t, err, "no array named")
+}
+
+func TestCluster_NewSharedArrayElemType(t *testing.T) {
+	c := newTestCluster()
+
+	// Four-byte elements fit PageSize/4 to a page
+	perPage := dsm.PageSize / 4
+	for _, elemType := range []ElemType{Float32Element, Int32Element} {
+		arr, err := c.NewSharedArray(2*perPage+1, Policy{Placement: local.Placement, ElemType: elemType})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, arr.(*sharedArray).array.NumPages)
+
+		// The first element of the second page lands at its start
+		pageID, offset := arr.(*sharedArray).array.PageAndOffset(perPage)
+		assert.Equal(t, dsm.PageID(1), pageID)
+		assert.Equal(t, 0, offset)
+	}
+
+	f32, err := c.NewSharedArray(2*perPage+1, Policy{Placement: local.Placement, ElemType: Float32Element})
+	assert.NoError(t, err)
+	assert.NoError(t, f32.Set(perPage, float32(2.5)))
+	v, err := f32.Get(perPage)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(2.5), v)
+
+	i32, err := c.NewSharedArray(2*perPage+1, Policy{Placement: local.Placement, ElemType: Int32Element})
+	assert.NoError(t, err)
+	assert.NoError(t, i32.Set(2*perPage, int32(-7)))
+	assert.NoError(t, i32.Set(perPage-1, 42))
+	v, err = i32.Get(2 * perPage)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(-7), v)
+	v, err = i32.Get(perPage - 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(42), v)
+	assert.ErrorIs(t, i32.Set(0, int64(1)), ErrElementType)
+	assert.ErrorIs(t, i32.Set(0, 1<<40), ErrElementType)
+
+	// Without an element type arrays hold int64 as before
+	i64, err := c.NewSharedArray(perPage, local)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, i64.(*sharedArray).array.NumPages)
+}
+
+// complexCodec encodes complex128 elements as two little-endian float64s
+type complexCodec struct{}
+
+func (complexCodec) Size() int { return 16 }
+
+func (complexCodec) Encode(v any, dst []byte) error {
+	c, ok := v.(complex128)
+	if !ok {
+		return fmt.Errorf("want complex128, got %T", v)
+	}
+	binary.LittleEndian.PutUint64(dst[:8], math.Float64bits(real(c)))
+	binary.LittleEndian.PutUint64(dst[8:], math.Float64bits(imag(c)))
+	return nil
+}
+
+func (complexCodec) Decode(src []byte) any {
+	re := math.Float64frombits(binary.LittleEndian.Uint64(src[:8]))
+	im := math.Float64frombits(binary.LittleEndian.Uint64(src[8:]))
+	return complex(re, im)
+}
+
+// customTypes hands out element types, since the codec registry is global
+// and keeps registrations across test runs in the same binary
+var customTypes atomic.Int32
+
+func TestSharedArray_CustomElementCodec(t *testing.T) {
+	complexElement := CustomElement + ElemType(customTypes.Add(1))
+	assert.NoError(t, RegisterElementCodec(complexElement, "complex128", complexCodec{}))
+	assert.Error(t, RegisterElementCodec(complexElement, "complex128", complexCodec{}))
+	assert.Error(t, RegisterElementCodec(Float32Element, "float32", complexCodec{}))
+
+	c := newTestCluster()
+	perPage := dsm.PageSize / 16
+	arr, err := c.NewSharedArray(2*perPage+1, Policy{Placement: local.Placement, ElemType: complexElement})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, arr.(*sharedArray).array.NumPages)
+	assert.Equal(t, "complex128", arr.(*sharedArray).array.ElementType.String())
+
+	// Values round-trip, including across page boundaries
+	for _, i := range []int{0, perPage - 1, perPage, 2 * perPage} {
+		want := complex(float64(i), -float64(i)/2)
+		assert.NoError(t, arr.Set(i, want))
+		v, err := arr.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, want, v)
+	}
+	assert.ErrorIs(t, arr.Set(0, 1.5), ErrElementType)
+
+	// Types nobody registered can't be allocated
+	_, err = c.NewSharedArray(10, Policy{Placement: local.Placement, ElemType: CustomElement + 99})
+	assert.ErrorIs(t, err, dsm.ErrUnknownElementType)
+}
+
+func TestSharedArray_Sparse(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	c := &Cluster{localNode: "node-1", memoryManager: dsm.NewMemoryManager(bus, logger), leases: dsm.NewLeaseManager(time.Minute, logger), logger: logger}
+
+	elementsPerPage := dsm.PageSize / 8
+	arr, err := c.NewSharedArray(1000*elementsPerPage, Policy{Sparse: true})
+	assert.NoError(t, err)
+	sa := arr.(*sharedArray)
+
+	// Reads of untouched pages see zeroes without materializing them
+	for _, i := range []int{0, 500 * elementsPerPage, 1000*elementsPerPage - 1} {
+		v, err := sa.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), v)
+	}
+	assert.Zero(t, sa.array.ResidentPages())
+
+	// Only the written pages are materialized
+	for _, i := range []int{3, 7, 42 * elementsPerPage, 999 * elementsPerPage} {
+		assert.NoError(t, sa.Set(i, int64(i)))
+	}
+	assert.NoError(t, sa.Sync())
+	assert.Equal(t, 1000, sa.array.PageCount())
+	assert.Equal(t, 3, sa.array.ResidentPages())
+
+	v, err := sa.Get(42 * elementsPerPage)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42*elementsPerPage), v)
+	v, err = sa.Get(43 * elementsPerPage)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), v)
+	assert.Equal(t, 3, sa.array.ResidentPages())
+}
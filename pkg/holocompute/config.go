@@ -0,0 +1,110 @@
+package holocompute
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/config"
+	"github.com/melihxz/holocompute/internal/configsync"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// ClusterConfig is the configuration every node of the cluster shares
+type ClusterConfig = config.ClusterConfig
+
+// DefaultConfigTimeout bounds how long Config and UpdateConfig wait for each agent
+const DefaultConfigTimeout = 2 * time.Second
+
+// Config returns the cluster configuration the agents run with, as last
+// updated: every alive agent is queried and the configuration of the most
+// recent update wins. Agents that don't answer in time are left out.
+func (c *Cluster) Config(ctx context.Context) (*ClusterConfig, error) {
+	if c.bus == nil {
+		return nil, errors.New("cluster not connected")
+	}
+
+	agents := c.agents()
+	docs := make([]*proto.ConfigDocument, len(agents))
+	var wg sync.WaitGroup
+	for i, nodeID := range agents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			queryCtx, cancel := context.WithTimeout(ctx, DefaultConfigTimeout)
+			defer cancel()
+
+			doc, err := configsync.Query(queryCtx, c.bus, nodeID)
+			if err != nil {
+				c.logger.Warn("member config unavailable", "node_id", nodeID, "error", err)
+				return
+			}
+			docs[i] = doc
+		}()
+	}
+	wg.Wait()
+
+	var latest *proto.ConfigDocument
+	for _, doc := range docs {
+		if doc != nil && (latest == nil || doc.Version > latest.Version) {
+			latest = doc
+		}
+	}
+	if latest == nil {
+		return nil, errors.New("no agent answered the config query")
+	}
+
+	cc, err := config.Import(latest.Config)
+	if err != nil {
+		return nil, fmt.Errorf("config of %s: %w", latest.NodeId, err)
+	}
+	return cc, nil
+}
+
+// UpdateConfig signs cc with key and pushes it to every alive agent, each
+// of which checks the signer is trusted, validates cc and saves it over its
+// own cluster-wide settings. It returns the outcome for each agent, nil for
+// those that applied it.
+func (c *Cluster) UpdateConfig(ctx context.Context, cc *ClusterConfig, key ed25519.PrivateKey) (map[NodeID]error, error) {
+	if c.bus == nil {
+		return nil, errors.New("cluster not connected")
+	}
+	if err := cc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	agents := c.agents()
+	if len(agents) == 0 {
+		return nil, errors.New("no agents to update")
+	}
+
+	// Issue times order updates, so a later one always supersedes this
+	update, err := configsync.Sign(cc, time.Now().UnixNano(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[NodeID]error, len(agents))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, nodeID := range agents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			pushCtx, cancel := context.WithTimeout(ctx, DefaultConfigTimeout)
+			defer cancel()
+
+			err := configsync.Push(pushCtx, c.bus, nodeID, update)
+			mu.Lock()
+			results[nodeID] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
@@ -0,0 +1,66 @@
+package holocompute
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/config"
+	"github.com/melihxz/holocompute/internal/configsync"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCluster_UpdateConfig(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, operator, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	trusted := hyperbus.NewTrustedKeys(operator.Public().(ed25519.PublicKey))
+
+	// Two agents serve the config they run with
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, hyperbus.NewMux(), logger)
+	members := membership.NewMembership(&membership.Member{ID: "node-1"}, logger)
+	servers := make(map[NodeID]*configsync.Server)
+	for _, nodeID := range []NodeID{"node-2", "node-3"} {
+		server := configsync.NewServer(nodeID, filepath.Join(t.TempDir(), "config.yaml"), config.DefaultConfig().Cluster(), trusted, logger)
+		servers[nodeID] = server
+		mux := hyperbus.NewMux()
+		mux.Handle(hyperbus.MsgConfigQuery, server)
+		mux.Handle(hyperbus.MsgConfigUpdate, server)
+		hyperbus.ConnectMemory(bus, hyperbus.New(hyperbus.NodeInfo{ID: nodeID}, mux, logger))
+		members.Join(ctx, &membership.Member{ID: nodeID, Status: membership.Alive, Capabilities: &proto.NodeCapabilities{CpuCores: 1}})
+	}
+	c := &Cluster{localNode: "node-1", bus: bus, members: members, logger: logger}
+
+	cc, err := c.Config(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, config.DefaultConfig().Cluster(), cc)
+
+	cc.Storage.ReadRepairRate = 5
+	results, err := c.UpdateConfig(ctx, cc, operator)
+	assert.NoError(t, err)
+	assert.Equal(t, map[NodeID]error{"node-2": nil, "node-3": nil}, results)
+	for nodeID, server := range servers {
+		current, _ := server.Current()
+		assert.Equal(t, 5, current.Storage.ReadRepairRate, nodeID)
+	}
+
+	exported, err := c.Config(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, cc, exported)
+
+	// Invalid configs aren't sent at all
+	cc.Storage.CacheSize = 0
+	_, err = c.UpdateConfig(ctx, cc, operator)
+	assert.ErrorContains(t, err, "invalid config")
+}
@@ -0,0 +1,119 @@
+package holocompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/melihxz/holocompute/internal/deadline"
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/internal/scheduler"
+	"github.com/melihxz/holocompute/internal/task"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// IterationError is returned by DistributedFor when the task's function
+// fails at an index
+type IterationError = scheduler.IterationError
+
+// DistributedFor calls the task's function with each index from 0 to n-1,
+// splitting the indices into one contiguous range per agent, or running
+// them here if there are none. The function takes the index as an i32
+// followed by the (offset, length) pair of each input; a task run this way
+// can't have outputs. onProgress, if set, receives the number of indices
+// completed across the cluster as the agents report it. When an agent
+// leaves the cluster, the rest of its range is rescheduled on the others.
+// A failing call ends the loop with an *IterationError. A ctx without a
+// deadline is bounded by Options.TaskTimeout.
+func (c *Cluster) DistributedFor(ctx context.Context, spec TaskSpec, n int, onProgress func(done, total int)) error {
+	if c.tasks == nil && c.executor == nil {
+		return errors.New("cluster not connected")
+	}
+	if len(spec.Outputs) > 0 {
+		return errors.New("tasks run by DistributedFor can't have outputs")
+	}
+
+	ctx, cancel := deadline.WithDefault(ctx, c.taskTimeout)
+	defer cancel()
+
+	workers := c.workers()
+	if len(workers) == 0 {
+		if _, ok := c.executor.(task.RangeExecutor); !ok {
+			return errors.New("no workers available")
+		}
+		workers = []NodeID{c.localNode}
+	}
+	return scheduler.DistributedFor(ctx, c.logger, n, workers, &rangeRunner{c: c, spec: spec}, onProgress)
+}
+
+// rangeRunner runs the ranges of a DistributedFor on agents through the task
+// client, or on this node through its executor
+type rangeRunner struct {
+	c    *Cluster
+	spec TaskSpec
+}
+
+// RunRange implements scheduler.RangeRunner. A remote range is abandoned
+// once its agent is declared dead, so the rest of it gets rescheduled.
+func (r *rangeRunner) RunRange(ctx context.Context, worker NodeID, begin, end int, progress func(done int)) error {
+	submit := newTaskSubmit(r.spec)
+	if worker == r.c.localNode {
+		return r.c.executor.(task.RangeExecutor).ExecuteRange(ctx, submit, begin, end, progress, scheduler.DefaultProgressInterval)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := r.c.onMemberLost(worker, func() {
+		cancel(fmt.Errorf("worker %s left the cluster", worker))
+	})
+	defer stop()
+
+	result, err := r.c.tasks.RunRange(ctx, worker, &proto.RangeRun{
+		Task:                  submit,
+		Begin:                 int64(begin),
+		End:                   int64(end),
+		ProgressIntervalNanos: int64(scheduler.DefaultProgressInterval),
+	}, progress)
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+		return err
+	}
+
+	switch {
+	case result.IterationFailed:
+		return &IterationError{Index: int(result.FailedIndex), Err: errors.New(result.Error)}
+	case result.Error != "":
+		return fmt.Errorf("worker %s failed the range: %s", worker, result.Error)
+	}
+	return nil
+}
+
+// onMemberLost calls lost once if nodeID isn't alive now or is later
+// declared dead or leaves, until the returned stop function is called
+func (c *Cluster) onMemberLost(nodeID NodeID, lost func()) (stop func()) {
+	if c.members == nil {
+		return func() {}
+	}
+
+	events := c.members.Subscribe()
+	if !c.members.IsAlive(nodeID) {
+		c.members.Unsubscribe(events)
+		lost()
+		return func() {}
+	}
+
+	go func() {
+		for event := range events {
+			if event.Member.ID != nodeID {
+				continue
+			}
+			if event.Type == membership.MemberLeft || event.NewStatus == membership.Dead {
+				lost()
+				return
+			}
+		}
+	}()
+	return func() { c.members.Unsubscribe(events) }
+}
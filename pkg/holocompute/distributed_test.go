@@ -0,0 +1,132 @@
+package holocompute
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/internal/task"
+	"github.com/melihxz/holocompute/internal/wasm"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// rangeRecorder records the indices it runs. With stall set, it stops
+// after that many indices of its range, reports them and hangs until
+// cancelled, as an agent dropping out would.
+type rangeRecorder struct {
+	ran     map[int]int
+	stall   int
+	stalled chan struct{}
+	mu      sync.Mutex
+}
+
+func (r *rangeRecorder) Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	return nil, errors.New("only runs ranges")
+}
+
+func (r *rangeRecorder) ExecuteRange(ctx context.Context, submit *proto.TaskSubmit, begin, end int, progress func(done int), interval time.Duration) error {
+	for i := begin; i < end; i++ {
+		if r.stall > 0 && i-begin == r.stall {
+			progress(r.stall)
+			close(r.stalled)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		r.mu.Lock()
+		r.ran[i]++
+		r.mu.Unlock()
+	}
+	progress(end - begin)
+	return nil
+}
+
+func TestCluster_DistributedForReschedulesLostWorker(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mux := hyperbus.NewMux()
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, mux, logger)
+	tasks := task.NewClient(bus, logger)
+	mux.Handle(hyperbus.MsgRangeProgress, tasks)
+	mux.Handle(hyperbus.MsgRangeResult, tasks)
+	members := membership.NewMembership(&membership.Member{ID: "node-1"}, logger)
+
+	// node-2 runs its range, node-3 drops out part way through its own
+	healthy := &rangeRecorder{ran: make(map[int]int)}
+	dropping := &rangeRecorder{ran: make(map[int]int), stall: 10, stalled: make(chan struct{})}
+	for nodeID, executor := range map[NodeID]*rangeRecorder{"node-2": healthy, "node-3": dropping} {
+		workerMux := hyperbus.NewMux()
+		workerBus := hyperbus.New(hyperbus.NodeInfo{ID: nodeID}, workerMux, logger)
+		workerMux.Handle(hyperbus.MsgRangeRun, task.NewWorker(workerBus, executor, logger))
+		hyperbus.ConnectMemory(bus, workerBus)
+		members.Join(ctx, &membership.Member{ID: nodeID, Status: membership.Alive, Capabilities: &proto.NodeCapabilities{CpuCores: 1}})
+	}
+	go func() {
+		<-dropping.stalled
+		members.UpdateMemberStatus("node-3", membership.Dead)
+	}()
+
+	c := &Cluster{localNode: "node-1", bus: bus, members: members, tasks: tasks, logger: logger, taskTimeout: time.Minute}
+	var reported []int
+	err := c.DistributedFor(ctx, TaskSpec{Module: WASMModule{Bytes: []byte("kernel")}, Func: "check"}, 100, func(done, total int) {
+		assert.Equal(t, 100, total)
+		reported = append(reported, done)
+	})
+	assert.NoError(t, err)
+
+	// The aggregated progress only grows, up to the total
+	if assert.NotEmpty(t, reported) {
+		assert.Equal(t, 100, reported[len(reported)-1])
+	}
+	for i := 1; i < len(reported); i++ {
+		assert.Greater(t, reported[i], reported[i-1])
+	}
+
+	// Every index ran, the lost worker's rest on the healthy one
+	for i := 0; i < 100; i++ {
+		assert.Positive(t, healthy.ran[i]+dropping.ran[i], "index %d", i)
+	}
+	assert.Len(t, dropping.ran, 10)
+}
+
+func TestCluster_DistributedForRunsLocally(t *testing.T) {
+	c := newTestCluster()
+	executor, err := wasm.NewExecutor(context.Background(), c.memoryManager, c.logger)
+	assert.NoError(t, err)
+	defer executor.Close(context.Background())
+	c.executor = executor
+
+	a, err := NewTypedArray[float32](c, 10, local)
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, a.Set(i, float32(i)))
+	}
+
+	// check traps at negative elements
+	spec := TaskSpec{
+		Module: MustLoadWASM("../../internal/wasm/testdata/check_index.wasm"),
+		Func:   "check",
+		Inputs: Inputs{"a": a.Shared()},
+	}
+	var done int
+	assert.NoError(t, c.DistributedFor(context.Background(), spec, 10, func(d, total int) { done = d }))
+	assert.Equal(t, 10, done)
+
+	assert.NoError(t, a.Set(7, -1))
+	err = c.DistributedFor(context.Background(), spec, 10, nil)
+	var iterErr *IterationError
+	if assert.ErrorAs(t, err, &iterErr) {
+		assert.Equal(t, 7, iterErr.Index)
+	}
+
+	spec.Outputs = Outputs{"c": a.Shared()}
+	assert.ErrorContains(t, c.DistributedFor(context.Background(), spec, 10, nil), "can't have outputs")
+}
@@ -3,32 +3,96 @@ package holocompute
 
 import (
 	"context"
-
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/melihxz/holocompute/internal/deadline"
 	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/internal/metrics"
+	"github.com/melihxz/holocompute/internal/scheduler"
+	"github.com/melihxz/holocompute/internal/task"
+	"github.com/melihxz/holocompute/internal/wasm"
+	"github.com/melihxz/holocompute/pkg/proto"
 )
 
+// NodeID identifies a cluster node
+type NodeID = hyperbus.NodeID
+
+// ArrayID identifies a shared array
+type ArrayID = dsm.ArrayID
+
+// PageID identifies a page within a shared array
+type PageID = dsm.PageID
+
 // Cluster represents a connection to a HoloCompute cluster
 type Cluster struct {
 	// internal fields hidden
+	localNode     NodeID
+	bus           *hyperbus.Bus
+	quicBus       *hyperbus.QUICBus // set when connected to bootstrap peers
+	members       *membership.Membership
+	swim          *membership.SWIM
 	memoryManager *dsm.MemoryManager
+	leases        *dsm.LeaseManager
+	tasks         *task.Client
+	executor      task.Executor // runs tasks here when no worker should
+	logger        *log.Logger
+
+	// How long ClusterMetrics waits for each member
+	metricsTimeout time.Duration
+
+	// Deadline of submitted tasks whose context sets none
+	taskTimeout time.Duration
+
+	// Number of ParallelFor calls that took the local fast path
+	localParallelForRuns atomic.Int64
 }
 
+// DefaultTaskTimeout bounds a submitted task when neither its context nor
+// Options set a deadline
+const DefaultTaskTimeout = 10 * time.Minute
+
 // Options contains options for connecting to a cluster
 type Options struct {
+	// Bootstrap are the addresses of agents to join the cluster through.
+	// Without any the cluster is this process alone.
 	Bootstrap []string
+
+	// ListenAddr is the QUIC address the client listens on for peers once
+	// it joins through Bootstrap (default an ephemeral port)
+	ListenAddr string
+
+	// TaskTimeout bounds a submitted task from scheduling to result when its
+	// context has no deadline (default DefaultTaskTimeout)
+	TaskTimeout time.Duration
 }
 
 // SharedArray represents a distributed shared array
 type SharedArray interface {
+	// ID returns the cluster-wide identifier of the array
+	ID() ArrayID
+
 	// Len returns the length of the array
 	Len() int
 
 	// Get retrieves the element at index i
 	Get(i int) (interface{}, error)
 
-	// Set sets the element at index i to value v
+	// Set sets the element at index i to value v. Only pages owned by
+	// this node can be written; others fail with ErrRemotePage.
 	Set(i int, v interface{}) error
 
+	// Append adds v after the last element and returns its index
+	Append(v interface{}) (int, error)
+
 	// Slice returns a sub-array
 	Slice(begin, end int) SharedArray
 
@@ -52,6 +116,30 @@ type Policy struct {
 
 	// Write policy (exclusive vs. optimistic with conflict detect)
 	Write WritePolicy
+
+	// ConflictResolver merges optimistic writes to a page with those another
+	// writer committed meanwhile; without one, Sync fails with ErrWriteConflict
+	ConflictResolver ConflictResolver
+
+	// Placement pins pages to these nodes round-robin, overriding hashing
+	Placement []NodeID
+
+	// AffinityWith co-locates pages with the same-index pages of another array of equal length
+	AffinityWith ArrayID
+
+	// ReadOnly arrays reject writes and skip leases, letting pages be cached
+	// freely; NewTypedArrayFrom gives them their contents
+	ReadOnly bool
+
+	// Sparse arrays materialize pages on first write; unwritten elements read as zero
+	Sparse bool
+
+	// ElemType is the type of the array's elements (default Int64Element);
+	// it determines the element size and so how many pages the array spans
+	ElemType ElemType
+
+	// AccessPattern hints how the array will be read, tuning caching and prefetch
+	AccessPattern AccessPattern
 }
 
 // Compression represents a compression algorithm
@@ -68,6 +156,107 @@ const (
 	ZstdCompression
 )
 
+// encoding returns the wire encoding for the compression algorithm
+func (c Compression) encoding() proto.Encoding {
+	switch c {
+	case LZ4Compression:
+		return proto.Encoding_LZ4
+	case ZstdCompression:
+		return proto.Encoding_ZSTD
+	default:
+		return proto.Encoding_RAW
+	}
+}
+
+// AccessPattern hints how an array's elements will be read
+type AccessPattern int
+
+const (
+	// UnspecifiedAccess gives no hint: pages are prefetched only once a
+	// reader is seen moving on to the next page
+	UnspecifiedAccess AccessPattern = iota
+
+	// SequentialAccess streams through the array: pages ahead of each read
+	// are prefetched, and pages already read are evicted first
+	SequentialAccess
+
+	// RandomAccess reads the array in no predictable order: nothing is
+	// prefetched, and frequently read pages are retained
+	RandomAccess
+)
+
+// accessPattern returns the memory manager's form of the hint
+func (a AccessPattern) accessPattern() dsm.AccessPattern {
+	switch a {
+	case SequentialAccess:
+		return dsm.AccessSequential
+	case RandomAccess:
+		return dsm.AccessRandom
+	default:
+		return dsm.AccessUnspecified
+	}
+}
+
+// ElemType represents the type of a shared array's elements
+type ElemType int
+
+const (
+	// Int64Element stores 64-bit signed integers
+	Int64Element ElemType = iota
+
+	// Int32Element stores 32-bit signed integers
+	Int32Element
+
+	// Float32Element stores single precision floats
+	Float32Element
+
+	// Float64Element stores double precision floats
+	Float64Element
+
+	// Float16Element stores half precision floats, written as float32
+	Float16Element
+
+	// BFloat16Element stores bfloat16 floats, written as float32
+	BFloat16Element
+
+	// CustomElement is the first element type available to RegisterElementCodec
+	CustomElement ElemType = ElemType(dsm.ElementCustom)
+)
+
+// ElementCodec encodes the elements of a custom element type
+type ElementCodec = dsm.ElementCodec
+
+// RegisterElementCodec makes t, CustomElement or above, an element type
+// whose values codec encodes; arrays of it are created with Policy.ElemType
+// set to t, and Get and Set take and return the values codec handles. Every
+// node must register the same types at startup.
+func RegisterElementCodec(t ElemType, name string, codec ElementCodec) error {
+	if t < CustomElement {
+		return fmt.Errorf("element type %d is reserved for built-in types", int(t))
+	}
+	return dsm.RegisterElementCodec(dsm.ElementType(t), name, codec)
+}
+
+// elementType returns the page encoding for the element type
+func (e ElemType) elementType() dsm.ElementType {
+	switch e {
+	case Int32Element:
+		return dsm.ElementInt32
+	case Float32Element:
+		return dsm.ElementFloat32
+	case Float64Element:
+		return dsm.ElementFloat64
+	case Float16Element:
+		return dsm.ElementFloat16
+	case BFloat16Element:
+		return dsm.ElementBFloat16
+	}
+	if e >= CustomElement {
+		return dsm.ElementType(e)
+	}
+	return dsm.ElementInt64
+}
+
 // WritePolicy represents a write policy
 type WritePolicy int
 
@@ -94,6 +283,36 @@ type schedOptions struct {
 
 	// Deadline
 	Deadline DeadlinePreference
+
+	// Array whose elements the loop indices address
+	Array SharedArray
+}
+
+// WithMaxConcurrency caps the number of iterations running at once
+func WithMaxConcurrency(n int) SchedOpt {
+	return func(o *schedOptions) {
+		o.MaxConcurrency = n
+	}
+}
+
+// WithRetryLimit retries each failing iteration up to n times, backing off
+// exponentially between attempts; failures wrapping ErrPermanent aren't retried
+func WithRetryLimit(n int) SchedOpt {
+	return func(o *schedOptions) {
+		o.RetryLimit = n
+	}
+}
+
+// ErrPermanent marks failures that retrying can't fix; wrap it in the error
+// a loop body returns to fail without retries
+var ErrPermanent = scheduler.ErrPermanent
+
+// WithArray declares that loop index i addresses element i of the array,
+// letting the scheduler run iterations next to the data
+func WithArray(arr SharedArray) SchedOpt {
+	return func(o *schedOptions) {
+		o.Array = arr
+	}
 }
 
 // LocalityPreference represents a locality preference
@@ -126,26 +345,296 @@ const (
 
 // Connect establishes a connection to a HoloCompute cluster
 func Connect(ctx context.Context, opts Options) (*Cluster, error) {
-	// TODO: Implement connection logic
-	return &Cluster{}, nil
+	logger := log.New(slog.LevelInfo)
+
+	// The client joins as a node of its own so it can hold pages
+	localNode := hyperbus.NodeInfo{ID: NodeID("client-" + uuid.New().String())}
+	mux := hyperbus.NewMux()
+
+	// Reaching bootstrap peers takes a real transport; without them the bus
+	// only serves this process
+	var bus *hyperbus.Bus
+	var quicBus *hyperbus.QUICBus
+	if len(opts.Bootstrap) > 0 {
+		listenAddr := opts.ListenAddr
+		if listenAddr == "" {
+			listenAddr = ":0"
+		}
+		addr, err := net.ResolveUDPAddr("udp", listenAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve listen address: %w", err)
+		}
+		localNode.Address = addr
+
+		if quicBus, err = hyperbus.NewQUICBus(localNode, mux, logger); err != nil {
+			return nil, fmt.Errorf("failed to start hyperbus: %w", err)
+		}
+		bus = quicBus.Bus
+	} else {
+		bus = hyperbus.New(localNode, mux, logger)
+	}
+
+	memoryManager := dsm.NewMemoryManager(bus, logger)
+	mux.Handle(hyperbus.MsgPageRequest, memoryManager)
+	mux.Handle(hyperbus.MsgArrayQuery, memoryManager)
+	mux.Handle(hyperbus.MsgPagePush, memoryManager)
+	mux.Handle(hyperbus.MsgPageRemap, memoryManager)
+
+	members := membership.NewMembership(&membership.Member{ID: localNode.ID, Address: bus.LocalNode().Address, Status: membership.Alive}, logger)
+	bus.SetPeerObserver(members)
+	bus.SetPeerDirectory(members)
+	memoryManager.SetLivenessChecker(members)
+
+	// Gossip with the cluster, sharing array leases and names with it
+	swimConfig := membership.DefaultSWIMConfig()
+	swim := membership.NewSWIM(members, bus, swimConfig, logger)
+	prober := membership.NewBusProber(bus, swimConfig.Probe.Timeout)
+	swim.SetProber(prober)
+	swim.AddGossipParticipant(memoryManager.ArrayLeases())
+	swim.AddGossipParticipant(memoryManager.ArrayNames())
+	mux.Handle(hyperbus.MsgClusterState, swim)
+	mux.Handle(hyperbus.MsgProbePing, prober)
+	mux.Handle(hyperbus.MsgProbePingReq, prober)
+
+	if quicBus != nil {
+		if err := swim.Bootstrap(ctx, quicBus.Dial, opts.Bootstrap); err != nil {
+			quicBus.Close()
+			return nil, fmt.Errorf("failed to join cluster: %w", err)
+		}
+		swim.Start(context.Background())
+	}
+
+	// Leases lapse when rebalancing moves their page to another owner
+	leases := dsm.NewLeaseManager(dsm.DefaultLeaseTTL, logger)
+	leases.SetEpochSource(memoryManager)
+	leases.SetNodeID(localNode.ID)
+	mux.Handle(hyperbus.MsgLeaseQuery, leases)
+
+	executor, err := wasm.NewExecutor(ctx, memoryManager, logger)
+	if err != nil {
+		if quicBus != nil {
+			swim.Stop()
+			quicBus.Close()
+		}
+		return nil, fmt.Errorf("failed to create task executor: %w", err)
+	}
+
+	taskTimeout := opts.TaskTimeout
+	if taskTimeout <= 0 {
+		taskTimeout = DefaultTaskTimeout
+	}
+
+	// Tasks sent to workers report their results and logs back here
+	tasks := task.NewClient(bus, logger)
+	tasks.SetSubmitTimeout(taskTimeout)
+	mux.Handle(hyperbus.MsgTaskResult, tasks)
+	mux.Handle(hyperbus.MsgTaskLog, tasks)
+	mux.Handle(hyperbus.MsgRangeProgress, tasks)
+	mux.Handle(hyperbus.MsgRangeResult, tasks)
+
+	c := &Cluster{
+		localNode:      localNode.ID,
+		bus:            bus,
+		quicBus:        quicBus,
+		members:        members,
+		swim:           swim,
+		memoryManager:  memoryManager,
+		leases:         leases,
+		tasks:          tasks,
+		executor:       executor,
+		logger:         logger,
+		metricsTimeout: DefaultMetricsTimeout,
+		taskTimeout:    taskTimeout,
+	}
+	mux.Handle(hyperbus.MsgMetricsQuery, metrics.NewServer(localNode.ID, c.collectMetrics))
+	return c, nil
+}
+
+// Close leaves the cluster, stopping gossip and closing the connections to
+// its members, and releases the runtime tasks run in here
+func (c *Cluster) Close() error {
+	if c.swim != nil {
+		c.swim.Stop()
+	}
+
+	var err error
+	if executor, ok := c.executor.(*wasm.Executor); ok {
+		err = executor.Close(context.Background())
+	}
+	switch {
+	case c.quicBus != nil:
+		if busErr := c.quicBus.Close(); busErr != nil {
+			err = busErr
+		}
+	case c.bus != nil:
+		if busErr := c.bus.Close(); busErr != nil {
+			err = busErr
+		}
+	}
+	return err
 }
 
-// NewSharedArray creates a new shared array
+// NewSharedArray creates a new shared array of n elements of type
+// p.ElemType, int64 unless set
 func (c *Cluster) NewSharedArray(n int, p Policy) (SharedArray, error) {
-	// TODO: Implement array creation
-	return &sharedArray{}, nil
+	return c.createArray(n, p, p.ElemType.elementType())
+}
+
+// createArray allocates an array of n elements of the given type according to p
+func (c *Cluster) createArray(n int, p Policy, elemType dsm.ElementType) (*sharedArray, error) {
+	if c.memoryManager == nil {
+		return nil, errors.New("cluster not connected")
+	}
+
+	opts := []dsm.ArrayOption{
+		dsm.WithElementType(elemType),
+		dsm.WithReplication(p.Replication),
+		dsm.WithCompression(p.Compression.encoding()),
+		dsm.WithAccessPattern(p.AccessPattern.accessPattern()),
+	}
+	switch {
+	case len(p.Placement) > 0:
+		opts = append(opts, dsm.WithPlacement(p.Placement))
+	case p.AffinityWith == "" && c.localNode != "":
+		// Without a placement hint pages stay on this node
+		opts = append(opts, dsm.WithPlacement([]NodeID{c.localNode}))
+	}
+	if p.AffinityWith != "" {
+		opts = append(opts, dsm.WithAffinity(p.AffinityWith))
+	}
+	if p.ReadOnly {
+		opts = append(opts, dsm.WithReadOnly())
+	}
+	if p.Sparse {
+		opts = append(opts, dsm.WithSparse())
+	}
+
+	array, err := c.memoryManager.CreateArray(context.Background(), n, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create array: %w", err)
+	}
+	return &sharedArray{cluster: c, array: array, write: p.Write, resolver: p.ConflictResolver}, nil
+}
+
+// OpenArray returns a handle to an existing array by ID, wherever in the
+// cluster it was created. Writes through the handle use ExclusiveWrite.
+func (c *Cluster) OpenArray(ctx context.Context, id ArrayID) (SharedArray, error) {
+	if c.memoryManager == nil {
+		return nil, errors.New("cluster not connected")
+	}
+
+	array, err := c.memoryManager.OpenArray(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open array: %w", err)
+	}
+	return &sharedArray{cluster: c, array: array}, nil
+}
+
+// CreateNamedArray creates an array of type p.ElemType and binds a cluster-wide unique
+// name to it, failing if the name is already taken
+func (c *Cluster) CreateNamedArray(ctx context.Context, name string, n int, p Policy) (SharedArray, error) {
+	if c.memoryManager == nil {
+		return nil, errors.New("cluster not connected")
+	}
+
+	names := c.memoryManager.ArrayNames()
+	if arrayID, exists := names.Lookup(name); exists {
+		return nil, &dsm.ErrNameTaken{Name: name, ArrayID: arrayID}
+	}
+
+	sa, err := c.createArray(n, p, p.ElemType.elementType())
+	if err != nil {
+		return nil, err
+	}
+
+	// Another caller may have taken the name while the array was created
+	if err := names.Register(name, sa.array.ID); err != nil {
+		c.memoryManager.DeleteArray(ctx, sa.array.ID)
+		return nil, err
+	}
+	return sa, nil
+}
+
+// OpenNamedArray returns a handle to the array bound to name
+func (c *Cluster) OpenNamedArray(ctx context.Context, name string) (SharedArray, error) {
+	if c.memoryManager == nil {
+		return nil, errors.New("cluster not connected")
+	}
+
+	arrayID, exists := c.memoryManager.ArrayNames().Lookup(name)
+	if !exists {
+		return nil, fmt.Errorf("no array named %q", name)
+	}
+	return c.OpenArray(ctx, arrayID)
 }
 
 // ParallelFor executes a function in parallel for indices 0 to n-1
 func (c *Cluster) ParallelFor(n int, fn func(i int) error, opts ...SchedOpt) error {
-	// TODO: Implement parallel for
-	return nil
+	var options schedOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return c.parallelFor(n, fn, options, c.isLocalRange(options.Array, n))
+}
+
+// parallelFor runs fn for indices 0 to n-1, on this node alone if local is
+// set and through the scheduler otherwise
+func (c *Cluster) parallelFor(n int, fn func(i int) error, options schedOptions, local bool) error {
+	if n <= 0 {
+		return nil
+	}
+	fn = scheduler.Retrying(context.Background(), fn, options.RetryLimit, 0)
+
+	// Ranges held entirely by this node skip the distributed machinery
+	if local {
+		c.localParallelForRuns.Add(1)
+		return localParallelFor(context.Background(), n, fn, options.MaxConcurrency)
+	}
+
+	return scheduler.ParallelFor(context.Background(), c.logger, n, fn, options.MaxConcurrency)
 }
 
-// Map applies a function to each element of an array and stores the result in another array
+// Map applies a function to each element of an array and stores the result in another array.
+// The result for in[i] is always stored at out[i], whatever the concurrency,
+// chunking or placement of the arrays' pages.
 func (c *Cluster) Map(in SharedArray, fn func(interface{}) (interface{}, error), out SharedArray, opts ...SchedOpt) error {
-	// TODO: Implement map
-	return nil
+	if in.Len() != out.Len() {
+		return fmt.Errorf("input and output arrays must have the same length: %d != %d", in.Len(), out.Len())
+	}
+
+	// Iterations run next to the input unless the caller says otherwise
+	opts = append([]SchedOpt{WithArray(in)}, opts...)
+	return c.ParallelFor(in.Len(), func(i int) error {
+		v, err := in.Get(i)
+		if err != nil {
+			return fmt.Errorf("failed to read element %d: %w", i, err)
+		}
+		result, err := fn(v)
+		if err != nil {
+			return err
+		}
+		return out.Set(i, result)
+	}, opts...)
+}
+
+// MapNew is like Map but stores the results in a new array allocated with
+// policy p and the input's element type
+func (c *Cluster) MapNew(in SharedArray, fn func(interface{}) (interface{}, error), p Policy, opts ...SchedOpt) (SharedArray, error) {
+	elemType := dsm.ElementInt64
+	if sa, ok := in.(*sharedArray); ok {
+		elemType = sa.array.ElementType
+	}
+
+	out, err := c.createArray(in.Len(), p, elemType)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Map(in, fn, out, opts...); err != nil {
+		out.Close()
+		return nil, err
+	}
+	return out, nil
 }
 
 // Reduce applies a reduction function to an array
@@ -154,8 +643,134 @@ func (c *Cluster) Reduce(in SharedArray, mapFn func(interface{}) (interface{}, e
 	return nil
 }
 
-// SubmitTask submits a task for execution
-func (c *Cluster) SubmitTask(ctx context.Context, task TaskSpec) (*TaskResult, error) {
-	// TODO: Implement task submission
-	return nil, nil
+// WindowedReduce reduces each consecutive window of windowSize elements of
+// arr with reduceFn, returning one aggregate per window in order. The last
+// window holds whatever elements remain, so it may be shorter. Windows are
+// reduced in parallel, each from its first element up.
+func (c *Cluster) WindowedReduce(arr SharedArray, windowSize int, reduceFn func(interface{}, interface{}) interface{}, opts ...SchedOpt) ([]interface{}, error) {
+	if windowSize <= 0 {
+		return nil, fmt.Errorf("window size must be positive: %d", windowSize)
+	}
+
+	// Window indices don't address elements, so the windows run next to the
+	// data only if this node holds all of the array they cover
+	options := schedOptions{Array: arr}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	local := options.Array != nil && c.isLocalRange(options.Array, options.Array.Len())
+
+	n := arr.Len()
+	results := make([]interface{}, (n+windowSize-1)/windowSize)
+	err := c.parallelFor(len(results), func(w int) error {
+		begin := w * windowSize
+		end := min(begin+windowSize, n)
+
+		acc, err := arr.Get(begin)
+		if err != nil {
+			return fmt.Errorf("failed to read element %d: %w", begin, err)
+		}
+		for i := begin + 1; i < end; i++ {
+			v, err := arr.Get(i)
+			if err != nil {
+				return fmt.Errorf("failed to read element %d: %w", i, err)
+			}
+			acc = reduceFn(acc, v)
+		}
+		results[w] = acc
+		return nil
+	}, options, local)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SubmitTask submits a task for execution.
+// Cancelling ctx cancels the task on the worker; the cancelled result is
+// returned together with ctx.Err(). The deadline of ctx covers scheduling,
+// module fetch, execution and result transfer; if it passes, the error is a
+// *StageTimeoutError. A ctx without a deadline is bounded by
+// Options.TaskTimeout. The task runs on the agent owning all of its
+// outputs' pages, on any agent if it has no outputs, and on this node
+// otherwise.
+func (c *Cluster) SubmitTask(ctx context.Context, spec TaskSpec) (*TaskResult, error) {
+	return c.submitTask(ctx, spec, nil)
+}
+
+// StartTask submits a task like SubmitTask without waiting for it to
+// finish. The task's log lines arrive on the returned task's LogStream as
+// they're written.
+func (c *Cluster) StartTask(ctx context.Context, spec TaskSpec) *RunningTask {
+	logs := make(chan string, logStreamBuffer)
+	rt := &RunningTask{LogStream: logs, done: make(chan struct{})}
+
+	go func() {
+		defer close(rt.done)
+		defer close(logs)
+		rt.result, rt.err = c.submitTask(ctx, spec, func(line string) {
+			select {
+			case logs <- line:
+			default:
+				c.logger.Warn("task log stream full, dropping line", "func", spec.Func)
+			}
+		})
+	}()
+	return rt
+}
+
+// submitTask runs a task, passing each line it logs to onLog if set
+func (c *Cluster) submitTask(ctx context.Context, spec TaskSpec, onLog func(line string)) (*TaskResult, error) {
+	if c.tasks == nil && c.executor == nil {
+		return nil, errors.New("cluster not connected")
+	}
+
+	ctx, cancel := deadline.WithDefault(ctx, c.taskTimeout)
+	defer cancel()
+
+	submit := newTaskSubmit(spec)
+	worker, err := c.pickWorker(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	if worker == "" {
+		return c.runLocal(ctx, spec, submit, onLog)
+	}
+
+	result, err := c.tasks.SubmitStreaming(ctx, worker, submit, onLog)
+	if result == nil {
+		return nil, err
+	}
+	return &TaskResult{
+		Status:  taskStatusFromProto(result.Status),
+		Outputs: spec.Outputs,
+		Logs:    result.Logs,
+		Error:   result.Error,
+	}, err
+}
+
+// newTaskSubmit builds the message submitting a task under a new ID
+func newTaskSubmit(spec TaskSpec) *proto.TaskSubmit {
+	submit := &proto.TaskSubmit{
+		TaskId:        uuid.New().String(),
+		WasmModSha:    spec.Module.SHA256,
+		WasmModule:    spec.Module.Bytes,
+		FuncName:      spec.Func,
+		InputsRef:     make(map[string]string, len(spec.Inputs)),
+		OutputsRef:    make(map[string]string, len(spec.Outputs)),
+		ResourceHints: spec.ResourceHints.ToProto(),
+	}
+	for name, array := range spec.Inputs {
+		submit.InputsRef[name] = string(array.ID())
+	}
+	for name, array := range spec.Outputs {
+		submit.OutputsRef[name] = string(array.ID())
+	}
+	if len(spec.Imports) > 0 {
+		submit.Imports = make(map[string][]byte, len(spec.Imports))
+		for name, module := range spec.Imports {
+			submit.Imports[name] = module.Bytes
+		}
+	}
+	return submit
 }
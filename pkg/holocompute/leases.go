@@ -0,0 +1,64 @@
+package holocompute
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// Lease is a lease held on a page of a shared array
+type Lease = dsm.Lease
+
+// DefaultLeaseQueryTimeout bounds how long Leases waits for each member
+const DefaultLeaseQueryTimeout = 2 * time.Second
+
+// Leases queries every alive member for the leases it holds on an array and
+// merges them, ordered by page. Members that don't answer in time are left
+// out rather than failing the query.
+func (c *Cluster) Leases(ctx context.Context, arrayID ArrayID) ([]*Lease, error) {
+	if c.members == nil || c.bus == nil || c.leases == nil {
+		return nil, errors.New("cluster not connected")
+	}
+
+	reports := []*proto.LeaseReport{c.leases.Report(string(c.localNode), arrayID)}
+
+	// Query the alive members concurrently
+	var remote []NodeID
+	for _, member := range c.members.Snapshot() {
+		if member.Status == membership.Alive && member.ID != c.localNode {
+			remote = append(remote, member.ID)
+		}
+	}
+
+	results := make([]*proto.LeaseReport, len(remote))
+	var wg sync.WaitGroup
+	for i, nodeID := range remote {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			queryCtx, cancel := context.WithTimeout(ctx, DefaultLeaseQueryTimeout)
+			defer cancel()
+
+			report, err := dsm.QueryLeases(queryCtx, c.bus, nodeID, arrayID)
+			if err != nil {
+				c.logger.Warn("member leases unavailable", "node_id", nodeID, "error", err)
+				return
+			}
+			results[i] = report
+		}()
+	}
+	wg.Wait()
+
+	for _, report := range results {
+		if report != nil {
+			reports = append(reports, report)
+		}
+	}
+	return dsm.MergeLeaseReports(reports...), nil
+}
@@ -0,0 +1,49 @@
+package holocompute
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCluster_Leases(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	members := membership.NewMembership(&membership.Member{ID: "node-1"}, logger)
+	local := dsm.NewLeaseManager(time.Minute, logger)
+	_, err := local.AcquireLease(ctx, "array-1", 0, dsm.WriteLease, "node-1", 1)
+	assert.NoError(t, err)
+
+	// A member answers lease queries with a lease of its own
+	remote := dsm.NewLeaseManager(time.Minute, logger)
+	remote.SetNodeID("node-2")
+	_, err = remote.AcquireLease(ctx, "array-1", 1, dsm.ReadLease, "node-2", 1)
+	assert.NoError(t, err)
+	mux := hyperbus.NewMux()
+	mux.Handle(hyperbus.MsgLeaseQuery, remote)
+	hyperbus.ConnectMemory(bus, hyperbus.New(hyperbus.NodeInfo{ID: "node-2"}, mux, logger))
+	members.Join(ctx, &membership.Member{ID: "node-2", Status: membership.Alive})
+
+	// Dead members aren't queried
+	members.Join(ctx, &membership.Member{ID: "node-3", Status: membership.Dead})
+
+	c := &Cluster{localNode: "node-1", bus: bus, members: members, leases: local, logger: logger}
+	leases, err := c.Leases(ctx, "array-1")
+	assert.NoError(t, err)
+	if assert.Len(t, leases, 2) {
+		assert.Equal(t, "node-1", leases[0].Owner)
+		assert.Equal(t, dsm.WriteLease, leases[0].Type)
+		assert.Equal(t, "node-2", leases[1].Owner)
+		assert.Equal(t, PageID(1), leases[1].PageID)
+	}
+}
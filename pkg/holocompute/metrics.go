@@ -0,0 +1,159 @@
+package holocompute
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/internal/metrics"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// DefaultMetricsTimeout bounds how long ClusterMetrics waits for each member
+const DefaultMetricsTimeout = 2 * time.Second
+
+// Metrics are the figures a node reports about itself
+type Metrics struct {
+	// Arrays counts the arrays known to the node
+	Arrays int64
+
+	// LocalPages counts the pages the node holds as owner or replica
+	LocalPages int64
+
+	// MemoryBytes is the memory held by local and cached pages
+	MemoryBytes int64
+
+	// Page cache occupancy and hit counts
+	CachePages    int64
+	CacheCapacity int64
+	CacheHits     int64
+	CacheMisses   int64
+
+	// TasksRunning counts tasks executing on the node, TasksPending those it
+	// submitted and awaits results for
+	TasksRunning int64
+	TasksPending int64
+}
+
+// add sums other into m
+func (m *Metrics) add(other Metrics) {
+	m.Arrays += other.Arrays
+	m.LocalPages += other.LocalPages
+	m.MemoryBytes += other.MemoryBytes
+	m.CachePages += other.CachePages
+	m.CacheCapacity += other.CacheCapacity
+	m.CacheHits += other.CacheHits
+	m.CacheMisses += other.CacheMisses
+	m.TasksRunning += other.TasksRunning
+	m.TasksPending += other.TasksPending
+}
+
+// metricsFromProto converts a node's reported metrics
+func metricsFromProto(m *proto.NodeMetrics) Metrics {
+	return Metrics{
+		Arrays:        m.Arrays,
+		LocalPages:    m.LocalPages,
+		MemoryBytes:   m.MemoryBytes,
+		CachePages:    m.CachePages,
+		CacheCapacity: m.CacheCapacity,
+		CacheHits:     m.CacheHits,
+		CacheMisses:   m.CacheMisses,
+		TasksRunning:  m.TasksRunning,
+		TasksPending:  m.TasksPending,
+	}
+}
+
+// NodeMetrics are the metrics reported by one node
+type NodeMetrics struct {
+	ID NodeID
+
+	// Available is false if the node didn't answer in time; Err says why
+	Available bool
+	Err       error
+
+	Metrics
+}
+
+// ClusterMetrics aggregates the metrics of the cluster's alive members
+type ClusterMetrics struct {
+	// Total sums the metrics of the available nodes
+	Total Metrics
+
+	// Nodes are sorted by ID, the local node included
+	Nodes []NodeMetrics
+
+	// Unavailable lists the nodes that didn't answer, sorted by ID
+	Unavailable []NodeID
+}
+
+// collectMetrics adds this node's metrics to m
+func (c *Cluster) collectMetrics(m *proto.NodeMetrics) {
+	if c.memoryManager != nil {
+		c.memoryManager.CollectMetrics(m)
+	}
+	if c.tasks != nil {
+		c.tasks.CollectMetrics(m)
+	}
+}
+
+// ClusterMetrics queries every alive member for its local metrics and
+// aggregates them. Members that don't answer within the metrics timeout are
+// reported unavailable rather than failing the query.
+func (c *Cluster) ClusterMetrics(ctx context.Context) (ClusterMetrics, error) {
+	if c.members == nil || c.bus == nil {
+		return ClusterMetrics{}, errors.New("cluster not connected")
+	}
+
+	timeout := c.metricsTimeout
+	if timeout <= 0 {
+		timeout = DefaultMetricsTimeout
+	}
+
+	local := &proto.NodeMetrics{NodeId: string(c.localNode)}
+	c.collectMetrics(local)
+	nodes := []NodeMetrics{{ID: c.localNode, Available: true, Metrics: metricsFromProto(local)}}
+
+	// Query the alive members concurrently
+	var remote []NodeID
+	for _, member := range c.members.Snapshot() {
+		if member.Status == membership.Alive && member.ID != c.localNode {
+			remote = append(remote, member.ID)
+		}
+	}
+
+	results := make([]NodeMetrics, len(remote))
+	var wg sync.WaitGroup
+	for i, nodeID := range remote {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			queryCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			m, err := metrics.Query(queryCtx, c.bus, nodeID)
+			if err != nil {
+				c.logger.Warn("member metrics unavailable", "node_id", nodeID, "error", err)
+				results[i] = NodeMetrics{ID: nodeID, Err: err}
+				return
+			}
+			results[i] = NodeMetrics{ID: nodeID, Available: true, Metrics: metricsFromProto(m)}
+		}()
+	}
+	wg.Wait()
+	nodes = append(nodes, results...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	cm := ClusterMetrics{Nodes: nodes}
+	for _, node := range nodes {
+		if !node.Available {
+			cm.Unavailable = append(cm.Unavailable, node.ID)
+			continue
+		}
+		cm.Total.add(node.Metrics)
+	}
+	return cm, ctx.Err()
+}
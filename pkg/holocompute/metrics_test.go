@@ -0,0 +1,74 @@
+package holocompute
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/internal/metrics"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// silentHandler drops every message unanswered, like a member that hangs
+type silentHandler struct{}
+
+func (silentHandler) HandleMessage(ctx context.Context, conn hyperbus.Connection, stream hyperbus.Stream, data []byte) error {
+	return nil
+}
+
+func TestCluster_ClusterMetrics(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	members := membership.NewMembership(&membership.Member{ID: "node-1"}, logger)
+
+	// Two fake members report fixed metrics, a third never answers
+	fakes := map[NodeID]metrics.Source{
+		"node-2": func(m *proto.NodeMetrics) { m.Arrays, m.MemoryBytes, m.TasksRunning = 2, 1024, 3 },
+		"node-3": func(m *proto.NodeMetrics) { m.Arrays, m.MemoryBytes, m.CacheHits = 1, 2048, 5 },
+	}
+	for nodeID, source := range fakes {
+		mux := hyperbus.NewMux()
+		mux.Handle(hyperbus.MsgMetricsQuery, metrics.NewServer(nodeID, source))
+		hyperbus.ConnectMemory(bus, hyperbus.New(hyperbus.NodeInfo{ID: nodeID}, mux, logger))
+	}
+	hyperbus.ConnectMemory(bus, hyperbus.New(hyperbus.NodeInfo{ID: "node-4"}, silentHandler{}, logger))
+	for _, nodeID := range []NodeID{"node-2", "node-3", "node-4"} {
+		members.Join(context.TODO(), &membership.Member{ID: nodeID, Status: membership.Alive})
+	}
+
+	// Dead members aren't queried
+	members.Join(context.TODO(), &membership.Member{ID: "node-5", Status: membership.Dead})
+
+	mm := dsm.NewMemoryManager(bus, logger)
+	_, err := mm.CreateArray(context.TODO(), dsm.PageSize/8, dsm.WithPlacement([]NodeID{"node-1"}))
+	assert.NoError(t, err)
+
+	c := &Cluster{localNode: "node-1", bus: bus, members: members, memoryManager: mm, logger: logger, metricsTimeout: 100 * time.Millisecond}
+	cm, err := c.ClusterMetrics(context.TODO())
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(4), cm.Total.Arrays)
+	assert.Equal(t, int64(3072), cm.Total.MemoryBytes)
+	assert.Equal(t, int64(3), cm.Total.TasksRunning)
+	assert.Equal(t, int64(5), cm.Total.CacheHits)
+	assert.Equal(t, int64(dsm.DefaultCacheCapacity), cm.Total.CacheCapacity)
+	assert.Equal(t, []NodeID{"node-4"}, cm.Unavailable)
+
+	if assert.Len(t, cm.Nodes, 4) {
+		assert.Equal(t, NodeID("node-1"), cm.Nodes[0].ID)
+		assert.Equal(t, int64(1), cm.Nodes[0].Arrays)
+		assert.Equal(t, NodeID("node-2"), cm.Nodes[1].ID)
+		assert.Equal(t, int64(3), cm.Nodes[1].TasksRunning)
+
+		stalled := cm.Nodes[3]
+		assert.Equal(t, NodeID("node-4"), stalled.ID)
+		assert.False(t, stalled.Available)
+		assert.ErrorIs(t, stalled.Err, context.DeadlineExceeded)
+	}
+}
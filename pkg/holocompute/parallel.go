@@ -0,0 +1,29 @@
+package holocompute
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/melihxz/holocompute/internal/scheduler"
+)
+
+// isLocalRange returns true if indices 0 to n-1 address pages all owned by this node
+func (c *Cluster) isLocalRange(arr SharedArray, n int) bool {
+	sa, ok := arr.(*sharedArray)
+	if !ok || c.memoryManager == nil || n <= 0 || n > sa.array.Len() {
+		return false
+	}
+
+	last, _ := sa.array.PageAndOffset(n - 1)
+	return c.memoryManager.OwnsPages(sa.array.ID, 0, last)
+}
+
+// localParallelFor runs fn over 0 to n-1 on this node, one worker per
+// core, in chunks sized from the measured per-index cost
+func localParallelFor(ctx context.Context, n int, fn func(i int) error, maxConcurrency int) error {
+	workers := runtime.GOMAXPROCS(0)
+	if maxConcurrency > 0 && maxConcurrency < workers {
+		workers = maxConcurrency
+	}
+	return scheduler.AdaptiveParallelFor(ctx, n, fn, workers)
+}
@@ -0,0 +1,250 @@
+package holocompute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelFor_LocalFastPath(t *testing.T) {
+	n := 3*dsm.PageSize/8 + 10
+	sa := newTestArray(t, n)
+	c := sa.cluster
+
+	var calls atomic.Int64
+	err := c.ParallelFor(n, func(i int) error {
+		calls.Add(1)
+		return sa.Set(i, int64(i)*2)
+	}, WithArray(sa))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), c.localParallelForRuns.Load())
+	assert.Equal(t, int64(n), calls.Load())
+
+	for _, i := range []int{0, 1, n / 2, n - 1} {
+		v, err := sa.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i)*2, v)
+	}
+
+	// Errors stop the loop and are returned
+	failure := errors.New("boom")
+	err = c.ParallelFor(n, func(i int) error {
+		if i == n/2 {
+			return failure
+		}
+		return nil
+	}, WithArray(sa), WithMaxConcurrency(2))
+	assert.ErrorIs(t, err, failure)
+}
+
+func TestParallelFor_GeneralPath(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	mm := dsm.NewMemoryManager(bus, logger)
+	mm.SetLivenessChecker(aliveNodes{"node-2": true})
+
+	// The second page lives on another node
+	array, err := mm.CreateArray(context.TODO(), 2*dsm.PageSize/8, dsm.WithPlacement([]NodeID{"node-1", "node-2"}))
+	assert.NoError(t, err)
+	c := &Cluster{memoryManager: mm, logger: logger}
+	sa := &sharedArray{cluster: c, array: array}
+
+	var calls atomic.Int64
+	count := func(i int) error {
+		calls.Add(1)
+		return nil
+	}
+
+	assert.NoError(t, c.ParallelFor(array.Length, count, WithArray(sa)))
+
+	// Without an array there is no locality to exploit
+	assert.NoError(t, c.ParallelFor(100, count))
+
+	assert.Equal(t, int64(0), c.localParallelForRuns.Load())
+	assert.Equal(t, int64(array.Length+100), calls.Load())
+
+	// The locally owned first page alone qualifies
+	assert.NoError(t, c.ParallelFor(dsm.PageSize/8, count, WithArray(sa)))
+	assert.Equal(t, int64(1), c.localParallelForRuns.Load())
+}
+
+func TestParallelFor_RetryLimit(t *testing.T) {
+	c := &Cluster{logger: log.New(slog.LevelDebug)}
+
+	// Every iteration fails on its first attempt
+	var attempts [8]atomic.Int32
+	flaky := func(i int) error {
+		if attempts[i].Add(1) == 1 {
+			return errors.New("transient")
+		}
+		return nil
+	}
+	assert.NoError(t, c.ParallelFor(len(attempts), flaky, WithRetryLimit(1)))
+	for i := range attempts {
+		assert.Equal(t, int32(2), attempts[i].Load())
+	}
+
+	// Permanent failures aren't retried
+	var calls atomic.Int32
+	err := c.ParallelFor(1, func(i int) error {
+		calls.Add(1)
+		return fmt.Errorf("bad input: %w", ErrPermanent)
+	}, WithRetryLimit(3))
+	assert.ErrorIs(t, err, ErrPermanent)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestCluster_WindowedReducePartlyRemoteArray(t *testing.T) {
+	c1, c2 := newConnectedClusters()
+	c1.memoryManager.SetLivenessChecker(aliveNodes{"node-1": true, "node-2": true})
+	elementsPerPage := dsm.PageSize / 8
+
+	// The second page lives on node-2, which keeps it zeroed
+	arr, err := c1.createArray(2*elementsPerPage, Policy{Placement: []NodeID{"node-1", "node-2"}}, dsm.ElementInt64)
+	assert.NoError(t, err)
+	_, err = c2.OpenArray(context.TODO(), arr.ID())
+	assert.NoError(t, err)
+	var expected int64
+	for i := 0; i < elementsPerPage; i++ {
+		assert.NoError(t, arr.Set(i, int64(i%97)))
+		expected += int64(i % 97)
+	}
+
+	// Only two windows, both addressing elements of the local first page,
+	// yet the windows read the whole array
+	sum := func(a, b interface{}) interface{} { return a.(int64) + b.(int64) }
+	got, err := c1.WindowedReduce(arr, elementsPerPage, sum, WithArray(arr))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{expected, int64(0)}, got)
+	assert.Equal(t, int64(0), c1.localParallelForRuns.Load())
+}
+
+// aliveNodes reports a fixed set of nodes as alive
+type aliveNodes map[NodeID]bool
+
+func (a aliveNodes) IsAlive(nodeID NodeID) bool {
+	return a[nodeID]
+}
+
+func BenchmarkParallelFor(b *testing.B) {
+	const n = 1 << 16
+	logger := log.New(slog.LevelError)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	mm := dsm.NewMemoryManager(bus, logger)
+	array, err := mm.CreateArray(context.TODO(), n, dsm.WithPlacement([]NodeID{"node-1"}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := &Cluster{memoryManager: mm, logger: logger}
+	sa := &sharedArray{cluster: c, array: array}
+
+	out := make([]int64, n)
+	fn := func(i int) error {
+		out[i] = int64(i) * int64(i)
+		return nil
+	}
+
+	b.Run("local", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := c.ParallelFor(n, fn, WithArray(sa)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("general", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := c.ParallelFor(n, fn); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCluster_MapPreservesOrder(t *testing.T) {
+	c := newTestCluster()
+	n := 3*dsm.PageSize/8 + 17
+
+	in, err := c.createArray(n, local, dsm.ElementInt64)
+	assert.NoError(t, err)
+	assert.NoError(t, c.ParallelFor(n, func(i int) error {
+		return in.Set(i, int64(i))
+	}))
+
+	square := func(v interface{}) (interface{}, error) {
+		x := v.(int64)
+		return x*x - x, nil
+	}
+	check := func(out SharedArray) {
+		for i := 0; i < n; i++ {
+			v, err := out.Get(i)
+			assert.NoError(t, err)
+			if !assert.Equal(t, int64(i)*int64(i)-int64(i), v, "index %d", i) {
+				return
+			}
+		}
+	}
+
+	// The local chunked path and the general per-index path
+	for _, opts := range [][]SchedOpt{
+		{WithMaxConcurrency(64)},
+		{WithArray(nil), WithMaxConcurrency(64)},
+	} {
+		out, err := c.createArray(n, local, dsm.ElementInt64)
+		assert.NoError(t, err)
+		assert.NoError(t, c.Map(in, square, out, opts...))
+		check(out)
+	}
+
+	out, err := c.MapNew(in, square, local)
+	assert.NoError(t, err)
+	check(out)
+
+	short, err := c.createArray(n-1, local, dsm.ElementInt64)
+	assert.NoError(t, err)
+	assert.Error(t, c.Map(in, square, short))
+}
+
+func TestCluster_WindowedReduce(t *testing.T) {
+	c := newTestCluster()
+	n := dsm.PageSize/8 + 1000
+
+	arr, err := c.createArray(n, local, dsm.ElementInt64)
+	assert.NoError(t, err)
+	assert.NoError(t, c.ParallelFor(n, func(i int) error {
+		return arr.Set(i, int64(i%97))
+	}))
+
+	sum := func(a, b interface{}) interface{} { return a.(int64) + b.(int64) }
+
+	// 300 doesn't divide n, so the last window is partial
+	const windowSize = 300
+	var expected []interface{}
+	for begin := 0; begin < n; begin += windowSize {
+		var total int64
+		for i := begin; i < min(begin+windowSize, n); i++ {
+			total += int64(i % 97)
+		}
+		expected = append(expected, total)
+	}
+
+	got, err := c.WindowedReduce(arr, windowSize, sum, WithMaxConcurrency(8))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	// A window wider than the array covers all of it
+	got, err = c.WindowedReduce(arr, 2*n, sum)
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+
+	_, err = c.WindowedReduce(arr, 0, sum)
+	assert.Error(t, err)
+}
@@ -1,9 +1,24 @@
 package holocompute
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/internal/task"
 	"github.com/melihxz/holocompute/pkg/proto"
 )
 
+// StageTimeoutError is returned by SubmitTask when the submit context's
+// deadline passes, naming the stage the task was in
+type StageTimeoutError = task.StageTimeoutError
+
 // TaskSpec specifies a task to be executed
 type TaskSpec struct {
 	// Module is the WASM module to execute
@@ -12,6 +27,10 @@ type TaskSpec struct {
 	// Func is the function to call in the module
 	Func string
 
+	// Imports are the modules Module imports, by module name. Workers
+	// compile each once, however many tasks import it.
+	Imports map[string]WASMModule
+
 	// Inputs are the input arrays
 	Inputs Inputs
 
@@ -59,6 +78,9 @@ type TaskResult struct {
 
 	// Logs contains any logs from the task execution
 	Logs string
+
+	// Error says why the task failed or timed out; empty otherwise
+	Error string
 }
 
 // TaskStatus represents the status of a task
@@ -79,12 +101,174 @@ const (
 
 	// TaskTimeout means the task timed out
 	TaskTimeout
+
+	// TaskCancelled means the task was cancelled before it completed
+	TaskCancelled
 )
 
+// taskStatusFromProto converts a protobuf TaskStatus to a TaskStatus
+func taskStatusFromProto(s proto.TaskStatus) TaskStatus {
+	switch s {
+	case proto.TaskStatus_RUNNING:
+		return TaskRunning
+	case proto.TaskStatus_SUCCESS:
+		return TaskSuccess
+	case proto.TaskStatus_FAILED:
+		return TaskFailed
+	case proto.TaskStatus_TIMEOUT:
+		return TaskTimeout
+	case proto.TaskStatus_CANCELLED:
+		return TaskCancelled
+	default:
+		return TaskPending
+	}
+}
+
+// logStreamBuffer is how many log lines a RunningTask's LogStream holds
+// before further lines are dropped from it
+const logStreamBuffer = 256
+
+// RunningTask is a task started with StartTask
+type RunningTask struct {
+	// LogStream receives the task's log lines as they're written and is
+	// closed once it finishes. Lines arriving while it is full are dropped
+	// from the stream; the result's Logs always holds every line.
+	LogStream <-chan string
+
+	done   chan struct{}
+	result *TaskResult
+	err    error
+}
+
+// Wait blocks until the task finishes and returns what SubmitTask would
+func (rt *RunningTask) Wait() (*TaskResult, error) {
+	<-rt.done
+	return rt.result, rt.err
+}
+
+// agents returns the alive remote members running an agent, which
+// advertise their capabilities, unlike clients joined through Connect
+func (c *Cluster) agents() []NodeID {
+	if c.members == nil {
+		return nil
+	}
+
+	var agents []NodeID
+	for _, member := range c.members.Snapshot() {
+		if member.ID != c.localNode && member.Status == membership.Alive && member.Capabilities != nil {
+			agents = append(agents, member.ID)
+		}
+	}
+	return agents
+}
+
+// workers returns the agents tasks can be sent to
+func (c *Cluster) workers() []NodeID {
+	if c.tasks == nil {
+		return nil
+	}
+	return c.agents()
+}
+
+// pickWorker chooses the worker a task runs on, or "" to run it on this
+// node. Writes to remote pages can't be pushed back to their owners yet, so
+// a task with outputs only leaves this node for a worker owning all their pages.
+func (c *Cluster) pickWorker(ctx context.Context, spec TaskSpec) (NodeID, error) {
+	workers := c.workers()
+	if len(spec.Outputs) == 0 {
+		if len(workers) > 0 {
+			return workers[rand.Intn(len(workers))], nil
+		}
+	} else if owner := c.outputOwner(ctx, spec.Outputs); owner != "" && slices.Contains(workers, owner) {
+		return owner, nil
+	}
+
+	if c.executor == nil {
+		return "", errors.New("no workers available")
+	}
+	return "", nil
+}
+
+// outputOwner returns the node owning every page of the outputs, or "" if
+// they're spread over several nodes or unknown here
+func (c *Cluster) outputOwner(ctx context.Context, outputs Outputs) NodeID {
+	if c.memoryManager == nil {
+		return ""
+	}
+
+	var owner NodeID
+	for _, output := range outputs {
+		array, err := c.memoryManager.GetArray(ctx, output.ID())
+		if err != nil {
+			return ""
+		}
+		for _, nodeID := range array.PageOwners() {
+			if owner != "" && nodeID != owner {
+				return ""
+			}
+			owner = nodeID
+		}
+	}
+	return owner
+}
+
+// runLocal executes a task on this node, reporting its outcome the way a
+// remote worker would
+func (c *Cluster) runLocal(ctx context.Context, spec TaskSpec, submit *proto.TaskSubmit, onLog func(line string)) (*TaskResult, error) {
+	execCtx := ctx
+	if onLog != nil {
+		execCtx = task.WithLogHandler(ctx, onLog)
+	}
+	result, err := c.executor.Execute(execCtx, submit)
+
+	out := &TaskResult{Status: TaskSuccess, Outputs: spec.Outputs}
+	if result != nil {
+		out.Logs = result.Logs
+	}
+
+	switch stopped := ctx.Err(); {
+	case errors.Is(stopped, context.DeadlineExceeded):
+		timeout := &StageTimeoutError{Stage: task.StageExecute}
+		out.Status = TaskTimeout
+		out.Error = timeout.Error()
+		return out, timeout
+	case stopped != nil:
+		out.Status = TaskCancelled
+		return out, stopped
+	case err != nil:
+		out.Status = TaskFailed
+		out.Error = err.Error()
+	}
+	return out, nil
+}
+
+// ErrNotWASM is returned when loading a file that isn't a WASM module
+var ErrNotWASM = errors.New("not a WASM module")
+
+// wasmMagic starts every WASM binary
+var wasmMagic = []byte("\x00asm")
+
+// LoadWASM loads a WASM module from a file and computes its SHA256
+func LoadWASM(filename string) (WASMModule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return WASMModule{}, fmt.Errorf("failed to read WASM module: %w", err)
+	}
+	if !bytes.HasPrefix(data, wasmMagic) {
+		return WASMModule{}, fmt.Errorf("%w: %s doesn't start with the \\0asm magic number", ErrNotWASM, filename)
+	}
+
+	hash := sha256.Sum256(data)
+	return WASMModule{Bytes: data, SHA256: hash[:]}, nil
+}
+
 // MustLoadWASM loads a WASM module from a file, panicking on error
 func MustLoadWASM(filename string) WASMModule {
-	// TODO: Implement WASM loading
-	return WASMModule{}
+	mod, err := LoadWASM(filename)
+	if err != nil {
+		panic(err)
+	}
+	return mod
 }
 
 // ToProto converts a ResourceHints to a protobuf ResourceHints
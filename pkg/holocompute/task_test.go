@@ -0,0 +1,235 @@
+package holocompute
+
+import (
+	"context"
+	"crypto/sha256"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/internal/task"
+	"github.com/melihxz/holocompute/internal/wasm"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// vectorAddModule adds two float32 arrays, logging "vector add"
+const vectorAddModule = "../../internal/wasm/testdata/vector_add.wasm"
+
+func TestCluster_SubmitTaskRunsLocally(t *testing.T) {
+	c := newTestCluster()
+	executor, err := wasm.NewExecutor(context.Background(), c.memoryManager, c.logger)
+	assert.NoError(t, err)
+	defer executor.Close(context.Background())
+	c.executor = executor
+
+	a, err := NewTypedArray[float32](c, 100, local)
+	assert.NoError(t, err)
+	b, err := NewTypedArray[float32](c, 100, local)
+	assert.NoError(t, err)
+	out, err := NewTypedArray[float32](c, 100, local)
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, a.Set(i, float32(i)))
+		assert.NoError(t, b.Set(i, 1.5))
+	}
+
+	spec := TaskSpec{
+		Module:  MustLoadWASM(vectorAddModule),
+		Func:    "add",
+		Inputs:  Inputs{"a": a.Shared(), "b": b.Shared()},
+		Outputs: Outputs{"c": out.Shared()},
+	}
+
+	result, err := c.SubmitTask(context.Background(), spec)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskSuccess, result.Status)
+	assert.Contains(t, result.Logs, "vector add")
+	for i := 0; i < 100; i++ {
+		v, err := out.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(i)+1.5, v)
+	}
+
+	// A module that doesn't match its hash never runs
+	spec.Module.SHA256 = make([]byte, sha256.Size)
+	result, err = c.SubmitTask(context.Background(), spec)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskFailed, result.Status)
+	assert.Contains(t, result.Error, "hash mismatch")
+}
+
+func TestCluster_SubmitTaskSharingImport(t *testing.T) {
+	c := newTestCluster()
+	executor, err := wasm.NewExecutor(context.Background(), c.memoryManager, c.logger)
+	assert.NoError(t, err)
+	defer executor.Close(context.Background())
+	c.executor = executor
+
+	a, err := NewTypedArray[float32](c, 10, local)
+	assert.NoError(t, err)
+	sum, err := NewTypedArray[float32](c, 10, local)
+	assert.NoError(t, err)
+	double, err := NewTypedArray[float32](c, 10, local)
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, a.Set(i, float32(i)))
+	}
+
+	// Both kernels import the helper module as "helper"
+	imports := map[string]WASMModule{"helper": MustLoadWASM("../../internal/wasm/testdata/helper.wasm")}
+	result, err := c.SubmitTask(context.Background(), TaskSpec{
+		Module:  MustLoadWASM("../../internal/wasm/testdata/vector_add_import.wasm"),
+		Func:    "add",
+		Imports: imports,
+		Inputs:  Inputs{"a": a.Shared(), "b": a.Shared()},
+		Outputs: Outputs{"c": sum.Shared()},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, TaskSuccess, result.Status)
+
+	result, err = c.SubmitTask(context.Background(), TaskSpec{
+		Module:  MustLoadWASM("../../internal/wasm/testdata/vector_double_import.wasm"),
+		Func:    "double",
+		Imports: imports,
+		Inputs:  Inputs{"a": a.Shared()},
+		Outputs: Outputs{"c": double.Shared()},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, TaskSuccess, result.Status)
+
+	for i := 0; i < 10; i++ {
+		v, err := sum.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(2*i), v)
+		v, err = double.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(2*i), v)
+	}
+}
+
+// steppedExecutor logs a line per step, waiting for each to be released
+type steppedExecutor struct {
+	steps chan struct{}
+}
+
+func (e *steppedExecutor) Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	logs := ""
+	for _, line := range []string{"step 1", "step 2", "step 3"} {
+		<-e.steps
+		task.LogHandler(ctx)(line)
+		logs += line + "\n"
+	}
+	return &proto.TaskResult{Status: proto.TaskStatus_SUCCESS, Logs: logs}, nil
+}
+
+func TestCluster_StartTaskStreamsLogs(t *testing.T) {
+	c := newTestCluster()
+	executor := &steppedExecutor{steps: make(chan struct{})}
+	c.executor = executor
+
+	rt := c.StartTask(context.Background(), TaskSpec{Func: "run"})
+
+	// Each line arrives while the task is still running
+	for _, want := range []string{"step 1", "step 2", "step 3"} {
+		executor.steps <- struct{}{}
+		select {
+		case line := <-rt.LogStream:
+			assert.Equal(t, want, line)
+		case <-time.After(time.Second):
+			t.Fatalf("%q not streamed", want)
+		}
+	}
+
+	result, err := rt.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, TaskSuccess, result.Status)
+	assert.Equal(t, "step 1\nstep 2\nstep 3\n", result.Logs)
+
+	_, open := <-rt.LogStream
+	assert.False(t, open)
+}
+
+// stuckExecutor never finishes a task before it is cancelled
+type stuckExecutor struct{}
+
+func (stuckExecutor) Execute(ctx context.Context, submit *proto.TaskSubmit) (*proto.TaskResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestCluster_SubmitTaskDefaultTimeout(t *testing.T) {
+	c := newTestCluster()
+	c.executor = stuckExecutor{}
+	c.taskTimeout = 20 * time.Millisecond
+
+	// A context without a deadline gets the cluster's task timeout
+	result, err := c.SubmitTask(context.Background(), TaskSpec{Func: "run"})
+	var timeout *StageTimeoutError
+	assert.ErrorAs(t, err, &timeout)
+	assert.Equal(t, TaskTimeout, result.Status)
+}
+
+func TestLoadWASM(t *testing.T) {
+	t.Run("valid module", func(t *testing.T) {
+		mod, err := LoadWASM(vectorAddModule)
+		assert.NoError(t, err)
+
+		data, err := os.ReadFile(vectorAddModule)
+		assert.NoError(t, err)
+		hash := sha256.Sum256(data)
+		assert.Equal(t, data, mod.Bytes)
+		assert.Equal(t, hash[:], mod.SHA256)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadWASM(filepath.Join(t.TempDir(), "missing.wasm"))
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		assert.Panics(t, func() { MustLoadWASM(filepath.Join(t.TempDir(), "missing.wasm")) })
+	})
+
+	t.Run("wrong magic", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "kernel.wasm")
+		assert.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho not wasm\n"), 0o644))
+
+		_, err := LoadWASM(path)
+		assert.ErrorIs(t, err, ErrNotWASM)
+		assert.ErrorContains(t, err, "magic number")
+	})
+}
+
+func TestCluster_PickWorker(t *testing.T) {
+	c := newTestCluster()
+	c.members = membership.NewMembership(&membership.Member{ID: "node-1"}, c.logger)
+	c.tasks = task.NewClient(c.bus, c.logger)
+
+	// node-2 is an agent; node-3 is a client, advertising no capabilities
+	c.members.Join(context.TODO(), &membership.Member{ID: "node-2", Status: membership.Alive, Capabilities: &proto.NodeCapabilities{CpuCores: 4}})
+	c.members.Join(context.TODO(), &membership.Member{ID: "node-3", Status: membership.Alive})
+
+	// Without an executor here, a task needs a worker
+	worker, err := c.pickWorker(context.Background(), TaskSpec{})
+	assert.NoError(t, err)
+	assert.Equal(t, NodeID("node-2"), worker)
+
+	// Outputs owned here keep the task here
+	out, err := NewTypedArray[float32](c, 100, local)
+	assert.NoError(t, err)
+	_, err = c.pickWorker(context.Background(), TaskSpec{Outputs: Outputs{"c": out.Shared()}})
+	assert.EqualError(t, err, "no workers available")
+
+	c.executor = &steppedExecutor{}
+	worker, err = c.pickWorker(context.Background(), TaskSpec{Outputs: Outputs{"c": out.Shared()}})
+	assert.NoError(t, err)
+	assert.Equal(t, NodeID(""), worker)
+
+	// Outputs owned by the agent send the task there
+	remote, err := NewTypedArray[float32](c, 100, Policy{Placement: []NodeID{"node-2"}})
+	assert.NoError(t, err)
+	worker, err = c.pickWorker(context.Background(), TaskSpec{Outputs: Outputs{"c": remote.Shared()}})
+	assert.NoError(t, err)
+	assert.Equal(t, NodeID("node-2"), worker)
+}
@@ -0,0 +1,142 @@
+package holocompute
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/pkg/proto"
+)
+
+// NodeTopology describes one node of the cluster as seen from this node
+type NodeTopology struct {
+	ID           NodeID
+	Address      string
+	Status       string
+	Capabilities *proto.NodeCapabilities
+	LastSeen     time.Time
+
+	// OwnedPages counts the pages of known arrays this node owns
+	OwnedPages int
+
+	// Local is true for the node the topology was taken from
+	Local bool
+
+	// Connected reports whether this node holds a connection to it
+	Connected bool
+}
+
+// Topology aggregates the cluster's members, their pages and connection health
+type Topology struct {
+	// Nodes are sorted by ID, the local node included
+	Nodes []NodeTopology
+
+	// Number of nodes in each membership status, keyed by status name
+	StatusCounts map[string]int
+
+	// TotalPages counts the pages of all known arrays
+	TotalPages int
+
+	// Connected counts the remote nodes this node holds a connection to
+	Connected int
+}
+
+// Topology returns the cluster topology built from membership, page
+// ownership and the bus's connections
+func (c *Cluster) Topology(ctx context.Context) (*Topology, error) {
+	if c.members == nil || c.memoryManager == nil {
+		return nil, errors.New("cluster not connected")
+	}
+
+	connected := make(map[NodeID]bool)
+	if c.bus != nil {
+		for _, nodeID := range c.bus.Peers() {
+			connected[nodeID] = true
+		}
+	}
+	pages := c.memoryManager.PageCounts()
+
+	// Membership need not list the local node itself
+	members := c.members.Snapshot()
+	local := c.members.LocalMember()
+	known := false
+	for _, member := range members {
+		known = known || member.ID == local.ID
+	}
+	if !known {
+		members = append(members, membership.Member{
+			ID:           local.ID,
+			Address:      local.Address,
+			Status:       membership.Alive,
+			Capabilities: local.Capabilities,
+			LastSeen:     time.Now(),
+		})
+	}
+
+	topology := &Topology{
+		Nodes:        make([]NodeTopology, 0, len(members)),
+		StatusCounts: make(map[string]int),
+	}
+	for _, member := range members {
+		node := NodeTopology{
+			ID:           member.ID,
+			Status:       member.Status.String(),
+			Capabilities: member.Capabilities,
+			LastSeen:     member.LastSeen,
+			OwnedPages:   pages[member.ID],
+			Local:        member.ID == local.ID,
+			Connected:    connected[member.ID],
+		}
+		if member.Address != nil {
+			node.Address = member.Address.String()
+		}
+
+		topology.Nodes = append(topology.Nodes, node)
+		topology.StatusCounts[node.Status]++
+		if node.Connected {
+			topology.Connected++
+		}
+	}
+	sort.Slice(topology.Nodes, func(i, j int) bool { return topology.Nodes[i].ID < topology.Nodes[j].ID })
+
+	for _, count := range pages {
+		topology.TotalPages += count
+	}
+	return topology, nil
+}
+
+// WatchTopology sends the topology now and again after every membership
+// change, until ctx is done and the channel is closed
+func (c *Cluster) WatchTopology(ctx context.Context) (<-chan *Topology, error) {
+	if c.members == nil || c.memoryManager == nil {
+		return nil, errors.New("cluster not connected")
+	}
+
+	events := c.members.Subscribe()
+	updates := make(chan *Topology)
+	go func() {
+		defer close(updates)
+		defer c.members.Unsubscribe(events)
+
+		for {
+			topology, err := c.Topology(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case updates <- topology:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case <-events:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
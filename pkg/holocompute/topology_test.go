@@ -0,0 +1,128 @@
+package holocompute
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/melihxz/holocompute/internal/membership"
+	"github.com/melihxz/holocompute/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCluster_Topology(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	peer := hyperbus.New(hyperbus.NodeInfo{ID: "node-2"}, nil, logger)
+	hyperbus.ConnectMemory(bus, peer)
+
+	members := membership.NewMembership(&membership.Member{ID: "node-1"}, logger)
+	members.Join(context.TODO(), &membership.Member{ID: "node-2", Status: membership.Alive, Capabilities: &proto.NodeCapabilities{CpuCores: 8}})
+	members.Join(context.TODO(), &membership.Member{ID: "node-3", Status: membership.Alive})
+	members.UpdateMemberStatus("node-3", membership.Suspect)
+
+	// Four pages alternate between the first two nodes, two more sit on the third
+	mm := dsm.NewMemoryManager(bus, logger)
+	elemsPerPage := dsm.PageSize / 8
+	_, err := mm.CreateArray(context.TODO(), 4*elemsPerPage, dsm.WithPlacement([]NodeID{"node-1", "node-2"}))
+	assert.NoError(t, err)
+	_, err = mm.CreateArray(context.TODO(), 2*elemsPerPage, dsm.WithPlacement([]NodeID{"node-3"}))
+	assert.NoError(t, err)
+
+	c := &Cluster{localNode: "node-1", bus: bus, members: members, memoryManager: mm, logger: logger}
+	topology, err := c.Topology(context.TODO())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 6, topology.TotalPages)
+	assert.Equal(t, 1, topology.Connected)
+	assert.Equal(t, map[string]int{"alive": 2, "suspect": 1}, topology.StatusCounts)
+
+	if assert.Len(t, topology.Nodes, 3) {
+		local, second, third := topology.Nodes[0], topology.Nodes[1], topology.Nodes[2]
+		assert.Equal(t, NodeID("node-1"), local.ID)
+		assert.True(t, local.Local)
+		assert.Equal(t, 2, local.OwnedPages)
+
+		assert.Equal(t, NodeID("node-2"), second.ID)
+		assert.Equal(t, 2, second.OwnedPages)
+		assert.True(t, second.Connected)
+		assert.Equal(t, int32(8), second.Capabilities.CpuCores)
+
+		assert.Equal(t, NodeID("node-3"), third.ID)
+		assert.Equal(t, "suspect", third.Status)
+		assert.Equal(t, 2, third.OwnedPages)
+		assert.False(t, third.Connected)
+	}
+}
+
+func TestCluster_WatchTopology(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	members := membership.NewMembership(&membership.Member{ID: "node-1"}, logger)
+	c := &Cluster{localNode: "node-1", bus: bus, members: members, memoryManager: dsm.NewMemoryManager(bus, logger), logger: logger}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	updates, err := c.WatchTopology(ctx)
+	assert.NoError(t, err)
+	topology := <-updates
+	assert.Len(t, topology.Nodes, 1)
+
+	// A join is rendered as it happens
+	members.Join(ctx, &membership.Member{ID: "node-2", Status: membership.Alive})
+	topology = <-updates
+	assert.Len(t, topology.Nodes, 2)
+
+	cancel()
+	for range updates {
+	}
+}
+
+func TestConnect_JoinsThroughBootstrap(t *testing.T) {
+	logger := log.New(slog.LevelDebug)
+
+	// A stand-in agent that knows of one more member
+	mux := hyperbus.NewMux()
+	agentBus, err := hyperbus.NewQUICBus(hyperbus.NodeInfo{ID: "agent", Address: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}}, mux, logger)
+	assert.NoError(t, err)
+	defer agentBus.Close()
+	agentMembers := membership.NewMembership(&membership.Member{ID: "agent", Address: agentBus.LocalNode().Address, Status: membership.Alive}, logger)
+	agentMembers.Join(context.TODO(), &membership.Member{ID: "node-3", Status: membership.Alive, LastSeen: time.Now()})
+	agent := membership.NewSWIM(agentMembers, agentBus.Bus, membership.DefaultSWIMConfig(), logger)
+	mux.Handle(hyperbus.MsgClusterState, agent)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cluster, err := Connect(ctx, Options{
+		Bootstrap:  []string{agentBus.LocalNode().Address.String()},
+		ListenAddr: "127.0.0.1:0",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer cluster.Close()
+
+	topology, err := cluster.Topology(ctx)
+	assert.NoError(t, err)
+	ids := make([]NodeID, 0, len(topology.Nodes))
+	for _, node := range topology.Nodes {
+		ids = append(ids, node.ID)
+	}
+	assert.Equal(t, []NodeID{"agent", cluster.localNode, "node-3"}, ids)
+	assert.Equal(t, 1, topology.Connected)
+
+	// The agent learned of the client in return
+	assert.True(t, agentMembers.IsAlive(cluster.localNode))
+
+	unreachable, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err = Connect(unreachable, Options{Bootstrap: []string{"127.0.0.1:1"}})
+	assert.ErrorContains(t, err, "no bootstrap node reachable")
+}
@@ -0,0 +1,151 @@
+package holocompute
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+)
+
+// Element is a Go type that can be stored in a TypedArray
+type Element interface {
+	int64 | float32 | float64
+}
+
+// elementTypeOf returns the page encoding for T
+func elementTypeOf[T Element]() dsm.ElementType {
+	var zero T
+	switch any(zero).(type) {
+	case float32:
+		return dsm.ElementFloat32
+	case float64:
+		return dsm.ElementFloat64
+	default:
+		return dsm.ElementInt64
+	}
+}
+
+// TypedArray is a shared array of T, read and written without boxing
+type TypedArray[T Element] struct {
+	sa *sharedArray
+}
+
+// NewTypedArray creates a shared array of n elements of type T; T decides
+// the element type, so p.ElemType is ignored
+func NewTypedArray[T Element](c *Cluster, n int, p Policy) (*TypedArray[T], error) {
+	sa, err := c.createArray(n, p, elementTypeOf[T]())
+	if err != nil {
+		return nil, err
+	}
+	return &TypedArray[T]{sa: sa}, nil
+}
+
+// NewTypedArrayFrom creates a shared array holding a copy of data. With
+// p.ReadOnly set the array is sealed once data is written, which is how a
+// read-only array gets its contents.
+func NewTypedArrayFrom[T Element](c *Cluster, data []T, p Policy) (*TypedArray[T], error) {
+	readOnly := p.ReadOnly
+	p.ReadOnly = false
+	ta, err := NewTypedArray[T](c, len(data), p)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ta.fill(data, readOnly); err != nil {
+		ta.Close()
+		c.memoryManager.DeleteArray(context.Background(), ta.ID())
+		return nil, err
+	}
+	return ta, nil
+}
+
+// fill writes data into the array and syncs it, sealing it if readOnly
+func (ta *TypedArray[T]) fill(data []T, readOnly bool) error {
+	for i, v := range data {
+		if err := ta.Set(i, v); err != nil {
+			return fmt.Errorf("failed to write element %d: %w", i, err)
+		}
+	}
+	if err := ta.Sync(); err != nil {
+		return err
+	}
+	if readOnly {
+		return ta.sa.cluster.memoryManager.SealArray(context.Background(), ta.ID())
+	}
+	return nil
+}
+
+// AsTyped views a shared array as a TypedArray, failing if its elements aren't T
+func AsTyped[T Element](arr SharedArray) (*TypedArray[T], error) {
+	sa, ok := arr.(*sharedArray)
+	if !ok {
+		return nil, fmt.Errorf("unsupported array implementation %T", arr)
+	}
+	if want := elementTypeOf[T](); sa.array.ElementType != want {
+		return nil, fmt.Errorf("%w: array holds %s, not %s", ErrElementType, sa.array.ElementType, want)
+	}
+	return &TypedArray[T]{sa: sa}, nil
+}
+
+// ID returns the cluster-wide identifier of the array
+func (ta *TypedArray[T]) ID() ArrayID {
+	return ta.sa.ID()
+}
+
+// Len returns the length of the array
+func (ta *TypedArray[T]) Len() int {
+	return ta.sa.Len()
+}
+
+// Get retrieves the element at index i
+func (ta *TypedArray[T]) Get(i int) (T, error) {
+	var v T
+	page, offset, err := ta.sa.page(i, false)
+	if err != nil {
+		return v, err
+	}
+
+	switch p := any(&v).(type) {
+	case *float32:
+		*p, err = page.GetFloat32(offset)
+	case *float64:
+		*p, err = page.GetFloat64(offset)
+	case *int64:
+		*p, err = page.GetInt64(offset)
+	}
+	return v, err
+}
+
+// Set sets the element at index i to v
+func (ta *TypedArray[T]) Set(i int, v T) error {
+	return ta.sa.writeAt(i, func(page *dsm.Page, offset int) error {
+		switch v := any(v).(type) {
+		case float32:
+			return page.SetFloat32(offset, v)
+		case float64:
+			return page.SetFloat64(offset, v)
+		default:
+			return page.SetInt64(offset, v.(int64))
+		}
+	})
+}
+
+// Append adds v after the last element and returns its index
+func (ta *TypedArray[T]) Append(v T) (int, error) {
+	return ta.sa.Append(v)
+}
+
+// Sync synchronizes the array, flushing writes and revoking leases
+func (ta *TypedArray[T]) Sync() error {
+	return ta.sa.Sync()
+}
+
+// Close releases resources associated with the array
+func (ta *TypedArray[T]) Close() error {
+	return ta.sa.Close()
+}
+
+// Shared returns the array as an untyped SharedArray, e.g. for task inputs
+func (ta *TypedArray[T]) Shared() SharedArray {
+	return ta.sa
+}
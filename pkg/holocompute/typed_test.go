@@ -0,0 +1,92 @@
+package holocompute
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/melihxz/holocompute/internal/dsm"
+	"github.com/melihxz/holocompute/internal/hyperbus"
+	"github.com/melihxz/holocompute/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCluster creates a cluster whose arrays all live on a single local node
+func newTestCluster() *Cluster {
+	logger := log.New(slog.LevelDebug)
+	bus := hyperbus.New(hyperbus.NodeInfo{ID: "node-1"}, nil, logger)
+	return &Cluster{
+		localNode:     "node-1",
+		memoryManager: dsm.NewMemoryManager(bus, logger),
+		leases:        dsm.NewLeaseManager(time.Minute, logger),
+		logger:        logger,
+	}
+}
+
+// local pins every page to the test cluster's node
+var local = Policy{Placement: []NodeID{"node-1"}}
+
+func TestTypedArray_RoundTrip(t *testing.T) {
+	c := newTestCluster()
+
+	f32, err := NewTypedArray[float32](c, 3*dsm.PageSize/4, local)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, f32.sa.array.NumPages)
+	assert.NoError(t, f32.Set(dsm.PageSize/4+1, 1.5))
+	v32, err := f32.Get(dsm.PageSize/4 + 1)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(1.5), v32)
+
+	f64, err := NewTypedArray[float64](c, 100, local)
+	assert.NoError(t, err)
+	assert.NoError(t, f64.Set(99, -2.25))
+	v64, err := f64.Get(99)
+	assert.NoError(t, err)
+	assert.Equal(t, -2.25, v64)
+
+	i64, err := NewTypedArray[int64](c, 100, local)
+	assert.NoError(t, err)
+	assert.NoError(t, i64.Set(0, 1<<40))
+	vi, err := i64.Get(0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1<<40), vi)
+
+	_, err = i64.Get(100)
+	assert.Error(t, err)
+}
+
+func TestTypedArray_ElementTypeChecks(t *testing.T) {
+	c := newTestCluster()
+
+	f32, err := NewTypedArray[float32](c, 100, local)
+	assert.NoError(t, err)
+	assert.NoError(t, f32.Set(3, 0.5))
+
+	// The untyped view returns the stored type and rejects mismatched writes
+	arr := f32.Shared()
+	v, err := arr.Get(3)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(0.5), v)
+	assert.ErrorIs(t, arr.Set(3, int64(1)), ErrElementType)
+	assert.ErrorIs(t, arr.Set(3, 0.5), ErrElementType)
+	assert.NoError(t, arr.Set(3, float32(0.25)))
+
+	_, err = AsTyped[float64](arr)
+	assert.ErrorIs(t, err, ErrElementType)
+	typed, err := AsTyped[float32](arr)
+	assert.NoError(t, err)
+	v32, err := typed.Get(3)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(0.25), v32)
+
+	// Untyped arrays default to int64
+	ints, err := c.NewSharedArray(10, local)
+	assert.NoError(t, err)
+	_, err = AsTyped[int64](ints)
+	assert.NoError(t, err)
+}
+
+func TestNewSharedArray_NotConnected(t *testing.T) {
+	_, err := (&Cluster{}).NewSharedArray(10, Policy{})
+	assert.Error(t, err)
+}
@@ -73,11 +73,12 @@ func (Encoding) EnumDescriptor() ([]byte, []int) {
 type TaskStatus int32
 
 const (
-	TaskStatus_PENDING TaskStatus = 0
-	TaskStatus_RUNNING TaskStatus = 1
-	TaskStatus_SUCCESS TaskStatus = 2
-	TaskStatus_FAILED  TaskStatus = 3
-	TaskStatus_TIMEOUT TaskStatus = 4
+	TaskStatus_PENDING   TaskStatus = 0
+	TaskStatus_RUNNING   TaskStatus = 1
+	TaskStatus_SUCCESS   TaskStatus = 2
+	TaskStatus_FAILED    TaskStatus = 3
+	TaskStatus_TIMEOUT   TaskStatus = 4
+	TaskStatus_CANCELLED TaskStatus = 5
 )
 
 // Enum value maps for TaskStatus.
@@ -88,13 +89,15 @@ var (
 		2: "SUCCESS",
 		3: "FAILED",
 		4: "TIMEOUT",
+		5: "CANCELLED",
 	}
 	TaskStatus_value = map[string]int32{
-		"PENDING": 0,
-		"RUNNING": 1,
-		"SUCCESS": 2,
-		"FAILED":  3,
-		"TIMEOUT": 4,
+		"PENDING":   0,
+		"RUNNING":   1,
+		"SUCCESS":   2,
+		"FAILED":    3,
+		"TIMEOUT":   4,
+		"CANCELLED": 5,
 	}
 )
 
@@ -131,6 +134,7 @@ const (
 	PageResponse_OK               PageResponse_Status = 0
 	PageResponse_NOT_FOUND        PageResponse_Status = 1
 	PageResponse_VERSION_MISMATCH PageResponse_Status = 2
+	PageResponse_OWNED            PageResponse_Status = 3 // the node owns the page, so it takes no pushed copy of it
 )
 
 // Enum value maps for PageResponse_Status.
@@ -139,11 +143,13 @@ var (
 		0: "OK",
 		1: "NOT_FOUND",
 		2: "VERSION_MISMATCH",
+		3: "OWNED",
 	}
 	PageResponse_Status_value = map[string]int32{
 		"OK":               0,
 		"NOT_FOUND":        1,
 		"VERSION_MISMATCH": 2,
+		"OWNED":            3,
 	}
 )
 
@@ -171,7 +177,7 @@ func (x PageResponse_Status) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use PageResponse_Status.Descriptor instead.
 func (PageResponse_Status) EnumDescriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{7, 0}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{8, 0}
 }
 
 type LeaseRequest_Kind int32
@@ -217,18 +223,23 @@ func (x LeaseRequest_Kind) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use LeaseRequest_Kind.Descriptor instead.
 func (LeaseRequest_Kind) EnumDescriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{8, 0}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{9, 0}
 }
 
 // Control plane messages
 type ControlHello struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
-	Caps          *NodeCapabilities      `protobuf:"bytes,2,opt,name=caps,proto3" json:"caps,omitempty"`
-	Pubkey        []byte                 `protobuf:"bytes,3,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
-	PqPubkey      []byte                 `protobuf:"bytes,4,opt,name=pq_pubkey,json=pqPubkey,proto3" json:"pq_pubkey,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	NodeId             string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Caps               *NodeCapabilities      `protobuf:"bytes,2,opt,name=caps,proto3" json:"caps,omitempty"`
+	Pubkey             []byte                 `protobuf:"bytes,3,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	PqPubkey           []byte                 `protobuf:"bytes,4,opt,name=pq_pubkey,json=pqPubkey,proto3" json:"pq_pubkey,omitempty"`
+	SentAtUnixNano     int64                  `protobuf:"varint,5,opt,name=sent_at_unix_nano,json=sentAtUnixNano,proto3" json:"sent_at_unix_nano,omitempty"`
+	ProtocolVersion    uint32                 `protobuf:"varint,6,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	MinProtocolVersion uint32                 `protobuf:"varint,7,opt,name=min_protocol_version,json=minProtocolVersion,proto3" json:"min_protocol_version,omitempty"`
+	PqPubkeySig        []byte                 `protobuf:"bytes,8,opt,name=pq_pubkey_sig,json=pqPubkeySig,proto3" json:"pq_pubkey_sig,omitempty"`     // Ed25519 signature of pq_pubkey by pubkey's owner
+	PlacementHash      string                 `protobuf:"bytes,9,opt,name=placement_hash,json=placementHash,proto3" json:"placement_hash,omitempty"` // hash placing pages on the ring; empty if the node places none
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *ControlHello) Reset() {
@@ -289,6 +300,41 @@ func (x *ControlHello) GetPqPubkey() []byte {
 	return nil
 }
 
+func (x *ControlHello) GetSentAtUnixNano() int64 {
+	if x != nil {
+		return x.SentAtUnixNano
+	}
+	return 0
+}
+
+func (x *ControlHello) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *ControlHello) GetMinProtocolVersion() uint32 {
+	if x != nil {
+		return x.MinProtocolVersion
+	}
+	return 0
+}
+
+func (x *ControlHello) GetPqPubkeySig() []byte {
+	if x != nil {
+		return x.PqPubkeySig
+	}
+	return nil
+}
+
+func (x *ControlHello) GetPlacementHash() string {
+	if x != nil {
+		return x.PlacementHash
+	}
+	return ""
+}
+
 type NodeCapabilities struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	CpuCores      int32                  `protobuf:"varint,1,opt,name=cpu_cores,json=cpuCores,proto3" json:"cpu_cores,omitempty"`
@@ -363,6 +409,12 @@ type ClusterState struct {
 	Rings            map[string]*Ring            `protobuf:"bytes,2,rep,name=rings,proto3" json:"rings,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	ShardAssignments map[string]*ShardAssignment `protobuf:"bytes,3,rep,name=shard_assignments,json=shardAssignments,proto3" json:"shard_assignments,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	Epoch            uint64                      `protobuf:"varint,4,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	SenderId         string                      `protobuf:"bytes,5,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	SentAtUnixNano   int64                       `protobuf:"varint,6,opt,name=sent_at_unix_nano,json=sentAtUnixNano,proto3" json:"sent_at_unix_nano,omitempty"`
+	ArrayLeases      []*ArrayLease               `protobuf:"bytes,7,rep,name=array_leases,json=arrayLeases,proto3" json:"array_leases,omitempty"`
+	Members          []*MemberState              `protobuf:"bytes,8,rep,name=members,proto3" json:"members,omitempty"`
+	Reply            bool                        `protobuf:"varint,9,opt,name=reply,proto3" json:"reply,omitempty"`
+	ArrayNames       []*ArrayName                `protobuf:"bytes,10,rep,name=array_names,json=arrayNames,proto3" json:"array_names,omitempty"`
 	unknownFields    protoimpl.UnknownFields
 	sizeCache        protoimpl.SizeCache
 }
@@ -425,6 +477,133 @@ func (x *ClusterState) GetEpoch() uint64 {
 	return 0
 }
 
+func (x *ClusterState) GetSenderId() string {
+	if x != nil {
+		return x.SenderId
+	}
+	return ""
+}
+
+func (x *ClusterState) GetSentAtUnixNano() int64 {
+	if x != nil {
+		return x.SentAtUnixNano
+	}
+	return 0
+}
+
+func (x *ClusterState) GetArrayLeases() []*ArrayLease {
+	if x != nil {
+		return x.ArrayLeases
+	}
+	return nil
+}
+
+func (x *ClusterState) GetMembers() []*MemberState {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *ClusterState) GetReply() bool {
+	if x != nil {
+		return x.Reply
+	}
+	return false
+}
+
+func (x *ClusterState) GetArrayNames() []*ArrayName {
+	if x != nil {
+		return x.ArrayNames
+	}
+	return nil
+}
+
+// A member's view of another member, exchanged by gossip
+type MemberState struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	NodeId           string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Address          string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Status           int32                  `protobuf:"varint,3,opt,name=status,proto3" json:"status,omitempty"`
+	LastSeenUnixNano int64                  `protobuf:"varint,4,opt,name=last_seen_unix_nano,json=lastSeenUnixNano,proto3" json:"last_seen_unix_nano,omitempty"`
+	Caps             *NodeCapabilities      `protobuf:"bytes,5,opt,name=caps,proto3" json:"caps,omitempty"`
+	Incarnation      uint64                 `protobuf:"varint,6,opt,name=incarnation,proto3" json:"incarnation,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *MemberState) Reset() {
+	*x = MemberState{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MemberState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MemberState) ProtoMessage() {}
+
+func (x *MemberState) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MemberState.ProtoReflect.Descriptor instead.
+func (*MemberState) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *MemberState) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *MemberState) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *MemberState) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *MemberState) GetLastSeenUnixNano() int64 {
+	if x != nil {
+		return x.LastSeenUnixNano
+	}
+	return 0
+}
+
+func (x *MemberState) GetCaps() *NodeCapabilities {
+	if x != nil {
+		return x.Caps
+	}
+	return nil
+}
+
+func (x *MemberState) GetIncarnation() uint64 {
+	if x != nil {
+		return x.Incarnation
+	}
+	return 0
+}
+
 type Ring struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ResourceClass string                 `protobuf:"bytes,1,opt,name=resource_class,json=resourceClass,proto3" json:"resource_class,omitempty"`
@@ -435,7 +614,7 @@ type Ring struct {
 
 func (x *Ring) Reset() {
 	*x = Ring{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[3]
+	mi := &file_pkg_proto_messages_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -447,7 +626,7 @@ func (x *Ring) String() string {
 func (*Ring) ProtoMessage() {}
 
 func (x *Ring) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[3]
+	mi := &file_pkg_proto_messages_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -460,7 +639,7 @@ func (x *Ring) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Ring.ProtoReflect.Descriptor instead.
 func (*Ring) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{3}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Ring) GetResourceClass() string {
@@ -487,7 +666,7 @@ type RingNode struct {
 
 func (x *RingNode) Reset() {
 	*x = RingNode{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[4]
+	mi := &file_pkg_proto_messages_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -499,7 +678,7 @@ func (x *RingNode) String() string {
 func (*RingNode) ProtoMessage() {}
 
 func (x *RingNode) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[4]
+	mi := &file_pkg_proto_messages_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -512,7 +691,7 @@ func (x *RingNode) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RingNode.ProtoReflect.Descriptor instead.
 func (*RingNode) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{4}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *RingNode) GetNodeId() string {
@@ -540,7 +719,7 @@ type ShardAssignment struct {
 
 func (x *ShardAssignment) Reset() {
 	*x = ShardAssignment{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[5]
+	mi := &file_pkg_proto_messages_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -552,7 +731,7 @@ func (x *ShardAssignment) String() string {
 func (*ShardAssignment) ProtoMessage() {}
 
 func (x *ShardAssignment) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[5]
+	mi := &file_pkg_proto_messages_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -565,7 +744,7 @@ func (x *ShardAssignment) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShardAssignment.ProtoReflect.Descriptor instead.
 func (*ShardAssignment) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{5}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ShardAssignment) GetArrayId() string {
@@ -595,13 +774,14 @@ type PageRequest struct {
 	ArrayId       string                 `protobuf:"bytes,1,opt,name=array_id,json=arrayId,proto3" json:"array_id,omitempty"`
 	PageId        int32                  `protobuf:"varint,2,opt,name=page_id,json=pageId,proto3" json:"page_id,omitempty"`
 	WantVersion   int64                  `protobuf:"varint,3,opt,name=want_version,json=wantVersion,proto3" json:"want_version,omitempty"`
+	VersionOnly   bool                   `protobuf:"varint,4,opt,name=version_only,json=versionOnly,proto3" json:"version_only,omitempty"` // reply with the page's version and no payload
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PageRequest) Reset() {
 	*x = PageRequest{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[6]
+	mi := &file_pkg_proto_messages_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -613,7 +793,7 @@ func (x *PageRequest) String() string {
 func (*PageRequest) ProtoMessage() {}
 
 func (x *PageRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[6]
+	mi := &file_pkg_proto_messages_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -626,7 +806,7 @@ func (x *PageRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PageRequest.ProtoReflect.Descriptor instead.
 func (*PageRequest) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{6}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *PageRequest) GetArrayId() string {
@@ -650,6 +830,13 @@ func (x *PageRequest) GetWantVersion() int64 {
 	return 0
 }
 
+func (x *PageRequest) GetVersionOnly() bool {
+	if x != nil {
+		return x.VersionOnly
+	}
+	return false
+}
+
 type PageResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Status        PageResponse_Status    `protobuf:"varint,1,opt,name=status,proto3,enum=holocompute.proto.PageResponse_Status" json:"status,omitempty"`
@@ -663,7 +850,7 @@ type PageResponse struct {
 
 func (x *PageResponse) Reset() {
 	*x = PageResponse{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[7]
+	mi := &file_pkg_proto_messages_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -675,7 +862,7 @@ func (x *PageResponse) String() string {
 func (*PageResponse) ProtoMessage() {}
 
 func (x *PageResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[7]
+	mi := &file_pkg_proto_messages_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -688,7 +875,7 @@ func (x *PageResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PageResponse.ProtoReflect.Descriptor instead.
 func (*PageResponse) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{7}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *PageResponse) GetStatus() PageResponse_Status {
@@ -737,7 +924,7 @@ type LeaseRequest struct {
 
 func (x *LeaseRequest) Reset() {
 	*x = LeaseRequest{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[8]
+	mi := &file_pkg_proto_messages_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -749,7 +936,7 @@ func (x *LeaseRequest) String() string {
 func (*LeaseRequest) ProtoMessage() {}
 
 func (x *LeaseRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[8]
+	mi := &file_pkg_proto_messages_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -762,7 +949,7 @@ func (x *LeaseRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LeaseRequest.ProtoReflect.Descriptor instead.
 func (*LeaseRequest) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{8}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *LeaseRequest) GetArrayId() string {
@@ -796,7 +983,7 @@ type LeaseGrant struct {
 
 func (x *LeaseGrant) Reset() {
 	*x = LeaseGrant{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[9]
+	mi := &file_pkg_proto_messages_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -808,7 +995,7 @@ func (x *LeaseGrant) String() string {
 func (*LeaseGrant) ProtoMessage() {}
 
 func (x *LeaseGrant) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[9]
+	mi := &file_pkg_proto_messages_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -821,7 +1008,7 @@ func (x *LeaseGrant) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LeaseGrant.ProtoReflect.Descriptor instead.
 func (*LeaseGrant) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{9}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *LeaseGrant) GetLeaseId() string {
@@ -839,18 +1026,24 @@ func (x *LeaseGrant) GetTtlMs() int64 {
 }
 
 type TaskSubmit struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
-	WasmModSha    []byte                 `protobuf:"bytes,2,opt,name=wasm_mod_sha,json=wasmModSha,proto3" json:"wasm_mod_sha,omitempty"`
-	InputsRef     map[string]string      `protobuf:"bytes,3,rep,name=inputs_ref,json=inputsRef,proto3" json:"inputs_ref,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	ResourceHints *ResourceHints         `protobuf:"bytes,4,opt,name=resource_hints,json=resourceHints,proto3" json:"resource_hints,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	TaskId           string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	WasmModSha       []byte                 `protobuf:"bytes,2,opt,name=wasm_mod_sha,json=wasmModSha,proto3" json:"wasm_mod_sha,omitempty"`
+	InputsRef        map[string]string      `protobuf:"bytes,3,rep,name=inputs_ref,json=inputsRef,proto3" json:"inputs_ref,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ResourceHints    *ResourceHints         `protobuf:"bytes,4,opt,name=resource_hints,json=resourceHints,proto3" json:"resource_hints,omitempty"`
+	WasmModule       []byte                 `protobuf:"bytes,5,opt,name=wasm_module,json=wasmModule,proto3" json:"wasm_module,omitempty"`
+	FuncName         string                 `protobuf:"bytes,6,opt,name=func_name,json=funcName,proto3" json:"func_name,omitempty"`
+	OutputsRef       map[string]string      `protobuf:"bytes,7,rep,name=outputs_ref,json=outputsRef,proto3" json:"outputs_ref,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DeadlineUnixNano int64                  `protobuf:"varint,8,opt,name=deadline_unix_nano,json=deadlineUnixNano,proto3" json:"deadline_unix_nano,omitempty"`                               // end-to-end deadline, 0 if none
+	TimeoutNanos     int64                  `protobuf:"varint,9,opt,name=timeout_nanos,json=timeoutNanos,proto3" json:"timeout_nanos,omitempty"`                                             // time left until the deadline when sent, 0 if none
+	Imports          map[string][]byte      `protobuf:"bytes,10,rep,name=imports,proto3" json:"imports,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // bytecode of the modules wasm_module imports, by module name
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *TaskSubmit) Reset() {
 	*x = TaskSubmit{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[10]
+	mi := &file_pkg_proto_messages_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -862,7 +1055,7 @@ func (x *TaskSubmit) String() string {
 func (*TaskSubmit) ProtoMessage() {}
 
 func (x *TaskSubmit) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[10]
+	mi := &file_pkg_proto_messages_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -875,7 +1068,7 @@ func (x *TaskSubmit) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TaskSubmit.ProtoReflect.Descriptor instead.
 func (*TaskSubmit) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{10}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *TaskSubmit) GetTaskId() string {
@@ -906,6 +1099,100 @@ func (x *TaskSubmit) GetResourceHints() *ResourceHints {
 	return nil
 }
 
+func (x *TaskSubmit) GetWasmModule() []byte {
+	if x != nil {
+		return x.WasmModule
+	}
+	return nil
+}
+
+func (x *TaskSubmit) GetFuncName() string {
+	if x != nil {
+		return x.FuncName
+	}
+	return ""
+}
+
+func (x *TaskSubmit) GetOutputsRef() map[string]string {
+	if x != nil {
+		return x.OutputsRef
+	}
+	return nil
+}
+
+func (x *TaskSubmit) GetDeadlineUnixNano() int64 {
+	if x != nil {
+		return x.DeadlineUnixNano
+	}
+	return 0
+}
+
+func (x *TaskSubmit) GetTimeoutNanos() int64 {
+	if x != nil {
+		return x.TimeoutNanos
+	}
+	return 0
+}
+
+func (x *TaskSubmit) GetImports() map[string][]byte {
+	if x != nil {
+		return x.Imports
+	}
+	return nil
+}
+
+type TaskCancel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskCancel) Reset() {
+	*x = TaskCancel{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskCancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskCancel) ProtoMessage() {}
+
+func (x *TaskCancel) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskCancel.ProtoReflect.Descriptor instead.
+func (*TaskCancel) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TaskCancel) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *TaskCancel) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
 type ResourceHints struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Cpu           int32                  `protobuf:"varint,1,opt,name=cpu,proto3" json:"cpu,omitempty"`
@@ -917,7 +1204,7 @@ type ResourceHints struct {
 
 func (x *ResourceHints) Reset() {
 	*x = ResourceHints{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[11]
+	mi := &file_pkg_proto_messages_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -929,7 +1216,7 @@ func (x *ResourceHints) String() string {
 func (*ResourceHints) ProtoMessage() {}
 
 func (x *ResourceHints) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[11]
+	mi := &file_pkg_proto_messages_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -942,7 +1229,7 @@ func (x *ResourceHints) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResourceHints.ProtoReflect.Descriptor instead.
 func (*ResourceHints) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{11}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ResourceHints) GetCpu() int32 {
@@ -972,13 +1259,16 @@ type TaskResult struct {
 	Status        TaskStatus             `protobuf:"varint,2,opt,name=status,proto3,enum=holocompute.proto.TaskStatus" json:"status,omitempty"`
 	OutputsRef    map[string]string      `protobuf:"bytes,3,rep,name=outputs_ref,json=outputsRef,proto3" json:"outputs_ref,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	Logs          string                 `protobuf:"bytes,4,opt,name=logs,proto3" json:"logs,omitempty"`
+	Stage         string                 `protobuf:"bytes,5,opt,name=stage,proto3" json:"stage,omitempty"`                        // stage the task stopped in if it didn't complete
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`                        // why the task failed or timed out, empty on success
+	LogLines      uint64                 `protobuf:"varint,7,opt,name=log_lines,json=logLines,proto3" json:"log_lines,omitempty"` // number of TaskLog lines relayed before the result
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TaskResult) Reset() {
 	*x = TaskResult{}
-	mi := &file_pkg_proto_messages_proto_msgTypes[12]
+	mi := &file_pkg_proto_messages_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -990,7 +1280,7 @@ func (x *TaskResult) String() string {
 func (*TaskResult) ProtoMessage() {}
 
 func (x *TaskResult) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_messages_proto_msgTypes[12]
+	mi := &file_pkg_proto_messages_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1003,33 +1293,1777 @@ func (x *TaskResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TaskResult.ProtoReflect.Descriptor instead.
 func (*TaskResult) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{12}
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TaskResult) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *TaskResult) GetStatus() TaskStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TaskStatus_PENDING
+}
+
+func (x *TaskResult) GetOutputsRef() map[string]string {
+	if x != nil {
+		return x.OutputsRef
+	}
+	return nil
+}
+
+func (x *TaskResult) GetLogs() string {
+	if x != nil {
+		return x.Logs
+	}
+	return ""
+}
+
+func (x *TaskResult) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *TaskResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *TaskResult) GetLogLines() uint64 {
+	if x != nil {
+		return x.LogLines
+	}
+	return 0
+}
+
+// Log line a running task wrote, relayed to its submitter as it's produced
+type TaskLog struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Line          string                 `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`
+	Seq           uint64                 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"` // position of the line among those relayed, from 0
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskLog) Reset() {
+	*x = TaskLog{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskLog) ProtoMessage() {}
+
+func (x *TaskLog) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskLog.ProtoReflect.Descriptor instead.
+func (*TaskLog) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *TaskLog) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *TaskLog) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
+}
+
+func (x *TaskLog) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+// Sent before closing a connection the peer violated the protocol on
+type ProtocolError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          uint64                 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtocolError) Reset() {
+	*x = ProtocolError{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtocolError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtocolError) ProtoMessage() {}
+
+func (x *ProtocolError) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtocolError.ProtoReflect.Descriptor instead.
+func (*ProtocolError) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ProtocolError) GetCode() uint64 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ProtocolError) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Signed control envelope
+type SignedEnvelope struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SenderId       string                 `protobuf:"bytes,1,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	Nonce          []byte                 `protobuf:"bytes,2,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	SentAtUnixNano int64                  `protobuf:"varint,3,opt,name=sent_at_unix_nano,json=sentAtUnixNano,proto3" json:"sent_at_unix_nano,omitempty"`
+	MsgType        uint32                 `protobuf:"varint,4,opt,name=msg_type,json=msgType,proto3" json:"msg_type,omitempty"`
+	Payload        []byte                 `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
+	Signature      []byte                 `protobuf:"bytes,6,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SignedEnvelope) Reset() {
+	*x = SignedEnvelope{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignedEnvelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignedEnvelope) ProtoMessage() {}
+
+func (x *SignedEnvelope) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignedEnvelope.ProtoReflect.Descriptor instead.
+func (*SignedEnvelope) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SignedEnvelope) GetSenderId() string {
+	if x != nil {
+		return x.SenderId
+	}
+	return ""
+}
+
+func (x *SignedEnvelope) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *SignedEnvelope) GetSentAtUnixNano() int64 {
+	if x != nil {
+		return x.SentAtUnixNano
+	}
+	return 0
+}
+
+func (x *SignedEnvelope) GetMsgType() uint32 {
+	if x != nil {
+		return x.MsgType
+	}
+	return 0
+}
+
+func (x *SignedEnvelope) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *SignedEnvelope) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+// Time-bounded hold on a whole array, gossiped for cleanup coordination
+type ArrayLease struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	ArrayId           string                 `protobuf:"bytes,1,opt,name=array_id,json=arrayId,proto3" json:"array_id,omitempty"`
+	Holder            string                 `protobuf:"bytes,2,opt,name=holder,proto3" json:"holder,omitempty"`
+	ExpiresAtUnixNano int64                  `protobuf:"varint,3,opt,name=expires_at_unix_nano,json=expiresAtUnixNano,proto3" json:"expires_at_unix_nano,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ArrayLease) Reset() {
+	*x = ArrayLease{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArrayLease) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArrayLease) ProtoMessage() {}
+
+func (x *ArrayLease) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArrayLease.ProtoReflect.Descriptor instead.
+func (*ArrayLease) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ArrayLease) GetArrayId() string {
+	if x != nil {
+		return x.ArrayId
+	}
+	return ""
+}
+
+func (x *ArrayLease) GetHolder() string {
+	if x != nil {
+		return x.Holder
+	}
+	return ""
+}
+
+func (x *ArrayLease) GetExpiresAtUnixNano() int64 {
+	if x != nil {
+		return x.ExpiresAtUnixNano
+	}
+	return 0
+}
+
+// Human-readable name bound to an array, gossiped so names are unique cluster-wide
+type ArrayName struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Name                 string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ArrayId              string                 `protobuf:"bytes,2,opt,name=array_id,json=arrayId,proto3" json:"array_id,omitempty"`
+	RegisteredAtUnixNano int64                  `protobuf:"varint,3,opt,name=registered_at_unix_nano,json=registeredAtUnixNano,proto3" json:"registered_at_unix_nano,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *ArrayName) Reset() {
+	*x = ArrayName{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArrayName) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArrayName) ProtoMessage() {}
+
+func (x *ArrayName) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArrayName.ProtoReflect.Descriptor instead.
+func (*ArrayName) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ArrayName) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ArrayName) GetArrayId() string {
+	if x != nil {
+		return x.ArrayId
+	}
+	return ""
+}
+
+func (x *ArrayName) GetRegisteredAtUnixNano() int64 {
+	if x != nil {
+		return x.RegisteredAtUnixNano
+	}
+	return 0
+}
+
+// Lease enumeration
+type LeaseQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ArrayId       string                 `protobuf:"bytes,1,opt,name=array_id,json=arrayId,proto3" json:"array_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseQuery) Reset() {
+	*x = LeaseQuery{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseQuery) ProtoMessage() {}
+
+func (x *LeaseQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseQuery.ProtoReflect.Descriptor instead.
+func (*LeaseQuery) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *LeaseQuery) GetArrayId() string {
+	if x != nil {
+		return x.ArrayId
+	}
+	return ""
+}
+
+type LeaseInfo struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	LeaseId           string                 `protobuf:"bytes,1,opt,name=lease_id,json=leaseId,proto3" json:"lease_id,omitempty"`
+	ArrayId           string                 `protobuf:"bytes,2,opt,name=array_id,json=arrayId,proto3" json:"array_id,omitempty"`
+	PageId            int32                  `protobuf:"varint,3,opt,name=page_id,json=pageId,proto3" json:"page_id,omitempty"`
+	Kind              LeaseRequest_Kind      `protobuf:"varint,4,opt,name=kind,proto3,enum=holocompute.proto.LeaseRequest_Kind" json:"kind,omitempty"`
+	Owner             string                 `protobuf:"bytes,5,opt,name=owner,proto3" json:"owner,omitempty"`
+	ExpiresAtUnixNano int64                  `protobuf:"varint,6,opt,name=expires_at_unix_nano,json=expiresAtUnixNano,proto3" json:"expires_at_unix_nano,omitempty"`
+	Version           int64                  `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	Epoch             int64                  `protobuf:"varint,8,opt,name=epoch,proto3" json:"epoch,omitempty"` // ownership epoch of the page when the lease was granted
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *LeaseInfo) Reset() {
+	*x = LeaseInfo{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseInfo) ProtoMessage() {}
+
+func (x *LeaseInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseInfo.ProtoReflect.Descriptor instead.
+func (*LeaseInfo) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *LeaseInfo) GetLeaseId() string {
+	if x != nil {
+		return x.LeaseId
+	}
+	return ""
+}
+
+func (x *LeaseInfo) GetArrayId() string {
+	if x != nil {
+		return x.ArrayId
+	}
+	return ""
+}
+
+func (x *LeaseInfo) GetPageId() int32 {
+	if x != nil {
+		return x.PageId
+	}
+	return 0
+}
+
+func (x *LeaseInfo) GetKind() LeaseRequest_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return LeaseRequest_READ
+}
+
+func (x *LeaseInfo) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *LeaseInfo) GetExpiresAtUnixNano() int64 {
+	if x != nil {
+		return x.ExpiresAtUnixNano
+	}
+	return 0
+}
+
+func (x *LeaseInfo) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *LeaseInfo) GetEpoch() int64 {
+	if x != nil {
+		return x.Epoch
+	}
+	return 0
+}
+
+type LeaseReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Leases        []*LeaseInfo           `protobuf:"bytes,2,rep,name=leases,proto3" json:"leases,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseReport) Reset() {
+	*x = LeaseReport{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseReport) ProtoMessage() {}
+
+func (x *LeaseReport) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseReport.ProtoReflect.Descriptor instead.
+func (*LeaseReport) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *LeaseReport) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *LeaseReport) GetLeases() []*LeaseInfo {
+	if x != nil {
+		return x.Leases
+	}
+	return nil
+}
+
+// Array metadata lookup
+type ArrayQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ArrayId       string                 `protobuf:"bytes,1,opt,name=array_id,json=arrayId,proto3" json:"array_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArrayQuery) Reset() {
+	*x = ArrayQuery{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArrayQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArrayQuery) ProtoMessage() {}
+
+func (x *ArrayQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArrayQuery.ProtoReflect.Descriptor instead.
+func (*ArrayQuery) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ArrayQuery) GetArrayId() string {
+	if x != nil {
+		return x.ArrayId
+	}
+	return ""
+}
+
+type ArrayInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ArrayId       string                 `protobuf:"bytes,1,opt,name=array_id,json=arrayId,proto3" json:"array_id,omitempty"`
+	Found         bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	NumPages      int32                  `protobuf:"varint,4,opt,name=num_pages,json=numPages,proto3" json:"num_pages,omitempty"`
+	ElementType   int32                  `protobuf:"varint,5,opt,name=element_type,json=elementType,proto3" json:"element_type,omitempty"`
+	Version       int64                  `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	ReadOnly      bool                   `protobuf:"varint,7,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
+	Replication   int32                  `protobuf:"varint,8,opt,name=replication,proto3" json:"replication,omitempty"`
+	Compression   Encoding               `protobuf:"varint,9,opt,name=compression,proto3,enum=holocompute.proto.Encoding" json:"compression,omitempty"`
+	PageOwners    map[int32]string       `protobuf:"bytes,10,rep,name=page_owners,json=pageOwners,proto3" json:"page_owners,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Sparse        bool                   `protobuf:"varint,11,opt,name=sparse,proto3" json:"sparse,omitempty"`
+	PageEpochs    map[int32]int64        `protobuf:"bytes,12,rep,name=page_epochs,json=pageEpochs,proto3" json:"page_epochs,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // pages whose ownership epoch isn't 0
+	AccessPattern int32                  `protobuf:"varint,13,opt,name=access_pattern,json=accessPattern,proto3" json:"access_pattern,omitempty"`
+	LastAccess    int64                  `protobuf:"varint,14,opt,name=last_access,json=lastAccess,proto3" json:"last_access,omitempty"` // unix nanoseconds the array was last accessed on the answering node
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArrayInfo) Reset() {
+	*x = ArrayInfo{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArrayInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArrayInfo) ProtoMessage() {}
+
+func (x *ArrayInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArrayInfo.ProtoReflect.Descriptor instead.
+func (*ArrayInfo) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ArrayInfo) GetArrayId() string {
+	if x != nil {
+		return x.ArrayId
+	}
+	return ""
+}
+
+func (x *ArrayInfo) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *ArrayInfo) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *ArrayInfo) GetNumPages() int32 {
+	if x != nil {
+		return x.NumPages
+	}
+	return 0
+}
+
+func (x *ArrayInfo) GetElementType() int32 {
+	if x != nil {
+		return x.ElementType
+	}
+	return 0
+}
+
+func (x *ArrayInfo) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ArrayInfo) GetReadOnly() bool {
+	if x != nil {
+		return x.ReadOnly
+	}
+	return false
+}
+
+func (x *ArrayInfo) GetReplication() int32 {
+	if x != nil {
+		return x.Replication
+	}
+	return 0
+}
+
+func (x *ArrayInfo) GetCompression() Encoding {
+	if x != nil {
+		return x.Compression
+	}
+	return Encoding_RAW
+}
+
+func (x *ArrayInfo) GetPageOwners() map[int32]string {
+	if x != nil {
+		return x.PageOwners
+	}
+	return nil
+}
+
+func (x *ArrayInfo) GetSparse() bool {
+	if x != nil {
+		return x.Sparse
+	}
+	return false
+}
+
+func (x *ArrayInfo) GetPageEpochs() map[int32]int64 {
+	if x != nil {
+		return x.PageEpochs
+	}
+	return nil
+}
+
+func (x *ArrayInfo) GetAccessPattern() int32 {
+	if x != nil {
+		return x.AccessPattern
+	}
+	return 0
+}
+
+func (x *ArrayInfo) GetLastAccess() int64 {
+	if x != nil {
+		return x.LastAccess
+	}
+	return 0
+}
+
+// Copy of a page pushed to a replica, answered with a PageResponse
+type PagePush struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ArrayId       string                 `protobuf:"bytes,1,opt,name=array_id,json=arrayId,proto3" json:"array_id,omitempty"`
+	PageId        int32                  `protobuf:"varint,2,opt,name=page_id,json=pageId,proto3" json:"page_id,omitempty"`
+	Version       int64                  `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
+	Handoff       bool                   `protobuf:"varint,5,opt,name=handoff,proto3" json:"handoff,omitempty"` // hands the page to its new owner, which may already own it
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PagePush) Reset() {
+	*x = PagePush{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PagePush) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PagePush) ProtoMessage() {}
+
+func (x *PagePush) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PagePush.ProtoReflect.Descriptor instead.
+func (*PagePush) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *PagePush) GetArrayId() string {
+	if x != nil {
+		return x.ArrayId
+	}
+	return ""
+}
+
+func (x *PagePush) GetPageId() int32 {
+	if x != nil {
+		return x.PageId
+	}
+	return 0
+}
+
+func (x *PagePush) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *PagePush) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *PagePush) GetHandoff() bool {
+	if x != nil {
+		return x.Handoff
+	}
+	return false
+}
+
+// New owners of an array's pages and the ownership epochs they moved to,
+// or the owners of pages appended to it, answered with a PageResponse
+type PageRemap struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ArrayId       string                 `protobuf:"bytes,1,opt,name=array_id,json=arrayId,proto3" json:"array_id,omitempty"`
+	PageOwners    map[int32]string       `protobuf:"bytes,2,rep,name=page_owners,json=pageOwners,proto3" json:"page_owners,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	PageEpochs    map[int32]int64        `protobuf:"bytes,3,rep,name=page_epochs,json=pageEpochs,proto3" json:"page_epochs,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	NumPages      int32                  `protobuf:"varint,4,opt,name=num_pages,json=numPages,proto3" json:"num_pages,omitempty"` // set when the array grew to this many pages
+	Length        int64                  `protobuf:"varint,5,opt,name=length,proto3" json:"length,omitempty"`                     // length of the array when it grew
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageRemap) Reset() {
+	*x = PageRemap{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageRemap) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageRemap) ProtoMessage() {}
+
+func (x *PageRemap) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageRemap.ProtoReflect.Descriptor instead.
+func (*PageRemap) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *PageRemap) GetArrayId() string {
+	if x != nil {
+		return x.ArrayId
+	}
+	return ""
+}
+
+func (x *PageRemap) GetPageOwners() map[int32]string {
+	if x != nil {
+		return x.PageOwners
+	}
+	return nil
+}
+
+func (x *PageRemap) GetPageEpochs() map[int32]int64 {
+	if x != nil {
+		return x.PageEpochs
+	}
+	return nil
+}
+
+func (x *PageRemap) GetNumPages() int32 {
+	if x != nil {
+		return x.NumPages
+	}
+	return 0
+}
+
+func (x *PageRemap) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+// Reply to a ControlHello carrying a PQ key: the ML-KEM ciphertext
+// encapsulated to the hello's key and a tag confirming the shared secret
+type KeyExchange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ciphertext    []byte                 `protobuf:"bytes,1,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	Confirmation  []byte                 `protobuf:"bytes,2,opt,name=confirmation,proto3" json:"confirmation,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"` // Ed25519 signature of the ciphertext by the sender's identity key
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyExchange) Reset() {
+	*x = KeyExchange{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyExchange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyExchange) ProtoMessage() {}
+
+func (x *KeyExchange) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyExchange.ProtoReflect.Descriptor instead.
+func (*KeyExchange) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *KeyExchange) GetCiphertext() []byte {
+	if x != nil {
+		return x.Ciphertext
+	}
+	return nil
+}
+
+func (x *KeyExchange) GetConfirmation() []byte {
+	if x != nil {
+		return x.Confirmation
+	}
+	return nil
+}
+
+func (x *KeyExchange) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+// Control message authenticated with the connection's PQ-derived key
+type AuthenticatedMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       []byte                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Mac           []byte                 `protobuf:"bytes,2,opt,name=mac,proto3" json:"mac,omitempty"`
+	Counter       uint64                 `protobuf:"varint,3,opt,name=counter,proto3" json:"counter,omitempty"` // per-direction sequence number covered by the MAC
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthenticatedMessage) Reset() {
+	*x = AuthenticatedMessage{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthenticatedMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthenticatedMessage) ProtoMessage() {}
+
+func (x *AuthenticatedMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthenticatedMessage.ProtoReflect.Descriptor instead.
+func (*AuthenticatedMessage) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *AuthenticatedMessage) GetMessage() []byte {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *AuthenticatedMessage) GetMac() []byte {
+	if x != nil {
+		return x.Mac
+	}
+	return nil
+}
+
+func (x *AuthenticatedMessage) GetCounter() uint64 {
+	if x != nil {
+		return x.Counter
+	}
+	return 0
+}
+
+type Ping struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SentAtUnixNano int64                  `protobuf:"varint,1,opt,name=sent_at_unix_nano,json=sentAtUnixNano,proto3" json:"sent_at_unix_nano,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Ping) Reset() {
+	*x = Ping{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ping) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ping) ProtoMessage() {}
+
+func (x *Ping) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ping.ProtoReflect.Descriptor instead.
+func (*Ping) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *Ping) GetSentAtUnixNano() int64 {
+	if x != nil {
+		return x.SentAtUnixNano
+	}
+	return 0
+}
+
+// Direct failure-detector probe, answered with ProbeAck
+type ProbePing struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbePing) Reset() {
+	*x = ProbePing{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbePing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbePing) ProtoMessage() {}
+
+func (x *ProbePing) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbePing.ProtoReflect.Descriptor instead.
+func (*ProbePing) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{30}
+}
+
+// Asks the receiver to probe target_id on the sender's behalf, answered with ProbeAck
+type ProbePingReq struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TargetId      string                 `protobuf:"bytes,1,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbePingReq) Reset() {
+	*x = ProbePingReq{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbePingReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbePingReq) ProtoMessage() {}
+
+func (x *ProbePingReq) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbePingReq.ProtoReflect.Descriptor instead.
+func (*ProbePingReq) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ProbePingReq) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+type ProbeAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reached       bool                   `protobuf:"varint,1,opt,name=reached,proto3" json:"reached,omitempty"` // false if an indirect probe got no answer from its target
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeAck) Reset() {
+	*x = ProbeAck{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeAck) ProtoMessage() {}
+
+func (x *ProbeAck) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeAck.ProtoReflect.Descriptor instead.
+func (*ProbeAck) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ProbeAck) GetReached() bool {
+	if x != nil {
+		return x.Reached
+	}
+	return false
+}
+
+// Request for a node's local metrics, answered with NodeMetrics
+type MetricsQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetricsQuery) Reset() {
+	*x = MetricsQuery{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetricsQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricsQuery) ProtoMessage() {}
+
+func (x *MetricsQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricsQuery.ProtoReflect.Descriptor instead.
+func (*MetricsQuery) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{33}
+}
+
+type NodeMetrics struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Arrays        int64                  `protobuf:"varint,2,opt,name=arrays,proto3" json:"arrays,omitempty"`
+	LocalPages    int64                  `protobuf:"varint,3,opt,name=local_pages,json=localPages,proto3" json:"local_pages,omitempty"`
+	MemoryBytes   int64                  `protobuf:"varint,4,opt,name=memory_bytes,json=memoryBytes,proto3" json:"memory_bytes,omitempty"`
+	CachePages    int64                  `protobuf:"varint,5,opt,name=cache_pages,json=cachePages,proto3" json:"cache_pages,omitempty"`
+	CacheCapacity int64                  `protobuf:"varint,6,opt,name=cache_capacity,json=cacheCapacity,proto3" json:"cache_capacity,omitempty"`
+	CacheHits     int64                  `protobuf:"varint,7,opt,name=cache_hits,json=cacheHits,proto3" json:"cache_hits,omitempty"`
+	CacheMisses   int64                  `protobuf:"varint,8,opt,name=cache_misses,json=cacheMisses,proto3" json:"cache_misses,omitempty"`
+	TasksRunning  int64                  `protobuf:"varint,9,opt,name=tasks_running,json=tasksRunning,proto3" json:"tasks_running,omitempty"`
+	TasksPending  int64                  `protobuf:"varint,10,opt,name=tasks_pending,json=tasksPending,proto3" json:"tasks_pending,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeMetrics) Reset() {
+	*x = NodeMetrics{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeMetrics) ProtoMessage() {}
+
+func (x *NodeMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeMetrics.ProtoReflect.Descriptor instead.
+func (*NodeMetrics) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *NodeMetrics) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *NodeMetrics) GetArrays() int64 {
+	if x != nil {
+		return x.Arrays
+	}
+	return 0
+}
+
+func (x *NodeMetrics) GetLocalPages() int64 {
+	if x != nil {
+		return x.LocalPages
+	}
+	return 0
+}
+
+func (x *NodeMetrics) GetMemoryBytes() int64 {
+	if x != nil {
+		return x.MemoryBytes
+	}
+	return 0
+}
+
+func (x *NodeMetrics) GetCachePages() int64 {
+	if x != nil {
+		return x.CachePages
+	}
+	return 0
+}
+
+func (x *NodeMetrics) GetCacheCapacity() int64 {
+	if x != nil {
+		return x.CacheCapacity
+	}
+	return 0
+}
+
+func (x *NodeMetrics) GetCacheHits() int64 {
+	if x != nil {
+		return x.CacheHits
+	}
+	return 0
+}
+
+func (x *NodeMetrics) GetCacheMisses() int64 {
+	if x != nil {
+		return x.CacheMisses
+	}
+	return 0
+}
+
+func (x *NodeMetrics) GetTasksRunning() int64 {
+	if x != nil {
+		return x.TasksRunning
+	}
+	return 0
+}
+
+func (x *NodeMetrics) GetTasksPending() int64 {
+	if x != nil {
+		return x.TasksPending
+	}
+	return 0
+}
+
+// Runs task's function with each index from begin to end-1 as part of a
+// distributed loop, answered with RangeResult. A TaskCancel for the task's
+// ID stops it.
+type RangeRun struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Task                  *TaskSubmit            `protobuf:"bytes,1,opt,name=task,proto3" json:"task,omitempty"`
+	Begin                 int64                  `protobuf:"varint,2,opt,name=begin,proto3" json:"begin,omitempty"`
+	End                   int64                  `protobuf:"varint,3,opt,name=end,proto3" json:"end,omitempty"`
+	ProgressIntervalNanos int64                  `protobuf:"varint,4,opt,name=progress_interval_nanos,json=progressIntervalNanos,proto3" json:"progress_interval_nanos,omitempty"` // 0 for the worker's default
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *RangeRun) Reset() {
+	*x = RangeRun{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RangeRun) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeRun) ProtoMessage() {}
+
+func (x *RangeRun) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func (x *TaskResult) GetTaskId() string {
+// Deprecated: Use RangeRun.ProtoReflect.Descriptor instead.
+func (*RangeRun) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *RangeRun) GetTask() *TaskSubmit {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+func (x *RangeRun) GetBegin() int64 {
+	if x != nil {
+		return x.Begin
+	}
+	return 0
+}
+
+func (x *RangeRun) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *RangeRun) GetProgressIntervalNanos() int64 {
+	if x != nil {
+		return x.ProgressIntervalNanos
+	}
+	return 0
+}
+
+// Number of indices of a RangeRun completed so far, counted from its begin
+type RangeProgress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Done          int64                  `protobuf:"varint,2,opt,name=done,proto3" json:"done,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RangeProgress) Reset() {
+	*x = RangeProgress{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RangeProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeProgress) ProtoMessage() {}
+
+func (x *RangeProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangeProgress.ProtoReflect.Descriptor instead.
+func (*RangeProgress) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *RangeProgress) GetTaskId() string {
 	if x != nil {
 		return x.TaskId
 	}
 	return ""
 }
 
-func (x *TaskResult) GetStatus() TaskStatus {
+func (x *RangeProgress) GetDone() int64 {
 	if x != nil {
-		return x.Status
+		return x.Done
 	}
-	return TaskStatus_PENDING
+	return 0
 }
 
-func (x *TaskResult) GetOutputsRef() map[string]string {
+type RangeResult struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TaskId          string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Error           string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`                                             // empty if every index ran
+	IterationFailed bool                   `protobuf:"varint,3,opt,name=iteration_failed,json=iterationFailed,proto3" json:"iteration_failed,omitempty"` // error came from the function at failed_index
+	FailedIndex     int64                  `protobuf:"varint,4,opt,name=failed_index,json=failedIndex,proto3" json:"failed_index,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RangeResult) Reset() {
+	*x = RangeResult{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RangeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeResult) ProtoMessage() {}
+
+func (x *RangeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[37]
 	if x != nil {
-		return x.OutputsRef
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangeResult.ProtoReflect.Descriptor instead.
+func (*RangeResult) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *RangeResult) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *RangeResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *RangeResult) GetIterationFailed() bool {
+	if x != nil {
+		return x.IterationFailed
+	}
+	return false
+}
+
+func (x *RangeResult) GetFailedIndex() int64 {
+	if x != nil {
+		return x.FailedIndex
+	}
+	return 0
+}
+
+// Request for the cluster-wide configuration a node runs with, answered
+// with ConfigDocument
+type ConfigQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigQuery) Reset() {
+	*x = ConfigQuery{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigQuery) ProtoMessage() {}
+
+func (x *ConfigQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigQuery.ProtoReflect.Descriptor instead.
+func (*ConfigQuery) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{38}
+}
+
+type ConfigDocument struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Config        []byte                 `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`    // YAML cluster configuration
+	Version       int64                  `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"` // version of the last update applied, 0 if none
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigDocument) Reset() {
+	*x = ConfigDocument{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigDocument) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigDocument) ProtoMessage() {}
+
+func (x *ConfigDocument) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigDocument.ProtoReflect.Descriptor instead.
+func (*ConfigDocument) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *ConfigDocument) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *ConfigDocument) GetConfig() []byte {
+	if x != nil {
+		return x.Config
 	}
 	return nil
 }
 
-func (x *TaskResult) GetLogs() string {
+func (x *ConfigDocument) GetVersion() int64 {
 	if x != nil {
-		return x.Logs
+		return x.Version
+	}
+	return 0
+}
+
+// Cluster-wide configuration pushed to every member, answered with ConfigAck
+type ConfigUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int64                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`                     // issue time in unix nanoseconds; older updates are ignored
+	Config        []byte                 `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`                        // YAML cluster configuration
+	SignerKey     []byte                 `protobuf:"bytes,3,opt,name=signer_key,json=signerKey,proto3" json:"signer_key,omitempty"` // ed25519 public key of the issuer
+	Signature     []byte                 `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`                  // signer's signature over version and config
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigUpdate) Reset() {
+	*x = ConfigUpdate{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigUpdate) ProtoMessage() {}
+
+func (x *ConfigUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigUpdate.ProtoReflect.Descriptor instead.
+func (*ConfigUpdate) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ConfigUpdate) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ConfigUpdate) GetConfig() []byte {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *ConfigUpdate) GetSignerKey() []byte {
+	if x != nil {
+		return x.SignerKey
+	}
+	return nil
+}
+
+func (x *ConfigUpdate) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type ConfigAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // why the update was rejected, empty if applied
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigAck) Reset() {
+	*x = ConfigAck{}
+	mi := &file_pkg_proto_messages_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigAck) ProtoMessage() {}
+
+func (x *ConfigAck) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_messages_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigAck.ProtoReflect.Descriptor instead.
+func (*ConfigAck) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_messages_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *ConfigAck) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *ConfigAck) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
@@ -1038,29 +3072,49 @@ var File_pkg_proto_messages_proto protoreflect.FileDescriptor
 
 const file_pkg_proto_messages_proto_rawDesc = "" +
 	"\n" +
-	"\x18pkg/proto/messages.proto\x12\x11holocompute.proto\"\x95\x01\n" +
+	"\x18pkg/proto/messages.proto\x12\x11holocompute.proto\"\xe8\x02\n" +
 	"\fControlHello\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x127\n" +
 	"\x04caps\x18\x02 \x01(\v2#.holocompute.proto.NodeCapabilitiesR\x04caps\x12\x16\n" +
 	"\x06pubkey\x18\x03 \x01(\fR\x06pubkey\x12\x1b\n" +
-	"\tpq_pubkey\x18\x04 \x01(\fR\bpqPubkey\"\x7f\n" +
+	"\tpq_pubkey\x18\x04 \x01(\fR\bpqPubkey\x12)\n" +
+	"\x11sent_at_unix_nano\x18\x05 \x01(\x03R\x0esentAtUnixNano\x12)\n" +
+	"\x10protocol_version\x18\x06 \x01(\rR\x0fprotocolVersion\x120\n" +
+	"\x14min_protocol_version\x18\a \x01(\rR\x12minProtocolVersion\x12\"\n" +
+	"\rpq_pubkey_sig\x18\b \x01(\fR\vpqPubkeySig\x12%\n" +
+	"\x0eplacement_hash\x18\t \x01(\tR\rplacementHash\"\x7f\n" +
 	"\x10NodeCapabilities\x12\x1b\n" +
 	"\tcpu_cores\x18\x01 \x01(\x05R\bcpuCores\x12!\n" +
 	"\fmemory_bytes\x18\x02 \x01(\x03R\vmemoryBytes\x12\x17\n" +
 	"\ahas_gpu\x18\x03 \x01(\bR\x06hasGpu\x12\x12\n" +
-	"\x04tags\x18\x04 \x03(\tR\x04tags\"\xa3\x03\n" +
+	"\x04tags\x18\x04 \x03(\tR\x04tags\"\xbc\x05\n" +
 	"\fClusterState\x12\x1b\n" +
 	"\traft_term\x18\x01 \x01(\x04R\braftTerm\x12@\n" +
 	"\x05rings\x18\x02 \x03(\v2*.holocompute.proto.ClusterState.RingsEntryR\x05rings\x12b\n" +
 	"\x11shard_assignments\x18\x03 \x03(\v25.holocompute.proto.ClusterState.ShardAssignmentsEntryR\x10shardAssignments\x12\x14\n" +
-	"\x05epoch\x18\x04 \x01(\x04R\x05epoch\x1aQ\n" +
+	"\x05epoch\x18\x04 \x01(\x04R\x05epoch\x12\x1b\n" +
+	"\tsender_id\x18\x05 \x01(\tR\bsenderId\x12)\n" +
+	"\x11sent_at_unix_nano\x18\x06 \x01(\x03R\x0esentAtUnixNano\x12@\n" +
+	"\farray_leases\x18\a \x03(\v2\x1d.holocompute.proto.ArrayLeaseR\varrayLeases\x128\n" +
+	"\amembers\x18\b \x03(\v2\x1e.holocompute.proto.MemberStateR\amembers\x12\x14\n" +
+	"\x05reply\x18\t \x01(\bR\x05reply\x12=\n" +
+	"\varray_names\x18\n" +
+	" \x03(\v2\x1c.holocompute.proto.ArrayNameR\n" +
+	"arrayNames\x1aQ\n" +
 	"\n" +
 	"RingsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
 	"\x05value\x18\x02 \x01(\v2\x17.holocompute.proto.RingR\x05value:\x028\x01\x1ag\n" +
 	"\x15ShardAssignmentsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x128\n" +
-	"\x05value\x18\x02 \x01(\v2\".holocompute.proto.ShardAssignmentR\x05value:\x028\x01\"`\n" +
+	"\x05value\x18\x02 \x01(\v2\".holocompute.proto.ShardAssignmentR\x05value:\x028\x01\"\xe2\x01\n" +
+	"\vMemberState\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\x05R\x06status\x12-\n" +
+	"\x13last_seen_unix_nano\x18\x04 \x01(\x03R\x10lastSeenUnixNano\x127\n" +
+	"\x04caps\x18\x05 \x01(\v2#.holocompute.proto.NodeCapabilitiesR\x04caps\x12 \n" +
+	"\vincarnation\x18\x06 \x01(\x04R\vincarnation\"`\n" +
 	"\x04Ring\x12%\n" +
 	"\x0eresource_class\x18\x01 \x01(\tR\rresourceClass\x121\n" +
 	"\x05nodes\x18\x02 \x03(\v2\x1b.holocompute.proto.RingNodeR\x05nodes\";\n" +
@@ -1070,21 +3124,23 @@ const file_pkg_proto_messages_proto_rawDesc = "" +
 	"\x0fShardAssignment\x12\x19\n" +
 	"\barray_id\x18\x01 \x01(\tR\aarrayId\x12\x17\n" +
 	"\apage_id\x18\x02 \x01(\x05R\x06pageId\x12\"\n" +
-	"\rowner_node_id\x18\x03 \x01(\tR\vownerNodeId\"d\n" +
+	"\rowner_node_id\x18\x03 \x01(\tR\vownerNodeId\"\x87\x01\n" +
 	"\vPageRequest\x12\x19\n" +
 	"\barray_id\x18\x01 \x01(\tR\aarrayId\x12\x17\n" +
 	"\apage_id\x18\x02 \x01(\x05R\x06pageId\x12!\n" +
-	"\fwant_version\x18\x03 \x01(\x03R\vwantVersion\"\x8e\x02\n" +
+	"\fwant_version\x18\x03 \x01(\x03R\vwantVersion\x12!\n" +
+	"\fversion_only\x18\x04 \x01(\bR\vversionOnly\"\x99\x02\n" +
 	"\fPageResponse\x12>\n" +
 	"\x06status\x18\x01 \x01(\x0e2&.holocompute.proto.PageResponse.StatusR\x06status\x12\x18\n" +
 	"\aversion\x18\x02 \x01(\x03R\aversion\x12\x1a\n" +
 	"\bchecksum\x18\x03 \x01(\fR\bchecksum\x127\n" +
 	"\bencoding\x18\x04 \x01(\x0e2\x1b.holocompute.proto.EncodingR\bencoding\x12\x18\n" +
-	"\apayload\x18\x05 \x01(\fR\apayload\"5\n" +
+	"\apayload\x18\x05 \x01(\fR\apayload\"@\n" +
 	"\x06Status\x12\x06\n" +
 	"\x02OK\x10\x00\x12\r\n" +
 	"\tNOT_FOUND\x10\x01\x12\x14\n" +
-	"\x10VERSION_MISMATCH\x10\x02\"\x99\x01\n" +
+	"\x10VERSION_MISMATCH\x10\x02\x12\t\n" +
+	"\x05OWNED\x10\x03\"\x99\x01\n" +
 	"\fLeaseRequest\x12\x19\n" +
 	"\barray_id\x18\x01 \x01(\tR\aarrayId\x12\x17\n" +
 	"\apage_id\x18\x02 \x01(\x05R\x06pageId\x128\n" +
@@ -1095,7 +3151,7 @@ const file_pkg_proto_messages_proto_rawDesc = "" +
 	"\n" +
 	"LeaseGrant\x12\x19\n" +
 	"\blease_id\x18\x01 \x01(\tR\aleaseId\x12\x15\n" +
-	"\x06ttl_ms\x18\x02 \x01(\x03R\x05ttlMs\"\x9b\x02\n" +
+	"\x06ttl_ms\x18\x02 \x01(\x03R\x05ttlMs\"\xbd\x05\n" +
 	"\n" +
 	"TaskSubmit\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12 \n" +
@@ -1103,28 +3159,196 @@ const file_pkg_proto_messages_proto_rawDesc = "" +
 	"wasmModSha\x12K\n" +
 	"\n" +
 	"inputs_ref\x18\x03 \x03(\v2,.holocompute.proto.TaskSubmit.InputsRefEntryR\tinputsRef\x12G\n" +
-	"\x0eresource_hints\x18\x04 \x01(\v2 .holocompute.proto.ResourceHintsR\rresourceHints\x1a<\n" +
+	"\x0eresource_hints\x18\x04 \x01(\v2 .holocompute.proto.ResourceHintsR\rresourceHints\x12\x1f\n" +
+	"\vwasm_module\x18\x05 \x01(\fR\n" +
+	"wasmModule\x12\x1b\n" +
+	"\tfunc_name\x18\x06 \x01(\tR\bfuncName\x12N\n" +
+	"\voutputs_ref\x18\a \x03(\v2-.holocompute.proto.TaskSubmit.OutputsRefEntryR\n" +
+	"outputsRef\x12,\n" +
+	"\x12deadline_unix_nano\x18\b \x01(\x03R\x10deadlineUnixNano\x12#\n" +
+	"\rtimeout_nanos\x18\t \x01(\x03R\ftimeoutNanos\x12D\n" +
+	"\aimports\x18\n" +
+	" \x03(\v2*.holocompute.proto.TaskSubmit.ImportsEntryR\aimports\x1a<\n" +
 	"\x0eInputsRefEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"P\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a=\n" +
+	"\x0fOutputsRefEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a:\n" +
+	"\fImportsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"=\n" +
+	"\n" +
+	"TaskCancel\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"P\n" +
 	"\rResourceHints\x12\x10\n" +
 	"\x03cpu\x18\x01 \x01(\x05R\x03cpu\x12\x10\n" +
 	"\x03gpu\x18\x02 \x01(\bR\x03gpu\x12\x1b\n" +
-	"\tmemory_mb\x18\x03 \x01(\x05R\bmemoryMb\"\xff\x01\n" +
+	"\tmemory_mb\x18\x03 \x01(\x05R\bmemoryMb\"\xc8\x02\n" +
 	"\n" +
 	"TaskResult\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x125\n" +
 	"\x06status\x18\x02 \x01(\x0e2\x1d.holocompute.proto.TaskStatusR\x06status\x12N\n" +
 	"\voutputs_ref\x18\x03 \x03(\v2-.holocompute.proto.TaskResult.OutputsRefEntryR\n" +
 	"outputsRef\x12\x12\n" +
-	"\x04logs\x18\x04 \x01(\tR\x04logs\x1a=\n" +
+	"\x04logs\x18\x04 \x01(\tR\x04logs\x12\x14\n" +
+	"\x05stage\x18\x05 \x01(\tR\x05stage\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12\x1b\n" +
+	"\tlog_lines\x18\a \x01(\x04R\blogLines\x1a=\n" +
 	"\x0fOutputsRefEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01*&\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"H\n" +
+	"\aTaskLog\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x12\n" +
+	"\x04line\x18\x02 \x01(\tR\x04line\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\x04R\x03seq\";\n" +
+	"\rProtocolError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x04R\x04code\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\xc1\x01\n" +
+	"\x0eSignedEnvelope\x12\x1b\n" +
+	"\tsender_id\x18\x01 \x01(\tR\bsenderId\x12\x14\n" +
+	"\x05nonce\x18\x02 \x01(\fR\x05nonce\x12)\n" +
+	"\x11sent_at_unix_nano\x18\x03 \x01(\x03R\x0esentAtUnixNano\x12\x19\n" +
+	"\bmsg_type\x18\x04 \x01(\rR\amsgType\x12\x18\n" +
+	"\apayload\x18\x05 \x01(\fR\apayload\x12\x1c\n" +
+	"\tsignature\x18\x06 \x01(\fR\tsignature\"p\n" +
+	"\n" +
+	"ArrayLease\x12\x19\n" +
+	"\barray_id\x18\x01 \x01(\tR\aarrayId\x12\x16\n" +
+	"\x06holder\x18\x02 \x01(\tR\x06holder\x12/\n" +
+	"\x14expires_at_unix_nano\x18\x03 \x01(\x03R\x11expiresAtUnixNano\"q\n" +
+	"\tArrayName\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x19\n" +
+	"\barray_id\x18\x02 \x01(\tR\aarrayId\x125\n" +
+	"\x17registered_at_unix_nano\x18\x03 \x01(\x03R\x14registeredAtUnixNano\"'\n" +
+	"\n" +
+	"LeaseQuery\x12\x19\n" +
+	"\barray_id\x18\x01 \x01(\tR\aarrayId\"\x8b\x02\n" +
+	"\tLeaseInfo\x12\x19\n" +
+	"\blease_id\x18\x01 \x01(\tR\aleaseId\x12\x19\n" +
+	"\barray_id\x18\x02 \x01(\tR\aarrayId\x12\x17\n" +
+	"\apage_id\x18\x03 \x01(\x05R\x06pageId\x128\n" +
+	"\x04kind\x18\x04 \x01(\x0e2$.holocompute.proto.LeaseRequest.KindR\x04kind\x12\x14\n" +
+	"\x05owner\x18\x05 \x01(\tR\x05owner\x12/\n" +
+	"\x14expires_at_unix_nano\x18\x06 \x01(\x03R\x11expiresAtUnixNano\x12\x18\n" +
+	"\aversion\x18\a \x01(\x03R\aversion\x12\x14\n" +
+	"\x05epoch\x18\b \x01(\x03R\x05epoch\"\\\n" +
+	"\vLeaseReport\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x124\n" +
+	"\x06leases\x18\x02 \x03(\v2\x1c.holocompute.proto.LeaseInfoR\x06leases\"'\n" +
+	"\n" +
+	"ArrayQuery\x12\x19\n" +
+	"\barray_id\x18\x01 \x01(\tR\aarrayId\"\xa8\x05\n" +
+	"\tArrayInfo\x12\x19\n" +
+	"\barray_id\x18\x01 \x01(\tR\aarrayId\x12\x14\n" +
+	"\x05found\x18\x02 \x01(\bR\x05found\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\x12\x1b\n" +
+	"\tnum_pages\x18\x04 \x01(\x05R\bnumPages\x12!\n" +
+	"\felement_type\x18\x05 \x01(\x05R\velementType\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x03R\aversion\x12\x1b\n" +
+	"\tread_only\x18\a \x01(\bR\breadOnly\x12 \n" +
+	"\vreplication\x18\b \x01(\x05R\vreplication\x12=\n" +
+	"\vcompression\x18\t \x01(\x0e2\x1b.holocompute.proto.EncodingR\vcompression\x12M\n" +
+	"\vpage_owners\x18\n" +
+	" \x03(\v2,.holocompute.proto.ArrayInfo.PageOwnersEntryR\n" +
+	"pageOwners\x12\x16\n" +
+	"\x06sparse\x18\v \x01(\bR\x06sparse\x12M\n" +
+	"\vpage_epochs\x18\f \x03(\v2,.holocompute.proto.ArrayInfo.PageEpochsEntryR\n" +
+	"pageEpochs\x12%\n" +
+	"\x0eaccess_pattern\x18\r \x01(\x05R\raccessPattern\x12\x1f\n" +
+	"\vlast_access\x18\x0e \x01(\x03R\n" +
+	"lastAccess\x1a=\n" +
+	"\x0fPageOwnersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x05R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a=\n" +
+	"\x0fPageEpochsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x05R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x8c\x01\n" +
+	"\bPagePush\x12\x19\n" +
+	"\barray_id\x18\x01 \x01(\tR\aarrayId\x12\x17\n" +
+	"\apage_id\x18\x02 \x01(\x05R\x06pageId\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x03R\aversion\x12\x18\n" +
+	"\apayload\x18\x04 \x01(\fR\apayload\x12\x18\n" +
+	"\ahandoff\x18\x05 \x01(\bR\ahandoff\"\xf7\x02\n" +
+	"\tPageRemap\x12\x19\n" +
+	"\barray_id\x18\x01 \x01(\tR\aarrayId\x12M\n" +
+	"\vpage_owners\x18\x02 \x03(\v2,.holocompute.proto.PageRemap.PageOwnersEntryR\n" +
+	"pageOwners\x12M\n" +
+	"\vpage_epochs\x18\x03 \x03(\v2,.holocompute.proto.PageRemap.PageEpochsEntryR\n" +
+	"pageEpochs\x12\x1b\n" +
+	"\tnum_pages\x18\x04 \x01(\x05R\bnumPages\x12\x16\n" +
+	"\x06length\x18\x05 \x01(\x03R\x06length\x1a=\n" +
+	"\x0fPageOwnersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x05R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a=\n" +
+	"\x0fPageEpochsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x05R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"o\n" +
+	"\vKeyExchange\x12\x1e\n" +
+	"\n" +
+	"ciphertext\x18\x01 \x01(\fR\n" +
+	"ciphertext\x12\"\n" +
+	"\fconfirmation\x18\x02 \x01(\fR\fconfirmation\x12\x1c\n" +
+	"\tsignature\x18\x03 \x01(\fR\tsignature\"\\\n" +
+	"\x14AuthenticatedMessage\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\fR\amessage\x12\x10\n" +
+	"\x03mac\x18\x02 \x01(\fR\x03mac\x12\x18\n" +
+	"\acounter\x18\x03 \x01(\x04R\acounter\"1\n" +
+	"\x04Ping\x12)\n" +
+	"\x11sent_at_unix_nano\x18\x01 \x01(\x03R\x0esentAtUnixNano\"\v\n" +
+	"\tProbePing\"+\n" +
+	"\fProbePingReq\x12\x1b\n" +
+	"\ttarget_id\x18\x01 \x01(\tR\btargetId\"$\n" +
+	"\bProbeAck\x12\x18\n" +
+	"\areached\x18\x01 \x01(\bR\areached\"\x0e\n" +
+	"\fMetricsQuery\"\xd6\x02\n" +
+	"\vNodeMetrics\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x16\n" +
+	"\x06arrays\x18\x02 \x01(\x03R\x06arrays\x12\x1f\n" +
+	"\vlocal_pages\x18\x03 \x01(\x03R\n" +
+	"localPages\x12!\n" +
+	"\fmemory_bytes\x18\x04 \x01(\x03R\vmemoryBytes\x12\x1f\n" +
+	"\vcache_pages\x18\x05 \x01(\x03R\n" +
+	"cachePages\x12%\n" +
+	"\x0ecache_capacity\x18\x06 \x01(\x03R\rcacheCapacity\x12\x1d\n" +
+	"\n" +
+	"cache_hits\x18\a \x01(\x03R\tcacheHits\x12!\n" +
+	"\fcache_misses\x18\b \x01(\x03R\vcacheMisses\x12#\n" +
+	"\rtasks_running\x18\t \x01(\x03R\ftasksRunning\x12#\n" +
+	"\rtasks_pending\x18\n" +
+	" \x01(\x03R\ftasksPending\"\x9d\x01\n" +
+	"\bRangeRun\x121\n" +
+	"\x04task\x18\x01 \x01(\v2\x1d.holocompute.proto.TaskSubmitR\x04task\x12\x14\n" +
+	"\x05begin\x18\x02 \x01(\x03R\x05begin\x12\x10\n" +
+	"\x03end\x18\x03 \x01(\x03R\x03end\x126\n" +
+	"\x17progress_interval_nanos\x18\x04 \x01(\x03R\x15progressIntervalNanos\"<\n" +
+	"\rRangeProgress\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x12\n" +
+	"\x04done\x18\x02 \x01(\x03R\x04done\"\x8a\x01\n" +
+	"\vRangeResult\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12)\n" +
+	"\x10iteration_failed\x18\x03 \x01(\bR\x0fiterationFailed\x12!\n" +
+	"\ffailed_index\x18\x04 \x01(\x03R\vfailedIndex\"\r\n" +
+	"\vConfigQuery\"[\n" +
+	"\x0eConfigDocument\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x16\n" +
+	"\x06config\x18\x02 \x01(\fR\x06config\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x03R\aversion\"}\n" +
+	"\fConfigUpdate\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x03R\aversion\x12\x16\n" +
+	"\x06config\x18\x02 \x01(\fR\x06config\x12\x1d\n" +
+	"\n" +
+	"signer_key\x18\x03 \x01(\fR\tsignerKey\x12\x1c\n" +
+	"\tsignature\x18\x04 \x01(\fR\tsignature\":\n" +
+	"\tConfigAck\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error*&\n" +
 	"\bEncoding\x12\a\n" +
 	"\x03RAW\x10\x00\x12\a\n" +
 	"\x03LZ4\x10\x01\x12\b\n" +
-	"\x04ZSTD\x10\x02*L\n" +
+	"\x04ZSTD\x10\x02*[\n" +
 	"\n" +
 	"TaskStatus\x12\v\n" +
 	"\aPENDING\x10\x00\x12\v\n" +
@@ -1132,7 +3356,8 @@ const file_pkg_proto_messages_proto_rawDesc = "" +
 	"\aSUCCESS\x10\x02\x12\n" +
 	"\n" +
 	"\x06FAILED\x10\x03\x12\v\n" +
-	"\aTIMEOUT\x10\x04B*Z(github.com/melihxz/holocompute/pkg/protob\x06proto3"
+	"\aTIMEOUT\x10\x04\x12\r\n" +
+	"\tCANCELLED\x10\x05B*Z(github.com/melihxz/holocompute/pkg/protob\x06proto3"
 
 var (
 	file_pkg_proto_messages_proto_rawDescOnce sync.Once
@@ -1147,49 +3372,98 @@ func file_pkg_proto_messages_proto_rawDescGZIP() []byte {
 }
 
 var file_pkg_proto_messages_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
-var file_pkg_proto_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_pkg_proto_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 52)
 var file_pkg_proto_messages_proto_goTypes = []any{
-	(Encoding)(0),            // 0: holocompute.proto.Encoding
-	(TaskStatus)(0),          // 1: holocompute.proto.TaskStatus
-	(PageResponse_Status)(0), // 2: holocompute.proto.PageResponse.Status
-	(LeaseRequest_Kind)(0),   // 3: holocompute.proto.LeaseRequest.Kind
-	(*ControlHello)(nil),     // 4: holocompute.proto.ControlHello
-	(*NodeCapabilities)(nil), // 5: holocompute.proto.NodeCapabilities
-	(*ClusterState)(nil),     // 6: holocompute.proto.ClusterState
-	(*Ring)(nil),             // 7: holocompute.proto.Ring
-	(*RingNode)(nil),         // 8: holocompute.proto.RingNode
-	(*ShardAssignment)(nil),  // 9: holocompute.proto.ShardAssignment
-	(*PageRequest)(nil),      // 10: holocompute.proto.PageRequest
-	(*PageResponse)(nil),     // 11: holocompute.proto.PageResponse
-	(*LeaseRequest)(nil),     // 12: holocompute.proto.LeaseRequest
-	(*LeaseGrant)(nil),       // 13: holocompute.proto.LeaseGrant
-	(*TaskSubmit)(nil),       // 14: holocompute.proto.TaskSubmit
-	(*ResourceHints)(nil),    // 15: holocompute.proto.ResourceHints
-	(*TaskResult)(nil),       // 16: holocompute.proto.TaskResult
-	nil,                      // 17: holocompute.proto.ClusterState.RingsEntry
-	nil,                      // 18: holocompute.proto.ClusterState.ShardAssignmentsEntry
-	nil,                      // 19: holocompute.proto.TaskSubmit.InputsRefEntry
-	nil,                      // 20: holocompute.proto.TaskResult.OutputsRefEntry
+	(Encoding)(0),                // 0: holocompute.proto.Encoding
+	(TaskStatus)(0),              // 1: holocompute.proto.TaskStatus
+	(PageResponse_Status)(0),     // 2: holocompute.proto.PageResponse.Status
+	(LeaseRequest_Kind)(0),       // 3: holocompute.proto.LeaseRequest.Kind
+	(*ControlHello)(nil),         // 4: holocompute.proto.ControlHello
+	(*NodeCapabilities)(nil),     // 5: holocompute.proto.NodeCapabilities
+	(*ClusterState)(nil),         // 6: holocompute.proto.ClusterState
+	(*MemberState)(nil),          // 7: holocompute.proto.MemberState
+	(*Ring)(nil),                 // 8: holocompute.proto.Ring
+	(*RingNode)(nil),             // 9: holocompute.proto.RingNode
+	(*ShardAssignment)(nil),      // 10: holocompute.proto.ShardAssignment
+	(*PageRequest)(nil),          // 11: holocompute.proto.PageRequest
+	(*PageResponse)(nil),         // 12: holocompute.proto.PageResponse
+	(*LeaseRequest)(nil),         // 13: holocompute.proto.LeaseRequest
+	(*LeaseGrant)(nil),           // 14: holocompute.proto.LeaseGrant
+	(*TaskSubmit)(nil),           // 15: holocompute.proto.TaskSubmit
+	(*TaskCancel)(nil),           // 16: holocompute.proto.TaskCancel
+	(*ResourceHints)(nil),        // 17: holocompute.proto.ResourceHints
+	(*TaskResult)(nil),           // 18: holocompute.proto.TaskResult
+	(*TaskLog)(nil),              // 19: holocompute.proto.TaskLog
+	(*ProtocolError)(nil),        // 20: holocompute.proto.ProtocolError
+	(*SignedEnvelope)(nil),       // 21: holocompute.proto.SignedEnvelope
+	(*ArrayLease)(nil),           // 22: holocompute.proto.ArrayLease
+	(*ArrayName)(nil),            // 23: holocompute.proto.ArrayName
+	(*LeaseQuery)(nil),           // 24: holocompute.proto.LeaseQuery
+	(*LeaseInfo)(nil),            // 25: holocompute.proto.LeaseInfo
+	(*LeaseReport)(nil),          // 26: holocompute.proto.LeaseReport
+	(*ArrayQuery)(nil),           // 27: holocompute.proto.ArrayQuery
+	(*ArrayInfo)(nil),            // 28: holocompute.proto.ArrayInfo
+	(*PagePush)(nil),             // 29: holocompute.proto.PagePush
+	(*PageRemap)(nil),            // 30: holocompute.proto.PageRemap
+	(*KeyExchange)(nil),          // 31: holocompute.proto.KeyExchange
+	(*AuthenticatedMessage)(nil), // 32: holocompute.proto.AuthenticatedMessage
+	(*Ping)(nil),                 // 33: holocompute.proto.Ping
+	(*ProbePing)(nil),            // 34: holocompute.proto.ProbePing
+	(*ProbePingReq)(nil),         // 35: holocompute.proto.ProbePingReq
+	(*ProbeAck)(nil),             // 36: holocompute.proto.ProbeAck
+	(*MetricsQuery)(nil),         // 37: holocompute.proto.MetricsQuery
+	(*NodeMetrics)(nil),          // 38: holocompute.proto.NodeMetrics
+	(*RangeRun)(nil),             // 39: holocompute.proto.RangeRun
+	(*RangeProgress)(nil),        // 40: holocompute.proto.RangeProgress
+	(*RangeResult)(nil),          // 41: holocompute.proto.RangeResult
+	(*ConfigQuery)(nil),          // 42: holocompute.proto.ConfigQuery
+	(*ConfigDocument)(nil),       // 43: holocompute.proto.ConfigDocument
+	(*ConfigUpdate)(nil),         // 44: holocompute.proto.ConfigUpdate
+	(*ConfigAck)(nil),            // 45: holocompute.proto.ConfigAck
+	nil,                          // 46: holocompute.proto.ClusterState.RingsEntry
+	nil,                          // 47: holocompute.proto.ClusterState.ShardAssignmentsEntry
+	nil,                          // 48: holocompute.proto.TaskSubmit.InputsRefEntry
+	nil,                          // 49: holocompute.proto.TaskSubmit.OutputsRefEntry
+	nil,                          // 50: holocompute.proto.TaskSubmit.ImportsEntry
+	nil,                          // 51: holocompute.proto.TaskResult.OutputsRefEntry
+	nil,                          // 52: holocompute.proto.ArrayInfo.PageOwnersEntry
+	nil,                          // 53: holocompute.proto.ArrayInfo.PageEpochsEntry
+	nil,                          // 54: holocompute.proto.PageRemap.PageOwnersEntry
+	nil,                          // 55: holocompute.proto.PageRemap.PageEpochsEntry
 }
 var file_pkg_proto_messages_proto_depIdxs = []int32{
 	5,  // 0: holocompute.proto.ControlHello.caps:type_name -> holocompute.proto.NodeCapabilities
-	17, // 1: holocompute.proto.ClusterState.rings:type_name -> holocompute.proto.ClusterState.RingsEntry
-	18, // 2: holocompute.proto.ClusterState.shard_assignments:type_name -> holocompute.proto.ClusterState.ShardAssignmentsEntry
-	8,  // 3: holocompute.proto.Ring.nodes:type_name -> holocompute.proto.RingNode
-	2,  // 4: holocompute.proto.PageResponse.status:type_name -> holocompute.proto.PageResponse.Status
-	0,  // 5: holocompute.proto.PageResponse.encoding:type_name -> holocompute.proto.Encoding
-	3,  // 6: holocompute.proto.LeaseRequest.kind:type_name -> holocompute.proto.LeaseRequest.Kind
-	19, // 7: holocompute.proto.TaskSubmit.inputs_ref:type_name -> holocompute.proto.TaskSubmit.InputsRefEntry
-	15, // 8: holocompute.proto.TaskSubmit.resource_hints:type_name -> holocompute.proto.ResourceHints
-	1,  // 9: holocompute.proto.TaskResult.status:type_name -> holocompute.proto.TaskStatus
-	20, // 10: holocompute.proto.TaskResult.outputs_ref:type_name -> holocompute.proto.TaskResult.OutputsRefEntry
-	7,  // 11: holocompute.proto.ClusterState.RingsEntry.value:type_name -> holocompute.proto.Ring
-	9,  // 12: holocompute.proto.ClusterState.ShardAssignmentsEntry.value:type_name -> holocompute.proto.ShardAssignment
-	13, // [13:13] is the sub-list for method output_type
-	13, // [13:13] is the sub-list for method input_type
-	13, // [13:13] is the sub-list for extension type_name
-	13, // [13:13] is the sub-list for extension extendee
-	0,  // [0:13] is the sub-list for field type_name
+	46, // 1: holocompute.proto.ClusterState.rings:type_name -> holocompute.proto.ClusterState.RingsEntry
+	47, // 2: holocompute.proto.ClusterState.shard_assignments:type_name -> holocompute.proto.ClusterState.ShardAssignmentsEntry
+	22, // 3: holocompute.proto.ClusterState.array_leases:type_name -> holocompute.proto.ArrayLease
+	7,  // 4: holocompute.proto.ClusterState.members:type_name -> holocompute.proto.MemberState
+	23, // 5: holocompute.proto.ClusterState.array_names:type_name -> holocompute.proto.ArrayName
+	5,  // 6: holocompute.proto.MemberState.caps:type_name -> holocompute.proto.NodeCapabilities
+	9,  // 7: holocompute.proto.Ring.nodes:type_name -> holocompute.proto.RingNode
+	2,  // 8: holocompute.proto.PageResponse.status:type_name -> holocompute.proto.PageResponse.Status
+	0,  // 9: holocompute.proto.PageResponse.encoding:type_name -> holocompute.proto.Encoding
+	3,  // 10: holocompute.proto.LeaseRequest.kind:type_name -> holocompute.proto.LeaseRequest.Kind
+	48, // 11: holocompute.proto.TaskSubmit.inputs_ref:type_name -> holocompute.proto.TaskSubmit.InputsRefEntry
+	17, // 12: holocompute.proto.TaskSubmit.resource_hints:type_name -> holocompute.proto.ResourceHints
+	49, // 13: holocompute.proto.TaskSubmit.outputs_ref:type_name -> holocompute.proto.TaskSubmit.OutputsRefEntry
+	50, // 14: holocompute.proto.TaskSubmit.imports:type_name -> holocompute.proto.TaskSubmit.ImportsEntry
+	1,  // 15: holocompute.proto.TaskResult.status:type_name -> holocompute.proto.TaskStatus
+	51, // 16: holocompute.proto.TaskResult.outputs_ref:type_name -> holocompute.proto.TaskResult.OutputsRefEntry
+	3,  // 17: holocompute.proto.LeaseInfo.kind:type_name -> holocompute.proto.LeaseRequest.Kind
+	25, // 18: holocompute.proto.LeaseReport.leases:type_name -> holocompute.proto.LeaseInfo
+	0,  // 19: holocompute.proto.ArrayInfo.compression:type_name -> holocompute.proto.Encoding
+	52, // 20: holocompute.proto.ArrayInfo.page_owners:type_name -> holocompute.proto.ArrayInfo.PageOwnersEntry
+	53, // 21: holocompute.proto.ArrayInfo.page_epochs:type_name -> holocompute.proto.ArrayInfo.PageEpochsEntry
+	54, // 22: holocompute.proto.PageRemap.page_owners:type_name -> holocompute.proto.PageRemap.PageOwnersEntry
+	55, // 23: holocompute.proto.PageRemap.page_epochs:type_name -> holocompute.proto.PageRemap.PageEpochsEntry
+	15, // 24: holocompute.proto.RangeRun.task:type_name -> holocompute.proto.TaskSubmit
+	8,  // 25: holocompute.proto.ClusterState.RingsEntry.value:type_name -> holocompute.proto.Ring
+	10, // 26: holocompute.proto.ClusterState.ShardAssignmentsEntry.value:type_name -> holocompute.proto.ShardAssignment
+	27, // [27:27] is the sub-list for method output_type
+	27, // [27:27] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
 }
 
 func init() { file_pkg_proto_messages_proto_init() }
@@ -1203,7 +3477,7 @@ func file_pkg_proto_messages_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_proto_messages_proto_rawDesc), len(file_pkg_proto_messages_proto_rawDesc)),
 			NumEnums:      4,
-			NumMessages:   17,
+			NumMessages:   52,
 			NumExtensions: 0,
 			NumServices:   0,
 		},